@@ -0,0 +1,306 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+// Package apiclient provides typed clients for the REST APIs of the Pelican
+// federation services, for tools that work with a federation's registry and
+// director.  The clients handle authentication, retry transient failures, and
+// return the services' errors as *APIError.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+)
+
+type (
+	// Options of the API clients; the zero value is usable
+	Options struct {
+		// The bearer token sent with every request, if not empty
+		Token string
+		// Called for the bearer token of each request, e.g. to refresh the token
+		// before it expires; takes precedence over Token
+		TokenSource func(ctx context.Context) (string, error)
+		// The number of times a request is retried after a transient failure
+		// (a network error or an HTTP status 429, 502, 503 or 504); 3 if zero,
+		// and negative to never retry
+		MaxRetries int
+		// The wait before the first retry, doubled for each retry after it, unless
+		// the service says when to retry with Retry-After; 1s if zero
+		RetryBackoff time.Duration
+		// The HTTP client making the requests; by default, a client with the
+		// transport of config.GetTransport()
+		HTTPClient *http.Client
+		// The User-Agent header of the requests
+		UserAgent string
+	}
+
+	// An error response of a federation service
+	APIError struct {
+		Method     string
+		URL        string
+		StatusCode int
+		Problem    common.Problem
+	}
+
+	// The health of a server and its components, as reported by the server
+	HealthStatus struct {
+		Status     string                     `json:"status"`
+		Components map[string]ComponentStatus `json:"components"`
+	}
+
+	ComponentStatus struct {
+		Status     string `json:"status"`
+		Message    string `json:"message,omitempty"`
+		LastUpdate int64  `json:"last_update"`
+	}
+
+	// The state shared by the clients of the services
+	baseClient struct {
+		serviceUrl *url.URL
+		options    Options
+		httpClient *http.Client
+	}
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = time.Second
+	maxRetryAfter       = 5 * time.Minute
+)
+
+func (e *APIError) Error() string {
+	detail := e.Problem.Detail
+	if detail == "" {
+		detail = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s failed with HTTP status %d (%s): %s", e.Method, e.URL, e.StatusCode, e.Problem.Code, detail)
+}
+
+// Get the machine-readable code of the error, e.g. common.ErrCodeNotFound
+func (e *APIError) Code() common.ErrorCode {
+	return e.Problem.Code
+}
+
+func newBaseClient(serviceUrl string, options *Options) (*baseClient, error) {
+	if serviceUrl == "" {
+		return nil, errors.New("the URL of the service is empty")
+	}
+	parsed, err := url.Parse(serviceUrl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid URL of the service %s", serviceUrl)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, errors.Errorf("the URL of the service %s has no scheme or host", serviceUrl)
+	}
+	client := &baseClient{serviceUrl: parsed}
+	if options != nil {
+		client.options = *options
+	}
+	if client.options.MaxRetries == 0 {
+		client.options.MaxRetries = defaultMaxRetries
+	}
+	if client.options.RetryBackoff <= 0 {
+		client.options.RetryBackoff = defaultRetryBackoff
+	}
+	if client.options.UserAgent == "" {
+		client.options.UserAgent = "pelican-apiclient/" + config.PelicanVersion
+	}
+	if client.options.HTTPClient != nil {
+		// Copy the client so the login cookie doesn't leak to the caller's other requests
+		httpClient := *client.options.HTTPClient
+		client.httpClient = &httpClient
+	} else {
+		client.httpClient = &http.Client{Transport: config.GetTransport()}
+	}
+	if client.httpClient.Jar == nil {
+		if client.httpClient.Jar, err = cookiejar.New(nil); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Log in to the web API of the service with a username and password.  The requests
+// made by the client afterwards carry the login cookie, which the service requires
+// for the APIs of its web UI.
+func (c *baseClient) Login(ctx context.Context, user, password string) error {
+	body := map[string]string{"user": user, "password": password}
+	return c.do(ctx, http.MethodPost, "/api/v1.0/auth/login", nil, body, nil, false)
+}
+
+// Get the version of the service, the server modules it runs, and their features
+func (c *baseClient) Version(ctx context.Context) (*common.VersionInfo, error) {
+	info := &common.VersionInfo{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/version", nil, nil, info, true); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Get the health of the service's components; requires logging in
+func (c *baseClient) Health(ctx context.Context) (*HealthStatus, error) {
+	health := &HealthStatus{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/metrics/health", nil, nil, health, true); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// Check if the status means the request may succeed if it's retried
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Get how long the response asks the client to wait before retrying, or zero
+func retryAfter(resp *http.Response) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if when, err := http.ParseTime(header); err == nil {
+		wait = time.Until(when)
+	}
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
+
+// Make a request to the API at apiPath with the query and a JSON body, decoding the
+// JSON response into res if it's not nil.  Idempotent requests are retried after
+// transient failures.
+func (c *baseClient) do(ctx context.Context, method, apiPath string, query url.Values, body, res interface{}, idempotent bool) error {
+	reqUrl := *c.serviceUrl
+	reqUrl.Path = strings.TrimSuffix(reqUrl.Path, "/") + apiPath
+	reqUrl.RawPath = ""
+	reqUrl.RawQuery = query.Encode()
+
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "failed to encode the request body")
+		}
+	}
+
+	backoff := c.options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		wait, err := c.attempt(ctx, method, reqUrl.String(), bodyBytes, res)
+		if err == nil {
+			return nil
+		}
+		if !idempotent || wait < 0 || attempt >= c.options.MaxRetries {
+			return err
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		log.Debugf("Retrying %s %s in %s after a failure: %v", method, reqUrl.String(), wait, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Make one attempt at a request.  On failure, returns how long to wait before
+// retrying, zero for the default backoff, or negative if retrying won't help.
+func (c *baseClient) attempt(ctx context.Context, method, reqUrl string, body []byte, res interface{}) (time.Duration, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, bodyReader)
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("User-Agent", c.options.UserAgent)
+	req.Header.Set("Accept", "application/json, "+common.ProblemContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token := c.options.Token
+	if c.options.TokenSource != nil {
+		if token, err = c.options.TokenSource(ctx); err != nil {
+			return -1, errors.Wrap(err, "failed to get the token for the request")
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read the response of %s %s", method, reqUrl)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{Method: method, URL: reqUrl, StatusCode: resp.StatusCode}
+		if problem, ok := common.ParseProblem(resp.StatusCode, respBody); ok {
+			apiErr.Problem = problem
+		} else {
+			apiErr.Problem = common.NewProblem(resp.StatusCode, "", strings.TrimSpace(string(respBody)))
+		}
+		if isRetryableStatus(resp.StatusCode) {
+			return retryAfter(resp), apiErr
+		}
+		return -1, apiErr
+	}
+	if res == nil || len(respBody) == 0 {
+		return 0, nil
+	}
+	if err = json.Unmarshal(respBody, res); err != nil {
+		return -1, errors.Wrapf(err, "failed to decode the response of %s %s", method, reqUrl)
+	}
+	return 0, nil
+}
@@ -0,0 +1,162 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestRegistryClient(t *testing.T) {
+	var listCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1.0/registry", func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first call to exercise the retries
+		if listCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "Bearer some-token", r.Header.Get("Authorization"))
+		assert.Contains(t, []string{"/foo", "/foo/bar"}, r.URL.Query().Get("prefix"))
+		assert.Empty(t, r.URL.Query().Get("status"))
+		namespaces := []Namespace{
+			{ID: 1, Prefix: "/foo", AdminMetadata: AdminMetadata{Status: "Approved"}},
+			{ID: 2, Prefix: "/foo/bar", AdminMetadata: AdminMetadata{Status: "Pending"}},
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(namespaces))
+	})
+	mux.HandleFunc("/api/v1.0/registry/foo/.well-known/issuer.jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		assert.NoError(t, json.NewEncoder(w).Encode(common.NewProblem(http.StatusForbidden, "", "The origin has not been approved by federation administrator")))
+	})
+	mux.HandleFunc("/api/v1.0/registry/checkNamespaceStatus", func(w http.ResponseWriter, r *http.Request) {
+		req := map[string]string{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "/foo/bar", req["prefix"])
+		_, err := w.Write([]byte(`{"approved": false, "write_mode": "immutable", "cache_policy": {"max-age": 3600, "must-revalidate": true}}`))
+		assert.NoError(t, err)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewRegistryClient(server.URL, &Options{Token: "some-token", RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+
+	namespaces, err := client.ListNamespaces(context.Background(), NamespaceFilter{Prefix: "/foo"})
+	require.NoError(t, err)
+	assert.Len(t, namespaces, 2)
+	assert.Equal(t, int32(2), listCalls.Load())
+
+	status, err := client.CheckNamespaceStatus(context.Background(), "/foo/bar", nil)
+	require.NoError(t, err)
+	assert.True(t, status.Registered)
+	assert.False(t, status.Approved)
+
+	writeMode, err := client.GetNamespaceWriteMode(context.Background(), "/foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, common.WriteModeImmutable, writeMode)
+
+	policies, err := client.GetNamespacePolicies(context.Background(), "/foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, common.WriteModeImmutable, policies.WriteMode)
+	assert.Equal(t, &common.CachePolicy{MaxAge: 3600, MustRevalidate: true}, policies.CachePolicy)
+
+	_, err = client.GetNamespaceKeys(context.Background(), "/foo")
+	apiErr := &APIError{}
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, common.ErrCodeForbidden, apiErr.Code())
+	assert.Contains(t, err.Error(), "not been approved")
+}
+
+func TestDirectorClient(t *testing.T) {
+	var statCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1.0/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		login := map[string]string{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+		if login["user"] != "admin" || login["password"] != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "Password and user didn't match"}`))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "login", Value: "some-login", Path: "/"})
+	})
+	mux.HandleFunc("/api/v1.0/director_ui/servers/origins/stat/foo/bar.txt", func(w http.ResponseWriter, r *http.Request) {
+		statCalls.Add(1)
+		if cookie, err := r.Cookie("login"); err != nil || cookie.Value != "some-login" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "Authentication required to perform this operation"}`))
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("min_responses"))
+		_, _ = w.Write([]byte(`{"ok": true, "message": "", "metadata": [{"url": {"Scheme": "https", "Host": "origin.example.com", "Path": "/foo/bar.txt"}, "content_length": 5}]}`))
+	})
+	mux.HandleFunc("/api/v1.0/director_ui/servers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "cache", r.URL.Query().Get("server_type"))
+		_, _ = w.Write([]byte(`[{"name": "cache1", "url": "https://cache.example.com", "type": "Cache"}]`))
+	})
+	mux.HandleFunc("/api/v1.0/director/resolve", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "doi:10.1234/abc", r.URL.Query().Get("identifier"))
+		_, _ = w.Write([]byte(`{"identifier": "doi:10.1234/abc", "path": "/foo/abc"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewDirectorClient(server.URL, &Options{RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	servers, err := client.ListServers(ctx, common.CacheType)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, common.CacheType, servers[0].Type)
+
+	resolved, err := client.ResolveIdentifier(ctx, "doi:10.1234/abc")
+	require.NoError(t, err)
+	assert.Equal(t, "/foo/abc", resolved.Path)
+
+	// Errors that retrying can't fix aren't retried
+	_, err = client.StatObject(ctx, "/foo/bar.txt", StatOptions{MinResponses: 1})
+	apiErr := &APIError{}
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, common.ErrCodeUnauthenticated, apiErr.Code())
+	assert.Equal(t, int32(1), statCalls.Load())
+
+	assert.Error(t, client.Login(ctx, "admin", "wrong"))
+	require.NoError(t, client.Login(ctx, "admin", "password"))
+	result, err := client.StatObject(ctx, "/foo/bar.txt", StatOptions{MinResponses: 1})
+	require.NoError(t, err)
+	assert.True(t, result.OK)
+	require.Len(t, result.Metadata, 1)
+	assert.Equal(t, "origin.example.com", result.Metadata[0].URL.Host)
+	assert.Equal(t, 5, result.Metadata[0].ContentLength)
+}
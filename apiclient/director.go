@@ -0,0 +1,137 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+type (
+	// A client of the director APIs
+	DirectorClient struct {
+		*baseClient
+	}
+
+	// An origin or cache advertised to the director
+	ServerInfo struct {
+		Name      string            `json:"name"`
+		AuthURL   string            `json:"authUrl"`
+		URL       string            `json:"url"`    // The URL of the server's data transfers
+		WebURL    string            `json:"webUrl"` // The URL of the server's web UI and APIs
+		Type      common.ServerType `json:"type"`
+		Latitude  float64           `json:"latitude"`
+		Longitude float64           `json:"longitude"`
+		Version   string            `json:"version,omitempty"`
+		Outdated  bool              `json:"outdated,omitempty"`    // The version is older than the federation's minimum
+		Skew      string            `json:"versionSkew,omitempty"` // Why the version may not work with the director's, if it may not
+		// "draining" or "active" if the server is drained for or under scheduled maintenance
+		Maintenance string `json:"maintenance,omitempty"`
+	}
+
+	// Options of DirectorClient.StatObject; the zero value uses the director's defaults
+	StatOptions struct {
+		MinResponses int // The number of origins with the object to wait for
+		MaxResponses int // The maximum number of origins to return
+	}
+
+	// The origins that have an object
+	StatResult struct {
+		OK       bool             `json:"ok"`
+		Message  string           `json:"message"`
+		Metadata []ObjectMetadata `json:"metadata"`
+	}
+
+	// An origin's metadata of an object
+	ObjectMetadata struct {
+		URL           url.URL `json:"url"` // The URL of the object at the origin
+		Checksum      string  `json:"checksum"`
+		ContentLength int     `json:"content_length"`
+	}
+)
+
+// Create a client of the director at directorUrl, e.g. the federation's
+// Federation.DirectorUrl.  The options may be nil.
+func NewDirectorClient(directorUrl string, options *Options) (*DirectorClient, error) {
+	base, err := newBaseClient(directorUrl, options)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectorClient{base}, nil
+}
+
+// List the origins and caches advertised to the director; serverType is
+// common.OriginType, common.CacheType or empty for both
+func (c *DirectorClient) ListServers(ctx context.Context, serverType common.ServerType) ([]ServerInfo, error) {
+	query := url.Values{}
+	switch serverType {
+	case common.OriginType:
+		query.Set("server_type", "origin")
+	case common.CacheType:
+		query.Set("server_type", "cache")
+	}
+	servers := []ServerInfo{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/director_ui/servers", query, nil, &servers, true); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// List the namespaces advertised by the origins of the federation
+func (c *DirectorClient) ListNamespaces(ctx context.Context) ([]common.NamespaceAdV2, error) {
+	namespaces := []common.NamespaceAdV2{}
+	if err := c.do(ctx, http.MethodGet, "/api/v2.0/director/listNamespaces", nil, nil, &namespaces, true); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+// Find the origins that have the object at objectPath; requires logging in
+func (c *DirectorClient) StatObject(ctx context.Context, objectPath string, options StatOptions) (*StatResult, error) {
+	query := url.Values{}
+	if options.MinResponses > 0 {
+		query.Set("min_responses", strconv.Itoa(options.MinResponses))
+	}
+	if options.MaxResponses > 0 {
+		query.Set("max_responses", strconv.Itoa(options.MaxResponses))
+	}
+	result := &StatResult{}
+	apiPath := path.Join("/api/v1.0/director_ui/servers/origins/stat", objectPath)
+	if err := c.do(ctx, http.MethodGet, apiPath, query, nil, result, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Resolve a persistent identifier, such as doi:10.1234/abc, to the federation path of the
+// object or collection its namespace's owner assigned it to
+func (c *DirectorClient) ResolveIdentifier(ctx context.Context, identifier string) (*common.PersistentIdentifier, error) {
+	query := url.Values{}
+	query.Set("identifier", identifier)
+	resolved := &common.PersistentIdentifier{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/director/resolve", query, nil, resolved, true); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
@@ -0,0 +1,280 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+type (
+	// A client of the registry APIs
+	RegistryClient struct {
+		*baseClient
+	}
+
+	// A namespace registered with the registry
+	Namespace struct {
+		ID            int                    `json:"id"`
+		Prefix        string                 `json:"prefix"`
+		Pubkey        string                 `json:"pubkey,omitempty"` // The public keys of the namespace, a JWK set
+		Identity      string                 `json:"identity,omitempty"`
+		AdminMetadata AdminMetadata          `json:"admin_metadata"`
+		CustomFields  map[string]interface{} `json:"custom_fields,omitempty"`
+	}
+
+	// The information about a namespace registration kept by the registry administrators
+	AdminMetadata struct {
+		UserID                string           `json:"user_id"`
+		Description           string           `json:"description"`
+		SiteName              string           `json:"site_name"`
+		Institution           string           `json:"institution"`
+		SecurityContactUserID string           `json:"security_contact_user_id"`
+		WriteMode             common.WriteMode `json:"write_mode"`
+		CacheMaxAge           int              `json:"cache_max_age"`
+		CacheMustRevalidate   bool             `json:"cache_must_revalidate"`
+		Status                string           `json:"status"` // "Pending", "Approved", "Denied" or "Unknown"
+		ApproverID            string           `json:"approver_id"`
+		ApprovedAt            time.Time        `json:"approved_at"`
+		CreatedAt             time.Time        `json:"created_at"`
+		UpdatedAt             time.Time        `json:"updated_at"`
+	}
+
+	// Filters of the namespaces listed by RegistryClient.ListNamespaces; empty
+	// fields don't filter
+	NamespaceFilter struct {
+		Status      string // e.g. "Approved"
+		Prefix      string // Only namespaces under this prefix
+		Institution string // The ID of the institution
+		ServerType  string // "origin" or "cache"
+	}
+
+	// A search of the registered namespaces by RegistryClient.SearchNamespaces
+	NamespaceSearch struct {
+		Query      string // The prefix or substring to find
+		Substring  bool   // Find namespaces containing Query rather than starting with it
+		Status     string
+		ServerType string
+		Page       int // Starting at 1; the first page if zero
+		PageSize   int // The registry's default if zero
+	}
+
+	// A page of the namespaces found by a search
+	NamespaceSearchResult struct {
+		Namespaces []Namespace `json:"namespaces"`
+		Total      int         `json:"total"`
+		Page       int         `json:"page"`
+		PageSize   int         `json:"page_size"`
+	}
+
+	// The status of a namespace prefix at the registry
+	NamespaceStatus struct {
+		Prefix     string
+		Registered bool
+		// Whether the registered public key is the one checked; false if no key was checked
+		KeyMatch bool
+		Approved bool
+		Message  string
+		// Whether the namespace's objects may be changed once written
+		WriteMode common.WriteMode
+		// How long caches may keep the namespace's objects; nil if the caches' settings apply
+		CachePolicy *common.CachePolicy
+	}
+
+	// The policies the registration of a namespace sets for the servers handling its objects
+	NamespacePolicies struct {
+		WriteMode   common.WriteMode
+		CachePolicy *common.CachePolicy
+	}
+)
+
+// Create a client of the registry at registryUrl, e.g. the federation's
+// Federation.RegistryUrl.  The options may be nil.
+func NewRegistryClient(registryUrl string, options *Options) (*RegistryClient, error) {
+	base, err := newBaseClient(registryUrl, options)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryClient{base}, nil
+}
+
+// List the registered namespaces.  Unless the client's token was issued by the registry
+// with the web_ui.access scope, the registry only lists approved namespaces.
+func (c *RegistryClient) ListNamespaces(ctx context.Context, filter NamespaceFilter) ([]Namespace, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"status":      filter.Status,
+		"prefix":      filter.Prefix,
+		"institution": filter.Institution,
+		"server_type": filter.ServerType,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	namespaces := []Namespace{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/registry", query, nil, &namespaces, true); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+// Search the registered namespaces, one page at a time
+func (c *RegistryClient) SearchNamespaces(ctx context.Context, search NamespaceSearch) (*NamespaceSearchResult, error) {
+	query := url.Values{}
+	query.Set("q", search.Query)
+	if search.Substring {
+		query.Set("match", "substring")
+	}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	if search.ServerType != "" {
+		query.Set("server_type", search.ServerType)
+	}
+	if search.Page > 0 {
+		query.Set("page", strconv.Itoa(search.Page))
+	}
+	if search.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(search.PageSize))
+	}
+	result := &NamespaceSearchResult{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1.0/registry/search", query, nil, result, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Check if the namespace prefix is registered and approved.  If publicKey isn't nil,
+// also check if it's the public key the prefix is registered with.  Without the key,
+// the prefix is looked up with ListNamespaces, so registrations that aren't approved
+// are only found with a registry token.
+func (c *RegistryClient) CheckNamespaceStatus(ctx context.Context, prefix string, publicKey jwk.Key) (*NamespaceStatus, error) {
+	status := &NamespaceStatus{Prefix: prefix}
+	if publicKey == nil {
+		// The registry only checks registrations against a key; find the namespace in the list instead
+		namespaces, err := c.ListNamespaces(ctx, NamespaceFilter{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespaces {
+			if ns.Prefix == prefix {
+				status.Registered = true
+				status.Approved = ns.AdminMetadata.Status == "Approved"
+				status.WriteMode = ns.AdminMetadata.WriteMode
+				if ns.AdminMetadata.CacheMaxAge > 0 || ns.AdminMetadata.CacheMustRevalidate {
+					status.CachePolicy = &common.CachePolicy{MaxAge: int64(ns.AdminMetadata.CacheMaxAge), MustRevalidate: ns.AdminMetadata.CacheMustRevalidate}
+				}
+				break
+			}
+		}
+		return status, nil
+	}
+
+	keyBytes, err := json.Marshal(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the public key")
+	}
+	existsReq := map[string]string{"prefix": prefix, "pubkey": string(keyBytes)}
+	existsRes := struct {
+		PrefixExists bool   `json:"prefix_exists"`
+		KeyMatch     bool   `json:"key_match"`
+		Message      string `json:"message"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/api/v1.0/registry/checkNamespaceExists", nil, existsReq, &existsRes, true); err != nil {
+		return nil, err
+	}
+	status.Registered = existsRes.PrefixExists
+	status.KeyMatch = existsRes.KeyMatch
+	status.Message = existsRes.Message
+	if !status.Registered {
+		return status, nil
+	}
+
+	statusRes, err := c.checkNamespaceStatus(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	status.Approved = statusRes.Approved
+	status.WriteMode = statusRes.WriteMode
+	status.CachePolicy = statusRes.CachePolicy
+	return status, nil
+}
+
+// The response of the registry's checkNamespaceStatus API
+type namespaceStatusResponse struct {
+	Approved    bool                `json:"approved"`
+	WriteMode   common.WriteMode    `json:"write_mode"`
+	CachePolicy *common.CachePolicy `json:"cache_policy"`
+}
+
+func (c *RegistryClient) checkNamespaceStatus(ctx context.Context, prefix string) (*namespaceStatusResponse, error) {
+	statusRes := &namespaceStatusResponse{}
+	if err := c.do(ctx, http.MethodPost, "/api/v1.0/registry/checkNamespaceStatus", nil, map[string]string{"prefix": prefix}, statusRes, true); err != nil {
+		return nil, err
+	}
+	return statusRes, nil
+}
+
+// Get the write mode set in the registration of the namespace prefix, which the origin
+// exporting the namespace enforces.  Unlike CheckNamespaceStatus, namespaces that aren't
+// approved are found without a registry token.
+func (c *RegistryClient) GetNamespaceWriteMode(ctx context.Context, prefix string) (common.WriteMode, error) {
+	statusRes, err := c.checkNamespaceStatus(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	return statusRes.WriteMode, nil
+}
+
+// Get the write mode and cache policy set in the registration of the namespace prefix,
+// which the origin exporting the namespace enforces and passes on to the caches.  Like
+// GetNamespaceWriteMode, namespaces that aren't approved are found without a registry token.
+func (c *RegistryClient) GetNamespacePolicies(ctx context.Context, prefix string) (*NamespacePolicies, error) {
+	statusRes, err := c.checkNamespaceStatus(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &NamespacePolicies{WriteMode: statusRes.WriteMode, CachePolicy: statusRes.CachePolicy}, nil
+}
+
+// Get the public keys of the namespace prefix.  The registry refuses with
+// common.ErrCodeForbidden if the namespace isn't approved.
+func (c *RegistryClient) GetNamespaceKeys(ctx context.Context, prefix string) (jwk.Set, error) {
+	raw := json.RawMessage{}
+	apiPath := path.Join("/api/v1.0/registry", prefix, ".well-known", "issuer.jwks")
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, nil, &raw, true); err != nil {
+		return nil, err
+	}
+	keys, err := jwk.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the public keys of %s", prefix)
+	}
+	return keys, nil
+}
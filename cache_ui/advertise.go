@@ -21,6 +21,7 @@ package cache_ui
 import (
 	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/server_utils"
 )
 
@@ -36,6 +37,10 @@ func (server *CacheServer) CreateAdvertisement(name string, originUrl string, or
 		DataURL:    originUrl,
 		WebURL:     originWebUrl,
 		Namespaces: server.GetNamespaceAds(),
+		Caps: common.Capabilities{
+			ServeStale: param.Cache_ServeStaleOnOriginOutage.GetBool(),
+			WriteBack:  param.Cache_EnableWriteBack.GetBool(),
+		},
 	}
 
 	return ad, nil
@@ -0,0 +1,112 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Remove the copies of the namespace's objects that were cached longer ago than the max
+// age of its cache policy, so the next read fetches the object again from the origin.
+// Returns the number of objects removed.
+func evictExpiredObjects(ctx context.Context, ns common.NamespaceAdV2, now time.Time) (int, error) {
+	if ns.CachePolicy == nil || ns.CachePolicy.MaxAge <= 0 {
+		return 0, nil
+	}
+	nsDir := filepath.Join(param.Cache_DataLocation.GetString(), filepath.FromSlash(ns.Path))
+	evicted := 0
+	err := filepath.WalkDir(nsDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || strings.HasSuffix(filePath, cinfoSuffix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// As when serving stale objects, the modification time is when the object was cached
+		if ns.CachePolicy.IsFresh(now.Sub(info.ModTime())) {
+			return nil
+		}
+		// Remove the state first, so the file cache never trusts a half-removed object
+		if err := os.Remove(filePath + cinfoSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return errors.Wrapf(err, "failed to remove the state of expired object %s", filePath)
+		}
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return errors.Wrapf(err, "failed to remove expired object %s", filePath)
+		}
+		evicted++
+		return nil
+	})
+	return evicted, err
+}
+
+// Enforce the max age of the cache policies the namespaces set in their registrations
+// by periodically evicting the copies that expired.  XRootD's file cache has no notion
+// of an object's age, so without this, copies of mutable objects could be served for as
+// long as they fit in the cache.
+func LaunchCachePolicyEnforcement(ctx context.Context, egrp *errgroup.Group, server *CacheServer) {
+	interval := param.Cache_CachePolicyCheckInterval.GetDuration()
+	if interval <= 0 {
+		log.Warningln("Cache.CachePolicyCheckInterval is not positive; the namespaces' cache max ages will not be enforced")
+		return
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			for _, ns := range server.GetNamespaceAds() {
+				evicted, err := evictExpiredObjects(ctx, ns, time.Now())
+				if err != nil && ctx.Err() == nil {
+					log.Warningf("Failed to evict the expired objects of namespace %s: %v", ns.Path, err)
+				}
+				if evicted > 0 {
+					log.Infof("Evicted %d objects of namespace %s cached longer than its max age of %ds", evicted, ns.Path, ns.CachePolicy.MaxAge)
+				}
+			}
+		}
+	})
+}
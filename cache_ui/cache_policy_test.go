@@ -0,0 +1,88 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestEvictExpiredObjects(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dataLocation := t.TempDir()
+	viper.Set("Cache.DataLocation", dataLocation)
+
+	now := time.Now()
+	writeCached := func(objectPath string, age time.Duration) string {
+		filePath := filepath.Join(dataLocation, objectPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+		require.NoError(t, os.WriteFile(filePath+cinfoSuffix, []byte{}, 0644))
+		cachedAt := now.Add(-age)
+		require.NoError(t, os.Chtimes(filePath, cachedAt, cachedAt))
+		return filePath
+	}
+	fresh := writeCached("/mutable/fresh.txt", time.Minute)
+	expired := writeCached("/mutable/sub/expired.txt", 2*time.Hour)
+	unlimited := writeCached("/static/old.txt", 2*time.Hour)
+
+	ctx := context.Background()
+	evicted, err := evictExpiredObjects(ctx, common.NamespaceAdV2{Path: "/mutable", CachePolicy: &common.CachePolicy{MaxAge: 3600}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	assert.FileExists(t, fresh)
+	assert.FileExists(t, fresh+cinfoSuffix)
+	assert.NoFileExists(t, expired)
+	assert.NoFileExists(t, expired+cinfoSuffix)
+
+	// Namespaces without a max age are left to the file cache's own purging
+	evicted, err = evictExpiredObjects(ctx, common.NamespaceAdV2{Path: "/static"}, now)
+	require.NoError(t, err)
+	assert.Zero(t, evicted)
+	evicted, err = evictExpiredObjects(ctx, common.NamespaceAdV2{Path: "/static", CachePolicy: &common.CachePolicy{MustRevalidate: true}}, now)
+	require.NoError(t, err)
+	assert.Zero(t, evicted)
+	assert.FileExists(t, unlimited)
+
+	// Nothing of the namespace is cached yet
+	evicted, err = evictExpiredObjects(ctx, common.NamespaceAdV2{Path: "/missing", CachePolicy: &common.CachePolicy{MaxAge: 1}}, now)
+	require.NoError(t, err)
+	assert.Zero(t, evicted)
+}
+
+func TestCachePolicyCacheControl(t *testing.T) {
+	var policy *common.CachePolicy
+	assert.Equal(t, "", policy.CacheControl())
+	assert.True(t, policy.IsFresh(365*24*time.Hour))
+
+	policy = &common.CachePolicy{MaxAge: 3600, MustRevalidate: true}
+	assert.Equal(t, "max-age=3600, must-revalidate", policy.CacheControl())
+	assert.True(t, policy.IsFresh(time.Hour))
+	assert.False(t, policy.IsFresh(time.Hour+time.Second))
+}
@@ -0,0 +1,246 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// The settings of XRootD's file cache administrators may tune at runtime
+	pfcConfig struct {
+		BlockSize      string `json:"blockSize"`
+		PrefetchBlocks int    `json:"prefetchBlocks"`
+		RamSize        string `json:"ramSize"`
+		MaxRequestSize string `json:"maxRequestSize"`
+	}
+
+	// A change to the settings; settings left out are unchanged
+	pfcConfigUpdate struct {
+		BlockSize      *string `json:"blockSize"`
+		PrefetchBlocks *int    `json:"prefetchBlocks"`
+		RamSize        *string `json:"ramSize"`
+		MaxRequestSize *string `json:"maxRequestSize"`
+		// Restart XRootD right away instead of after Cache.ConfigRestartDelay
+		RestartNow bool `json:"restartNow"`
+	}
+
+	pfcConfigStatus struct {
+		pfcConfig
+		// The time of the scheduled restart applying changed settings, if any
+		RestartAt *time.Time `json:"restartAt,omitempty"`
+		// Why the last restart failed, if it did
+		LastRestartError string `json:"lastRestartError,omitempty"`
+	}
+
+	// Applies changed settings by restarting XRootD, once per batch of changes
+	pfcRestarter struct {
+		mutex            sync.Mutex
+		ctx              context.Context
+		restart          func(ctx context.Context) error
+		timer            *time.Timer
+		restartAt        time.Time
+		lastRestartError string
+	}
+)
+
+// Parse an XRootD size such as "128k", whose unit is one of the suffixes
+func parseXrootdSize(size string, suffixes string) (int64, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	if size == "" {
+		return 0, errors.New("the size is empty")
+	}
+	multiplier := int64(1)
+	if unit := size[len(size)-1]; unit < '0' || unit > '9' {
+		exponent := strings.IndexByte("kmgt", unit)
+		if exponent < 0 || !strings.ContainsRune(suffixes, rune(unit)) {
+			return 0, errors.Errorf("the unit of %q must be one of %q", size, suffixes)
+		}
+		multiplier <<= 10 * (exponent + 1)
+		size = size[:len(size)-1]
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, errors.Errorf("%q is not a positive size", size)
+	}
+	return value * multiplier, nil
+}
+
+// Check that the settings are ones XRootD accepts
+func (config pfcConfig) validate() error {
+	blockSize, err := parseXrootdSize(config.BlockSize, "km")
+	if err != nil {
+		return errors.Wrap(err, "invalid block size")
+	}
+	if blockSize < 4<<10 || blockSize > 16<<20 || blockSize%(4<<10) != 0 {
+		return errors.Errorf("the block size %s must be a multiple of 4k between 4k and 16m", config.BlockSize)
+	}
+	if config.PrefetchBlocks < 0 || config.PrefetchBlocks > 128 {
+		return errors.Errorf("the number of prefetched blocks %d must be between 0 and 128", config.PrefetchBlocks)
+	}
+	ramSize, err := parseXrootdSize(config.RamSize, "kmgt")
+	if err != nil {
+		return errors.Wrap(err, "invalid RAM size")
+	}
+	// XRootD needs room for the prefetched blocks of at least a few files
+	if ramSize < 4*blockSize*int64(config.PrefetchBlocks+1) {
+		return errors.Errorf("the RAM size %s is too small for %d prefetched blocks of %s", config.RamSize, config.PrefetchBlocks, config.BlockSize)
+	}
+	if config.MaxRequestSize != "" {
+		if _, err := parseXrootdSize(config.MaxRequestSize, "kmg"); err != nil {
+			return errors.Wrap(err, "invalid maximum request size")
+		}
+	}
+	return nil
+}
+
+// The current settings, including changes not yet applied by a restart
+func getPfcConfig() pfcConfig {
+	return pfcConfig{
+		BlockSize:      param.Cache_BlockSize.GetString(),
+		PrefetchBlocks: param.Cache_PrefetchBlocks.GetInt(),
+		RamSize:        param.Cache_RamSize.GetString(),
+		MaxRequestSize: param.Cache_MaxRequestSize.GetString(),
+	}
+}
+
+// Schedule a restart after the delay, unless one is scheduled sooner.  Returns the
+// time of the restart.
+func (restarter *pfcRestarter) schedule(delay time.Duration) time.Time {
+	restarter.mutex.Lock()
+	defer restarter.mutex.Unlock()
+	restartAt := time.Now().Add(delay)
+	if restarter.timer != nil {
+		if !restarter.restartAt.After(restartAt) {
+			return restarter.restartAt
+		}
+		restarter.timer.Stop()
+	}
+	restarter.restartAt = restartAt
+	restarter.timer = time.AfterFunc(delay, restarter.run)
+	return restartAt
+}
+
+func (restarter *pfcRestarter) run() {
+	restarter.mutex.Lock()
+	restarter.timer = nil
+	restarter.mutex.Unlock()
+	if restarter.ctx.Err() != nil {
+		return
+	}
+
+	log.Infoln("Restarting XRootD to apply the changed cache settings")
+	errMsg := ""
+	if err := restarter.restart(restarter.ctx); err != nil {
+		log.Errorln("Failed to restart XRootD to apply the changed cache settings:", err)
+		errMsg = err.Error()
+	}
+	restarter.mutex.Lock()
+	restarter.lastRestartError = errMsg
+	restarter.mutex.Unlock()
+}
+
+func (restarter *pfcRestarter) getStatus() pfcConfigStatus {
+	restarter.mutex.Lock()
+	defer restarter.mutex.Unlock()
+	status := pfcConfigStatus{pfcConfig: getPfcConfig(), LastRestartError: restarter.lastRestartError}
+	if restarter.timer != nil {
+		restartAt := restarter.restartAt
+		status.RestartAt = &restartAt
+	}
+	return status
+}
+
+func (restarter *pfcRestarter) handleGetConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, restarter.getStatus())
+}
+
+func (restarter *pfcRestarter) handleUpdateConfig(ctx *gin.Context) {
+	update := pfcConfigUpdate{}
+	if err := ctx.ShouldBindJSON(&update); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	config := getPfcConfig()
+	if update.BlockSize != nil {
+		config.BlockSize = *update.BlockSize
+	}
+	if update.PrefetchBlocks != nil {
+		config.PrefetchBlocks = *update.PrefetchBlocks
+	}
+	if update.RamSize != nil {
+		config.RamSize = *update.RamSize
+	}
+	if update.MaxRequestSize != nil {
+		config.MaxRequestSize = *update.MaxRequestSize
+	}
+	if err := config.validate(); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	viper.Set("Cache.BlockSize", config.BlockSize)
+	viper.Set("Cache.PrefetchBlocks", config.PrefetchBlocks)
+	viper.Set("Cache.RamSize", config.RamSize)
+	viper.Set("Cache.MaxRequestSize", config.MaxRequestSize)
+	delay := param.Cache_ConfigRestartDelay.GetDuration()
+	if update.RestartNow {
+		delay = 0
+	}
+	restartAt := restarter.schedule(delay)
+	log.Infof("User %s changed the cache settings to %+v; XRootD will restart at %s to apply them", ctx.GetString("User"), config, restartAt.Format(time.RFC3339))
+	ctx.JSON(http.StatusOK, restarter.getStatus())
+}
+
+// Register the API for administrators to tune XRootD's file cache at runtime.  XRootD only
+// reads these settings at startup, so changes are applied by restart, which regenerates the
+// XRootD configuration and restarts the daemons.  Changes last until the cache is restarted;
+// they must also be made in the configuration file to persist.
+func RegisterPfcConfigAPI(ctx context.Context, router *gin.Engine, restart func(ctx context.Context) error) {
+	restarter := &pfcRestarter{ctx: ctx, restart: restart}
+	group := router.Group("/api/v1.0/cache_ui/config/pfc")
+	web_ui.HandleAPI(group, http.MethodGet, "", web_ui.APIDoc{
+		Summary:  "Get the settings of the cache's file cache and whether a restart applying changes is scheduled",
+		Auth:     web_ui.APIAuthLogin,
+		Response: pfcConfigStatus{},
+	}, web_ui.AuthHandler, restarter.handleGetConfig)
+	web_ui.HandleAPI(group, http.MethodPatch, "", web_ui.APIDoc{
+		Summary: "Change the settings of the cache's file cache",
+		Description: "The changes are applied by restarting XRootD after Cache.ConfigRestartDelay, or right away if restartNow is set; " +
+			"further changes within the delay are applied by the same restart",
+		Auth:      web_ui.APIAuthAdmin,
+		Responses: map[int]string{http.StatusOK: "The settings and the time of the restart applying them"},
+		Request:   pfcConfigUpdate{},
+		Response:  pfcConfigStatus{},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, restarter.handleUpdateConfig)
+}
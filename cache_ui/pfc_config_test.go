@@ -0,0 +1,142 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+func TestParseXrootdSize(t *testing.T) {
+	size, err := parseXrootdSize("128k", "km")
+	require.NoError(t, err)
+	assert.Equal(t, int64(128<<10), size)
+	size, err = parseXrootdSize("4G", "kmgt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4<<30), size)
+	size, err = parseXrootdSize("4096", "km")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), size)
+
+	for _, invalid := range []string{"", "k", "-4k", "4x", "4g"} {
+		_, err = parseXrootdSize(invalid, "km")
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPfcConfigValidate(t *testing.T) {
+	valid := pfcConfig{BlockSize: "128k", PrefetchBlocks: 20, RamSize: "4g"}
+	assert.NoError(t, valid.validate())
+
+	invalid := valid
+	invalid.BlockSize = "130k"
+	assert.Error(t, invalid.validate())
+	invalid = valid
+	invalid.PrefetchBlocks = -1
+	assert.Error(t, invalid.validate())
+	invalid = valid
+	invalid.RamSize = "1m"
+	assert.Error(t, invalid.validate())
+	invalid = valid
+	invalid.MaxRequestSize = "big"
+	assert.Error(t, invalid.validate())
+}
+
+func TestPfcConfigAPI(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Cache.BlockSize", "128k")
+	viper.Set("Cache.PrefetchBlocks", 20)
+	viper.Set("Cache.RamSize", "4g")
+	viper.Set("Cache.ConfigRestartDelay", time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	restarted := make(chan struct{}, 1)
+	var restarts atomic.Int32
+	restarter := &pfcRestarter{ctx: ctx, restart: func(ctx context.Context) error {
+		restarts.Add(1)
+		restarted <- struct{}{}
+		return nil
+	}}
+	router := gin.New()
+	router.GET("/config", restarter.handleGetConfig)
+	router.PATCH("/config", restarter.handleUpdateConfig)
+
+	update := func(body string) (*httptest.ResponseRecorder, pfcConfigStatus) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/config", bytes.NewBufferString(body)))
+		status := pfcConfigStatus{}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		}
+		return w, status
+	}
+
+	w, _ := update(`{"blockSize": "3k"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "128k", param.Cache_BlockSize.GetString())
+
+	// Changes wait for the scheduled restart
+	w, status := update(`{"blockSize": "1m", "ramSize": "8g"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pfcConfig{BlockSize: "1m", PrefetchBlocks: 20, RamSize: "8g"}, status.pfcConfig)
+	require.NotNil(t, status.RestartAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *status.RestartAt, time.Minute)
+	assert.Equal(t, "1m", param.Cache_BlockSize.GetString())
+	assert.Equal(t, int32(0), restarts.Load())
+
+	// A later change doesn't push the restart back
+	_, status = update(`{"prefetchBlocks": 10}`)
+	require.NotNil(t, status.RestartAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *status.RestartAt, time.Minute)
+	assert.Equal(t, 10, status.PrefetchBlocks)
+
+	// Restarting now applies all the pending changes with a single restart
+	_, _ = update(`{"restartNow": true}`)
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("XRootD wasn't restarted")
+	}
+	assert.Eventually(t, func() bool { return restarter.getStatus().RestartAt == nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), restarts.Load())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	status = pfcConfigStatus{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, pfcConfig{BlockSize: "1m", PrefetchBlocks: 10, RamSize: "8g"}, status.pfcConfig)
+	assert.Nil(t, status.RestartAt)
+	assert.Empty(t, status.LastRestartError)
+}
@@ -0,0 +1,156 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// XRootD's file cache keeps the state of each cached file next to it in a file with this suffix
+const cinfoSuffix = ".cinfo"
+
+// Find the namespace of the cache's advertised namespaces the object belongs to
+func (server *CacheServer) getObjectNamespace(objectPath string) *common.NamespaceAdV2 {
+	var best *common.NamespaceAdV2
+	for _, ns := range server.GetNamespaceAds() {
+		prefix := strings.TrimSuffix(ns.Path, "/")
+		if objectPath != prefix && !strings.HasPrefix(objectPath, prefix+"/") {
+			continue
+		}
+		if best == nil || len(ns.Path) > len(best.Path) {
+			nsCopy := ns
+			best = &nsCopy
+		}
+	}
+	return best
+}
+
+// GET/HEAD /api/v1.0/cache/stale/*path
+//
+// Serve an object the cache already holds while its origin is unavailable.  The
+// response tells the client the origin is unavailable and how long ago the object
+// was cached; objects that aren't fully cached are rejected with 504 Gateway Timeout.
+func (server *CacheServer) serveStaleObject(ctx *gin.Context) {
+	objectPath := path.Clean("/" + ctx.Param("path"))
+	ctx.Header(common.OriginStatusHeader, "unavailable")
+
+	ns := server.getObjectNamespace(objectPath)
+	if ns == nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "No namespace served by this cache contains "+objectPath)
+		return
+	}
+	if !ns.Caps.PublicRead {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Only objects of public namespaces are served while their origin is unavailable")
+		return
+	}
+	if ns.CachePolicy != nil && ns.CachePolicy.MustRevalidate {
+		web_ui.WriteProblem(ctx, http.StatusGatewayTimeout, common.ErrCodeUnavailable, "The namespace requires checking cached objects with its origin, which is unavailable")
+		return
+	}
+	if strings.HasSuffix(objectPath, cinfoSuffix) {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "Object not found")
+		return
+	}
+
+	// The file cache stores objects under their full path in Cache.DataLocation
+	filePath := filepath.Join(param.Cache_DataLocation.GetString(), filepath.FromSlash(objectPath))
+	info, err := os.Stat(filePath)
+	if err == nil && !info.IsDir() {
+		_, err = os.Stat(filePath + cinfoSuffix)
+	}
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+		web_ui.WriteProblem(ctx, http.StatusGatewayTimeout, common.ErrCodeUnavailable, "The object isn't cached and its origin is unavailable")
+		return
+	} else if err != nil {
+		log.Errorf("Failed to stat cached object %s: %v", filePath, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to look up the cached object")
+		return
+	}
+	if !isFullyCached(info) {
+		web_ui.WriteProblem(ctx, http.StatusGatewayTimeout, common.ErrCodeUnavailable, "The object is only partially cached and its origin is unavailable")
+		return
+	}
+
+	age := time.Since(info.ModTime())
+	if age < 0 {
+		age = 0
+	}
+	if !ns.CachePolicy.IsFresh(age) {
+		web_ui.WriteProblem(ctx, http.StatusGatewayTimeout, common.ErrCodeUnavailable, "The cached object is older than the namespace's max age and its origin is unavailable")
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Errorf("Failed to open cached object %s: %v", filePath, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to open the cached object")
+		return
+	}
+	defer file.Close()
+
+	if cacheControl := ns.CachePolicy.CacheControl(); cacheControl != "" {
+		ctx.Header("Cache-Control", cacheControl)
+	}
+	ctx.Header("Age", strconv.FormatInt(int64(age.Seconds()), 10))
+	http.ServeContent(ctx.Writer, ctx.Request, path.Base(objectPath), info.ModTime(), file)
+}
+
+// Add the namespaces of previous that are missing from refreshed.  The director only
+// lists namespaces with an available origin, so while the cache serves stale objects
+// it keeps advertising the namespaces whose origins went away.
+func RetainNamespaces(previous []common.NamespaceAdV2, refreshed []common.NamespaceAdV2) []common.NamespaceAdV2 {
+	seen := make(map[string]bool, len(refreshed))
+	for _, ns := range refreshed {
+		seen[ns.Path] = true
+	}
+	merged := append([]common.NamespaceAdV2{}, refreshed...)
+	for _, ns := range previous {
+		if !seen[ns.Path] {
+			log.Debugf("Namespace %s is no longer listed by the director; still serving its cached objects", ns.Path)
+			merged = append(merged, ns)
+		}
+	}
+	return merged
+}
+
+// Register the cache's APIs on the web engine
+func RegisterCacheAPI(router *gin.Engine, server *CacheServer) {
+	if param.Cache_ServeStaleOnOriginOutage.GetBool() {
+		staleDoc := web_ui.APIDoc{
+			Summary:   "Serve an object the cache already holds while its origin is unavailable",
+			Responses: map[int]string{http.StatusOK: "The cached object", http.StatusGatewayTimeout: "The object isn't fully cached"},
+		}
+		web_ui.HandleAPI(&router.RouterGroup, http.MethodGet, common.StaleObjectAPIPath+"/*path", staleDoc, server.serveStaleObject)
+		web_ui.HandleAPI(&router.RouterGroup, http.MethodHead, common.StaleObjectAPIPath+"/*path", staleDoc, server.serveStaleObject)
+	}
+}
@@ -0,0 +1,128 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+func TestServeStaleObject(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Partially cached objects can only be detected on Linux")
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dataLocation := t.TempDir()
+	viper.Set("Cache.DataLocation", dataLocation)
+	viper.Set("Cache.ServeStaleOnOriginOutage", true)
+
+	writeCached := func(objectPath string, content []byte, sparseSize int64) {
+		filePath := filepath.Join(dataLocation, objectPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, content, 0644))
+		if sparseSize > 0 {
+			require.NoError(t, os.Truncate(filePath, sparseSize))
+		}
+		require.NoError(t, os.WriteFile(filePath+cinfoSuffix, []byte{}, 0644))
+	}
+	writeCached("/public/hello.txt", []byte("Hello, World!"), 0)
+	writeCached("/public/partial.bin", []byte{}, 1<<20)
+	writeCached("/protected/secret.txt", []byte("secret"), 0)
+	writeCached("/mutable/hello.txt", []byte("Hello, World!"), 0)
+	writeCached("/mutable/old.txt", []byte("Hello, World!"), 0)
+	writeCached("/revalidated/hello.txt", []byte("Hello, World!"), 0)
+	cachedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dataLocation, "public", "hello.txt"), cachedAt, cachedAt))
+	require.NoError(t, os.Chtimes(filepath.Join(dataLocation, "mutable", "old.txt"), cachedAt, cachedAt))
+
+	server := &CacheServer{}
+	server.SetNamespaceAds([]common.NamespaceAdV2{
+		{Path: "/public", Caps: common.Capabilities{PublicRead: true, Read: true}},
+		{Path: "/protected", Caps: common.Capabilities{Read: true}},
+		{Path: "/mutable", Caps: common.Capabilities{PublicRead: true, Read: true}, CachePolicy: &common.CachePolicy{MaxAge: 600}},
+		{Path: "/revalidated", Caps: common.Capabilities{PublicRead: true, Read: true}, CachePolicy: &common.CachePolicy{MustRevalidate: true}},
+	})
+	router := gin.New()
+	RegisterCacheAPI(router, server)
+
+	doRequest := func(objectPath string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", common.StaleObjectAPIPath+objectPath, nil)
+		require.NoError(t, err)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "unavailable", w.Header().Get(common.OriginStatusHeader))
+		return w
+	}
+
+	w := doRequest("/public/hello.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Hello, World!", w.Body.String())
+	assert.Equal(t, "3600", w.Header().Get("Age"))
+
+	assert.Equal(t, http.StatusGatewayTimeout, doRequest("/public/partial.bin").Code)
+	assert.Equal(t, http.StatusGatewayTimeout, doRequest("/public/missing.txt").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest("/public/hello.txt.cinfo").Code)
+	assert.Equal(t, http.StatusForbidden, doRequest("/protected/secret.txt").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest("/other/hello.txt").Code)
+
+	// The namespaces' cache policies limit what's served without the origin
+	w = doRequest("/mutable/hello.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "max-age=600", w.Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusGatewayTimeout, doRequest("/mutable/old.txt").Code)
+	assert.Equal(t, http.StatusGatewayTimeout, doRequest("/revalidated/hello.txt").Code)
+}
+
+func TestRetainNamespaces(t *testing.T) {
+	previous := []common.NamespaceAdV2{{Path: "/foo"}, {Path: "/bar"}}
+	refreshed := []common.NamespaceAdV2{{Path: "/bar", PublicRead: true}, {Path: "/baz"}}
+	merged := RetainNamespaces(previous, refreshed)
+	require.Len(t, merged, 3)
+	assert.Equal(t, common.NamespaceAdV2{Path: "/bar", PublicRead: true}, merged[0])
+	assert.Equal(t, "/baz", merged[1].Path)
+	assert.Equal(t, "/foo", merged[2].Path)
+}
+
+func TestCacheAPIDocumented(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Cache.ServeStaleOnOriginOutage", true)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	RegisterCacheAPI(engine, &CacheServer{})
+	RegisterPfcConfigAPI(context.Background(), engine, func(ctx context.Context) error { return nil })
+
+	assert.Empty(t, web_ui.UndocumentedRoutes(engine.Routes()))
+}
@@ -0,0 +1,794 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	writeBackState string
+
+	// An upload the cache accepted and writes back to the origin.  The record is kept in
+	// Cache.WriteBackLocation as <id>.json next to the object's data in <id>.data.
+	writeBackRecord struct {
+		ID         string         `json:"id"`
+		Path       string         `json:"path"`
+		Token      string         `json:"token,omitempty"`
+		Size       int64          `json:"size"`
+		ReceivedAt time.Time      `json:"receivedAt"`
+		Attempts   int            `json:"attempts"`
+		LastError  string         `json:"lastError,omitempty"`
+		State      writeBackState `json:"state"`
+	}
+
+	writeBackQueue struct {
+		dir          string
+		directorUrl  string
+		maxSpoolSize int64
+		retries      int
+		retryDelay   time.Duration
+		client       *http.Client
+
+		ctx        context.Context
+		mutex      sync.Mutex
+		records    map[string]*writeBackRecord
+		waiters    map[string][]chan error
+		spoolBytes int64
+		pending    chan string
+	}
+
+	// The director or origin refused the write-back in a way retrying won't change
+	permanentWriteBackError struct {
+		err error
+	}
+)
+
+const (
+	writeBackQueued writeBackState = "queued"
+	writeBackFailed writeBackState = "failed"
+)
+
+var (
+	// Check the uploader's token authorizes writing the object; replaced in tests
+	verifyWriteBackToken = verifyNamespaceToken
+)
+
+func (e *permanentWriteBackError) Error() string {
+	return e.err.Error()
+}
+
+// The record as listed to administrators; the uploader's token is never shown
+func (record writeBackRecord) withoutToken() writeBackRecord {
+	record.Token = ""
+	return record
+}
+
+func newWriteBackQueue(ctx context.Context, dir string) (*writeBackQueue, error) {
+	maxSpoolSize, err := parseXrootdSize(param.Cache_WriteBackMaxSpoolSize.GetString(), "kmgt")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Cache.WriteBackMaxSpoolSize")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create the write-back spool directory %s", dir)
+	}
+	return &writeBackQueue{
+		dir:          dir,
+		directorUrl:  param.Federation_DirectorUrl.GetString(),
+		maxSpoolSize: maxSpoolSize,
+		retries:      param.Cache_WriteBackRetries.GetInt(),
+		retryDelay:   param.Cache_WriteBackRetryInterval.GetDuration(),
+		client:       &http.Client{Transport: config.GetTransport()},
+		ctx:          ctx,
+		records:      make(map[string]*writeBackRecord),
+		waiters:      make(map[string][]chan error),
+		pending:      make(chan string, 1024),
+	}, nil
+}
+
+func (q *writeBackQueue) dataFile(id string) string {
+	return filepath.Join(q.dir, id+".data")
+}
+
+func (q *writeBackQueue) recordFile(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+// Atomically write the record next to its data
+func (q *writeBackQueue) saveRecord(record *writeBackRecord) error {
+	contents, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	filename := q.recordFile(record.ID)
+	if err := os.WriteFile(filename+".tmp", contents, 0600); err != nil {
+		return errors.Wrap(err, "failed to write the write-back record")
+	}
+	return errors.Wrap(os.Rename(filename+".tmp", filename), "failed to replace the write-back record")
+}
+
+// Load the uploads spooled before the cache restarted; the queued ones are written back again
+func (q *writeBackQueue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the write-back spool directory %s", q.dir)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if !found {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			return errors.Wrap(err, "failed to read a write-back record")
+		}
+		record := &writeBackRecord{}
+		if err := json.Unmarshal(contents, record); err != nil || record.ID != id {
+			log.Warningf("Ignoring the invalid write-back record %s", entry.Name())
+			continue
+		}
+		if _, err := os.Stat(q.dataFile(id)); err != nil {
+			log.Warningf("Ignoring the write-back record %s, whose data is missing: %v", entry.Name(), err)
+			continue
+		}
+		q.records[id] = record
+		q.spoolBytes += record.Size
+	}
+	// Data of uploads that were interrupted before they were acknowledged is removed
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".data")
+		if found && q.records[id] == nil {
+			if err := os.Remove(filepath.Join(q.dir, entry.Name())); err != nil {
+				log.Warningf("Failed to remove the incomplete upload %s: %v", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Reserve space in the spool for size more bytes; false if that exceeds Cache.WriteBackMaxSpoolSize
+func (q *writeBackQueue) reserve(size int64) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.spoolBytes+size > q.maxSpoolSize {
+		return false
+	}
+	q.spoolBytes += size
+	return true
+}
+
+// Whether size more bytes fit in the spool, without reserving them
+func (q *writeBackQueue) fits(size int64) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.spoolBytes+size <= q.maxSpoolSize
+}
+
+func (q *writeBackQueue) release(size int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.spoolBytes -= size
+}
+
+// Queue the record for write-back
+func (q *writeBackQueue) schedule(id string) {
+	select {
+	case q.pending <- id:
+	default:
+		// Don't block the upload if the workers are far behind
+		go func() {
+			select {
+			case q.pending <- id:
+			case <-q.ctx.Done():
+			}
+		}()
+	}
+}
+
+// Add an upload whose data was spooled; if wait is set, the returned channel receives
+// the result of the write-back
+func (q *writeBackQueue) add(record *writeBackRecord, wait bool) (<-chan error, error) {
+	if err := q.saveRecord(record); err != nil {
+		return nil, err
+	}
+	var result chan error
+	q.mutex.Lock()
+	q.records[record.ID] = record
+	if wait {
+		result = make(chan error, 1)
+		q.waiters[record.ID] = append(q.waiters[record.ID], result)
+	}
+	q.mutex.Unlock()
+	q.schedule(record.ID)
+	return result, nil
+}
+
+// Tell the uploads waiting for the write-back of the record how it went
+func (q *writeBackQueue) notify(id string, err error) {
+	q.mutex.Lock()
+	waiters := q.waiters[id]
+	delete(q.waiters, id)
+	q.mutex.Unlock()
+	for _, waiter := range waiters {
+		waiter <- err
+	}
+}
+
+// Remove the record and its data from the spool
+func (q *writeBackQueue) remove(id string) (*writeBackRecord, error) {
+	q.mutex.Lock()
+	record := q.records[id]
+	if record == nil {
+		q.mutex.Unlock()
+		return nil, nil
+	}
+	delete(q.records, id)
+	q.spoolBytes -= record.Size
+	q.mutex.Unlock()
+
+	if err := os.Remove(q.recordFile(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return record, errors.Wrap(err, "failed to remove the write-back record")
+	}
+	if err := os.Remove(q.dataFile(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return record, errors.Wrap(err, "failed to remove the spooled upload")
+	}
+	return record, nil
+}
+
+// List the spooled uploads, oldest first
+func (q *writeBackQueue) list() []writeBackRecord {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	records := make([]writeBackRecord, 0, len(q.records))
+	for _, record := range q.records {
+		records = append(records, record.withoutToken())
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ReceivedAt.Before(records[j].ReceivedAt)
+	})
+	return records
+}
+
+// Move a failed upload back to the queue; false if there's no such failed upload
+func (q *writeBackQueue) retry(id string) (bool, error) {
+	q.mutex.Lock()
+	record := q.records[id]
+	if record == nil || record.State != writeBackFailed {
+		q.mutex.Unlock()
+		return false, nil
+	}
+	record.State = writeBackQueued
+	record.Attempts = 0
+	record.LastError = ""
+	recordCopy := *record
+	q.mutex.Unlock()
+
+	if err := q.saveRecord(&recordCopy); err != nil {
+		return true, err
+	}
+	q.schedule(id)
+	return true, nil
+}
+
+// Find the origin to write the object to by asking the director, as clients do
+func (q *writeBackQueue) getOriginUrl(ctx context.Context, record *writeBackRecord) (string, error) {
+	directorUrl, err := url.Parse(q.directorUrl)
+	if err != nil || directorUrl.Host == "" {
+		return "", errors.Errorf("invalid director URL %q", q.directorUrl)
+	}
+	directorUrl.Path = path.Join(directorUrl.Path, "/api/v1.0/director/origin", record.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, directorUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+record.Token)
+	req.Header.Set(common.ObjectSizeHeader, fmt.Sprint(record.Size))
+	// The redirect is followed by hand, as the token isn't sent on to other hosts
+	client := *q.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to query the director")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		err = errors.Errorf("the director responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if isPermanentWriteBackStatus(resp.StatusCode) {
+			return "", &permanentWriteBackError{err}
+		}
+		return "", err
+	}
+	return resp.Header.Get("Location"), nil
+}
+
+// Whether the response refuses the write-back for good rather than until something recovers
+func isPermanentWriteBackStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge:
+		return true
+	}
+	return false
+}
+
+// Upload the spooled object to its origin
+func (q *writeBackQueue) upload(ctx context.Context, record *writeBackRecord) error {
+	originUrl, err := q.getOriginUrl(ctx, record)
+	if err != nil {
+		return err
+	}
+	data, err := os.Open(q.dataFile(record.ID))
+	if err != nil {
+		return &permanentWriteBackError{errors.Wrap(err, "failed to open the spooled upload")}
+	}
+	defer data.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, originUrl, data)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = record.Size
+	if record.Size == 0 {
+		req.Body = http.NoBody
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return os.Open(q.dataFile(record.ID))
+	}
+	req.Header.Set("Authorization", "Bearer "+record.Token)
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to upload to the origin")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = errors.Errorf("the origin responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if isPermanentWriteBackStatus(resp.StatusCode) {
+			return &permanentWriteBackError{err}
+		}
+		return err
+	}
+	return nil
+}
+
+// Make one attempt at writing the record back, then remove it, schedule a retry, or
+// move it to the failure queue
+func (q *writeBackQueue) process(ctx context.Context, id string) {
+	q.mutex.Lock()
+	record := q.records[id]
+	if record == nil || record.State != writeBackQueued {
+		q.mutex.Unlock()
+		return
+	}
+	recordCopy := *record
+	q.mutex.Unlock()
+
+	err := q.upload(ctx, &recordCopy)
+	if err == nil {
+		log.Infof("Wrote the upload of %s (%d bytes) back to its origin", recordCopy.Path, recordCopy.Size)
+		if _, err := q.remove(id); err != nil {
+			log.Warningf("Failed to remove the upload of %s from the write-back spool: %v", recordCopy.Path, err)
+		}
+		q.notify(id, nil)
+		return
+	}
+	if ctx.Err() != nil {
+		// The cache is shutting down; the record is picked up again on restart
+		return
+	}
+
+	q.mutex.Lock()
+	if q.records[id] != record {
+		q.mutex.Unlock()
+		return
+	}
+	record.Attempts++
+	record.LastError = err.Error()
+	var permanent *permanentWriteBackError
+	if errors.As(err, &permanent) || record.Attempts >= q.retries {
+		record.State = writeBackFailed
+	}
+	recordCopy = *record
+	q.mutex.Unlock()
+
+	if saveErr := q.saveRecord(&recordCopy); saveErr != nil {
+		log.Warningf("Failed to save the write-back record of %s: %v", recordCopy.Path, saveErr)
+	}
+	if recordCopy.State == writeBackFailed {
+		log.Errorf("Giving up writing the upload of %s back to its origin after %d attempts: %v", recordCopy.Path, recordCopy.Attempts, err)
+		q.notify(id, err)
+		return
+	}
+	delay := q.retryDelay << (recordCopy.Attempts - 1)
+	log.Warningf("Failed to write the upload of %s back to its origin (attempt %d); retrying in %s: %v", recordCopy.Path, recordCopy.Attempts, delay, err)
+	time.AfterFunc(delay, func() { q.schedule(id) })
+}
+
+// Start the workers writing the queued uploads back to their origins
+func (q *writeBackQueue) launch(ctx context.Context, egrp *errgroup.Group, workers int) {
+	for i := 0; i < workers; i++ {
+		egrp.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case id := <-q.pending:
+					q.process(ctx, id)
+				}
+			}
+		})
+	}
+	q.mutex.Lock()
+	queued := []string{}
+	for id, record := range q.records {
+		if record.State == writeBackQueued {
+			queued = append(queued, id)
+		}
+	}
+	q.mutex.Unlock()
+	for _, id := range queued {
+		q.schedule(id)
+	}
+}
+
+// Check the token was issued for the namespace by one of its issuers and grants
+// writing the object
+func verifyNamespaceToken(ctx context.Context, ns *common.NamespaceAdV2, objectPath string, token string) error {
+	unverified, err := jwt.Parse([]byte(token), jwt.WithVerify(false), jwt.WithValidate(false))
+	if err != nil {
+		return errors.Wrap(err, "invalid token")
+	}
+	var issuer *common.TokenIssuer
+	for idx := range ns.Issuer {
+		if strings.TrimSuffix(ns.Issuer[idx].IssuerUrl.String(), "/") == strings.TrimSuffix(unverified.Issuer(), "/") {
+			issuer = &ns.Issuer[idx]
+			break
+		}
+	}
+	if issuer == nil {
+		return errors.Errorf("the token's issuer %s is not an issuer of the namespace %s", unverified.Issuer(), ns.Path)
+	}
+
+	metadata := struct {
+		JwksUri string `json:"jwks_uri"`
+	}{}
+	metadataUrl := strings.TrimSuffix(issuer.IssuerUrl.String(), "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: config.GetTransport()}).Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the issuer's metadata")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("the issuer's metadata at %s returned HTTP status %d", metadataUrl, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil || metadata.JwksUri == "" {
+		return errors.Errorf("the issuer's metadata at %s has no jwks_uri", metadataUrl)
+	}
+	keys, err := utils.GetIssuerJWKS(ctx, metadata.JwksUri)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the issuer's public keys")
+	}
+	parsed, err := jwt.Parse([]byte(token), jwt.WithKeySet(keys), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
+	if err != nil {
+		return errors.Wrap(err, "failed to verify the token")
+	}
+	if !tokenGrantsWrite(parsed, issuer.BasePaths, objectPath) {
+		return errors.Errorf("the token doesn't grant writing %s", objectPath)
+	}
+	return nil
+}
+
+// Whether one of the token's storage.create or storage.modify scopes covers the object; the
+// scopes' paths are relative to the issuer's base paths
+func tokenGrantsWrite(token jwt.Token, basePaths []string, objectPath string) bool {
+	scopeClaim, ok := token.Get("scope")
+	if !ok {
+		return false
+	}
+	scopes, ok := scopeClaim.(string)
+	if !ok {
+		return false
+	}
+	for _, scope := range strings.Fields(scopes) {
+		authz, scopePath, _ := strings.Cut(scope, ":")
+		if authz != "storage.create" && authz != "storage.modify" {
+			continue
+		}
+		if scopePath == "" {
+			scopePath = "/"
+		}
+		for _, basePath := range basePaths {
+			granted := path.Join(basePath, path.Clean("/"+scopePath))
+			if objectPath == granted || strings.HasPrefix(objectPath, strings.TrimSuffix(granted, "/")+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Generate the identifier of an upload
+func newWriteBackID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Copy the body into the spool file, reserving space for each chunk; bodies may be chunked,
+// so their size isn't known in advance
+func (q *writeBackQueue) spoolBody(body io.Reader, file *os.File) (int64, error) {
+	written := int64(0)
+	buf := make([]byte, 1024*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if !q.reserve(int64(n)) {
+				return written, errWriteBackSpoolFull
+			}
+			written += int64(n)
+			if _, err := file.Write(buf[:n]); err != nil {
+				return written, errors.Wrap(err, "failed to spool the upload")
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		} else if readErr != nil {
+			return written, errors.Wrap(readErr, "failed to read the upload")
+		}
+	}
+}
+
+var (
+	errWriteBackSpoolFull = errors.New("the write-back spool is full")
+	errWriteBackTooLarge  = errors.New("the object is larger than the namespace accepts")
+)
+
+// PUT /api/v1.0/cache/writeback/*path
+//
+// Accept an upload and write it back to the object's origin.  The upload is acknowledged
+// once it was stored durably, or with the ack header set to "origin", once it reached the origin.
+func (q *writeBackQueue) handleUpload(server *CacheServer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		objectPath := path.Clean("/" + ctx.Param("path"))
+		ack := ctx.GetHeader(common.WriteBackAckHeader)
+		if ack == "" {
+			ack = common.WriteBackAckSpooled
+		}
+		if ack != common.WriteBackAckSpooled && ack != common.WriteBackAckOrigin {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid %s header %q; accepted values are %q and %q",
+				common.WriteBackAckHeader, ack, common.WriteBackAckSpooled, common.WriteBackAckOrigin))
+			return
+		}
+
+		ns := server.getObjectNamespace(objectPath)
+		if ns == nil {
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "No namespace served by this cache contains "+objectPath)
+			return
+		}
+		if !ns.Caps.Write {
+			web_ui.WriteProblem(ctx, http.StatusMethodNotAllowed, common.ErrCodeMethodNotAllowed, fmt.Sprintf("The namespace %s doesn't accept uploads", ns.Path))
+			return
+		}
+		if ns.UploadPolicy != nil && (!ns.UploadPolicy.AllowsName(ns.Path, objectPath) || !ns.UploadPolicy.AllowsSize(ctx.Request.ContentLength)) {
+			web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeUploadPolicyViolated, fmt.Sprintf("The upload is refused by the upload policy of the namespace %s", ns.Path))
+			return
+		}
+		token, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" {
+			web_ui.WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeMissingToken, "Uploads for write-back require a bearer token in the Authorization header")
+			return
+		}
+		if err := verifyWriteBackToken(ctx.Request.Context(), ns, objectPath, token); err != nil {
+			log.Debugf("Refusing the upload of %s for write-back: %v", objectPath, err)
+			web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "The token doesn't authorize writing the object: "+err.Error())
+			return
+		}
+		if !q.fits(ctx.Request.ContentLength) {
+			web_ui.WriteProblem(ctx, http.StatusInsufficientStorage, common.ErrCodeUnavailable, errWriteBackSpoolFull.Error())
+			return
+		}
+
+		id, err := newWriteBackID()
+		if err != nil {
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to generate an upload ID")
+			return
+		}
+		file, err := os.OpenFile(q.dataFile(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Errorf("Failed to create the spool file of an upload: %v", err)
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to spool the upload")
+			return
+		}
+		size, err := q.spoolBody(ctx.Request.Body, file)
+		if err == nil {
+			// The upload is acknowledged as durable, so the data must be on disk first
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil && ns.UploadPolicy != nil && !ns.UploadPolicy.AllowsSize(size) {
+			err = errWriteBackTooLarge
+		}
+		var record *writeBackRecord
+		var result <-chan error
+		if err == nil {
+			record = &writeBackRecord{
+				ID:         id,
+				Path:       objectPath,
+				Token:      token,
+				Size:       size,
+				ReceivedAt: time.Now().UTC(),
+				State:      writeBackQueued,
+			}
+			result, err = q.add(record, ack == common.WriteBackAckOrigin)
+		}
+		if err != nil {
+			q.release(size)
+			if removeErr := os.Remove(q.dataFile(id)); removeErr != nil {
+				log.Warningf("Failed to remove the spool file of a refused upload: %v", removeErr)
+			}
+			if errors.Is(err, errWriteBackSpoolFull) {
+				web_ui.WriteProblem(ctx, http.StatusInsufficientStorage, common.ErrCodeUnavailable, err.Error())
+			} else if errors.Is(err, errWriteBackTooLarge) {
+				web_ui.WriteProblem(ctx, http.StatusRequestEntityTooLarge, common.ErrCodeUploadPolicyViolated,
+					fmt.Sprintf("The object is %d bytes but the namespace %s accepts objects of at most %d bytes", size, ns.Path, ns.UploadPolicy.MaxObjectSize))
+			} else {
+				log.Errorf("Failed to spool the upload of %s: %v", objectPath, err)
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to spool the upload")
+			}
+			return
+		}
+		log.Debugf("Spooled the upload of %s (%d bytes) for write-back as %s", objectPath, size, id)
+
+		ctx.Header(common.WriteBackIDHeader, id)
+		ctx.Header(common.WriteBackAckHeader, ack)
+		if result != nil {
+			select {
+			case err = <-result:
+			case <-ctx.Request.Context().Done():
+				return
+			}
+			if err != nil {
+				web_ui.WriteProblem(ctx, http.StatusBadGateway, common.ErrCodeUnavailable, "The upload was spooled but couldn't be written back to the origin: "+err.Error())
+				return
+			}
+		}
+		// Clients expect the status origins respond with to uploads
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// GET /api/v1.0/cache_ui/writeback
+func (q *writeBackQueue) handleList(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, q.list())
+}
+
+// POST /api/v1.0/cache_ui/writeback/:id/retry
+func (q *writeBackQueue) handleRetry(ctx *gin.Context) {
+	found, err := q.retry(ctx.Param("id"))
+	if !found {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "No failed upload with the ID "+ctx.Param("id"))
+		return
+	} else if err != nil {
+		log.Errorf("Failed to requeue the upload %s for write-back: %v", ctx.Param("id"), err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to requeue the upload")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// DELETE /api/v1.0/cache_ui/writeback/:id
+func (q *writeBackQueue) handleDelete(ctx *gin.Context) {
+	record, err := q.remove(ctx.Param("id"))
+	if record == nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "No spooled upload with the ID "+ctx.Param("id"))
+		return
+	} else if err != nil {
+		log.Errorf("Failed to discard the spooled upload %s: %v", ctx.Param("id"), err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to discard the upload")
+		return
+	}
+	log.Infof("Discarded the upload of %s spooled for write-back", record.Path)
+	q.notify(record.ID, errors.New("the upload was discarded by an administrator"))
+	ctx.Status(http.StatusNoContent)
+}
+
+// Accept uploads for write-back when Cache.EnableWriteBack is set, writing the spooled
+// uploads back to their origins in the background
+func LaunchWriteBack(ctx context.Context, egrp *errgroup.Group, router *gin.Engine, server *CacheServer) error {
+	if !param.Cache_EnableWriteBack.GetBool() {
+		return nil
+	}
+	q, err := newWriteBackQueue(ctx, param.Cache_WriteBackLocation.GetString())
+	if err != nil {
+		return err
+	}
+	if err = q.load(); err != nil {
+		return err
+	}
+	workers := param.Cache_WriteBackWorkers.GetInt()
+	if workers <= 0 {
+		return errors.Errorf("Cache.WriteBackWorkers must be positive; got %d", workers)
+	}
+	log.Infof("Accepting uploads for write-back; %d uploads are spooled in %s", len(q.records), q.dir)
+	q.launch(ctx, egrp, workers)
+
+	web_ui.HandleAPI(&router.RouterGroup, http.MethodPut, common.WriteBackAPIPath+"/*path", web_ui.APIDoc{
+		Summary: "Upload an object the cache writes back to its origin",
+		Description: "The upload is acknowledged once the cache stored it, or once it reached the origin if the " +
+			common.WriteBackAckHeader + " header is \"origin\"",
+		Auth:      web_ui.APIAuthBearer,
+		Responses: map[int]string{http.StatusOK: "The upload was acknowledged", http.StatusInsufficientStorage: "The write-back spool is full"},
+	}, q.handleUpload(server))
+
+	group := router.Group("/api/v1.0/cache_ui/writeback")
+	web_ui.HandleAPI(group, http.MethodGet, "", web_ui.APIDoc{
+		Summary:  "List the uploads waiting to be written back and those that failed",
+		Auth:     web_ui.APIAuthAdmin,
+		Response: []writeBackRecord{},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, q.handleList)
+	web_ui.HandleAPI(group, http.MethodPost, "/:id/retry", web_ui.APIDoc{
+		Summary:   "Queue a failed upload for write-back again",
+		Auth:      web_ui.APIAuthAdmin,
+		Responses: map[int]string{http.StatusNoContent: "The upload was queued"},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, q.handleRetry)
+	web_ui.HandleAPI(group, http.MethodDelete, "/:id", web_ui.APIDoc{
+		Summary:   "Discard a spooled upload without writing it back",
+		Auth:      web_ui.APIAuthAdmin,
+		Responses: map[int]string{http.StatusNoContent: "The upload was discarded"},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, q.handleDelete)
+	return nil
+}
@@ -0,0 +1,305 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package cache_ui
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+// A director redirecting uploads to an origin that stores them, or refuses them with failStatus
+type fakeWriteBackFederation struct {
+	director   *httptest.Server
+	origin     *httptest.Server
+	mutex      sync.Mutex
+	objects    map[string]string
+	tokens     map[string]string
+	failStatus int
+}
+
+func newFakeWriteBackFederation(t *testing.T) *fakeWriteBackFederation {
+	fed := &fakeWriteBackFederation{objects: map[string]string{}, tokens: map[string]string{}}
+	fed.origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fed.mutex.Lock()
+		defer fed.mutex.Unlock()
+		if fed.failStatus != 0 {
+			w.WriteHeader(fed.failStatus)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		fed.objects[r.URL.Path] = string(body)
+		fed.tokens[r.URL.Path] = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(fed.origin.Close)
+	fed.director = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		objectPath := strings.TrimPrefix(r.URL.Path, "/api/v1.0/director/origin")
+		http.Redirect(w, r, fed.origin.URL+objectPath, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(fed.director.Close)
+	return fed
+}
+
+func (fed *fakeWriteBackFederation) getObject(objectPath string) (string, bool) {
+	fed.mutex.Lock()
+	defer fed.mutex.Unlock()
+	object, ok := fed.objects[objectPath]
+	return object, ok
+}
+
+func setupWriteBack(t *testing.T, fed *fakeWriteBackFederation) (*writeBackQueue, *gin.Engine, context.Context) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Federation.DirectorUrl", fed.director.URL)
+	viper.Set("Cache.EnableWriteBack", true)
+	viper.Set("Cache.WriteBackLocation", t.TempDir())
+	viper.Set("Cache.WriteBackRetries", 2)
+	viper.Set("Cache.WriteBackRetryInterval", "10ms")
+	viper.Set("Cache.WriteBackMaxSpoolSize", "1k")
+
+	oldVerify := verifyWriteBackToken
+	verifyWriteBackToken = func(ctx context.Context, ns *common.NamespaceAdV2, objectPath string, token string) error {
+		if token != "good-token" {
+			return assert.AnError
+		}
+		return nil
+	}
+	t.Cleanup(func() { verifyWriteBackToken = oldVerify })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	egrp := &errgroup.Group{}
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, egrp.Wait())
+	})
+	q, err := newWriteBackQueue(ctx, viper.GetString("Cache.WriteBackLocation"))
+	require.NoError(t, err)
+	q.launch(ctx, egrp, 2)
+
+	server := &CacheServer{}
+	server.SetNamespaceAds([]common.NamespaceAdV2{
+		{Path: "/writable", Caps: common.Capabilities{Read: true, Write: true}},
+		{Path: "/readonly", Caps: common.Capabilities{Read: true}},
+	})
+	router := gin.New()
+	router.PUT(common.WriteBackAPIPath+"/*path", q.handleUpload(server))
+	router.GET("/api/v1.0/cache_ui/writeback", q.handleList)
+	router.POST("/api/v1.0/cache_ui/writeback/:id/retry", q.handleRetry)
+	router.DELETE("/api/v1.0/cache_ui/writeback/:id", q.handleDelete)
+	return q, router, ctx
+}
+
+func doWriteBackUpload(router *gin.Engine, objectPath, token, ack, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, common.WriteBackAPIPath+objectPath, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if ack != "" {
+		req.Header.Set(common.WriteBackAckHeader, ack)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWriteBackUpload(t *testing.T) {
+	fed := newFakeWriteBackFederation(t)
+	q, router, _ := setupWriteBack(t, fed)
+
+	t.Run("spooled", func(t *testing.T) {
+		w := doWriteBackUpload(router, "/writable/hello.txt", "good-token", "", "Hello, World!")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, common.WriteBackAckSpooled, w.Header().Get(common.WriteBackAckHeader))
+		assert.NotEmpty(t, w.Header().Get(common.WriteBackIDHeader))
+
+		require.Eventually(t, func() bool {
+			_, ok := fed.getObject("/writable/hello.txt")
+			return ok
+		}, 5*time.Second, 10*time.Millisecond)
+		object, _ := fed.getObject("/writable/hello.txt")
+		assert.Equal(t, "Hello, World!", object)
+		// The spool is emptied once the object reached the origin
+		require.Eventually(t, func() bool { return len(q.list()) == 0 }, 5*time.Second, 10*time.Millisecond)
+		entries, err := os.ReadDir(q.dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("origin-ack", func(t *testing.T) {
+		w := doWriteBackUpload(router, "/writable/acked.txt", "good-token", common.WriteBackAckOrigin, "acked")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		object, ok := fed.getObject("/writable/acked.txt")
+		require.True(t, ok)
+		assert.Equal(t, "acked", object)
+		fed.mutex.Lock()
+		assert.Equal(t, "Bearer good-token", fed.tokens["/writable/acked.txt"])
+		fed.mutex.Unlock()
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		w := doWriteBackUpload(router, "/writable/hello.txt", "", "", "Hello")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = doWriteBackUpload(router, "/writable/hello.txt", "bad-token", "", "Hello")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, common.ErrCodeInvalidToken, test_utils.ParseProblem(t, w.Body.Bytes()).Code)
+		w = doWriteBackUpload(router, "/readonly/hello.txt", "good-token", "", "Hello")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		w = doWriteBackUpload(router, "/unknown/hello.txt", "good-token", "", "Hello")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doWriteBackUpload(router, "/writable/hello.txt", "good-token", "eventually", "Hello")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("spool-full", func(t *testing.T) {
+		w := doWriteBackUpload(router, "/writable/big.bin", "good-token", "", strings.Repeat("x", 2048))
+		assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+
+		// Chunked bodies are only found to be too large as they're spooled
+		req := httptest.NewRequest(http.MethodPut, common.WriteBackAPIPath+"/writable/big.bin", io.MultiReader(strings.NewReader(strings.Repeat("x", 2048))))
+		req.ContentLength = -1
+		req.Header.Set("Authorization", "Bearer good-token")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+		q.mutex.Lock()
+		assert.Zero(t, q.spoolBytes)
+		q.mutex.Unlock()
+		entries, err := os.ReadDir(q.dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestWriteBackFailureQueue(t *testing.T) {
+	fed := newFakeWriteBackFederation(t)
+	q, router, _ := setupWriteBack(t, fed)
+	fed.mutex.Lock()
+	fed.failStatus = http.StatusServiceUnavailable
+	fed.mutex.Unlock()
+
+	w := doWriteBackUpload(router, "/writable/retried.txt", "good-token", common.WriteBackAckOrigin, "retried")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	id := w.Header().Get(common.WriteBackIDHeader)
+	require.NotEmpty(t, id)
+
+	// Listed without the uploader's token
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1.0/cache_ui/writeback", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"failed"`)
+	assert.Contains(t, w.Body.String(), `"attempts":2`)
+	assert.NotContains(t, w.Body.String(), "good-token")
+
+	// Only failed uploads are retried
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1.0/cache_ui/writeback/unknown/retry", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	fed.mutex.Lock()
+	fed.failStatus = 0
+	fed.mutex.Unlock()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1.0/cache_ui/writeback/"+id+"/retry", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Eventually(t, func() bool {
+		_, ok := fed.getObject("/writable/retried.txt")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(q.list()) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// Uploads the origin refuses outright fail without retries and can be discarded
+	fed.mutex.Lock()
+	fed.failStatus = http.StatusForbidden
+	fed.mutex.Unlock()
+	w = doWriteBackUpload(router, "/writable/refused.txt", "good-token", common.WriteBackAckOrigin, "refused")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	records := q.list()
+	require.Len(t, records, 1)
+	assert.Equal(t, 1, records[0].Attempts)
+	assert.Equal(t, writeBackFailed, records[0].State)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1.0/cache_ui/writeback/"+records[0].ID, nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, q.list())
+	entries, err := os.ReadDir(q.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWriteBackReload(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Cache.WriteBackMaxSpoolSize", "1g")
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q, err := newWriteBackQueue(ctx, dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(q.dataFile("spooled"), []byte("Hello"), 0600))
+	_, err = q.add(&writeBackRecord{ID: "spooled", Path: "/writable/hello.txt", Token: "good-token", Size: 5, State: writeBackQueued}, false)
+	require.NoError(t, err)
+	// An upload interrupted before it was acknowledged has no record
+	require.NoError(t, os.WriteFile(q.dataFile("interrupted"), []byte("Hel"), 0600))
+
+	reloaded, err := newWriteBackQueue(ctx, dir)
+	require.NoError(t, err)
+	require.NoError(t, reloaded.load())
+	records := reloaded.list()
+	require.Len(t, records, 1)
+	assert.Equal(t, "/writable/hello.txt", records[0].Path)
+	assert.Equal(t, int64(5), reloaded.spoolBytes)
+	assert.Equal(t, "good-token", reloaded.records["spooled"].Token)
+	_, err = os.Stat(reloaded.dataFile("interrupted"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestTokenGrantsWrite(t *testing.T) {
+	newToken := func(scope string) jwt.Token {
+		tok, err := jwt.NewBuilder().Claim("scope", scope).Build()
+		require.NoError(t, err)
+		return tok
+	}
+
+	assert.True(t, tokenGrantsWrite(newToken("storage.create:/"), []string{"/foo"}, "/foo/bar.txt"))
+	assert.True(t, tokenGrantsWrite(newToken("storage.read:/ storage.modify:/bar"), []string{"/foo"}, "/foo/bar/baz.txt"))
+	assert.True(t, tokenGrantsWrite(newToken("storage.create:/bar.txt"), []string{"/other", "/foo"}, "/foo/bar.txt"))
+	assert.False(t, tokenGrantsWrite(newToken("storage.read:/"), []string{"/foo"}, "/foo/bar.txt"))
+	assert.False(t, tokenGrantsWrite(newToken("storage.create:/bar"), []string{"/foo"}, "/foo/barbaz.txt"))
+	assert.False(t, tokenGrantsWrite(newToken("storage.create:/"), []string{"/foo"}, "/foobar/baz.txt"))
+	assert.False(t, tokenGrantsWrite(newToken(""), []string{"/foo"}, "/foo/bar.txt"))
+}
@@ -0,0 +1,182 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// A namespace of the federation and the access the user has to it
+type AccessibleNamespace struct {
+	Path string `json:"path"`
+	// Whether the namespace's objects may be read by anyone, without a token
+	PublicRead bool `json:"public_read"`
+	Read       bool `json:"read"`
+	Write      bool `json:"write"`
+	// The issuer of the user's token, if the token grants access to the namespace
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// Find the token the client would use when no token is named for the transfer: the
+// one given on the command line or found through the WLCG or HTCondor token discovery.
+// An empty token is returned if there is none.
+func DiscoverToken() (string, error) {
+	tokenLocation := ObjectClientOptions.Token
+	if tokenLocation == "" {
+		var bearerToken string
+		if bearerToken, tokenLocation = discoverWLCGToken(); bearerToken != "" {
+			return bearerToken, nil
+		}
+		if tokenLocation == "" {
+			tokenLocation = discoverHTCondorToken("")
+		}
+		if tokenLocation == "" {
+			return "", nil
+		}
+	}
+	return readTokenFile(tokenLocation)
+}
+
+// Whether one of the two cleaned paths contains the other
+func pathsOverlap(path1, path2 string) bool {
+	contains := func(parent, child string) bool {
+		return parent == "/" || child == parent || strings.HasPrefix(child, parent+"/")
+	}
+	return contains(path1, path2) || contains(path2, path1)
+}
+
+// Determine the access to the namespace granted by the token's storage scopes.  Scope
+// paths are relative to the base paths of the namespace's issuer matching the token's;
+// a scope anywhere within the namespace counts as access to it.
+func namespaceAccess(ns common.NamespaceAdV2, issuer string, scopes []string) (read bool, write bool) {
+	nsPath := path.Clean("/" + ns.Path)
+	for _, nsIssuer := range ns.Issuer {
+		if strings.TrimSuffix(nsIssuer.IssuerUrl.String(), "/") != issuer {
+			continue
+		}
+		for _, scope := range scopes {
+			authz, scopePath, _ := strings.Cut(scope, ":")
+			canRead := config.TokenRead.AcceptsStorageScope(authz)
+			canWrite := config.TokenWrite.AcceptsStorageScope(authz)
+			if !canRead && !canWrite {
+				continue
+			}
+			for _, basePath := range nsIssuer.BasePaths {
+				if pathsOverlap(path.Join("/", basePath, scopePath), nsPath) {
+					read = read || canRead
+					write = write || canWrite
+				}
+			}
+		}
+	}
+	return
+}
+
+// Get the namespaces advertised to the director
+func getDirectorNamespaces(ctx context.Context) ([]common.NamespaceAdV2, error) {
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	if directorUrl == "" {
+		return nil, errors.New("the federation's director URL is not known")
+	}
+	nsUrl, err := url.JoinPath(directorUrl, "api", "v2.0", "director", "listNamespaces")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid director URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, nsUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+ObjectClientOptions.Version)
+	client := http.Client{Transport: traceTransport(config.GetTransport())}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query the director for its namespaces")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HttpErrResp{resp.StatusCode, "failed to query the director for its namespaces: " + http.StatusText(resp.StatusCode)}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []common.NamespaceAdV2{}
+	if err = json.Unmarshal(body, &namespaces); err != nil {
+		return nil, errors.Wrap(err, "invalid response from the director")
+	}
+	return namespaces, nil
+}
+
+// List the namespaces of the federation the token's holder may read or write, sorted by
+// path.  Whether the token grants access to a namespace is decided from the issuers the
+// namespace registered and the token's scopes; the token's signature isn't checked, as
+// the origins do so when it's used.  Without a token, only the public namespaces are listed.
+func ListAccessibleNamespaces(ctx context.Context, token string) ([]AccessibleNamespace, error) {
+	issuer := ""
+	scopes := []string{}
+	if token != "" {
+		parser := jwt.Parser{SkipClaimsValidation: true}
+		claims := jwt.MapClaims{}
+		if _, _, err := parser.ParseUnverified(token, &claims); err != nil {
+			return nil, errors.Wrap(err, "failed to parse the token")
+		}
+		issuer, _ = claims["iss"].(string)
+		issuer = strings.TrimSuffix(issuer, "/")
+		if scope, ok := claims["scope"].(string); ok {
+			scopes = strings.Fields(scope)
+		}
+		log.Debugf("Checking the namespaces accessible with a token from %s with scopes %v", issuer, scopes)
+	}
+
+	namespaces, err := getDirectorNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []AccessibleNamespace{}
+	for _, ns := range namespaces {
+		accessible := AccessibleNamespace{Path: ns.Path, PublicRead: ns.PublicRead || ns.Caps.PublicRead}
+		if issuer != "" {
+			accessible.Read, accessible.Write = namespaceAccess(ns, issuer, scopes)
+			if accessible.Read || accessible.Write {
+				accessible.Issuer = issuer
+			}
+		}
+		accessible.Read = accessible.Read || accessible.PublicRead
+		if accessible.Read || accessible.Write {
+			result = append(result, accessible)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
@@ -0,0 +1,146 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+)
+
+func TestListAccessibleNamespaces(t *testing.T) {
+	config.ResetConfig()
+	t.Cleanup(config.ResetConfig)
+
+	issuerUrl, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+	otherIssuerUrl, err := url.Parse("https://other.example.com")
+	require.NoError(t, err)
+	namespaces := []common.NamespaceAdV2{
+		{Path: "/public", PublicRead: true},
+		{Path: "/data/project", Issuer: []common.TokenIssuer{{IssuerUrl: *issuerUrl, BasePaths: []string{"/data"}}}},
+		{Path: "/data/other", Issuer: []common.TokenIssuer{{IssuerUrl: *issuerUrl, BasePaths: []string{"/data"}}}},
+		{Path: "/elsewhere", Issuer: []common.TokenIssuer{{IssuerUrl: *otherIssuerUrl, BasePaths: []string{"/"}}}},
+	}
+	director := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/director/listNamespaces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(namespaces))
+	}))
+	t.Cleanup(director.Close)
+	viper.Set("Federation.DirectorUrl", director.URL)
+
+	makeToken := func(issuer, scope string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iss":      issuer,
+			"wlcg.ver": "1.0",
+			"scope":    scope,
+		})
+		signed, err := tok.SignedString([]byte("not-a-real-secret"))
+		require.NoError(t, err)
+		return signed
+	}
+
+	t.Run("no-token-lists-public", func(t *testing.T) {
+		result, err := ListAccessibleNamespaces(context.Background(), "")
+		require.NoError(t, err)
+		assert.Equal(t, []AccessibleNamespace{{Path: "/public", PublicRead: true, Read: true}}, result)
+	})
+
+	t.Run("scopes-relative-to-base-path", func(t *testing.T) {
+		token := makeToken("https://issuer.example.com/", "storage.read:/project storage.create:/project/sub")
+		result, err := ListAccessibleNamespaces(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, []AccessibleNamespace{
+			{Path: "/data/project", Read: true, Write: true, Issuer: "https://issuer.example.com"},
+			{Path: "/public", PublicRead: true, Read: true},
+		}, result)
+	})
+
+	t.Run("scope-without-path-covers-issuer", func(t *testing.T) {
+		token := makeToken("https://issuer.example.com", "storage.modify")
+		result, err := ListAccessibleNamespaces(context.Background(), token)
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		assert.Equal(t, "/data/other", result[0].Path)
+		assert.False(t, result[0].Read)
+		assert.True(t, result[0].Write)
+		assert.Equal(t, "/data/project", result[1].Path)
+		assert.True(t, result[1].Write)
+	})
+
+	t.Run("unknown-issuer", func(t *testing.T) {
+		token := makeToken("https://unknown.example.com", "storage.read:/ storage.modify:/")
+		result, err := ListAccessibleNamespaces(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, []AccessibleNamespace{{Path: "/public", PublicRead: true, Read: true}}, result)
+	})
+
+	t.Run("invalid-token", func(t *testing.T) {
+		_, err := ListAccessibleNamespaces(context.Background(), "not-a-token")
+		assert.Error(t, err)
+	})
+}
+
+func TestDiscoverToken(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("_CONDOR_CREDS", t.TempDir())
+
+	t.Run("bearer-token", func(t *testing.T) {
+		t.Setenv("BEARER_TOKEN", "env-token")
+		token, err := DiscoverToken()
+		require.NoError(t, err)
+		assert.Equal(t, "env-token", token)
+	})
+
+	t.Run("bearer-token-file", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte(`{"access_token": "file-token", "expires_in": 60}`), 0600))
+		t.Setenv("BEARER_TOKEN_FILE", tokenFile)
+		token, err := DiscoverToken()
+		require.NoError(t, err)
+		assert.Equal(t, "file-token", token)
+	})
+
+	t.Run("command-line", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("cli-token\n"), 0600))
+		t.Setenv("BEARER_TOKEN", "env-token")
+		ObjectClientOptions.Token = tokenFile
+		t.Cleanup(func() { ObjectClientOptions.Token = "" })
+		token, err := DiscoverToken()
+		require.NoError(t, err)
+		assert.Equal(t, "cli-token", token)
+	})
+}
@@ -35,22 +35,30 @@ import (
 )
 
 func TokenIsAcceptable(jwtSerialized string, osdfPath string, namespace namespaces.Namespace, opts config.TokenGenerationOpts) bool {
+	_, ok := tokenScopeCoverage(jwtSerialized, osdfPath, namespace, opts)
+	return ok
+}
+
+// Check whether the token's scopes authorize the operation on osdfPath.  If they do, also
+// return the length of the narrowest scope path doing so, so the least-privileged of
+// several acceptable tokens can be preferred: the longer the path, the narrower the scope.
+func tokenScopeCoverage(jwtSerialized string, osdfPath string, namespace namespaces.Namespace, opts config.TokenGenerationOpts) (int, bool) {
 	parser := jwt.Parser{SkipClaimsValidation: true}
 	token, _, err := parser.ParseUnverified(jwtSerialized, &jwt.MapClaims{})
 	if err != nil {
 		log.Warningln("Failed to parse token:", err)
-		return false
+		return 0, false
 	}
 
 	// For now, we'll accept any WLCG token
 	wlcg_ver := (*token.Claims.(*jwt.MapClaims))["wlcg.ver"]
 	if wlcg_ver == nil {
-		return false
+		return 0, false
 	}
 
 	osdfPathCleaned := path.Clean(osdfPath)
 	if !strings.HasPrefix(osdfPathCleaned, namespace.Path) {
-		return false
+		return 0, false
 	}
 
 	// For some issuers, the token base path is distinct from the OSDF base path.
@@ -65,37 +73,38 @@ func TokenIsAcceptable(jwtSerialized string, osdfPath string, namespace namespac
 		targetResource = path.Clean("/" + osdfPathCleaned[len(*namespace.CredentialGen.BasePath):])
 	}
 
-	scopes_iface := (*token.Claims.(*jwt.MapClaims))["scope"]
-	if scopes, ok := scopes_iface.(string); ok {
-		acceptableScope := false
-		for _, scope := range strings.Split(scopes, " ") {
-			scope_info := strings.Split(scope, ":")
-			scopeOK := false
-			if (opts.Operation == config.TokenWrite || opts.Operation == config.TokenSharedWrite) && (scope_info[0] == "storage.modify" || scope_info[0] == "storage.create") {
-				scopeOK = true
-			} else if scope_info[0] == "storage.read" {
-				scopeOK = true
-			}
-			if !scopeOK {
-				continue
-			}
+	scopes, ok := (*token.Claims.(*jwt.MapClaims))["scope"].(string)
+	if !ok {
+		return 0, false
+	}
+	best := -1
+	for _, scope := range strings.Split(scopes, " ") {
+		scope_info := strings.SplitN(scope, ":", 2)
+		if !opts.Operation.AcceptsStorageScope(scope_info[0]) {
+			continue
+		}
 
-			if len(scope_info) == 1 {
-				acceptableScope = true
-				break
-			}
-			// Shared URLs must have exact matches; otherwise, prefix matching is acceptable.
-			if ((opts.Operation == config.TokenSharedWrite || opts.Operation == config.TokenSharedRead) && (targetResource == scope_info[1])) ||
-				strings.HasPrefix(targetResource, scope_info[1]) {
-				acceptableScope = true
-				break
+		// A scope without a path authorizes the whole namespace
+		scopePath := "/"
+		if len(scope_info) == 2 {
+			scopePath = path.Clean("/" + scope_info[1])
+		}
+		// Shared URLs must have exact matches; otherwise, the scope's path must contain the resource.
+		if opts.Operation.IsShared() {
+			if targetResource != scopePath {
+				continue
 			}
+		} else if scopePath != "/" && targetResource != scopePath && !strings.HasPrefix(targetResource, scopePath+"/") {
+			continue
 		}
-		if acceptableScope {
-			return true
+		if len(scopePath) > best {
+			best = len(scopePath)
 		}
 	}
-	return false
+	if best < 0 {
+		return 0, false
+	}
+	return best, true
 }
 
 func TokenIsExpired(jwtSerialized string) bool {
@@ -204,23 +213,24 @@ func AcquireToken(destination *url.URL, namespace namespaces.Namespace, opts con
 		}
 	}
 
-	// For now, a fairly useless token-selection algorithm - take the first in the list.
-	// In the future, we should:
-	// - Check scopes
+	// Among the cached tokens authorizing the operation, prefer the one with the narrowest
+	// scope; an expired one may still be refreshed
 	var acceptableToken *config.TokenEntry = nil
 	acceptableUnexpiredToken := ""
+	acceptableCoverage, unexpiredCoverage := -1, -1
 	for idx, token := range prefixEntry.Tokens {
-		if !TokenIsAcceptable(token.AccessToken, destination.Path, namespace, opts) {
+		coverage, ok := tokenScopeCoverage(token.AccessToken, destination.Path, namespace, opts)
+		if !ok {
 			continue
 		}
-		if acceptableToken == nil {
-			acceptableToken = &prefixEntry.Tokens[idx]
-		} else if acceptableUnexpiredToken != "" {
-			// Both tokens are non-empty; let's use them
-			break
-		}
 		if !TokenIsExpired(token.AccessToken) {
-			acceptableUnexpiredToken = token.AccessToken
+			if coverage > unexpiredCoverage {
+				acceptableUnexpiredToken = token.AccessToken
+				unexpiredCoverage = coverage
+			}
+		} else if len(token.RefreshToken) > 0 && coverage > acceptableCoverage {
+			acceptableToken = &prefixEntry.Tokens[idx]
+			acceptableCoverage = coverage
 		}
 	}
 	if len(acceptableUnexpiredToken) > 0 {
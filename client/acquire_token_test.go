@@ -0,0 +1,83 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+func makeScopedToken(t *testing.T, scope string) string {
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"wlcg.ver": "1.0",
+		"scope":    scope,
+	})
+	signed, err := tok.SignedString([]byte("not-a-real-secret"))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestTokenScopeCoverage(t *testing.T) {
+	namespace := namespaces.Namespace{Path: "/foo"}
+	read := config.TokenGenerationOpts{Operation: config.TokenRead}
+	write := config.TokenGenerationOpts{Operation: config.TokenWrite}
+	del := config.TokenGenerationOpts{Operation: config.TokenDelete}
+	sharedRead := config.TokenGenerationOpts{Operation: config.TokenSharedRead}
+
+	t.Run("operation-needs-matching-scope", func(t *testing.T) {
+		readToken := makeScopedToken(t, "storage.read:/bar")
+		assert.True(t, TokenIsAcceptable(readToken, "/foo/bar/obj", namespace, read))
+		assert.False(t, TokenIsAcceptable(readToken, "/foo/bar/obj", namespace, write))
+		assert.False(t, TokenIsAcceptable(readToken, "/foo/bar/obj", namespace, del))
+
+		createToken := makeScopedToken(t, "storage.create:/bar")
+		assert.True(t, TokenIsAcceptable(createToken, "/foo/bar/obj", namespace, write))
+		assert.False(t, TokenIsAcceptable(createToken, "/foo/bar/obj", namespace, del))
+
+		modifyToken := makeScopedToken(t, "storage.modify:/bar")
+		assert.True(t, TokenIsAcceptable(modifyToken, "/foo/bar/obj", namespace, write))
+		assert.True(t, TokenIsAcceptable(modifyToken, "/foo/bar/obj", namespace, del))
+	})
+
+	t.Run("scope-paths-match-whole-components", func(t *testing.T) {
+		token := makeScopedToken(t, "storage.read:/bar")
+		assert.True(t, TokenIsAcceptable(token, "/foo/bar", namespace, read))
+		assert.False(t, TokenIsAcceptable(token, "/foo/barn/obj", namespace, read))
+		assert.True(t, TokenIsAcceptable(makeScopedToken(t, "storage.read:/"), "/foo/barn/obj", namespace, read))
+	})
+
+	t.Run("shared-operations-need-exact-scope", func(t *testing.T) {
+		assert.False(t, TokenIsAcceptable(makeScopedToken(t, "storage.read:/bar"), "/foo/bar/obj", namespace, sharedRead))
+		assert.True(t, TokenIsAcceptable(makeScopedToken(t, "storage.read:/bar/obj"), "/foo/bar/obj", namespace, sharedRead))
+	})
+
+	t.Run("narrower-scope-has-greater-coverage", func(t *testing.T) {
+		broad, ok := tokenScopeCoverage(makeScopedToken(t, "storage.read:/"), "/foo/bar/obj", namespace, read)
+		require.True(t, ok)
+		narrow, ok := tokenScopeCoverage(makeScopedToken(t, "storage.read:/ storage.read:/bar"), "/foo/bar/obj", namespace, read)
+		require.True(t, ok)
+		assert.Greater(t, narrow, broad)
+	})
+}
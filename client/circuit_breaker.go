@@ -0,0 +1,174 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// The health of a single endpoint (a cache or origin, by host:port) as seen by a transfer
+	endpointHealth struct {
+		// Failures in a row since the endpoint last answered
+		failures int
+		// The endpoint isn't contacted again until this time; set when the circuit opens
+		// or when the endpoint asked to be left alone with a Retry-After
+		blockedUntil time.Time
+		// Whether a half-open probe of the endpoint is in flight
+		probing bool
+	}
+
+	// A circuit breaker shared by all the files of a transfer.  After Client.CircuitBreakerThreshold
+	// consecutive failures of an endpoint, its circuit opens and the endpoint is skipped for
+	// Client.CircuitBreakerCooldown.  Once the cooldown passes, the circuit is half-open: a single
+	// download probes the endpoint, closing the circuit if it succeeds and reopening it otherwise.
+	circuitBreaker struct {
+		threshold int
+		cooldown  time.Duration
+		endpoints map[string]*endpointHealth
+		mutex     sync.Mutex
+	}
+)
+
+// Create a circuit breaker from the client configuration; returns nil, meaning
+// every endpoint is always tried, if Client.CircuitBreakerThreshold isn't positive
+func newCircuitBreaker() *circuitBreaker {
+	threshold := param.Client_CircuitBreakerThreshold.GetInt()
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  param.Client_CircuitBreakerCooldown.GetDuration(),
+		endpoints: make(map[string]*endpointHealth),
+	}
+}
+
+// Whether a download may contact the endpoint now.  If not, also returns how long
+// until the endpoint may be contacted again, or 0 if that's unknown because a
+// half-open probe is in flight.
+func (cb *circuitBreaker) allow(endpoint string) (bool, time.Duration) {
+	if cb == nil {
+		return true, 0
+	}
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	health, ok := cb.endpoints[endpoint]
+	if !ok {
+		return true, 0
+	}
+	if wait := time.Until(health.blockedUntil); wait > 0 {
+		return false, wait
+	}
+	if health.failures < cb.threshold {
+		return true, 0
+	}
+	if health.probing {
+		return false, 0
+	}
+	log.Debugf("Circuit for %s is half-open; probing it with a single download", endpoint)
+	health.probing = true
+	return true, 0
+}
+
+// Record that the endpoint answered, closing its circuit
+func (cb *circuitBreaker) recordSuccess(endpoint string) {
+	if cb == nil {
+		return
+	}
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if health, ok := cb.endpoints[endpoint]; ok {
+		if health.failures >= cb.threshold {
+			log.Infof("%s is responding again; closing its circuit", endpoint)
+		}
+		delete(cb.endpoints, endpoint)
+	}
+}
+
+// Record a failed download from the endpoint.  A retryAfter greater than 0 is the
+// delay the endpoint asked for before it's contacted again.
+func (cb *circuitBreaker) recordFailure(endpoint string, retryAfter time.Duration) {
+	if cb == nil {
+		return
+	}
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	health, ok := cb.endpoints[endpoint]
+	if !ok {
+		health = &endpointHealth{}
+		cb.endpoints[endpoint] = health
+	}
+	now := time.Now()
+	health.failures++
+	if health.failures >= cb.threshold {
+		if health.failures == cb.threshold || health.probing {
+			log.Warningf("%s failed %d times in a row; skipping it for %s", endpoint, health.failures, cb.cooldown)
+		}
+		health.blockedUntil = now.Add(cb.cooldown)
+	}
+	health.probing = false
+	if until := now.Add(retryAfter); until.After(health.blockedUntil) {
+		health.blockedUntil = until
+	}
+}
+
+// Record the outcome of a download from the endpoint.  Only failures indicating the
+// endpoint itself is unhealthy count against it; an endpoint that answers, even with
+// an error such as 404 or a staging response, is healthy.
+func (cb *circuitBreaker) record(ctx context.Context, endpoint string, err error) {
+	if cb == nil || ctx.Err() != nil {
+		return
+	}
+	var sbe *ServerBusyError
+	if err == nil || errors.Is(err, &StagingError{}) {
+		cb.recordSuccess(endpoint)
+	} else if errors.As(err, &sbe) {
+		cb.recordFailure(endpoint, sbe.RetryAfter)
+	} else if IsRetryable(err) {
+		cb.recordFailure(endpoint, 0)
+	} else {
+		cb.recordSuccess(endpoint)
+	}
+}
+
+// Whether a download may contact the endpoint.  If the endpoint is the last source
+// left and asked to be retried within Client.MaxRetryAfter, wait for it rather than
+// failing the download.
+func waitForEndpoint(ctx context.Context, cb *circuitBreaker, endpoint string, lastSource bool) bool {
+	ok, wait := cb.allow(endpoint)
+	if ok || !lastSource || wait <= 0 || wait > param.Client_MaxRetryAfter.GetDuration() {
+		return ok
+	}
+	log.Infof("Waiting %s before contacting %s again", wait.Round(time.Second), endpoint)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+	}
+	ok, _ = cb.allow(endpoint)
+	return ok
+}
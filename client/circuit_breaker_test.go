@@ -0,0 +1,128 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Run("disabled", func(t *testing.T) {
+		viper.Set("Client.CircuitBreakerThreshold", 0)
+		breaker := newCircuitBreaker()
+		assert.Nil(t, breaker)
+		breaker.recordFailure("cache.example.com:8443", time.Minute)
+		ok, _ := breaker.allow("cache.example.com:8443")
+		assert.True(t, ok)
+	})
+
+	t.Run("opens-and-probes", func(t *testing.T) {
+		viper.Set("Client.CircuitBreakerThreshold", 2)
+		viper.Set("Client.CircuitBreakerCooldown", "100ms")
+		breaker := newCircuitBreaker()
+		require.NotNil(t, breaker)
+		endpoint := "cache.example.com:8443"
+
+		breaker.recordFailure(endpoint, 0)
+		ok, _ := breaker.allow(endpoint)
+		assert.True(t, ok, "One failure is below the threshold")
+
+		breaker.recordFailure(endpoint, 0)
+		ok, wait := breaker.allow(endpoint)
+		assert.False(t, ok, "The circuit should be open")
+		assert.True(t, wait > 0 && wait <= 100*time.Millisecond)
+		ok, _ = breaker.allow("other.example.com:8443")
+		assert.True(t, ok, "Other endpoints are unaffected")
+
+		// After the cooldown, exactly one download probes the endpoint
+		time.Sleep(150 * time.Millisecond)
+		ok, _ = breaker.allow(endpoint)
+		assert.True(t, ok)
+		ok, wait = breaker.allow(endpoint)
+		assert.False(t, ok, "Only one probe may be in flight")
+		assert.Zero(t, wait)
+
+		// A failed probe reopens the circuit
+		breaker.recordFailure(endpoint, 0)
+		ok, _ = breaker.allow(endpoint)
+		assert.False(t, ok)
+
+		// A successful probe closes it
+		time.Sleep(150 * time.Millisecond)
+		ok, _ = breaker.allow(endpoint)
+		require.True(t, ok)
+		breaker.recordSuccess(endpoint)
+		ok, _ = breaker.allow(endpoint)
+		assert.True(t, ok)
+		ok, _ = breaker.allow(endpoint)
+		assert.True(t, ok)
+	})
+
+	t.Run("retry-after", func(t *testing.T) {
+		viper.Set("Client.CircuitBreakerThreshold", 5)
+		viper.Set("Client.CircuitBreakerCooldown", "1m")
+		breaker := newCircuitBreaker()
+		endpoint := "cache.example.com:8443"
+
+		// A busy endpoint is left alone for as long as it asked, even below the threshold
+		breaker.record(context.Background(), endpoint, &ServerBusyError{URL: "https://" + endpoint, StatusCode: 429, RetryAfter: 100 * time.Millisecond})
+		ok, wait := breaker.allow(endpoint)
+		assert.False(t, ok)
+		assert.True(t, wait > 0 && wait <= 100*time.Millisecond)
+
+		// The last source is waited for if it's within Client.MaxRetryAfter
+		viper.Set("Client.MaxRetryAfter", "0s")
+		assert.False(t, waitForEndpoint(context.Background(), breaker, endpoint, true))
+		viper.Set("Client.MaxRetryAfter", "1m")
+		assert.False(t, waitForEndpoint(context.Background(), breaker, endpoint, false))
+		assert.True(t, waitForEndpoint(context.Background(), breaker, endpoint, true))
+	})
+
+	t.Run("healthy-answers", func(t *testing.T) {
+		viper.Set("Client.CircuitBreakerThreshold", 1)
+		breaker := newCircuitBreaker()
+		endpoint := "cache.example.com:8443"
+
+		// Not found and staging responses come from a healthy endpoint
+		breaker.record(context.Background(), endpoint, &HttpErrResp{Code: 404, Err: "not found"})
+		breaker.record(context.Background(), endpoint, &StagingError{URL: "https://" + endpoint, RetryAfter: time.Second})
+		ok, _ := breaker.allow(endpoint)
+		assert.True(t, ok)
+
+		// Failures after the transfer is cancelled aren't the endpoint's fault
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		breaker.record(ctx, endpoint, &HttpErrResp{Code: 503, Err: "unavailable"})
+		ok, _ = breaker.allow(endpoint)
+		assert.True(t, ok)
+
+		breaker.record(context.Background(), endpoint, &HttpErrResp{Code: 503, Err: "unavailable"})
+		ok, _ = breaker.allow(endpoint)
+		assert.False(t, ok)
+	})
+}
@@ -19,6 +19,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -27,6 +28,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pelicanplatform/pelican/apiclient"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	namespaces "github.com/pelicanplatform/pelican/namespaces"
 	"github.com/pkg/errors"
@@ -133,6 +136,11 @@ func CreateNsFromDirectorResp(dirResp *http.Response) (namespace namespaces.Name
 // Make a request to the director for a given verb/resource; return the
 // HTTP response object only if a 307 is returned.
 func queryDirector(verb, source, directorUrl string) (resp *http.Response, err error) {
+	return queryDirectorWithHeader(verb, source, directorUrl, nil)
+}
+
+// Like queryDirector, adding the headers in header to the request
+func queryDirectorWithHeader(verb, source, directorUrl string, header http.Header) (resp *http.Response, err error) {
 	resourceUrl := directorUrl + source
 	// Here we use http.Transport to prevent the client from following the director's
 	// redirect. We use the Location url elsewhere (plus we still need to do the token
@@ -140,7 +148,7 @@ func queryDirector(verb, source, directorUrl string) (resp *http.Response, err e
 	var client *http.Client
 	tr := config.GetTransport()
 	client = &http.Client{
-		Transport: tr,
+		Transport: traceTransport(tr),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -151,12 +159,22 @@ func queryDirector(verb, source, directorUrl string) (resp *http.Response, err e
 		log.Errorln("Failed to create an HTTP request:", err)
 		return nil, err
 	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
 
 	// Include the Client's version as a User-Agent header. The Director will decide
 	// if it supports the version, and provide an error message in the case that it
 	// cannot.
 	userAgent := "pelican-client/" + ObjectClientOptions.Version
 	req.Header.Set("User-Agent", userAgent)
+	// List the features of the client so the director can tailor its response, along with
+	// those the caller opted in to for this request
+	features := common.FeatureLinkFallback + ", " + common.FeatureOriginFallback
+	if extra := req.Header.Get(common.ClientFeaturesHeader); extra != "" {
+		features += ", " + extra
+	}
+	req.Header.Set(common.ClientFeaturesHeader, features)
 
 	// Perform the HTTP request
 	resp, err = client.Do(req)
@@ -186,6 +204,27 @@ func queryDirector(verb, source, directorUrl string) (resp *http.Response, err e
 	return
 }
 
+// Resolve a persistent identifier, such as doi:10.1234/abc, to the federation path of the
+// object or collection it was assigned to with the director
+func resolvePersistentIdentifier(ctx context.Context, identifier, directorUrl string) (string, error) {
+	if directorUrl == "" {
+		return "", errors.Errorf("Resolving the persistent identifier %s requires a director, but the federation has none", identifier)
+	}
+	directorClient, err := apiclient.NewDirectorClient(directorUrl, &apiclient.Options{
+		HTTPClient: &http.Client{Transport: traceTransport(config.GetTransport())},
+		UserAgent:  "pelican-client/" + ObjectClientOptions.Version,
+	})
+	if err != nil {
+		return "", err
+	}
+	resolved, err := directorClient.ResolveIdentifier(ctx, identifier)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve the persistent identifier %s", identifier)
+	}
+	log.Debugf("Resolved the persistent identifier %s to %s", identifier, resolved.Path)
+	return resolved.Path, nil
+}
+
 func GetCachesFromDirectorResponse(resp *http.Response, needsToken bool) (caches []namespaces.DirectorCache, err error) {
 	// Get the Link header
 	linkHeader := resp.Header.Values("Link")
@@ -202,11 +241,14 @@ func GetCachesFromDirectorResponse(resp *http.Response, needsToken bool) (caches
 		// we start looking at cases where we want to duplicate from caches if we're throttling
 		// connections to the origin.
 		var pri int
+		fallbackOrigin := false
 		for _, val := range links {
 			if strings.HasPrefix(val, "<") {
 				endpoint = val[1 : len(val)-1]
 			} else if strings.HasPrefix(val, "pri") {
 				pri, _ = strconv.Atoi(val[4:])
+			} else if val == `fallback="origin"` {
+				fallbackOrigin = true
 			}
 			// } else if strings.HasPrefix(val, "rel") {
 			// 	rel = val[5 : len(val)-1]
@@ -219,6 +261,7 @@ func GetCachesFromDirectorResponse(resp *http.Response, needsToken bool) (caches
 		cache.AuthedReq = needsToken
 		cache.EndpointUrl = endpoint
 		cache.Priority = pri
+		cache.FallbackOrigin = fallbackOrigin
 		caches = append(caches, cache)
 	}
 
@@ -20,14 +20,18 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/pelicanplatform/pelican/common"
 	namespaces "github.com/pelicanplatform/pelican/namespaces"
 )
 
@@ -71,6 +75,26 @@ func TestGetCachesFromDirectorResponse(t *testing.T) {
 	assert.Equal(t, true, caches[1].AuthedReq)
 }
 
+func TestGetFallbackOriginsFromDirectorResponse(t *testing.T) {
+	directorResponse := &http.Response{
+		StatusCode: 307,
+		Header: http.Header{"Link": []string{`<https://my-cache.edu:8443>; rel="duplicate"; pri=1, ` +
+			`<https://my-origin.edu:8443>; rel="duplicate"; pri=2; fallback="origin"`}},
+		Body: io.NopCloser(bytes.NewReader(nil)),
+	}
+	directorCaches, err := GetCachesFromDirectorResponse(directorResponse, false)
+	require.NoError(t, err)
+	require.Len(t, directorCaches, 2)
+	assert.False(t, directorCaches[0].FallbackOrigin)
+	assert.True(t, directorCaches[1].FallbackOrigin)
+
+	// The origins are tried after the caches, however many caches are tried
+	sources := []CacheInterface{directorCaches[1], directorCaches[0]}
+	caches, fallbackOrigins := splitFallbackOrigins(sources)
+	assert.Equal(t, []CacheInterface{directorCaches[0]}, caches)
+	assert.Equal(t, []CacheInterface{directorCaches[1]}, fallbackOrigins)
+}
+
 func TestCreateNsFromDirectorResp(t *testing.T) {
 	//Craft the Director's response
 	directorHeaders := make(map[string][]string)
@@ -195,6 +219,29 @@ func TestNewTransferDetailsUsingDirector(t *testing.T) {
 	assert.Equal(t, false, transfers[1].Proxy)
 }
 
+func TestQueryDirectorObjectSize(t *testing.T) {
+	var gotSize string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSize = r.Header.Get(common.ObjectSizeHeader)
+		w.Header().Set("Location", "http://redirect.com")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set(common.ObjectSizeHeader, "1024")
+	_, err := queryDirectorWithHeader("PUT", "/foo/bar", server.URL, header)
+	require.NoError(t, err)
+	assert.Equal(t, "1024", gotSize)
+
+	// The size of a directory isn't known up front
+	dir := t.TempDir()
+	assert.Equal(t, int64(-1), getUploadSize(dir))
+	file := filepath.Join(dir, "object")
+	require.NoError(t, os.WriteFile(file, make([]byte, 1024), 0644))
+	assert.Equal(t, int64(1024), getUploadSize(file))
+}
+
 func TestQueryDirector(t *testing.T) {
 	// Construct a local server that we can poke with QueryDirector
 	expectedLocation := "http://redirect.com"
@@ -222,3 +269,27 @@ func TestQueryDirector(t *testing.T) {
 		t.Errorf("Expected HTTP status code %d, but got %d", http.StatusFound, actualResp.StatusCode)
 	}
 }
+
+func TestResolvePersistentIdentifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1.0/director/resolve", r.URL.Path)
+		if r.URL.Query().Get("identifier") != "doi:10.1234/abc" {
+			w.Header().Set("Content-Type", common.ProblemContentType)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status": 404, "code": "not-found", "detail": "No namespace in the federation advertises the persistent identifier"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"identifier": "doi:10.1234/abc", "path": "/foo/abc"}`))
+	}))
+	defer server.Close()
+
+	objectPath, err := resolvePersistentIdentifier(context.Background(), "doi:10.1234/abc", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/foo/abc", objectPath)
+
+	_, err = resolvePersistentIdentifier(context.Background(), "doi:10.1234/missing", server.URL)
+	assert.ErrorContains(t, err, "No namespace in the federation advertises")
+
+	_, err = resolvePersistentIdentifier(context.Background(), "doi:10.1234/abc", "")
+	assert.ErrorContains(t, err, "requires a director")
+}
@@ -0,0 +1,430 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+// An object encrypted on upload is stored with a header object next to it, named by appending
+// this suffix, that holds what's needed to decrypt it other than the user's key
+const encryptionHeaderSuffix = ".pelican-enc"
+
+const (
+	encryptionKeySize   = 32
+	encryptionChunkSize = 64 * 1024
+	encryptionCipher    = "AES-256-GCM"
+)
+
+// Returned when decrypting an object with a key other than the one it was encrypted with
+var ErrWrongEncryptionKey = errors.New("the object was encrypted with a different key")
+
+// The header object of an encrypted object.  Each object is encrypted with its own random
+// data key, which is stored encrypted with the user's key.  The object is split into chunks
+// that are each sealed with AES-256-GCM, with the chunk's index and whether it's the last
+// one as the nonce, so chunks can't be reordered or the object truncated unnoticed.
+type encryptionHeader struct {
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"`
+	ChunkSize  int    `json:"chunkSize"`
+	KeyNonce   []byte `json:"keyNonce"`
+	WrappedKey []byte `json:"wrappedKey"`
+}
+
+// Parse a base64-encoded 256-bit encryption key, such as one made by `openssl rand -base64 32`
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.Wrap(err, "the encryption key isn't base64-encoded")
+	}
+	if len(key) != encryptionKeySize {
+		return nil, errors.Errorf("the encryption key is %d bytes long rather than %d", len(key), encryptionKeySize)
+	}
+	return key, nil
+}
+
+// Read a base64-encoded 256-bit encryption key from a file
+func ReadEncryptionKeyFile(keyFile string) ([]byte, error) {
+	contents, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the encryption key file")
+	}
+	key, err := ParseEncryptionKey(string(contents))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid encryption key file %s", keyFile)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Create the header of a new encrypted object, returning the object's data key
+func newEncryptionHeader(key []byte) (*encryptionHeader, []byte, error) {
+	keyCipher, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKey := make([]byte, encryptionKeySize)
+	header := &encryptionHeader{
+		Version:   1,
+		Cipher:    encryptionCipher,
+		ChunkSize: encryptionChunkSize,
+		KeyNonce:  make([]byte, keyCipher.NonceSize()),
+	}
+	if _, err = rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	if _, err = rand.Read(header.KeyNonce); err != nil {
+		return nil, nil, err
+	}
+	header.WrappedKey = keyCipher.Seal(nil, header.KeyNonce, dataKey, nil)
+	return header, dataKey, nil
+}
+
+// Get the data key of an encrypted object with the user's key
+func (header *encryptionHeader) dataKey(key []byte) ([]byte, error) {
+	if header.Version != 1 || header.Cipher != encryptionCipher {
+		return nil, errors.Errorf("unsupported encryption (version %d, cipher %s); a newer client may be needed", header.Version, header.Cipher)
+	}
+	if header.ChunkSize <= 0 || header.ChunkSize > 16*1024*1024 {
+		return nil, errors.Errorf("invalid encryption chunk size %d", header.ChunkSize)
+	}
+	keyCipher, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.KeyNonce) != keyCipher.NonceSize() {
+		return nil, errors.New("invalid encryption header")
+	}
+	dataKey, err := keyCipher.Open(nil, header.KeyNonce, header.WrappedKey, nil)
+	if err != nil {
+		return nil, ErrWrongEncryptionKey
+	}
+	return dataKey, nil
+}
+
+// The nonce of a chunk: its index, followed by a byte that is 1 for the last chunk
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Read the next chunk of up to len(buf) bytes, reporting whether it's the last one
+func readChunk(in *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(in, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	if _, err = in.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+// Encrypt src to dst in chunks of chunkSize bytes
+func encryptStream(dst io.Writer, src io.Reader, dataKey []byte, chunkSize int) error {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	in := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize, chunkSize+aead.Overhead())
+	for index := uint64(0); ; index++ {
+		n, last, err := readChunk(in, buf)
+		if err != nil {
+			return err
+		}
+		if _, err = dst.Write(aead.Seal(buf[:0], chunkNonce(index, last), buf[:n], nil)); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt src, which was encrypted in chunks of chunkSize bytes, to dst
+func decryptStream(dst io.Writer, src io.Reader, dataKey []byte, chunkSize int) error {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	sealedSize := chunkSize + aead.Overhead()
+	in := bufio.NewReaderSize(src, sealedSize)
+	buf := make([]byte, sealedSize)
+	for index := uint64(0); ; index++ {
+		n, last, err := readChunk(in, buf)
+		if err != nil {
+			return err
+		}
+		chunk, err := aead.Open(buf[:0], chunkNonce(index, last), buf[:n], nil)
+		if err != nil {
+			return errors.New("the encrypted object is corrupted or truncated")
+		}
+		if _, err = dst.Write(chunk); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Encrypt the file src to dest, writing its header to dest with encryptionHeaderSuffix appended
+func encryptFile(src, dest string, key []byte) error {
+	header, dataKey, err := newEncryptionHeader(key)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err = os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err = encryptStream(out, in, dataKey, header.ChunkSize); err != nil {
+		out.Close()
+		return errors.Wrapf(err, "failed to encrypt %s", src)
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest+encryptionHeaderSuffix, headerBytes, 0600)
+}
+
+// Decrypt the downloaded file at localPath in place
+func decryptFile(localPath string, header *encryptionHeader, key []byte) error {
+	dataKey, err := header.dataKey(key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt %s", localPath)
+	}
+	in, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".decrypt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if err = decryptStream(out, in, dataKey, header.ChunkSize); err != nil {
+		out.Close()
+		return errors.Wrapf(err, "failed to decrypt %s", localPath)
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if info, err := in.Stat(); err == nil {
+		if err = os.Chmod(out.Name(), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(out.Name(), localPath)
+}
+
+// Encrypt the file or directory src into a temporary directory for uploading.  Returns the
+// path of the encrypted copy, which has the same name as src and holds the header of each
+// file next to it, and a function removing the temporary directory.
+func encryptUpload(src string, recursive bool, key []byte) (encrypted string, cleanup func(), err error) {
+	tmpDir, err := os.MkdirTemp("", "pelican-encrypt-")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create a directory for the encrypted files")
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warningln("Failed to remove the encrypted files:", err)
+		}
+	}
+	encrypted = filepath.Join(tmpDir, filepath.Base(src))
+	if !recursive {
+		err = encryptFile(src, encrypted, key)
+	} else {
+		err = filepath.WalkDir(src, func(walkPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(src, walkPath)
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return os.MkdirAll(filepath.Join(encrypted, relPath), 0700)
+			} else if !entry.Type().IsRegular() {
+				log.Warningln("Skipping", walkPath, "as it isn't a regular file")
+				return nil
+			}
+			return encryptFile(walkPath, filepath.Join(encrypted, relPath), key)
+		})
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return encrypted, cleanup, nil
+}
+
+// Fetch the header object of objectPath from the caches of its namespace.  Returns nil
+// if the object has no header, meaning it isn't encrypted.
+func fetchEncryptionHeader(ctx context.Context, objectPath string, namespace namespaces.Namespace, tokenName string) (*encryptionHeader, error) {
+	var token string
+	if namespace.UseTokenOnRead {
+		if ObjectClientOptions.NoTokens {
+			return nil, ErrTokenRequired
+		}
+		var err error
+		if token, err = getToken(&url.URL{Path: objectPath}, namespace, false, tokenName); err != nil {
+			return nil, err
+		}
+	}
+	caches, err := GetCachesFromNamespace(namespace, hasSortedCaches())
+	if err != nil {
+		return nil, err
+	}
+	caches, fallbackOrigins := splitFallbackOrigins(caches)
+	if len(caches) > CachesToTry {
+		caches = caches[:CachesToTry]
+	}
+	caches = append(caches, fallbackOrigins...)
+	lastErr := errors.New("no caches found")
+	for _, cache := range caches {
+		for _, transfer := range GenerateTransferDetailsUsingCache(cache, TransferDetailsOptions{NeedsToken: namespace.ReadHTTPS || namespace.UseTokenOnRead}) {
+			headerUrl := transfer.Url
+			headerUrl.Path = objectPath + encryptionHeaderSuffix
+			header, err := getEncryptionHeader(ctx, &headerUrl, token)
+			if err == nil {
+				return header, nil
+			}
+			log.Debugf("Failed to fetch the encryption header of %s from %s: %v", objectPath, headerUrl.Host, err)
+			lastErr = err
+		}
+	}
+	return nil, errors.Wrapf(lastErr, "failed to check whether %s is encrypted", objectPath)
+}
+
+func getEncryptionHeader(ctx context.Context, headerUrl *url.URL, token string) (*encryptionHeader, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, headerUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+ObjectClientOptions.Version)
+	client := http.Client{Transport: traceTransport(config.GetTransport())}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, &HttpErrResp{resp.StatusCode, http.StatusText(resp.StatusCode)}
+	}
+	header := &encryptionHeader{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(header); err != nil {
+		return nil, errors.Wrap(err, "invalid encryption header")
+	}
+	return header, nil
+}
+
+// Decrypt the encrypted objects among those downloaded from remoteObject to localDestination.
+// A single object is decrypted if it has a header object; for a directory, the header
+// objects downloaded with it are used and then removed.
+func decryptDownload(ctx context.Context, remoteObject string, localDestination string, namespace namespaces.Namespace, recursive bool, tokenName string) error {
+	key := ObjectClientOptions.EncryptionKey
+	if !recursive {
+		header, err := fetchEncryptionHeader(ctx, path.Clean("/"+remoteObject), namespace, tokenName)
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			log.Debugln(remoteObject, "isn't encrypted")
+			return nil
+		}
+		return decryptFile(localDestination, header, key)
+	}
+	return filepath.WalkDir(localDestination, func(walkPath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(walkPath, encryptionHeaderSuffix) {
+			return err
+		}
+		headerBytes, err := os.ReadFile(walkPath)
+		if err != nil {
+			return err
+		}
+		header := &encryptionHeader{}
+		if err = json.Unmarshal(headerBytes, header); err != nil {
+			return errors.Wrapf(err, "invalid encryption header %s", walkPath)
+		}
+		objectPath := strings.TrimSuffix(walkPath, encryptionHeaderSuffix)
+		if _, err = os.Stat(objectPath); err != nil {
+			return errors.Wrapf(err, "the object of encryption header %s wasn't downloaded", walkPath)
+		}
+		if err = decryptFile(objectPath, header, key); err != nil {
+			return err
+		}
+		return os.Remove(walkPath)
+	})
+}
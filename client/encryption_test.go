@@ -0,0 +1,231 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+func newTestEncryptionKey(t *testing.T) []byte {
+	key := make([]byte, encryptionKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+type createdAsOKWriter struct {
+	http.ResponseWriter
+}
+
+func (w *createdAsOKWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusCreated {
+		statusCode = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := newTestEncryptionKey(t)
+	parsed, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseEncryptionKey("not base64!")
+	assert.Error(t, err)
+	_, err = ParseEncryptionKey(base64.StdEncoding.EncodeToString(key[:16]))
+	assert.Error(t, err)
+}
+
+func TestEncryptStream(t *testing.T) {
+	dataKey := newTestEncryptionKey(t)
+	chunkSize := 1024
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, 3*chunkSize + 7} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		encrypted := &bytes.Buffer{}
+		require.NoError(t, encryptStream(encrypted, bytes.NewReader(plaintext), dataKey, chunkSize))
+		chunks := size/chunkSize + 1
+		if size > 0 && size%chunkSize == 0 {
+			chunks--
+		}
+		assert.Equal(t, size+16*chunks, encrypted.Len(), "size %d", size)
+
+		decrypted := &bytes.Buffer{}
+		require.NoError(t, decryptStream(decrypted, bytes.NewReader(encrypted.Bytes()), dataKey, chunkSize))
+		assert.True(t, bytes.Equal(plaintext, decrypted.Bytes()), "size %d", size)
+
+		// Dropping the last chunk or tampering with the object is noticed
+		if chunks > 1 {
+			truncated := encrypted.Bytes()[:(chunks-1)*(chunkSize+16)]
+			assert.Error(t, decryptStream(io.Discard, bytes.NewReader(truncated), dataKey, chunkSize))
+		}
+		tampered := bytes.Clone(encrypted.Bytes())
+		tampered[len(tampered)-1] ^= 1
+		assert.Error(t, decryptStream(io.Discard, bytes.NewReader(tampered), dataKey, chunkSize))
+	}
+}
+
+func TestEncryptionHeader(t *testing.T) {
+	key := newTestEncryptionKey(t)
+	header, dataKey, err := newEncryptionHeader(key)
+	require.NoError(t, err)
+	unwrapped, err := header.dataKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	_, err = header.dataKey(newTestEncryptionKey(t))
+	assert.ErrorIs(t, err, ErrWrongEncryptionKey)
+
+	header.Version = 2
+	_, err = header.dataKey(key)
+	assert.Error(t, err)
+}
+
+func TestEncryptUpload(t *testing.T) {
+	key := newTestEncryptionKey(t)
+	src := filepath.Join(t.TempDir(), "dataset")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("first"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("second"), 0644))
+
+	encrypted, cleanup, err := encryptUpload(src, true, key)
+	require.NoError(t, err)
+	assert.Equal(t, "dataset", filepath.Base(encrypted))
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		assert.FileExists(t, filepath.Join(encrypted, name))
+		assert.FileExists(t, filepath.Join(encrypted, name+encryptionHeaderSuffix))
+	}
+
+	// Decrypting the directory in place, as if it were downloaded, restores the files
+	ObjectClientOptions.EncryptionKey = key
+	t.Cleanup(func() { ObjectClientOptions.EncryptionKey = nil })
+	require.NoError(t, decryptDownload(context.Background(), "/test/dataset", encrypted, namespaces.Namespace{}, true, ""))
+	for name, contents := range map[string]string{"a.txt": "first", "sub/b.txt": "second"} {
+		data, err := os.ReadFile(filepath.Join(encrypted, name))
+		require.NoError(t, err)
+		assert.Equal(t, contents, string(data))
+		assert.NoFileExists(t, filepath.Join(encrypted, name+encryptionHeaderSuffix))
+	}
+	cleanup()
+	assert.NoDirExists(t, filepath.Dir(encrypted))
+}
+
+func TestEncryptedTransfers(t *testing.T) {
+	config.ResetConfig()
+	t.Cleanup(config.ResetConfig)
+	ctx := context.Background()
+
+	fs := webdav.NewMemFS()
+	davHandler := &webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()}
+	// Like XRootD, answer uploads with 200 OK rather than 201 Created
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		davHandler.ServeHTTP(&createdAsOKWriter{w}, r)
+	}))
+	t.Cleanup(origin.Close)
+	require.NoError(t, fs.Mkdir(ctx, "/test", 0755))
+	readObject := func(name string) []byte {
+		f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+		require.NoError(t, err)
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return data
+	}
+
+	fedFile := filepath.Join(t.TempDir(), "federation.yaml")
+	require.NoError(t, os.WriteFile(fedFile, []byte(`
+namespaces:
+  - path: /test
+    origins: ["`+origin.URL+`"]
+`), 0644))
+	viper.Set("Client.StaticFederationFile", fedFile)
+	viper.Set("TLSSkipVerify", true)
+	t.Setenv("BEARER_TOKEN", "test-token")
+
+	key := newTestEncryptionKey(t)
+	ObjectClientOptions.EncryptionKey = key
+	t.Cleanup(func() {
+		ObjectClientOptions.EncryptionKey = nil
+		ObjectClientOptions.Encrypt = false
+	})
+
+	plaintext := []byte("restricted data")
+	localDir := t.TempDir()
+	localFile := filepath.Join(localDir, "secret.txt")
+	require.NoError(t, os.WriteFile(localFile, plaintext, 0644))
+
+	t.Run("put-encrypted", func(t *testing.T) {
+		ObjectClientOptions.Encrypt = true
+		_, err := DoPut(ctx, localFile, "/test/secret.txt", false)
+		ObjectClientOptions.Encrypt = false
+		require.NoError(t, err)
+
+		assert.NotContains(t, string(readObject("/test/secret.txt")), string(plaintext))
+		header := &encryptionHeader{}
+		require.NoError(t, json.Unmarshal(readObject("/test/secret.txt"+encryptionHeaderSuffix), header))
+		assert.Equal(t, encryptionCipher, header.Cipher)
+	})
+
+	t.Run("get-decrypts", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "secret.txt")
+		_, err := DoGet(ctx, "/test/secret.txt", dest, false)
+		require.NoError(t, err)
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, data)
+	})
+
+	t.Run("get-wrong-key", func(t *testing.T) {
+		ObjectClientOptions.EncryptionKey = newTestEncryptionKey(t)
+		defer func() { ObjectClientOptions.EncryptionKey = key }()
+		_, err := DoGet(ctx, "/test/secret.txt", filepath.Join(t.TempDir(), "secret.txt"), false)
+		assert.ErrorIs(t, err, ErrWrongEncryptionKey)
+	})
+
+	t.Run("get-unencrypted", func(t *testing.T) {
+		_, err := DoPut(ctx, localFile, "/test/plain.txt", false)
+		require.NoError(t, err)
+		dest := filepath.Join(t.TempDir(), "plain.txt")
+		_, err = DoGet(ctx, "/test/plain.txt", dest, false)
+		require.NoError(t, err)
+		data, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, data)
+	})
+}
@@ -94,6 +94,14 @@ func IsRetryable(err error) bool {
 	if errors.Is(err, &SlowTransferError{}) {
 		return true
 	}
+	// The server is bringing the object online, so it's likely available later
+	if errors.Is(err, &StagingError{}) {
+		return true
+	}
+	// The server asked to be retried later
+	if errors.Is(err, &ServerBusyError{}) {
+		return true
+	}
 	if errors.Is(err, grab.ErrBadLength) {
 		return false
 	}
@@ -101,10 +109,7 @@ func IsRetryable(err error) bool {
 	if errors.As(err, &cse) {
 		if sce, ok := cse.Unwrap().(grab.StatusCodeError); ok {
 			switch int(sce) {
-			case http.StatusInternalServerError:
-			case http.StatusBadGateway:
-			case http.StatusServiceUnavailable:
-			case http.StatusGatewayTimeout:
+			case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
 				return true
 			default:
 				return false
@@ -115,10 +120,7 @@ func IsRetryable(err error) bool {
 	var hep *HttpErrResp
 	if errors.As(err, &hep) {
 		switch int(hep.Code) {
-		case http.StatusInternalServerError:
-		case http.StatusBadGateway:
-		case http.StatusServiceUnavailable:
-		case http.StatusGatewayTimeout:
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
 			return true
 		default:
 			return false
@@ -127,6 +129,22 @@ func IsRetryable(err error) bool {
 	return false
 }
 
+// Whether the server responded that the requested object doesn't exist
+func isNotFound(err error) bool {
+	var cse *ConnectionSetupError
+	if errors.As(err, &cse) {
+		if sce, ok := cse.Unwrap().(grab.StatusCodeError); ok {
+			return int(sce) == http.StatusNotFound
+		}
+		return false
+	}
+	var hep *HttpErrResp
+	if errors.As(err, &hep) {
+		return hep.Code == http.StatusNotFound
+	}
+	return false
+}
+
 // ErrorsRetryable returns if the errors in the stack are retryable later
 func ErrorsRetryable() bool {
 	mu.Lock()
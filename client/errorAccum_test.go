@@ -20,9 +20,12 @@ package client
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
 	"testing"
 
+	grab "github.com/opensaucerer/grab/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,3 +80,12 @@ func TestErrorsRetryableTrue(t *testing.T) {
 	assert.True(t, ErrorsRetryable(), "ErrorsRetryable should be true")
 
 }
+
+// TestIsNotFound tests that only a 404 response counts as a missing object
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(&ConnectionSetupError{Err: grab.StatusCodeError(http.StatusNotFound)}))
+	assert.True(t, isNotFound(fmt.Errorf("download failed: %w", &HttpErrResp{Code: http.StatusNotFound})))
+	assert.False(t, isNotFound(&ConnectionSetupError{Err: grab.StatusCodeError(http.StatusForbidden)}))
+	assert.False(t, isNotFound(&ConnectionSetupError{Err: errors.New("connection refused")}))
+	assert.False(t, isNotFound(errors.New("not found")))
+}
@@ -120,7 +120,7 @@ func GetBestCache(cacheListName string) ([]string, error) {
 				} else {
 					log.Debugln("Querying", GeoIpUrl.String())
 				}
-				client := &http.Client{Transport: defaultTransport}
+				client := &http.Client{Transport: traceTransport(defaultTransport)}
 				req, err := http.NewRequest("GET", GeoIpUrl.String(), nil)
 				if err != nil {
 					log.Errorln("Failed to create HTTP request:", err)
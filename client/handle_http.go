@@ -43,6 +43,7 @@ import (
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/namespaces"
 	"github.com/pelicanplatform/pelican/param"
@@ -53,6 +54,9 @@ var (
 	progressCtr     *mpb.Progress
 )
 
+// How long to wait before checking on a staging object again when the server doesn't say
+const defaultStageRetryAfter = 30 * time.Second
+
 type StoppedTransferError struct {
 	Err string
 }
@@ -103,6 +107,82 @@ func (e *SlowTransferError) Is(target error) bool {
 	return ok
 }
 
+// StagingError is returned when the object is offline (e.g., on tape) and the server
+// is bringing it online; the download may be retried after RetryAfter
+type StagingError struct {
+	URL        string
+	RetryAfter time.Duration
+}
+
+func (e *StagingError) Error() string {
+	return "the object at " + e.URL + " is offline and is being staged by the server"
+}
+
+func (e *StagingError) Is(target error) bool {
+	_, ok := target.(*StagingError)
+	return ok
+}
+
+// ServerBusyError is returned when a cache or origin answers 429 Too Many Requests or
+// 503 Service Unavailable with a Retry-After header; the server shouldn't be contacted
+// again before RetryAfter passes
+type ServerBusyError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *ServerBusyError) Error() string {
+	return "the server at " + e.URL + " is busy (" + strconv.Itoa(e.StatusCode) + " " +
+		http.StatusText(e.StatusCode) + ") and asked to retry after " + e.RetryAfter.String()
+}
+
+func (e *ServerBusyError) Is(target error) bool {
+	_, ok := target.(*ServerBusyError)
+	return ok
+}
+
+// Parse the value of a Retry-After header, given either as a number of seconds or as an HTTP date
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	} else if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
+
+// Create the error for a response telling the client the object is being staged
+func newStagingError(resp *http.Response) *StagingError {
+	retryAfter := defaultStageRetryAfter
+	if parsed, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		retryAfter = parsed
+	}
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &StagingError{URL: resp.Request.URL.String(), RetryAfter: retryAfter}
+}
+
+// Create the error for a 429 or 503 response; returns nil if the server didn't say
+// when to retry, leaving the response to be handled like any other failed status
+func newServerBusyError(resp *http.Response) *ServerBusyError {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return nil
+	}
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		return nil
+	}
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &ServerBusyError{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+}
+
 type FileDownloadError struct {
 	Text string
 	Err  error
@@ -118,7 +198,7 @@ func (e *FileDownloadError) Unwrap() error {
 
 // Determines whether or not we can interact with the site HTTP proxy
 func IsProxyEnabled() bool {
-	if _, isSet := os.LookupEnv("http_proxy"); !isSet {
+	if !config.IsProxyConfigured() {
 		return false
 	}
 	if param.Client_DisableHttpProxy.GetBool() {
@@ -175,6 +255,10 @@ type TransferDetails struct {
 
 	// Specifies the pack option in the transfer URL
 	PackOption string
+
+	// Whether the transfer reads from the namespace's origin, which is only tried once
+	// the caches failed
+	FallbackOrigin bool
 }
 
 // NewTransferDetails creates the TransferDetails struct with the given cache
@@ -275,7 +359,19 @@ func GenerateTransferDetailsUsingCache(cache CacheInterface, opts TransferDetail
 	return nil
 }
 
-func download_http(sourceUrl *url.URL, destination string, payload *payloadStruct, namespace namespaces.Namespace, recursive bool, tokenName string) (transferResults []TransferResults, err error) {
+// Separate the namespace's origins that permit direct reads from the caches
+func splitFallbackOrigins(sources []CacheInterface) (caches []CacheInterface, fallbackOrigins []CacheInterface) {
+	for _, source := range sources {
+		if directorCache, ok := source.(namespaces.DirectorCache); ok && directorCache.FallbackOrigin {
+			fallbackOrigins = append(fallbackOrigins, source)
+		} else {
+			caches = append(caches, source)
+		}
+	}
+	return
+}
+
+func download_http(ctx context.Context, sourceUrl *url.URL, destination string, payload *payloadStruct, namespace namespaces.Namespace, recursive bool, tokenName string) (transferResults []TransferResults, err error) {
 	// First, create a handler for any panics that occur
 	defer func() {
 		if r := recover(); r != nil {
@@ -288,13 +384,27 @@ func download_http(sourceUrl *url.URL, destination string, payload *payloadStruc
 		}
 	}()
 
+	initThroughputBaselines()
+	defer persistThroughputBaselines()
+
 	packOption := sourceUrl.Query().Get("pack")
 	if packOption != "" {
 		log.Debugln("Will use unpack option value", packOption)
 	}
 	sourceUrl = &url.URL{Path: sourceUrl.Path}
 
+	// Objects that need a token to read aren't cached locally, since the local cache
+	// can't check the reader is authorized
+	var lc *localCache
+	if packOption == "" && !namespace.UseTokenOnRead {
+		lc = getLocalCache()
+	}
+
 	var token string
+	var tokens *tokenManager
+	if namespace.UseTokenOnRead && ObjectClientOptions.NoTokens {
+		return nil, ErrTokenRequired
+	}
 	if namespace.UseTokenOnRead {
 		var err error
 		token, err = getToken(sourceUrl, namespace, false, tokenName)
@@ -302,19 +412,22 @@ func download_http(sourceUrl *url.URL, destination string, payload *payloadStruc
 			log.Errorln("Failed to get token though required to read from this namespace:", err)
 			return nil, err
 		}
+		tokens = newTokenManager(token, sourceUrl, namespace, false, tokenName)
 	}
 
 	// Check the env var "USE_OSDF_DIRECTOR" and decide if ordered caches should come from director
 	var transfers []TransferDetails
 	var files []string
-	directorUrl := param.Federation_DirectorUrl.GetString()
-	closestNamespaceCaches, err := GetCachesFromNamespace(namespace, directorUrl != "")
+	closestNamespaceCaches, err := GetCachesFromNamespace(namespace, hasSortedCaches())
 	if err != nil {
 		log.Errorln("Failed to get namespaced caches (treated as non-fatal):", err)
 	}
 
 	log.Debugln("Matched caches:", closestNamespaceCaches)
 
+	// The origins permitting direct reads are tried after, not instead of, the caches
+	closestNamespaceCaches, fallbackOrigins := splitFallbackOrigins(closestNamespaceCaches)
+
 	// Make sure we only try as many caches as we have
 	cachesToTry := CachesToTry
 	if cachesToTry > len(closestNamespaceCaches) {
@@ -342,6 +455,16 @@ func download_http(sourceUrl *url.URL, destination string, payload *payloadStruc
 		}
 		transfers = append(transfers, GenerateTransferDetailsUsingCache(cache, td)...)
 	}
+	for _, origin := range fallbackOrigins {
+		td := TransferDetailsOptions{
+			NeedsToken: namespace.ReadHTTPS || namespace.UseTokenOnRead,
+			PackOption: packOption,
+		}
+		for _, transfer := range GenerateTransferDetailsUsingCache(origin, td) {
+			transfer.FallbackOrigin = true
+			transfers = append(transfers, transfer)
+		}
+	}
 
 	if len(transfers) > 0 {
 		log.Debugln("Transfers:", transfers[0].Url.Opaque)
@@ -359,15 +482,30 @@ func download_http(sourceUrl *url.URL, destination string, payload *payloadStruc
 	if ObjectClientOptions.Recursive && ObjectClientOptions.ProgressBars {
 		log.SetOutput(getProgressContainer())
 	}
+	if ObjectClientOptions.TUI {
+		monitor := startTransferMonitor(files)
+		defer monitor.stop()
+	} else if ObjectClientOptions.ProgressJSON {
+		monitor := startProgressJSON(files)
+		defer monitor.stop()
+	}
+	// The workers share the health of the sources so a dead cache is only tried a few times
+	breaker := newCircuitBreaker()
 	// Start the workers
 	for i := 1; i <= 5; i++ {
 		wg.Add(1)
-		go startDownloadWorker(sourceUrl.Path, destination, token, transfers, payload, &wg, workChan, results)
+		go startDownloadWorker(ctx, sourceUrl.Path, destination, tokens, transfers, payload, lc, breaker, &wg, workChan, results)
 	}
 
-	// For each file, send it to the worker
+	// For each file, send it to the worker; once the deadline passes, the remaining files are abandoned
+	// as the workers may have already given up
+SendLoop:
 	for _, file := range files {
-		workChan <- file
+		select {
+		case workChan <- file:
+		case <-ctx.Done():
+			break SendLoop
+		}
 	}
 	close(workChan)
 
@@ -397,7 +535,7 @@ func download_http(sourceUrl *url.URL, destination string, payload *payloadStruc
 
 }
 
-func startDownloadWorker(source string, destination string, token string, transfers []TransferDetails, payload *payloadStruct, wg *sync.WaitGroup, workChan <-chan string, results chan<- TransferResults) {
+func startDownloadWorker(ctx context.Context, source string, destination string, tokens *tokenManager, transfers []TransferDetails, payload *payloadStruct, lc *localCache, breaker *circuitBreaker, wg *sync.WaitGroup, workChan <-chan string, results chan<- TransferResults) {
 
 	defer wg.Done()
 	var success bool
@@ -413,16 +551,59 @@ func startDownloadWorker(source string, destination string, token string, transf
 			results <- TransferResults{Error: errors.New("Failed to make directory:" + directory)}
 			continue
 		}
+		// A cached copy is only used while the federation serves the same version of the object
+		var version objectVersion
+		cacheable := false
+		getTransferMonitor().started(file)
+		if lc != nil {
+			if version, err = getObjectVersion(ctx, transfers, file, tokens.get()); err != nil {
+				log.Debugf("Not using the local cache for %s as its version couldn't be checked: %v", file, err)
+			} else if result, ok := lc.get(file, finalDest, version); ok {
+				getTransferMonitor().finished(file, result.TransferedBytes, result.Error)
+				results <- result
+				continue
+			} else {
+				cacheable = true
+			}
+		}
+		// Whether every source responded that the object doesn't exist
+		notFound := len(transfers) > 0
 		for idx, transfer := range transfers { // For each transfer (usually 3), populate each attempt given
+			// Don't fail over to another source once the transfer is past its deadline
+			if ctx.Err() != nil {
+				break
+			}
+			if transfer.FallbackOrigin && (idx == 0 || !transfers[idx-1].FallbackOrigin) {
+				log.Warningf("No cache could serve %s; falling back to reading it from the origin %s directly, as its namespace permits",
+					file, transfer.Url.Hostname())
+			}
 			var attempt Attempt
 			var timeToFirstByte int64
 			var serverVersion string
 			attempt.Number = idx // Start with 0
 			attempt.Endpoint = transfer.Url.Host
+			if !waitForEndpoint(ctx, breaker, transfer.Url.Host, idx == len(transfers)-1) {
+				log.Debugf("Skipping %s for %s as its circuit is open", transfer.Url.Host, file)
+				notFound = false
+				attempt.Error = errors.Errorf("Skipped %s as it failed repeatedly or asked to be retried later", transfer.Url.Host)
+				attempt.TransferEndTime = time.Now().Unix()
+				attempts = append(attempts, attempt)
+				continue
+			}
 			transfer.Url.Path = file
 			log.Debugln("Constructed URL:", transfer.Url.String())
-			if downloaded, timeToFirstByte, serverVersion, err = DownloadHTTP(transfer, finalDest, token, payload); err != nil {
+			token := tokens.get()
+			downloaded, timeToFirstByte, serverVersion, err = downloadHTTPWaitForStage(ctx, transfer, transfers[idx+1:], finalDest, token, payload)
+			// The token may have expired partway through a long transfer; retry the same source
+			// once with a fresh one; the download resumes from the partial file where possible
+			if err != nil && tokens.refreshIfRejected(err, token) {
+				getTransferMonitor().attemptFailed(file, err.Error())
+				downloaded, timeToFirstByte, serverVersion, err = downloadHTTPWaitForStage(ctx, transfer, transfers[idx+1:], finalDest, tokens.get(), payload)
+			}
+			breaker.record(ctx, transfer.Url.Host, err)
+			if err != nil {
 				log.Debugln("Failed to download:", err)
+				notFound = notFound && isNotFound(err)
 				transferEndTime := time.Now().Unix()
 				var ope *net.OpError
 				var cse *ConnectionSetupError
@@ -447,6 +628,7 @@ func startDownloadWorker(source string, destination string, token string, transf
 						": " + err.Error()
 				}
 				AddError(&FileDownloadError{errorString, err})
+				getTransferMonitor().attemptFailed(file, errorString)
 				attempt.TransferFileBytes = downloaded
 				attempt.TimeToFirstByte = timeToFirstByte
 				attempt.Error = errors.New(errorString)
@@ -469,13 +651,24 @@ func startDownloadWorker(source string, destination string, token string, transf
 		}
 		if !success {
 			log.Debugln("Failed to download with HTTP")
+			downloadErr := errors.New("failed to download with HTTP")
+			if notFound && ctx.Err() == nil {
+				downloadErr = errors.Wrap(ErrObjectNotFound, "failed to download with HTTP")
+			}
+			getTransferMonitor().finished(file, downloaded, downloadErr)
 			results <- TransferResults{
 				TransferedBytes: downloaded,
-				Error:           errors.New("failed to download with HTTP"),
+				Error:           downloadErr,
 				Attempts:        attempts,
 			}
 			return
 		} else {
+			getTransferMonitor().finished(file, downloaded, nil)
+			if cacheable {
+				if err := lc.put(file, finalDest, version); err != nil {
+					log.Warningf("Failed to add %s to the local cache: %v", file, err)
+				}
+			}
 			results <- TransferResults{
 				TransferedBytes: downloaded,
 				Error:           nil,
@@ -499,9 +692,67 @@ func parseTransferStatus(status string) (int, string) {
 	return statusCode, strings.TrimSpace(parts[1])
 }
 
+// Download the object, waiting for up to Client.StageTimeout while the server reports
+// it's staging the object from offline storage.  When the server is busy and there's no
+// other source to fail over to, wait as long as its Retry-After asks, up to Client.MaxRetryAfter
+// in total.
+func downloadHTTPWaitForStage(ctx context.Context, transfer TransferDetails, alternatives []TransferDetails, dest string, token string, payload *payloadStruct) (int64, int64, string, error) {
+	stageTimeout := param.Client_StageTimeout.GetDuration()
+	stageDeadline := time.Now().Add(stageTimeout)
+	busyDeadline := time.Now().Add(param.Client_MaxRetryAfter.GetDuration())
+	for {
+		downloaded, timeToFirstByte, serverVersion, err := downloadHTTP(ctx, transfer, alternatives, dest, token, payload)
+		var sbe *ServerBusyError
+		if errors.As(err, &sbe) {
+			if len(alternatives) > 0 || time.Now().Add(sbe.RetryAfter).After(busyDeadline) {
+				return downloaded, timeToFirstByte, serverVersion, err
+			}
+			log.Infof("%s is busy; retrying in %s as it asked", transfer.Url.String(), sbe.RetryAfter)
+			select {
+			case <-ctx.Done():
+				return downloaded, timeToFirstByte, serverVersion, err
+			case <-time.After(sbe.RetryAfter):
+			}
+			continue
+		}
+		var se *StagingError
+		if !errors.As(err, &se) {
+			return downloaded, timeToFirstByte, serverVersion, err
+		}
+		// Drop the empty file left by the attempt so the next one doesn't try to resume it
+		if info, statErr := os.Stat(dest); statErr == nil && info.Mode().IsRegular() && info.Size() == 0 {
+			_ = os.Remove(dest)
+		}
+		if stageTimeout <= 0 {
+			return downloaded, timeToFirstByte, serverVersion, errors.Wrap(err, "not waiting for the object to be staged; set --stage-timeout (Client.StageTimeout) to wait")
+		}
+		remaining := time.Until(stageDeadline)
+		if remaining <= 0 {
+			return downloaded, timeToFirstByte, serverVersion, errors.Wrapf(err, "the object wasn't staged within the stage timeout of %s", stageTimeout)
+		}
+		wait := se.RetryAfter
+		if wait > remaining {
+			wait = remaining
+		}
+		log.Infof("%s is being staged from offline storage; checking again in %s", transfer.Url.String(), wait)
+		select {
+		case <-ctx.Done():
+			return downloaded, timeToFirstByte, serverVersion, err
+		case <-time.After(wait):
+		}
+	}
+}
+
 // DownloadHTTP - Perform the actual download of the file
 // Returns: downloaded size, time to 1st byte downloaded, serverVersion and an error if there is one
-func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *payloadStruct) (int64, int64, string, error) {
+func DownloadHTTP(ctx context.Context, transfer TransferDetails, dest string, token string, payload *payloadStruct) (int64, int64, string, error) {
+	return downloadHTTP(ctx, transfer, nil, dest, token, payload)
+}
+
+// Perform the download of the file from transfer. The alternatives are the sources the
+// caller will fail over to if this download fails; the adaptive slow transfer detection
+// uses them to decide whether to abort a slow download early.
+func downloadHTTP(parentCtx context.Context, transfer TransferDetails, alternatives []TransferDetails, dest string, token string, payload *payloadStruct) (int64, int64, string, error) {
 
 	// Create the client, request, and context
 	client := grab.NewClient()
@@ -513,9 +764,9 @@ func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *
 	if !ok {
 		return 0, 0, "", errors.New("Internal error: implementation is not a http.Client type")
 	}
-	httpClient.Transport = transport
+	httpClient.Transport = traceTransport(transport)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 	log.Debugln("Transfer URL String:", transfer.Url.String())
 	var req *grab.Request
@@ -550,6 +801,13 @@ func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *
 		req.HTTPRequest.Header.Set("User-Agent", payload.ProjectName)
 	}
 	req.WithContext(ctx)
+	// A server answers 202 Accepted, without the object, while it stages the object from tape
+	req.BeforeCopy = func(resp *grab.Response) error {
+		if resp.HTTPResponse.StatusCode == http.StatusAccepted {
+			return newStagingError(resp.HTTPResponse)
+		}
+		return nil
+	}
 
 	// Test the transfer speed every 5 seconds
 	t := time.NewTicker(5000 * time.Millisecond)
@@ -577,6 +835,9 @@ func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *
 				err = fmt.Errorf("Local copy of file is larger than remote copy %w", grab.ErrBadLength)
 			}
 			log.Errorln("Failed to download:", err)
+			if busyErr := newServerBusyError(resp.HTTPResponse); busyErr != nil {
+				return 0, 0, "", busyErr
+			}
 			return 0, 0, "", &ConnectionSetupError{Err: err}
 		}
 	}
@@ -586,8 +847,8 @@ func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *
 	contentLength := resp.Size()
 	// Do a head request for content length if resp.Size is unknown
 	if contentLength <= 0 && ObjectClientOptions.ProgressBars {
-		headClient := &http.Client{Transport: config.GetTransport()}
-		headRequest, _ := http.NewRequest("HEAD", transfer.Url.String(), nil)
+		headClient := &http.Client{Transport: traceTransport(config.GetTransport())}
+		headRequest, _ := http.NewRequestWithContext(ctx, "HEAD", transfer.Url.String(), nil)
 		headResponse, err := headClient.Do(headRequest)
 		if err != nil {
 			log.Errorln("Could not successfully get response for HEAD request")
@@ -627,6 +888,9 @@ func DownloadHTTP(transfer TransferDetails, dest string, token string, payload *
 	var lastBytesComplete int64
 	var timeToFirstByte int64
 	timeToFirstByteRecorded := false
+	adaptiveDetection := param.Client_SlowTransferPolicy.GetString() != slowTransferPolicyLegacy
+	detector := newSlowTransferDetector(transfer.Url.Host, alternatives, float64(downloadLimit),
+		time.Duration(slowTransferRampupTime)*time.Second, time.Duration(slowTransferWindow)*time.Second, downloadStart)
 	// Loop of the download
 Loop:
 	for {
@@ -635,6 +899,7 @@ Loop:
 			if !timeToFirstByteRecorded && resp.BytesComplete() > 1 {
 				timeToFirstByte = int64(time.Since(downloadStart))
 			}
+			getTransferMonitor().progress(transfer.Url.Path, resp.BytesComplete(), contentLength)
 			if ObjectClientOptions.ProgressBars {
 				progressBar.SetTotal(contentLength, false)
 				currentCompletedBytes := resp.BytesComplete()
@@ -667,6 +932,34 @@ Loop:
 			}
 			lastBytesComplete = resp.BytesComplete()
 
+			if adaptiveDetection {
+				warn, abort := detector.observe(resp.BytesComplete(), contentLength, time.Now())
+				if warn {
+					warning := []byte("Warning! Downloading too slow...\n")
+					if status, err := getProgressContainer().Write(warning); err != nil {
+						log.Errorln("Problem displaying slow message", err, status)
+					}
+				}
+				if !abort {
+					continue
+				}
+				cancel()
+				if ObjectClientOptions.ProgressBars {
+					progressBar.Abort(true)
+					progressBar.Wait()
+				}
+
+				log.Errorln("Cancelled: Download speed of", int64(detector.rate()), "bytes/s is below the expected speed of", int64(detector.threshold()), "bytes/s or another source is predicted to be faster")
+				recordThroughputBaseline(transfer.Url.Host, detector.rate())
+
+				return 0, timeToFirstByte, serverVersion, &SlowTransferError{
+					BytesTransferred: resp.BytesComplete(),
+					BytesPerSecond:   int64(detector.rate()),
+					Duration:         resp.Duration(),
+					BytesTotal:       contentLength,
+				}
+			}
+
 			// Check if we are downloading fast enough
 			if resp.BytesPerSecond() < float64(downloadLimit) {
 				// Give the download `slowTransferRampupTime` (default 120) seconds to start
@@ -765,6 +1058,11 @@ Loop:
 		}
 	}
 
+	// Transfers shorter than a second are dominated by latency rather than throughput
+	if resp.Duration() > time.Second {
+		recordThroughputBaseline(transfer.Url.Host, resp.BytesPerSecond())
+	}
+
 	log.Debugln("HTTP Transfer was successful")
 	return resp.BytesComplete(), timeToFirstByte, serverVersion, nil
 }
@@ -821,8 +1119,9 @@ func (pr *ProgressReader) Size() int64 {
 }
 
 // Recursively uploads a directory with all files and nested dirs, keeping file structure on server side
-func UploadDirectory(src string, dest *url.URL, token string, namespace namespaces.Namespace, projectName string) (transferResults []TransferResults, err error) {
+func UploadDirectory(ctx context.Context, src string, dest *url.URL, token string, namespace namespaces.Namespace, projectName string) (transferResults []TransferResults, err error) {
 	var files []string
+	tokens := newTokenManager(token, dest, namespace, true, "")
 	srcUrl := url.URL{Path: src}
 	// Get the list of files as well as make any directories on the server end
 	files, err = walkDavDir(&srcUrl, namespace, token, dest.Path, true)
@@ -833,6 +1132,13 @@ func UploadDirectory(src string, dest *url.URL, token string, namespace namespac
 	if ObjectClientOptions.ProgressBars {
 		log.SetOutput(getProgressContainer())
 	}
+	if ObjectClientOptions.TUI {
+		monitor := startTransferMonitor(files)
+		defer monitor.stop()
+	} else if ObjectClientOptions.ProgressJSON {
+		monitor := startProgressJSON(files)
+		defer monitor.stop()
+	}
 	var transfer TransferResults
 
 	// Check if there is a directory specified in dest, if not then use the local one
@@ -861,7 +1167,14 @@ func UploadDirectory(src string, dest *url.URL, token string, namespace namespac
 		if err != nil {
 			return nil, err
 		}
-		transfer, err = UploadFile(file, &tempDest, token, namespace, projectName)
+		getTransferMonitor().started(file)
+		fileToken := tokens.get()
+		transfer, err = UploadFile(ctx, file, &tempDest, fileToken, namespace, projectName)
+		if err != nil && tokens.refreshIfRejected(err, fileToken) {
+			getTransferMonitor().attemptFailed(file, err.Error())
+			transfer, err = UploadFile(ctx, file, &tempDest, tokens.get(), namespace, projectName)
+		}
+		getTransferMonitor().finished(file, transfer.TransferedBytes, err)
 		if err != nil {
 			return nil, err
 		}
@@ -877,7 +1190,7 @@ func UploadDirectory(src string, dest *url.URL, token string, namespace namespac
 }
 
 // UploadFile Uploads a file using HTTP
-func UploadFile(src string, origDest *url.URL, token string, namespace namespaces.Namespace, projectName string) (transferResult TransferResults, err error) {
+func UploadFile(ctx context.Context, src string, origDest *url.URL, token string, namespace namespaces.Namespace, projectName string) (transferResult TransferResults, err error) {
 	log.Debugln("In UploadFile")
 	log.Debugln("Dest", origDest.String())
 	var attempt Attempt
@@ -933,7 +1246,7 @@ func UploadFile(src string, origDest *url.URL, token string, namespace namespace
 	dest := &url.URL{
 		Host:   writebackhostUrl.Host,
 		Scheme: "https",
-		Path:   origDest.Path,
+		Path:   writebackhostUrl.Path + origDest.Path,
 	}
 	attempt.Endpoint = dest.Host
 	// Create the wrapped reader and send it to the request
@@ -941,7 +1254,7 @@ func UploadFile(src string, origDest *url.URL, token string, namespace namespace
 	errorChan := make(chan error, 1)
 	responseChan := make(chan *http.Response)
 	reader := &ProgressReader{ioreader, sizer, closed}
-	putContext, cancel := context.WithCancel(context.Background())
+	putContext, cancel := context.WithCancel(ctx)
 	defer cancel()
 	log.Debugln("Full destination URL:", dest.String())
 	var request *http.Request
@@ -958,6 +1271,9 @@ func UploadFile(src string, origDest *url.URL, token string, namespace namespace
 	}
 	// Set the authorization header
 	request.Header.Set("Authorization", "Bearer "+token)
+	if strings.HasPrefix(dest.Path, common.WriteBackAPIPath+"/") {
+		request.Header.Set(common.WriteBackAckHeader, param.Client_WriteBackAck.GetString())
+	}
 	if projectName != "" {
 		request.Header.Set("User-Agent", projectName)
 	}
@@ -1014,6 +1330,7 @@ Loop:
 				progressBar.SetTotal(reader.Size(), false)
 				progressBar.EwmaSetCurrent(reader.BytesComplete(), tickerDuration)
 			}
+			getTransferMonitor().progress(src, reader.BytesComplete(), reader.Size())
 
 		case <-t.C:
 			// If we are not making any progress, if we haven't written 1MB in the last 5 seconds
@@ -1072,7 +1389,7 @@ Loop:
 
 // Actually perform the Put request to the server
 func doPut(request *http.Request, responseChan chan<- *http.Response, errorChan chan<- error) {
-	var UploadClient = &http.Client{Transport: config.GetTransport()}
+	var UploadClient = &http.Client{Transport: traceTransport(config.GetTransport())}
 	client := UploadClient
 	dump, _ := httputil.DumpRequestOut(request, false)
 	log.Debugf("Dumping request: %s", dump)
@@ -1123,7 +1440,7 @@ func walkDavDir(url *url.URL, namespace namespaces.Namespace, token string, dest
 
 	// XRootD does not like keep alives and kills things, so turn them off.
 	transport := config.GetTransport()
-	c.SetTransport(transport)
+	c.SetTransport(traceTransport(transport))
 	var files []string
 	var err error
 	if upload {
@@ -1215,7 +1532,7 @@ func StatHttp(dest *url.URL, namespace namespaces.Namespace) (uint64, error) {
 			log.Debugln("Performing HEAD", dest.String())
 		}
 
-		client := &http.Client{Transport: transport}
+		client := &http.Client{Transport: traceTransport(transport)}
 		req, err := http.NewRequest("HEAD", dest.String(), nil)
 		if err != nil {
 			log.Errorln("Failed to create HTTP request:", err)
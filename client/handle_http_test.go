@@ -190,7 +190,7 @@ func TestSlowTransfers(t *testing.T) {
 	var err error
 	// Do a quick timeout
 	go func() {
-		_, _, _, err = DownloadHTTP(transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
+		_, _, _, err = DownloadHTTP(context.Background(), transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
 		finishedChannel <- true
 	}()
 
@@ -253,7 +253,7 @@ func TestStoppedTransfer(t *testing.T) {
 	var err error
 
 	go func() {
-		_, _, _, err = DownloadHTTP(transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
+		_, _, _, err = DownloadHTTP(context.Background(), transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
 		finishedChannel <- true
 	}()
 
@@ -283,7 +283,7 @@ func TestConnectionError(t *testing.T) {
 	addr := l.Addr().String()
 	l.Close()
 
-	_, _, _, err = DownloadHTTP(TransferDetails{Url: url.URL{Host: addr, Scheme: "http"}, Proxy: false}, filepath.Join(t.TempDir(), "test.txt"), "", nil)
+	_, _, _, err = DownloadHTTP(context.Background(), TransferDetails{Url: url.URL{Host: addr, Scheme: "http"}, Proxy: false}, filepath.Join(t.TempDir(), "test.txt"), "", nil)
 
 	assert.IsType(t, &ConnectionSetupError{}, err)
 
@@ -316,12 +316,134 @@ func TestTrailerError(t *testing.T) {
 	assert.Equal(t, svr.URL, transfers[0].Url.String())
 
 	// Call DownloadHTTP and check if the error is returned correctly
-	_, _, _, err := DownloadHTTP(transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
+	_, _, _, err := DownloadHTTP(context.Background(), transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
 
 	assert.NotNil(t, err)
 	assert.EqualError(t, err, "transfer error: Unable to read test.txt; input/output error")
 }
 
+// A download that stalls is abandoned once its context's deadline passes, rather than
+// waiting for the stopped transfer detection
+func TestDownloadDeadline(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("Test data"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer svr.Close()
+
+	testCache := namespaces.Cache{
+		AuthEndpoint: svr.URL,
+		Endpoint:     svr.URL,
+		Resource:     "Cache",
+	}
+	transfers := NewTransferDetails(testCache, TransferDetailsOptions{false, ""})
+	require.NotEmpty(t, transfers)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, _, err := DownloadHTTP(ctx, transfers[0], filepath.Join(t.TempDir(), "test.txt"), "", nil)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	err = checkTransferDeadline(ctx, err)
+	assert.ErrorIs(t, err, ErrTransferDeadlineExceeded)
+	assert.False(t, IsRetryable(err))
+}
+
+// A download of an object the server is staging is retried until the object is online,
+// for up to Client.StageTimeout
+func TestDownloadWaitForStage(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		requests++
+		if requests < 3 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"staging": true}`))
+			return
+		}
+		_, _ = w.Write([]byte("Test data"))
+	}))
+	defer svr.Close()
+
+	testCache := namespaces.Cache{
+		AuthEndpoint: svr.URL,
+		Endpoint:     svr.URL,
+		Resource:     "Cache",
+	}
+	transfers := NewTransferDetails(testCache, TransferDetailsOptions{false, ""})
+	require.NotEmpty(t, transfers)
+	dest := filepath.Join(t.TempDir(), "test.txt")
+
+	// Without a stage timeout, the download fails right away
+	_, _, _, err := downloadHTTPWaitForStage(context.Background(), transfers[0], nil, dest, "", nil)
+	assert.ErrorIs(t, err, &StagingError{})
+	assert.True(t, IsRetryable(err))
+	assert.Equal(t, 1, requests)
+
+	viper.Set("Client.StageTimeout", "1m")
+	_, _, _, err = downloadHTTPWaitForStage(context.Background(), transfers[0], nil, dest, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "Test data", string(content))
+}
+
+// A busy server's Retry-After is honored when it's the only source, up to Client.MaxRetryAfter
+func TestDownloadServerBusy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("Test data"))
+	}))
+	defer svr.Close()
+
+	testCache := namespaces.Cache{
+		AuthEndpoint: svr.URL,
+		Endpoint:     svr.URL,
+		Resource:     "Cache",
+	}
+	transfers := NewTransferDetails(testCache, TransferDetailsOptions{false, ""})
+	require.NotEmpty(t, transfers)
+	dest := filepath.Join(t.TempDir(), "test.txt")
+
+	// With an alternative source, the client fails over rather than waiting
+	viper.Set("Client.MaxRetryAfter", "1m")
+	_, _, _, err := downloadHTTPWaitForStage(context.Background(), transfers[0], transfers, dest, "", nil)
+	var sbe *ServerBusyError
+	require.ErrorAs(t, err, &sbe)
+	assert.Equal(t, http.StatusTooManyRequests, sbe.StatusCode)
+	assert.Equal(t, time.Second, sbe.RetryAfter)
+	assert.True(t, IsRetryable(err))
+	assert.Equal(t, 1, requests)
+
+	requests = 0
+	_, _, _, err = downloadHTTPWaitForStage(context.Background(), transfers[0], nil, dest, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "Test data", string(content))
+}
+
 func TestUploadZeroLengthFile(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -529,14 +651,14 @@ func TestFullUpload(t *testing.T) {
 		uploadURL := "stash:///test/" + fileName
 
 		methods := []string{"http"}
-		transferResults, err := DoStashCPSingle(tempFile.Name(), uploadURL, methods, false)
+		transferResults, err := DoStashCPSingle(context.Background(), tempFile.Name(), uploadURL, methods, false)
 		assert.NoError(t, err, "Error uploading file")
 		assert.Equal(t, int64(len(testFileContent)), transferResults[0].TransferedBytes, "Uploaded file size does not match")
 
 		// Upload an osdf file
 		uploadURL = "osdf:///test/stuff/blah.txt"
 		assert.NoError(t, err, "Error parsing upload URL")
-		transferResults, err = DoStashCPSingle(tempFile.Name(), uploadURL, methods, false)
+		transferResults, err = DoStashCPSingle(context.Background(), tempFile.Name(), uploadURL, methods, false)
 		assert.NoError(t, err, "Error uploading file")
 		assert.Equal(t, int64(len(testFileContent)), transferResults[0].TransferedBytes, "Uploaded file size does not match")
 	})
@@ -19,6 +19,7 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path"
@@ -103,7 +104,7 @@ func DoShadowIngest(sourceFile string, originPrefix string, shadowOriginPrefix s
 			time.Sleep(5 * time.Second)
 		}
 
-		transferResults, err := DoStashCPSingle(sourceFile, shadowFile, methods, false)
+		transferResults, err := DoStashCPSingle(context.Background(), sourceFile, shadowFile, methods, false)
 		if err != nil {
 			return 0, "", err
 		}
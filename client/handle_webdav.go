@@ -0,0 +1,273 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/studio-b12/gowebdav"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+// An object or collection changed through the WebDAV interface of the origin the director
+// sends writes of its namespace to
+type davTarget struct {
+	// The URL the object was named by, whose scheme may name the token to use
+	objectUrl  *url.URL
+	objectPath string
+	namespace  namespaces.Namespace
+	// The URL of the object at the origin
+	originUrl *url.URL
+}
+
+// Find the origin to change the object named by a pelican://, osdf:// or federation-relative
+// URL at.  The director is asked as if the object were uploaded, so the object's name is held
+// to the upload policy of its namespace.
+func getDavTarget(remoteObject string) (*davTarget, error) {
+	remoteObject, remoteScheme := correctURLWithUnderscore(remoteObject)
+	objectUrl, err := url.Parse(remoteObject)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", remoteObject)
+	}
+	objectUrl.Scheme = remoteScheme
+	scheme, _ := getTokenName(objectUrl)
+	if (scheme == "osdf" || scheme == "stash") && objectUrl.Host != "" {
+		objectUrl.Path = "/" + objectUrl.Host + objectUrl.Path
+		objectUrl.Host = ""
+	}
+
+	directorUrl := ""
+	if !usingStaticFederation() {
+		schemeUrl := *objectUrl
+		schemeUrl.Scheme = scheme
+		if directorUrl, err = getDirectorFromUrl(&schemeUrl); err != nil {
+			return nil, err
+		}
+		objectUrl.Path = schemeUrl.Path
+	}
+	target := &davTarget{
+		objectUrl:  objectUrl,
+		objectPath: path.Clean("/" + objectUrl.Path),
+	}
+	if target.namespace, err = getNamespaceInfo(target.objectPath, directorUrl, true, -1); err != nil {
+		if errors.Is(err, ErrReadOnlyNamespace) {
+			return nil, errors.Wrapf(ErrReadOnlyNamespace, "cannot change %s", target.objectPath)
+		}
+		return nil, err
+	}
+	if target.namespace.WriteBackHost == "" {
+		return nil, errors.Wrapf(ErrReadOnlyNamespace, "cannot change %s as no origin of %s accepts writes", target.objectPath, target.namespace.Path)
+	}
+	if target.originUrl, err = url.Parse(target.namespace.WriteBackHost); err != nil {
+		return nil, errors.Wrapf(err, "invalid origin URL %s", target.namespace.WriteBackHost)
+	}
+	target.originUrl.Path = target.objectPath
+	return target, nil
+}
+
+// Get the URL of another object at the origin of target
+func (target *davTarget) originUrlOf(objectPath string) *url.URL {
+	objectUrl := *target.originUrl
+	objectUrl.Path = objectPath
+	return &objectUrl
+}
+
+// Get a WebDAV client for browsing the origin of target
+func (target *davTarget) davClient(token string) *gowebdav.Client {
+	rootUrl := target.originUrlOf("")
+	c := gowebdav.NewAuthClient(rootUrl.String(), &bearerAuth{token: token})
+	c.SetTransport(traceTransport(config.GetTransport()))
+	return c
+}
+
+// Send a WebDAV request to an origin, failing with an *HttpErrResp unless it succeeds.
+// Common failures are described with what they mean for the request's method.
+func doDavRequest(ctx context.Context, method string, target *url.URL, token string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+ObjectClientOptions.Version)
+	client := http.Client{Transport: traceTransport(config.GetTransport())}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	log.Debugf("%s %s failed with HTTP status %d: %s", method, target.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+
+	reason := ""
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		reason = "the token doesn't authorize the change"
+		if method == "MOVE" {
+			reason += "; a move needs a token with the storage.modify scope for both paths"
+		}
+	case resp.StatusCode == http.StatusNotFound:
+		reason = "it doesn't exist"
+	case resp.StatusCode == http.StatusMethodNotAllowed && method == "MKCOL":
+		reason = "it already exists"
+	case resp.StatusCode == http.StatusConflict:
+		reason = "its parent collection doesn't exist"
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		reason = "the destination already exists"
+	default:
+		reason = strings.TrimSpace(string(body))
+	}
+	return &HttpErrResp{resp.StatusCode, fmt.Sprintf("%s of %s failed (HTTP status %d): %s", method, target.Path, resp.StatusCode, reason)}
+}
+
+// Create the collection (directory) remoteDir at the origin of its namespace.  With parents,
+// the missing collections above it in its namespace are created too, and it's no error for
+// remoteDir to exist already.
+func DoMkdir(ctx context.Context, remoteDir string, parents bool) error {
+	fd := config.GetFederation()
+	defer config.SetFederation(fd)
+
+	target, err := getDavTarget(remoteDir)
+	if err != nil {
+		return err
+	}
+	token, err := getTokenForOperation(target.objectUrl, target.namespace, config.TokenWrite, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to get a token to create the collection")
+	}
+	if !parents {
+		return doDavRequest(ctx, "MKCOL", target.originUrl, token, nil)
+	}
+
+	// The namespace itself always exists, so only the collections below it are created
+	nsPath := path.Clean("/" + target.namespace.Path)
+	collections := []string{}
+	for dir := target.objectPath; dir != nsPath && dir != "/"; dir = path.Dir(dir) {
+		collections = append(collections, dir)
+	}
+	for idx := len(collections) - 1; idx >= 0; idx-- {
+		err = doDavRequest(ctx, "MKCOL", target.originUrlOf(collections[idx]), token, nil)
+		var her *HttpErrResp
+		if errors.As(err, &her) && her.Code == http.StatusMethodNotAllowed {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move or rename the object or collection at remoteSrc to remoteDest.  The move is done by
+// the origin, so both must be in the same namespace; an existing object at remoteDest isn't
+// replaced.  A collection is only moved if recursive is set, in which case everything in it
+// moves along.
+func DoMove(ctx context.Context, remoteSrc string, remoteDest string, recursive bool) error {
+	fd := config.GetFederation()
+	defer config.SetFederation(fd)
+
+	src, err := getDavTarget(remoteSrc)
+	if err != nil {
+		return err
+	}
+	dest, err := getDavTarget(remoteDest)
+	if err != nil {
+		return err
+	}
+	if src.namespace.Path != dest.namespace.Path || src.originUrl.Host != dest.originUrl.Host {
+		return errors.Errorf("%s and %s are in different namespaces; only objects in the same namespace can be moved", src.objectPath, dest.objectPath)
+	}
+	// Moving an object removes it from its old path, so the token must be allowed to
+	// modify objects, not just create them
+	token, err := getTokenForOperation(src.objectUrl, src.namespace, config.TokenDelete, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to get a token to move the object")
+	}
+
+	info, err := src.davClient(token).Stat(src.objectPath)
+	if gowebdav.IsErrNotFound(err) {
+		return errors.Errorf("%s doesn't exist", src.objectPath)
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to look up %s", src.objectPath)
+	}
+	if info.IsDir() && !recursive {
+		return errors.Errorf("%s is a collection, which is only moved recursively", src.objectPath)
+	}
+
+	err = moveObject(ctx, src, src.objectPath, dest.objectPath, token)
+	var her *HttpErrResp
+	if info.IsDir() && errors.As(err, &her) && (her.Code == http.StatusMethodNotAllowed || her.Code == http.StatusNotImplemented) {
+		log.Infof("The origin can't move the collection %s as a whole; moving the objects in it one at a time", src.objectPath)
+		return moveCollectionContents(ctx, src, src.objectPath, dest.objectPath, token)
+	}
+	return err
+}
+
+// Move the object or collection at srcPath to destPath at the origin of target
+func moveObject(ctx context.Context, target *davTarget, srcPath string, destPath string, token string) error {
+	header := http.Header{}
+	header.Set("Destination", target.originUrlOf(destPath).String())
+	header.Set("Overwrite", "F")
+	return doDavRequest(ctx, "MOVE", target.originUrlOf(srcPath), token, header)
+}
+
+// Move the objects in the collection at srcDir to a new collection at destDir one at a time,
+// then remove the emptied collection.  Used with origins that can't rename a collection
+// as a whole, such as those backed by object stores.
+func moveCollectionContents(ctx context.Context, target *davTarget, srcDir string, destDir string, token string) error {
+	if err := doDavRequest(ctx, "MKCOL", target.originUrlOf(destDir), token, nil); err != nil {
+		return err
+	}
+	infos, err := target.davClient(token).ReadDir(srcDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list %s", srcDir)
+	}
+	for _, info := range infos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		srcPath := path.Join(srcDir, info.Name())
+		destPath := path.Join(destDir, info.Name())
+		if info.IsDir() {
+			err = moveCollectionContents(ctx, target, srcPath, destPath, token)
+		} else {
+			err = moveObject(ctx, target, srcPath, destPath, token)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return doDavRequest(ctx, http.MethodDelete, target.originUrlOf(srcDir), token, nil)
+}
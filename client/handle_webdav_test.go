@@ -0,0 +1,160 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+
+	"github.com/pelicanplatform/pelican/config"
+)
+
+func TestWebDAVOperations(t *testing.T) {
+	config.ResetConfig()
+	t.Cleanup(config.ResetConfig)
+	ctx := context.Background()
+
+	// A WebDAV origin keeping its objects in memory, which can be made to refuse moving
+	// collections as a whole like an origin backed by an object store
+	fs := webdav.NewMemFS()
+	davHandler := &webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()}
+	refuseCollectionMoves := false
+	var lastAuth string
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuth = r.Header.Get("Authorization")
+		if r.Method == "MOVE" && refuseCollectionMoves {
+			if info, err := fs.Stat(r.Context(), r.URL.Path); err == nil && info.IsDir() {
+				w.WriteHeader(http.StatusNotImplemented)
+				return
+			}
+		}
+		davHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(origin.Close)
+	writeObject := func(name string) {
+		f, err := fs.OpenFile(ctx, name, os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = f.Write([]byte("data"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	exists := func(name string) bool {
+		_, err := fs.Stat(ctx, name)
+		return err == nil
+	}
+	require.NoError(t, fs.Mkdir(ctx, "/foo", 0755))
+	writeObject("/foo/data.txt")
+
+	fedFile := filepath.Join(t.TempDir(), "federation.yaml")
+	require.NoError(t, os.WriteFile(fedFile, []byte(`
+namespaces:
+  - path: /foo
+    origins: ["`+origin.URL+`"]
+  - path: /bar
+    origins: ["`+origin.URL+`"]
+  - path: /public
+    caches: ["https://cache.example.com:8443"]
+`), 0644))
+	viper.Set("Client.StaticFederationFile", fedFile)
+	viper.Set("TLSSkipVerify", true)
+	t.Setenv("BEARER_TOKEN", "test-token")
+
+	t.Run("mkdir", func(t *testing.T) {
+		require.NoError(t, DoMkdir(ctx, "/foo/newdir", false))
+		assert.True(t, exists("/foo/newdir"))
+		assert.Equal(t, "Bearer test-token", lastAuth)
+
+		err := DoMkdir(ctx, "/foo/newdir", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already exists")
+
+		err = DoMkdir(ctx, "/foo/a/b", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parent collection doesn't exist")
+
+		// With parents, the missing collections are created and existing ones are fine
+		require.NoError(t, DoMkdir(ctx, "/foo/a/b", true))
+		assert.True(t, exists("/foo/a/b"))
+		require.NoError(t, DoMkdir(ctx, "/foo/a/b", true))
+	})
+
+	t.Run("move", func(t *testing.T) {
+		require.NoError(t, DoMove(ctx, "/foo/data.txt", "/foo/renamed.txt", false))
+		assert.False(t, exists("/foo/data.txt"))
+		assert.True(t, exists("/foo/renamed.txt"))
+
+		// Existing objects aren't replaced
+		writeObject("/foo/other.txt")
+		err := DoMove(ctx, "/foo/other.txt", "/foo/renamed.txt", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "destination already exists")
+
+		err = DoMove(ctx, "/foo/missing.txt", "/foo/moved.txt", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't exist")
+
+		// The origin can't move objects between namespaces
+		err = DoMove(ctx, "/foo/other.txt", "/bar/other.txt", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "different namespaces")
+	})
+
+	t.Run("move-collection", func(t *testing.T) {
+		require.NoError(t, fs.Mkdir(ctx, "/foo/dir", 0755))
+		require.NoError(t, fs.Mkdir(ctx, "/foo/dir/sub", 0755))
+		writeObject("/foo/dir/one.txt")
+		writeObject("/foo/dir/sub/two.txt")
+
+		err := DoMove(ctx, "/foo/dir", "/foo/dir2", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only moved recursively")
+
+		require.NoError(t, DoMove(ctx, "/foo/dir", "/foo/dir2", true))
+		assert.False(t, exists("/foo/dir"))
+		assert.True(t, exists("/foo/dir2/sub/two.txt"))
+
+		// Origins that can't move a collection as a whole get its objects moved one by one
+		refuseCollectionMoves = true
+		defer func() { refuseCollectionMoves = false }()
+		require.NoError(t, DoMove(ctx, "/foo/dir2", "/foo/dir3", true))
+		assert.False(t, exists("/foo/dir2"))
+		assert.True(t, exists("/foo/dir3/one.txt"))
+		assert.True(t, exists("/foo/dir3/sub/two.txt"))
+	})
+
+	t.Run("read-only-namespace", func(t *testing.T) {
+		err := DoMkdir(ctx, "/public/newdir", false)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrReadOnlyNamespace))
+
+		err = DoMove(ctx, "/public/a.txt", "/public/b.txt", false)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrReadOnlyNamespace))
+	})
+}
@@ -0,0 +1,442 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A cache of downloaded objects on local disk, shared by the processes of a user
+	// configured with the same Client.LocalCacheLocation.  Each user gets a private
+	// directory within it, so one user can't change the objects another is served.
+	// Readers hold a shared lock on the cache while copying an object out; adding and
+	// evicting objects takes an exclusive lock.
+	localCache struct {
+		location string
+		maxSize  int64
+	}
+
+	// The metadata stored next to each cached object
+	localCacheEntry struct {
+		Federation   string    `json:"federation"`
+		Object       string    `json:"object"`
+		Size         int64     `json:"size"`
+		ETag         string    `json:"etag,omitempty"`
+		LastModified string    `json:"last-modified,omitempty"`
+		Checksum     string    `json:"sha256"`
+		Stored       time.Time `json:"stored"`
+	}
+
+	// The version of an object the federation currently serves, used to check that a
+	// cached copy is still fresh
+	objectVersion struct {
+		Size         int64
+		ETag         string
+		LastModified string
+	}
+)
+
+const (
+	// The endpoint recorded in the transfer attempts served from the local cache
+	localCacheEndpoint = "local-cache"
+	localCacheMetaExt  = ".json"
+)
+
+// Get the local cache configured for the client, or nil if there's none
+func getLocalCache() *localCache {
+	location := param.Client_LocalCacheLocation.GetString()
+	if location == "" {
+		return nil
+	}
+	maxSize := int64(param.Client_LocalCacheSize.GetInt()) * 1024 * 1024
+	if maxSize <= 0 {
+		log.Warningln("Client.LocalCacheSize is not positive; the local cache is disabled")
+		return nil
+	}
+	userDir, err := getLocalCacheUserDir(location)
+	if err != nil {
+		log.Warningln("The local cache is disabled:", err)
+		return nil
+	}
+	return &localCache{location: userDir, maxSize: maxSize}
+}
+
+// Get the current user's private directory in the local cache at location, creating it if
+// needed.  The directories of all the users live in a sticky, world-writable directory, like
+// /tmp, so users can add their own but not tamper with anyone else's.
+func getLocalCacheUserDir(location string) (string, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to look up the current user")
+	}
+	usersDir := filepath.Join(location, "users")
+	if _, err = os.Stat(usersDir); errors.Is(err, fs.ErrNotExist) {
+		if err = os.MkdirAll(usersDir, 0755); err != nil {
+			return "", errors.Wrap(err, "failed to create the local cache directory")
+		}
+		if err = os.Chmod(usersDir, 0777|fs.ModeSticky); err != nil {
+			return "", errors.Wrap(err, "failed to make the local cache directory shareable")
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	userDir := filepath.Join(usersDir, currentUser.Uid)
+	if err = os.Mkdir(userDir, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", errors.Wrap(err, "failed to create the local cache directory")
+	}
+	info, err := os.Stat(userDir)
+	if err != nil {
+		return "", err
+	}
+	// A directory others can write to could have been prepared by another user
+	if !info.IsDir() || info.Mode().Perm()&0077 != 0 {
+		return "", errors.Errorf("%s is not a private directory", userDir)
+	}
+	return userDir, nil
+}
+
+// Get the version of objectPath the first reachable server in transfers serves, with a HEAD
+// request, so a cached copy of it is only served while it's fresh
+func getObjectVersion(ctx context.Context, transfers []TransferDetails, objectPath string, token string) (version objectVersion, err error) {
+	err = errors.New("no servers to check the object with")
+	for _, transfer := range transfers {
+		objectUrl := transfer.Url
+		objectUrl.Path = objectPath
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodHead, objectUrl.String(), nil)
+		if err != nil {
+			return
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		transport := config.GetTransport()
+		if !transfer.Proxy {
+			transport.Proxy = nil
+		}
+		client := &http.Client{Transport: traceTransport(transport)}
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err != nil {
+			log.Debugf("Failed to check the version of %s with %s: %v", objectPath, objectUrl.Host, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = errors.Errorf("%s responded to the HEAD request with status %d", objectUrl.Host, resp.StatusCode)
+			continue
+		}
+		version.Size, err = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+		if err != nil {
+			err = errors.Errorf("%s didn't report the size of %s", objectUrl.Host, objectPath)
+			continue
+		}
+		version.ETag = resp.Header.Get("ETag")
+		version.LastModified = resp.Header.Get("Last-Modified")
+		return version, nil
+	}
+	return
+}
+
+// Whether the cached copy described by entry is the version of the object the federation
+// serves.  The ETag and modification time are only compared when both sides have them.
+func (entry *localCacheEntry) isFresh(version objectVersion) bool {
+	if entry.Size != version.Size {
+		return false
+	}
+	if entry.ETag != "" && version.ETag != "" && entry.ETag != version.ETag {
+		return false
+	}
+	if entry.LastModified != "" && version.LastModified != "" && entry.LastModified != version.LastModified {
+		return false
+	}
+	return true
+}
+
+func (lc *localCache) objectsDir() string {
+	return filepath.Join(lc.location, "objects")
+}
+
+// Objects are stored under a hash of the federation and the object's path so that
+// objects of different federations don't collide
+func (lc *localCache) entryPath(objectPath string) string {
+	hash := sha256.Sum256([]byte(config.GetFederation().DirectorEndpoint + "\x00" + objectPath))
+	name := hex.EncodeToString(hash[:])
+	return filepath.Join(lc.objectsDir(), name[:2], name)
+}
+
+// The file a download to dest is written to; like the downloader, a download to an
+// existing directory goes into a file named after the object
+func resolveDownloadDest(dest string, objectPath string) string {
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		return filepath.Join(dest, path.Base(objectPath))
+	}
+	return dest
+}
+
+func readLocalCacheEntry(metaPath string) (entry localCacheEntry, err error) {
+	contents, err := os.ReadFile(metaPath)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(contents, &entry)
+	return
+}
+
+// Copy src to dest through a temporary file next to dest, returning the number of
+// bytes copied and their SHA-256 checksum.  dest is only replaced, with the given
+// permissions, if the copy succeeds and, when expectedChecksum is set, the checksum
+// matches it.
+func copyWithChecksum(src string, dest string, expectedChecksum string, perm fs.FileMode) (int64, string, error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return 0, "", err
+	}
+	defer srcFile.Close()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hasher), srcFile)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return 0, "", err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if expectedChecksum != "" && checksum != expectedChecksum {
+		return size, checksum, errors.Errorf("checksum %s doesn't match the expected %s", checksum, expectedChecksum)
+	}
+	if err = os.Chmod(tmpFile.Name(), perm); err != nil {
+		return 0, "", err
+	}
+	if err = os.Rename(tmpFile.Name(), dest); err != nil {
+		return 0, "", err
+	}
+	return size, checksum, nil
+}
+
+// Copy the cached copy of objectPath to dest if it's the version the federation serves.
+// Returns whether the object was served from the cache; a cached copy that's stale or
+// fails its integrity check is evicted.
+func (lc *localCache) get(objectPath string, dest string, version objectVersion) (TransferResults, bool) {
+	entryPath := lc.entryPath(objectPath)
+	unlock, err := lockLocalCache(lc.location, false)
+	if err != nil {
+		log.Warningln("Failed to lock the local cache for reading:", err)
+		return TransferResults{}, false
+	}
+	entry, err := readLocalCacheEntry(entryPath + localCacheMetaExt)
+	if err != nil {
+		unlock()
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Debugf("Ignoring the unreadable local cache entry for %s: %v", objectPath, err)
+		}
+		return TransferResults{}, false
+	}
+	if entry.Object != objectPath {
+		unlock()
+		return TransferResults{}, false
+	}
+	if !entry.isFresh(version) {
+		unlock()
+		log.Debugf("The local cache copy of %s is stale and will be evicted", objectPath)
+		lc.evict(entryPath)
+		return TransferResults{}, false
+	}
+
+	dest = resolveDownloadDest(dest, objectPath)
+	size, _, err := copyWithChecksum(entryPath, dest, entry.Checksum, 0644)
+	unlock()
+	if err == nil && size != entry.Size {
+		err = errors.Errorf("size %d doesn't match the expected %d", size, entry.Size)
+	}
+	if err != nil {
+		log.Warningf("Local cache copy of %s failed verification and will be evicted: %v", objectPath, err)
+		lc.evict(entryPath)
+		return TransferResults{}, false
+	}
+
+	// Record the access for the least-recently-used eviction
+	now := time.Now()
+	if err := os.Chtimes(entryPath+localCacheMetaExt, now, now); err != nil {
+		log.Debugf("Failed to record the access to the local cache entry of %s: %v", objectPath, err)
+	}
+	log.Debugf("Served %s from the local cache", objectPath)
+	return TransferResults{
+		TransferedBytes: size,
+		Attempts: []Attempt{{
+			TransferFileBytes: size,
+			TransferEndTime:   now.Unix(),
+			Endpoint:          localCacheEndpoint,
+		}},
+	}, true
+}
+
+// Add the downloaded copy of objectPath at dest, of the given version, to the cache and
+// evict the least recently used objects if the cache exceeds its size
+func (lc *localCache) put(objectPath string, dest string, version objectVersion) error {
+	dest = resolveDownloadDest(dest, objectPath)
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	// A download that doesn't match the version checked beforehand may be of a newer one
+	if !info.Mode().IsRegular() || info.Size() > lc.maxSize || info.Size() != version.Size {
+		return nil
+	}
+
+	entryPath := lc.entryPath(objectPath)
+	if err = os.MkdirAll(filepath.Dir(entryPath), 0700); err != nil {
+		return errors.Wrap(err, "failed to create the local cache directory")
+	}
+	// Copy outside of the lock so readers aren't blocked by a large object
+	staging := fmt.Sprintf("%s.%d.staging", entryPath, os.Getpid())
+	size, checksum, err := copyWithChecksum(dest, staging, "", 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy %s into the local cache", dest)
+	}
+	defer os.Remove(staging)
+	entryBytes, err := json.Marshal(localCacheEntry{
+		Federation:   config.GetFederation().DirectorEndpoint,
+		Object:       objectPath,
+		Size:         size,
+		ETag:         version.ETag,
+		LastModified: version.LastModified,
+		Checksum:     checksum,
+		Stored:       time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockLocalCache(lc.location, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock the local cache for writing")
+	}
+	defer unlock()
+	// Replace any previous entry; the metadata goes last so readers never see an
+	// entry without its object
+	os.Remove(entryPath + localCacheMetaExt)
+	if err = os.Rename(staging, entryPath); err != nil {
+		return errors.Wrap(err, "failed to add the object to the local cache")
+	}
+	if err = os.WriteFile(entryPath+localCacheMetaExt, entryBytes, 0600); err != nil {
+		os.Remove(entryPath)
+		return errors.Wrap(err, "failed to write the local cache entry")
+	}
+	log.Debugf("Added %s (%d bytes) to the local cache", objectPath, size)
+	lc.prune()
+	return nil
+}
+
+// Remove an entry from the cache
+func (lc *localCache) evict(entryPath string) {
+	unlock, err := lockLocalCache(lc.location, true)
+	if err != nil {
+		log.Warningln("Failed to lock the local cache for writing:", err)
+		return
+	}
+	defer unlock()
+	os.Remove(entryPath + localCacheMetaExt)
+	os.Remove(entryPath)
+}
+
+// Evict the least recently used objects until the cache fits in its size.  The
+// cache's exclusive lock must be held.
+func (lc *localCache) prune() {
+	type cachedObject struct {
+		entryPath  string
+		size       int64
+		lastAccess time.Time
+	}
+	objects := []cachedObject{}
+	var total int64
+	err := filepath.WalkDir(lc.objectsDir(), func(walkPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(walkPath, localCacheMetaExt) {
+			return nil
+		}
+		metaInfo, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entryPath := strings.TrimSuffix(walkPath, localCacheMetaExt)
+		objectInfo, err := os.Stat(entryPath)
+		if err != nil {
+			// An entry without its object is useless
+			os.Remove(walkPath)
+			return nil
+		}
+		objects = append(objects, cachedObject{entryPath, objectInfo.Size(), metaInfo.ModTime()})
+		total += objectInfo.Size()
+		return nil
+	})
+	if err != nil {
+		log.Warningln("Failed to scan the local cache:", err)
+		return
+	}
+	if total <= lc.maxSize {
+		return
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].lastAccess.Before(objects[j].lastAccess)
+	})
+	for _, object := range objects {
+		if total <= lc.maxSize {
+			break
+		}
+		if err := os.Remove(object.entryPath + localCacheMetaExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Debugf("Failed to evict %s from the local cache: %v", object.entryPath, err)
+			continue
+		}
+		os.Remove(object.entryPath)
+		total -= object.size
+	}
+	log.Debugf("Pruned the local cache to %d bytes", total)
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Lock the local cache at location, exclusively for writers and shared for readers.
+// The lock is held until the returned function is called.
+func lockLocalCache(location string, exclusive bool) (func(), error) {
+	if err := os.MkdirAll(location, 0700); err != nil {
+		return nil, err
+	}
+	fp, err := os.OpenFile(filepath.Join(location, ".lock"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err = syscall.Flock(int(fp.Fd()), how); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return func() {
+		if err := syscall.Flock(int(fp.Fd()), syscall.LOCK_UN); err != nil {
+			log.Warningln("Failed to unlock the local cache:", err)
+		}
+		fp.Close()
+	}, nil
+}
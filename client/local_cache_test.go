@@ -0,0 +1,185 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalCache(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	assert.Nil(t, getLocalCache())
+
+	location := t.TempDir()
+	viper.Set("Client.LocalCacheLocation", location)
+	viper.Set("Client.LocalCacheSize", 1)
+	lc := getLocalCache()
+	require.NotNil(t, lc)
+	assert.Equal(t, int64(1024*1024), lc.maxSize)
+
+	// Each user gets a private directory in the shared location
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(location, "users", currentUser.Uid), lc.location)
+	info, err := os.Stat(lc.location)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(location, "users"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0777), info.Mode().Perm())
+	assert.NotZero(t, info.Mode()&os.ModeSticky)
+
+	destDir := t.TempDir()
+	download := func(name string, size int) string {
+		dest := filepath.Join(destDir, name)
+		require.NoError(t, os.WriteFile(dest, make([]byte, size), 0644))
+		return dest
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		version := objectVersion{Size: 1000, ETag: `"v1"`}
+		require.NoError(t, lc.put("/foo/bar.txt", download("bar.txt", 1000), version))
+		info, err := os.Stat(lc.entryPath("/foo/bar.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		// A download to a directory goes into a file named after the object
+		dest := t.TempDir()
+		result, ok := lc.get("/foo/bar.txt", dest, version)
+		require.True(t, ok)
+		assert.Equal(t, int64(1000), result.TransferedBytes)
+		require.Len(t, result.Attempts, 1)
+		assert.Equal(t, localCacheEndpoint, result.Attempts[0].Endpoint)
+		info, err = os.Stat(filepath.Join(dest, "bar.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), info.Size())
+
+		_, ok = lc.get("/foo/missing.txt", dest, version)
+		assert.False(t, ok)
+	})
+
+	t.Run("stale-entries-are-evicted", func(t *testing.T) {
+		version := objectVersion{Size: 1000, ETag: `"v1"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}
+		for _, newVersion := range []objectVersion{
+			{Size: 1001, ETag: `"v1"`, LastModified: version.LastModified},
+			{Size: 1000, ETag: `"v2"`, LastModified: version.LastModified},
+			{Size: 1000, ETag: `"v1"`, LastModified: "Tue, 02 Jan 2024 00:00:00 GMT"},
+		} {
+			require.NoError(t, lc.put("/foo/stale.txt", download("stale.txt", 1000), version))
+			_, ok := lc.get("/foo/stale.txt", t.TempDir(), newVersion)
+			assert.False(t, ok)
+			assert.NoFileExists(t, lc.entryPath("/foo/stale.txt"))
+		}
+
+		// A server that doesn't report an ETag or modification time is only checked by size
+		require.NoError(t, lc.put("/foo/stale.txt", download("stale.txt", 1000), version))
+		_, ok := lc.get("/foo/stale.txt", t.TempDir(), objectVersion{Size: 1000})
+		assert.True(t, ok)
+
+		// A download that doesn't match the checked version isn't cached
+		require.NoError(t, lc.put("/foo/changed.txt", download("changed.txt", 1000), objectVersion{Size: 999}))
+		assert.NoFileExists(t, lc.entryPath("/foo/changed.txt"))
+	})
+
+	t.Run("corrupt-entries-are-evicted", func(t *testing.T) {
+		version := objectVersion{Size: 1000}
+		require.NoError(t, lc.put("/foo/corrupt.txt", download("corrupt.txt", 1000), version))
+		entryPath := lc.entryPath("/foo/corrupt.txt")
+		require.NoError(t, os.WriteFile(entryPath, []byte("garbage"), 0644))
+
+		dest := filepath.Join(t.TempDir(), "corrupt.txt")
+		_, ok := lc.get("/foo/corrupt.txt", dest, version)
+		assert.False(t, ok)
+		assert.NoFileExists(t, dest)
+		assert.NoFileExists(t, entryPath)
+		assert.NoFileExists(t, entryPath+localCacheMetaExt)
+	})
+
+	t.Run("least-recently-used-are-pruned", func(t *testing.T) {
+		require.NoError(t, lc.put("/lru/old", download("old", 400*1024), objectVersion{Size: 400 * 1024}))
+		require.NoError(t, lc.put("/lru/recent", download("recent", 400*1024), objectVersion{Size: 400 * 1024}))
+		// Make the first object the least recently used regardless of the clock's resolution
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(lc.entryPath("/lru/old")+localCacheMetaExt, past, past))
+
+		require.NoError(t, lc.put("/lru/new", download("new", 400*1024), objectVersion{Size: 400 * 1024}))
+		assert.NoFileExists(t, lc.entryPath("/lru/old"))
+		assert.FileExists(t, lc.entryPath("/lru/recent"))
+		assert.FileExists(t, lc.entryPath("/lru/new"))
+
+		// Objects larger than the cache aren't cached
+		require.NoError(t, lc.put("/lru/huge", download("huge", 2*1024*1024), objectVersion{Size: 2 * 1024 * 1024}))
+		assert.NoFileExists(t, lc.entryPath("/lru/huge"))
+	})
+}
+
+func TestLocalCacheUserDirMustBePrivate(t *testing.T) {
+	location := t.TempDir()
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+	userDir := filepath.Join(location, "users", currentUser.Uid)
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.Chmod(userDir, 0777))
+
+	_, err = getLocalCacheUserDir(location)
+	assert.Error(t, err)
+}
+
+func TestGetObjectVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path != "/foo/bar.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "1000")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverUrl, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	// The first server is unreachable, so the second one is checked
+	unreachable := *serverUrl
+	unreachable.Host = "127.0.0.1:1"
+	transfers := []TransferDetails{{Url: unreachable}, {Url: *serverUrl}}
+
+	version, err := getObjectVersion(context.Background(), transfers, "/foo/bar.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, objectVersion{Size: 1000, ETag: `"v1"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}, version)
+
+	_, err = getObjectVersion(context.Background(), transfers, "/foo/missing.txt", "")
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,6 +39,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/namespaces"
 	"github.com/pelicanplatform/pelican/param"
@@ -50,10 +52,38 @@ type OptionsStruct struct {
 	Plugin       bool
 	Token        string
 	Version      string
+	// Never look up a token; downloads from namespaces that require one fail with
+	// ErrTokenRequired.  Used by services downloading on behalf of others, which must
+	// not hand out objects with their own credentials.
+	NoTokens bool
+	// Show a full-screen terminal UI with the progress of every file instead of
+	// the progress bars
+	TUI bool
+	// Write the progress of every file to stderr as newline-delimited JSON
+	// ProgressEvents instead of showing the progress bars
+	ProgressJSON bool
+	// Encrypt uploads with EncryptionKey before they leave the client
+	Encrypt bool
+	// The key encrypting uploads and decrypting the encrypted objects downloaded; if
+	// unset, encrypted objects are downloaded as they're stored
+	EncryptionKey []byte
 }
 
 var ObjectClientOptions OptionsStruct
 
+// Returned when a transfer is abandoned because it ran past Client.TransferTimeout or the
+// deadline of the context it was started with
+var ErrTransferDeadlineExceeded = errors.New("transfer did not complete before its deadline")
+
+// Returned when downloading an object that needs a token while ObjectClientOptions.NoTokens is set
+var ErrTokenRequired = errors.New("reading from this namespace requires a token")
+
+// Returned when every source of a download responded that the object doesn't exist
+var ErrObjectNotFound = errors.New("the object doesn't exist")
+
+// Returned when writing to a namespace none of whose origins accept writes
+var ErrReadOnlyNamespace = errors.New("the namespace is read-only")
+
 var (
 	version string
 )
@@ -98,17 +128,48 @@ func getTokenName(destination *url.URL) (scheme, tokenName string) {
 	return
 }
 
+// Bound a transfer by Client.TransferTimeout, in addition to any deadline of the caller's context
+func withTransferTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := param.Client_TransferTimeout.GetDurationIn(param.FromContext(ctx)); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// If a failed transfer ran past its deadline, replace its error with one saying so; the
+// underlying error is usually just a canceled request
+func checkTransferDeadline(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	deadlineErr := fmt.Errorf("%w (%s)", ErrTransferDeadlineExceeded, deadline.Format(time.RFC3339))
+	AddError(deadlineErr)
+	return deadlineErr
+}
+
 // Do writeback to stash using SciTokens
-func doWriteBack(source string, destination *url.URL, namespace namespaces.Namespace, recursive bool, projectName string) (transferResults []TransferResults, err error) {
+func doWriteBack(ctx context.Context, source string, destination *url.URL, namespace namespaces.Namespace, recursive bool, projectName string) (transferResults []TransferResults, err error) {
 
 	scitoken_contents, err := getToken(destination, namespace, true, "")
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get token for write-back: %v", err)
 	}
 	if recursive {
-		return UploadDirectory(source, destination, scitoken_contents, namespace, projectName)
+		return UploadDirectory(ctx, source, destination, scitoken_contents, namespace, projectName)
 	} else {
-		transferResult, err := UploadFile(source, destination, scitoken_contents, namespace, projectName)
+		if ObjectClientOptions.ProgressJSON {
+			monitor := startProgressJSON([]string{source})
+			defer monitor.stop()
+		}
+		getTransferMonitor().started(source)
+		transferResult, err := UploadFile(ctx, source, destination, scitoken_contents, namespace, projectName)
+		tokens := newTokenManager(scitoken_contents, destination, namespace, true, "")
+		if err != nil && tokens.refreshIfRejected(err, scitoken_contents) {
+			getTransferMonitor().attemptFailed(source, err.Error())
+			transferResult, err = UploadFile(ctx, source, destination, tokens.get(), namespace, projectName)
+		}
+		getTransferMonitor().finished(source, transferResult.TransferedBytes, err)
 		transferResults = append(transferResults, transferResult)
 		return transferResults, err
 	}
@@ -119,14 +180,20 @@ func doWriteBack(source string, destination *url.URL, namespace namespaces.Names
 // If token_name is not empty, it will be used as the token name.
 // If token_name is empty, the token name will be determined from the destination URL (if possible) using getTokenName
 func getToken(destination *url.URL, namespace namespaces.Namespace, isWrite bool, token_name string) (string, error) {
+	operation := config.TokenSharedRead
+	if isWrite {
+		operation = config.TokenSharedWrite
+	}
+	return getTokenForOperation(destination, namespace, operation, token_name)
+}
+
+// getTokenForOperation is getToken for a specific operation: if a token has to be
+// generated, it's one authorizing the operation on the destination
+func getTokenForOperation(destination *url.URL, namespace namespaces.Namespace, operation config.TokenOperation, token_name string) (string, error) {
 	if token_name == "" {
 		_, token_name = getTokenName(destination)
 	}
 
-	type tokenJson struct {
-		AccessKey string `json:"access_token"`
-		ExpiresIn int    `json:"expires_in"`
-	}
 	/*
 		Search for the location of the authentiction token.  It can be set explicitly on the command line (TODO),
 		with the environment variable "TOKEN", or it can be searched in the standard HTCondor directory pointed
@@ -137,43 +204,9 @@ func getToken(destination *url.URL, namespace namespaces.Namespace, isWrite bool
 		token_location = ObjectClientOptions.Token
 		log.Debugln("Getting token location from command line:", ObjectClientOptions.Token)
 	} else {
-
-		// WLCG Token Discovery
-		if bearerToken, isBearerTokenSet := os.LookupEnv("BEARER_TOKEN"); isBearerTokenSet {
+		var bearerToken string
+		if bearerToken, token_location = discoverWLCGToken(); bearerToken != "" {
 			return bearerToken, nil
-		} else if bearerTokenFile, isBearerTokenFileSet := os.LookupEnv("BEARER_TOKEN_FILE"); isBearerTokenFileSet {
-			if _, err := os.Stat(bearerTokenFile); err != nil {
-				log.Warningln("Environment variable BEARER_TOKEN_FILE is set, but file being point to does not exist:", err)
-			} else {
-				token_location = bearerTokenFile
-			}
-		}
-		if xdgRuntimeDir, xdgRuntimeDirSet := os.LookupEnv("XDG_RUNTIME_DIR"); token_location == "" && xdgRuntimeDirSet {
-			// Get the uid
-			uid := os.Getuid()
-			tmpTokenPath := filepath.Join(xdgRuntimeDir, "bt_u"+strconv.Itoa(uid))
-			if _, err := os.Stat(tmpTokenPath); err == nil {
-				token_location = tmpTokenPath
-			}
-		}
-
-		// Check for /tmp/bt_u<uid>
-		if token_location == "" {
-			uid := os.Getuid()
-			tmpTokenPath := "/tmp/bt_u" + strconv.Itoa(uid)
-			if _, err := os.Stat(tmpTokenPath); err == nil {
-				token_location = tmpTokenPath
-			}
-		}
-
-		// Backwards compatibility for getting scitokens
-		// If TOKEN is not set in environment, and _CONDOR_CREDS is set, then...
-		if tokenFile, isTokenSet := os.LookupEnv("TOKEN"); isTokenSet && token_location == "" {
-			if _, err := os.Stat(tokenFile); err != nil {
-				log.Warningln("Environment variable TOKEN is set, but file being point to does not exist:", err)
-			} else {
-				token_location = tokenFile
-			}
 		}
 
 		// Finally, look in the HTCondor runtime
@@ -183,10 +216,7 @@ func getToken(destination *url.URL, namespace namespaces.Namespace, isWrite bool
 
 		if token_location == "" {
 			if !ObjectClientOptions.Plugin {
-				opts := config.TokenGenerationOpts{Operation: config.TokenSharedRead}
-				if isWrite {
-					opts.Operation = config.TokenSharedWrite
-				}
+				opts := config.TokenGenerationOpts{Operation: operation}
 				value, err := AcquireToken(destination, namespace, opts)
 				if err == nil {
 					return value, nil
@@ -205,6 +235,59 @@ func getToken(destination *url.URL, namespace namespaces.Namespace, isWrite bool
 		}
 	}
 
+	return readTokenFile(token_location)
+}
+
+// Find the token following the WLCG bearer token discovery: the token itself if it's
+// in the environment, otherwise the location of the file holding it, if any
+func discoverWLCGToken() (token string, location string) {
+	if bearerToken, isBearerTokenSet := os.LookupEnv("BEARER_TOKEN"); isBearerTokenSet {
+		return bearerToken, ""
+	} else if bearerTokenFile, isBearerTokenFileSet := os.LookupEnv("BEARER_TOKEN_FILE"); isBearerTokenFileSet {
+		if _, err := os.Stat(bearerTokenFile); err != nil {
+			log.Warningln("Environment variable BEARER_TOKEN_FILE is set, but file being point to does not exist:", err)
+		} else {
+			location = bearerTokenFile
+		}
+	}
+	if xdgRuntimeDir, xdgRuntimeDirSet := os.LookupEnv("XDG_RUNTIME_DIR"); location == "" && xdgRuntimeDirSet {
+		// Get the uid
+		uid := os.Getuid()
+		tmpTokenPath := filepath.Join(xdgRuntimeDir, "bt_u"+strconv.Itoa(uid))
+		if _, err := os.Stat(tmpTokenPath); err == nil {
+			location = tmpTokenPath
+		}
+	}
+
+	// Check for /tmp/bt_u<uid>
+	if location == "" {
+		uid := os.Getuid()
+		tmpTokenPath := "/tmp/bt_u" + strconv.Itoa(uid)
+		if _, err := os.Stat(tmpTokenPath); err == nil {
+			location = tmpTokenPath
+		}
+	}
+
+	// Backwards compatibility for getting scitokens
+	// If TOKEN is not set in environment, and _CONDOR_CREDS is set, then...
+	if tokenFile, isTokenSet := os.LookupEnv("TOKEN"); isTokenSet && location == "" {
+		if _, err := os.Stat(tokenFile); err != nil {
+			log.Warningln("Environment variable TOKEN is set, but file being point to does not exist:", err)
+		} else {
+			location = tokenFile
+		}
+	}
+	return
+}
+
+// Read the token from the file; the file is either the JSON written when the token
+// was acquired or holds only the token
+func readTokenFile(token_location string) (string, error) {
+	type tokenJson struct {
+		AccessKey string `json:"access_token"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+
 	//Read in the JSON
 	log.Debug("Opening token file: " + token_location)
 	tokenContents, err := os.ReadFile(token_location)
@@ -256,7 +339,7 @@ func CheckOSDF(destination string, methods []string) (remoteSize uint64, err err
 	if (dest_uri.Scheme == "osdf" || dest_uri.Scheme == "stash") && dest_uri.Host != "" {
 		dest_uri.Path = path.Clean("/" + dest_uri.Host + "/" + dest_uri.Path)
 		dest_uri.Host = ""
-	} else if dest_uri.Scheme == "pelican" {
+	} else if dest_uri.Scheme == "pelican" && !usingStaticFederation() {
 		federationUrl, _ := url.Parse(dest_uri.String())
 		federationUrl.Scheme = "https"
 		federationUrl.Path = ""
@@ -291,12 +374,12 @@ func CheckOSDF(destination string, methods []string) (remoteSize uint64, err err
 func GetCacheHostnames(testFile string) (urls []string, err error) {
 
 	directorUrl := param.Federation_DirectorUrl.GetString()
-	ns, err := getNamespaceInfo(testFile, directorUrl, false)
+	ns, err := getNamespaceInfo(testFile, directorUrl, false, -1)
 	if err != nil {
 		return
 	}
 
-	caches, err := GetCachesFromNamespace(ns, directorUrl != "")
+	caches, err := GetCachesFromNamespace(ns, hasSortedCaches())
 	if err != nil {
 		return
 	}
@@ -307,6 +390,9 @@ func GetCacheHostnames(testFile string) (urls []string, err error) {
 			host := strings.Split(url_string, ":")[0]
 			urls = append(urls, host)
 		} else if cache, ok := cacheGeneric.(namespaces.DirectorCache); ok {
+			if cache.FallbackOrigin {
+				continue
+			}
 			cacheUrl, err := url.Parse(cache.EndpointUrl)
 			if err != nil {
 				log.Debugln("Failed to parse returned cache as a URL:", cacheUrl)
@@ -415,10 +501,31 @@ func discoverHTCondorToken(tokenName string) string {
 	return tokenLocation
 }
 
+// Get the size of the local file to upload, or -1 if it's a directory or can't be read;
+// the upload then fails or is reported later, when the transfer itself is attempted
+func getUploadSize(localPath string) int64 {
+	info, err := os.Stat(localPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return -1
+	}
+	return info.Size()
+}
+
 // Retrieve federation namespace information for a given URL.
-// If OSDFDirectorUrl is non-empty, then the namespace information will be pulled from the director;
+// If Client.StaticFederationFile is set, the namespace information is pulled from the static federation;
+// otherwise, if OSDFDirectorUrl is non-empty, then the namespace information will be pulled from the director;
 // otherwise, it is pulled from topology.
-func getNamespaceInfo(resourcePath, OSDFDirectorUrl string, isPut bool) (ns namespaces.Namespace, err error) {
+// For a PUT, uploadSize is the size of the object to write, reported to the director so it can
+// enforce the namespace's upload policy up front; it's -1 if the size isn't known.
+func getNamespaceInfo(resourcePath, OSDFDirectorUrl string, isPut bool, uploadSize int64) (ns namespaces.Namespace, err error) {
+	// A static federation replaces the director entirely
+	if usingStaticFederation() {
+		ns, err = getNamespaceFromStaticFederation(resourcePath, isPut)
+		if err != nil {
+			AddError(err)
+		}
+		return
+	}
 	// If we have a director set, go through that for namespace info, otherwise use topology
 	if OSDFDirectorUrl != "" {
 		log.Debugln("Will query director at", OSDFDirectorUrl, "for object", resourcePath)
@@ -426,11 +533,19 @@ func getNamespaceInfo(resourcePath, OSDFDirectorUrl string, isPut bool) (ns name
 		if isPut {
 			verb = "PUT"
 		}
+		header := http.Header{}
+		if isPut && uploadSize >= 0 {
+			header.Set(common.ObjectSizeHeader, strconv.FormatInt(uploadSize, 10))
+			// The cache needs the whole object before acknowledging it, so only uploads of known size are written back
+			if param.Client_EnableWriteBackUploads.GetBool() {
+				header.Set(common.ClientFeaturesHeader, common.FeatureCacheWriteBack)
+			}
+		}
 		var dirResp *http.Response
-		dirResp, err = queryDirector(verb, resourcePath, OSDFDirectorUrl)
+		dirResp, err = queryDirectorWithHeader(verb, resourcePath, OSDFDirectorUrl, header)
 		if err != nil {
 			if isPut && dirResp != nil && dirResp.StatusCode == 405 {
-				err = errors.New("Error 405: No writeable origins were found")
+				err = fmt.Errorf("Error 405: No writeable origins were found: %w", ErrReadOnlyNamespace)
 				AddError(err)
 				return
 			} else {
@@ -455,6 +570,11 @@ func getNamespaceInfo(resourcePath, OSDFDirectorUrl string, isPut bool) (ns name
 				return
 			}
 			ns.WriteBackHost = "https://" + writeBackUrl.Host
+			// Uploads redirected to a cache go to its write-back API rather than the object's path
+			if strings.HasPrefix(writeBackUrl.Path, common.WriteBackAPIPath+"/") {
+				log.Debugln("The director redirected the upload to cache", writeBackUrl.Host, "for write-back")
+				ns.WriteBackHost += common.WriteBackAPIPath
+			}
 		}
 		return
 	} else {
@@ -473,8 +593,10 @@ func getNamespaceInfo(resourcePath, OSDFDirectorUrl string, isPut bool) (ns name
 localObject: the source file/directory you would like to upload
 remoteDestination: the end location of the upload
 recursive: a boolean indicating if the source is a directory or not
+
+The transfer is abandoned once ctx is done or Client.TransferTimeout passes.
 */
-func DoPut(localObject string, remoteDestination string, recursive bool) (transferResults []TransferResults, err error) {
+func DoPut(ctx context.Context, localObject string, remoteDestination string, recursive bool) (transferResults []TransferResults, err error) {
 	isPut := true
 	// First, create a handler for any panics that occur
 	defer func() {
@@ -488,6 +610,8 @@ func DoPut(localObject string, remoteDestination string, recursive bool) (transf
 			AddError(errors.New(ret))
 		}
 	}()
+	ctx, cancel := withTransferTimeout(ctx)
+	defer cancel()
 
 	// Parse the source and destination with URL parse
 	localObjectUrl, err := url.Parse(localObject)
@@ -513,7 +637,7 @@ func DoPut(localObject string, remoteDestination string, recursive bool) (transf
 				log.Errorln("Failed to join remote destination url path:", err)
 				return nil, err
 			}
-		} else if remoteDestUrl.Scheme == "pelican" {
+		} else if remoteDestUrl.Scheme == "pelican" && !usingStaticFederation() {
 
 			config.SetFederation(config.FederationDiscovery{})
 			federationUrl, _ := url.Parse(remoteDestUrl.String())
@@ -544,14 +668,41 @@ func DoPut(localObject string, remoteDestination string, recursive bool) (transf
 		remoteDestination = strings.TrimPrefix(remoteDestination, remoteDestScheme+"://")
 	}
 
-	ns, err := getNamespaceInfo(remoteDestination, directorUrl, isPut)
+	ns, err := getNamespaceInfo(remoteDestination, directorUrl, isPut, getUploadSize(localObjectUrl.Path))
 	if err != nil {
 		log.Errorln(err)
 		return nil, errors.New("Failed to get namespace information from source")
 	}
-	uploadedBytes, err := doWriteBack(localObjectUrl.Path, remoteDestUrl, ns, recursive, "")
+
+	source := localObjectUrl.Path
+	if ObjectClientOptions.Encrypt {
+		if remoteDestUrl.Query().Get("pack") != "" {
+			return nil, errors.New("Encrypted uploads can't be packed")
+		}
+		if len(ObjectClientOptions.EncryptionKey) == 0 {
+			return nil, errors.New("Encrypting an upload needs an encryption key")
+		}
+		encrypted, cleanup, err := encryptUpload(source, recursive, ObjectClientOptions.EncryptionKey)
+		if err != nil {
+			AddError(err)
+			return nil, err
+		}
+		defer cleanup()
+		source = encrypted
+		// A directory's headers are uploaded with its files; a single file's header is
+		// uploaded first so the object is never there without it
+		if !recursive {
+			headerUrl := *remoteDestUrl
+			headerUrl.Path += encryptionHeaderSuffix
+			if _, err = doWriteBack(ctx, source+encryptionHeaderSuffix, &headerUrl, ns, false, ""); err != nil {
+				AddError(err)
+				return nil, checkTransferDeadline(ctx, err)
+			}
+		}
+	}
+	uploadedBytes, err := doWriteBack(ctx, source, remoteDestUrl, ns, recursive, "")
 	AddError(err)
-	return uploadedBytes, err
+	return uploadedBytes, checkTransferDeadline(ctx, err)
 
 }
 
@@ -561,8 +712,10 @@ func DoPut(localObject string, remoteDestination string, recursive bool) (transf
 remoteObject: the source file/directory you would like to upload
 localDestination: the end location of the upload
 recursive: a boolean indicating if the source is a directory or not
+
+The transfer is abandoned once ctx is done or Client.TransferTimeout passes.
 */
-func DoGet(remoteObject string, localDestination string, recursive bool) (transferResults []TransferResults, err error) {
+func DoGet(ctx context.Context, remoteObject string, localDestination string, recursive bool) (transferResults []TransferResults, err error) {
 	isPut := false
 	// First, create a handler for any panics that occur
 	defer func() {
@@ -576,6 +729,15 @@ func DoGet(remoteObject string, localDestination string, recursive bool) (transf
 			AddError(errors.New(ret))
 		}
 	}()
+	ctx, cancel := withTransferTimeout(ctx)
+	defer cancel()
+
+	// A persistent identifier, such as a DOI, stands for the federation path it resolves to
+	if common.IsPersistentIdentifier(remoteObject) {
+		if remoteObject, err = resolvePersistentIdentifier(ctx, remoteObject, param.Federation_DirectorUrl.GetString()); err != nil {
+			return nil, err
+		}
+	}
 
 	// Parse the source with URL parse
 	remoteObject, remoteObjectScheme := correctURLWithUnderscore(remoteObject)
@@ -596,7 +758,7 @@ func DoGet(remoteObject string, localDestination string, recursive bool) (transf
 				log.Errorln("Failed to join source url path:", err)
 				return nil, err
 			}
-		} else if remoteObjectUrl.Scheme == "pelican" {
+		} else if remoteObjectUrl.Scheme == "pelican" && !usingStaticFederation() {
 
 			config.SetFederation(fd)
 			federationUrl, _ := url.Parse(remoteObjectUrl.String())
@@ -630,7 +792,7 @@ func DoGet(remoteObject string, localDestination string, recursive bool) (transf
 
 	directorUrl := param.Federation_DirectorUrl.GetString()
 
-	ns, err := getNamespaceInfo(remoteObject, directorUrl, isPut)
+	ns, err := getNamespaceInfo(remoteObject, directorUrl, isPut, -1)
 	if err != nil {
 		log.Errorln(err)
 		return nil, errors.New("Failed to get namespace information from source")
@@ -664,9 +826,15 @@ func DoGet(remoteObject string, localDestination string, recursive bool) (transf
 	_, token_name := getTokenName(remoteObjectUrl)
 
 	var downloaded int64
-	if transferResults, err = download_http(remoteObjectUrl, localDestination, &payload, ns, recursive, token_name); err == nil {
+	if transferResults, err = download_http(ctx, remoteObjectUrl, localDestination, &payload, ns, recursive, token_name); err == nil {
 		success = true
 	}
+	if success && len(ObjectClientOptions.EncryptionKey) > 0 {
+		if err = decryptDownload(ctx, remoteObjectUrl.Path, localDestination, ns, recursive, token_name); err != nil {
+			AddError(err)
+			return nil, err
+		}
+	}
 
 	payload.end1 = time.Now().Unix()
 
@@ -685,14 +853,36 @@ func DoGet(remoteObject string, localDestination string, recursive bool) (transf
 	}
 
 	if !success {
-		return nil, errors.New("failed to download file")
+		return nil, checkTransferDeadline(ctx, errors.New("failed to download file"))
 	} else {
 		return transferResults, err
 	}
 }
 
+// Download the object at objectPath in the configured federation to dest.  Unlike DoGet,
+// it doesn't modify the global configuration, so services downloading objects on behalf
+// of others may call it concurrently.  The director and the transfer timeout are taken
+// from the configuration context attached to ctx with param.WithContext, if any.
+func DownloadObject(ctx context.Context, objectPath string, dest string) (transferResults []TransferResults, err error) {
+	ctx, cancel := withTransferTimeout(ctx)
+	defer cancel()
+
+	ns, err := getNamespaceInfo(objectPath, param.Federation_DirectorUrl.GetStringIn(param.FromContext(ctx)), false, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace information for %s: %w", objectPath, err)
+	}
+	payload := payloadStruct{
+		version:  version,
+		filename: objectPath,
+	}
+	transferResults, err = download_http(ctx, &url.URL{Path: objectPath}, dest, &payload, ns, false, "")
+	return transferResults, checkTransferDeadline(ctx, err)
+}
+
 // Start the transfer, whether read or write back. Primarily used for backwards compatibility
-func DoStashCPSingle(sourceFile string, destination string, methods []string, recursive bool) (transferResults []TransferResults, err error) {
+//
+// The transfer is abandoned once ctx is done or Client.TransferTimeout passes.
+func DoStashCPSingle(ctx context.Context, sourceFile string, destination string, methods []string, recursive bool) (transferResults []TransferResults, err error) {
 
 	// First, create a handler for any panics that occur
 	defer func() {
@@ -706,6 +896,8 @@ func DoStashCPSingle(sourceFile string, destination string, methods []string, re
 			AddError(errors.New(ret))
 		}
 	}()
+	ctx, cancel := withTransferTimeout(ctx)
+	defer cancel()
 
 	// Parse the source and destination with URL parse
 	sourceFile, source_scheme := correctURLWithUnderscore(sourceFile)
@@ -730,7 +922,7 @@ func DoStashCPSingle(sourceFile string, destination string, methods []string, re
 	if source_url.Host != "" {
 		if source_url.Scheme == "osdf" || source_url.Scheme == "stash" {
 			source_url.Path = "/" + path.Join(source_url.Host, source_url.Path)
-		} else if source_url.Scheme == "pelican" {
+		} else if source_url.Scheme == "pelican" && !usingStaticFederation() {
 			config.SetFederation(config.FederationDiscovery{})
 			federationUrl, _ := url.Parse(source_url.String())
 			federationUrl.Scheme = "https"
@@ -746,7 +938,7 @@ func DoStashCPSingle(sourceFile string, destination string, methods []string, re
 	if dest_url.Host != "" {
 		if dest_url.Scheme == "osdf" || dest_url.Scheme == "stash" {
 			dest_url.Path = "/" + path.Join(dest_url.Host, dest_url.Path)
-		} else if dest_url.Scheme == "pelican" {
+		} else if dest_url.Scheme == "pelican" && !usingStaticFederation() {
 			config.SetFederation(config.FederationDiscovery{})
 			federationUrl, _ := url.Parse(dest_url.String())
 			federationUrl.Scheme = "https"
@@ -788,14 +980,14 @@ func DoStashCPSingle(sourceFile string, destination string, methods []string, re
 
 	if isPut {
 		log.Debugln("Detected object write to remote federation object", dest_url.Path)
-		ns, err := getNamespaceInfo(dest_url.Path, OSDFDirectorUrl, isPut)
+		ns, err := getNamespaceInfo(dest_url.Path, OSDFDirectorUrl, isPut, getUploadSize(source_url.Path))
 		if err != nil {
 			log.Errorln(err)
 			return nil, errors.New("Failed to get namespace information from destination")
 		}
-		transferResults, err := doWriteBack(source_url.Path, dest_url, ns, recursive, payload.ProjectName) //TODO dowriteback transferResults!!!!!
+		transferResults, err := doWriteBack(ctx, source_url.Path, dest_url, ns, recursive, payload.ProjectName) //TODO dowriteback transferResults!!!!!
 		AddError(err)
-		return transferResults, err
+		return transferResults, checkTransferDeadline(ctx, err)
 	}
 
 	if dest_url.Scheme == "file" {
@@ -810,7 +1002,7 @@ func DoStashCPSingle(sourceFile string, destination string, methods []string, re
 		sourceFile = "/" + sourceFile
 	}
 
-	ns, err := getNamespaceInfo(sourceFile, OSDFDirectorUrl, isPut)
+	ns, err := getNamespaceInfo(sourceFile, OSDFDirectorUrl, isPut, -1)
 	if err != nil {
 		log.Errorln(err)
 		return nil, errors.New("Failed to get namespace information from source")
@@ -854,7 +1046,7 @@ Loop:
 		switch method {
 		case "http":
 			log.Info("Trying HTTP...")
-			if transferResults, err = download_http(source_url, destination, &payload, ns, recursive, token_name); err == nil {
+			if transferResults, err = download_http(ctx, source_url, destination, &payload, ns, recursive, token_name); err == nil {
 				success = true
 				break Loop
 			}
@@ -878,7 +1070,7 @@ Loop:
 		return transferResults, nil
 	} else {
 		payload.status = "Fail"
-		return transferResults, errors.New("All methods failed! Unable to download file.")
+		return transferResults, checkTransferDeadline(ctx, errors.New("All methods failed! Unable to download file."))
 	}
 }
 
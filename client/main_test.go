@@ -19,6 +19,7 @@
 package client
 
 import (
+	"context"
 	"net"
 	"net/url"
 	"os"
@@ -26,12 +27,14 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/namespaces"
+	"github.com/pelicanplatform/pelican/param"
 )
 
 // TestGetIps calls main.get_ips with a hostname, checking
@@ -333,3 +336,22 @@ func TestParseNoJobAd(t *testing.T) {
 	payload := payloadStruct{}
 	parse_job_ad(&payload)
 }
+
+func TestWithTransferTimeout(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	ctx, cancel := withTransferTimeout(context.Background())
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	// A configuration context attached to the caller's context takes precedence over the global configuration
+	pc := param.NewContext()
+	pc.Set("Client.TransferTimeout", "1h")
+	ctx, cancel = withTransferTimeout(param.WithContext(context.Background(), pc))
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	assert.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+}
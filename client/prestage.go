@@ -0,0 +1,263 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The staging status of an object, as reported by the staging API of an origin in "hsm" mode
+type StageStatus struct {
+	Path    string `json:"path"`
+	Online  bool   `json:"online"`
+	Staging bool   `json:"staging,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Looks up the origins serving objects through the director
+type originLocator struct {
+	directorUrl string
+	// The web URL of each origin, keyed by the host of its data URL
+	webUrls map[string]string
+}
+
+// Find the web URL of the origin the director redirects reads of objectPath to
+func (l *originLocator) originWebUrl(ctx context.Context, objectPath string) (string, error) {
+	resp, err := queryDirector("GET", "/api/v1.0/director/origin"+objectPath, l.directorUrl)
+	if err != nil {
+		return "", err
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Host == "" {
+		return "", errors.Errorf("the director didn't redirect %s to an origin", objectPath)
+	}
+
+	if l.webUrls == nil {
+		serversUrl, err := url.JoinPath(l.directorUrl, "api", "v1.0", "director_ui", "servers")
+		if err != nil {
+			return "", err
+		}
+		servers := []struct {
+			URL    string `json:"url"`
+			WebURL string `json:"webUrl"`
+		}{}
+		if err = getJSON(ctx, serversUrl+"?server_type=origin", &servers); err != nil {
+			return "", errors.Wrap(err, "failed to list the federation's origins")
+		}
+		l.webUrls = make(map[string]string)
+		for _, server := range servers {
+			if serverUrl, err := url.Parse(server.URL); err == nil && server.WebURL != "" {
+				l.webUrls[serverUrl.Host] = server.WebURL
+			}
+		}
+	}
+	webUrl, ok := l.webUrls[location.Host]
+	if !ok {
+		return "", errors.Errorf("the origin %s serving %s has no web API", location.Host, objectPath)
+	}
+	return webUrl, nil
+}
+
+// Fetch the JSON document at docUrl into res
+func getJSON(ctx context.Context, docUrl string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docUrl, nil)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Transport: traceTransport(config.GetTransport())}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, res)
+}
+
+// Check on the staging of an object through the origin's staging API; a POST also
+// starts staging the object if it's offline.  Returns the status and how long to
+// wait before checking again.
+func queryStage(ctx context.Context, method, originWebUrl, objectPath string) (StageStatus, time.Duration, error) {
+	status := StageStatus{Path: objectPath}
+	stageUrl, err := url.JoinPath(originWebUrl, "api", "v1.0", "origin-api", "stage", objectPath)
+	if err != nil {
+		return status, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, stageUrl, nil)
+	if err != nil {
+		return status, 0, err
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+ObjectClientOptions.Version)
+	client := http.Client{Transport: traceTransport(config.GetTransport())}
+	resp, err := client.Do(req)
+	if err != nil {
+		return status, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return status, 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		if err = json.Unmarshal(body, &status); err != nil {
+			return status, 0, errors.Wrap(err, "failed to parse the origin's response")
+		}
+		if resp.StatusCode == http.StatusAccepted {
+			return status, newStagingError(resp).RetryAfter, nil
+		}
+		return status, 0, nil
+	default:
+		errResp := struct {
+			Error string `json:"error"`
+		}{}
+		if json.Unmarshal(body, &errResp) != nil || errResp.Error == "" {
+			errResp.Error = strings.TrimSpace(string(body))
+		}
+		return status, 0, errors.Errorf("the origin answered with HTTP status %d: %s", resp.StatusCode, errResp.Error)
+	}
+}
+
+// Bring the objects online at the origins of their namespaces, without downloading them.
+// The objects are named by pelican://, osdf:// or federation-relative URLs.  Unless wait
+// is false, waits until every object is online, failed to stage, or ctx is done.  The
+// report function, if not nil, is called with the status of each object when it's known
+// to be online or have failed.
+func PrestageObjects(ctx context.Context, objects []string, wait bool, report func(StageStatus)) (statuses []StageStatus, err error) {
+	type pendingObject struct {
+		originWebUrl string
+		index        int
+	}
+	locators := make(map[string]*originLocator)
+	pending := []pendingObject{}
+	nextCheck := time.Duration(0)
+	finish := func(idx int, status StageStatus) {
+		statuses[idx] = status
+		if report != nil {
+			report(status)
+		}
+	}
+
+	statuses = make([]StageStatus, len(objects))
+	for idx, object := range objects {
+		objectUrl, err := url.Parse(object)
+		if err != nil {
+			finish(idx, StageStatus{Path: object, Error: err.Error()})
+			continue
+		}
+		directorUrl, err := getDirectorFromUrl(objectUrl)
+		if err == nil && directorUrl == "" {
+			directorUrl = param.Federation_DirectorUrl.GetString()
+		}
+		if err != nil || directorUrl == "" {
+			if err == nil {
+				err = errors.New("no director is configured")
+			}
+			finish(idx, StageStatus{Path: object, Error: err.Error()})
+			continue
+		}
+		objectPath := "/" + strings.TrimPrefix(objectUrl.Path, "/")
+		locator, ok := locators[directorUrl]
+		if !ok {
+			locator = &originLocator{directorUrl: directorUrl}
+			locators[directorUrl] = locator
+		}
+
+		originWebUrl, err := locator.originWebUrl(ctx, objectPath)
+		if err != nil {
+			finish(idx, StageStatus{Path: objectPath, Error: err.Error()})
+			continue
+		}
+		status, retryAfter, err := queryStage(ctx, http.MethodPost, originWebUrl, objectPath)
+		if err != nil {
+			status.Error = err.Error()
+		}
+		if status.Online || status.Error != "" || !wait {
+			finish(idx, status)
+			continue
+		}
+		statuses[idx] = status
+		pending = append(pending, pendingObject{originWebUrl: originWebUrl, index: idx})
+		if nextCheck == 0 || retryAfter < nextCheck {
+			nextCheck = retryAfter
+		}
+	}
+
+	for len(pending) > 0 {
+		log.Infof("Waiting for %d of %d objects to be staged; checking again in %s", len(pending), len(objects), nextCheck)
+		select {
+		case <-ctx.Done():
+			return statuses, errors.Errorf("%d objects were not online before the stage timeout", len(pending))
+		case <-time.After(nextCheck):
+		}
+
+		stillPending := []pendingObject{}
+		nextCheck = defaultStageRetryAfter
+		for _, obj := range pending {
+			status, retryAfter, err := queryStage(ctx, http.MethodGet, obj.originWebUrl, statuses[obj.index].Path)
+			if err != nil {
+				if ctx.Err() != nil {
+					stillPending = append(stillPending, obj)
+					continue
+				}
+				status.Error = err.Error()
+			} else if !status.Online && !status.Staging {
+				// The origin forgot about the request (e.g., it restarted); ask again
+				status, retryAfter, err = queryStage(ctx, http.MethodPost, obj.originWebUrl, statuses[obj.index].Path)
+				if err != nil {
+					status.Error = err.Error()
+				}
+			}
+			if status.Online || status.Error != "" {
+				finish(obj.index, status)
+				continue
+			}
+			stillPending = append(stillPending, obj)
+			if retryAfter > 0 && retryAfter < nextCheck {
+				nextCheck = retryAfter
+			}
+		}
+		pending = stillPending
+	}
+
+	for _, status := range statuses {
+		if status.Error != "" {
+			return statuses, errors.New("failed to stage some of the objects")
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,98 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrestageObjects(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	// The same server plays the director and an origin in "hsm" mode, where
+	// each object is online once it's been checked on twice after being staged
+	checks := make(map[string]int)
+	checksMutex := sync.Mutex{}
+	var svr *httptest.Server
+	svr = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v1.0/director/origin/"):
+			w.Header().Set("Location", svr.URL+strings.TrimPrefix(r.URL.Path, "/api/v1.0/director/origin"))
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		case r.URL.Path == "/api/v1.0/director_ui/servers":
+			assert.Equal(t, "origin", r.URL.Query().Get("server_type"))
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"url": svr.URL, "webUrl": svr.URL}})
+		case strings.HasPrefix(r.URL.Path, "/api/v1.0/origin-api/stage/"):
+			objectPath := strings.TrimPrefix(r.URL.Path, "/api/v1.0/origin-api/stage")
+			if objectPath == "/test/missing.txt" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "Failed to stage the object"})
+				return
+			}
+			checksMutex.Lock()
+			defer checksMutex.Unlock()
+			if r.Method == http.MethodPost && checks[objectPath] == 0 {
+				checks[objectPath] = 1
+			} else if checks[objectPath] > 0 {
+				checks[objectPath]++
+			}
+			status := StageStatus{Path: objectPath, Online: checks[objectPath] > 2, Staging: checks[objectPath] > 0 && checks[objectPath] <= 2}
+			if status.Staging {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusAccepted)
+			}
+			_ = json.NewEncoder(w).Encode(status)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer svr.Close()
+	viper.Set("Federation.DirectorUrl", svr.URL)
+
+	reported := []string{}
+	statuses, err := PrestageObjects(context.Background(), []string{"/test/a.txt", "/test/b.txt"}, true, func(status StageStatus) {
+		reported = append(reported, status.Path)
+	})
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Online)
+	assert.True(t, statuses[1].Online)
+	assert.ElementsMatch(t, []string{"/test/a.txt", "/test/b.txt"}, reported)
+
+	// Without waiting, the objects are only requested
+	statuses, err = PrestageObjects(context.Background(), []string{"/test/c.txt"}, false, nil)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Online)
+	assert.True(t, statuses[0].Staging)
+
+	statuses, err = PrestageObjects(context.Background(), []string{"/test/missing.txt"}, true, nil)
+	assert.Error(t, err)
+	assert.Contains(t, statuses[0].Error, "Failed to stage the object")
+}
@@ -0,0 +1,98 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+type (
+	ProgressEventType string
+
+	// A line of the newline-delimited JSON progress written to stderr when
+	// ObjectClientOptions.ProgressJSON is set, for tools wrapping the client
+	// rather than parsing the progress bars.  The logs are written to stderr too,
+	// so lines that aren't JSON objects should be skipped.
+	ProgressEvent struct {
+		Event ProgressEventType `json:"event"`
+		Time  time.Time         `json:"time"`
+		File  string            `json:"file"`
+		// The 1-based position of the file in the batch and the number of files in it
+		Index int   `json:"index"`
+		Files int   `json:"files"`
+		Bytes int64 `json:"bytes"`
+		// The size of the file and the percent transferred, left out while the size is unknown
+		Total   int64   `json:"total,omitempty"`
+		Percent float64 `json:"percent,omitempty"`
+		// The average rate since the file's transfer started, in bytes per second
+		Rate  float64 `json:"rate"`
+		Error string  `json:"error,omitempty"`
+	}
+)
+
+const (
+	progressStart  ProgressEventType = "start"
+	progressUpdate ProgressEventType = "progress"
+	progressRetry  ProgressEventType = "retry"
+	progressDone   ProgressEventType = "done"
+	progressFailed ProgressEventType = "failed"
+)
+
+// Report the progress of the transfers of the named files as JSON events on
+// stderr until stop is called
+func startProgressJSON(names []string) *transferMonitor {
+	monitor := newTransferMonitor(names, os.Stderr)
+	monitor.events = json.NewEncoder(monitor.out)
+	activeTransferMonitor.Store(monitor)
+	return monitor
+}
+
+func newProgressEvent(eventType ProgressEventType, file *monitoredFile, files int, now time.Time) ProgressEvent {
+	event := ProgressEvent{
+		Event: eventType,
+		Time:  now,
+		File:  file.name,
+		Index: file.index,
+		Files: files,
+		Bytes: file.bytes,
+		Total: file.total,
+	}
+	if file.total > 0 {
+		event.Percent = min(100*float64(file.bytes)/float64(file.total), 100)
+	}
+	if elapsed := now.Sub(file.started).Seconds(); elapsed > 0 && !file.started.IsZero() {
+		event.Rate = float64(file.bytes) / elapsed
+	}
+	if eventType == progressRetry || eventType == progressFailed {
+		event.Error = file.lastError
+	}
+	return event
+}
+
+// Write an event for the file if the progress is reported as JSON.  Must be
+// called with the mutex held.
+func (monitor *transferMonitor) emit(eventType ProgressEventType, file *monitoredFile) {
+	if monitor.events == nil {
+		return
+	}
+	// A failure to write the progress mustn't fail the transfer
+	_ = monitor.events.Encode(newProgressEvent(eventType, file, len(monitor.files), time.Now()))
+}
@@ -0,0 +1,98 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	monitor := newTransferMonitor([]string{"/data/a.txt", "/data/b.txt"}, out)
+	monitor.events = json.NewEncoder(out)
+
+	monitor.started("/data/a.txt")
+	monitor.progress("/data/a.txt", 250, 1000)
+	monitor.finished("/data/a.txt", 1000, nil)
+	monitor.started("/data/b.txt")
+	monitor.progress("/data/b.txt", 100, 0)
+	monitor.attemptFailed("/data/b.txt", "Failed to download from cache-a: timeout")
+	monitor.finished("/data/b.txt", 0, errors.New("failed to download with HTTP"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 7)
+	events := make([]ProgressEvent, len(lines))
+	for idx, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &events[idx]), line)
+	}
+
+	assert.Equal(t, progressStart, events[0].Event)
+	assert.Equal(t, "/data/a.txt", events[0].File)
+	assert.Equal(t, 1, events[0].Index)
+	assert.Equal(t, 2, events[0].Files)
+
+	assert.Equal(t, progressUpdate, events[1].Event)
+	assert.Equal(t, int64(250), events[1].Bytes)
+	assert.Equal(t, int64(1000), events[1].Total)
+	assert.Equal(t, 25.0, events[1].Percent)
+	assert.Greater(t, events[1].Rate, 0.0)
+
+	assert.Equal(t, progressDone, events[2].Event)
+	assert.Equal(t, 100.0, events[2].Percent)
+
+	assert.Equal(t, 2, events[3].Index)
+	// The percent is left out while the size is unknown
+	assert.Equal(t, progressUpdate, events[4].Event)
+	assert.NotContains(t, lines[4], "percent")
+	assert.Empty(t, events[4].Error)
+
+	assert.Equal(t, progressRetry, events[5].Event)
+	assert.Equal(t, "Failed to download from cache-a: timeout", events[5].Error)
+	assert.Equal(t, progressFailed, events[6].Event)
+	assert.Equal(t, "Failed to download from cache-a: timeout", events[6].Error)
+}
+
+func TestProgressEventRate(t *testing.T) {
+	start := time.Now()
+	file := &monitoredFile{name: "/data/a.txt", index: 3, bytes: 2000, total: 8000, started: start}
+	event := newProgressEvent(progressUpdate, file, 5, start.Add(2*time.Second))
+	assert.Equal(t, 1000.0, event.Rate)
+	assert.Equal(t, 25.0, event.Percent)
+	assert.Equal(t, 3, event.Index)
+	assert.Equal(t, 5, event.Files)
+
+	// A file that hasn't started has no rate yet
+	file.started = time.Time{}
+	assert.Zero(t, newProgressEvent(progressUpdate, file, 5, start).Rate)
+}
+
+func TestStopProgressJSON(t *testing.T) {
+	monitor := startProgressJSON([]string{"/data/a.txt"})
+	assert.Equal(t, monitor, getTransferMonitor())
+	monitor.stop()
+	assert.Nil(t, getTransferMonitor())
+}
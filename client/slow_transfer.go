@@ -0,0 +1,256 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+)
+
+const (
+	slowTransferPolicyAdaptive = "adaptive"
+	slowTransferPolicyLegacy   = "legacy"
+
+	// Weight of the newest throughput sample in the moving average
+	throughputEwmaAlpha = 0.3
+	// A transfer running below this fraction of its cache's baseline is considered slow
+	slowBaselineFraction = 0.1
+	// Fail over early only if another cache is predicted to finish this many times sooner
+	failoverSpeedupFactor = 3.0
+
+	// Name of the file, next to the client credentials, that keeps the baselines between runs
+	throughputBaselinesFileName = "throughput-baselines.json"
+)
+
+var (
+	// The historical throughput, in bytes per second, of each cache host, as a
+	// moving average over completed transfers. It is loaded from (and saved to)
+	// disk so that short-lived client invocations benefit from earlier ones.
+	cacheThroughputBaselines      = make(map[string]float64)
+	cacheThroughputBaselinesMutex = sync.RWMutex{}
+
+	loadThroughputBaselinesOnce sync.Once
+)
+
+// The file where the throughput baselines persist, alongside the client credentials
+func getThroughputBaselinesFile() (string, error) {
+	credentialsFile, err := config.GetEncryptedConfigName()
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(credentialsFile) {
+		return "", errors.Errorf("Client credentials location %s is not an absolute path", credentialsFile)
+	}
+	return filepath.Join(filepath.Dir(credentialsFile), throughputBaselinesFileName), nil
+}
+
+// Merge the baselines saved in filename into memory. Baselines already
+// measured by this process take precedence over the saved ones.
+func loadThroughputBaselines(filename string) error {
+	contents, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "Failed to read the saved throughput baselines")
+	}
+	saved := make(map[string]float64)
+	if err = json.Unmarshal(contents, &saved); err != nil {
+		return errors.Wrapf(err, "Failed to parse the saved throughput baselines in %s", filename)
+	}
+	cacheThroughputBaselinesMutex.Lock()
+	defer cacheThroughputBaselinesMutex.Unlock()
+	for host, baseline := range saved {
+		if _, ok := cacheThroughputBaselines[host]; ok || baseline <= 0 || math.IsInf(baseline, 0) || math.IsNaN(baseline) {
+			continue
+		}
+		cacheThroughputBaselines[host] = baseline
+	}
+	return nil
+}
+
+// Atomically save the in-memory baselines to filename
+func saveThroughputBaselines(filename string) error {
+	cacheThroughputBaselinesMutex.RLock()
+	contents, err := json.Marshal(cacheThroughputBaselines)
+	cacheThroughputBaselinesMutex.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "Failed to serialize the throughput baselines")
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return errors.Wrap(err, "Failed to create the directory for the throughput baselines")
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), "."+throughputBaselinesFileName+".*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create a temporary file for the throughput baselines")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Failed to write the throughput baselines")
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to write the throughput baselines")
+	}
+	if err = os.Rename(tmpFile.Name(), filename); err != nil {
+		return errors.Wrap(err, "Failed to save the throughput baselines")
+	}
+	return nil
+}
+
+// Load the saved baselines the first time this process starts a download
+func initThroughputBaselines() {
+	loadThroughputBaselinesOnce.Do(func() {
+		filename, err := getThroughputBaselinesFile()
+		if err != nil {
+			log.Debugln("Not loading saved throughput baselines:", err)
+			return
+		}
+		if err = loadThroughputBaselines(filename); err != nil {
+			log.Warningln(err)
+		}
+	})
+}
+
+// Save the baselines so later client invocations can use them
+func persistThroughputBaselines() {
+	filename, err := getThroughputBaselinesFile()
+	if err != nil {
+		log.Debugln("Not saving throughput baselines:", err)
+		return
+	}
+	if err = saveThroughputBaselines(filename); err != nil {
+		log.Warningln(err)
+	}
+}
+
+func getThroughputBaseline(host string) float64 {
+	cacheThroughputBaselinesMutex.RLock()
+	defer cacheThroughputBaselinesMutex.RUnlock()
+	return cacheThroughputBaselines[host]
+}
+
+func recordThroughputBaseline(host string, bytesPerSecond float64) {
+	if bytesPerSecond <= 0 || math.IsInf(bytesPerSecond, 0) || math.IsNaN(bytesPerSecond) {
+		return
+	}
+	cacheThroughputBaselinesMutex.Lock()
+	defer cacheThroughputBaselinesMutex.Unlock()
+	if baseline, ok := cacheThroughputBaselines[host]; ok {
+		cacheThroughputBaselines[host] = throughputEwmaAlpha*bytesPerSecond + (1-throughputEwmaAlpha)*baseline
+	} else {
+		cacheThroughputBaselines[host] = bytesPerSecond
+	}
+}
+
+// Detects slow transfers by comparing the moving average of a transfer's
+// throughput against the historical baseline of the cache serving it and
+// against the baselines of the caches it could fail over to
+type slowTransferDetector struct {
+	minimum         float64       // Absolute floor in bytes per second
+	baseline        float64       // Historical throughput of this cache; 0 if unknown
+	bestAlternative float64       // Best historical throughput of the remaining sources; 0 if unknown
+	rampup          time.Duration // Grace period at the start of the transfer
+	window          time.Duration // How long the transfer may stay slow before it's aborted
+
+	start      time.Time
+	lastTime   time.Time
+	lastBytes  int64
+	ewma       float64
+	belowSince time.Time
+}
+
+func newSlowTransferDetector(host string, alternatives []TransferDetails, minimum float64, rampup, window time.Duration, start time.Time) *slowTransferDetector {
+	detector := &slowTransferDetector{
+		minimum:  minimum,
+		baseline: getThroughputBaseline(host),
+		rampup:   rampup,
+		window:   window,
+		start:    start,
+		lastTime: start,
+	}
+	for _, alternative := range alternatives {
+		// Slow transfers fail over to other caches only; the origins are for failures
+		if alternative.Url.Host == host || alternative.FallbackOrigin {
+			continue
+		}
+		detector.bestAlternative = math.Max(detector.bestAlternative, getThroughputBaseline(alternative.Url.Host))
+	}
+	return detector
+}
+
+// The throughput below which the transfer is considered slow
+func (d *slowTransferDetector) threshold() float64 {
+	return math.Max(d.minimum, d.baseline*slowBaselineFraction)
+}
+
+// Feed a new sample of the transfer progress to the detector. It returns whether
+// the transfer just became slow (so the user can be warned) and whether it
+// should be aborted in favor of another source.
+func (d *slowTransferDetector) observe(bytesComplete int64, totalBytes int64, now time.Time) (warn bool, abort bool) {
+	elapsed := now.Sub(d.lastTime).Seconds()
+	if elapsed <= 0 {
+		return false, false
+	}
+	rate := float64(bytesComplete-d.lastBytes) / elapsed
+	if d.lastBytes == 0 && d.ewma == 0 {
+		d.ewma = rate
+	} else {
+		d.ewma = throughputEwmaAlpha*rate + (1-throughputEwmaAlpha)*d.ewma
+	}
+	d.lastBytes = bytesComplete
+	d.lastTime = now
+
+	if now.Sub(d.start) < d.rampup {
+		return false, false
+	}
+
+	// If another source is predicted to finish the remainder of the transfer
+	// much sooner, abort now rather than waiting out the window. Partial
+	// downloads are resumed, so only the remaining bytes count.
+	if d.bestAlternative > 0 && totalBytes > 0 && d.ewma > 0 {
+		remaining := float64(totalBytes - bytesComplete)
+		if remaining/d.ewma > failoverSpeedupFactor*(remaining/d.bestAlternative) {
+			return false, true
+		}
+	}
+
+	if d.ewma >= d.threshold() {
+		d.belowSince = time.Time{}
+		return false, false
+	}
+	if d.belowSince.IsZero() {
+		d.belowSince = now
+		return true, false
+	}
+	return false, now.Sub(d.belowSince) >= d.window
+}
+
+// The current moving average of the transfer throughput, in bytes per second
+func (d *slowTransferDetector) rate() float64 {
+	return d.ewma
+}
@@ -0,0 +1,149 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetThroughputBaselines(t *testing.T) {
+	cacheThroughputBaselinesMutex.Lock()
+	defer cacheThroughputBaselinesMutex.Unlock()
+	cacheThroughputBaselines = make(map[string]float64)
+	t.Cleanup(func() {
+		cacheThroughputBaselinesMutex.Lock()
+		defer cacheThroughputBaselinesMutex.Unlock()
+		cacheThroughputBaselines = make(map[string]float64)
+	})
+}
+
+func TestThroughputBaseline(t *testing.T) {
+	resetThroughputBaselines(t)
+
+	assert.Equal(t, 0.0, getThroughputBaseline("cache.example.com"))
+	recordThroughputBaseline("cache.example.com", 1000)
+	assert.Equal(t, 1000.0, getThroughputBaseline("cache.example.com"))
+	recordThroughputBaseline("cache.example.com", 2000)
+	assert.InDelta(t, 1300.0, getThroughputBaseline("cache.example.com"), 0.001)
+
+	// Bogus samples are ignored
+	recordThroughputBaseline("cache.example.com", 0)
+	assert.InDelta(t, 1300.0, getThroughputBaseline("cache.example.com"), 0.001)
+}
+
+func TestPersistThroughputBaselines(t *testing.T) {
+	resetThroughputBaselines(t)
+	filename := filepath.Join(t.TempDir(), "credentials", throughputBaselinesFileName)
+
+	// A missing file is not an error
+	require.NoError(t, loadThroughputBaselines(filename))
+
+	recordThroughputBaseline("cache.example.com", 1000)
+	recordThroughputBaseline("other.example.com", 5000)
+	require.NoError(t, saveThroughputBaselines(filename))
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A new process starts with the saved baselines
+	resetThroughputBaselines(t)
+	recordThroughputBaseline("other.example.com", 200)
+	require.NoError(t, loadThroughputBaselines(filename))
+	assert.Equal(t, 1000.0, getThroughputBaseline("cache.example.com"))
+	// Measurements from this process aren't overwritten by saved ones
+	assert.Equal(t, 200.0, getThroughputBaseline("other.example.com"))
+
+	require.NoError(t, os.WriteFile(filename, []byte("not json"), 0600))
+	assert.Error(t, loadThroughputBaselines(filename))
+}
+
+func TestSlowTransferDetector(t *testing.T) {
+	start := time.Now()
+
+	t.Run("threshold-uses-baseline", func(t *testing.T) {
+		resetThroughputBaselines(t)
+		detector := newSlowTransferDetector("cache.example.com", nil, 100, 0, 0, start)
+		assert.Equal(t, 100.0, detector.threshold())
+
+		recordThroughputBaseline("cache.example.com", 100000)
+		detector = newSlowTransferDetector("cache.example.com", nil, 100, 0, 0, start)
+		assert.Equal(t, 10000.0, detector.threshold())
+	})
+
+	t.Run("abort-after-window", func(t *testing.T) {
+		resetThroughputBaselines(t)
+		detector := newSlowTransferDetector("cache.example.com", nil, 1000, 2*time.Second, 5*time.Second, start)
+
+		// Slow during the rampup doesn't count
+		warn, abort := detector.observe(100, 1000000, start.Add(time.Second))
+		assert.False(t, warn)
+		assert.False(t, abort)
+
+		warn, abort = detector.observe(200, 1000000, start.Add(3*time.Second))
+		assert.True(t, warn)
+		assert.False(t, abort)
+
+		warn, abort = detector.observe(300, 1000000, start.Add(6*time.Second))
+		assert.False(t, warn)
+		assert.False(t, abort)
+
+		warn, abort = detector.observe(400, 1000000, start.Add(9*time.Second))
+		assert.False(t, warn)
+		assert.True(t, abort)
+	})
+
+	t.Run("recovers-above-threshold", func(t *testing.T) {
+		resetThroughputBaselines(t)
+		detector := newSlowTransferDetector("cache.example.com", nil, 1000, 0, 5*time.Second, start)
+
+		warn, _ := detector.observe(100, 1000000, start.Add(time.Second))
+		assert.True(t, warn)
+		_, abort := detector.observe(100000, 1000000, start.Add(2*time.Second))
+		assert.False(t, abort)
+		assert.True(t, detector.belowSince.IsZero())
+	})
+
+	t.Run("early-failover", func(t *testing.T) {
+		resetThroughputBaselines(t)
+		recordThroughputBaseline("fast.example.com", 1000000)
+		alternatives := []TransferDetails{
+			{Url: url.URL{Host: "cache.example.com"}},
+			{Url: url.URL{Host: "fast.example.com"}},
+		}
+
+		// Fast enough to pass the minimum, but the alternative is predicted to be much faster
+		detector := newSlowTransferDetector("cache.example.com", alternatives, 1000, 0, time.Minute, start)
+		assert.Equal(t, 1000000.0, detector.bestAlternative)
+		warn, abort := detector.observe(10000, 10000000, start.Add(time.Second))
+		assert.False(t, warn)
+		assert.True(t, abort)
+
+		// Comparable speeds don't trigger a failover
+		detector = newSlowTransferDetector("cache.example.com", alternatives, 1000, 0, time.Minute, start)
+		_, abort = detector.observe(500000, 10000000, start.Add(time.Second))
+		assert.False(t, abort)
+	})
+}
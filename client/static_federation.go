@@ -0,0 +1,161 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pelicanplatform/pelican/namespaces"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A federation described by a static map of namespaces to the servers that
+	// serve them, in place of a director (see Client.StaticFederationFile)
+	StaticFederation struct {
+		Namespaces []StaticNamespace `yaml:"namespaces"`
+	}
+
+	StaticNamespace struct {
+		Path string `yaml:"path"`
+		// The origins exporting the namespace; the first is used for writes
+		Origins []string `yaml:"origins"`
+		// The caches serving the namespace, in order of preference
+		Caches         []string `yaml:"caches"`
+		RequireToken   bool     `yaml:"require_token"`
+		Issuers        []string `yaml:"issuers"`
+		CollectionsUrl string   `yaml:"collections_url"`
+	}
+)
+
+// Whether the client is configured to use a static federation rather than a director.
+// In this mode, the federation named in pelican:// URLs is ignored.
+func usingStaticFederation() bool {
+	return param.Client_StaticFederationFile.GetString() != ""
+}
+
+// Whether the client gets an ordered list of caches with the namespace information,
+// either from the director or from the static federation
+func hasSortedCaches() bool {
+	return param.Federation_DirectorUrl.GetString() != "" || usingStaticFederation()
+}
+
+// Load and validate the static federation file.  As JSON is a subset of YAML,
+// the file may be in either format.
+func loadStaticFederation(fedFile string) (*StaticFederation, error) {
+	contents, err := os.ReadFile(fedFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the static federation file %s", fedFile)
+	}
+	fed := StaticFederation{}
+	if err = yaml.Unmarshal(contents, &fed); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the static federation file %s", fedFile)
+	}
+	for idx, ns := range fed.Namespaces {
+		if !strings.HasPrefix(ns.Path, "/") {
+			return nil, errors.Errorf("Namespace %q in the static federation file %s must be an absolute path", ns.Path, fedFile)
+		}
+		if len(ns.Origins) == 0 && len(ns.Caches) == 0 {
+			return nil, errors.Errorf("Namespace %s in the static federation file %s has no origins or caches", ns.Path, fedFile)
+		}
+		for _, server := range append(append([]string{}, ns.Origins...), ns.Caches...) {
+			if serverUrl, err := url.Parse(server); err != nil || serverUrl.Host == "" {
+				return nil, errors.Errorf("Namespace %s in the static federation file %s has an invalid server URL %q", ns.Path, fedFile, server)
+			}
+		}
+		fed.Namespaces[idx].Path = path.Clean(ns.Path)
+	}
+	return &fed, nil
+}
+
+// Find the namespace with the longest prefix containing the object
+func (fed *StaticFederation) matchNamespace(objectPath string) *StaticNamespace {
+	objectPath = path.Clean("/" + objectPath)
+	var best *StaticNamespace
+	for idx, ns := range fed.Namespaces {
+		if ns.Path != "/" && objectPath != ns.Path && !strings.HasPrefix(objectPath, ns.Path+"/") {
+			continue
+		}
+		if best == nil || len(ns.Path) > len(best.Path) {
+			best = &fed.Namespaces[idx]
+		}
+	}
+	return best
+}
+
+// Build the namespace information for an object from the static federation, mirroring
+// what the client would otherwise get from the director's response
+func getNamespaceFromStaticFederation(objectPath string, isPut bool) (ns namespaces.Namespace, err error) {
+	fedFile := param.Client_StaticFederationFile.GetString()
+	fed, err := loadStaticFederation(fedFile)
+	if err != nil {
+		return
+	}
+	staticNs := fed.matchNamespace(objectPath)
+	if staticNs == nil {
+		err = errors.Errorf("No namespace in the static federation file %s matches %s", fedFile, objectPath)
+		return
+	}
+	log.Debugln("Matched object", objectPath, "to namespace", staticNs.Path, "in the static federation")
+
+	ns.Path = staticNs.Path
+	ns.UseTokenOnRead = staticNs.RequireToken
+	ns.Issuer = staticNs.Issuers
+	ns.DirListHost = staticNs.CollectionsUrl
+	if ns.DirListHost == "" && len(staticNs.Origins) > 0 {
+		ns.DirListHost = staticNs.Origins[0]
+	}
+
+	// Without caches, read straight from the origins
+	servers := staticNs.Caches
+	if len(servers) == 0 {
+		servers = staticNs.Origins
+	}
+	ns.SortedDirectorCaches = make([]namespaces.DirectorCache, 0, len(servers))
+	for idx, server := range servers {
+		serverUrl, _ := url.Parse(server)
+		// Like Pelican servers advertised by the director, HTTPS servers are read over HTTPS
+		if staticNs.RequireToken || serverUrl.Scheme == "https" {
+			ns.ReadHTTPS = true
+		}
+		ns.SortedDirectorCaches = append(ns.SortedDirectorCaches, namespaces.DirectorCache{
+			ResourceName: serverUrl.Hostname(),
+			EndpointUrl:  server,
+			Priority:     idx,
+			AuthedReq:    staticNs.RequireToken,
+		})
+	}
+
+	if isPut {
+		if len(staticNs.Origins) == 0 {
+			err = errors.Wrapf(ErrReadOnlyNamespace, "Namespace %s in the static federation has no origins to write to", staticNs.Path)
+			return
+		}
+		originUrl, _ := url.Parse(staticNs.Origins[0])
+		ns.WriteBackHost = "https://" + originUrl.Host
+	}
+	return
+}
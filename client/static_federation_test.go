@@ -0,0 +1,107 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFederation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "federation.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+namespaces:
+  - path: /foo
+    origins: ["https://origin.example.com:8443"]
+    caches: ["https://cache1.example.com:8443", "https://cache2.example.com:8443"]
+  - path: /foo/private/
+    origins: ["https://private-origin.example.com:8443"]
+    require_token: true
+    issuers: ["https://issuer.example.com"]
+`), 0644))
+	jsonFile := filepath.Join(dir, "federation.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"namespaces": [{"path": "/bar", "caches": ["http://cache.example.com:8000"]}]}`), 0644))
+
+	t.Run("longest-prefix-match", func(t *testing.T) {
+		viper.Set("Client.StaticFederationFile", yamlFile)
+		require.True(t, hasSortedCaches())
+
+		ns, err := getNamespaceFromStaticFederation("/foo/bar.txt", false)
+		require.NoError(t, err)
+		assert.Equal(t, "/foo", ns.Path)
+		assert.True(t, ns.ReadHTTPS)
+		assert.False(t, ns.UseTokenOnRead)
+		assert.Equal(t, "https://origin.example.com:8443", ns.DirListHost)
+		require.Len(t, ns.SortedDirectorCaches, 2)
+		assert.Equal(t, "https://cache1.example.com:8443", ns.SortedDirectorCaches[0].EndpointUrl)
+		assert.Equal(t, "https://cache2.example.com:8443", ns.SortedDirectorCaches[1].EndpointUrl)
+
+		// Without caches, objects are read from the origin
+		ns, err = getNamespaceFromStaticFederation("/foo/private/secret.txt", true)
+		require.NoError(t, err)
+		assert.Equal(t, "/foo/private", ns.Path)
+		assert.True(t, ns.UseTokenOnRead)
+		assert.Equal(t, []string{"https://issuer.example.com"}, ns.Issuer)
+		assert.Equal(t, "https://private-origin.example.com:8443", ns.WriteBackHost)
+		require.Len(t, ns.SortedDirectorCaches, 1)
+		assert.Equal(t, "https://private-origin.example.com:8443", ns.SortedDirectorCaches[0].EndpointUrl)
+		assert.True(t, ns.SortedDirectorCaches[0].AuthedReq)
+
+		// Prefixes only match on path boundaries
+		_, err = getNamespaceFromStaticFederation("/foobar/baz.txt", false)
+		assert.Error(t, err)
+	})
+
+	t.Run("json-file", func(t *testing.T) {
+		viper.Set("Client.StaticFederationFile", jsonFile)
+		ns, err := getNamespaceFromStaticFederation("/bar/baz.txt", false)
+		require.NoError(t, err)
+		assert.Equal(t, "/bar", ns.Path)
+		assert.False(t, ns.ReadHTTPS)
+		require.Len(t, ns.SortedDirectorCaches, 1)
+		assert.Equal(t, "http://cache.example.com:8000", ns.SortedDirectorCaches[0].EndpointUrl)
+
+		// There's no origin to write to
+		_, err = getNamespaceFromStaticFederation("/bar/baz.txt", true)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid-file", func(t *testing.T) {
+		invalidFile := filepath.Join(dir, "invalid.yaml")
+		require.NoError(t, os.WriteFile(invalidFile, []byte(`{"namespaces": [{"path": "relative", "caches": ["https://cache.example.com"]}]}`), 0644))
+		_, err := loadStaticFederation(invalidFile)
+		assert.Error(t, err)
+
+		require.NoError(t, os.WriteFile(invalidFile, []byte(`{"namespaces": [{"path": "/empty"}]}`), 0644))
+		_, err = loadStaticFederation(invalidFile)
+		assert.Error(t, err)
+
+		_, err = loadStaticFederation(filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,118 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	grab "github.com/opensaucerer/grab/v3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+// The token shared by the workers of a transfer.  A token may expire while a long transfer
+// is in progress; once a server refuses it, a fresh one is fetched the same way as the
+// original and the refused request is retried.
+type tokenManager struct {
+	mutex       sync.Mutex
+	destination *url.URL
+	namespace   namespaces.Namespace
+	isWrite     bool
+	tokenName   string
+	token       string
+}
+
+func newTokenManager(token string, destination *url.URL, namespace namespaces.Namespace, isWrite bool, tokenName string) *tokenManager {
+	return &tokenManager{
+		destination: destination,
+		namespace:   namespace,
+		isWrite:     isWrite,
+		tokenName:   tokenName,
+		token:       token,
+	}
+}
+
+// Get the current token; a nil manager stands for a transfer that uses no token
+func (tm *tokenManager) get() string {
+	if tm == nil {
+		return ""
+	}
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	return tm.token
+}
+
+// Replace a token refused by a server.  When several workers see the same token refused,
+// only the first fetches a new one; the others get the token it fetched.  Fails if no
+// token other than the refused one can be found, in which case retrying is pointless.
+func (tm *tokenManager) refresh(refused string) (string, error) {
+	if tm == nil {
+		return "", errors.New("transfer does not use a token")
+	}
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	if tm.token != refused {
+		return tm.token, nil
+	}
+	token, err := getToken(tm.destination, tm.namespace, tm.isWrite, tm.tokenName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to refresh token")
+	}
+	if token == refused {
+		return "", errors.New("no new token available to replace the one refused by the server")
+	}
+	tm.token = token
+	return token, nil
+}
+
+// Check whether a failed transfer was refused on the grounds of its token, and if so,
+// fetch a new one.  Returns true if the transfer should be retried with tm.get().
+func (tm *tokenManager) refreshIfRejected(err error, refused string) bool {
+	if tm == nil || refused == "" || !isTokenRejected(err) {
+		return false
+	}
+	if _, refreshErr := tm.refresh(refused); refreshErr != nil {
+		log.Warningln("Server refused the transfer token and it couldn't be replaced:", refreshErr)
+		return false
+	}
+	log.Infoln("Server refused the transfer token; retrying with a refreshed token")
+	return true
+}
+
+// Check whether an error is a server refusing the token of a request (HTTP 401 or 403)
+func isTokenRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := 0
+	var cse *ConnectionSetupError
+	var her *HttpErrResp
+	if errors.As(err, &cse) {
+		if sce, ok := cse.Unwrap().(grab.StatusCodeError); ok {
+			code = int(sce)
+		}
+	} else if errors.As(err, &her) {
+		code = her.Code
+	}
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
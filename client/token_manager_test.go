@@ -0,0 +1,84 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	grab "github.com/opensaucerer/grab/v3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/namespaces"
+)
+
+func TestIsTokenRejected(t *testing.T) {
+	assert.False(t, isTokenRejected(nil))
+	assert.False(t, isTokenRejected(errors.New("connection reset")))
+	assert.True(t, isTokenRejected(&ConnectionSetupError{Err: grab.StatusCodeError(http.StatusUnauthorized)}))
+	assert.True(t, isTokenRejected(errors.Wrap(&ConnectionSetupError{Err: grab.StatusCodeError(http.StatusForbidden)}, "download failed")))
+	assert.False(t, isTokenRejected(&ConnectionSetupError{Err: grab.StatusCodeError(http.StatusNotFound)}))
+	assert.True(t, isTokenRejected(&HttpErrResp{Code: http.StatusForbidden, Err: "Request failed (HTTP status 403)"}))
+	assert.False(t, isTokenRejected(&HttpErrResp{Code: http.StatusInternalServerError, Err: "Request failed (HTTP status 500)"}))
+}
+
+func TestTokenManagerRefresh(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first"), 0600))
+	t.Setenv("BEARER_TOKEN_FILE", tokenFile)
+
+	dest, err := url.Parse("/foo/bar")
+	require.NoError(t, err)
+	tokens := newTokenManager("first", dest, namespaces.Namespace{Path: "/foo"}, false, "")
+	rejected := &HttpErrResp{Code: http.StatusUnauthorized, Err: "Request failed (HTTP status 401)"}
+
+	t.Run("not-rejected", func(t *testing.T) {
+		assert.False(t, tokens.refreshIfRejected(fmt.Errorf("timeout"), "first"))
+		assert.Equal(t, "first", tokens.get())
+	})
+
+	t.Run("no-new-token", func(t *testing.T) {
+		assert.False(t, tokens.refreshIfRejected(rejected, "first"))
+		assert.Equal(t, "first", tokens.get())
+	})
+
+	t.Run("refreshed", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tokenFile, []byte("second"), 0600))
+		assert.True(t, tokens.refreshIfRejected(rejected, "first"))
+		assert.Equal(t, "second", tokens.get())
+
+		// Another worker that saw the old token refused gets the refreshed one
+		require.NoError(t, os.WriteFile(tokenFile, []byte("third"), 0600))
+		token, err := tokens.refresh("first")
+		require.NoError(t, err)
+		assert.Equal(t, "second", token)
+	})
+
+	t.Run("no-token", func(t *testing.T) {
+		var noTokens *tokenManager
+		assert.Equal(t, "", noTokens.get())
+		assert.False(t, noTokens.refreshIfRejected(rejected, ""))
+	})
+}
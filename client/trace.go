@@ -0,0 +1,380 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// Records every HTTP exchange of the client's transfers as JSON lines in a
+	// diagnostic file, with credentials redacted
+	transferTracer struct {
+		mutex   sync.Mutex
+		file    *os.File
+		encoder *json.Encoder
+	}
+
+	traceSession struct {
+		Type      string    `json:"type"`
+		Started   time.Time `json:"started"`
+		Version   string    `json:"version"`
+		OS        string    `json:"os"`
+		Arch      string    `json:"arch"`
+		Arguments []string  `json:"arguments"`
+	}
+
+	traceTiming struct {
+		DNSMs          float64 `json:"dnsMs,omitempty"`
+		ConnectMs      float64 `json:"connectMs,omitempty"`
+		TLSHandshakeMs float64 `json:"tlsHandshakeMs,omitempty"`
+		FirstByteMs    float64 `json:"firstByteMs,omitempty"`
+		TotalMs        float64 `json:"totalMs"`
+	}
+
+	traceCertificate struct {
+		Subject  string    `json:"subject"`
+		Issuer   string    `json:"issuer"`
+		DNSNames []string  `json:"dnsNames,omitempty"`
+		NotAfter time.Time `json:"notAfter"`
+	}
+
+	traceTLS struct {
+		Version            string             `json:"version"`
+		CipherSuite        string             `json:"cipherSuite"`
+		ServerName         string             `json:"serverName"`
+		NegotiatedProtocol string             `json:"negotiatedProtocol,omitempty"`
+		Resumed            bool               `json:"resumed"`
+		PeerCertificates   []traceCertificate `json:"peerCertificates"`
+	}
+
+	// A single HTTP request and its response
+	traceRequest struct {
+		Type            string      `json:"type"`
+		Started         time.Time   `json:"started"`
+		Method          string      `json:"method"`
+		URL             string      `json:"url"`
+		RedirectedFrom  string      `json:"redirectedFrom,omitempty"`
+		RequestHeaders  http.Header `json:"requestHeaders"`
+		Status          int         `json:"status,omitempty"`
+		ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+		Trailers        http.Header `json:"trailers,omitempty"`
+		BodyBytes       int64       `json:"bodyBytes"`
+		RemoteAddr      string      `json:"remoteAddr,omitempty"`
+		ReusedConn      bool        `json:"reusedConnection"`
+		TLS             *traceTLS   `json:"tls,omitempty"`
+		Timing          traceTiming `json:"timing"`
+		Error           string      `json:"error,omitempty"`
+	}
+
+	tracingTransport struct {
+		tracer *transferTracer
+		next   http.RoundTripper
+	}
+
+	// A response body that records its request once it has been read
+	tracedBody struct {
+		io.ReadCloser
+		tracer   *transferTracer
+		record   *traceRequest
+		start    time.Time
+		doneOnce sync.Once
+		resp     *http.Response
+	}
+)
+
+const traceRedacted = "REDACTED"
+
+var (
+	activeTracerMutex sync.RWMutex
+	activeTracer      *transferTracer
+
+	// Headers whose values are credentials
+	traceSecretHeaders = map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+		"Set-Cookie":          true,
+		"X-Auth-Token":        true,
+	}
+	// Headers whose values are URLs, which may carry credentials in their query
+	traceURLHeaders = []string{"Location", "Content-Location"}
+	// Query parameters whose values are credentials
+	traceSecretParams = map[string]bool{
+		"authz":                true,
+		"access_token":         true,
+		"token":                true,
+		"x-amz-signature":      true,
+		"x-amz-credential":     true,
+		"x-amz-security-token": true,
+	}
+)
+
+// Start recording every HTTP request the client makes, with its response, timing, redirect
+// chain and TLS details, to traceFile.  Credentials in headers and URLs are redacted.  The
+// returned function stops the trace and closes the file.
+func StartTrace(traceFile string) (func() error, error) {
+	file, err := os.OpenFile(traceFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the trace file")
+	}
+	tracer := &transferTracer{file: file, encoder: json.NewEncoder(file)}
+	tracer.write(traceSession{
+		Type:      "session",
+		Started:   time.Now(),
+		Version:   ObjectClientOptions.Version,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Arguments: redactArguments(os.Args),
+	})
+
+	activeTracerMutex.Lock()
+	activeTracer = tracer
+	activeTracerMutex.Unlock()
+	log.Infoln("Recording a trace of the transfers to", traceFile)
+
+	return func() error {
+		activeTracerMutex.Lock()
+		if activeTracer == tracer {
+			activeTracer = nil
+		}
+		activeTracerMutex.Unlock()
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		return tracer.file.Close()
+	}, nil
+}
+
+// Wrap the transport of an HTTP client so its requests are recorded in the active
+// trace, if any
+func traceTransport(next http.RoundTripper) http.RoundTripper {
+	activeTracerMutex.RLock()
+	defer activeTracerMutex.RUnlock()
+	if activeTracer == nil {
+		return next
+	}
+	return &tracingTransport{tracer: activeTracer, next: next}
+}
+
+func (tracer *transferTracer) write(record interface{}) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	if err := tracer.encoder.Encode(record); err != nil {
+		log.Debugln("Failed to write to the trace file:", err)
+	}
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		for key := range query {
+			if traceSecretParams[strings.ToLower(key)] {
+				query.Set(key, traceRedacted)
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+func redactHeaders(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	redacted := header.Clone()
+	for key := range redacted {
+		if traceSecretHeaders[http.CanonicalHeaderKey(key)] {
+			redacted[key] = []string{traceRedacted}
+		}
+	}
+	for _, key := range traceURLHeaders {
+		values := redacted.Values(key)
+		for idx, value := range values {
+			if u, err := url.Parse(value); err == nil {
+				values[idx] = redactURL(u)
+			}
+		}
+	}
+	return redacted
+}
+
+// Redact the credentials in the URLs given on the command line
+func redactArguments(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for idx, arg := range redacted {
+		if u, err := url.Parse(arg); err == nil && u.RawQuery != "" {
+			redacted[idx] = redactURL(u)
+		}
+	}
+	return redacted
+}
+
+func newTraceTLS(state *tls.ConnectionState) *traceTLS {
+	result := &traceTLS{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Resumed:            state.DidResume,
+		PeerCertificates:   []traceCertificate{},
+	}
+	for _, cert := range state.PeerCertificates {
+		result.PeerCertificates = append(result.PeerCertificates, traceCertificate{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			DNSNames: cert.DNSNames,
+			NotAfter: cert.NotAfter,
+		})
+	}
+	return result
+}
+
+func msSince(start time.Time, end time.Time) float64 {
+	return float64(end.Sub(start)) / float64(time.Millisecond)
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record := &traceRequest{
+		Type:           "request",
+		Started:        time.Now(),
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Header),
+	}
+	// Requests following a redirect carry the response that caused them
+	if req.Response != nil && req.Response.Request != nil {
+		record.RedirectedFrom = redactURL(req.Response.Request.URL)
+	}
+
+	// The hooks may run on other goroutines than the caller's
+	var mutex sync.Mutex
+	var dnsStart, connectStart, tlsStart time.Time
+	clientTrace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			record.Timing.DNSMs = msSince(dnsStart, time.Now())
+		},
+		ConnectStart: func(string, string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			connectStart = time.Now()
+		},
+		ConnectDone: func(_ string, addr string, _ error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			record.Timing.ConnectMs = msSince(connectStart, time.Now())
+			record.RemoteAddr = addr
+		},
+		TLSHandshakeStart: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			record.Timing.TLSHandshakeMs = msSince(tlsStart, time.Now())
+			record.TLS = newTraceTLS(&state)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			record.ReusedConn = info.Reused
+			if info.Conn != nil {
+				record.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			record.Timing.FirstByteMs = msSince(record.Started, time.Now())
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), clientTrace))
+
+	resp, err := t.next.RoundTrip(req)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if err != nil {
+		record.Error = err.Error()
+		record.Timing.TotalMs = msSince(record.Started, time.Now())
+		t.tracer.write(record)
+		return resp, err
+	}
+	record.Status = resp.StatusCode
+	record.ResponseHeaders = redactHeaders(resp.Header)
+	if record.TLS == nil && resp.TLS != nil {
+		record.TLS = newTraceTLS(resp.TLS)
+	}
+	resp.Body = &tracedBody{ReadCloser: resp.Body, tracer: t.tracer, record: record, start: record.Started, resp: resp}
+	return resp, nil
+}
+
+func (body *tracedBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.record.BodyBytes += int64(n)
+	if err != nil {
+		body.finish(err)
+	}
+	return n, err
+}
+
+func (body *tracedBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.finish(nil)
+	return err
+}
+
+// Record the request once its body is done; trailers are only available by then
+func (body *tracedBody) finish(err error) {
+	body.doneOnce.Do(func() {
+		body.record.Timing.TotalMs = msSince(body.start, time.Now())
+		if err != nil && err != io.EOF {
+			body.record.Error = err.Error()
+		}
+		if len(body.resp.Trailer) > 0 {
+			body.record.Trailers = redactHeaders(body.resp.Trailer)
+		}
+		body.tracer.write(body.record)
+	})
+}
@@ -0,0 +1,97 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/object?authz=secret-token&other=visible", http.StatusTemporaryRedirect)
+			return
+		}
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		_, _ = w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	traceFile := filepath.Join(t.TempDir(), "trace.jsonl")
+	stop, err := StartTrace(traceFile)
+	require.NoError(t, err)
+
+	httpClient := &http.Client{Transport: traceTransport(server.Client().Transport)}
+	req, err := http.NewRequest("GET", server.URL+"/redirect", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	resp, err := httpClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "hello world", string(body))
+	require.NoError(t, stop())
+
+	// Once stopped, new clients aren't traced
+	_, traced := traceTransport(http.DefaultTransport).(*tracingTransport)
+	assert.False(t, traced)
+
+	contents, err := os.ReadFile(traceFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(contents), "secret-token")
+	assert.NotContains(t, string(contents), "secret-cookie")
+
+	file, err := os.Open(traceFile)
+	require.NoError(t, err)
+	defer file.Close()
+	records := []map[string]interface{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+	assert.Equal(t, "session", records[0]["type"])
+
+	redirect := records[1]
+	assert.Equal(t, "request", redirect["type"])
+	assert.Equal(t, float64(http.StatusTemporaryRedirect), redirect["status"])
+	assert.Contains(t, redirect["responseHeaders"].(map[string]interface{})["Location"].([]interface{})[0], "authz=REDACTED")
+	assert.NotNil(t, redirect["tls"])
+
+	object := records[2]
+	assert.Equal(t, float64(http.StatusOK), object["status"])
+	assert.Equal(t, server.URL+"/redirect", object["redirectedFrom"])
+	assert.Contains(t, object["url"], "authz=REDACTED")
+	assert.Contains(t, object["url"], "other=visible")
+	assert.Equal(t, float64(len("hello world")), object["bodyBytes"])
+	assert.Equal(t, []interface{}{"REDACTED"}, object["requestHeaders"].(map[string]interface{})["Authorization"])
+}
@@ -0,0 +1,452 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+type (
+	monitoredFileState int
+
+	monitoredFile struct {
+		name      string
+		index     int // The 1-based position of the file in the batch
+		state     monitoredFileState
+		bytes     int64
+		total     int64 // The size of the file, or 0 if it's unknown
+		attempts  int
+		started   time.Time
+		lastError string
+	}
+
+	// The state of a batch of transfers displayed by the full-screen terminal UI
+	// of ObjectClientOptions.TUI, or reported as JSON for ObjectClientOptions.ProgressJSON
+	transferMonitor struct {
+		mutex    sync.Mutex
+		files    map[string]*monitoredFile
+		start    time.Time
+		retries  int
+		failures []*monitoredFile
+		logLines []string
+
+		// The throughput, as a moving average updated on every refresh
+		lastSampleBytes int64
+		lastSampleTime  time.Time
+		rate            float64
+
+		out        io.Writer
+		prevLogOut io.Writer
+		done       chan struct{}
+		stopped    sync.WaitGroup
+		signals    chan os.Signal
+		// Set when the progress is reported as JSON events rather than drawn
+		events *json.Encoder
+	}
+)
+
+const (
+	fileQueued monitoredFileState = iota
+	fileActive
+	fileDone
+	fileFailed
+)
+
+const (
+	tuiRefreshInterval = 250 * time.Millisecond
+	tuiMaxLogLines     = 100
+	// The weight of the latest sample in the throughput's moving average
+	tuiRateSmoothing = 0.2
+)
+
+var activeTransferMonitor atomic.Pointer[transferMonitor]
+
+// Get the monitor of the transfers in progress, or nil if neither the terminal UI
+// nor the JSON progress is shown.  The methods of a nil monitor do nothing.
+func getTransferMonitor() *transferMonitor {
+	return activeTransferMonitor.Load()
+}
+
+func newTransferMonitor(names []string, out io.Writer) *transferMonitor {
+	monitor := &transferMonitor{
+		files: make(map[string]*monitoredFile, len(names)),
+		start: time.Now(),
+		out:   out,
+		done:  make(chan struct{}),
+	}
+	monitor.lastSampleTime = monitor.start
+	for idx, name := range names {
+		monitor.files[name] = &monitoredFile{name: name, index: idx + 1}
+	}
+	return monitor
+}
+
+// Take over the terminal to show the progress of the transfers of the named
+// files until stop is called.  The logs are shown in the UI rather than
+// interleaved with it.
+func startTransferMonitor(names []string) *transferMonitor {
+	monitor := newTransferMonitor(names, os.Stdout)
+	monitor.prevLogOut = log.StandardLogger().Out
+	log.SetOutput(monitor)
+	activeTransferMonitor.Store(monitor)
+
+	// Switch to the alternate screen and hide the cursor
+	fmt.Fprint(monitor.out, "\x1b[?1049h\x1b[?25l")
+	// Restore the terminal if the transfers are interrupted
+	monitor.signals = make(chan os.Signal, 1)
+	signal.Notify(monitor.signals, syscall.SIGINT, syscall.SIGTERM)
+
+	monitor.stopped.Add(1)
+	go func() {
+		defer monitor.stopped.Done()
+		ticker := time.NewTicker(tuiRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-monitor.done:
+				return
+			case <-monitor.signals:
+				monitor.restoreTerminal()
+				os.Exit(130)
+			case <-ticker.C:
+				monitor.draw()
+			}
+		}
+	}()
+	return monitor
+}
+
+// Give the terminal back and print a summary of the transfers; the JSON progress
+// just stops
+func (monitor *transferMonitor) stop() {
+	if monitor == nil {
+		return
+	}
+	if monitor.events != nil {
+		activeTransferMonitor.CompareAndSwap(monitor, nil)
+		return
+	}
+	close(monitor.done)
+	monitor.stopped.Wait()
+	signal.Stop(monitor.signals)
+	activeTransferMonitor.CompareAndSwap(monitor, nil)
+	monitor.restoreTerminal()
+	fmt.Fprintln(monitor.out, monitor.summary())
+}
+
+func (monitor *transferMonitor) restoreTerminal() {
+	fmt.Fprint(monitor.out, "\x1b[?25h\x1b[?1049l")
+	if monitor.prevLogOut != nil {
+		log.SetOutput(monitor.prevLogOut)
+	}
+}
+
+// Redraw the screen at the size of the terminal
+func (monitor *transferMonitor) draw() {
+	width, height := 100, 30
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		width, height = w, h
+	}
+	fmt.Fprint(monitor.out, monitor.render(time.Now(), width, height))
+}
+
+func (monitor *transferMonitor) getFile(name string) *monitoredFile {
+	file, ok := monitor.files[name]
+	if !ok {
+		file = &monitoredFile{name: name, index: len(monitor.files) + 1}
+		monitor.files[name] = file
+	}
+	return file
+}
+
+// Record that the transfer of a file has started a new attempt
+func (monitor *transferMonitor) started(name string) {
+	if monitor == nil {
+		return
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	file := monitor.getFile(name)
+	if file.state != fileActive {
+		file.state = fileActive
+		file.started = time.Now()
+		monitor.emit(progressStart, file)
+	}
+}
+
+// Record the bytes transferred by the current attempt of a file
+func (monitor *transferMonitor) progress(name string, bytes, total int64) {
+	if monitor == nil {
+		return
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	file := monitor.getFile(name)
+	file.bytes = bytes
+	if total > 0 {
+		file.total = total
+	}
+	monitor.emit(progressUpdate, file)
+}
+
+// Record a failed attempt of a file's transfer, which is retried from another source
+func (monitor *transferMonitor) attemptFailed(name string, errMsg string) {
+	if monitor == nil {
+		return
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	file := monitor.getFile(name)
+	file.attempts++
+	file.bytes = 0
+	file.lastError = errMsg
+	monitor.retries++
+	monitor.emit(progressRetry, file)
+}
+
+// Record the end of a file's transfer
+func (monitor *transferMonitor) finished(name string, bytes int64, err error) {
+	if monitor == nil {
+		return
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	file := monitor.getFile(name)
+	file.bytes = bytes
+	if err != nil {
+		file.state = fileFailed
+		if file.lastError == "" {
+			file.lastError = err.Error()
+		}
+		monitor.failures = append(monitor.failures, file)
+		monitor.emit(progressFailed, file)
+	} else {
+		file.state = fileDone
+		if file.total < bytes {
+			file.total = bytes
+		}
+		monitor.emit(progressDone, file)
+	}
+}
+
+// Capture the logs, which would otherwise scroll the UI
+func (monitor *transferMonitor) Write(p []byte) (int, error) {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		monitor.logLines = append(monitor.logLines, line)
+	}
+	if len(monitor.logLines) > tuiMaxLogLines {
+		monitor.logLines = monitor.logLines[len(monitor.logLines)-tuiMaxLogLines:]
+	}
+	return len(p), nil
+}
+
+// Shorten s to width, keeping its end, which is the most specific part of a path
+func truncateLeft(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
+
+func truncateRight(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func progressBar(bytes, total int64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	filled := 0
+	if total > 0 {
+		filled = int(float64(width) * float64(bytes) / float64(total))
+		if filled > width {
+			filled = width
+		}
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// Render the screen as of now.  Must be called with the mutex released.
+func (monitor *transferMonitor) render(now time.Time, width, height int) string {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	var queued, active, done, failed int
+	var transferred int64
+	activeFiles := []*monitoredFile{}
+	for _, file := range monitor.files {
+		transferred += file.bytes
+		switch file.state {
+		case fileQueued:
+			queued++
+		case fileActive:
+			active++
+			activeFiles = append(activeFiles, file)
+		case fileDone:
+			done++
+		case fileFailed:
+			failed++
+		}
+	}
+	sort.Slice(activeFiles, func(i, j int) bool {
+		if !activeFiles[i].started.Equal(activeFiles[j].started) {
+			return activeFiles[i].started.Before(activeFiles[j].started)
+		}
+		return activeFiles[i].name < activeFiles[j].name
+	})
+
+	if elapsed := now.Sub(monitor.lastSampleTime).Seconds(); elapsed > 0 {
+		sampleRate := float64(transferred-monitor.lastSampleBytes) / elapsed
+		if sampleRate < 0 {
+			// A retry started over
+			sampleRate = 0
+		}
+		if monitor.lastSampleBytes == 0 && monitor.rate == 0 {
+			monitor.rate = sampleRate
+		} else {
+			monitor.rate = tuiRateSmoothing*sampleRate + (1-tuiRateSmoothing)*monitor.rate
+		}
+		monitor.lastSampleBytes = transferred
+		monitor.lastSampleTime = now
+	}
+
+	lines := []string{
+		fmt.Sprintf("Pelican transfers — %s elapsed", now.Sub(monitor.start).Truncate(time.Second)),
+		fmt.Sprintf("Files: %d/%d done, %d active, %d queued, %d failed   Retries: %d", done, len(monitor.files), active, queued, failed, monitor.retries),
+		fmt.Sprintf("Transferred: %s   Throughput: %s/s", ByteCountSI(transferred), ByteCountSI(int64(monitor.rate))),
+		strings.Repeat("─", width),
+	}
+
+	// Share the rest of the screen between the active files, the failures and the logs
+	remaining := height - len(lines) - 1
+	errorRows := 0
+	if len(monitor.failures) > 0 {
+		errorRows = min(len(monitor.failures), max(remaining/4, 1))
+	}
+	logRows := 0
+	if len(monitor.logLines) > 0 {
+		logRows = min(len(monitor.logLines), max(remaining/4, 1))
+	}
+	activeRows := remaining - errorRows - logRows
+	if errorRows > 0 {
+		activeRows--
+	}
+	if logRows > 0 {
+		activeRows--
+	}
+
+	barWidth := min(30, width/4)
+	for idx, file := range activeFiles {
+		if idx >= activeRows {
+			break
+		}
+		counters := ByteCountSI(file.bytes)
+		if file.total > 0 {
+			counters += " / " + ByteCountSI(file.total)
+		}
+		if file.attempts > 0 {
+			counters += fmt.Sprintf(" (retry %d)", file.attempts)
+		}
+		nameWidth := width - barWidth - len([]rune(counters)) - 3
+		lines = append(lines, fmt.Sprintf("%-*s %s %s", max(nameWidth, 0), truncateLeft(file.name, nameWidth), progressBar(file.bytes, file.total, barWidth), counters))
+	}
+	for len(lines) < 4+activeRows {
+		lines = append(lines, "")
+	}
+	if errorRows > 0 {
+		lines = append(lines, fmt.Sprintf("Failures (%d):", len(monitor.failures)))
+		for _, file := range monitor.failures[len(monitor.failures)-errorRows:] {
+			lines = append(lines, truncateRight(truncateLeft(file.name, width/2)+": "+file.lastError, width))
+		}
+	}
+	if logRows > 0 {
+		lines = append(lines, "Log:")
+		for _, line := range monitor.logLines[len(monitor.logLines)-logRows:] {
+			lines = append(lines, truncateRight(line, width))
+		}
+	}
+
+	// Draw from the top-left corner, clearing what's left of every line and of the screen
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	for idx, line := range lines {
+		if idx >= height {
+			break
+		}
+		screen.WriteString(truncateRight(line, width))
+		screen.WriteString("\x1b[K\r\n")
+	}
+	screen.WriteString("\x1b[J")
+	return screen.String()
+}
+
+// Summarize the transfers once the UI is gone
+func (monitor *transferMonitor) summary() string {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	var done int
+	var transferred int64
+	for _, file := range monitor.files {
+		if file.state == fileDone {
+			done++
+			transferred += file.bytes
+		}
+	}
+	summary := fmt.Sprintf("Transferred %d of %d files (%s) in %s with %d retries", done, len(monitor.files),
+		ByteCountSI(transferred), time.Since(monitor.start).Truncate(time.Second), monitor.retries)
+	if len(monitor.failures) > 0 {
+		summary += fmt.Sprintf("; %d failed:", len(monitor.failures))
+		for _, file := range monitor.failures {
+			summary += "\n  " + file.name + ": " + file.lastError
+		}
+	}
+	return summary
+}
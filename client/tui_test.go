@@ -0,0 +1,97 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferMonitorRender(t *testing.T) {
+	monitor := newTransferMonitor([]string{"/data/a.txt", "/data/b.txt", "/data/c.txt", "/data/d.txt"}, &bytes.Buffer{})
+
+	monitor.started("/data/a.txt")
+	monitor.progress("/data/a.txt", 500, 1000)
+	monitor.started("/data/b.txt")
+	monitor.attemptFailed("/data/b.txt", "Failed to download from cache-a: timeout")
+	monitor.progress("/data/b.txt", 100, 0)
+	monitor.started("/data/c.txt")
+	monitor.finished("/data/c.txt", 2000, nil)
+	monitor.started("/data/d.txt")
+	monitor.finished("/data/d.txt", 0, errors.New("failed to download with HTTP"))
+	_, err := monitor.Write([]byte("level=warning msg=\"Downloading too slow\"\n"))
+	assert.NoError(t, err)
+
+	screen := monitor.render(monitor.start.Add(10*time.Second), 120, 30)
+	lines := strings.Split(screen, "\r\n")
+
+	assert.Contains(t, lines[0], "10s elapsed")
+	assert.Contains(t, lines[1], "Files: 1/4 done, 2 active, 0 queued, 1 failed   Retries: 1")
+	assert.Contains(t, lines[2], "Transferred: 2.6 kB")
+	// 2.6 kB over 10 seconds
+	assert.Contains(t, lines[2], "Throughput: 260 B/s")
+	assert.Contains(t, screen, "/data/a.txt")
+	assert.Contains(t, screen, "500 B / 1.0 kB")
+	assert.Contains(t, screen, "100 B (retry 1)")
+	assert.Contains(t, screen, "Failures (1):")
+	assert.Contains(t, screen, "/data/d.txt: failed to download with HTTP")
+	assert.Contains(t, screen, "Downloading too slow")
+	// Finished files aren't listed with the active ones
+	assert.NotContains(t, screen, "/data/c.txt")
+	for _, line := range lines {
+		line = strings.TrimPrefix(line, "\x1b[H")
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\x1b[J"), "\x1b[K")
+		assert.LessOrEqual(t, len([]rune(line)), 120)
+	}
+
+	// A small terminal only shows what fits
+	screen = monitor.render(monitor.start.Add(11*time.Second), 40, 6)
+	assert.LessOrEqual(t, strings.Count(screen, "\r\n"), 6)
+
+	summary := monitor.summary()
+	assert.Contains(t, summary, "Transferred 1 of 4 files (2.0 kB)")
+	assert.Contains(t, summary, "with 1 retries; 1 failed:")
+	assert.Contains(t, summary, "/data/d.txt: failed to download with HTTP")
+}
+
+func TestNilTransferMonitor(t *testing.T) {
+	// Without the UI, the transfers report to a nil monitor
+	var monitor *transferMonitor
+	assert.NotPanics(t, func() {
+		monitor.started("/data/a.txt")
+		monitor.progress("/data/a.txt", 1, 2)
+		monitor.attemptFailed("/data/a.txt", "error")
+		monitor.finished("/data/a.txt", 2, nil)
+		monitor.stop()
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "/data/a.txt", truncateLeft("/data/a.txt", 20))
+	assert.Equal(t, "…a/a.txt", truncateLeft("/data/a.txt", 8))
+	assert.Equal(t, "/data/a…", truncateRight("/data/a.txt", 8))
+	assert.Equal(t, "", truncateLeft("/data/a.txt", 0))
+	assert.Equal(t, "██░░", progressBar(50, 100, 4))
+	assert.Equal(t, "░░░░", progressBar(50, 0, 4))
+}
@@ -52,4 +52,5 @@ func initCache(ctx context.Context) error {
 func init() {
 	cacheCmd.AddCommand(cacheServeCmd)
 	cacheServeCmd.Flags().AddFlag(portFlag)
+	addDryRunFlag(cacheServeCmd)
 }
@@ -35,6 +35,7 @@ import (
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/daemon"
 	"github.com/pelicanplatform/pelican/director"
+	"github.com/pelicanplatform/pelican/launchers"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/server_ui"
 	"github.com/pelicanplatform/pelican/server_utils"
@@ -95,7 +96,49 @@ func getNSAdsFromDirector() ([]common.NamespaceAdV2, error) {
 	return respNS, nil
 }
 
+// Periodically refresh the namespace ads from the director so that the cache's
+// authorization configuration picks up new namespaces and changes to their issuers
+func launchPeriodicNamespaceRefresh(ctx context.Context, egrp *errgroup.Group, cacheServer *cache_ui.CacheServer) {
+	refreshInterval := param.Cache_IssuerMetadataRefreshInterval.GetDuration()
+	if refreshInterval <= 0 {
+		log.Warningln("Cache.IssuerMetadataRefreshInterval is not positive; namespaces will not be refreshed from the director")
+		return
+	}
+	ticker := time.NewTicker(refreshInterval)
+	egrp.Go(func() error {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				nsAds, err := getNSAdsFromDirector()
+				if err != nil {
+					log.Errorln("Failed to refresh the namespaces from the director:", err)
+					continue
+				}
+				if param.Cache_ServeStaleOnOriginOutage.GetBool() {
+					nsAds = cache_ui.RetainNamespaces(cacheServer.GetNamespaceAds(), nsAds)
+				}
+				cacheServer.SetNamespaceAds(nsAds)
+				if err = xrootd.EmitAuthfile(cacheServer); err != nil {
+					log.Errorln("Failure when generating authfile:", err)
+				}
+				if err = xrootd.EmitScitokensConfig(cacheServer); err != nil {
+					log.Errorln("Failure when emitting the scitokens.cfg:", err)
+				} else {
+					log.Debugln("Refreshed the cache's authorization configuration with", len(nsAds), "namespaces")
+				}
+			}
+		}
+	})
+}
+
 func serveCache(cmd *cobra.Command, _ []string) error {
+	if isDryRun(cmd) {
+		return finishDryRun(cmd, dryRunCache(cmd.Context()))
+	}
+
 	cancel, err := serveCacheInternal(cmd.Context())
 	if err != nil {
 		cancel()
@@ -105,6 +148,34 @@ func serveCache(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+// Do the initialization of serveCacheInternal, through rendering the XRootD configuration,
+// without binding any ports or launching XRootD
+func dryRunCache(cmdCtx context.Context) *launchers.DryRunReport {
+	ctx, cancel := context.WithCancel(cmdCtx)
+	defer cancel()
+
+	report := launchers.NewDryRunReport(config.CacheType)
+	if !report.Check("server configuration", config.InitServer(ctx, config.CacheType)) {
+		return report
+	}
+	_, err := config.GetIssuerPrivateJWK()
+	report.Check("issuer key", err)
+	report.Check("TLS certificate", launchers.CheckTLSCredentials())
+	report.Check("hostnames", server_utils.CheckHostnames())
+
+	nsAds, err := getNSAdsFromDirector()
+	report.Check("director connectivity", err)
+	report.Check("registry connectivity", launchers.CheckServiceReachable(ctx, param.Federation_RegistryUrl.GetString()))
+
+	cacheServer := &cache_ui.CacheServer{}
+	cacheServer.SetNamespaceAds(nsAds)
+	if report.Check("XRootD environment", server_ui.CheckDefaults(cacheServer)) {
+		_, err = xrootd.ConfigXrootd(ctx, false)
+		report.Check("XRootD configuration", err)
+	}
+	return report
+}
+
 func serveCacheInternal(cmdCtx context.Context) (context.CancelFunc, error) {
 	// Use this context for any goroutines that needs to react to server shutdown
 	ctx, shutdownCancel := context.WithCancel(cmdCtx)
@@ -153,10 +224,15 @@ func serveCacheInternal(cmdCtx context.Context) (context.CancelFunc, error) {
 
 	viper.Set("Origin.NamespacePrefix", cachePrefix)
 
+	if err = server_utils.LaunchClockSkewCheck(ctx, egrp); err != nil {
+		return shutdownCancel, err
+	}
+
 	if err = server_ui.RegisterNamespaceWithRetry(ctx, egrp); err != nil {
 		return shutdownCancel, err
 	}
 
+	server_utils.LaunchHostnameCheck(ctx, egrp)
 	if err = server_ui.LaunchPeriodicAdvertise(ctx, egrp, []server_utils.XRootDServer{cacheServer}); err != nil {
 		return shutdownCancel, err
 	}
@@ -170,6 +246,24 @@ func serveCacheInternal(cmdCtx context.Context) (context.CancelFunc, error) {
 	if err := web_ui.ConfigureServerWebAPI(ctx, engine, egrp); err != nil {
 		return shutdownCancel, err
 	}
+	cache_ui.RegisterCacheAPI(engine, cacheServer)
+	server_ui.RegisterRegistrationStatusAPI(engine)
+	cache_ui.LaunchCachePolicyEnforcement(ctx, egrp, cacheServer)
+	if err = cache_ui.LaunchWriteBack(ctx, egrp, engine, cacheServer); err != nil {
+		return shutdownCancel, err
+	}
+	cache_ui.RegisterPfcConfigAPI(ctx, engine, func(ctx context.Context) error {
+		_, changed, err := xrootd.RegenerateXrootdConfig(false)
+		if err != nil {
+			return errors.Wrap(err, "failed to regenerate the XRootD configuration")
+		}
+		// The settings may have been changed back before the restart came due
+		if !changed {
+			log.Infoln("The XRootD configuration is unchanged; not restarting XRootD")
+			return nil
+		}
+		return daemon.RestartDaemons(ctx)
+	})
 
 	egrp.Go(func() (err error) {
 		if err = web_ui.RunEngine(ctx, engine, egrp); err != nil {
@@ -181,6 +275,9 @@ func serveCacheInternal(cmdCtx context.Context) (context.CancelFunc, error) {
 		if err = web_ui.ConfigureEmbeddedPrometheus(ctx, engine); err != nil {
 			return shutdownCancel, errors.Wrap(err, "Failed to configure embedded prometheus instance")
 		}
+		if err = web_ui.LaunchAlerting(ctx, egrp); err != nil {
+			return shutdownCancel, errors.Wrap(err, "Failed to launch alerting")
+		}
 
 		if err = web_ui.InitServerWebLogin(ctx); err != nil {
 			return shutdownCancel, err
@@ -193,6 +290,7 @@ func serveCacheInternal(cmdCtx context.Context) (context.CancelFunc, error) {
 	}
 
 	xrootd.LaunchXrootdMaintenance(ctx, cacheServer, 2*time.Minute)
+	launchPeriodicNamespaceRefresh(ctx, egrp, cacheServer)
 
 	log.Info("Launching cache")
 	launchers, err := xrootd.ConfigureLaunchers(false, configPath, false, true)
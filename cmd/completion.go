@@ -0,0 +1,155 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	completionCmd = &cobra.Command{
+		Use:   "completion {bash|zsh|fish}",
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: `Generate the autocompletion script for pelican for the specified shell.
+
+Besides commands and flags, the generated script completes federation paths
+for the object commands (e.g. "pelican object get osdf:///ospool/<TAB>") by
+querying the namespaces known to the federation's director.
+
+To load completions in the current bash session, run:
+
+  source <(pelican completion bash)
+
+To load completions for every new zsh session, run once:
+
+  pelican completion zsh > "${fpath[1]}/_pelican"
+
+To load completions for every new fish session, run once:
+
+  pelican completion fish > ~/.config/fish/completions/pelican.fish`,
+		ValidArgs:             []string{"bash", "zsh", "fish"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE:                  completionMain,
+	}
+)
+
+func init() {
+	// Complete federation paths for the commands that take them as arguments
+	for _, cmd := range []*cobra.Command{getCmd, putCmd, copyCmd, shareCmd, mkdirCmd, mvCmd} {
+		cmd.ValidArgsFunction = completeFederationPath
+	}
+}
+
+func completionMain(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletionV2(cmd.OutOrStdout(), true)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(cmd.OutOrStdout())
+	case "fish":
+		return cmd.Root().GenFishCompletion(cmd.OutOrStdout(), true)
+	}
+	return errors.Errorf("Unsupported shell %q", args[0])
+}
+
+// Get the namespace prefixes advertised to the federation's director
+func getFederationNamespaces(ctx context.Context) ([]string, error) {
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	if directorUrl == "" {
+		return nil, errors.New("Director endpoint URL is not known")
+	}
+	nsUrl, err := url.JoinPath(directorUrl, "api", "v2.0", "director", "listNamespaces")
+	if err != nil {
+		return nil, err
+	}
+	namespaceAds := []common.NamespaceAdV2{}
+	if err = getFederationJSON(ctx, nsUrl, &namespaceAds); err != nil {
+		return nil, err
+	}
+	prefixes := make([]string, 0, len(namespaceAds))
+	seen := make(map[string]bool)
+	for _, ns := range namespaceAds {
+		if !seen[ns.Path] {
+			seen[ns.Path] = true
+			prefixes = append(prefixes, ns.Path)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+// Complete a federation path, either osdf:///<path> or pelican://<federation>/<path>,
+// against the federation's namespace prefixes. Anything else is completed as a local file.
+func completeFederationPath(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	urlPrefix := ""
+	pathPart := ""
+	if strings.HasPrefix(toComplete, "osdf://") {
+		urlPrefix = "osdf://"
+		pathPart = strings.TrimPrefix(toComplete, "osdf://")
+		// The client treats osdf://<path> the same as osdf:///<path>
+		if !strings.HasPrefix(pathPart, "/") {
+			urlPrefix = "osdf:/"
+			pathPart = "/" + pathPart
+		}
+	} else if strings.HasPrefix(toComplete, "pelican://") {
+		remainder := strings.TrimPrefix(toComplete, "pelican://")
+		host, path, found := strings.Cut(remainder, "/")
+		if !found || host == "" {
+			// Still typing the federation hostname
+			return nil, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+		viper.Set("Federation.DiscoveryUrl", "https://"+host)
+		urlPrefix = "pelican://" + host
+		pathPart = "/" + path
+	} else {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	if err := config.InitClient(); err != nil {
+		cobra.CompErrorln("Failed to initialize the client: " + err.Error())
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	prefixes, err := getFederationNamespaces(ctx)
+	if err != nil {
+		cobra.CompErrorln("Failed to get the federation namespaces: " + err.Error())
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := make([]string, 0)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(prefix, pathPart) {
+			completions = append(completions, urlPrefix+prefix+"/")
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
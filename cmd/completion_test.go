@@ -0,0 +1,71 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestCompleteFederationPath(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/director/listNamespaces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		nsAds := []common.NamespaceAdV2{{Path: "/foo/bar"}, {Path: "/foo/baz"}, {Path: "/other"}, {Path: "/foo/bar"}}
+		body, err := json.Marshal(nsAds)
+		require.NoError(t, err)
+		_, err = w.Write(body)
+		require.NoError(t, err)
+	}))
+	defer svr.Close()
+
+	viper.Set("ConfigDir", t.TempDir())
+	viper.Set("Federation.DirectorUrl", svr.URL)
+
+	t.Run("osdf-triple-slash", func(t *testing.T) {
+		completions, directive := completeFederationPath(nil, nil, "osdf:///foo")
+		assert.Equal(t, []string{"osdf:///foo/bar/", "osdf:///foo/baz/"}, completions)
+		assert.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+	})
+
+	t.Run("osdf-double-slash", func(t *testing.T) {
+		completions, _ := completeFederationPath(nil, nil, "osdf://ot")
+		assert.Equal(t, []string{"osdf://other/"}, completions)
+	})
+
+	t.Run("local-path", func(t *testing.T) {
+		completions, directive := completeFederationPath(nil, nil, "./local")
+		assert.Empty(t, completions)
+		assert.Equal(t, cobra.ShellCompDirectiveDefault, directive)
+	})
+}
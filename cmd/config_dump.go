@@ -0,0 +1,115 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/config"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect Pelican's configuration",
+	}
+
+	configDumpCmd = &cobra.Command{
+		Use:   "dump",
+		Short: "Print the effective configuration and where each value came from",
+		Long: `Print the value of every parameter after merging Pelican's defaults, the config
+file and the files it includes, and the environment, along with the source of
+each value: "default", "yaml", or "env".  Parameters without a value are omitted
+unless --all is given.  Values of parameters holding credentials are redacted.
+
+Only environment variables with the binary's preferred prefix are read (PELICAN_
+for pelican, OSDF_ for osdf); variables naming a parameter under another prefix
+are listed as ignored.`,
+		Args:         cobra.NoArgs,
+		RunE:         configDumpMain,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	configDumpCmd.Flags().Bool("all", false, "Also print the parameters without a value")
+	configCmd.AddCommand(configDumpCmd)
+}
+
+// Flatten the typed configuration into the parameter names and their typed values
+func flattenEffectiveConfig(value reflect.Value, prefix string, out map[string]reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		name := prefix + value.Type().Field(i).Name
+		if _, isParam := field.Type().FieldByName("Source"); isParam {
+			out[name] = field
+		} else if field.Kind() == reflect.Struct {
+			flattenEffectiveConfig(field, name+".", out)
+		}
+	}
+}
+
+func printEffectiveConfig(w io.Writer, effective interface{}, all bool) {
+	params := map[string]reflect.Value{}
+	flattenEffectiveConfig(reflect.ValueOf(effective).Elem(), "", params)
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := params[name].FieldByName("Source").String()
+		if source == "" && !all {
+			continue
+		}
+		if source == "" {
+			source = "unset"
+		}
+		fmt.Fprintf(w, "%s = %v (%s)\n", name, params[name].FieldByName("Value").Interface(), source)
+	}
+}
+
+func configDumpMain(cmd *cobra.Command, args []string) error {
+	effective, err := config.GetEffectiveConfig()
+	if err != nil {
+		return err
+	}
+	for _, envName := range config.GetIgnoredConfigEnv() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: ignoring the environment variable %s; this binary reads the %s_ prefix\n", envName, config.GetPreferredPrefix())
+	}
+
+	if outputJSON {
+		effectiveJSON, err := json.MarshalIndent(effective, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the configuration to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(effectiveJSON))
+		return nil
+	}
+	all, _ := cmd.Flags().GetBool("all")
+	printEffectiveConfig(cmd.OutOrStdout(), effective, all)
+	return nil
+}
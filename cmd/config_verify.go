@@ -0,0 +1,85 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/config"
+)
+
+var configVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the configuration strictly and report every problem",
+	Long: `Check the configuration, including the config files it includes and the
+environment, as StrictConfigValidation does when Pelican starts: keys of the config
+files that aren't Pelican parameters, values that don't match the type of their
+parameter or violate its constraints (e.g. invalid URLs), and settings that can't
+work together.  Give the modules the configuration is for with --module to also
+check the combination of servers.
+
+The command exits with an error if any problem is found.`,
+	Args:         cobra.NoArgs,
+	RunE:         configVerifyMain,
+	SilenceUsage: true,
+}
+
+func init() {
+	configVerifyCmd.Flags().StringSlice("module", []string{}, "The modules the configuration is for, e.g. origin,director")
+	configCmd.AddCommand(configVerifyCmd)
+}
+
+func configVerifyMain(cmd *cobra.Command, args []string) error {
+	modules, err := cmd.Flags().GetStringSlice("module")
+	if err != nil {
+		return err
+	}
+	servers := config.NewServerType()
+	for _, module := range modules {
+		if !servers.SetString(module) {
+			return errors.Errorf("Unknown module %q", module)
+		}
+	}
+
+	problems, err := config.VerifyConfig(servers)
+	if err != nil {
+		return err
+	}
+	if outputJSON {
+		problemsJSON, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the problems to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(problemsJSON))
+	} else if len(problems) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "The configuration is valid")
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintln(cmd.OutOrStdout(), "  -", problem.String())
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("Found %d problems with the configuration", len(problems))
+	}
+	return nil
+}
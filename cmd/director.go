@@ -71,6 +71,7 @@ func init() {
 
 	// Set up flags for the command
 	directorServeCmd.Flags().AddFlag(portFlag)
+	addDryRunFlag(directorServeCmd)
 
 	directorServeCmd.Flags().StringP("default-response", "", "", "Set whether the default endpoint should redirect clients to caches or origins")
 	err := viper.BindPFlag("Director.DefaultResponse", directorServeCmd.Flags().Lookup("default-response"))
@@ -31,6 +31,9 @@ var (
 		Long: `Starts pelican with a list of enabled modules [registry, director, cache, origin] to enable better
 		 end-to-end and integration testing.
 
+		 The localcache module runs on its own as an on-node cache for batch jobs, which download
+		 objects through the unix socket at LocalCache.Socket.
+
 		 If the director or namespace registry are enabled, then ensure there is a corresponding url in the
 		 pelican.yaml file.
 
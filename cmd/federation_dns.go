@@ -0,0 +1,85 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+var (
+	federationDNSCmd = &cobra.Command{
+		Use:   "dns-records",
+		Short: "Print the DNS records that publish the federation's services",
+		Long: `Print the zone file records a federation publishes so clients and servers
+can discover its services through DNS when the federation's
+.well-known/pelican-configuration endpoint is unreachable.
+
+The director and registry are published as SRV records at
+_pelican-director._tcp.<domain> and _pelican-registry._tcp.<domain>, and
+the endpoints of the discovery document as key=value TXT records at
+_pelican.<domain>, where <domain> is the host of Federation.DiscoveryUrl.`,
+		RunE:         federationDNSMain,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	federationDNSCmd.Flags().Duration("ttl", time.Hour, "The time to live of the generated records")
+	federationCmd.AddCommand(federationDNSCmd)
+}
+
+func federationDNSMain(cmd *cobra.Command, args []string) error {
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+	ttl, err := cmd.Flags().GetDuration("ttl")
+	if err != nil {
+		return err
+	}
+
+	discoveryUrl := param.Federation_DiscoveryUrl.GetString()
+	if discoveryUrl == "" {
+		return errors.New("No federation specified; give the federation name (-f)")
+	}
+	if !strings.Contains(discoveryUrl, "://") {
+		discoveryUrl = "https://" + discoveryUrl
+	}
+	fedUrl, err := url.Parse(discoveryUrl)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid federation discovery URL %s", discoveryUrl)
+	}
+
+	records, err := config.FederationDNSRecords(fedUrl.Hostname(), config.GetFederation(), ttl)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate the federation's DNS records")
+	}
+	for _, record := range records {
+		fmt.Fprintln(cmd.OutOrStdout(), record)
+	}
+	return nil
+}
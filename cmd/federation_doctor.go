@@ -0,0 +1,423 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// An inconsistency the doctor found in the federation
+	doctorFinding struct {
+		Level   string `json:"level"`
+		Check   string `json:"check"`
+		Subject string `json:"subject"`
+		Message string `json:"message"`
+	}
+
+	federationDoctorReport struct {
+		Federation     string          `json:"federation"`
+		ServersChecked []string        `json:"servers_checked"`
+		Findings       []doctorFinding `json:"findings"`
+	}
+
+	// A subset of the director's server listing response
+	doctorServer struct {
+		Name   string            `json:"name"`
+		Type   common.ServerType `json:"type"`
+		WebURL string            `json:"webUrl"`
+	}
+
+	// A subset of the registry's namespace listing response
+	registeredNamespace struct {
+		Prefix string `json:"prefix"`
+	}
+)
+
+const (
+	doctorError   = "error"
+	doctorWarning = "warning"
+
+	// Certificates expiring sooner than this are reported
+	certExpiryWarning = 14 * 24 * time.Hour
+)
+
+var (
+	federationDoctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the federation's services for inconsistencies between them",
+		Long: `Query the registry, the director, and a sample of the origins and caches
+of the federation and report inconsistencies between them:
+
+- namespaces that are registered but not advertised by any origin
+- namespaces and caches advertised to the director without an approved registration
+- token issuers of advertised namespaces that don't serve their public keys
+- servers whose clock is off from the local clock
+- servers whose TLS certificate fails verification or expires soon
+
+The command exits with an error if any of the checks fails.`,
+		RunE:         federationDoctorMain,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	flagSet := federationDoctorCmd.Flags()
+	flagSet.Int("sample", 5, "The number of origins and of caches to check the clock and certificate of")
+	flagSet.Duration("max-skew", 30*time.Second, "The largest clock difference tolerated between a server and the local host")
+	federationCmd.AddCommand(federationDoctorCmd)
+}
+
+func (report *federationDoctorReport) addFinding(level, check, subject, format string, args ...interface{}) {
+	report.Findings = append(report.Findings, doctorFinding{
+		Level:   level,
+		Check:   check,
+		Subject: subject,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Whether the path is the prefix or beneath it
+func isUnderPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Compare the namespaces and caches registered with the registry against the ones advertised
+// to the director
+func compareRegistrations(report *federationDoctorReport, registered []string, advertised []common.NamespaceAdV2, servers []doctorServer) {
+	pathSet := make(map[string]bool)
+	advertisedPaths := []string{}
+	for _, ns := range advertised {
+		path := strings.TrimSuffix(ns.Path, "/")
+		if !pathSet[path] {
+			pathSet[path] = true
+			advertisedPaths = append(advertisedPaths, path)
+		}
+	}
+	sort.Strings(advertisedPaths)
+	registeredCaches := make(map[string]bool)
+	cacheNames := []string{}
+	registeredNamespaces := []string{}
+	for _, prefix := range registered {
+		if cacheName, isCache := strings.CutPrefix(prefix, "/caches/"); isCache {
+			registeredCaches[cacheName] = true
+			cacheNames = append(cacheNames, cacheName)
+		} else {
+			registeredNamespaces = append(registeredNamespaces, prefix)
+		}
+	}
+
+	for _, prefix := range registeredNamespaces {
+		served := false
+		for _, path := range advertisedPaths {
+			if isUnderPrefix(path, prefix) {
+				served = true
+				break
+			}
+		}
+		if !served {
+			report.addFinding(doctorWarning, "namespace", prefix, "The namespace is registered but no origin advertises it to the director")
+		}
+	}
+	for _, path := range advertisedPaths {
+		registered := false
+		for _, prefix := range registeredNamespaces {
+			if isUnderPrefix(path, prefix) {
+				registered = true
+				break
+			}
+		}
+		if !registered {
+			report.addFinding(doctorError, "namespace", path, "The namespace is advertised to the director but has no approved registration")
+		}
+	}
+
+	advertisedCaches := make(map[string]bool)
+	for _, server := range servers {
+		if server.Type != common.CacheType {
+			continue
+		}
+		advertisedCaches[server.Name] = true
+		if !registeredCaches[server.Name] {
+			report.addFinding(doctorError, "cache", server.Name, "The cache is advertised to the director but has no approved registration")
+		}
+	}
+	for _, cacheName := range cacheNames {
+		if !advertisedCaches[cacheName] {
+			report.addFinding(doctorWarning, "cache", cacheName, "The cache is registered but doesn't advertise to the director")
+		}
+	}
+}
+
+// Check that the issuer serves its public keys through its openid-configuration
+func checkIssuerKeys(ctx context.Context, issuerUrl string) error {
+	metadata := struct {
+		JwksUri string `json:"jwks_uri"`
+	}{}
+	metadataUrl := strings.TrimSuffix(issuerUrl, "/") + "/.well-known/openid-configuration"
+	if err := getFederationJSON(ctx, metadataUrl, &metadata); err != nil {
+		return err
+	}
+	if metadata.JwksUri == "" {
+		return errors.Errorf("the metadata at %s has no jwks_uri", metadataUrl)
+	}
+	keys := json.RawMessage{}
+	if err := getFederationJSON(ctx, metadata.JwksUri, &keys); err != nil {
+		return err
+	}
+	keySet, err := jwk.Parse(keys)
+	if err != nil {
+		return errors.Wrapf(err, "the public keys at %s are invalid", metadata.JwksUri)
+	}
+	if keySet.Len() == 0 {
+		return errors.Errorf("the key set at %s is empty", metadata.JwksUri)
+	}
+	return nil
+}
+
+// Compare the clock of the server, as reported in the Date header of its responses,
+// against the local clock
+func checkServerClock(ctx context.Context, report *federationDoctorReport, name, serviceUrl string, maxSkew time.Duration) {
+	healthUrl, err := url.JoinPath(serviceUrl, "api", "v1.0", "health")
+	if err != nil {
+		report.addFinding(doctorError, "clock", name, "Invalid server URL %s: %v", serviceUrl, err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthUrl, nil)
+	if err != nil {
+		report.addFinding(doctorError, "clock", name, "Failed to create request to %s: %v", healthUrl, err)
+		return
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+version)
+	httpClient := http.Client{Transport: config.GetTransport(), Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		report.addFinding(doctorError, "clock", name, "Failed to query %s: %v", healthUrl, err)
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		report.addFinding(doctorWarning, "clock", name, "The server's responses have no valid Date header to check its clock with")
+		return
+	}
+	// The Date header has a resolution of a second; compare it against the middle of the request
+	localTime := start.Add(time.Since(start) / 2)
+	skew := serverTime.Sub(localTime).Truncate(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxSkew {
+		report.addFinding(doctorWarning, "clock", name, "The server's clock is %s off from the local clock, which makes tokens it issues or accepts appear expired or not yet valid", skew)
+	}
+}
+
+// Check that the server's TLS certificate passes verification and isn't about to expire
+func checkServerCertificate(report *federationDoctorReport, name, serviceUrl string) {
+	parsed, err := url.Parse(serviceUrl)
+	if err != nil || parsed.Scheme != "https" {
+		report.addFinding(doctorWarning, "certificate", name, "The server doesn't use https (%s)", serviceUrl)
+		return
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "443"
+	}
+	tlsConfig := &tls.Config{}
+	if transportConfig := config.GetTransport().TLSClientConfig; transportConfig != nil {
+		tlsConfig = transportConfig.Clone()
+	}
+	tlsConfig.ServerName = parsed.Hostname()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", net.JoinHostPort(parsed.Hostname(), port), tlsConfig)
+	if err != nil {
+		report.addFinding(doctorError, "certificate", name, "The TLS handshake with %s failed: %v", parsed.Host, err)
+		return
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return
+	}
+	if expiresIn := time.Until(certs[0].NotAfter); expiresIn < certExpiryWarning {
+		report.addFinding(doctorWarning, "certificate", name, "The server's certificate expires at %s, in %s",
+			certs[0].NotAfter.UTC().Format(time.RFC3339), expiresIn.Truncate(time.Minute))
+	}
+}
+
+// Pick up to `count` servers of the type at random
+func sampleServers(servers []doctorServer, serverType common.ServerType, count int) []doctorServer {
+	matching := []doctorServer{}
+	for _, server := range servers {
+		if server.Type == serverType && server.WebURL != "" {
+			matching = append(matching, server)
+		}
+	}
+	rand.Shuffle(len(matching), func(i, j int) { matching[i], matching[j] = matching[j], matching[i] })
+	if len(matching) > count {
+		matching = matching[:count]
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Name < matching[j].Name })
+	return matching
+}
+
+func getFederationDoctorReport(ctx context.Context, sample int, maxSkew time.Duration) (*federationDoctorReport, error) {
+	report := &federationDoctorReport{
+		Federation:     param.Federation_DiscoveryUrl.GetString(),
+		ServersChecked: []string{},
+		Findings:       []doctorFinding{},
+	}
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	registryUrl := param.Federation_RegistryUrl.GetString()
+	if directorUrl == "" || registryUrl == "" {
+		return nil, errors.New("The federation's director and registry URLs are not known; set the federation with --federation")
+	}
+
+	servers := []doctorServer{}
+	serversUrl, err := url.JoinPath(directorUrl, "api", "v1.0", "director_ui", "servers")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid director URL")
+	}
+	if err = getFederationJSON(ctx, serversUrl, &servers); err != nil {
+		return nil, errors.Wrap(err, "Failed to get the servers from the director")
+	}
+	advertised := []common.NamespaceAdV2{}
+	nsUrl, err := url.JoinPath(directorUrl, "api", "v2.0", "director", "listNamespaces")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid director URL")
+	}
+	if err = getFederationJSON(ctx, nsUrl, &advertised); err != nil {
+		return nil, errors.Wrap(err, "Failed to get the namespaces from the director")
+	}
+	// Unauthenticated requests only list the approved registrations
+	registrations := []registeredNamespace{}
+	regUrl, err := url.JoinPath(registryUrl, "api", "v1.0", "registry_ui", "namespaces")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid registry URL")
+	}
+	if err = getFederationJSON(ctx, regUrl, &registrations); err != nil {
+		return nil, errors.Wrap(err, "Failed to get the namespaces from the registry")
+	}
+	registered := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		registered = append(registered, registration.Prefix)
+	}
+	compareRegistrations(report, registered, advertised, servers)
+
+	issuers := make(map[string]bool)
+	for _, ns := range advertised {
+		for _, issuer := range ns.Issuer {
+			issuers[issuer.IssuerUrl.String()] = true
+		}
+	}
+	issuerUrls := make([]string, 0, len(issuers))
+	for issuerUrl := range issuers {
+		issuerUrls = append(issuerUrls, issuerUrl)
+	}
+	sort.Strings(issuerUrls)
+	for _, issuerUrl := range issuerUrls {
+		if err := checkIssuerKeys(ctx, issuerUrl); err != nil {
+			report.addFinding(doctorError, "issuer", issuerUrl, "The issuer doesn't serve its public keys, so tokens it issues can't be verified: %v", err)
+		}
+	}
+
+	checked := []doctorServer{{Name: "director", WebURL: directorUrl}, {Name: "registry", WebURL: registryUrl}}
+	checked = append(checked, sampleServers(servers, common.OriginType, sample)...)
+	checked = append(checked, sampleServers(servers, common.CacheType, sample)...)
+	for _, server := range checked {
+		report.ServersChecked = append(report.ServersChecked, server.Name)
+		checkServerClock(ctx, report, server.Name, server.WebURL, maxSkew)
+		checkServerCertificate(report, server.Name, server.WebURL)
+	}
+	return report, nil
+}
+
+func printFederationDoctorReport(w io.Writer, report *federationDoctorReport) {
+	fmt.Fprintln(w, "Federation:     ", report.Federation)
+	fmt.Fprintln(w, "Servers checked:", strings.Join(report.ServersChecked, ", "))
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(w, "No problems found")
+		return
+	}
+	fmt.Fprintln(w, "Findings:")
+	for _, finding := range report.Findings {
+		fmt.Fprintf(w, "  %-7s [%s] %s: %s\n", strings.ToUpper(finding.Level), finding.Check, finding.Subject, finding.Message)
+	}
+}
+
+func federationDoctorMain(cmd *cobra.Command, args []string) error {
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+	sample, err := cmd.Flags().GetInt("sample")
+	if err != nil {
+		return err
+	}
+	maxSkew, err := cmd.Flags().GetDuration("max-skew")
+	if err != nil {
+		return err
+	}
+
+	report, err := getFederationDoctorReport(cmd.Context(), sample, maxSkew)
+	if err != nil {
+		return err
+	}
+	if outputJSON {
+		reportJSON, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the report to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(reportJSON))
+	} else {
+		printFederationDoctorReport(cmd.OutOrStdout(), report)
+	}
+	errorCount := 0
+	for _, finding := range report.Findings {
+		if finding.Level == doctorError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return errors.Errorf("Found %d problems in the federation", errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func doctorSubjects(report *federationDoctorReport, level, check string) (subjects []string) {
+	for _, finding := range report.Findings {
+		if finding.Level == level && finding.Check == check {
+			subjects = append(subjects, finding.Subject)
+		}
+	}
+	return
+}
+
+func TestCompareRegistrations(t *testing.T) {
+	registered := []string{"/foo", "/bar", "/caches/cache-1", "/caches/cache-2"}
+	advertised := []common.NamespaceAdV2{
+		{Path: "/foo/data"},
+		{Path: "/foo/data"},
+		{Path: "/baz"},
+	}
+	servers := []doctorServer{
+		{Name: "origin-1", Type: common.OriginType},
+		{Name: "cache-1", Type: common.CacheType},
+		{Name: "cache-3", Type: common.CacheType},
+	}
+
+	report := &federationDoctorReport{}
+	compareRegistrations(report, registered, advertised, servers)
+	assert.Equal(t, []string{"/bar"}, doctorSubjects(report, doctorWarning, "namespace"))
+	assert.Equal(t, []string{"/baz"}, doctorSubjects(report, doctorError, "namespace"))
+	assert.Equal(t, []string{"cache-2"}, doctorSubjects(report, doctorWarning, "cache"))
+	assert.Equal(t, []string{"cache-3"}, doctorSubjects(report, doctorError, "cache"))
+}
+
+func TestCheckServerClock(t *testing.T) {
+	offset := time.Duration(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	report := &federationDoctorReport{}
+	checkServerClock(context.Background(), report, "server", server.URL, 30*time.Second)
+	assert.Empty(t, report.Findings)
+
+	offset = -5 * time.Minute
+	checkServerClock(context.Background(), report, "server", server.URL, 30*time.Second)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "clock", report.Findings[0].Check)
+	assert.Contains(t, report.Findings[0].Message, "5m")
+}
+
+func TestCheckServerCertificate(t *testing.T) {
+	// The test server's certificate isn't signed by a trusted CA
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	report := &federationDoctorReport{}
+	checkServerCertificate(report, "server", server.URL)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, doctorError, report.Findings[0].Level)
+
+	report = &federationDoctorReport{}
+	checkServerCertificate(report, "server", "http://example.com")
+	assert.Equal(t, []string{"server"}, doctorSubjects(report, doctorWarning, "certificate"))
+}
@@ -0,0 +1,277 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type (
+	serviceStatus struct {
+		URL     string `json:"url"`
+		Up      bool   `json:"up"`
+		Version string `json:"version,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+
+	federationStatus struct {
+		Federation           string        `json:"federation"`
+		Discovery            serviceStatus `json:"discovery"`
+		Director             serviceStatus `json:"director"`
+		Registry             serviceStatus `json:"registry"`
+		Origins              int           `json:"origins"`
+		Caches               int           `json:"caches"`
+		AdvertisedNamespaces int           `json:"advertised_namespaces"`
+		RegisteredNamespaces int           `json:"registered_namespaces"`
+		// The number of origins and caches advertising each Pelican version
+		ServerVersions map[string]int `json:"server_versions"`
+	}
+
+	// A subset of the director's server listing response
+	directorServer struct {
+		Name    string            `json:"name"`
+		Type    common.ServerType `json:"type"`
+		Version string            `json:"version"`
+	}
+)
+
+var (
+	federationCmd = &cobra.Command{
+		Use:   "federation",
+		Short: "Interact with a Pelican federation",
+	}
+
+	federationStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Print a summary of the health of the federation's services",
+		Long: `Query the discovery, director, and registry endpoints of the federation
+and print a summary of which services are up and their versions, along with
+the number of origins, caches, and namespaces known to the federation and the
+versions the servers advertised with.`,
+		RunE:         federationStatusMain,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	federationCmd.AddCommand(federationStatusCmd)
+}
+
+// Issue a GET request against the endpoint and decode the JSON response into `out`.
+// If `out` is nil, the response body is discarded.
+func getFederationJSON(ctx context.Context, endpoint string, out interface{}) error {
+	httpClient := http.Client{
+		Transport: config.GetTransport(),
+		Timeout:   10 * time.Second,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request to %s", endpoint)
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+version)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to query %s", endpoint)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read response from %s", endpoint)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s returned HTTP status %d", endpoint, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err = json.Unmarshal(body, out); err != nil {
+		return errors.Wrapf(err, "failed to parse response from %s", endpoint)
+	}
+	return nil
+}
+
+// Check the health endpoint of a Pelican web server at `serviceUrl`
+func checkServiceStatus(ctx context.Context, serviceUrl string) serviceStatus {
+	status := serviceStatus{URL: serviceUrl}
+	if serviceUrl == "" {
+		status.Error = "service URL is not known"
+		return status
+	}
+	healthUrl, err := url.JoinPath(serviceUrl, "api", "v1.0", "health")
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if err = getFederationJSON(ctx, healthUrl, nil); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Up = true
+
+	// Servers older than the version endpoint won't report their version
+	versionInfo := common.VersionInfo{}
+	if versionUrl, err := url.JoinPath(serviceUrl, "api", "v1.0", "version"); err == nil {
+		if err = getFederationJSON(ctx, versionUrl, &versionInfo); err == nil {
+			status.Version = versionInfo.Version
+		}
+	}
+	return status
+}
+
+func getFederationStatus(ctx context.Context) federationStatus {
+	fedStatus := federationStatus{
+		Federation:     param.Federation_DiscoveryUrl.GetString(),
+		ServerVersions: make(map[string]int),
+	}
+
+	if fedStatus.Federation != "" {
+		fedStatus.Discovery.URL = fedStatus.Federation
+		discoveryUrl := fedStatus.Federation
+		if !strings.HasPrefix(discoveryUrl, "http") {
+			discoveryUrl = "https://" + discoveryUrl
+		}
+		discoveryUrl = strings.TrimSuffix(discoveryUrl, "/") + "/.well-known/pelican-configuration"
+		if err := getFederationJSON(ctx, discoveryUrl, &config.FederationDiscovery{}); err != nil {
+			fedStatus.Discovery.Error = err.Error()
+		} else {
+			fedStatus.Discovery.Up = true
+		}
+	} else {
+		fedStatus.Discovery.Error = "no federation discovery URL configured"
+	}
+
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	fedStatus.Director = checkServiceStatus(ctx, directorUrl)
+	if fedStatus.Director.Up {
+		servers := []directorServer{}
+		if serversUrl, err := url.JoinPath(directorUrl, "api", "v1.0", "director_ui", "servers"); err == nil {
+			if err = getFederationJSON(ctx, serversUrl, &servers); err != nil {
+				fedStatus.Director.Error = err.Error()
+			}
+		}
+		for _, server := range servers {
+			if server.Type == common.OriginType {
+				fedStatus.Origins++
+			} else if server.Type == common.CacheType {
+				fedStatus.Caches++
+			}
+			serverVersion := server.Version
+			if serverVersion == "" {
+				serverVersion = "unknown"
+			}
+			fedStatus.ServerVersions[serverVersion]++
+		}
+
+		namespaces := []common.NamespaceAdV2{}
+		if nsUrl, err := url.JoinPath(directorUrl, "api", "v2.0", "director", "listNamespaces"); err == nil {
+			if err = getFederationJSON(ctx, nsUrl, &namespaces); err != nil {
+				fedStatus.Director.Error = err.Error()
+			}
+		}
+		prefixes := make(map[string]bool)
+		for _, ns := range namespaces {
+			prefixes[ns.Path] = true
+		}
+		fedStatus.AdvertisedNamespaces = len(prefixes)
+	}
+
+	registryUrl := param.Federation_RegistryUrl.GetString()
+	fedStatus.Registry = checkServiceStatus(ctx, registryUrl)
+	if fedStatus.Registry.Up {
+		// Unauthenticated requests only list the approved registrations
+		namespaces := []map[string]interface{}{}
+		if nsUrl, err := url.JoinPath(registryUrl, "api", "v1.0", "registry_ui", "namespaces"); err == nil {
+			if err = getFederationJSON(ctx, nsUrl, &namespaces); err != nil {
+				fedStatus.Registry.Error = err.Error()
+			}
+		}
+		fedStatus.RegisteredNamespaces = len(namespaces)
+	}
+
+	return fedStatus
+}
+
+func (status serviceStatus) String() string {
+	if status.Up && status.Version != "" {
+		return fmt.Sprintf("up (%s), version %s", status.URL, status.Version)
+	} else if status.Up {
+		return fmt.Sprintf("up (%s)", status.URL)
+	}
+	if status.URL == "" {
+		return fmt.Sprintf("down: %s", status.Error)
+	}
+	return fmt.Sprintf("down (%s): %s", status.URL, status.Error)
+}
+
+func printFederationStatus(w io.Writer, status federationStatus) {
+	fmt.Fprintln(w, "Federation:", status.Federation)
+	fmt.Fprintln(w, "Services:")
+	fmt.Fprintln(w, "  Discovery:", status.Discovery)
+	fmt.Fprintln(w, "  Director: ", status.Director)
+	fmt.Fprintln(w, "  Registry: ", status.Registry)
+	fmt.Fprintln(w, "Resources:")
+	fmt.Fprintln(w, "  Origins:              ", status.Origins)
+	fmt.Fprintln(w, "  Caches:               ", status.Caches)
+	fmt.Fprintln(w, "  Advertised namespaces:", status.AdvertisedNamespaces)
+	fmt.Fprintln(w, "  Registered namespaces:", status.RegisteredNamespaces)
+	if len(status.ServerVersions) > 0 {
+		fmt.Fprintln(w, "Advertised server versions:")
+		versions := make([]string, 0, len(status.ServerVersions))
+		for serverVersion := range status.ServerVersions {
+			versions = append(versions, serverVersion)
+		}
+		sort.Strings(versions)
+		for _, serverVersion := range versions {
+			fmt.Fprintf(w, "  %s: %d\n", serverVersion, status.ServerVersions[serverVersion])
+		}
+	}
+}
+
+func federationStatusMain(cmd *cobra.Command, args []string) error {
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+
+	status := getFederationStatus(cmd.Context())
+	if outputJSON {
+		statusJSON, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the federation status to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(statusJSON))
+		return nil
+	}
+	printFederationStatus(cmd.OutOrStdout(), status)
+	return nil
+}
@@ -19,7 +19,15 @@
 package main
 
 import (
+	"context"
+	"os"
+
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/client"
+	"github.com/pelicanplatform/pelican/param"
 )
 
 var (
@@ -28,3 +36,91 @@ var (
 		Short: "Interact with objects in the federation",
 	}
 )
+
+// Get the context for the transfers of an object command; it's done once the command's
+// --deadline passes
+func getTransferContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if deadline, _ := cmd.Flags().GetDuration("deadline"); deadline > 0 {
+		return context.WithTimeout(context.Background(), deadline)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Override Client.StageTimeout with the command's --stage-timeout, if it's set
+func setStageTimeout(cmd *cobra.Command) {
+	if cmd.Flags().Changed("stage-timeout") {
+		stageTimeout, _ := cmd.Flags().GetDuration("stage-timeout")
+		viper.Set("Client.StageTimeout", stageTimeout)
+	}
+}
+
+// Start recording the HTTP requests of the transfers if the command's --trace is set;
+// the returned function finishes the trace
+func startTrace(cmd *cobra.Command) func() {
+	traceFile, _ := cmd.Flags().GetString("trace")
+	if traceFile == "" {
+		return func() {}
+	}
+	stop, err := client.StartTrace(traceFile)
+	if err != nil {
+		log.Errorln(err)
+		os.Exit(1)
+	}
+	return func() {
+		if err := stop(); err != nil {
+			log.Warningln("Failed to finish the trace:", err)
+		}
+	}
+}
+
+// Replace the progress bars with the full-screen transfer UI if the command's --tui
+// is set.  The UI needs a terminal, so it's only shown where the progress bars would be.
+func setTUI(cmd *cobra.Command) {
+	if useTUI, _ := cmd.Flags().GetBool("tui"); !useTUI {
+		return
+	}
+	if !client.ObjectClientOptions.ProgressBars {
+		log.Warningln("Ignoring --tui as the output isn't an interactive terminal")
+		return
+	}
+	client.ObjectClientOptions.TUI = true
+	client.ObjectClientOptions.ProgressBars = false
+}
+
+// Replace the progress bars with JSON progress events on stderr if the command's
+// --progress-json is set.  Unlike the UI, the events don't need a terminal.
+func setProgressJSON(cmd *cobra.Command) {
+	if progressJSON, _ := cmd.Flags().GetBool("progress-json"); !progressJSON {
+		return
+	}
+	if client.ObjectClientOptions.TUI {
+		log.Warningln("Ignoring --tui as the progress is written as JSON")
+		client.ObjectClientOptions.TUI = false
+	}
+	client.ObjectClientOptions.ProgressJSON = true
+	client.ObjectClientOptions.ProgressBars = false
+}
+
+// Set the key encrypting uploads and decrypting downloads from the command's
+// --encryption-key-file, Client.EncryptionKeyFile or Client.EncryptionKey
+func setEncryptionKey(cmd *cobra.Command) {
+	keyFile := param.Client_EncryptionKeyFile.GetString()
+	if cmd.Flags().Changed("encryption-key-file") {
+		keyFile, _ = cmd.Flags().GetString("encryption-key-file")
+	}
+	var key []byte
+	var err error
+	if keyFile != "" {
+		key, err = client.ReadEncryptionKeyFile(keyFile)
+	} else if encodedKey := param.Client_EncryptionKey.GetString(); encodedKey != "" {
+		if key, err = client.ParseEncryptionKey(encodedKey); err != nil {
+			log.Errorln("Invalid Client.EncryptionKey:", err)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		log.Errorln(err)
+		os.Exit(1)
+	}
+	client.ObjectClientOptions.EncryptionKey = key
+}
@@ -52,6 +52,11 @@ func init() {
 	flagSet.StringP("cache", "c", "", "Cache to use")
 	flagSet.StringP("token", "t", "", "Token file to use for transfer")
 	flagSet.BoolP("recursive", "r", false, "Recursively copy a directory.  Forces methods to only be http to get the freshest directory contents")
+	flagSet.Duration("deadline", 0, "Abandon the transfers if they haven't completed within this duration, across all retries and sources")
+	flagSet.Duration("stage-timeout", 0, "Wait up to this duration for objects that are offline (e.g., on tape) to be staged by the server; overrides Client.StageTimeout")
+	flagSet.String("trace", "", "Record every HTTP request of the transfers (headers, timing, redirects and TLS details) to this file for diagnosing problems; credentials are redacted")
+	flagSet.Bool("tui", false, "Show a full-screen terminal UI with the progress, retries and errors of every file instead of the progress bars; useful for recursive transfers of many files")
+	flagSet.Bool("progress-json", false, "Write the progress of every file to stderr as newline-delimited JSON events (percent, bytes, rate, file index) instead of showing the progress bars, for tools wrapping the client")
 	flagSet.StringP("cache-list-name", "n", "xroot", "(Deprecated) Cache list to use, currently either xroot or xroots; may be ignored")
 	flagSet.Lookup("cache-list-name").Hidden = true
 	// All the deprecated or hidden flags that are only relevant if we are in historical "stashcp mode"
@@ -95,6 +100,7 @@ func copyMain(cmd *cobra.Command, args []string) {
 		log.Errorln(err)
 		os.Exit(1)
 	}
+	setStageTimeout(cmd)
 
 	if val, err := cmd.Flags().GetBool("version"); err == nil && val {
 		fmt.Println("Version:", version)
@@ -114,6 +120,8 @@ func copyMain(cmd *cobra.Command, args []string) {
 	} else {
 		client.ObjectClientOptions.ProgressBars = false
 	}
+	setTUI(cmd)
+	setProgressJSON(cmd)
 
 	if val, err := cmd.Flags().GetBool("namespaces"); err == nil && val {
 		namespaces, err := namespaces.GetNamespaces()
@@ -197,12 +205,17 @@ func copyMain(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	stopTrace := startTrace(cmd)
+	defer stopTrace()
+	ctx, cancel := getTransferContext(cmd)
+	defer cancel()
+
 	var result error
 	lastSrc := ""
 	for _, src := range source {
 		isRecursive, _ := cmd.Flags().GetBool("recursive")
 		client.ObjectClientOptions.Recursive = isRecursive
-		_, result = client.DoStashCPSingle(src, dest, splitMethods, isRecursive)
+		_, result = client.DoStashCPSingle(ctx, src, dest, splitMethods, isRecursive)
 		if result != nil {
 			lastSrc = src
 			break
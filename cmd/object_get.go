@@ -44,6 +44,12 @@ func init() {
 	flagSet.StringP("cache-list-name", "n", "xroot", "(Deprecated) Cache list to use, currently either xroot or xroots; may be ignored")
 	flagSet.Lookup("cache-list-name").Hidden = true
 	flagSet.String("caches", "", "A JSON file containing the list of caches")
+	flagSet.Duration("deadline", 0, "Abandon the transfers if they haven't completed within this duration, across all retries and sources")
+	flagSet.Duration("stage-timeout", 0, "Wait up to this duration for objects that are offline (e.g., on tape) to be staged by the server; overrides Client.StageTimeout")
+	flagSet.String("trace", "", "Record every HTTP request of the transfers (headers, timing, redirects and TLS details) to this file for diagnosing problems; credentials are redacted")
+	flagSet.Bool("tui", false, "Show a full-screen terminal UI with the progress, retries and errors of every file instead of the progress bars; useful for recursive transfers of many files")
+	flagSet.Bool("progress-json", false, "Write the progress of every file to stderr as newline-delimited JSON events (percent, bytes, rate, file index) instead of showing the progress bars, for tools wrapping the client")
+	flagSet.String("encryption-key-file", "", "File with the base64-encoded 256-bit key to decrypt encrypted objects with; overrides Client.EncryptionKeyFile")
 	objectCmd.AddCommand(getCmd)
 }
 
@@ -56,6 +62,7 @@ func getMain(cmd *cobra.Command, args []string) {
 		log.Errorln(err)
 		os.Exit(1)
 	}
+	setStageTimeout(cmd)
 
 	// Set the progress bars to the command line option
 	client.ObjectClientOptions.Token, _ = cmd.Flags().GetString("token")
@@ -67,6 +74,9 @@ func getMain(cmd *cobra.Command, args []string) {
 	} else {
 		client.ObjectClientOptions.ProgressBars = false
 	}
+	setTUI(cmd)
+	setProgressJSON(cmd)
+	setEncryptionKey(cmd)
 
 	log.Debugln("Len of source:", len(args))
 	if len(args) < 2 {
@@ -103,12 +113,17 @@ func getMain(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	stopTrace := startTrace(cmd)
+	defer stopTrace()
+	ctx, cancel := getTransferContext(cmd)
+	defer cancel()
+
 	var result error
 	lastSrc := ""
 	for _, src := range source {
 		isRecursive, _ := cmd.Flags().GetBool("recursive")
 		client.ObjectClientOptions.Recursive = isRecursive
-		_, result = client.DoGet(src, dest, isRecursive)
+		_, result = client.DoGet(ctx, src, dest, isRecursive)
 		if result != nil {
 			lastSrc = src
 			break
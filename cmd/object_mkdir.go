@@ -0,0 +1,65 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/client"
+	"github.com/pelicanplatform/pelican/config"
+)
+
+var (
+	mkdirCmd = &cobra.Command{
+		Use:   "mkdir {collection ...}",
+		Short: "Create collections (directories) in a Pelican federation",
+		Long: `Create collections at the origins of their namespaces through WebDAV.  The
+namespaces must be writable and the token must authorize writes to the collections
+(the storage.create or storage.modify scope).`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: mkdirMain,
+	}
+)
+
+func init() {
+	flagSet := mkdirCmd.Flags()
+	flagSet.StringP("token", "t", "", "Token file to use for the request")
+	flagSet.BoolP("parents", "p", false, "Create the missing collections above each collection in its namespace, and don't fail if the collection exists")
+	objectCmd.AddCommand(mkdirCmd)
+}
+
+func mkdirMain(cmd *cobra.Command, args []string) error {
+	client.ObjectClientOptions.Version = version
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+	client.ObjectClientOptions.Token, _ = cmd.Flags().GetString("token")
+
+	parents, _ := cmd.Flags().GetBool("parents")
+
+	ctx, cancel := getTransferContext(cmd)
+	defer cancel()
+	for _, collection := range args {
+		if err := client.DoMkdir(ctx, collection, parents); err != nil {
+			return errors.Wrapf(err, "Failed to create %s", collection)
+		}
+	}
+	return nil
+}
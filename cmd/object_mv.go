@@ -0,0 +1,65 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/client"
+	"github.com/pelicanplatform/pelican/config"
+)
+
+var (
+	mvCmd = &cobra.Command{
+		Use:   "mv {source} {destination}",
+		Short: "Move or rename an object in a Pelican federation",
+		Long: `Move or rename an object or collection at the origin of its namespace through
+WebDAV, without transferring its data.  Both paths must be in the same namespace, which
+must be writable, and an existing object at the destination isn't replaced.  As the
+object is removed from its old path, the token must have the storage.modify scope for
+both paths.  Moving a collection, along with everything in it, needs --recursive.`,
+		Args: cobra.ExactArgs(2),
+		RunE: mvMain,
+	}
+)
+
+func init() {
+	flagSet := mvCmd.Flags()
+	flagSet.StringP("token", "t", "", "Token file to use for the request")
+	flagSet.BoolP("recursive", "r", false, "Move a collection along with everything in it")
+	objectCmd.AddCommand(mvCmd)
+}
+
+func mvMain(cmd *cobra.Command, args []string) error {
+	client.ObjectClientOptions.Version = version
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+	client.ObjectClientOptions.Token, _ = cmd.Flags().GetString("token")
+
+	recursive, _ := cmd.Flags().GetBool("recursive")
+
+	ctx, cancel := getTransferContext(cmd)
+	defer cancel()
+	if err := client.DoMove(ctx, args[0], args[1], recursive); err != nil {
+		return errors.Wrapf(err, "Failed to move %s to %s", args[0], args[1])
+	}
+	return nil
+}
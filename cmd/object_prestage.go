@@ -0,0 +1,127 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/client"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+var (
+	prestageCmd = &cobra.Command{
+		Use:   "prestage {object ...}",
+		Short: "Bring objects stored on tape online without downloading them",
+		Long: `Ask the origins of the objects to bring them online from tape, so later
+transfers of the objects start right away.  The objects must be in publicly readable
+namespaces exported by origins in "hsm" mode.  By default, the command waits until
+every object is online, for up to --stage-timeout (Client.StageTimeout) if it's set.`,
+		RunE: prestageMain,
+	}
+)
+
+func init() {
+	flagSet := prestageCmd.Flags()
+	flagSet.StringP("file", "f", "", "Read the objects to stage from this file, one per line, in addition to the arguments")
+	flagSet.Bool("no-wait", false, "Request the staging of the objects without waiting for them to be online")
+	flagSet.Duration("stage-timeout", 0, "Stop waiting for the objects once this duration passes; overrides Client.StageTimeout")
+	objectCmd.AddCommand(prestageCmd)
+}
+
+// Read the objects listed in the file, one per line, skipping blank lines and comments
+func readObjectList(fileName string) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	objects := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		objects = append(objects, line)
+	}
+	return objects, scanner.Err()
+}
+
+func prestageMain(cmd *cobra.Command, args []string) error {
+	client.ObjectClientOptions.Version = version
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+	setStageTimeout(cmd)
+
+	objects := args
+	if fileName, _ := cmd.Flags().GetString("file"); fileName != "" {
+		listed, err := readObjectList(fileName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read the objects to stage from %s", fileName)
+		}
+		objects = append(objects, listed...)
+	}
+	if len(objects) == 0 {
+		return errors.New("No objects to stage were given")
+	}
+
+	ctx := context.Background()
+	if timeout := param.Client_StageTimeout.GetDuration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	noWait, _ := cmd.Flags().GetBool("no-wait")
+	done := 0
+	statuses, err := client.PrestageObjects(ctx, objects, !noWait, func(status client.StageStatus) {
+		done++
+		switch {
+		case status.Error != "":
+			fmt.Printf("[%d/%d] %s: failed: %s\n", done, len(objects), status.Path, status.Error)
+		case status.Online:
+			fmt.Printf("[%d/%d] %s: online\n", done, len(objects), status.Path)
+		default:
+			fmt.Printf("[%d/%d] %s: staging requested\n", done, len(objects), status.Path)
+		}
+	})
+
+	online := 0
+	for _, status := range statuses {
+		if status.Online {
+			online++
+		}
+	}
+	if online == len(objects) {
+		fmt.Printf("All %d objects are online\n", online)
+	} else {
+		fmt.Printf("%d of %d objects are online\n", online, len(objects))
+	}
+	return err
+}
@@ -40,6 +40,12 @@ func init() {
 	flagSet := putCmd.Flags()
 	flagSet.StringP("token", "t", "", "Token file to use for transfer")
 	flagSet.BoolP("recursive", "r", false, "Recursively upload a directory.  Forces methods to only be http to get the freshest directory contents")
+	flagSet.Duration("deadline", 0, "Abandon the transfers if they haven't completed within this duration, across all retries and sources")
+	flagSet.String("trace", "", "Record every HTTP request of the transfers (headers, timing, redirects and TLS details) to this file for diagnosing problems; credentials are redacted")
+	flagSet.Bool("tui", false, "Show a full-screen terminal UI with the progress, retries and errors of every file instead of the progress bars; useful for recursive transfers of many files")
+	flagSet.Bool("progress-json", false, "Write the progress of every file to stderr as newline-delimited JSON events (percent, bytes, rate, file index) instead of showing the progress bars, for tools wrapping the client")
+	flagSet.Bool("encrypt", false, "Encrypt the files before uploading them, so only holders of the key can read them, even from shared caches")
+	flagSet.String("encryption-key-file", "", "File with the base64-encoded 256-bit key to encrypt with; overrides Client.EncryptionKeyFile")
 	objectCmd.AddCommand(putCmd)
 }
 
@@ -63,6 +69,17 @@ func putMain(cmd *cobra.Command, args []string) {
 	} else {
 		client.ObjectClientOptions.ProgressBars = false
 	}
+	setTUI(cmd)
+	setProgressJSON(cmd)
+	client.ObjectClientOptions.Encrypt, _ = cmd.Flags().GetBool("encrypt")
+	if client.ObjectClientOptions.Encrypt {
+		setEncryptionKey(cmd)
+	}
+	if client.ObjectClientOptions.Encrypt && client.ObjectClientOptions.EncryptionKey == nil {
+		log.Errorln("--encrypt needs a key from --encryption-key-file, Client.EncryptionKeyFile or Client.EncryptionKey; " +
+			"one can be made with `openssl rand -base64 32`")
+		os.Exit(1)
+	}
 
 	log.Debugln("Len of source:", len(args))
 	if len(args) < 2 {
@@ -86,12 +103,17 @@ func putMain(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	stopTrace := startTrace(cmd)
+	defer stopTrace()
+	ctx, cancel := getTransferContext(cmd)
+	defer cancel()
+
 	var result error
 	lastSrc := ""
 	for _, src := range source {
 		isRecursive, _ := cmd.Flags().GetBool("recursive")
 		client.ObjectClientOptions.Recursive = isRecursive
-		_, result = client.DoPut(src, dest, isRecursive)
+		_, result = client.DoPut(ctx, src, dest, isRecursive)
 		if result != nil {
 			lastSrc = src
 			break
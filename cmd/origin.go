@@ -109,7 +109,7 @@ func init() {
 	originCmd.AddCommand(originServeCmd)
 
 	// The -m flag is used to specify what kind of backend we plan to use for the origin.
-	originServeCmd.Flags().StringP("mode", "m", "posix", "Set the mode for the origin service (default is 'posix')")
+	originServeCmd.Flags().StringP("mode", "m", "posix", "Set the mode for the origin service: 'posix', 's3' or 'hsm' (default is 'posix')")
 	if err := viper.BindPFlag("Origin.Mode", originServeCmd.Flags().Lookup("mode")); err != nil {
 		panic(err)
 	}
@@ -167,6 +167,7 @@ func init() {
 
 	// The port any web UI stuff will be served on
 	originServeCmd.Flags().AddFlag(portFlag)
+	addDryRunFlag(originServeCmd)
 
 	// origin token, used for creating and verifying tokens with
 	// the origin's signing jwk.
@@ -182,6 +183,7 @@ func init() {
 	if err := viper.BindPFlag("IssuerUrl", originTokenCreateCmd.Flags().Lookup("issuer")); err != nil {
 		panic(err)
 	}
+	originTokenCreateCmd.Flags().String("namespace", "", "Sign the token with the IssuerKey of this namespace in Origin.Exports instead of the origin's issuer key, and issue it from the namespace's own issuer.")
 	originTokenCreateCmd.Flags().String("private-key", viper.GetString("IssuerKey"), "Filepath designating the location of the private key in PEM format to be used for signing, if different from the origin's default.")
 	if err := viper.BindPFlag("IssuerKey", originTokenCreateCmd.Flags().Lookup("private-key")); err != nil {
 		panic(err)
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/server_utils"
 	"github.com/pelicanplatform/pelican/utils"
 )
 
@@ -132,8 +133,25 @@ func cliTokenCreate(cmd *cobra.Command, args []string) error {
 	}
 	tokenConfig.Subject = subject
 
-	// Finally, create the token
-	token, err := tokenConfig.CreateToken()
+	// Finally, create the token, signed with the namespace's own key if requested
+	var token string
+	if namespace, _ := cmd.Flags().GetString("namespace"); namespace != "" {
+		// A namespace with its own key is its own issuer
+		if tokenConfig.Issuer == "" && !cmd.Flags().Changed("issuer") {
+			issuerUrl, issuerErr := server_utils.GetNamespaceIssuerURL(namespace)
+			if issuerErr != nil {
+				return errors.Wrapf(issuerErr, "Failed to determine the issuer of namespace %s", namespace)
+			}
+			tokenConfig.Issuer = issuerUrl.String()
+		}
+		key, keyErr := config.GetNamespaceIssuerPrivateJWK(namespace)
+		if keyErr != nil {
+			return errors.Wrapf(keyErr, "Failed to load the signing key of namespace %s", namespace)
+		}
+		token, err = tokenConfig.CreateTokenWithKey(key)
+	} else {
+		token, err = tokenConfig.CreateToken()
+	}
 	if err != nil {
 		return errors.Wrap(err, "Failed to create the token")
 	}
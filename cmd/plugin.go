@@ -20,6 +20,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/fs"
 	"net/url"
@@ -375,7 +376,7 @@ func moveObjects(source []string, methods []string, upload bool, wg *sync.WaitGr
 		if upload {
 			source = append(source, transfer.localFile)
 			log.Debugln("Uploading:", transfer.localFile, "to", transfer.url)
-			transferResults, result = client.DoStashCPSingle(transfer.localFile, transfer.url, methods, false)
+			transferResults, result = client.DoStashCPSingle(context.Background(), transfer.localFile, transfer.url, methods, false)
 		} else {
 			source = append(source, transfer.url)
 			log.Debugln("Downloading:", transfer.url, "to", transfer.localFile)
@@ -390,7 +391,7 @@ func moveObjects(source []string, methods []string, upload bool, wg *sync.WaitGr
 				if url.Query().Get("pack") != "" {
 					localFile = filepath.Dir(localFile)
 				}
-				transferResults, result = client.DoStashCPSingle(transfer.url, localFile, methods, false)
+				transferResults, result = client.DoStashCPSingle(context.Background(), transfer.url, localFile, methods, false)
 			}
 		}
 		startTime := time.Now().Unix()
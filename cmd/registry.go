@@ -55,4 +55,5 @@ func init() {
 	registryCmd.AddCommand(registryServeCmd)
 	// Set up flags for the command
 	registryServeCmd.Flags().AddFlag(portFlag)
+	addDryRunFlag(registryServeCmd)
 }
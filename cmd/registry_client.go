@@ -28,10 +28,18 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pelicanplatform/pelican/client"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/registry"
@@ -46,6 +54,7 @@ import (
 var withIdentity bool
 var prefix string
 var pubkeyPath string
+var namespaceToken string
 
 func getNamespaceEndpoint() (string, error) {
 	namespaceEndpoint := param.Federation_RegistryUrl.GetString()
@@ -103,7 +112,7 @@ func registerANamespace(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	privateKeyRaw, err := config.LoadPrivateKey(param.IssuerKey.GetString())
+	privateKeyRaw, err := config.LoadSigningKey(param.IssuerKey.GetString())
 	if err != nil {
 		log.Error("Failed to load private key", err)
 		os.Exit(1)
@@ -114,6 +123,10 @@ func registerANamespace(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if acceptedTerms, _ := cmd.Flags().GetString("accept-terms"); acceptedTerms != "" {
+		viper.Set("Server.AcceptedTermsOfService", acceptedTerms)
+	}
+
 	if withIdentity {
 		err := registry.NamespaceRegisterWithIdentity(privateKey, registrationEndpointURL, prefix)
 		if err != nil {
@@ -169,43 +182,361 @@ func listAllNamespaces(cmd *cobra.Command, args []string) {
 
 	listEndpoint, err := url.JoinPath(namespaceEndpoint, "api", "v1.0", "registry")
 	if err != nil {
-		log.Errorf("Failed to construction list endpoint URL: %v", err)
+		log.Errorf("Failed to construct list endpoint URL: %v", err)
+		os.Exit(1)
 	}
 
-	err = registry.NamespaceList(listEndpoint)
+	query := url.Values{}
+	for _, filter := range []string{"status", "prefix", "institution", "server-type"} {
+		if value, _ := cmd.Flags().GetString(filter); value != "" {
+			query.Set(strings.ReplaceAll(filter, "-", "_"), value)
+		}
+	}
+
+	namespaces, err := registry.NamespaceFetch(listEndpoint, query, namespaceToken)
 	if err != nil {
 		log.Errorf("Failed to list namespace information: %v", err)
 		os.Exit(1)
 	}
+
+	if outputJSON {
+		printNamespaceJSON(namespaces)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tSTATUS\tINSTITUTION\tSITE")
+	for _, ns := range namespaces {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", ns.ID, ns.Prefix, namespaceStatus(ns), ns.AdminMetadata.Institution, ns.AdminMetadata.SiteName)
+	}
+	w.Flush()
+}
+
+func searchNamespaces(cmd *cobra.Command, args []string) {
+	err := config.InitClient()
+	if err != nil {
+		log.Errorln("Failed to initialize the client:", err)
+		os.Exit(1)
+	}
+
+	namespaceEndpoint, err := getNamespaceEndpoint()
+	if err != nil {
+		log.Errorln("Failed to get RegistryUrl from config:", err)
+		os.Exit(1)
+	}
+
+	searchEndpoint, err := url.JoinPath(namespaceEndpoint, "api", "v1.0", "registry", "search")
+	if err != nil {
+		log.Errorf("Failed to construct search endpoint URL: %v", err)
+		os.Exit(1)
+	}
+
+	query := url.Values{}
+	query.Set("q", args[0])
+	if substring, _ := cmd.Flags().GetBool("substring"); substring {
+		query.Set("match", "substring")
+	}
+	for _, filter := range []string{"status", "server-type"} {
+		if value, _ := cmd.Flags().GetString(filter); value != "" {
+			query.Set(strings.ReplaceAll(filter, "-", "_"), value)
+		}
+	}
+	for _, page := range []string{"page", "page-size"} {
+		if value, _ := cmd.Flags().GetInt(page); value > 0 {
+			query.Set(strings.ReplaceAll(page, "-", "_"), strconv.Itoa(value))
+		}
+	}
+
+	result, err := registry.NamespaceSearch(searchEndpoint, query)
+	if err != nil {
+		log.Errorf("Failed to search namespaces: %v", err)
+		os.Exit(1)
+	}
+
+	if outputJSON {
+		printNamespaceJSON(result)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tSTATUS\tINSTITUTION\tSITE")
+	for _, ns := range result.Namespaces {
+		status := ns.AdminMetadata.Status.String()
+		if status == "" {
+			status = registry.Unknown.String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", ns.ID, ns.Prefix, status, ns.AdminMetadata.Institution, ns.AdminMetadata.SiteName)
+	}
+	w.Flush()
+	if shown := (result.Page-1)*result.PageSize + len(result.Namespaces); shown < result.Total {
+		fmt.Printf("Showing %d of %d matches; use --page to see more\n", shown, result.Total)
+	}
+}
+
+func getNamespace(cmd *cobra.Command, args []string) {
+	err := config.InitClient()
+	if err != nil {
+		log.Errorln("Failed to initialize the client:", err)
+		os.Exit(1)
+	}
+
+	if prefix == "" {
+		log.Error("Error: prefix is required")
+		os.Exit(1)
+	}
+
+	namespaceEndpoint, err := getNamespaceEndpoint()
+	if err != nil {
+		log.Errorln("Failed to get RegistryUrl from config:", err)
+		os.Exit(1)
+	}
+
+	listEndpoint, err := url.JoinPath(namespaceEndpoint, "api", "v1.0", "registry")
+	if err != nil {
+		log.Errorf("Failed to construct list endpoint URL: %v", err)
+		os.Exit(1)
+	}
+
+	// The prefix filter also matches nested namespaces, so look for the exact match
+	namespaces, err := registry.NamespaceFetch(listEndpoint, url.Values{"prefix": []string{prefix}}, namespaceToken)
+	if err != nil {
+		log.Errorf("Failed to get namespace information for prefix %s: %v", prefix, err)
+		os.Exit(1)
+	}
+	var ns *registry.Namespace
+	for _, candidate := range namespaces {
+		if candidate.Prefix == path.Clean(prefix) {
+			ns = candidate
+			break
+		}
+	}
+	if ns == nil {
+		log.Errorf("Prefix %s is not registered at the registry", prefix)
+		os.Exit(1)
+	}
+
+	if outputJSON {
+		printNamespaceJSON(ns)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", ns.ID)
+	fmt.Fprintf(w, "Prefix:\t%s\n", ns.Prefix)
+	fmt.Fprintf(w, "Status:\t%s\n", namespaceStatus(ns))
+	fmt.Fprintf(w, "Institution:\t%s\n", ns.AdminMetadata.Institution)
+	fmt.Fprintf(w, "Site name:\t%s\n", ns.AdminMetadata.SiteName)
+	fmt.Fprintf(w, "Description:\t%s\n", ns.AdminMetadata.Description)
+	fmt.Fprintf(w, "Security contact:\t%s\n", ns.AdminMetadata.SecurityContactUserID)
+	if !ns.AdminMetadata.CreatedAt.IsZero() {
+		fmt.Fprintf(w, "Created at:\t%s\n", ns.AdminMetadata.CreatedAt.Format(time.RFC3339))
+	}
+	if !ns.AdminMetadata.ApprovedAt.IsZero() {
+		fmt.Fprintf(w, "Approved at:\t%s\n", ns.AdminMetadata.ApprovedAt.Format(time.RFC3339))
+	}
+	if keySet, err := jwk.ParseString(ns.Pubkey); err != nil {
+		fmt.Fprintf(w, "Public keys:\tfailed to parse the registered public key: %v\n", err)
+	} else {
+		fmt.Fprintf(w, "Public keys:\t%d\n", keySet.Len())
+		for idx := 0; idx < keySet.Len(); idx++ {
+			key, _ := keySet.Key(idx)
+			fmt.Fprintf(w, "  - kid:\t%s (%s, %s)\n", key.KeyID(), key.KeyType(), key.Algorithm())
+		}
+	}
+	w.Flush()
+}
+
+func checkNamespace(cmd *cobra.Command, args []string) {
+	err := config.InitClient()
+	if err != nil {
+		log.Errorln("Failed to initialize the client:", err)
+		os.Exit(1)
+	}
+
+	if prefix == "" {
+		log.Error("Error: prefix is required")
+		os.Exit(1)
+	}
+
+	namespaceEndpoint, err := getNamespaceEndpoint()
+	if err != nil {
+		log.Errorln("Failed to get RegistryUrl from config:", err)
+		os.Exit(1)
+	}
+
+	checkEndpoint, err := url.JoinPath(namespaceEndpoint, "api", "v1.0", "registry")
+	if err != nil {
+		log.Errorf("Failed to construction check endpoint URL: %v", err)
+	}
+
+	privateKey, err := config.GetIssuerPrivateJWK()
+	if err != nil {
+		log.Error("Failed to load private key", err)
+		os.Exit(1)
+	}
+
+	result, err := registry.NamespaceCheck(checkEndpoint, privateKey, prefix)
+	if err != nil {
+		log.Errorf("Failed to check prefix %s: %v", prefix, err)
+		os.Exit(1)
+	}
+
+	if outputJSON {
+		printNamespaceJSON(result)
+	} else {
+		fmt.Println("Prefix:    ", result.Prefix)
+		fmt.Println("Registered:", result.Registered)
+		if result.Registered {
+			fmt.Println("Key match: ", result.KeyMatch)
+			fmt.Println("Approved:  ", result.Approved)
+		}
+		if result.Message != "" {
+			fmt.Println("Message:   ", result.Message)
+		}
+	}
+	// Give scripts a way to tell that the namespace isn't usable as-is
+	if !result.Registered || !result.KeyMatch || !result.Approved {
+		os.Exit(1)
+	}
 }
 
-// Commenting until we're ready to use -- JH
-
-// func getNamespace(cmd *cobra.Command, args []string) {
-// 	err := config.InitClient()
-// 	if err != nil {
-// 		log.Errorln("Failed to initialize the client:", err)
-// 		os.Exit(1)
-// 	}
-
-// 	if jwks {
-// 		namespaceEndpoint, err := getNamespaceEndpoint()
-// 		if err != nil {
-// 			log.Errorln("Failed to get RegistryUrl from config:", err)
-// 			os.Exit(1)
-// 		}
-
-// 		endpoint := url.JoinPath(namespaceEndpoint, prefix, "issuer.jwks")
-// 		err = registry.NamespaceGet(endpoint)
-// 		if err != nil {
-// 			log.Errorf("Failed to get jwks info for prefix %s: %v", prefix, err)
-// 			os.Exit(1)
-// 		}
-// 	} else {
-// 		log.Error("Error: get command requires --jwks flag")
-// 		os.Exit(1)
-// 	}
-// }
+func snapshotNamespaces(cmd *cobra.Command, args []string) {
+	err := config.InitClient()
+	if err != nil {
+		log.Errorln("Failed to initialize the client:", err)
+		os.Exit(1)
+	}
+
+	namespaceEndpoint, err := getNamespaceEndpoint()
+	if err != nil {
+		log.Errorln("Failed to get RegistryUrl from config:", err)
+		os.Exit(1)
+	}
+
+	registryEndpoint, err := url.JoinPath(namespaceEndpoint, "api", "v1.0", "registry")
+	if err != nil {
+		log.Errorf("Failed to construct registry endpoint URL: %v", err)
+		os.Exit(1)
+	}
+
+	snapshot, registryKeys, err := registry.NamespaceSnapshotFetch(registryEndpoint)
+	if err != nil {
+		log.Errorln(err)
+		os.Exit(1)
+	}
+	result, err := registry.VerifyNamespaceSnapshot(snapshot, registryKeys)
+	if err != nil {
+		log.Errorln("The registry returned an invalid snapshot:", err)
+		os.Exit(1)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if err = os.WriteFile(output, snapshot, 0644); err != nil {
+		log.Errorln("Failed to write the snapshot:", err)
+		os.Exit(1)
+	}
+	if keysOutput, _ := cmd.Flags().GetString("keys-output"); keysOutput != "" {
+		keyBytes, err := json.MarshalIndent(registryKeys, "", "  ")
+		if err != nil {
+			log.Errorln("Failed to convert the registry's public keys to JSON:", err)
+			os.Exit(1)
+		}
+		if err = os.WriteFile(keysOutput, keyBytes, 0644); err != nil {
+			log.Errorln("Failed to write the registry's public keys:", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Saved a snapshot of %d namespaces to %s; it expires at %s\n", len(result.Namespaces), output, result.Expires.Format(time.RFC3339))
+}
+
+func verifyNamespaceSnapshot(cmd *cobra.Command, args []string) {
+	snapshot, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Errorln("Failed to read the snapshot:", err)
+		os.Exit(1)
+	}
+	keysFile, _ := cmd.Flags().GetString("keys")
+	registryKeys, err := jwk.ReadFile(keysFile)
+	if err != nil {
+		log.Errorln("Failed to read the registry's public keys:", err)
+		os.Exit(1)
+	}
+	result, err := registry.VerifyNamespaceSnapshot(snapshot, registryKeys)
+	if err != nil {
+		log.Errorln("Failed to verify the snapshot:", err)
+		os.Exit(1)
+	}
+
+	if outputJSON {
+		printNamespaceJSON(result)
+		return
+	}
+	fmt.Println("Issuer:    ", result.Issuer)
+	fmt.Println("Issued at: ", result.IssuedAt.Format(time.RFC3339))
+	fmt.Println("Expires:   ", result.Expires.Format(time.RFC3339))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PREFIX\tSTATUS")
+	for _, ns := range result.Namespaces {
+		fmt.Fprintf(w, "%s\t%s\n", ns.Prefix, ns.Status)
+	}
+	w.Flush()
+}
+
+// Registrations created before admin metadata existed have no status
+func listMyNamespaces(cmd *cobra.Command, args []string) {
+	err := config.InitClient()
+	if err != nil {
+		log.Errorln("Failed to initialize the client:", err)
+		os.Exit(1)
+	}
+
+	client.ObjectClientOptions.Version = config.PelicanVersion
+	client.ObjectClientOptions.Token, _ = cmd.Flags().GetString("token")
+	token, err := client.DiscoverToken()
+	if err != nil {
+		log.Errorln("Failed to read the token:", err)
+		os.Exit(1)
+	}
+	if token == "" {
+		log.Warningln("No token was found; only the namespaces that allow public reads are listed")
+	}
+
+	namespaces, err := client.ListAccessibleNamespaces(cmd.Context(), token)
+	if err != nil {
+		log.Errorln("Failed to list the accessible namespaces:", err)
+		os.Exit(1)
+	}
+
+	if outputJSON {
+		printNamespaceJSON(namespaces)
+		return
+	}
+	yesNo := func(value bool) string {
+		if value {
+			return "yes"
+		}
+		return "no"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PREFIX\tREAD\tWRITE\tPUBLIC")
+	for _, ns := range namespaces {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ns.Path, yesNo(ns.Read), yesNo(ns.Write), yesNo(ns.PublicRead))
+	}
+	w.Flush()
+}
+
+func namespaceStatus(ns *registry.Namespace) string {
+	if ns.AdminMetadata.Status == "" {
+		return registry.Unknown.String()
+	}
+	return ns.AdminMetadata.Status.String()
+}
+
+func printNamespaceJSON(obj interface{}) {
+	bytes, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		log.Errorln("Failed to convert the result to JSON:", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(bytes))
+}
 
 var namespaceCmd = &cobra.Command{
 	Use:   "namespace",
@@ -226,23 +557,81 @@ var deleteCmd = &cobra.Command{
 
 var listCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all namespaces",
+	Short: "List the registered namespaces",
 	Run:   listAllNamespaces,
 }
 
-// Commenting until we use -- JH
-// var getCmd = &cobra.Command{
-// 	Use:   "get",
-// 	Short: "Get a specific namespace",
-// 	Run:   getNamespace,
-// }
+var namespaceSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the registered namespaces by prefix",
+	Args:  cobra.ExactArgs(1),
+	Run:   searchNamespaces,
+}
+
+var namespaceGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get the registration details of a specific namespace",
+	Run:   getNamespace,
+}
+
+var namespaceSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save a signed snapshot of the registered namespaces for validating their keys offline",
+	Run:   snapshotNamespaces,
+}
+
+var namespaceVerifySnapshotCmd = &cobra.Command{
+	Use:   "verify-snapshot <snapshot file>",
+	Short: "Verify a namespace snapshot against the registry's public keys without contacting the registry",
+	Args:  cobra.ExactArgs(1),
+	Run:   verifyNamespaceSnapshot,
+}
+
+var namespaceMineCmd = &cobra.Command{
+	Use:   "mine",
+	Short: "List the namespaces you can read or write",
+	Long: `List the namespaces of the federation you can read or write, as determined
+from the namespaces' registered token issuers and the scopes of your token.
+Namespaces allowing public reads are always listed as readable.
+
+The token is the one given with --token or, otherwise, the one the client finds
+through the usual token discovery (e.g. the BEARER_TOKEN or BEARER_TOKEN_FILE
+environment variables).`,
+	Run: listMyNamespaces,
+}
+
+var namespaceCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check if a namespace is registered with the configured issuer key and approved",
+	Run:   checkNamespace,
+}
 
 func init() {
 	registerCmd.Flags().StringVar(&prefix, "prefix", "", "prefix for registering namespace")
 	registerCmd.Flags().BoolVar(&withIdentity, "with-identity", false, "Register a namespace with an identity")
-	//getCmd.Flags().StringVar(&prefix, "prefix", "", "prefix for get namespace")
-	//getCmd.Flags().BoolVar(&jwks, "jwks", false, "Get the jwks of the namespace")
+	registerCmd.Flags().String("accept-terms", "", "Accept this version of the federation's terms of service, which the registry may require to register")
+	namespaceGetCmd.Flags().StringVar(&prefix, "prefix", "", "prefix for get namespace")
+	namespaceGetCmd.Flags().StringVar(&namespaceToken, "token", "", "A token issued by the registry with the web_ui.access scope, needed to see namespaces that aren't approved")
+	namespaceCheckCmd.Flags().StringVar(&prefix, "prefix", "", "prefix for check namespace")
 	deleteCmd.Flags().StringVar(&prefix, "prefix", "", "prefix for delete namespace")
+	listCmd.Flags().String("status", "", "Only list namespaces with the registration status: Pending, Approved, Denied, or Unknown")
+	listCmd.Flags().String("prefix", "", "Only list the namespace with the prefix and the namespaces under it")
+	listCmd.Flags().String("institution", "", "Only list namespaces registered by the institution with the ID")
+	listCmd.Flags().String("server-type", "", "Only list namespaces of the server type: origin or cache")
+	listCmd.Flags().StringVar(&namespaceToken, "token", "", "A token issued by the registry with the web_ui.access scope, needed to see namespaces that aren't approved")
+	namespaceSearchCmd.Flags().Bool("substring", false, "Match namespaces containing the query anywhere in their prefix instead of starting with it")
+	namespaceSearchCmd.Flags().String("status", "", "Only match namespaces with the registration status: Pending, Approved, Denied, or Unknown")
+	namespaceSearchCmd.Flags().String("server-type", "", "Only match namespaces of the server type: origin or cache")
+	namespaceSearchCmd.Flags().Int("page", 1, "The page of matches to show")
+	namespaceSearchCmd.Flags().Int("page-size", 20, "The number of matches per page (at most 100)")
+
+	namespaceMineCmd.Flags().StringP("token", "t", "", "The file holding your token")
+	namespaceSnapshotCmd.Flags().StringP("output", "o", "namespaces.jws", "The file to save the snapshot to")
+	namespaceSnapshotCmd.Flags().String("keys-output", "", "Also save the registry's public keys, used to verify the snapshot, to the file")
+	namespaceVerifySnapshotCmd.Flags().String("keys", "", "A JWKS file with the registry's public keys")
+	if err := namespaceVerifySnapshotCmd.MarkFlagRequired("keys"); err != nil {
+		panic(err)
+	}
 
 	namespaceCmd.PersistentFlags().String("namespace-url", "", "Endpoint for the namespace registry")
 	// Don't override Federation.RegistryUrl if the flag value is empty
@@ -264,6 +653,10 @@ func init() {
 	namespaceCmd.AddCommand(registerCmd)
 	namespaceCmd.AddCommand(deleteCmd)
 	namespaceCmd.AddCommand(listCmd)
-	// Commenting until we use -- JH
-	//namespaceCmd.AddCommand(getCmd)
+	namespaceCmd.AddCommand(namespaceGetCmd)
+	namespaceCmd.AddCommand(namespaceSearchCmd)
+	namespaceCmd.AddCommand(namespaceCheckCmd)
+	namespaceCmd.AddCommand(namespaceMineCmd)
+	namespaceCmd.AddCommand(namespaceSnapshotCmd)
+	namespaceCmd.AddCommand(namespaceVerifySnapshotCmd)
 }
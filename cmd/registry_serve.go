@@ -26,6 +26,10 @@ import (
 )
 
 func serveRegistry(cmd *cobra.Command, _ []string) error {
+	if isDryRun(cmd) {
+		return finishDryRun(cmd, launchers.DryRunModules(cmd.Context(), config.RegistryType))
+	}
+
 	cancel, err := launchers.LaunchModules(cmd.Context(), config.RegistryType)
 	if err != nil {
 		cancel()
@@ -111,8 +111,13 @@ func init() {
 	rootCmd.AddCommand(cacheCmd)
 	rootCmd.AddCommand(namespaceCmd)
 	rootCmd.AddCommand(rootConfigCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(rootPluginCmd)
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(federationCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(completionCmd)
 	preferredPrefix := config.GetPreferredPrefix()
 	rootCmd.Use = strings.ToLower(preferredPrefix)
 
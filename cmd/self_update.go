@@ -0,0 +1,299 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// A client binary for a single platform in a release manifest
+	releaseBinary struct {
+		URL    string `json:"url"`
+		Sha256 string `json:"sha256"`
+		// Base64-encoded ASN.1 ECDSA signature over the SHA-256 digest of the
+		// release signing payload (see releaseSigningPayload)
+		Signature string `json:"signature"`
+	}
+
+	// The release manifest served at <Client.SelfUpdateUrl>/<channel>.json
+	releaseManifest struct {
+		Version  string                   `json:"version"`
+		Binaries map[string]releaseBinary `json:"binaries"` // Keyed by "<GOOS>-<GOARCH>"
+	}
+)
+
+var (
+	selfUpdateCmd = &cobra.Command{
+		Use:   "self-update",
+		Short: "Update the Pelican client binary to the latest release",
+		Long: `Check the release endpoint configured by Client.SelfUpdateUrl for a newer
+release of the client on the selected channel. If one is found, the binary for
+this platform is downloaded, its checksum and signature are verified against
+the key in Client.SelfUpdatePublicKey, and the running executable is atomically
+replaced.`,
+		RunE:         selfUpdateMain,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	flagSet := selfUpdateCmd.Flags()
+	flagSet.String("channel", "", "Release channel to update from: stable or testing. Defaults to Client.SelfUpdateChannel")
+	flagSet.Bool("check", false, "Only check if an update is available without installing it")
+	flagSet.Bool("force", false, "Install the release even if it is not newer than the running version")
+}
+
+func getReleaseManifest(ctx context.Context, updateUrl string, channel string) (*releaseManifest, error) {
+	manifestUrl, err := url.JoinPath(updateUrl, channel+".json")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to construct the release manifest URL")
+	}
+	body, err := downloadRelease(ctx, manifestUrl)
+	if err != nil {
+		return nil, err
+	}
+	manifest := releaseManifest{}
+	if err = json.Unmarshal(body, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the release manifest from %s", manifestUrl)
+	}
+	if manifest.Version == "" {
+		return nil, errors.Errorf("The release manifest from %s does not include a version", manifestUrl)
+	}
+	return &manifest, nil
+}
+
+func downloadRelease(ctx context.Context, releaseUrl string) ([]byte, error) {
+	client := http.Client{Transport: config.GetTransport(), Timeout: 5 * time.Minute}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseUrl, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create request to %s", releaseUrl)
+	}
+	req.Header.Set("User-Agent", "pelican-client/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to download %s", releaseUrl)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Failed to download %s: server responded with status %d", releaseUrl, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the response from %s", releaseUrl)
+	}
+	return body, nil
+}
+
+func loadReleasePublicKey(keyLocation string) (*ecdsa.PublicKey, error) {
+	contents, err := os.ReadFile(keyLocation)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the release public key")
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.Errorf("Release public key file %s is not PEM-encoded", keyLocation)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the release public key")
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("Release public key in %s is not an ECDSA key", keyLocation)
+	}
+	return ecdsaKey, nil
+}
+
+// The payload signed by the release key. It binds the binary's digest to the release
+// version and platform so that a validly-signed binary from an older release (or for
+// another platform) can't be served in place of the one the manifest advertises.
+func releaseSigningPayload(releaseVersion string, platform string, sha256Hex string) []byte {
+	return []byte(fmt.Sprintf("pelican-release\nversion: %s\nplatform: %s\nsha256: %s\n", releaseVersion, platform, sha256Hex))
+}
+
+// Verify the downloaded binary matches the checksum in the manifest and that the
+// manifest entry (version, platform, and checksum) is signed by the release key
+func verifyReleaseBinary(binary []byte, releaseVersion string, platform string, release releaseBinary, publicKey *ecdsa.PublicKey) error {
+	digest := sha256.Sum256(binary)
+	digestHex := hex.EncodeToString(digest[:])
+	if release.Sha256 == "" {
+		return errors.New("The release manifest does not include a checksum for the binary")
+	}
+	if digestHex != release.Sha256 {
+		return errors.New("Checksum of the downloaded binary does not match the release manifest")
+	}
+	signature, err := base64.StdEncoding.DecodeString(release.Signature)
+	if err != nil || len(signature) == 0 {
+		return errors.New("The release manifest does not include a valid signature for the binary")
+	}
+	payloadDigest := sha256.Sum256(releaseSigningPayload(releaseVersion, platform, digestHex))
+	if !ecdsa.VerifyASN1(publicKey, payloadDigest[:], signature) {
+		return errors.Errorf("Signature verification of the downloaded binary for release %s (%s) failed", releaseVersion, platform)
+	}
+	return nil
+}
+
+// Atomically replace the executable at exePath with the new binary. The new binary
+// is written next to the executable so that the final rename stays on one filesystem.
+func replaceExecutable(exePath string, binary []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat the current executable")
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(exePath), "."+filepath.Base(exePath)+".update-*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create a temporary file for the new executable")
+	}
+	tmpName := tmpFile.Name()
+	defer os.Remove(tmpName)
+
+	if _, err = tmpFile.Write(binary); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Failed to write the new executable")
+	}
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Failed to flush the new executable to disk")
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close the new executable")
+	}
+	if err = os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		return errors.Wrap(err, "Failed to set permissions on the new executable")
+	}
+
+	// Windows doesn't allow replacing a running executable, but it does allow renaming it
+	oldPath := ""
+	if runtime.GOOS == "windows" {
+		oldPath = exePath + ".old"
+		_ = os.Remove(oldPath)
+		if err = os.Rename(exePath, oldPath); err != nil {
+			return errors.Wrap(err, "Failed to move the current executable aside")
+		}
+	}
+	if err = os.Rename(tmpName, exePath); err != nil {
+		// Put the original executable back so a failed update doesn't leave nothing behind
+		if oldPath != "" {
+			if restoreErr := os.Rename(oldPath, exePath); restoreErr != nil {
+				log.Errorf("Failed to restore the original executable from %s: %v", oldPath, restoreErr)
+			}
+		}
+		return errors.Wrap(err, "Failed to replace the current executable")
+	}
+	return nil
+}
+
+func selfUpdateMain(cmd *cobra.Command, args []string) error {
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+
+	updateUrl := param.Client_SelfUpdateUrl.GetString()
+	if updateUrl == "" {
+		return errors.New("No release endpoint configured; set Client.SelfUpdateUrl to enable self-update")
+	}
+	channel, _ := cmd.Flags().GetString("channel")
+	if channel == "" {
+		channel = param.Client_SelfUpdateChannel.GetString()
+	}
+	if channel != "stable" && channel != "testing" {
+		return errors.Errorf("Invalid release channel %q; accepted values are stable and testing", channel)
+	}
+	checkOnly, _ := cmd.Flags().GetBool("check")
+	force, _ := cmd.Flags().GetBool("force")
+
+	manifest, err := getReleaseManifest(cmd.Context(), updateUrl, channel)
+	if err != nil {
+		return err
+	}
+	latestVer, err := goversion.NewVersion(manifest.Version)
+	if err != nil {
+		return errors.Wrapf(err, "Release manifest version %s is not a semantic version", manifest.Version)
+	}
+	// Development builds don't have a semantic version; always consider them out of date
+	if currentVer, err := goversion.NewVersion(version); err == nil && !latestVer.GreaterThan(currentVer) && !force {
+		fmt.Fprintf(cmd.OutOrStdout(), "Pelican %s is up to date (latest %s release is %s)\n", version, channel, latestVer.String())
+		return nil
+	}
+	if checkOnly {
+		fmt.Fprintf(cmd.OutOrStdout(), "Pelican %s is available on the %s channel (running %s)\n", latestVer.String(), channel, version)
+		return nil
+	}
+
+	platform := runtime.GOOS + "-" + runtime.GOARCH
+	release, ok := manifest.Binaries[platform]
+	if !ok || release.URL == "" {
+		return errors.Errorf("Release %s does not include a binary for %s", latestVer.String(), platform)
+	}
+
+	// Refuse to install anything we can't verify
+	keyLocation := param.Client_SelfUpdatePublicKey.GetString()
+	if keyLocation == "" {
+		return errors.New("No release public key configured; set Client.SelfUpdatePublicKey to verify downloaded binaries")
+	}
+	publicKey, err := loadReleasePublicKey(keyLocation)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Downloading Pelican %s for %s from %s", latestVer.String(), platform, release.URL)
+	binary, err := downloadRelease(cmd.Context(), release.URL)
+	if err != nil {
+		return err
+	}
+	if err = verifyReleaseBinary(binary, manifest.Version, platform, release, publicKey); err != nil {
+		return err
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "Failed to locate the current executable")
+	}
+	if exePath, err = filepath.EvalSymlinks(exePath); err != nil {
+		return errors.Wrap(err, "Failed to resolve the current executable")
+	}
+	if err = replaceExecutable(exePath, binary); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Updated %s from %s to %s\n", exePath, version, latestVer.String())
+	return nil
+}
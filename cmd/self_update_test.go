@@ -0,0 +1,109 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyReleaseBinary(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	binary := []byte("pelican binary contents")
+	digest := sha256.Sum256(binary)
+	digestHex := hex.EncodeToString(digest[:])
+	payloadDigest := sha256.Sum256(releaseSigningPayload("7.5.0", "linux-amd64", digestHex))
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, payloadDigest[:])
+	require.NoError(t, err)
+
+	release := releaseBinary{
+		Sha256:    digestHex,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+
+	t.Run("valid-signature", func(t *testing.T) {
+		assert.NoError(t, verifyReleaseBinary(binary, "7.5.0", "linux-amd64", release, &privateKey.PublicKey))
+	})
+
+	t.Run("tampered-binary", func(t *testing.T) {
+		assert.Error(t, verifyReleaseBinary([]byte("tampered"), "7.5.0", "linux-amd64", release, &privateKey.PublicKey))
+		noChecksum := release
+		noChecksum.Sha256 = ""
+		assert.Error(t, verifyReleaseBinary(binary, "7.5.0", "linux-amd64", noChecksum, &privateKey.PublicKey))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		// A binary signed for an older release can't be advertised as a newer one
+		assert.Error(t, verifyReleaseBinary(binary, "7.6.0", "linux-amd64", release, &privateKey.PublicKey))
+	})
+
+	t.Run("wrong-platform", func(t *testing.T) {
+		assert.Error(t, verifyReleaseBinary(binary, "7.5.0", "darwin-arm64", release, &privateKey.PublicKey))
+	})
+
+	t.Run("digest-only-signature", func(t *testing.T) {
+		digestSig, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+		require.NoError(t, err)
+		digestOnly := release
+		digestOnly.Signature = base64.StdEncoding.EncodeToString(digestSig)
+		assert.Error(t, verifyReleaseBinary(binary, "7.5.0", "linux-amd64", digestOnly, &privateKey.PublicKey))
+	})
+
+	t.Run("wrong-key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		assert.Error(t, verifyReleaseBinary(binary, "7.5.0", "linux-amd64", release, &otherKey.PublicKey))
+	})
+
+	t.Run("missing-signature", func(t *testing.T) {
+		unsigned := release
+		unsigned.Signature = ""
+		assert.Error(t, verifyReleaseBinary(binary, "7.5.0", "linux-amd64", unsigned, &privateKey.PublicKey))
+	})
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	exePath := filepath.Join(t.TempDir(), "pelican")
+	require.NoError(t, os.WriteFile(exePath, []byte("old"), 0755))
+
+	require.NoError(t, replaceExecutable(exePath, []byte("new")))
+
+	contents, err := os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(contents))
+	info, err := os.Stat(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// No temporary files should be left behind
+	entries, err := os.ReadDir(filepath.Dir(exePath))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
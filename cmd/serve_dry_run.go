@@ -0,0 +1,57 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/launchers"
+)
+
+// Add the --dry-run flag to a serve command
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Initialize the server and report problems with its configuration, "+
+		"then exit without binding any ports or launching any daemons")
+}
+
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	return err == nil && dryRun
+}
+
+// Print the report of a dry run, failing if any of its checks failed
+func finishDryRun(cmd *cobra.Command, report *launchers.DryRunReport) error {
+	if outputJSON {
+		reportJSON, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the dry run report to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(reportJSON))
+	} else {
+		report.Print(cmd.OutOrStdout())
+	}
+	if report.Failed() {
+		return errors.New("The dry run found problems with the server's configuration")
+	}
+	return nil
+}
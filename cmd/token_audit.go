@@ -0,0 +1,467 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+type (
+	// A problem the audit found with a token
+	auditFinding struct {
+		Level   string `json:"level"`
+		Check   string `json:"check"`
+		Message string `json:"message"`
+	}
+
+	// The federation policy a token is audited against
+	auditPolicy struct {
+		Audience    string
+		MaxLifetime time.Duration
+	}
+
+	tokenAudit struct {
+		Issuer            string         `json:"issuer"`
+		Subject           string         `json:"subject,omitempty"`
+		Profile           string         `json:"profile"`
+		Audience          []string       `json:"audience"`
+		Scopes            []string       `json:"scopes"`
+		Expiration        time.Time      `json:"expiration"`
+		Namespace         string         `json:"namespace,omitempty"`
+		SignatureVerified bool           `json:"signature_verified"`
+		Findings          []auditFinding `json:"findings"`
+	}
+
+	// A storage authorization parsed from a token's scope
+	storageScope struct {
+		Authz string
+		Path  string
+	}
+)
+
+const (
+	auditError   = "error"
+	auditWarning = "warning"
+
+	// The audience values the WLCG and SciTokens profiles define as "any audience"
+	wlcgAnyAudience = "https://wlcg.cern.ch/jwt/v1/any"
+	anyAudience     = "ANY"
+)
+
+var (
+	tokenCmd = &cobra.Command{
+		Use:   "token",
+		Short: "Inspect tokens used with a Pelican federation",
+	}
+
+	tokenAuditCmd = &cobra.Command{
+		Use:   "audit <token>",
+		Short: "Check a token for misconfigurations that cause authorization failures",
+		Long: `Audit a WLCG or SciTokens token against the federation's policy: the
+token profile, its lifetime, audience pinning, and the breadth of its scopes.
+With --namespace, the token is also checked against the issuers the namespace's
+origins advertise to the director and its scopes against the namespace, which
+catches the mismatches that commonly surface as a bare 403 from a cache or origin.
+
+The token may be given directly, as the path to a file holding it (such as the
+JSON file written by 'pelican object get'), or as '-' to read it from stdin.
+The command exits with an error if any of the checks fails.`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         tokenAuditMain,
+		SilenceUsage: true,
+	}
+
+	// The scope authorizations granting access to storage in each token profile
+	wlcgStorageAuthz      = []string{"storage.read", "storage.create", "storage.modify", "storage.stage"}
+	scitokensStorageAuthz = []string{"read", "write"}
+)
+
+func init() {
+	flagSet := tokenAuditCmd.Flags()
+	flagSet.StringP("namespace", "n", "", "Check the token against the namespace serving this path")
+	flagSet.String("audience", "", "The audience the token is expected to be pinned to")
+	flagSet.Duration("max-lifetime", 6*time.Hour, "The longest lifetime the federation allows for a token")
+	tokenCmd.AddCommand(tokenAuditCmd)
+}
+
+func (audit *tokenAudit) addFinding(level, check, format string, args ...interface{}) {
+	audit.Findings = append(audit.Findings, auditFinding{Level: level, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+func (audit *tokenAudit) errorCount() (count int) {
+	for _, finding := range audit.Findings {
+		if finding.Level == auditError {
+			count++
+		}
+	}
+	return
+}
+
+// Read the token from the argument, which may be the token itself, a file holding it, or "-" for stdin
+func readAuditToken(arg string, stdin io.Reader) (string, error) {
+	contents := arg
+	if arg == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to read the token from stdin")
+		}
+		contents = string(data)
+	} else if data, err := os.ReadFile(arg); err == nil {
+		contents = string(data)
+	}
+	contents = strings.TrimSpace(contents)
+
+	// Token files written by the client are JSON objects with the token in access_token
+	if strings.HasPrefix(contents, "{") {
+		tokenFile := struct {
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.Unmarshal([]byte(contents), &tokenFile); err != nil {
+			return "", errors.Wrap(err, "Failed to parse the token file")
+		}
+		contents = tokenFile.AccessToken
+	}
+	if contents == "" {
+		return "", errors.New("The token is empty")
+	}
+	return contents, nil
+}
+
+func isStorageAuthz(authz string) bool {
+	return slices.Contains(wlcgStorageAuthz, authz) || slices.Contains(scitokensStorageAuthz, authz)
+}
+
+// Parse the storage authorizations out of the token's scopes; scopes without a
+// path apply to the whole of the issuer's namespace
+func parseStorageScopes(scopes []string) (result []storageScope) {
+	for _, scope := range scopes {
+		authz, scopePath, _ := strings.Cut(scope, ":")
+		if !isStorageAuthz(authz) {
+			continue
+		}
+		if scopePath == "" {
+			scopePath = "/"
+		}
+		result = append(result, storageScope{Authz: authz, Path: path.Clean(scopePath)})
+	}
+	return
+}
+
+func getStringClaim(tok jwt.Token, name string) string {
+	if val, ok := tok.Get(name); ok {
+		if strVal, ok := val.(string); ok {
+			return strVal
+		}
+	}
+	return ""
+}
+
+// Check the token's claims against the federation policy
+func auditTokenClaims(tok jwt.Token, policy auditPolicy, now time.Time) *tokenAudit {
+	audit := &tokenAudit{
+		Issuer:     tok.Issuer(),
+		Subject:    tok.Subject(),
+		Audience:   tok.Audience(),
+		Expiration: tok.Expiration(),
+		Findings:   []auditFinding{},
+	}
+	if scope := getStringClaim(tok, "scope"); scope != "" {
+		audit.Scopes = strings.Fields(scope)
+	}
+
+	if audit.Issuer == "" {
+		audit.addFinding(auditError, "issuer", "The token has no issuer (iss) claim")
+	}
+
+	// Token profile
+	wlcgVer := getStringClaim(tok, "wlcg.ver")
+	scitokensVer := getStringClaim(tok, "ver")
+	var profileAuthz, otherAuthz []string
+	switch {
+	case wlcgVer != "":
+		audit.Profile = "wlcg " + wlcgVer
+		profileAuthz, otherAuthz = wlcgStorageAuthz, scitokensStorageAuthz
+		if audit.Subject == "" {
+			audit.addFinding(auditError, "profile", "WLCG tokens require a subject (sub) claim")
+		}
+	case strings.HasPrefix(scitokensVer, "scitoken"):
+		audit.Profile = scitokensVer
+		profileAuthz, otherAuthz = scitokensStorageAuthz, wlcgStorageAuthz
+	default:
+		audit.Profile = "unknown"
+		audit.addFinding(auditWarning, "profile", "The token has neither a wlcg.ver nor a SciTokens ver claim; servers may not accept it as either profile")
+	}
+
+	// Lifetime
+	if audit.Expiration.IsZero() {
+		audit.addFinding(auditError, "lifetime", "The token has no expiration (exp) claim")
+	} else if now.After(audit.Expiration) {
+		audit.addFinding(auditError, "lifetime", "The token expired at %s", audit.Expiration.UTC().Format(time.RFC3339))
+	}
+	if nbf := tok.NotBefore(); !nbf.IsZero() && nbf.After(now) {
+		audit.addFinding(auditError, "lifetime", "The token is not valid before %s; check the clock of the issuer", nbf.UTC().Format(time.RFC3339))
+	}
+	if iat := tok.IssuedAt(); !iat.IsZero() {
+		if iat.After(now.Add(time.Minute)) {
+			audit.addFinding(auditWarning, "lifetime", "The token was issued in the future (%s); check the clock of the issuer", iat.UTC().Format(time.RFC3339))
+		}
+		if !audit.Expiration.IsZero() && policy.MaxLifetime > 0 && audit.Expiration.Sub(iat) > policy.MaxLifetime {
+			audit.addFinding(auditWarning, "lifetime", "The token's lifetime of %s exceeds the maximum of %s", audit.Expiration.Sub(iat), policy.MaxLifetime)
+		}
+	}
+
+	// Audience
+	anyAud := false
+	for _, aud := range audit.Audience {
+		if aud == wlcgAnyAudience || aud == anyAudience {
+			anyAud = true
+		}
+	}
+	if len(audit.Audience) == 0 {
+		audit.addFinding(auditError, "audience", "The token has no audience (aud) claim, which both token profiles require")
+	} else if anyAud {
+		audit.addFinding(auditWarning, "audience", "The token is valid for any audience; pin it to the services it is meant for")
+	} else if policy.Audience != "" && !slices.Contains(audit.Audience, policy.Audience) {
+		audit.addFinding(auditError, "audience", "The token's audience %s does not include %s", strings.Join(audit.Audience, ", "), policy.Audience)
+	}
+
+	// Scope breadth
+	storageScopes := parseStorageScopes(audit.Scopes)
+	if len(storageScopes) == 0 {
+		audit.addFinding(auditError, "scope", "The token has no storage scopes, so it authorizes no access to objects")
+	}
+	for _, scope := range storageScopes {
+		if profileAuthz != nil && !slices.Contains(profileAuthz, scope.Authz) && slices.Contains(otherAuthz, scope.Authz) {
+			audit.addFinding(auditWarning, "scope", "The %s scope belongs to the other token profile than the token's %s", scope.Authz, audit.Profile)
+		}
+		if scope.Path == "/" {
+			audit.addFinding(auditWarning, "scope", "The %s scope grants access to everything the issuer controls; narrow it to the paths needed", scope.Authz)
+		}
+	}
+	return audit
+}
+
+// Whether one of the two cleaned paths contains the other
+func pathsOverlap(path1, path2 string) bool {
+	contains := func(parent, child string) bool {
+		return parent == "/" || child == parent || strings.HasPrefix(child, parent+"/")
+	}
+	return contains(path1, path2) || contains(path2, path1)
+}
+
+// Find the namespace with the longest prefix serving the path
+func findAuditNamespace(namespaces []common.NamespaceAdV2, objectPath string) *common.NamespaceAdV2 {
+	var best *common.NamespaceAdV2
+	objectPath = path.Clean("/" + objectPath)
+	for idx := range namespaces {
+		prefix := path.Clean("/" + namespaces[idx].Path)
+		if prefix != "/" && objectPath != prefix && !strings.HasPrefix(objectPath, prefix+"/") {
+			continue
+		}
+		if best == nil || len(prefix) > len(path.Clean("/"+best.Path)) {
+			best = &namespaces[idx]
+		}
+	}
+	return best
+}
+
+// Check the token against the issuers the namespace's origins advertise
+func auditTokenNamespace(audit *tokenAudit, ns *common.NamespaceAdV2) {
+	audit.Namespace = ns.Path
+	if len(ns.Issuer) == 0 {
+		if ns.PublicRead || ns.Caps.PublicRead {
+			audit.addFinding(auditWarning, "namespace", "The namespace %s allows public reads and advertises no token issuer; the token is not used", ns.Path)
+		} else {
+			audit.addFinding(auditError, "namespace", "The namespace %s advertises no token issuer, so no token will be accepted for it", ns.Path)
+		}
+		return
+	}
+
+	issuers := make([]string, 0, len(ns.Issuer))
+	var matched []common.TokenIssuer
+	for _, issuer := range ns.Issuer {
+		issuerUrl := strings.TrimSuffix(issuer.IssuerUrl.String(), "/")
+		issuers = append(issuers, issuerUrl)
+		if issuerUrl == strings.TrimSuffix(audit.Issuer, "/") {
+			matched = append(matched, issuer)
+		}
+	}
+	if len(matched) == 0 {
+		audit.addFinding(auditError, "namespace", "The token's issuer %s is not an issuer of the namespace %s; its issuers are %s",
+			audit.Issuer, ns.Path, strings.Join(issuers, ", "))
+		return
+	}
+
+	// Scope paths are relative to the base paths of the issuer
+	nsPath := path.Clean("/" + ns.Path)
+	basePaths := []string{}
+	for _, issuer := range matched {
+		basePaths = append(basePaths, issuer.BasePaths...)
+	}
+	for _, scope := range parseStorageScopes(audit.Scopes) {
+		for _, basePath := range basePaths {
+			if pathsOverlap(path.Join(basePath, scope.Path), nsPath) {
+				return
+			}
+		}
+	}
+	audit.addFinding(auditError, "namespace", "None of the token's scopes grant access within the namespace %s; scope paths are relative to the issuer's base paths %s",
+		ns.Path, strings.Join(basePaths, ", "))
+}
+
+// Verify the token's signature with the public keys its issuer publishes
+func verifyAuditSignature(ctx context.Context, audit *tokenAudit, rawToken string) {
+	if audit.Issuer == "" {
+		return
+	}
+	issuerUrl, err := url.Parse(audit.Issuer)
+	if err != nil || issuerUrl.Scheme != "https" {
+		audit.addFinding(auditError, "signature", "The token's issuer %s is not an https URL, so servers can't look up its public keys", audit.Issuer)
+		return
+	}
+	metadata := struct {
+		JwksUri string `json:"jwks_uri"`
+	}{}
+	metadataUrl := strings.TrimSuffix(audit.Issuer, "/") + "/.well-known/openid-configuration"
+	if err = getFederationJSON(ctx, metadataUrl, &metadata); err != nil {
+		audit.addFinding(auditError, "signature", "Failed to get the issuer's metadata, which servers need to verify the token: %v", err)
+		return
+	}
+	if metadata.JwksUri == "" {
+		audit.addFinding(auditError, "signature", "The issuer's metadata at %s has no jwks_uri", metadataUrl)
+		return
+	}
+	keys, err := utils.GetIssuerJWKS(ctx, metadata.JwksUri)
+	if err != nil {
+		audit.addFinding(auditError, "signature", "Failed to get the issuer's public keys: %v", err)
+		return
+	}
+	if _, err = jwt.Parse([]byte(rawToken), jwt.WithKeySet(keys), jwt.WithValidate(false)); err != nil {
+		audit.addFinding(auditError, "signature", "The token's signature doesn't match the issuer's public keys at %s", metadata.JwksUri)
+		return
+	}
+	audit.SignatureVerified = true
+}
+
+func printTokenAudit(w io.Writer, audit *tokenAudit) {
+	fmt.Fprintln(w, "Issuer:   ", audit.Issuer)
+	if audit.Subject != "" {
+		fmt.Fprintln(w, "Subject:  ", audit.Subject)
+	}
+	fmt.Fprintln(w, "Profile:  ", audit.Profile)
+	fmt.Fprintln(w, "Audience: ", strings.Join(audit.Audience, " "))
+	fmt.Fprintln(w, "Scopes:   ", strings.Join(audit.Scopes, " "))
+	if !audit.Expiration.IsZero() {
+		fmt.Fprintln(w, "Expires:  ", audit.Expiration.UTC().Format(time.RFC3339))
+	}
+	if audit.Namespace != "" {
+		fmt.Fprintln(w, "Namespace:", audit.Namespace)
+	}
+	fmt.Fprintln(w, "Signature:", map[bool]string{true: "verified", false: "not verified"}[audit.SignatureVerified])
+	if len(audit.Findings) == 0 {
+		fmt.Fprintln(w, "No problems found")
+		return
+	}
+	fmt.Fprintln(w, "Findings:")
+	for _, finding := range audit.Findings {
+		fmt.Fprintf(w, "  %-7s [%s] %s\n", strings.ToUpper(finding.Level), finding.Check, finding.Message)
+	}
+}
+
+func tokenAuditMain(cmd *cobra.Command, args []string) error {
+	if err := config.InitClient(); err != nil {
+		return errors.Wrap(err, "Failed to initialize the client")
+	}
+
+	rawToken, err := readAuditToken(args[0], cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	tok, err := jwt.Parse([]byte(rawToken), jwt.WithVerify(false), jwt.WithValidate(false))
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse the token")
+	}
+
+	policy := auditPolicy{}
+	if policy.Audience, err = cmd.Flags().GetString("audience"); err != nil {
+		return err
+	}
+	if policy.MaxLifetime, err = cmd.Flags().GetDuration("max-lifetime"); err != nil {
+		return err
+	}
+	audit := auditTokenClaims(tok, policy, time.Now())
+	verifyAuditSignature(cmd.Context(), audit, rawToken)
+
+	nsPath, err := cmd.Flags().GetString("namespace")
+	if err != nil {
+		return err
+	}
+	if nsPath != "" {
+		directorUrl := param.Federation_DirectorUrl.GetString()
+		if directorUrl == "" {
+			return errors.New("The federation's director URL is not known; set the federation with --federation")
+		}
+		nsUrl, err := url.JoinPath(directorUrl, "api", "v2.0", "director", "listNamespaces")
+		if err != nil {
+			return errors.Wrap(err, "Invalid director URL")
+		}
+		namespaces := []common.NamespaceAdV2{}
+		if err = getFederationJSON(cmd.Context(), nsUrl, &namespaces); err != nil {
+			return errors.Wrap(err, "Failed to get the namespaces from the director")
+		}
+		ns := findAuditNamespace(namespaces, nsPath)
+		if ns == nil {
+			audit.addFinding(auditError, "namespace", "No origin advertises a namespace serving %s", nsPath)
+		} else {
+			auditTokenNamespace(audit, ns)
+		}
+	}
+
+	if outputJSON {
+		auditJSON, err := json.MarshalIndent(audit, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert the token audit to JSON")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(auditJSON))
+	} else {
+		printTokenAudit(cmd.OutOrStdout(), audit)
+	}
+	if count := audit.errorCount(); count > 0 {
+		return errors.Errorf("The token failed %d of the audit checks", count)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func buildAuditToken(t *testing.T, now time.Time, claims map[string]interface{}) jwt.Token {
+	builder := jwt.NewBuilder().
+		Issuer("https://issuer.example.com").
+		Subject("user").
+		Audience([]string{"https://origin.example.com"}).
+		IssuedAt(now).
+		Expiration(now.Add(time.Hour)).
+		Claim("wlcg.ver", "1.0").
+		Claim("scope", "storage.read:/data")
+	for name, value := range claims {
+		builder = builder.Claim(name, value)
+	}
+	tok, err := builder.Build()
+	require.NoError(t, err)
+	return tok
+}
+
+func auditChecks(audit *tokenAudit, level string) (checks []string) {
+	for _, finding := range audit.Findings {
+		if finding.Level == level {
+			checks = append(checks, finding.Check)
+		}
+	}
+	return
+}
+
+func TestAuditTokenClaims(t *testing.T) {
+	now := time.Now()
+	policy := auditPolicy{MaxLifetime: 6 * time.Hour}
+
+	t.Run("well-formed-token", func(t *testing.T) {
+		audit := auditTokenClaims(buildAuditToken(t, now, nil), policy, now)
+		assert.Empty(t, audit.Findings)
+		assert.Equal(t, "wlcg 1.0", audit.Profile)
+		assert.Equal(t, []string{"storage.read:/data"}, audit.Scopes)
+	})
+
+	t.Run("expired-and-long-lived", func(t *testing.T) {
+		tok := buildAuditToken(t, now, map[string]interface{}{"exp": now.Add(-time.Minute).Unix(), "iat": now.Add(-24 * time.Hour).Unix()})
+		audit := auditTokenClaims(tok, policy, now)
+		assert.Equal(t, []string{"lifetime"}, auditChecks(audit, auditError))
+		assert.Equal(t, []string{"lifetime"}, auditChecks(audit, auditWarning))
+	})
+
+	t.Run("audience", func(t *testing.T) {
+		tok := buildAuditToken(t, now, map[string]interface{}{"aud": []string{wlcgAnyAudience}})
+		audit := auditTokenClaims(tok, policy, now)
+		assert.Equal(t, []string{"audience"}, auditChecks(audit, auditWarning))
+
+		audit = auditTokenClaims(buildAuditToken(t, now, nil), auditPolicy{Audience: "https://cache.example.com"}, now)
+		assert.Equal(t, []string{"audience"}, auditChecks(audit, auditError))
+	})
+
+	t.Run("scope-breadth", func(t *testing.T) {
+		tok := buildAuditToken(t, now, map[string]interface{}{"scope": "storage.read:/ read:/data"})
+		audit := auditTokenClaims(tok, policy, now)
+		assert.Equal(t, []string{"scope", "scope"}, auditChecks(audit, auditWarning))
+
+		tok = buildAuditToken(t, now, map[string]interface{}{"scope": "openid offline_access"})
+		audit = auditTokenClaims(tok, policy, now)
+		assert.Equal(t, []string{"scope"}, auditChecks(audit, auditError))
+	})
+
+	t.Run("scitokens-profile", func(t *testing.T) {
+		tok, err := jwt.NewBuilder().
+			Issuer("https://issuer.example.com").
+			Audience([]string{"ANY"}).
+			Expiration(now.Add(time.Hour)).
+			Claim("ver", "scitoken:2.0").
+			Claim("scope", "read:/data").
+			Build()
+		require.NoError(t, err)
+		audit := auditTokenClaims(tok, policy, now)
+		assert.Equal(t, "scitoken:2.0", audit.Profile)
+		assert.Empty(t, auditChecks(audit, auditError))
+	})
+}
+
+func TestAuditTokenNamespace(t *testing.T) {
+	issuerUrl, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+	otherIssuerUrl, err := url.Parse("https://other.example.com")
+	require.NoError(t, err)
+	namespaces := []common.NamespaceAdV2{
+		{Path: "/foo", Issuer: []common.TokenIssuer{{IssuerUrl: *issuerUrl, BasePaths: []string{"/foo"}}}},
+		{Path: "/foo/bar", Issuer: []common.TokenIssuer{{IssuerUrl: *otherIssuerUrl, BasePaths: []string{"/foo/bar"}}}},
+		{Path: "/public", PublicRead: true},
+	}
+
+	assert.Equal(t, "/foo", findAuditNamespace(namespaces, "/foo/baz/object.txt").Path)
+	assert.Equal(t, "/foo/bar", findAuditNamespace(namespaces, "/foo/bar/object.txt").Path)
+	assert.Nil(t, findAuditNamespace(namespaces, "/foobar"))
+
+	t.Run("matching-issuer-and-scope", func(t *testing.T) {
+		audit := &tokenAudit{Issuer: "https://issuer.example.com/", Scopes: []string{"storage.read:/baz"}}
+		auditTokenNamespace(audit, &namespaces[0])
+		assert.Empty(t, audit.Findings)
+	})
+
+	t.Run("wrong-issuer", func(t *testing.T) {
+		audit := &tokenAudit{Issuer: "https://issuer.example.com", Scopes: []string{"storage.read:/"}}
+		auditTokenNamespace(audit, &namespaces[1])
+		assert.Equal(t, []string{"namespace"}, auditChecks(audit, auditError))
+		assert.Contains(t, audit.Findings[0].Message, "https://other.example.com")
+	})
+
+	t.Run("scope-outside-namespace", func(t *testing.T) {
+		// Relative to the base path /foo, the scope repeating the prefix points at /foo/foo
+		audit := &tokenAudit{Issuer: "https://issuer.example.com", Scopes: []string{"storage.read:/foo"}}
+		ns := namespaces[0]
+		ns.Path = "/foo/baz"
+		auditTokenNamespace(audit, &ns)
+		assert.Equal(t, []string{"namespace"}, auditChecks(audit, auditError))
+	})
+
+	t.Run("public-namespace", func(t *testing.T) {
+		audit := &tokenAudit{Issuer: "https://issuer.example.com"}
+		auditTokenNamespace(audit, &namespaces[2])
+		assert.Equal(t, []string{"namespace"}, auditChecks(audit, auditWarning))
+	})
+}
+
+func TestReadAuditToken(t *testing.T) {
+	tok, err := readAuditToken("  abc.def.ghi\n", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", tok)
+
+	tokenFile := filepath.Join(t.TempDir(), "token.json")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(`{"access_token": "abc.def.ghi", "expires_in": 3600}`), 0600))
+	tok, err = readAuditToken(tokenFile, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", tok)
+
+	tok, err = readAuditToken("-", strings.NewReader("abc.def.ghi\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", tok)
+
+	_, err = readAuditToken("-", strings.NewReader(""))
+	assert.Error(t, err)
+}
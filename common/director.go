@@ -21,7 +21,13 @@ package common
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type (
@@ -44,6 +50,8 @@ type (
 		Write        bool
 		Listing      bool
 		FallBackRead bool
+		ServeStale   bool // True if the cache serves already-cached objects while their origin is unavailable
+		WriteBack    bool // True if the cache accepts uploads it writes back to their origin
 	}
 
 	NamespaceAdV2 struct {
@@ -52,6 +60,38 @@ type (
 		Path       string        `json:"path"`
 		Generation []TokenGen    `json:"token-generation"`
 		Issuer     []TokenIssuer `json:"token-issuer"`
+		// Restrictions on the objects that may be written to the namespace; nil if there are none
+		UploadPolicy *UploadPolicy `json:"upload-policy,omitempty"`
+		// How the namespace's objects may be changed once written, as set in its registration
+		WriteMode WriteMode `json:"write-mode,omitempty"`
+		// The persistent identifiers the namespace's owner assigned to its objects and collections
+		Identifiers []PersistentIdentifier `json:"identifiers,omitempty"`
+		// How long caches may keep the namespace's objects, as set in its registration; nil if
+		// the caches' own settings apply
+		CachePolicy *CachePolicy `json:"cache-policy,omitempty"`
+	}
+
+	// How caches treat the objects of a namespace whose contents change, so they don't
+	// serve outdated copies
+	CachePolicy struct {
+		// The longest time, in seconds, a cache may keep a copy of an object; 0 means no limit
+		MaxAge int64 `json:"max-age,omitempty"`
+		// Copies are never served without checking with the origin, such as while the
+		// origin is unavailable
+		MustRevalidate bool `json:"must-revalidate,omitempty"`
+	}
+
+	// A persistent identifier, such as a DOI or ARK, and the federation path it resolves to
+	PersistentIdentifier struct {
+		Identifier string `json:"identifier" mapstructure:"Identifier"` // In the form returned by ParsePersistentIdentifier
+		Path       string `json:"path" mapstructure:"Path"`
+	}
+
+	// Restrictions an origin places on the objects written to one of its exports
+	UploadPolicy struct {
+		Prefix              string   `json:"prefix" mapstructure:"Prefix"`
+		MaxObjectSize       int64    `json:"max-object-size,omitempty" mapstructure:"MaxObjectSize"` // In bytes; 0 means unlimited
+		AllowedNamePatterns []string `json:"allowed-name-patterns,omitempty" mapstructure:"AllowedNamePatterns"`
 	}
 
 	NamespaceAdV1 struct {
@@ -75,11 +115,16 @@ type (
 		Longitude          float64
 		EnableWrite        bool
 		EnableFallbackRead bool // True if reads from the origin are permitted when no cache is available
+		ServeStale         bool // True if the cache serves already-cached objects while their origin is unavailable
+		WriteBack          bool // True if the cache accepts uploads it writes back to their origin
 	}
 
 	ServerType   string
 	StrategyType string
 
+	// How the objects of a namespace may be changed once written
+	WriteMode string
+
 	OriginAdvertiseV2 struct {
 		Name       string          `json:"name"`
 		DataURL    string          `json:"data-url" binding:"required"`
@@ -87,6 +132,14 @@ type (
 		Caps       Capabilities    `json:"capabilities"`
 		Namespaces []NamespaceAdV2 `json:"namespaces"`
 		Issuer     []TokenIssuer   `json:"token-issuer"`
+		// Namespaces the origin exports but has paused; they are left out of Namespaces
+		PausedNamespaces []string `json:"paused-namespaces,omitempty"`
+		// Why the server failed its local health checks, if it did.  The director stops
+		// sending clients to a degraded server until it advertises as healthy again.
+		Degraded string `json:"degraded,omitempty"`
+		// A token for each namespace, keyed by its path, signed with the namespace's own
+		// key.  The director checks each namespace against its token.
+		NamespaceTokens map[string]string `json:"namespace-tokens,omitempty"`
 	}
 
 	OriginAdvertiseV1 struct {
@@ -97,8 +150,20 @@ type (
 		EnableWrite        bool            `json:"enablewrite"`
 		EnableFallbackRead bool            `json:"enable-fallback-read"` // True if the origin will allow direct client reads when no caches are available
 	}
+
+	// The version and capabilities a Pelican server reports at /api/v1.0/version
+	VersionInfo struct {
+		Version          string          `json:"version"`
+		Modules          []string        `json:"modules"`          // The enabled server modules, e.g. "origin" or "director"
+		AdSchemaVersions []string        `json:"adSchemaVersions"` // The versions of the server advertisement the server can send or accept
+		Features         map[string]bool `json:"features"`
+	}
 )
 
+// The server advertisement schema versions understood by this version of Pelican,
+// corresponding to OriginAdvertiseV1 and OriginAdvertiseV2
+var AdSchemaVersions = []string{"v1", "v2"}
+
 const (
 	CacheType  ServerType = "Cache"
 	OriginType ServerType = "Origin"
@@ -109,6 +174,64 @@ const (
 	VaultStrategy StrategyType = "Vault"
 )
 
+const (
+	// Objects may be overwritten and deleted.  Namespaces registered without a write mode are mutable.
+	WriteModeMutable WriteMode = "mutable"
+	// Objects may not be overwritten or deleted once written, for reproducible datasets
+	WriteModeImmutable WriteMode = "immutable"
+	// Objects may be overwritten, but each finished write is also kept as a new immutable
+	// version of the object, named by ObjectVersionPath
+	WriteModeVersioned WriteMode = "versioned"
+)
+
+// Version names end in ".v" and the version number, counting from 1
+var (
+	// A DOI's prefix is "10." followed by the registrant code; the suffix is any string
+	doiRegex = regexp.MustCompile(`^10\.[0-9]{4,9}/\S+$`)
+	// An ARK's NAAN identifies the assigning organization; the name is any string
+	arkRegex = regexp.MustCompile(`^[0-9a-z]{5}/\S+$`)
+)
+
+var objectVersionRegex = regexp.MustCompile(`\.v[1-9][0-9]*$`)
+
+const (
+	// The cache API serving already-cached objects while their origin is unavailable
+	StaleObjectAPIPath = "/api/v1.0/cache/stale"
+	// Set to "unavailable" on responses served without the object's origin
+	OriginStatusHeader = "X-Pelican-Origin-Status"
+	// The comma-separated features a client supports, sent on its requests to the director
+	ClientFeaturesHeader = "X-Pelican-Features"
+	// The size of the object a client is about to write, sent on its PUT to the director,
+	// as that request doesn't carry the object itself
+	ObjectSizeHeader = "X-Pelican-Object-Size"
+	// The cache API accepting uploads it writes back to their origin
+	WriteBackAPIPath = "/api/v1.0/cache/writeback"
+	// When the cache acknowledges an upload for write-back: WriteBackAckSpooled or WriteBackAckOrigin
+	WriteBackAckHeader = "X-Pelican-Write-Back-Ack"
+	// The ID of an upload the cache queued for write-back, to look up in its write-back queue
+	WriteBackIDHeader = "X-Pelican-Write-Back-Id"
+)
+
+// When a cache acknowledges an upload for write-back
+const (
+	// Once the object is safely on the cache's disk; the default
+	WriteBackAckSpooled = "spooled"
+	// Once the cache wrote the object to the origin
+	WriteBackAckOrigin = "origin"
+)
+
+// The features a client may list in the ClientFeaturesHeader
+const (
+	// The client falls back to the other servers listed in the Link header of a redirect
+	FeatureLinkFallback = "link-fallback"
+	// The client reads from the origins listed last in the Link header of a redirect,
+	// marked with fallback="origin", once the caches failed to serve the object
+	FeatureOriginFallback = "origin-fallback"
+	// The client uploads through a nearby cache writing objects back to their origin, when
+	// the director finds one; such caches are experimental
+	FeatureCacheWriteBack = "cache-write-back"
+)
+
 func (ad ServerAd) MarshalJSON() ([]byte, error) {
 	baseAd := struct {
 		Name               string     `json:"name"`
@@ -120,6 +243,8 @@ func (ad ServerAd) MarshalJSON() ([]byte, error) {
 		Longitude          float64    `json:"longitude"`
 		EnableWrite        bool       `json:"enable_write"`
 		EnableFallbackRead bool       `json:"enable_fallback_read"`
+		ServeStale         bool       `json:"serve_stale"`
+		WriteBack          bool       `json:"write_back"`
 	}{
 		Name:               ad.Name,
 		AuthURL:            ad.AuthURL.String(),
@@ -130,6 +255,106 @@ func (ad ServerAd) MarshalJSON() ([]byte, error) {
 		Longitude:          ad.Longitude,
 		EnableWrite:        ad.EnableWrite,
 		EnableFallbackRead: ad.EnableFallbackRead,
+		ServeStale:         ad.ServeStale,
+		WriteBack:          ad.WriteBack,
 	}
 	return json.Marshal(baseAd)
 }
+
+// Whether the policy permits writing the object at objectPath, in the namespace at namespacePath,
+// by its name.  Patterns containing a slash match the object's path relative to the namespace;
+// the rest match its base name.
+func (policy *UploadPolicy) AllowsName(namespacePath, objectPath string) bool {
+	if len(policy.AllowedNamePatterns) == 0 {
+		return true
+	}
+	baseName := path.Base(objectPath)
+	relPath := strings.TrimPrefix(strings.TrimPrefix(objectPath, namespacePath), "/")
+	for _, pattern := range policy.AllowedNamePatterns {
+		name := baseName
+		if strings.Contains(pattern, "/") {
+			name = relPath
+		}
+		// Origins validate their patterns, so a malformed one simply never matches
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the policy permits writing an object of size bytes
+func (policy *UploadPolicy) AllowsSize(size int64) bool {
+	return policy.MaxObjectSize <= 0 || size <= policy.MaxObjectSize
+}
+
+// The Cache-Control header value expressing the policy, e.g. "max-age=3600, must-revalidate"
+func (policy *CachePolicy) CacheControl() string {
+	if policy == nil {
+		return ""
+	}
+	directives := []string{}
+	if policy.MaxAge > 0 {
+		directives = append(directives, "max-age="+strconv.FormatInt(policy.MaxAge, 10))
+	}
+	if policy.MustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Whether a copy of an object cached age ago may still be served
+func (policy *CachePolicy) IsFresh(age time.Duration) bool {
+	return policy == nil || policy.MaxAge <= 0 || age <= time.Duration(policy.MaxAge)*time.Second
+}
+
+// Whether the write mode is one Pelican knows of; an empty mode is mutable
+func (mode WriteMode) IsValid() bool {
+	switch mode {
+	case "", WriteModeMutable, WriteModeImmutable, WriteModeVersioned:
+		return true
+	}
+	return false
+}
+
+// The path of version number version of the object at objectPath in a versioned namespace
+func ObjectVersionPath(objectPath string, version int) string {
+	return fmt.Sprintf("%s.v%d", objectPath, version)
+}
+
+// Whether objectPath names a version of an object in a versioned namespace rather than the object itself
+func IsObjectVersionPath(objectPath string) bool {
+	return objectVersionRegex.MatchString(objectPath)
+}
+
+// Whether the object name is a persistent identifier, e.g. doi:10.1234/abc, rather than a path or URL
+func IsPersistentIdentifier(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(lower, "doi:") || strings.HasPrefix(lower, "ark:")
+}
+
+// Parse a DOI ("doi:10.1234/abc") or ARK ("ark:12345/abc" or "ark:/12345/abc") into the form
+// identifiers are matched in: DOIs are case-insensitive, so they're lowercased, and ARKs drop
+// the optional slash after the scheme
+func ParsePersistentIdentifier(identifier string) (string, error) {
+	identifier = strings.TrimSpace(identifier)
+	scheme, value, found := strings.Cut(identifier, ":")
+	if !found {
+		return "", fmt.Errorf("persistent identifier %q has no doi: or ark: scheme", identifier)
+	}
+	switch strings.ToLower(scheme) {
+	case "doi":
+		value = strings.ToLower(value)
+		if !doiRegex.MatchString(value) {
+			return "", fmt.Errorf("invalid DOI %q; expected the form doi:10.<registrant>/<suffix>", identifier)
+		}
+		return "doi:" + value, nil
+	case "ark":
+		value = strings.TrimPrefix(value, "/")
+		if !arkRegex.MatchString(value) {
+			return "", fmt.Errorf("invalid ARK %q; expected the form ark:<NAAN>/<name>", identifier)
+		}
+		return "ark:" + value, nil
+	}
+	return "", fmt.Errorf("persistent identifier %q has the unsupported scheme %q; expected doi or ark", identifier, scheme)
+}
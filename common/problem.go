@@ -0,0 +1,140 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+type (
+	// A machine-readable code identifying the kind of error in a Problem.  Codes are
+	// stable across releases; the detail message is not.
+	ErrorCode string
+
+	// An error response of the Pelican server APIs, in the format of RFC 7807
+	// (application/problem+json).  Error repeats the detail for clients written
+	// against the older {"error": "..."} responses.
+	Problem struct {
+		Type     string    `json:"type"`
+		Title    string    `json:"title"`
+		Status   int       `json:"status"`
+		Detail   string    `json:"detail,omitempty"`
+		Instance string    `json:"instance,omitempty"`
+		Code     ErrorCode `json:"code"`
+		Error    string    `json:"error"`
+	}
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	ErrCodeInvalidRequest   ErrorCode = "invalid-request"
+	ErrCodeUnauthenticated  ErrorCode = "unauthenticated"
+	ErrCodeForbidden        ErrorCode = "forbidden"
+	ErrCodeNotFound         ErrorCode = "not-found"
+	ErrCodeMethodNotAllowed ErrorCode = "method-not-allowed"
+	ErrCodeConflict         ErrorCode = "conflict"
+	ErrCodeTooManyRequests  ErrorCode = "too-many-requests"
+	ErrCodeInternal         ErrorCode = "internal-error"
+	ErrCodeNotImplemented   ErrorCode = "not-implemented"
+	ErrCodeUnavailable      ErrorCode = "unavailable"
+	ErrCodeNotApproved      ErrorCode = "not-approved" // The namespace or server wasn't approved by a registry administrator
+
+	ErrCodeInvalidPrefix        ErrorCode = "invalid-prefix"         // The namespace prefix is malformed or missing
+	ErrCodeNamespaceExists      ErrorCode = "namespace-exists"       // The namespace prefix is already registered
+	ErrCodeNamespaceNotFound    ErrorCode = "namespace-not-found"    // No namespace is registered or advertised at the prefix
+	ErrCodeInvalidPublicKey     ErrorCode = "invalid-public-key"     // The public key or JWKS is malformed or doesn't match
+	ErrCodeMissingToken         ErrorCode = "missing-token"          // The request has no bearer token
+	ErrCodeInvalidToken         ErrorCode = "invalid-token"          // The token failed verification or lacks the required scope
+	ErrCodeIncompatibleVersion  ErrorCode = "incompatible-version"   // The client or server is older than the minimum supported version
+	ErrCodeNoServers            ErrorCode = "no-servers"             // No origin or cache serves the object
+	ErrCodeUploadPolicyViolated ErrorCode = "upload-policy-violated" // The upload is refused by the namespace's upload policy
+	ErrCodeTermsNotAccepted     ErrorCode = "terms-not-accepted"     // The registrant hasn't accepted the current terms of service
+)
+
+// Get the code of errors with the HTTP status that don't have a more specific code
+func ErrorCodeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthenticated
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return ErrCodeTooManyRequests
+	case http.StatusInternalServerError:
+		return ErrCodeInternal
+	case http.StatusNotImplemented:
+		return ErrCodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	default:
+		return ErrorCode("http-" + strconv.Itoa(status))
+	}
+}
+
+// Get the problem type URI of the error code
+func (code ErrorCode) TypeURI() string {
+	return "urn:pelican:error:" + string(code)
+}
+
+// Create the problem for an error with the HTTP status.  An empty code is replaced
+// by the status's code.
+func NewProblem(status int, code ErrorCode, detail string) Problem {
+	if code == "" {
+		code = ErrorCodeForStatus(status)
+	}
+	return Problem{
+		Type:   code.TypeURI(),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Error:  detail,
+	}
+}
+
+// Parse an error response of a Pelican server, whether it's a problem or an older
+// {"error": "..."} response.  Returns false if the body is neither.
+func ParseProblem(status int, body []byte) (Problem, bool) {
+	problem := Problem{}
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return problem, false
+	}
+	if problem.Code == "" {
+		if problem.Error == "" {
+			return problem, false
+		}
+		// An older response that only has the message
+		problem = NewProblem(status, "", problem.Error)
+	}
+	if problem.Detail == "" {
+		problem.Detail = problem.Error
+	}
+	return problem, true
+}
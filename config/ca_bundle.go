@@ -0,0 +1,155 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The CAs trusted by transports verifying servers with verifyWithTransportCAs; replaced
+// whenever Server.TLSCACertificateDirectory changes
+var transportCAs atomic.Pointer[x509.CertPool]
+
+// Add the certificates of the PEM files in the directory to the pool, returning the
+// number of files holding certificates.  Other files, such as the CRL URLs and signing
+// policies of an IGTF bundle, are skipped.
+func appendCADirectory(pool *x509.CertPool, caDir string) (int, error) {
+	entries, err := os.ReadDir(caDir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read the CA directory %s", caDir)
+	}
+	count := 0
+	for _, entry := range entries {
+		caPath := filepath.Join(caDir, entry.Name())
+		// Follow the hash links of c_rehash'd directories
+		if info, err := os.Stat(caPath); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		contents, err := os.ReadFile(caPath)
+		if err != nil {
+			log.Warningf("Failed to read %s in the CA directory: %v", caPath, err)
+			continue
+		}
+		if pool.AppendCertsFromPEM(contents) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Build the pool of CAs transports trust: the system's, the CA of
+// Server.TLSCACertificateFile, and those of Server.TLSCACertificateDirectory.  If the
+// directory can't be read, the pool of the others is returned with the error.
+func loadTransportCAs() (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Debugln("Failed to load the system's CAs:", err)
+		pool = x509.NewCertPool()
+	}
+	if caCert, err := LoadCertficate(param.Server_TLSCACertificateFile.GetString()); err == nil {
+		pool.AddCert(caCert)
+	}
+	if caDir := param.Server_TLSCACertificateDirectory.GetString(); caDir != "" {
+		count, err := appendCADirectory(pool, caDir)
+		if err != nil {
+			return pool, err
+		}
+		log.Debugf("Loaded the CA certificates of %d files in %s", count, caDir)
+	}
+	return pool, nil
+}
+
+// Verify the server's certificate chain and hostname against transportCAs.  Transports
+// using it in place of the RootCAs of their TLS config trust the CAs as they are at the
+// time of the connection, so changes to the CA directory apply to transports in use.
+func verifyWithTransportCAs(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("the server sent no certificate")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         transportCAs.Load(),
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// Watch Server.TLSCACertificateDirectory, reloading the CAs transports trust whenever
+// its files change, until the context is done
+func launchCADirectoryWatcher(ctx context.Context) {
+	caDir := param.Server_TLSCACertificateDirectory.GetString()
+	if caDir == "" || param.TLSSkipVerify.GetBool() {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warningln("Failed to watch the CA directory; changes to it need a restart:", err)
+		return
+	}
+	if err = watcher.Add(caDir); err != nil {
+		watcher.Close()
+		log.Warningf("Failed to watch the CA directory %s; changes to it need a restart: %v", caDir, err)
+		return
+	}
+	egrp, ok := ctx.Value(EgrpKey).(*errgroup.Group)
+	if !ok {
+		egrp = &errgroup.Group{}
+	}
+	egrp.Go(func() error {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+				log.Debugf("Got filesystem event (%v); reloading the CA certificates of %s", event, caDir)
+				pool, err := loadTransportCAs()
+				if err != nil {
+					log.Warningln("Failed to reload the CA certificates; keeping the current ones:", err)
+					continue
+				}
+				transportCAs.Store(pool)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				log.Warningf("Failure watching the CA directory %s: %v", caDir, err)
+			}
+		}
+	})
+}
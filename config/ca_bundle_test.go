@@ -0,0 +1,102 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func writeCertificatePEM(t *testing.T, filename string, cert *x509.Certificate) {
+	require.NoError(t, os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644))
+}
+
+func TestAppendCADirectory(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	caDir := t.TempDir()
+	writeCertificatePEM(t, filepath.Join(caDir, "ca.pem"), server.Certificate())
+	require.NoError(t, os.Symlink("ca.pem", filepath.Join(caDir, "5e7ab1c3.0")))
+	require.NoError(t, os.WriteFile(filepath.Join(caDir, "ca.signing_policy"), []byte("access_id_CA X509 '/CN=Test CA'\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(caDir, "subdir"), 0755))
+
+	pool := x509.NewCertPool()
+	count, err := appendCADirectory(pool, caDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	_, err = server.Certificate().Verify(x509.VerifyOptions{Roots: pool})
+	assert.NoError(t, err)
+
+	_, err = appendCADirectory(pool, filepath.Join(caDir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestTransportCADirectory(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		setupTransport()
+	})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	caDir := t.TempDir()
+	viper.Set("Server.TLSCACertificateDirectory", caDir)
+	viper.Set("Server.TLSCACertificateFile", filepath.Join(caDir, "missing.pem"))
+	client := &http.Client{Transport: setupTransport()}
+	get := func() error {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	require.Error(t, get())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	egrp := &errgroup.Group{}
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, egrp.Wait())
+	})
+	launchCADirectoryWatcher(context.WithValue(ctx, EgrpKey, egrp))
+
+	// The transport in use picks up the CA added to the directory
+	writeCertificatePEM(t, filepath.Join(caDir, "ca.pem"), server.Certificate())
+	assert.Eventually(t, func() bool { return get() == nil }, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(caDir, "ca.pem")))
+	assert.Eventually(t, func() bool {
+		client.CloseIdleConnections()
+		return get() != nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,109 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The client settings a federation may set.  Only transfer tuning knobs are allowed;
+// anything that points the client at URLs, files, keys, or trust roots (such as
+// Client.SelfUpdateUrl or Client.SelfUpdatePublicKey) must come from the local
+// configuration.  Keys are lowercase, as viper reports them.
+var federationClientConfigKeys = map[string]bool{
+	"client.stoppedtransfertimeout": true,
+	"client.slowtransferrampuptime": true,
+	"client.slowtransferwindow":     true,
+	"client.slowtransferpolicy":     true,
+	"client.transfertimeout":        true,
+	"client.stagetimeout":           true,
+	"client.minimumdownloadspeed":   true,
+	"client.disableproxyfallback":   true,
+}
+
+// Merge the federation's client settings document below the local configuration.
+// The settings become viper defaults, so anything set in a local config file or
+// environment variable still takes precedence. Only the tuning settings in
+// federationClientConfigKeys are accepted; the federation can't change anything
+// else about the client.
+func mergeFederationClientConfig(contents []byte) error {
+	fedConfig := viper.New()
+	fedConfig.SetConfigType("yaml")
+	if err := fedConfig.ReadConfig(bytes.NewReader(contents)); err != nil {
+		return errors.Wrap(err, "Failed to parse the federation's client configuration")
+	}
+	for _, key := range fedConfig.AllKeys() {
+		if !federationClientConfigKeys[strings.ToLower(key)] {
+			log.Warningf("Ignoring %s in the federation's client configuration; the federation may only set transfer tuning settings", key)
+			continue
+		}
+		log.Debugf("Federation client configuration sets the default of %s", key)
+		viper.SetDefault(key, fedConfig.Get(key))
+	}
+	return nil
+}
+
+// Fetch the client settings document published by the federation at
+// Federation.ClientConfigUrl and merge it below the local configuration
+func loadFederationClientConfig() error {
+	clientConfigUrl := param.Federation_ClientConfigUrl.GetString()
+	if clientConfigUrl == "" {
+		return nil
+	}
+
+	httpClient := http.Client{
+		Transport: GetTransport(),
+		Timeout:   time.Second * 5,
+	}
+	req, err := http.NewRequest(http.MethodGet, clientConfigUrl, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failure when creating the request for the federation's client configuration at %s", clientConfigUrl)
+	}
+	req.Header.Set("User-Agent", "pelican/7")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Failure when fetching the federation's client configuration from %s", clientConfigUrl)
+	}
+	defer resp.Body.Close()
+
+	// The document only holds a handful of settings; refuse anything unreasonably large
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return errors.Wrapf(err, "Failure when reading the federation's client configuration from %s", clientConfigUrl)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Fetching the federation's client configuration from %s failed with HTTP status %d", clientConfigUrl, resp.StatusCode)
+	}
+	if err = VerifyFederationResource(req.Context(), "the federation's client configuration at "+clientConfigUrl, body, resp.Header.Get(ResourceSignatureHeader)); err != nil {
+		return err
+	}
+
+	return mergeFederationClientConfig(body)
+}
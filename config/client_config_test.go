@@ -0,0 +1,81 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+func TestMergeFederationClientConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	// Local configuration must win over the federation's settings
+	localConfig := `
+Client:
+  SlowTransferWindow: 60
+`
+	viper.SetConfigType("yaml")
+	require.NoError(t, viper.ReadConfig(strings.NewReader(localConfig)))
+	viper.SetDefault("Client.SlowTransferPolicy", "adaptive")
+
+	fedConfig := `
+Client:
+  SlowTransferWindow: 10
+  SlowTransferPolicy: legacy
+  DisableProxyFallback: true
+  SelfUpdateUrl: https://evil.example.com/releases
+  SelfUpdatePublicKey: /tmp/federation-owned.pem
+  StaticFederationFile: /tmp/federation-owned.yaml
+IssuerKey: /tmp/federation-owned.jwk
+`
+	require.NoError(t, mergeFederationClientConfig([]byte(fedConfig)))
+
+	assert.Equal(t, 60, param.Client_SlowTransferWindow.GetInt())
+	assert.Equal(t, "legacy", param.Client_SlowTransferPolicy.GetString())
+	assert.True(t, param.Client_DisableProxyFallback.GetBool())
+	assert.Empty(t, param.IssuerKey.GetString())
+	// URLs, keys, and files are never taken from the federation
+	assert.Empty(t, param.Client_SelfUpdateUrl.GetString())
+	assert.Empty(t, param.Client_SelfUpdatePublicKey.GetString())
+	assert.Empty(t, param.Client_StaticFederationFile.GetString())
+
+	assert.Error(t, mergeFederationClientConfig([]byte("Client: [unterminated")))
+}
+
+func TestValidateSlowTransferPolicy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	for _, policy := range []string{"adaptive", "legacy"} {
+		viper.Set("Client.SlowTransferPolicy", policy)
+		assert.NoError(t, validateSlowTransferPolicy())
+	}
+	for _, policy := range []string{"", "Adaptive", "fastest"} {
+		viper.Set("Client.SlowTransferPolicy", policy)
+		assert.Error(t, validateSlowTransferPolicy())
+	}
+}
@@ -19,6 +19,7 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -32,6 +33,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,7 +46,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/sync/errgroup"
 )
 
 // Structs holding the OAuth2 state (and any other OSDF config needed)
@@ -76,6 +77,21 @@ type (
 		DirectorEndpoint              string `json:"director_endpoint"`
 		NamespaceRegistrationEndpoint string `json:"namespace_registration_endpoint"`
 		JwksUri                       string `json:"jwks_uri"`
+		ClientConfigUri               string `json:"client_config_uri,omitempty"`
+		BrokerEndpoint                string `json:"broker_endpoint,omitempty"`
+		// Human-facing information about the federation
+		DisplayName string `json:"display_name,omitempty"`
+		Contact     string `json:"contact,omitempty"`
+		// The optional features the federation's services offer; see the Feature* constants
+		SupportedFeatures []string `json:"supported_features,omitempty"`
+		// The oldest versions of each service the federation supports
+		MinimumOriginVersion string `json:"minimum_origin_version,omitempty"`
+		MinimumCacheVersion  string `json:"minimum_cache_version,omitempty"`
+		MinimumClientVersion string `json:"minimum_client_version,omitempty"`
+		// Fields of the discovery document this version of Pelican doesn't know about,
+		// including a federation's custom fields.  They're kept as-is when the document
+		// is parsed and written back out when it's serialized.
+		Extensions map[string]json.RawMessage `json:"-"`
 	}
 
 	TokenOperation int
@@ -94,6 +110,7 @@ const (
 	OriginType
 	DirectorType
 	RegistryType
+	LocalCacheType
 
 	EgrpKey ContextKey = "egrp"
 )
@@ -103,6 +120,7 @@ const (
 	TokenRead
 	TokenSharedWrite
 	TokenSharedRead
+	TokenDelete
 )
 
 var (
@@ -115,20 +133,21 @@ var (
 	//go:embed resources/osdf.yaml
 	osdfDefaultsYaml string
 
-	// Potentially holds a directory to cleanup
-	tempRunDir  string
-	cleanupOnce sync.Once
+	// Temporary directories to cleanup on shutdown
+	tempRunDirs      []string
+	tempRunDirsMutex sync.Mutex
 
-	// Our global transports that only will get reconfigured if needed
-	transport     *http.Transport
-	onceTransport sync.Once
+	// Our global transport; InitServer and InitClient replace it with one built from the
+	// current configuration
+	transport      *http.Transport
+	transportMutex sync.Mutex
 
 	// Global struct validator
 	validate *validator.Validate
 
 	// A variable indicating enabled Pelican servers in the current process
-	enabledServers ServerType
-	setServerOnce  sync.Once
+	enabledServers      ServerType
+	enabledServersMutex sync.RWMutex
 
 	// Pelican version
 	PelicanVersion string
@@ -161,23 +180,29 @@ func (sType *ServerType) Clear() {
 	*sType = ServerType(0)
 }
 
-// setEnabledServer sets the global variable config.EnabledServers to include newServers.
+// setEnabledServer sets the global variable config.EnabledServers to newServers.
 // Since this function should only be called in config package, we mark it "private" to avoid
 // reset value in other pacakge
 //
-// This will only be called once in a single process
+// Each call to InitServer replaces the enabled servers, so that a process (such as a test)
+// may initialize different servers in turn.
 func setEnabledServer(newServers ServerType) {
-	setServerOnce.Do(func() {
-		// For each process, we only want to set enabled servers once
-		enabledServers.Set(newServers)
-	})
+	enabledServersMutex.Lock()
+	defer enabledServersMutex.Unlock()
+	enabledServers = newServers
+}
+
+func getEnabledServers() ServerType {
+	enabledServersMutex.RLock()
+	defer enabledServersMutex.RUnlock()
+	return enabledServers
 }
 
 // IsServerEnabled checks if testServer is enabled in the current process.
 //
 // Use this function to check which server(s) are running in the current process.
 func IsServerEnabled(testServer ServerType) bool {
-	return enabledServers.IsEnabled(testServer)
+	return getEnabledServers().IsEnabled(testServer)
 }
 
 // Get a string slice of currently enabled servers, sorted by alphabetical order.
@@ -185,6 +210,7 @@ func IsServerEnabled(testServer ServerType) bool {
 // To get strings in lowerCase, set lowerCase = true.
 func GetEnabledServerString(lowerCase bool) []string {
 	servers := make([]string, 0)
+	enabledServers := getEnabledServers()
 	if enabledServers.IsEnabled(CacheType) {
 		servers = append(servers, CacheType.String())
 	}
@@ -197,6 +223,9 @@ func GetEnabledServerString(lowerCase bool) []string {
 	if enabledServers.IsEnabled(RegistryType) {
 		servers = append(servers, RegistryType.String())
 	}
+	if enabledServers.IsEnabled(LocalCacheType) {
+		servers = append(servers, LocalCacheType.String())
+	}
 	sort.Strings(servers)
 	if lowerCase {
 		for i, serverStr := range servers {
@@ -208,6 +237,37 @@ func GetEnabledServerString(lowerCase bool) []string {
 	}
 }
 
+// Get the least-privileged WLCG storage scope (without a path) authorizing the operation
+func (op TokenOperation) StorageScope() string {
+	switch op {
+	case TokenWrite, TokenSharedWrite:
+		return "storage.create"
+	case TokenDelete:
+		return "storage.modify"
+	default:
+		return "storage.read"
+	}
+}
+
+// Whether a token with the storage scope (without a path) authorizes the operation;
+// storage.modify implies storage.create
+func (op TokenOperation) AcceptsStorageScope(scope string) bool {
+	switch op {
+	case TokenWrite, TokenSharedWrite:
+		return scope == "storage.create" || scope == "storage.modify"
+	case TokenDelete:
+		return scope == "storage.modify"
+	default:
+		return scope == "storage.read"
+	}
+}
+
+// Whether the operation acts on the single object a shared URL was created for, in
+// which case a token's scope must name the object exactly
+func (op TokenOperation) IsShared() bool {
+	return op == TokenSharedWrite || op == TokenSharedRead
+}
+
 // Create a new, empty ServerType bitmask
 func NewServerType() ServerType {
 	return ServerType(0)
@@ -226,6 +286,8 @@ func (sType ServerType) String() string {
 		return "Director"
 	case RegistryType:
 		return "Registry"
+	case LocalCacheType:
+		return "LocalCache"
 	}
 	return "Unknown"
 }
@@ -244,6 +306,9 @@ func (sType *ServerType) SetString(name string) bool {
 	case "registry":
 		*sType |= RegistryType
 		return true
+	case "localcache":
+		*sType |= LocalCacheType
+		return true
 	}
 	return false
 }
@@ -295,6 +360,7 @@ func GetAllPrefixes() []string {
 func DiscoverFederation() error {
 	federationStr := param.Federation_DiscoveryUrl.GetString()
 	externalUrlStr := param.Server_ExternalWebUrl.GetString()
+	enabledServers := getEnabledServers()
 	defer func() {
 		// Set default guesses if these values are still unset.
 		if param.Federation_DirectorUrl.GetString() == "" && enabledServers.IsEnabled(DirectorType) {
@@ -344,19 +410,62 @@ func DiscoverFederation() error {
 		return errors.Wrap(err, "Unable to parse federation url because of invalid path")
 	}
 
+	metadata, err := fetchFederationMetadataWithRetry(discoveryUrl)
+	if err == nil {
+		if saveErr := saveDiscoveryCache(discoveryUrl.String(), metadata); saveErr != nil {
+			log.Warningln("Failed to save the federation discovery result:", saveErr)
+		}
+	} else if metadata, err = discoverFederationFallback(federationUrl, discoveryUrl, err); err != nil {
+		return err
+	}
+	applyFederationMetadata(metadata, FederationDiscovery{})
+	if metadata.DisplayName != "" {
+		log.Debugf("Discovered federation %q (contact: %s)", metadata.DisplayName, metadata.Contact)
+	}
+	warnIfBelowMinimumVersion(metadata)
+
+	return nil
+}
+
+// Find the federation's services when its discovery endpoint can't be reached: from
+// the services it publishes in DNS or, failing that, from the discovery document saved
+// the last time the endpoint was reached.  Returns fetchErr if neither is available.
+func discoverFederationFallback(federationUrl, discoveryUrl *url.URL, fetchErr error) (FederationDiscovery, error) {
+	// Federations can publish their services in DNS for when the discovery endpoint is down
+	if host := federationUrl.Hostname(); host != "" && net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		dnsMetadata, dnsErr := DiscoverFederationDNS(ctx, host)
+		if dnsErr == nil {
+			log.Warningf("Federation metadata lookup failed (%v); using the services published in DNS under %s instead", fetchErr, host)
+			return dnsMetadata, nil
+		}
+		log.Debugln("DNS fallback for federation discovery failed:", dnsErr)
+	}
+	if cached, ok := getCachedDiscovery(discoveryUrl.String()); ok {
+		log.Warningf("Federation metadata lookup failed (%v); using the result saved at %s instead",
+			fetchErr, cached.Fetched.Format(time.RFC3339))
+		setStaleDiscovery(&staleDiscovery{discoveryUrl: discoveryUrl, metadata: cached.Metadata})
+		return cached.Metadata, nil
+	}
+	return FederationDiscovery{}, fetchErr
+}
+
+// Fetch the federation metadata from its .well-known/pelican-configuration endpoint
+func fetchFederationMetadata(discoveryUrl *url.URL) (FederationDiscovery, error) {
 	httpClient := http.Client{
 		Transport: GetTransport(),
 		Timeout:   time.Second * 5,
 	}
 	req, err := http.NewRequest(http.MethodGet, discoveryUrl.String(), nil)
 	if err != nil {
-		return errors.Wrapf(err, "Failure when doing federation metadata request creation for %s", discoveryUrl)
+		return FederationDiscovery{}, errors.Wrapf(err, "Failure when doing federation metadata request creation for %s", discoveryUrl)
 	}
 	req.Header.Set("User-Agent", "pelican/7")
 
 	result, err := httpClient.Do(req)
 	if err != nil {
-		return errors.Wrapf(err, "Failure when doing federation metadata lookup to %s", discoveryUrl)
+		return FederationDiscovery{}, errors.Wrapf(err, "Failure when doing federation metadata lookup to %s", discoveryUrl)
 	}
 
 	if result.Body != nil {
@@ -365,7 +474,7 @@ func DiscoverFederation() error {
 
 	body, err := io.ReadAll(result.Body)
 	if err != nil {
-		return errors.Wrapf(err, "Failure when doing federation metadata read to %s", discoveryUrl)
+		return FederationDiscovery{}, errors.Wrapf(err, "Failure when doing federation metadata read to %s", discoveryUrl)
 	}
 
 	if result.StatusCode != http.StatusOK {
@@ -374,30 +483,14 @@ func DiscoverFederation() error {
 			truncatedMessage = string(body[:1000])
 			truncatedMessage += " [... remainder truncated ...]"
 		}
-		return errors.Errorf("Federation metadata discovery failed with HTTP status %d.  Error message: %s", result.StatusCode, truncatedMessage)
+		return FederationDiscovery{}, errors.Errorf("Federation metadata discovery failed with HTTP status %d.  Error message: %s", result.StatusCode, truncatedMessage)
 	}
 
 	metadata := FederationDiscovery{}
-	err = json.Unmarshal(body, &metadata)
-	if err != nil {
-		return errors.Wrapf(err, "Failure when parsing federation metadata at %s", discoveryUrl)
+	if err = json.Unmarshal(body, &metadata); err != nil {
+		return FederationDiscovery{}, errors.Wrapf(err, "Failure when parsing federation metadata at %s", discoveryUrl)
 	}
-	if curDirectorURL == "" {
-		log.Debugln("Federation service discovery resulted in director URL", metadata.DirectorEndpoint)
-		viper.Set("Federation.DirectorUrl", metadata.DirectorEndpoint)
-	}
-	if curRegistryURL == "" {
-		log.Debugln("Federation service discovery resulted in registry URL",
-			metadata.NamespaceRegistrationEndpoint)
-		viper.Set("Federation.RegistryUrl", metadata.NamespaceRegistrationEndpoint)
-	}
-	if curFederationJwkURL == "" {
-		log.Debugln("Federation service discovery resulted in JWKS URL",
-			metadata.JwksUri)
-		viper.Set("Federation.JwkUrl", metadata.JwksUri)
-	}
-
-	return nil
+	return metadata, nil
 }
 
 // Return a struct representing the current (global) federation metadata
@@ -406,6 +499,8 @@ func GetFederation() FederationDiscovery {
 		DirectorEndpoint:              param.Federation_DirectorUrl.GetString(),
 		NamespaceRegistrationEndpoint: param.Federation_RegistryUrl.GetString(),
 		JwksUri:                       param.Federation_JwkUrl.GetString(),
+		ClientConfigUri:               param.Federation_ClientConfigUrl.GetString(),
+		BrokerEndpoint:                param.Federation_BrokerUrl.GetString(),
 	}
 }
 
@@ -414,34 +509,32 @@ func SetFederation(fd FederationDiscovery) {
 	viper.Set("Federation.DirectorUrl", fd.DirectorEndpoint)
 	viper.Set("Federation.RegistryUrl", fd.NamespaceRegistrationEndpoint)
 	viper.Set("Federation.JwkUrl", fd.JwksUri)
+	viper.Set("Federation.ClientConfigUrl", fd.ClientConfigUri)
+	viper.Set("Federation.BrokerUrl", fd.BrokerEndpoint)
 }
 
-// TODO: It's not clear that this function works correctly.  We should
-// pass an errgroup here and ensure that the cleanup is complete before
-// the main thread shuts down.
-func cleanupDirOnShutdown(ctx context.Context, dir string) {
-	tempRunDir = dir
-	egrp, ok := ctx.Value(EgrpKey).(*errgroup.Group)
-	if !ok {
-		egrp = &errgroup.Group{}
-	}
-	egrp.Go(func() error {
-		<-ctx.Done()
-		err := CleanupTempResources()
-		if err != nil {
-			log.Infoln("Error when cleaning up temporary directories:", err)
+// Remove the directory once the server shuts down, after the daemons using it stopped
+func cleanupDirOnShutdown(dir string) {
+	tempRunDirsMutex.Lock()
+	tempRunDirs = append(tempRunDirs, dir)
+	tempRunDirsMutex.Unlock()
+	RegisterShutdownHook("temporary directory cleanup", ShutdownOrderCleanup, 30*time.Second, func(_ context.Context) error {
+		if err := CleanupTempResources(); err != nil {
+			return errors.Wrap(err, "failed to clean up the temporary directories")
 		}
-		return err
+		return nil
 	})
 }
 
 func CleanupTempResources() (err error) {
-	cleanupOnce.Do(func() {
-		if tempRunDir != "" {
-			err = os.RemoveAll(tempRunDir)
-			tempRunDir = ""
+	tempRunDirsMutex.Lock()
+	defer tempRunDirsMutex.Unlock()
+	for _, dir := range tempRunDirs {
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			err = rmErr
 		}
-	})
+	}
+	tempRunDirs = nil
 	return
 }
 
@@ -454,7 +547,8 @@ func getConfigBase() (string, error) {
 	return filepath.Join(home, ".config", "pelican"), nil
 }
 
-func setupTransport() {
+// Build a new transport from the current configuration
+func newTransport() *http.Transport {
 	//Getting timeouts and other information from defaults.yaml
 	maxIdleConns := param.Transport_MaxIdleConns.GetInt()
 	idleConnTimeout := param.Transport_IdleConnTimeout.GetDuration()
@@ -465,13 +559,30 @@ func setupTransport() {
 	transportDialerTimeout := param.Transport_DialerTimeout.GetDuration()
 	transportKeepAlive := param.Transport_DialerKeepAlive.GetDuration()
 
+	dialer := &net.Dialer{
+		Timeout:   transportDialerTimeout,
+		KeepAlive: transportKeepAlive,
+	}
+	dialContext := dialer.DialContext
+	// Race the addresses of multi-address hosts so one dead address doesn't fail the host
+	if attemptDelay := param.Transport_DialerAttemptDelay.GetDuration(); attemptDelay > 0 {
+		dialContext = (&multiEndpointDialer{
+			dialer:       dialer,
+			lookupIPAddr: net.DefaultResolver.LookupIPAddr,
+			attemptDelay: attemptDelay,
+		}).DialContext
+	}
+
+	proxy, err := getProxyFunc()
+	if err != nil {
+		log.Errorln("Ignoring the proxy configuration in favor of the environment's:", err)
+		proxy = http.ProxyFromEnvironment
+	}
+
 	//Set up the transport
-	transport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   transportDialerTimeout,
-			KeepAlive: transportKeepAlive,
-		}).DialContext,
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialContext,
 		MaxIdleConns:          maxIdleConns,
 		IdleConnTimeout:       idleConnTimeout,
 		TLSHandshakeTimeout:   transportTLSHandshakeTimeout,
@@ -480,6 +591,19 @@ func setupTransport() {
 	}
 	if param.TLSSkipVerify.GetBool() {
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	} else if param.Server_TLSCACertificateDirectory.GetString() != "" {
+		// The CAs of the directory may change while the transport is in use, so the
+		// server's certificate is verified against them as they are when connecting
+		pool, err := loadTransportCAs()
+		if err != nil {
+			log.Warningln("Failed to load the CA certificates:", err)
+		}
+		transportCAs.Store(pool)
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+			VerifyConnection:   verifyWithTransportCAs,
+		}
+		return transport
 	}
 	if caCert, err := LoadCertficate(param.Server_TLSCACertificateFile.GetString()); err == nil {
 		systemPool, err := x509.SystemCertPool()
@@ -493,6 +617,16 @@ func setupTransport() {
 			}
 		}
 	}
+	return transport
+}
+
+// Replace the global transport with one built from the current configuration
+func setupTransport() *http.Transport {
+	newTr := newTransport()
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	transport = newTr
+	return newTr
 }
 
 func parseServerIssuerURL(sType ServerType) error {
@@ -518,11 +652,11 @@ func parseServerIssuerURL(sType ServerType) error {
 	}
 
 	if sType.IsEnabled(OriginType) {
-		// If Origin.Mode is set to anything that isn't "posix" or "", assume we're running a plugin and
+		// If Origin.Mode is set to anything that isn't "posix", "hsm" or "", assume we're running a plugin and
 		// that the origin's issuer URL actually uses the same port as OriginUI instead of XRootD. This is
 		// because under that condition, keys are being served by the Pelican process instead of by XRootD
 		originMode := param.Origin_Mode.GetString()
-		if originMode == "" || originMode == "posix" {
+		if originMode == "" || originMode == "posix" || originMode == "hsm" {
 			// In this case, we use the default set up by config.go, which uses the xrootd port
 			issuerUrl, err := url.Parse(param.Origin_Url.GetString())
 			if err != nil {
@@ -549,11 +683,13 @@ func parseServerIssuerURL(sType ServerType) error {
 	}
 }
 
-// function to get/setup the transport (only once)
+// function to get/setup the transport (set up on first use if neither InitServer nor InitClient was called)
 func GetTransport() *http.Transport {
-	onceTransport.Do(func() {
-		setupTransport()
-	})
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	if transport == nil {
+		transport = newTransport()
+	}
 	return transport
 }
 
@@ -605,6 +741,9 @@ func InitConfig() {
 			cobra.CheckErr(err)
 		}
 	}
+	// 4) Merge the config fragments pelican.yaml includes, then those in its conf.d directory
+	cobra.CheckErr(mergeConfigIncludes())
+	cobra.CheckErr(validateLogFormat())
 	if param.Debug.GetBool() {
 		SetLogging(log.DebugLevel)
 	} else {
@@ -632,12 +771,95 @@ func InitConfig() {
 		log.SetOutput(f)
 	}
 
-	if oldNsUrl := viper.GetString("Federation.NamespaceUrl"); oldNsUrl != "" {
-		log.Warningln("Federation.NamespaceUrl is deprecated and will be removed in future release. Please migrate to use Federation.RegistryUrl instead")
-		viper.SetDefault("Federation.RegistryUrl", oldNsUrl)
+	handleDeprecatedConfig()
+}
+
+// Warn about each deprecated parameter that is set, and use its value as the default of
+// the parameter replacing it, if any (see the "deprecated" key in docs/parameters.yaml)
+func handleDeprecatedConfig() {
+	oldNames := make([]string, 0, len(param.DeprecatedParams))
+	for oldName := range param.DeprecatedParams {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+
+	for _, oldName := range oldNames {
+		value := viper.Get(oldName)
+		if value == nil || value == "" {
+			continue
+		}
+		newName := param.DeprecatedParams[oldName]
+		if newName == "" {
+			log.Warningf("%s is deprecated and will be removed in future release", oldName)
+			continue
+		}
+		log.Warningf("%s is deprecated and will be removed in future release. Please migrate to use %s instead", oldName, newName)
+		viper.SetDefault(newName, value)
 	}
 }
 
+// Merge the files listed in the Includes of the config file, in order, followed by the
+// *.yaml files of the conf.d directory next to the config file, in lexical order.  Later
+// files take precedence over earlier ones and over the config file itself; environment
+// variables still override them all.  Relative includes are relative to the config file,
+// and may be glob patterns.
+func mergeConfigIncludes() error {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		// The config file is optional
+		return nil
+	}
+	configDir := filepath.Dir(configFile)
+
+	fragments := []string{}
+	for _, include := range param.Includes.GetStringSlice() {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(configDir, include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return errors.Wrapf(err, "invalid pattern %s in Includes", include)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(include, "*?[") {
+			return errors.Errorf("included config file %s does not exist", include)
+		}
+		sort.Strings(matches)
+		fragments = append(fragments, matches...)
+	}
+	confD, err := filepath.Glob(filepath.Join(configDir, "conf.d", "*.yaml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(confD)
+	fragments = append(fragments, confD...)
+
+	includes := param.Includes.GetStringSlice()
+	merged := map[string]bool{configFile: true}
+	for _, fragment := range fragments {
+		if merged[fragment] {
+			continue
+		}
+		merged[fragment] = true
+		contents, err := os.ReadFile(fragment)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the included config file")
+		}
+		if err = viper.MergeConfig(bytes.NewReader(contents)); err != nil {
+			return errors.Wrapf(err, "failed to merge the included config file %s", fragment)
+		}
+		log.Debugln("Merged the config file", fragment)
+	}
+	// Only the main config file's includes are followed
+	if !slices.Equal(param.Includes.GetStringSlice(), includes) {
+		log.Warningln("Ignoring the Includes of included config files; only those of", configFile, "are merged")
+		viper.Set("Includes", includes)
+	}
+	return nil
+}
+
 func initConfigDir() error {
 	configDir := viper.GetString("ConfigDir")
 	if configDir == "" {
@@ -655,15 +877,26 @@ func initConfigDir() error {
 	return nil
 }
 
-// Initialize Pelican server instance. Pass a bit mask of `currentServers` if you want to enable multiple services.
-// Note not all configurations are supported: currently, if you enable both cache and origin then an error
-// is thrown
-func InitServer(ctx context.Context, currentServers ServerType) error {
+// Initialize the configuration of the servers in currentServers, a bit mask of the services
+// to enable, and set up the state they share: the TLS certificates, the issuer key, the
+// transport and the CSRF handler.  A cache and an origin can't be enabled together.
+//
+// It may be called repeatedly, e.g. by tests starting servers in turn; each call replaces the
+// process-wide state the previous one set up.  The returned ServerConfig is a snapshot of that
+// state, not a replacement for it: the servers themselves still read the globals (viper,
+// GetTransport, GetIssuerPrivateJWK, IsServerEnabled), so two sets of servers initialized by
+// separate calls can't run side by side in one process.
+func InitServerConfig(ctx context.Context, currentServers ServerType) (*ServerConfig, error) {
+	launchShutdownOnDone(ctx)
 	if err := initConfigDir(); err != nil {
-		return errors.Wrap(err, "Failed to initialize the server configuration")
+		return nil, errors.Wrap(err, "Failed to initialize the server configuration")
 	}
 	if currentServers.IsEnabled(OriginType) && currentServers.IsEnabled(CacheType) {
-		return errors.New("A cache and origin cannot both be enabled in the same instance")
+		return nil, errors.New("A cache and origin cannot both be enabled in the same instance")
+	}
+
+	if err := checkStrictConfig(currentServers); err != nil {
+		return nil, err
 	}
 
 	setEnabledServer(currentServers)
@@ -697,55 +930,72 @@ func InitServer(ctx context.Context, currentServers ServerType) error {
 		viper.SetDefault("Cache.DataLocation", "/run/pelican/xcache")
 		viper.SetDefault("Origin.Multiuser", true)
 		viper.SetDefault("Director.GeoIPLocation", "/var/cache/pelican/maxmind/GeoLite2-City.mmdb")
+		viper.SetDefault("Director.GeoIPOverridesFile", "/var/lib/pelican/geoip-overrides.yaml")
+		viper.SetDefault("Origin.PausedExportsFile", "/var/lib/pelican/paused-exports.yaml")
 		viper.SetDefault("Registry.DbLocation", "/var/lib/pelican/registry.sqlite")
 		viper.SetDefault("Monitoring.DataLocation", "/var/lib/pelican/monitoring/data")
+		viper.SetDefault("Monitoring.AccountingFile", "/var/lib/pelican/monitoring/accounting.json")
+		viper.SetDefault("Federation.DiscoveryCacheFile", "/var/lib/pelican/federation-discovery.json")
+		viper.SetDefault("Server.RegistrationStateFile", "/var/lib/pelican/registration.json")
 		viper.SetDefault("Shoveler.QueueDirectory", "/var/spool/pelican/shoveler/queue")
+		viper.SetDefault("Cache.WriteBackLocation", "/var/spool/pelican/writeback")
 		viper.SetDefault("Shoveler.AMQPTokenLocation", "/etc/pelican/shoveler-token")
 	} else {
 		viper.SetDefault("Director.GeoIPLocation", filepath.Join(configDir, "maxmind", "GeoLite2-City.mmdb"))
+		viper.SetDefault("Director.GeoIPOverridesFile", filepath.Join(configDir, "geoip-overrides.yaml"))
+		viper.SetDefault("Origin.PausedExportsFile", filepath.Join(configDir, "paused-exports.yaml"))
 		viper.SetDefault("Registry.DbLocation", filepath.Join(configDir, "ns-registry.sqlite"))
 		viper.SetDefault("Monitoring.DataLocation", filepath.Join(configDir, "monitoring/data"))
+		viper.SetDefault("Monitoring.AccountingFile", filepath.Join(configDir, "monitoring/accounting.json"))
+		viper.SetDefault("Federation.DiscoveryCacheFile", filepath.Join(configDir, "federation-discovery.json"))
+		viper.SetDefault("Server.RegistrationStateFile", filepath.Join(configDir, "registration.json"))
 		viper.SetDefault("Shoveler.QueueDirectory", filepath.Join(configDir, "shoveler/queue"))
+		viper.SetDefault("Cache.WriteBackLocation", filepath.Join(configDir, "writeback"))
 		viper.SetDefault("Shoveler.AMQPTokenLocation", filepath.Join(configDir, "shoveler-token"))
 
 		if userRuntimeDir := os.Getenv("XDG_RUNTIME_DIR"); userRuntimeDir != "" {
 			runtimeDir := filepath.Join(userRuntimeDir, "pelican", xrootdPrefix)
 			err := os.MkdirAll(runtimeDir, 0750)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			viper.SetDefault("Xrootd.RunLocation", runtimeDir)
 			viper.SetDefault("Cache.DataLocation", path.Join(runtimeDir, "xcache"))
 		} else {
 			dir, err := os.MkdirTemp("", "pelican-xrootd-*")
 			if err != nil {
-				return err
+				return nil, err
 			}
 			viper.SetDefault("Xrootd.RunLocation", filepath.Join(dir, xrootdPrefix))
 			viper.SetDefault("Cache.DataLocation", path.Join(dir, "xcache"))
-			cleanupDirOnShutdown(ctx, dir)
+			cleanupDirOnShutdown(dir)
 		}
 		viper.SetDefault("Origin.Multiuser", false)
 	}
+	// Secrets from Secrets.Provider replace the files of their parameters
+	if err := loadSecrets(ctx); err != nil {
+		return nil, err
+	}
+
 	// Any platform-specific paths should go here
 	err := InitServerOSDefaults()
 	if err != nil {
-		return errors.Wrapf(err, "Failure when setting up OS-specific configuration")
+		return nil, errors.Wrapf(err, "Failure when setting up OS-specific configuration")
 	}
 
 	err = os.MkdirAll(param.Monitoring_DataLocation.GetString(), 0750)
 	if err != nil {
-		return errors.Wrapf(err, "Failure when creating a directory for the monitoring data")
+		return nil, errors.Wrapf(err, "Failure when creating a directory for the monitoring data")
 	}
 
 	err = os.MkdirAll(param.Shoveler_QueueDirectory.GetString(), 0750)
 	if err != nil {
-		return errors.Wrapf(err, "Failure when creating a directory for the shoveler on-disk queue")
+		return nil, errors.Wrapf(err, "Failure when creating a directory for the shoveler on-disk queue")
 	}
 
 	hostname, err := os.Hostname()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	viper.SetDefault("Server.Hostname", hostname)
 	viper.SetDefault("Xrootd.Sitename", hostname)
@@ -767,7 +1017,10 @@ func InitServer(ctx context.Context, currentServers ServerType) error {
 	viper.SetDefault("Server.ExternalWebUrl", fmt.Sprint("https://", hostname, ":", webPort))
 	externalAddressStr := param.Server_ExternalWebUrl.GetString()
 	if _, err = url.Parse(externalAddressStr); err != nil {
-		return errors.Wrap(err, fmt.Sprint("Invalid Server.ExternalWebUrl: ", externalAddressStr))
+		return nil, errors.Wrap(err, fmt.Sprint("Invalid Server.ExternalWebUrl: ", externalAddressStr))
+	}
+	if err = checkListenAddresses(); err != nil {
+		return nil, err
 	}
 
 	if currentServers.IsEnabled(DirectorType) && param.Federation_DirectorUrl.GetString() == "" {
@@ -787,21 +1040,27 @@ func InitServer(ctx context.Context, currentServers ServerType) error {
 		minStatRes := param.Director_MinStatResponse.GetInt()
 		maxStatRes := param.Director_MaxStatResponse.GetInt()
 		if minStatRes <= 0 || maxStatRes <= 0 {
-			return errors.New("Invalid Director.MinStatResponse and Director.MaxStatResponse. MaxStatResponse and MinStatResponse must be positive integers")
+			return nil, errors.New("Invalid Director.MinStatResponse and Director.MaxStatResponse. MaxStatResponse and MinStatResponse must be positive integers")
 		}
 		if maxStatRes < minStatRes {
-			return errors.New("Invalid Director.MinStatResponse and Director.MaxStatResponse. MaxStatResponse is less than MinStatResponse")
+			return nil, errors.New("Invalid Director.MinStatResponse and Director.MaxStatResponse. MaxStatResponse is less than MinStatResponse")
 		}
 	}
 
 	// Unmarshal Viper config into a Go struct
 	unmarshalledConfig, err := param.UnmarshalConfig()
-	if err != nil || unmarshalledConfig == nil {
-		return err
+	if err != nil {
+		return nil, err
+	} else if unmarshalledConfig == nil {
+		return nil, errors.New("Failed to unmarshal the server configuration")
+	}
+	if err = unmarshalledConfig.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Reset issuerPrivateJWK to ensure test cases can use their own temp IssuerKey
 	issuerPrivateJWK.Store(nil)
+	resetNamespaceJWKs()
 
 	// As necessary, generate private keys, JWKS and corresponding certs
 
@@ -809,22 +1068,26 @@ func InitServer(ctx context.Context, currentServers ServerType) error {
 	// iff there isn't any valid private key present in that location
 	_, err = GetIssuerPublicJWKS()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err = launchIssuerKeyRotation(ctx); err != nil {
+		return nil, err
 	}
 
 	// Check if we have required files in place to set up TLS, or we will generate them
 	err = GenerateCert()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Generate the session secret and save it as the default value
 	if err := GenerateSessionSecret(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// After we know we have the certs we need, call setupTransport (which uses those certs for its TLSConfig)
-	setupTransport()
+	serverTransport := setupTransport()
+	launchCADirectoryWatcher(ctx)
 
 	// Setup CSRF middleware. To use it, you need to add this middleware to your chain
 	// of http handlers by calling config.GetCSRFHandler()
@@ -834,10 +1097,33 @@ func InitServer(ctx context.Context, currentServers ServerType) error {
 	// This populates Server.IssuerUrl, and can be safely fetched using server_utils.GetServerIssuerURL()
 	err = parseServerIssuerURL(currentServers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return DiscoverFederation()
+	if err = DiscoverFederation(); err != nil {
+		return nil, err
+	}
+	// A long-running server started from a saved discovery result picks up the
+	// federation's current services once its discovery endpoint is back
+	if stale := takeStaleDiscovery(); stale != nil {
+		go refreshFederationDiscovery(ctx, stale)
+	}
+
+	issuerKey, err := GetIssuerPrivateJWK()
+	if err != nil {
+		return nil, err
+	}
+	return &ServerConfig{
+		EnabledServers: currentServers,
+		Transport:      serverTransport,
+		IssuerKey:      issuerKey,
+	}, nil
+}
+
+// Initialize the configuration of the servers in currentServers.  See InitServerConfig.
+func InitServer(ctx context.Context, currentServers ServerType) error {
+	_, err := InitServerConfig(ctx, currentServers)
+	return err
 }
 
 func InitClient() error {
@@ -848,12 +1134,32 @@ func InitClient() error {
 
 	configDir := viper.GetString("ConfigDir")
 	viper.SetDefault("IssuerKey", filepath.Join(configDir, "issuer.jwk"))
+	viper.SetDefault("Federation.DiscoveryCacheFile", filepath.Join(configDir, "federation-discovery.json"))
+	viper.SetDefault("Client.PinnedKeysFile", filepath.Join(configDir, "pinned-federation-keys.json"))
 
 	upper_prefix := GetPreferredPrefix()
 
 	viper.SetDefault("Client.StoppedTransferTimeout", 100)
 	viper.SetDefault("Client.SlowTransferRampupTime", 100)
 	viper.SetDefault("Client.SlowTransferWindow", 30)
+	viper.SetDefault("Client.SlowTransferPolicy", "adaptive")
+	viper.SetDefault("Client.MaxRetryAfter", "1m")
+	viper.SetDefault("Client.CircuitBreakerThreshold", 5)
+	viper.SetDefault("Client.CircuitBreakerCooldown", "30s")
+	viper.SetDefault("Client.ResourceSignaturePolicy", "off")
+	viper.SetDefault("Client.WriteBackAck", "spooled")
+	viper.SetDefault("Client.SelfUpdateChannel", "stable")
+	viper.SetDefault("Client.LocalCacheSize", 10240)
+	viper.SetDefault("LocalCache.Size", 10240)
+	viper.SetDefault("LocalCache.SocketMode", "0666")
+	viper.SetDefault("LocalCache.NegativeCacheTTL", "30s")
+	if IsRootExecution() {
+		viper.SetDefault("LocalCache.Socket", "/run/pelican/localcache/localcache.sock")
+		viper.SetDefault("LocalCache.DataLocation", "/var/cache/pelican/localcache")
+	} else {
+		viper.SetDefault("LocalCache.Socket", filepath.Join(configDir, "localcache", "localcache.sock"))
+		viper.SetDefault("LocalCache.DataLocation", filepath.Join(configDir, "localcache", "data"))
+	}
 
 	if upper_prefix == "OSDF" || upper_prefix == "STASH" {
 		viper.SetDefault("Federation.TopologyNamespaceURL", "https://topology.opensciencegrid.org/osdf/namespaces")
@@ -950,21 +1256,68 @@ func InitClient() error {
 		viper.SetDefault("Client.DisableHttpProxy", param.DisableHttpProxy.GetBool())
 	}
 
+	if _, err := getProxyFunc(); err != nil {
+		return err
+	}
 	setupTransport()
 
+	if err := checkStrictConfig(NewServerType()); err != nil {
+		return err
+	}
+
 	// Unmarshal Viper config into a Go struct
 	unmarshalledConfig, err := param.UnmarshalConfig()
 	if err != nil || unmarshalledConfig == nil {
 		return err
 	}
+	if err = validateSlowTransferPolicy(); err != nil {
+		return err
+	}
+	if err = validateResourceSignaturePolicy(); err != nil {
+		return err
+	}
+	if err = validateWriteBackAck(); err != nil {
+		return err
+	}
 
-	return DiscoverFederation()
+	// A static federation has no director or federation metadata to discover
+	if param.Client_StaticFederationFile.GetString() != "" {
+		log.Debugln("Using the static federation in", param.Client_StaticFederationFile.GetString())
+		return nil
+	}
+
+	if err := DiscoverFederation(); err != nil {
+		return err
+	}
+
+	// The federation's client settings are a convenience; clients still work without them
+	if !param.Client_DisableFederationConfig.GetBool() && param.Federation_ClientConfigUrl.GetString() != "" {
+		if err := loadFederationClientConfig(); err != nil {
+			log.Warningln("Failed to load the federation's client configuration:", err)
+		} else if _, err := param.UnmarshalConfig(); err != nil {
+			return err
+		} else if err = validateSlowTransferPolicy(); err != nil {
+			return errors.Wrap(err, "The federation's client configuration is invalid")
+		}
+	}
+
+	return nil
+}
+
+// Check that Client.SlowTransferPolicy names one of the policies the client implements
+func validateSlowTransferPolicy() error {
+	policy := param.Client_SlowTransferPolicy.GetString()
+	if policy != "adaptive" && policy != "legacy" {
+		return errors.Errorf("Invalid Client.SlowTransferPolicy %q; accepted values are \"adaptive\" and \"legacy\"", policy)
+	}
+	return nil
 }
 
-func SetLogging(logLevel log.Level) {
-	textFormatter := log.TextFormatter{}
-	textFormatter.DisableLevelTruncation = true
-	textFormatter.FullTimestamp = true
-	log.SetFormatter(&textFormatter)
-	log.SetLevel(logLevel)
+// Check that Client.WriteBackAck is one of the acknowledgments caches offer for write-back
+func validateWriteBackAck() error {
+	ack := param.Client_WriteBackAck.GetString()
+	if ack != "spooled" && ack != "origin" {
+		return errors.Errorf("Invalid Client.WriteBackAck %q; accepted values are \"spooled\" and \"origin\"", ack)
+	}
+	return nil
 }
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -191,7 +192,7 @@ func TestDeprecateLogMessage(t *testing.T) {
 }
 
 func TestEnabledServers(t *testing.T) {
-	allServerTypes := []ServerType{OriginType, CacheType, DirectorType, RegistryType}
+	allServerTypes := []ServerType{OriginType, CacheType, DirectorType, RegistryType, LocalCacheType}
 	allServerStrs := make([]string, 0)
 	allServerStrsLower := make([]string, 0)
 	for _, st := range allServerTypes {
@@ -211,7 +212,6 @@ func TestEnabledServers(t *testing.T) {
 	t.Run("enable-one-server", func(t *testing.T) {
 		for _, server := range allServerTypes {
 			enabledServers = 0
-			// We didn't call setEnabledServer as it will only set once per process
 			enabledServers.SetList([]ServerType{server})
 			assert.True(t, IsServerEnabled(server))
 			assert.Equal(t, []string{server.String()}, GetEnabledServerString(false))
@@ -243,7 +243,7 @@ func TestEnabledServers(t *testing.T) {
 		assert.Equal(t, allServerStrsLower, GetEnabledServerString(true))
 	})
 
-	t.Run("setEnabledServer-only-set-once", func(t *testing.T) {
+	t.Run("setEnabledServer-replaces-servers", func(t *testing.T) {
 		enabledServers = 0
 		sType := OriginType
 		sType.Set(CacheType)
@@ -251,13 +251,94 @@ func TestEnabledServers(t *testing.T) {
 		assert.True(t, IsServerEnabled(OriginType))
 		assert.True(t, IsServerEnabled(CacheType))
 
+		// Re-initializing the servers, as a test starting another set of servers would
 		sType.Clear()
 		sType.Set(DirectorType)
 		sType.Set(RegistryType)
 		setEnabledServer(sType)
-		assert.True(t, IsServerEnabled(OriginType))
-		assert.True(t, IsServerEnabled(CacheType))
-		assert.False(t, IsServerEnabled(DirectorType))
-		assert.False(t, IsServerEnabled(RegistryType))
+		assert.False(t, IsServerEnabled(OriginType))
+		assert.False(t, IsServerEnabled(CacheType))
+		assert.True(t, IsServerEnabled(DirectorType))
+		assert.True(t, IsServerEnabled(RegistryType))
+	})
+}
+
+func TestResetConfig(t *testing.T) {
+	viper.Set("Federation.DirectorUrl", "https://director.example.com")
+	setEnabledServer(OriginType)
+	firstTransport := GetTransport()
+
+	ResetConfig()
+	t.Cleanup(viper.Reset)
+	assert.Empty(t, viper.GetString("Federation.DirectorUrl"))
+	assert.False(t, IsServerEnabled(OriginType))
+	assert.Empty(t, GetEnabledServerString(false))
+
+	// A fresh transport is set up for the next set of servers
+	secondTransport := GetTransport()
+	require.NotNil(t, secondTransport)
+	assert.NotSame(t, firstTransport, secondTransport)
+}
+
+func TestConfigIncludes(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	testingPreferredPrefix = ""
+
+	dir := t.TempDir()
+	writeFile := func(name string, contents string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0644))
+		return filename
+	}
+	mainFile := writeFile("pelican.yaml", `
+Includes:
+  - director.yaml
+  - sites/*.yaml
+Server:
+  WebHost: 1.1.1.1
+  WebPort: 1111
+Logging:
+  Level: Info
+`)
+	writeFile("director.yaml", `
+Server:
+  WebPort: 2222
+Director:
+  DefaultResponse: origin
+Includes:
+  - ignored.yaml
+`)
+	writeFile("sites/a.yaml", "Director:\n  FederationContact: a@example.com\n")
+	writeFile("sites/b.yaml", "Director:\n  FederationContact: b@example.com\n")
+	writeFile("conf.d/10-web.yaml", "Server:\n  WebPort: 3333\n")
+	writeFile("conf.d/README", "Not a config file")
+	writeFile("ignored.yaml", "Server:\n  WebHost: 9.9.9.9\n")
+
+	viper.Set("config", mainFile)
+	InitConfig()
+
+	assert.Equal(t, "1.1.1.1", param.Server_WebHost.GetString())
+	// conf.d comes after the includes
+	assert.Equal(t, 3333, param.Server_WebPort.GetInt())
+	assert.Equal(t, "origin", param.Director_DefaultResponse.GetString())
+	// Globs are merged in lexical order
+	assert.Equal(t, "b@example.com", param.Director_FederationContact.GetString())
+	assert.Equal(t, []string{"director.yaml", "sites/*.yaml"}, param.Includes.GetStringSlice())
+
+	t.Run("env-overrides-includes", func(t *testing.T) {
+		viper.Reset()
+		t.Setenv("PELICAN_SERVER_WEBPORT", "4444")
+		viper.Set("config", mainFile)
+		InitConfig()
+		assert.Equal(t, 4444, param.Server_WebPort.GetInt())
+	})
+
+	t.Run("missing-include", func(t *testing.T) {
+		viper.Reset()
+		viper.SetConfigFile(writeFile("missing.yaml", "Includes: [does-not-exist.yaml]\n"))
+		require.NoError(t, viper.MergeInConfig())
+		assert.Error(t, mergeConfigIncludes())
 	})
 }
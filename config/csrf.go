@@ -31,8 +31,8 @@ import (
 
 var (
 	// Global CSRF handler that shares the same auth key
-	csrfHanlder     gin.HandlerFunc
-	onceCSRFHanlder sync.Once
+	csrfHanlder      gin.HandlerFunc
+	csrfHanlderMutex sync.Mutex
 )
 
 func setupCSRFHandler() {
@@ -52,15 +52,23 @@ func setupCSRFHandler() {
 			}
 		})),
 	)
+	csrfHanlderMutex.Lock()
+	defer csrfHanlderMutex.Unlock()
 	csrfHanlder = adapter.Wrap(CSRF)
 }
 
 func GetCSRFHandler() (gin.HandlerFunc, error) {
-	onceCSRFHanlder.Do(func() {
+	csrfHanlderMutex.Lock()
+	handler := csrfHanlder
+	csrfHanlderMutex.Unlock()
+	if handler == nil {
 		setupCSRFHandler()
-	})
-	if csrfHanlder == nil {
+		csrfHanlderMutex.Lock()
+		handler = csrfHanlder
+		csrfHanlderMutex.Unlock()
+	}
+	if handler == nil {
 		return nil, errors.New("Error setting up the CSRF hanlder")
 	}
-	return csrfHanlder, nil
+	return handler, nil
 }
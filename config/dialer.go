@@ -0,0 +1,160 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// A dialer that races connection attempts to each of a hostname's addresses,
+	// staggered by attemptDelay, and returns the first connection to succeed.
+	// This follows the "happy eyeballs" algorithm of RFC 8305, but also fails
+	// over between addresses of the same family, e.g., for round-robin DNS.
+	multiEndpointDialer struct {
+		dialer       *net.Dialer
+		lookupIPAddr func(ctx context.Context, host string) ([]net.IPAddr, error)
+		attemptDelay time.Duration
+	}
+
+	dialResult struct {
+		conn net.Conn
+		addr string
+		err  error
+	}
+)
+
+// Interleave the addresses by family, starting with the family of the first
+// address, so that a broken IPv6 (or IPv4) network can't delay every attempt
+func interleaveAddrs(addrs []net.IPAddr) []net.IPAddr {
+	if len(addrs) == 0 {
+		return addrs
+	}
+	firstIsV4 := addrs[0].IP.To4() != nil
+	primary := make([]net.IPAddr, 0, len(addrs))
+	secondary := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == firstIsV4 {
+			primary = append(primary, addr)
+		} else {
+			secondary = append(secondary, addr)
+		}
+	}
+	result := make([]net.IPAddr, 0, len(addrs))
+	for idx := 0; idx < len(primary) || idx < len(secondary); idx++ {
+		if idx < len(primary) {
+			result = append(result, primary[idx])
+		}
+		if idx < len(secondary) {
+			result = append(result, secondary[idx])
+		}
+	}
+	return result
+}
+
+// Keep only the addresses that can be dialed on the network ("tcp", "tcp4", or "tcp6")
+func filterAddrs(network string, addrs []net.IPAddr) []net.IPAddr {
+	result := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		isV4 := addr.IP.To4() != nil
+		if (network == "tcp4" && !isV4) || (network == "tcp6" && isV4) {
+			continue
+		}
+		result = append(result, addr)
+	}
+	return result
+}
+
+func (d *multiEndpointDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil || (network != "tcp" && network != "tcp4" && network != "tcp6") {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	ipAddrs, err := d.lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	ipAddrs = interleaveAddrs(filterAddrs(network, ipAddrs))
+	if len(ipAddrs) <= 1 {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Buffered so attempts that finish after we return don't block
+	results := make(chan dialResult, len(ipAddrs))
+	next, inFlight := 0, 0
+	startAttempt := func() {
+		addr := net.JoinHostPort(ipAddrs[next].String(), port)
+		next++
+		inFlight++
+		go func() {
+			conn, err := d.dialer.DialContext(ctx, network, addr)
+			results <- dialResult{conn: conn, addr: addr, err: err}
+		}()
+	}
+
+	var firstErr error
+	startAttempt()
+	timer := time.NewTimer(d.attemptDelay)
+	defer timer.Stop()
+	for inFlight > 0 {
+		select {
+		case res := <-results:
+			inFlight--
+			if res.err == nil {
+				// Close the connections of any attempts that win after this one
+				cancel()
+				go func(remaining int) {
+					for ; remaining > 0; remaining-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(inFlight)
+				return res.conn, nil
+			}
+			log.Debugf("Failed to connect to %s (%s): %v", host, res.addr, res.err)
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			// Fail over to the next address right away rather than waiting for the delay
+			if next < len(ipAddrs) && ctx.Err() == nil {
+				startAttempt()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(d.attemptDelay)
+			}
+		case <-timer.C:
+			if next < len(ipAddrs) && ctx.Err() == nil {
+				startAttempt()
+				timer.Reset(d.attemptDelay)
+			}
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,100 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleaveAddrs(t *testing.T) {
+	v4a := net.IPAddr{IP: net.ParseIP("192.0.2.1")}
+	v4b := net.IPAddr{IP: net.ParseIP("192.0.2.2")}
+	v6a := net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+	v6b := net.IPAddr{IP: net.ParseIP("2001:db8::2")}
+
+	assert.Equal(t, []net.IPAddr{v6a, v4a, v6b, v4b}, interleaveAddrs([]net.IPAddr{v6a, v6b, v4a, v4b}))
+	assert.Equal(t, []net.IPAddr{v4a, v6a, v4b}, interleaveAddrs([]net.IPAddr{v4a, v4b, v6a}))
+	assert.Equal(t, []net.IPAddr{v4a, v4b}, filterAddrs("tcp4", []net.IPAddr{v6a, v4a, v4b}))
+	assert.Equal(t, []net.IPAddr{v6a}, filterAddrs("tcp6", []net.IPAddr{v6a, v4a}))
+}
+
+func TestMultiEndpointDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	newDialer := func(addrs ...string) *multiEndpointDialer {
+		return &multiEndpointDialer{
+			dialer: &net.Dialer{Timeout: 5 * time.Second},
+			lookupIPAddr: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+				ipAddrs := make([]net.IPAddr, 0, len(addrs))
+				for _, addr := range addrs {
+					ipAddrs = append(ipAddrs, net.IPAddr{IP: net.ParseIP(addr)})
+				}
+				return ipAddrs, nil
+			},
+			attemptDelay: 50 * time.Millisecond,
+		}
+	}
+
+	t.Run("fails-over-on-connect-error", func(t *testing.T) {
+		// Nothing listens on 127.0.0.2, so the first attempt is refused
+		dialer := newDialer("127.0.0.2", "127.0.0.1")
+		conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("cache.example.com", port))
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, listener.Addr().String(), conn.RemoteAddr().String())
+	})
+
+	t.Run("does-not-wait-for-unresponsive-address", func(t *testing.T) {
+		// 192.0.2.0/24 is reserved for documentation, so the attempt either hangs or fails
+		dialer := newDialer("192.0.2.1", "127.0.0.1")
+		start := time.Now()
+		conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("cache.example.com", port))
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, listener.Addr().String(), conn.RemoteAddr().String())
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("all-addresses-fail", func(t *testing.T) {
+		dialer := newDialer("127.0.0.2", "127.0.0.3")
+		_, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("cache.example.com", port))
+		require.Error(t, err)
+		var opErr *net.OpError
+		assert.ErrorAs(t, err, &opErr)
+	})
+}
@@ -0,0 +1,109 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// The optional features a federation can list in the supported_features field
+// of its discovery document
+const (
+	FeatureClientConfig    = "client_config"    // The director publishes default client settings
+	FeatureMinimumVersions = "minimum_versions" // The director enforces minimum server and client versions
+	FeatureBroker          = "broker"           // Servers behind firewalls are reachable through a connection broker
+	FeatureStaleCaches     = "stale_caches"     // Clients are sent to caches serving cached objects during origin outages
+)
+
+// The JSON names of the fields of the discovery document this version of Pelican knows about
+func knownDiscoveryFields() map[string]bool {
+	fields := map[string]bool{}
+	fdType := reflect.TypeOf(FederationDiscovery{})
+	for i := 0; i < fdType.NumField(); i++ {
+		name, _, _ := strings.Cut(fdType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Whether name is one of the fields of the discovery document this version of Pelican knows about
+func IsKnownDiscoveryField(name string) bool {
+	return knownDiscoveryFields()[name]
+}
+
+// Whether the federation lists feature in its supported features
+func (fd FederationDiscovery) HasFeature(feature string) bool {
+	for _, supported := range fd.SupportedFeatures {
+		if supported == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize the discovery document, including its extension fields.  Extensions
+// never replace a field this version of Pelican knows about.
+func (fd FederationDiscovery) MarshalJSON() ([]byte, error) {
+	type plainDiscovery FederationDiscovery
+	known, err := json.Marshal(plainDiscovery(fd))
+	if err != nil || len(fd.Extensions) == 0 {
+		return known, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(known, &fields); err != nil {
+		return nil, err
+	}
+	knownFields := knownDiscoveryFields()
+	for name, value := range fd.Extensions {
+		if !knownFields[name] {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Parse a discovery document.  Fields newer federations publish that this version of
+// Pelican doesn't know about are kept in Extensions rather than failing the parse.
+func (fd *FederationDiscovery) UnmarshalJSON(data []byte) error {
+	type plainDiscovery FederationDiscovery
+	parsed := plainDiscovery{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	knownFields := knownDiscoveryFields()
+	for name, value := range fields {
+		if knownFields[name] {
+			continue
+		}
+		if parsed.Extensions == nil {
+			parsed.Extensions = map[string]json.RawMessage{}
+		}
+		parsed.Extensions[name] = value
+	}
+	*fd = FederationDiscovery(parsed)
+	return nil
+}
@@ -0,0 +1,221 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// A federation's discovery document as last fetched from its discovery endpoint
+type cachedDiscovery struct {
+	Metadata FederationDiscovery `json:"metadata"`
+	Fetched  time.Time           `json:"fetched"`
+}
+
+// A discovery that fell back to a saved result, which servers keep refreshing
+// in the background until the federation's discovery endpoint is back
+type staleDiscovery struct {
+	discoveryUrl *url.URL
+	metadata     FederationDiscovery
+}
+
+var (
+	discoveryCacheMutex sync.Mutex
+
+	// Set when the last discovery used a saved result
+	lastStaleDiscovery      *staleDiscovery
+	lastStaleDiscoveryMutex sync.Mutex
+
+	// The delays between the attempts to fetch the discovery document; overridden by unit tests
+	discoveryRetryDelay      = time.Second
+	discoveryRefreshDelay    = 30 * time.Second
+	discoveryRefreshMaxDelay = 10 * time.Minute
+)
+
+// Read the saved discovery results, keyed by discovery URL
+func loadDiscoveryCache(filename string) (map[string]cachedDiscovery, error) {
+	contents, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]cachedDiscovery{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read the saved federation discovery results")
+	}
+	cache := map[string]cachedDiscovery{}
+	if err = json.Unmarshal(contents, &cache); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the saved federation discovery results in %s", filename)
+	}
+	return cache, nil
+}
+
+// Save the discovery document fetched from discoveryUrl to Federation.DiscoveryCacheFile,
+// if set, for when the federation's discovery endpoint is unreachable
+func saveDiscoveryCache(discoveryUrl string, metadata FederationDiscovery) error {
+	filename := param.Federation_DiscoveryCacheFile.GetString()
+	if filename == "" {
+		return nil
+	}
+	discoveryCacheMutex.Lock()
+	defer discoveryCacheMutex.Unlock()
+	cache, err := loadDiscoveryCache(filename)
+	if err != nil {
+		// Replace the unreadable file rather than never saving again
+		log.Debugln("Overwriting the saved federation discovery results:", err)
+		cache = map[string]cachedDiscovery{}
+	}
+	cache[discoveryUrl] = cachedDiscovery{Metadata: metadata, Fetched: time.Now()}
+	contents, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the federation discovery results")
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
+		return errors.Wrapf(err, "failed to create the directory for %s", filename)
+	}
+	tmpFile := filename + ".tmp"
+	if err = os.WriteFile(tmpFile, contents, 0640); err != nil {
+		return errors.Wrapf(err, "failed to write the federation discovery results to %s", tmpFile)
+	}
+	if err = os.Rename(tmpFile, filename); err != nil {
+		return errors.Wrapf(err, "failed to move the federation discovery results into %s", filename)
+	}
+	return nil
+}
+
+// The discovery document saved for discoveryUrl, if it was fetched within Federation.DiscoveryCacheTTL
+func getCachedDiscovery(discoveryUrl string) (cachedDiscovery, bool) {
+	filename := param.Federation_DiscoveryCacheFile.GetString()
+	if filename == "" {
+		return cachedDiscovery{}, false
+	}
+	discoveryCacheMutex.Lock()
+	defer discoveryCacheMutex.Unlock()
+	cache, err := loadDiscoveryCache(filename)
+	if err != nil {
+		log.Warningln(err)
+		return cachedDiscovery{}, false
+	}
+	entry, ok := cache[discoveryUrl]
+	if !ok {
+		return cachedDiscovery{}, false
+	}
+	if ttl := param.Federation_DiscoveryCacheTTL.GetDuration(); ttl > 0 && time.Since(entry.Fetched) > ttl {
+		log.Debugf("The saved discovery result for %s, from %s, is older than Federation.DiscoveryCacheTTL (%s)",
+			discoveryUrl, entry.Fetched.Format(time.RFC3339), ttl)
+		return cachedDiscovery{}, false
+	}
+	return entry, true
+}
+
+// Fetch the discovery document, retrying up to Federation.DiscoveryRetries times
+// with exponential backoff so a transient outage doesn't fail the discovery
+func fetchFederationMetadataWithRetry(discoveryUrl *url.URL) (FederationDiscovery, error) {
+	retries := param.Federation_DiscoveryRetries.GetInt()
+	delay := discoveryRetryDelay
+	for attempt := 0; ; attempt++ {
+		metadata, err := fetchFederationMetadata(discoveryUrl)
+		if err == nil || attempt >= retries {
+			return metadata, err
+		}
+		log.Debugf("Federation discovery failed (attempt %d of %d); retrying in %s: %v", attempt+1, retries+1, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Set the services found by discovery.  A service is only set if it's unset or
+// still has the value of previous, the services found by an earlier discovery;
+// services the configuration sets are never overwritten.
+func applyFederationMetadata(metadata FederationDiscovery, previous FederationDiscovery) {
+	if current := param.Federation_DirectorUrl.GetString(); current == "" || current == previous.DirectorEndpoint {
+		log.Debugln("Federation service discovery resulted in director URL", metadata.DirectorEndpoint)
+		viper.Set("Federation.DirectorUrl", metadata.DirectorEndpoint)
+	}
+	if current := param.Federation_RegistryUrl.GetString(); current == "" || current == previous.NamespaceRegistrationEndpoint {
+		log.Debugln("Federation service discovery resulted in registry URL",
+			metadata.NamespaceRegistrationEndpoint)
+		viper.Set("Federation.RegistryUrl", metadata.NamespaceRegistrationEndpoint)
+	}
+	if current := param.Federation_JwkUrl.GetString(); current == "" || current == previous.JwksUri {
+		log.Debugln("Federation service discovery resulted in JWKS URL",
+			metadata.JwksUri)
+		viper.Set("Federation.JwkUrl", metadata.JwksUri)
+	}
+	if current := param.Federation_ClientConfigUrl.GetString(); (current == "" || current == previous.ClientConfigUri) && metadata.ClientConfigUri != "" {
+		log.Debugln("Federation service discovery resulted in client configuration URL",
+			metadata.ClientConfigUri)
+		viper.Set("Federation.ClientConfigUrl", metadata.ClientConfigUri)
+	}
+	if current := param.Federation_BrokerUrl.GetString(); (current == "" || current == previous.BrokerEndpoint) && metadata.BrokerEndpoint != "" {
+		log.Debugln("Federation service discovery resulted in broker URL", metadata.BrokerEndpoint)
+		viper.Set("Federation.BrokerUrl", metadata.BrokerEndpoint)
+	}
+}
+
+// Record that the discovery used a saved result
+func setStaleDiscovery(stale *staleDiscovery) {
+	lastStaleDiscoveryMutex.Lock()
+	defer lastStaleDiscoveryMutex.Unlock()
+	lastStaleDiscovery = stale
+}
+
+// Return, and forget, the saved result the last discovery used, if any
+func takeStaleDiscovery() *staleDiscovery {
+	lastStaleDiscoveryMutex.Lock()
+	defer lastStaleDiscoveryMutex.Unlock()
+	stale := lastStaleDiscovery
+	lastStaleDiscovery = nil
+	return stale
+}
+
+// Keep fetching the discovery document in the background, with exponential backoff,
+// until the federation's discovery endpoint is reachable again; then replace the
+// services found in the saved result with the federation's current ones
+func refreshFederationDiscovery(ctx context.Context, stale *staleDiscovery) {
+	delay := discoveryRefreshDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		metadata, err := fetchFederationMetadata(stale.discoveryUrl)
+		if err != nil {
+			delay = min(delay*2, discoveryRefreshMaxDelay)
+			log.Debugf("Federation discovery endpoint is still unreachable; retrying in %s: %v", delay, err)
+			continue
+		}
+		log.Infof("Federation discovery endpoint %s is reachable again; updating the federation's services", stale.discoveryUrl)
+		if err = saveDiscoveryCache(stale.discoveryUrl.String(), metadata); err != nil {
+			log.Warningln("Failed to save the federation discovery result:", err)
+		}
+		applyFederationMetadata(metadata, stale.metadata)
+		return
+	}
+}
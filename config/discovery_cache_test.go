@@ -0,0 +1,158 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Serve a discovery document whose director is directorUrl; the first failures requests fail
+func newDiscoveryServer(t *testing.T, directorUrl *atomic.Value, failures int32) (*httptest.Server, *atomic.Int32) {
+	requests := &atomic.Int32{}
+	svr := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		director := directorUrl.Load().(string)
+		_ = json.NewEncoder(w).Encode(FederationDiscovery{
+			DirectorEndpoint:              director,
+			NamespaceRegistrationEndpoint: "https://registry.example.org",
+			JwksUri:                       director + "/.well-known/issuer.jwks",
+		})
+	}))
+	t.Cleanup(svr.Close)
+	return svr, requests
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	oldRetryDelay, oldRefreshDelay := discoveryRetryDelay, discoveryRefreshDelay
+	discoveryRetryDelay, discoveryRefreshDelay = time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() { discoveryRetryDelay, discoveryRefreshDelay = oldRetryDelay, oldRefreshDelay })
+
+	setup := func(t *testing.T, discoveryUrl string) string {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+		viper.Set("TLSSkipVerify", true)
+		setupTransport()
+		cacheFile := filepath.Join(t.TempDir(), "federation-discovery.json")
+		viper.Set("Federation.DiscoveryUrl", discoveryUrl)
+		viper.Set("Federation.DiscoveryCacheFile", cacheFile)
+		viper.Set("Federation.DiscoveryCacheTTL", "1h")
+		_ = takeStaleDiscovery()
+		return cacheFile
+	}
+
+	t.Run("retries", func(t *testing.T) {
+		director := &atomic.Value{}
+		director.Store("https://director.example.org")
+		svr, requests := newDiscoveryServer(t, director, 2)
+		setup(t, svr.URL)
+
+		viper.Set("Federation.DiscoveryRetries", 1)
+		assert.Error(t, DiscoverFederation())
+		assert.Equal(t, int32(2), requests.Load())
+
+		requests.Store(0)
+		viper.Set("Federation.DiscoveryRetries", 3)
+		require.NoError(t, DiscoverFederation())
+		assert.Equal(t, int32(3), requests.Load())
+		assert.Equal(t, "https://director.example.org", param.Federation_DirectorUrl.GetString())
+	})
+
+	t.Run("fallback-to-saved", func(t *testing.T) {
+		director := &atomic.Value{}
+		director.Store("https://director.example.org")
+		svr, _ := newDiscoveryServer(t, director, 0)
+		// The test server has an IP address, so the DNS fallback doesn't apply
+		cacheFile := setup(t, svr.URL)
+		require.NoError(t, DiscoverFederation())
+		assert.FileExists(t, cacheFile)
+		assert.Nil(t, takeStaleDiscovery())
+
+		// The federation's discovery endpoint goes down
+		svr.Close()
+		viper.Reset()
+		viper.Set("TLSSkipVerify", true)
+		viper.Set("Federation.DiscoveryUrl", svr.URL)
+		viper.Set("Federation.DiscoveryCacheFile", cacheFile)
+		viper.Set("Federation.DiscoveryCacheTTL", "1h")
+		require.NoError(t, DiscoverFederation())
+		assert.Equal(t, "https://director.example.org", param.Federation_DirectorUrl.GetString())
+		assert.Equal(t, "https://registry.example.org", param.Federation_RegistryUrl.GetString())
+		assert.NotNil(t, takeStaleDiscovery())
+
+		// A saved document older than the TTL isn't used
+		viper.Set("Federation.DirectorUrl", "")
+		viper.Set("Federation.RegistryUrl", "")
+		viper.Set("Federation.JwkUrl", "")
+		viper.Set("Federation.DiscoveryCacheTTL", "1ns")
+		assert.Error(t, DiscoverFederation())
+	})
+
+	t.Run("background-refresh", func(t *testing.T) {
+		director := &atomic.Value{}
+		director.Store("https://new-director.example.org")
+		svr, _ := newDiscoveryServer(t, director, 0)
+		cacheFile := setup(t, svr.URL)
+
+		stale := FederationDiscovery{
+			DirectorEndpoint:              "https://old-director.example.org",
+			NamespaceRegistrationEndpoint: "https://registry.example.org",
+			JwksUri:                       "https://old-director.example.org/.well-known/issuer.jwks",
+		}
+		applyFederationMetadata(stale, FederationDiscovery{})
+		// Services the configuration sets are never replaced
+		viper.Set("Federation.JwkUrl", "https://configured.example.org/jwks")
+
+		discoveryUrl, err := url.Parse(svr.URL + "/.well-known/pelican-configuration")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			refreshFederationDiscovery(ctx, &staleDiscovery{discoveryUrl: discoveryUrl, metadata: stale})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("The background refresh didn't finish")
+		}
+		assert.Equal(t, "https://new-director.example.org", param.Federation_DirectorUrl.GetString())
+		assert.Equal(t, "https://configured.example.org/jwks", param.Federation_JwkUrl.GetString())
+		cache, err := loadDiscoveryCache(cacheFile)
+		require.NoError(t, err)
+		assert.Contains(t, cache, discoveryUrl.String())
+	})
+}
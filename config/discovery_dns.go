@@ -0,0 +1,199 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The DNS names a federation can publish its services under when its
+// .well-known/pelican-configuration endpoint is unreachable.  For a
+// federation at example.org, the director is published as an SRV record at
+// _pelican-director._tcp.example.org and the full discovery document as
+// key=value TXT records at _pelican.example.org.
+const (
+	dnsDirectorService = "pelican-director"
+	dnsRegistryService = "pelican-registry"
+	dnsTXTPrefix       = "_pelican"
+)
+
+// The fields of the discovery document a federation can publish as TXT records
+var dnsDiscoveryFields = []string{
+	"director_endpoint",
+	"namespace_registration_endpoint",
+	"jwks_uri",
+	"client_config_uri",
+	"broker_endpoint",
+}
+
+// The resolver functions used for DNS discovery; overridden by unit tests
+var (
+	lookupSRV = net.DefaultResolver.LookupSRV
+	lookupTXT = net.DefaultResolver.LookupTXT
+)
+
+func discoveryFieldPointer(fd *FederationDiscovery, field string) *string {
+	switch field {
+	case "director_endpoint":
+		return &fd.DirectorEndpoint
+	case "namespace_registration_endpoint":
+		return &fd.NamespaceRegistrationEndpoint
+	case "jwks_uri":
+		return &fd.JwksUri
+	case "client_config_uri":
+		return &fd.ClientConfigUri
+	case "broker_endpoint":
+		return &fd.BrokerEndpoint
+	}
+	return nil
+}
+
+// Look up the SRV record for service under the federation's domain, returning
+// the https URL of the highest priority target
+func lookupServiceSRV(ctx context.Context, service, domain string) (string, error) {
+	_, records, err := lookupSRV(ctx, service, "tcp", domain)
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", errors.Errorf("no SRV records for %s under %s", service, domain)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Priority != records[j].Priority {
+			return records[i].Priority < records[j].Priority
+		}
+		return records[i].Weight > records[j].Weight
+	})
+	target := strings.TrimSuffix(records[0].Target, ".")
+	if target == "" {
+		return "", errors.Errorf("the SRV record for %s under %s has no target", service, domain)
+	}
+	serviceUrl := url.URL{Scheme: "https", Host: target}
+	if records[0].Port != 0 && records[0].Port != 443 {
+		serviceUrl.Host = net.JoinHostPort(target, strconv.Itoa(int(records[0].Port)))
+	}
+	return serviceUrl.String(), nil
+}
+
+// Build the federation's metadata from the SRV and TXT records published under
+// domain.  TXT records take precedence over SRV records, which only carry the
+// director and registry endpoints.
+func DiscoverFederationDNS(ctx context.Context, domain string) (FederationDiscovery, error) {
+	metadata := FederationDiscovery{}
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return metadata, errors.New("no federation domain to look up")
+	}
+
+	txtRecords, txtErr := lookupTXT(ctx, dnsTXTPrefix+"."+domain)
+	for _, record := range txtRecords {
+		key, value, found := strings.Cut(strings.TrimSpace(record), "=")
+		if !found {
+			continue
+		}
+		if field := discoveryFieldPointer(&metadata, key); field != nil && *field == "" {
+			*field = value
+		}
+	}
+
+	var srvErr error
+	if metadata.DirectorEndpoint == "" {
+		metadata.DirectorEndpoint, srvErr = lookupServiceSRV(ctx, dnsDirectorService, domain)
+	}
+	if metadata.NamespaceRegistrationEndpoint == "" {
+		if registryUrl, err := lookupServiceSRV(ctx, dnsRegistryService, domain); err == nil {
+			metadata.NamespaceRegistrationEndpoint = registryUrl
+		} else if srvErr == nil {
+			srvErr = err
+		}
+	}
+
+	if metadata.DirectorEndpoint == "" {
+		if txtErr != nil {
+			return metadata, errors.Wrapf(txtErr, "no director published in DNS under %s (SRV lookup: %v)", domain, srvErr)
+		}
+		return metadata, errors.Errorf("no director published in DNS under %s (SRV lookup: %v)", domain, srvErr)
+	}
+	if metadata.JwksUri == "" {
+		metadata.JwksUri = strings.TrimSuffix(metadata.DirectorEndpoint, "/") + "/.well-known/issuer.jwks"
+	}
+	return metadata, nil
+}
+
+// Generate the zone file records a federation at domain publishes so clients
+// and servers can discover it through DNS when the federation's
+// .well-known/pelican-configuration endpoint is unreachable
+func FederationDNSRecords(domain string, metadata FederationDiscovery, ttl time.Duration) ([]string, error) {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return nil, errors.New("the federation domain is empty")
+	}
+	if metadata.DirectorEndpoint == "" {
+		return nil, errors.New("the federation has no director endpoint")
+	}
+	ttlSeconds := int(ttl.Seconds())
+	records := []string{}
+
+	srvRecord := func(service, endpoint string) error {
+		if endpoint == "" {
+			return nil
+		}
+		endpointUrl, err := url.Parse(endpoint)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s endpoint %s", service, endpoint)
+		}
+		// SRV records can't carry a path; such endpoints are only published as TXT records
+		if endpointUrl.Scheme != "https" || strings.Trim(endpointUrl.Path, "/") != "" {
+			return nil
+		}
+		port := 443
+		if endpointUrl.Port() != "" {
+			if port, err = strconv.Atoi(endpointUrl.Port()); err != nil {
+				return errors.Wrapf(err, "invalid port in %s endpoint %s", service, endpoint)
+			}
+		}
+		records = append(records, fmt.Sprintf("_%s._tcp.%s. %d IN SRV 0 0 %d %s.",
+			service, domain, ttlSeconds, port, endpointUrl.Hostname()))
+		return nil
+	}
+	if err := srvRecord(dnsDirectorService, metadata.DirectorEndpoint); err != nil {
+		return nil, err
+	}
+	if err := srvRecord(dnsRegistryService, metadata.NamespaceRegistrationEndpoint); err != nil {
+		return nil, err
+	}
+
+	for _, field := range dnsDiscoveryFields {
+		value := *discoveryFieldPointer(&metadata, field)
+		if value == "" {
+			continue
+		}
+		records = append(records, fmt.Sprintf("%s.%s. %d IN TXT %q", dnsTXTPrefix, domain, ttlSeconds, field+"="+value))
+	}
+	return records, nil
+}
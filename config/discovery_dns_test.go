@@ -0,0 +1,146 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Replace the DNS resolver with one answering from the given zone file records
+func mockDNSRecords(t *testing.T, records []string) {
+	origSRV, origTXT := lookupSRV, lookupTXT
+	t.Cleanup(func() {
+		lookupSRV, lookupTXT = origSRV, origTXT
+	})
+	srvs := map[string][]*net.SRV{}
+	txts := map[string][]string{}
+	for _, record := range records {
+		name, rest, _ := strings.Cut(record, " ")
+		fields := strings.Fields(rest)
+		switch fields[2] {
+		case "SRV":
+			port, _ := strconv.Atoi(fields[5])
+			srvs[name] = append(srvs[name], &net.SRV{Target: fields[6], Port: uint16(port)})
+		case "TXT":
+			_, value, _ := strings.Cut(rest, "TXT ")
+			txts[name] = append(txts[name], strings.Trim(value, `"`))
+		}
+	}
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		fqdn := "_" + service + "._" + proto + "." + name + "."
+		if found, ok := srvs[fqdn]; ok {
+			return fqdn, found, nil
+		}
+		return "", nil, &net.DNSError{Err: "no such host", Name: fqdn, IsNotFound: true}
+	}
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if found, ok := txts[name+"."]; ok {
+			return found, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+}
+
+func TestFederationDNSRecords(t *testing.T) {
+	metadata := FederationDiscovery{
+		DirectorEndpoint:              "https://director.example.org:8444",
+		NamespaceRegistrationEndpoint: "https://registry.example.org",
+		JwksUri:                       "https://director.example.org:8444/.well-known/issuer.jwks",
+		BrokerEndpoint:                "https://broker.example.org/api",
+	}
+	records, err := FederationDNSRecords("example.org", metadata, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"_pelican-director._tcp.example.org. 3600 IN SRV 0 0 8444 director.example.org.",
+		"_pelican-registry._tcp.example.org. 3600 IN SRV 0 0 443 registry.example.org.",
+		`_pelican.example.org. 3600 IN TXT "director_endpoint=https://director.example.org:8444"`,
+		`_pelican.example.org. 3600 IN TXT "namespace_registration_endpoint=https://registry.example.org"`,
+		`_pelican.example.org. 3600 IN TXT "jwks_uri=https://director.example.org:8444/.well-known/issuer.jwks"`,
+		`_pelican.example.org. 3600 IN TXT "broker_endpoint=https://broker.example.org/api"`,
+	}, records)
+
+	_, err = FederationDNSRecords("example.org", FederationDiscovery{}, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestDiscoverFederationDNS(t *testing.T) {
+	t.Run("records-round-trip", func(t *testing.T) {
+		metadata := FederationDiscovery{
+			DirectorEndpoint:              "https://director.example.org:8444",
+			NamespaceRegistrationEndpoint: "https://registry.example.org",
+			JwksUri:                       "https://director.example.org:8444/.well-known/issuer.jwks",
+			BrokerEndpoint:                "https://broker.example.org/api",
+		}
+		records, err := FederationDNSRecords("example.org", metadata, time.Hour)
+		require.NoError(t, err)
+		mockDNSRecords(t, records)
+
+		discovered, err := DiscoverFederationDNS(context.Background(), "example.org")
+		require.NoError(t, err)
+		assert.Equal(t, metadata, discovered)
+	})
+
+	t.Run("srv-only", func(t *testing.T) {
+		mockDNSRecords(t, []string{
+			"_pelican-director._tcp.example.org. 3600 IN SRV 0 0 8444 director.example.org.",
+			"_pelican-registry._tcp.example.org. 3600 IN SRV 0 0 443 registry.example.org.",
+		})
+		discovered, err := DiscoverFederationDNS(context.Background(), "example.org")
+		require.NoError(t, err)
+		assert.Equal(t, "https://director.example.org:8444", discovered.DirectorEndpoint)
+		assert.Equal(t, "https://registry.example.org", discovered.NamespaceRegistrationEndpoint)
+		assert.Equal(t, "https://director.example.org:8444/.well-known/issuer.jwks", discovered.JwksUri)
+	})
+
+	t.Run("no-records", func(t *testing.T) {
+		mockDNSRecords(t, nil)
+		_, err := DiscoverFederationDNS(context.Background(), "example.org")
+		assert.Error(t, err)
+	})
+
+	t.Run("fallback-when-endpoint-is-down", func(t *testing.T) {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+		mockDNSRecords(t, []string{
+			"_pelican-director._tcp.localhost. 3600 IN SRV 0 0 8444 director.example.org.",
+		})
+
+		// Nothing listens on the port of a closed listener
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+
+		viper.Set("Federation.DiscoveryUrl", "https://localhost:"+strconv.Itoa(port))
+		require.NoError(t, DiscoverFederation())
+		assert.Equal(t, "https://director.example.org:8444", param.Federation_DirectorUrl.GetString())
+		assert.Equal(t, "https://director.example.org:8444/.well-known/issuer.jwks", param.Federation_JwkUrl.GetString())
+	})
+}
@@ -0,0 +1,85 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationDiscoveryJSON(t *testing.T) {
+	t.Run("unknown-fields-are-kept", func(t *testing.T) {
+		document := `{
+			"director_endpoint": "https://director.example.org",
+			"namespace_registration_endpoint": "https://registry.example.org",
+			"jwks_uri": "https://director.example.org/.well-known/issuer.jwks",
+			"broker_endpoint": "https://broker.example.org",
+			"display_name": "Example Federation",
+			"contact": "help@example.org",
+			"supported_features": ["broker", "some_future_feature"],
+			"status_page": "https://status.example.org",
+			"regions": {"us-east": ["cache1"]}
+		}`
+		metadata := FederationDiscovery{}
+		require.NoError(t, json.Unmarshal([]byte(document), &metadata))
+		assert.Equal(t, "https://director.example.org", metadata.DirectorEndpoint)
+		assert.Equal(t, "https://broker.example.org", metadata.BrokerEndpoint)
+		assert.Equal(t, "Example Federation", metadata.DisplayName)
+		assert.Equal(t, "help@example.org", metadata.Contact)
+		assert.True(t, metadata.HasFeature(FeatureBroker))
+		assert.False(t, metadata.HasFeature(FeatureClientConfig))
+		require.Len(t, metadata.Extensions, 2)
+		assert.JSONEq(t, `"https://status.example.org"`, string(metadata.Extensions["status_page"]))
+		assert.JSONEq(t, `{"us-east": ["cache1"]}`, string(metadata.Extensions["regions"]))
+
+		// Serializing the parsed document gives the original back
+		encoded, err := json.Marshal(metadata)
+		require.NoError(t, err)
+		assert.JSONEq(t, document, string(encoded))
+	})
+
+	t.Run("extensions-dont-replace-known-fields", func(t *testing.T) {
+		metadata := FederationDiscovery{
+			DirectorEndpoint: "https://director.example.org",
+			Extensions: map[string]json.RawMessage{
+				"director_endpoint": json.RawMessage(`"https://evil.example.org"`),
+				"status_page":       json.RawMessage(`"https://status.example.org"`),
+			},
+		}
+		encoded, err := json.Marshal(metadata)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"director_endpoint": "https://director.example.org",
+			"namespace_registration_endpoint": "",
+			"jwks_uri": "",
+			"status_page": "https://status.example.org"
+		}`, string(encoded))
+	})
+
+	t.Run("no-extensions", func(t *testing.T) {
+		metadata := FederationDiscovery{}
+		require.NoError(t, json.Unmarshal([]byte(`{"director_endpoint": "https://director.example.org"}`), &metadata))
+		assert.Nil(t, metadata.Extensions)
+		assert.True(t, IsKnownDiscoveryField("supported_features"))
+		assert.False(t, IsKnownDiscoveryField("status_page"))
+	})
+}
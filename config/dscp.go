@@ -0,0 +1,82 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The DSCP values of the per-hop behaviors that aren't class selectors (CSn)
+// or assured forwarding classes (AFxy)
+var dscpNames = map[string]int{
+	"EF": 46, // Expedited forwarding
+	"VA": 44, // Voice admit
+	"LE": 1,  // Lower effort
+}
+
+// Parse a DSCP value: a number from 0 to 63 or the name of a standard per-hop
+// behavior, such as CS1, AF41 or EF
+func ParseDSCP(value string) (int, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+	if dscp, ok := dscpNames[name]; ok {
+		return dscp, nil
+	}
+	if class, ok := strings.CutPrefix(name, "CS"); ok && len(class) == 1 && class[0] >= '0' && class[0] <= '7' {
+		return int(class[0]-'0') * 8, nil
+	}
+	if class, ok := strings.CutPrefix(name, "AF"); ok && len(class) == 2 &&
+		class[0] >= '1' && class[0] <= '4' && class[1] >= '1' && class[1] <= '3' {
+		return int(class[0]-'0')*8 + int(class[1]-'0')*2, nil
+	}
+	dscp, err := strconv.Atoi(name)
+	if err != nil || dscp < 0 || dscp > 63 {
+		return 0, errors.Errorf("invalid DSCP %q: must be a number from 0 to 63 or a per-hop behavior such as CS1, AF41 or EF", value)
+	}
+	return dscp, nil
+}
+
+// Get the DSCP values the traffic of the exports in Origin.Exports is marked with,
+// by federation prefix; exports without a DSCP are left out
+func GetExportDSCPs() (map[string]int, error) {
+	exports := []OriginExport{}
+	if err := param.Origin_Exports.Unmarshal(&exports); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse Origin.Exports")
+	}
+	dscps := make(map[string]int)
+	for idx, export := range exports {
+		if export.DSCP == "" {
+			continue
+		}
+		if export.FederationPrefix == "" {
+			return nil, errors.Errorf("Entry %d of Origin.Exports has no FederationPrefix", idx)
+		}
+		dscp, err := ParseDSCP(export.DSCP)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Origin.Exports entry for %s", export.FederationPrefix)
+		}
+		dscps[path.Clean(export.FederationPrefix)] = dscp
+	}
+	return dscps, nil
+}
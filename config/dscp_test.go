@@ -0,0 +1,74 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSCP(t *testing.T) {
+	for value, expected := range map[string]int{
+		"0":    0,
+		"46":   46,
+		"63":   63,
+		"cs0":  0,
+		"CS1":  8,
+		"CS7":  56,
+		"AF11": 10,
+		"af41": 34,
+		"AF43": 38,
+		"EF":   46,
+		"LE":   1,
+	} {
+		dscp, err := ParseDSCP(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, dscp, value)
+	}
+
+	for _, value := range []string{"", "64", "-1", "CS8", "AF14", "AF51", "gold"} {
+		_, err := ParseDSCP(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGetExportDSCPs(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Origin.Exports", []map[string]string{
+		{"FederationPrefix": "/dataset-a/", "DSCP": "AF41"},
+		{"FederationPrefix": "/dataset-b", "IssuerKey": "/etc/pelican/dataset-b.pem"},
+		{"FederationPrefix": "/dataset-c", "DSCP": "8"},
+	})
+	dscps, err := GetExportDSCPs()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/dataset-a": 34, "/dataset-c": 8}, dscps)
+
+	viper.Set("Origin.Exports", []map[string]string{{"FederationPrefix": "/dataset-a", "DSCP": "platinum"}})
+	_, err = GetExportDSCPs()
+	assert.ErrorContains(t, err, "/dataset-a")
+
+	viper.Set("Origin.Exports", []map[string]string{{"DSCP": "EF"}})
+	_, err = GetExportDSCPs()
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Where the effective value of a parameter came from
+const (
+	ConfigSourceDefault = "default" // Pelican's defaults, including values Pelican computes, such as those discovered from the federation
+	ConfigSourceEnv     = "env"     // An environment variable with the binary's preferred prefix, e.g. PELICAN_LOGGING_LEVEL
+	ConfigSourceYaml    = "yaml"    // The config file or the files it includes
+)
+
+// The environment variable holding the parameter for the binary's preferred prefix
+func getParamEnvName(prefix string, name string) string {
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// Load Pelican's built-in defaults, which viper merges as though they were config files
+func loadBuiltInDefaults() (*viper.Viper, error) {
+	defaults := viper.New()
+	defaults.SetConfigType("yaml")
+	if err := defaults.MergeConfig(strings.NewReader(defaultsYaml)); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the built-in defaults")
+	}
+	prefix := GetPreferredPrefix()
+	if prefix == "OSDF" || (prefix == "STASH" && os.Getenv("STASH_USE_TOPOLOGY") == "") {
+		if err := defaults.MergeConfig(strings.NewReader(osdfDefaultsYaml)); err != nil {
+			return nil, errors.Wrap(err, "failed to parse the built-in OSDF defaults")
+		}
+	}
+	return defaults, nil
+}
+
+// Get where the effective value of the parameter came from, following viper's precedence
+// of environment variables over config files over defaults; empty if the parameter is unset
+func getConfigSource(defaults *viper.Viper, name string) string {
+	if _, isSet := os.LookupEnv(getParamEnvName(GetPreferredPrefix(), name)); isSet {
+		return ConfigSourceEnv
+	}
+	// The built-in defaults are merged into viper's config, so a value in the config
+	// matching the built-in default is taken to be the default
+	if viper.InConfig(name) && (!defaults.InConfig(name) || !reflect.DeepEqual(viper.Get(name), defaults.Get(name))) {
+		return ConfigSourceYaml
+	}
+	if viper.IsSet(name) {
+		return ConfigSourceDefault
+	}
+	return ""
+}
+
+// Get the fully-merged configuration as the typed configuration struct, annotating each
+// parameter with the source of its value (see the ConfigSource* constants); the values of
+// secret parameters are redacted.  Meant for debugging which of the defaults, config files,
+// and environment variables set a parameter.
+func GetEffectiveConfig() (*param.ConfigWithType, error) {
+	defaults, err := loadBuiltInDefaults()
+	if err != nil {
+		return nil, err
+	}
+	rawConfig, err := param.UnmarshalConfigIn(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the configuration")
+	}
+	return param.ConvertToConfigWithSource(rawConfig, func(name string) string {
+		return getConfigSource(defaults, name)
+	}), nil
+}
+
+// Get the environment variables that name a parameter under one of Pelican's other
+// prefixes (PELICAN, OSDF, or STASH), which the binary ignores; for example, the pelican
+// binary only reads PELICAN_LOGGING_LEVEL, not OSDF_LOGGING_LEVEL.
+func GetIgnoredConfigEnv() []string {
+	names := param.GetParamNames()
+	preferred := GetPreferredPrefix()
+	ignored := []string{}
+	for _, prefix := range []string{"PELICAN", "OSDF", "STASH"} {
+		if prefix == preferred {
+			continue
+		}
+		for _, name := range names {
+			envName := getParamEnvName(prefix, name)
+			if _, isSet := os.LookupEnv(envName); isSet {
+				ignored = append(ignored, envName)
+			}
+		}
+	}
+	sort.Strings(ignored)
+	return ignored
+}
@@ -0,0 +1,76 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+func TestGetEffectiveConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	testingPreferredPrefix = "PELICAN"
+	t.Cleanup(func() { testingPreferredPrefix = "" })
+
+	configFile := filepath.Join(t.TempDir(), "pelican.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+Server:
+  WebHost: 1.1.1.1
+  WebPort: 8444
+Shoveler:
+  StompPassword: hunter2
+`), 0644))
+	t.Setenv("PELICAN_LOGGING_LEVEL", "debug")
+	t.Setenv("PELICAN_PLUGIN_TOKEN", "secret-token")
+	t.Setenv("PELICAN_SERVER_CLOCKSKEWTOLERANCE", "1m")
+	t.Setenv("OSDF_FEDERATION_DISCOVERYURL", "https://osg-htc.org")
+	viper.Set("config", configFile)
+	InitConfig()
+
+	effective, err := GetEffectiveConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", effective.Server.WebHost.Value)
+	assert.Equal(t, ConfigSourceYaml, effective.Server.WebHost.Source)
+	// Setting a parameter to its default in the config file keeps it a default
+	assert.Equal(t, 8444, effective.Server.WebPort.Value)
+	assert.Equal(t, ConfigSourceDefault, effective.Server.WebPort.Source)
+	assert.Equal(t, "debug", effective.Logging.Level.Value)
+	assert.Equal(t, ConfigSourceEnv, effective.Logging.Level.Source)
+	assert.Empty(t, effective.Federation.DiscoveryUrl.Source)
+	// Environment variables are decoded like config files
+	assert.Equal(t, time.Minute, effective.Server.ClockSkewTolerance.Value)
+
+	// Secrets are redacted, unless unset
+	assert.Equal(t, param.RedactedValue, effective.Shoveler.StompPassword.Value)
+	assert.Equal(t, ConfigSourceYaml, effective.Shoveler.StompPassword.Source)
+	assert.Equal(t, param.RedactedValue, effective.Plugin.Token.Value)
+	assert.Equal(t, ConfigSourceEnv, effective.Plugin.Token.Source)
+	assert.Equal(t, "", effective.Client.EncryptionKey.Value)
+
+	assert.Equal(t, []string{"OSDF_FEDERATION_DISCOVERYURL"}, GetIgnoredConfigEnv())
+}
@@ -19,9 +19,11 @@
 package config
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -30,12 +32,13 @@ import (
 	"math/big"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pkg/errors"
@@ -46,19 +49,48 @@ var (
 	// This is the private JWK for the server to sign tokens. This key remains
 	// the same if the IssuerKey is unchanged
 	issuerPrivateJWK atomic.Pointer[jwk.Key]
+
+	// The private JWKs of the namespaces with their own issuer keys, by key file
+	namespaceJWKs      = make(map[string]jwk.Key)
+	namespaceJWKsMutex sync.Mutex
 )
 
+// The settings of one of the origin's exports in Origin.Exports.  An export with an
+// IssuerKey signs its tokens and advertisements with that key instead of IssuerKey;
+// one with a DSCP has the traffic the origin's web engine serves for it marked with it.
+type OriginExport struct {
+	FederationPrefix string `mapstructure:"FederationPrefix"`
+	IssuerKey        string `mapstructure:"IssuerKey"`
+	DSCP             string `mapstructure:"DSCP"`
+}
+
 // Return a pointer to an ECDSA private key read from keyLocation.
 //
 // This can be used to load any ECDSA private key we generated for
 // various purposes including IssuerKey, TLSKey, and TLSCAKey
 func LoadPrivateKey(keyLocation string) (*ecdsa.PrivateKey, error) {
+	signer, err := LoadSigningKey(keyLocation)
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	privateKey, ok := signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Private key file, %v, contains a %T rather than an ECDSA private key", keyLocation, signer)
+	}
+	return privateKey, nil
+}
+
+// Return the ECDSA or RSA private key read from keyLocation.
+//
+// Unlike LoadPrivateKey, this accepts any kind of key Issuer.KeyAlgorithm
+// can generate, so it is the one to use for issuer keys
+func LoadSigningKey(keyLocation string) (crypto.Signer, error) {
 	rest, err := os.ReadFile(keyLocation)
 	if err != nil {
 		return nil, nil
 	}
 
-	var privateKey *ecdsa.PrivateKey
+	var privateKey crypto.Signer
 	var block *pem.Block
 	for {
 		block, rest = pem.Decode(rest)
@@ -72,6 +104,8 @@ func LoadPrivateKey(keyLocation string) (*ecdsa.PrivateKey, error) {
 			switch key := genericPrivateKey.(type) {
 			case *ecdsa.PrivateKey:
 				privateKey = key
+			case *rsa.PrivateKey:
+				privateKey = key
 			default:
 				return nil, fmt.Errorf("Unsupported private key type: %T", key)
 			}
@@ -88,6 +122,14 @@ func LoadPrivateKey(keyLocation string) (*ecdsa.PrivateKey, error) {
 // and writes a PEM-encoded ECDSA-encrypted private key with elliptic curve assigned
 // by curve
 func GeneratePrivateKey(keyLocation string, curve elliptic.Curve) error {
+	return generatePrivateKey(keyLocation, func() (crypto.Signer, error) {
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	})
+}
+
+// Check if a file exists at keyLocation, return the file if so; otherwise, generate
+// a private key with generate and write it PEM-encoded to keyLocation
+func generatePrivateKey(keyLocation string, generate func() (crypto.Signer, error)) error {
 	uid, err := GetDaemonUID()
 	if err != nil {
 		return err
@@ -109,7 +151,7 @@ func GeneratePrivateKey(keyLocation string, curve elliptic.Curve) error {
 	if file, err := os.Open(keyLocation); err == nil {
 		defer file.Close()
 		// Make sure key is valid if there is one
-		if _, err := LoadPrivateKey(keyLocation); err != nil {
+		if _, err := LoadSigningKey(keyLocation); err != nil {
 			return err
 		}
 		return nil
@@ -126,7 +168,7 @@ func GeneratePrivateKey(keyLocation string, curve elliptic.Curve) error {
 		return errors.Wrap(err, "Failed to create new private key file")
 	}
 	defer file.Close()
-	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	priv, err := generate()
 	if err != nil {
 		return err
 	}
@@ -432,24 +474,33 @@ func GenerateCert() error {
 	return nil
 }
 
-// Helper function to load the issuer/server's private key to sign tokens it issues.
-// Only intended to be called internally
-func loadIssuerPrivateJWK(issuerKeyFile string) (jwk.Key, error) {
-	// Check to see if we already had an IssuerKey or generate one
-	if err := GeneratePrivateKey(issuerKeyFile, elliptic.P256()); err != nil {
+// Load the private key at keyFile, generating one if there's none, as a JWK with
+// its algorithm and key ID set
+func loadPrivateJWK(keyFile string) (jwk.Key, error) {
+	// Check to see if we already had a key or generate one
+	if err := GenerateIssuerKey(keyFile); err != nil {
 		return nil, errors.Wrap(err, "Failed to generate new private key")
 	}
-	contents, err := os.ReadFile(issuerKeyFile)
+	return parsePrivateJWK(keyFile)
+}
+
+// Parse the private key at keyFile as a JWK with its algorithm and key ID set
+func parsePrivateJWK(keyFile string) (jwk.Key, error) {
+	contents, err := os.ReadFile(keyFile)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to read issuer key file")
 	}
 	key, err := jwk.ParseKey(contents, jwk.WithPEM(true))
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to parse issuer key file %v", issuerKeyFile)
+		return nil, errors.Wrapf(err, "Failed to parse issuer key file %v", keyFile)
 	}
 
 	// Add the algorithm to the key, needed for verifying tokens elsewhere
-	err = key.Set(jwk.AlgorithmKey, jwa.ES256)
+	alg, err := KeySignatureAlgorithm(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to determine the signing algorithm of issuer key file %v", keyFile)
+	}
+	err = key.Set(jwk.AlgorithmKey, alg)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to add alg specification to key header")
 	}
@@ -460,6 +511,16 @@ func loadIssuerPrivateJWK(issuerKeyFile string) (jwk.Key, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to assign key ID to private key")
 	}
+	return key, nil
+}
+
+// Helper function to load the issuer/server's private key to sign tokens it issues.
+// Only intended to be called internally
+func loadIssuerPrivateJWK(issuerKeyFile string) (jwk.Key, error) {
+	key, err := loadPrivateJWK(issuerKeyFile)
+	if err != nil {
+		return nil, err
+	}
 
 	// Store the key in the in-memory cache
 	issuerPrivateJWK.Store(&key)
@@ -467,6 +528,113 @@ func loadIssuerPrivateJWK(issuerKeyFile string) (jwk.Key, error) {
 	return key, nil
 }
 
+// Get the exports in Origin.Exports that have their own issuer key
+func getExportIssuerKeys() ([]OriginExport, error) {
+	exports := []OriginExport{}
+	if err := param.Origin_Exports.Unmarshal(&exports); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse Origin.Exports")
+	}
+	keys := []OriginExport{}
+	for idx, export := range exports {
+		if export.FederationPrefix == "" {
+			return nil, errors.Errorf("Entry %d of Origin.Exports has no FederationPrefix", idx)
+		}
+		if export.IssuerKey == "" {
+			continue
+		}
+		export.FederationPrefix = path.Clean(export.FederationPrefix)
+		keys = append(keys, export)
+	}
+	return keys, nil
+}
+
+// Check whether the namespace at prefix has its own issuer key in Origin.Exports
+func HasNamespaceIssuerKey(prefix string) (bool, error) {
+	keys, err := getExportIssuerKeys()
+	if err != nil {
+		return false, err
+	}
+	prefix = path.Clean(prefix)
+	for _, nsKey := range keys {
+		if nsKey.FederationPrefix == prefix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Get the prefixes of the namespaces with their own issuer keys in Origin.Exports
+func GetNamespacesWithIssuerKeys() ([]string, error) {
+	keys, err := getExportIssuerKeys()
+	if err != nil {
+		return nil, err
+	}
+	prefixes := make([]string, 0, len(keys))
+	for _, nsKey := range keys {
+		prefixes = append(prefixes, nsKey.FederationPrefix)
+	}
+	return prefixes, nil
+}
+
+// Return the private JWK for signing the tokens and advertisements of the namespace
+// at prefix: its IssuerKey in Origin.Exports, or the server's issuer key if the
+// namespace has none
+func GetNamespaceIssuerPrivateJWK(prefix string) (jwk.Key, error) {
+	keys, err := getExportIssuerKeys()
+	if err != nil {
+		return nil, err
+	}
+	prefix = path.Clean(prefix)
+	for _, nsKey := range keys {
+		if nsKey.FederationPrefix != prefix {
+			continue
+		}
+		namespaceJWKsMutex.Lock()
+		defer namespaceJWKsMutex.Unlock()
+		if key, ok := namespaceJWKs[nsKey.IssuerKey]; ok {
+			return key, nil
+		}
+		key, err := loadPrivateJWK(nsKey.IssuerKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load the issuer key of namespace %s", prefix)
+		}
+		namespaceJWKs[nsKey.IssuerKey] = key
+		return key, nil
+	}
+	return GetIssuerPrivateJWK()
+}
+
+// Return the public JWKS of the namespace at prefix, which holds only the public key
+// of its IssuerKey in Origin.Exports.  Each namespace with its own key is its own
+// issuer, so a token signed with one namespace's key isn't accepted for another.
+func GetNamespaceIssuerPublicJWKS(prefix string) (jwk.Set, error) {
+	if hasKey, err := HasNamespaceIssuerKey(prefix); err != nil {
+		return nil, err
+	} else if !hasKey {
+		return nil, errors.Errorf("The namespace %s has no issuer key of its own", prefix)
+	}
+	key, err := GetNamespaceIssuerPrivateJWK(prefix)
+	if err != nil {
+		return nil, err
+	}
+	pkey, err := jwk.PublicKeyOf(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to generate the public key of namespace %s", prefix)
+	}
+	jwks := jwk.NewSet()
+	if err = jwks.AddKey(pkey); err != nil {
+		return nil, errors.Wrap(err, "Failed to add public key to new JWKS")
+	}
+	return jwks, nil
+}
+
+// Forget the loaded namespace keys so they're reloaded from Origin.Exports
+func resetNamespaceJWKs() {
+	namespaceJWKsMutex.Lock()
+	defer namespaceJWKsMutex.Unlock()
+	namespaceJWKs = make(map[string]jwk.Key)
+}
+
 // Helper function to load the issuer/server's public key for other servers
 // to verify the token signed by this server. Only intended to be called internally
 func loadIssuerPublicJWKS(existingJWKS string, issuerKeyFile string) (jwk.Set, error) {
@@ -497,6 +665,17 @@ func loadIssuerPublicJWKS(existingJWKS string, issuerKeyFile string) (jwk.Set, e
 	if err = jwks.AddKey(pkey); err != nil {
 		return nil, errors.Wrap(err, "Failed to add public key to new JWKS")
 	}
+
+	// Tokens signed with the key retired by the last rotation are accepted until the overlap ends
+	if previousKey, _, ok := getPreviousIssuerPrivateJWK(issuerKeyFile); ok {
+		previousPkey, err := jwk.PublicKeyOf(previousKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to generate public key of the previous issuer key")
+		}
+		if err = jwks.AddKey(previousPkey); err != nil {
+			return nil, errors.Wrap(err, "Failed to add the previous public key to the JWKS")
+		}
+	}
 	return jwks, nil
 }
 
@@ -603,7 +782,7 @@ func GenerateSessionSecret() error {
 
 	// Use issuer private key as the source to generate the secret
 	issuerKeyFile := param.IssuerKey.GetString()
-	privateKey, err := LoadPrivateKey(issuerKeyFile)
+	privateKey, err := LoadSigningKey(issuerKeyFile)
 	if err != nil {
 		return err
 	}
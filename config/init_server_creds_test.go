@@ -0,0 +1,85 @@
+/***************************************************************
+ *
+ * Copyright (C) 2023, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceIssuerKeys(t *testing.T) {
+	viper.Reset()
+	issuerPrivateJWK.Store(nil)
+	resetNamespaceJWKs()
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerPrivateJWK.Store(nil)
+		resetNamespaceJWKs()
+	})
+
+	dir := t.TempDir()
+	viper.Set("IssuerKey", filepath.Join(dir, "issuer.pem"))
+	viper.Set("Origin.Exports", []map[string]string{
+		{"FederationPrefix": "/dataset-a/", "IssuerKey": filepath.Join(dir, "dataset-a.pem")},
+		{"FederationPrefix": "/dataset-b"},
+	})
+
+	issuerKey, err := GetIssuerPrivateJWK()
+	require.NoError(t, err)
+	nsKey, err := GetNamespaceIssuerPrivateJWK("/dataset-a")
+	require.NoError(t, err)
+	assert.NotEqual(t, issuerKey.KeyID(), nsKey.KeyID())
+
+	// The key is loaded once
+	again, err := GetNamespaceIssuerPrivateJWK("/dataset-a")
+	require.NoError(t, err)
+	assert.Equal(t, nsKey.KeyID(), again.KeyID())
+
+	// Namespaces without their own key use the issuer key
+	otherKey, err := GetNamespaceIssuerPrivateJWK("/dataset-b")
+	require.NoError(t, err)
+	assert.Equal(t, issuerKey.KeyID(), otherKey.KeyID())
+
+	prefixes, err := GetNamespacesWithIssuerKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dataset-a"}, prefixes)
+
+	// The namespace's keys are kept apart from the origin's
+	jwks, err := GetIssuerPublicJWKS()
+	require.NoError(t, err)
+	assert.Equal(t, 1, jwks.Len())
+	_, found := jwks.LookupKeyID(nsKey.KeyID())
+	assert.False(t, found)
+
+	nsJwks, err := GetNamespaceIssuerPublicJWKS("/dataset-a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, nsJwks.Len())
+	_, found = nsJwks.LookupKeyID(nsKey.KeyID())
+	assert.True(t, found)
+	_, err = GetNamespaceIssuerPublicJWKS("/dataset-b")
+	assert.Error(t, err)
+
+	viper.Set("Origin.Exports", []map[string]string{{"IssuerKey": filepath.Join(dir, "dataset-c.pem")}})
+	_, err = GetNamespaceIssuerPrivateJWK("/dataset-c")
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The generators of the kinds of issuer key Issuer.KeyAlgorithm can select
+var issuerKeyGenerators = map[string]func() (crypto.Signer, error){
+	"EC-P256":  func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+	"EC-P384":  func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+	"EC-P521":  func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P521(), rand.Reader) },
+	"RSA-2048": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+	"RSA-4096": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 4096) },
+}
+
+// Check if a file exists at keyLocation, return the file if so; otherwise, generate
+// and write a PEM-encoded private key of the kind set by Issuer.KeyAlgorithm
+func GenerateIssuerKey(keyLocation string) error {
+	algorithm := param.Issuer_KeyAlgorithm.GetString()
+	if algorithm == "" {
+		algorithm = "EC-P256"
+	}
+	generate, ok := issuerKeyGenerators[algorithm]
+	if !ok {
+		return errors.Errorf("Unsupported issuer key algorithm %q set by Issuer.KeyAlgorithm", algorithm)
+	}
+	return generatePrivateKey(keyLocation, generate)
+}
+
+// Get the JWS algorithm a raw ECDSA or RSA key, public or private, signs with:
+// ES256, ES384 or ES512 for the P-256, P-384 and P-521 curves, and RS256 for RSA
+func rawKeySignatureAlgorithm(rawKey interface{}) (jwa.SignatureAlgorithm, error) {
+	if signer, ok := rawKey.(crypto.Signer); ok {
+		rawKey = signer.Public()
+	}
+	switch key := rawKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwa.ES256, nil
+		case elliptic.P384():
+			return jwa.ES384, nil
+		case elliptic.P521():
+			return jwa.ES512, nil
+		}
+		return "", errors.Errorf("Unsupported elliptic curve %s", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return jwa.RS256, nil
+	}
+	return "", errors.Errorf("Unsupported key type %T", rawKey)
+}
+
+// Get the JWS algorithm key signs with: the one set on the key if there is one,
+// otherwise the one its type (and, for ECDSA, curve) calls for
+func KeySignatureAlgorithm(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	if alg, ok := key.Algorithm().(jwa.SignatureAlgorithm); ok && alg != "" {
+		return alg, nil
+	}
+	var rawKey interface{}
+	if err := key.Raw(&rawKey); err != nil {
+		return "", errors.Wrap(err, "Failed to get the raw key of the JWK")
+	}
+	return rawKeySignatureAlgorithm(rawKey)
+}
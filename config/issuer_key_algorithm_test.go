@@ -0,0 +1,121 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuerKeyAlgorithm(t *testing.T) {
+	reset := func() {
+		viper.Reset()
+		issuerPrivateJWK.Store(nil)
+		resetNamespaceJWKs()
+	}
+	reset()
+	t.Cleanup(reset)
+
+	for algorithm, expected := range map[string]jwa.SignatureAlgorithm{
+		"":         jwa.ES256,
+		"EC-P256":  jwa.ES256,
+		"EC-P384":  jwa.ES384,
+		"EC-P521":  jwa.ES512,
+		"RSA-2048": jwa.RS256,
+		"RSA-4096": jwa.RS256,
+	} {
+		t.Run(algorithm, func(t *testing.T) {
+			reset()
+			keyFile := filepath.Join(t.TempDir(), "issuer.pem")
+			viper.Set("IssuerKey", keyFile)
+			viper.Set("Issuer.KeyAlgorithm", algorithm)
+
+			key, err := GetIssuerPrivateJWK()
+			require.NoError(t, err)
+			assert.Equal(t, expected, key.Algorithm())
+
+			signer, err := LoadSigningKey(keyFile)
+			require.NoError(t, err)
+			alg, err := rawKeySignatureAlgorithm(signer)
+			require.NoError(t, err)
+			assert.Equal(t, expected, alg)
+
+			// Whatever is signed with the key verifies against the published JWKS
+			signed, err := jws.Sign([]byte("payload"), jws.WithKey(key.Algorithm(), key))
+			require.NoError(t, err)
+			jwks, err := GetIssuerPublicJWKS()
+			require.NoError(t, err)
+			_, err = jws.Verify(signed, jws.WithKeySet(jwks))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		reset()
+		viper.Set("IssuerKey", filepath.Join(t.TempDir(), "issuer.pem"))
+		viper.Set("Issuer.KeyAlgorithm", "DSA-1024")
+		_, err := GetIssuerPrivateJWK()
+		assert.ErrorContains(t, err, "Unsupported issuer key algorithm")
+	})
+
+	t.Run("existing-key-kept", func(t *testing.T) {
+		reset()
+		keyFile := filepath.Join(t.TempDir(), "issuer.pem")
+		require.NoError(t, GeneratePrivateKey(keyFile, elliptic.P256()))
+		viper.Set("IssuerKey", keyFile)
+		viper.Set("Issuer.KeyAlgorithm", "RSA-2048")
+
+		key, err := GetIssuerPrivateJWK()
+		require.NoError(t, err)
+		assert.Equal(t, jwa.ES256, key.Algorithm())
+	})
+}
+
+func TestKeySignatureAlgorithm(t *testing.T) {
+	raw, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(raw)
+	require.NoError(t, err)
+
+	// Without an alg, the algorithm comes from the curve
+	alg, err := KeySignatureAlgorithm(key)
+	require.NoError(t, err)
+	assert.Equal(t, jwa.ES384, alg)
+
+	// An alg set on the key takes precedence
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+	alg, err = KeySignatureAlgorithm(key)
+	require.NoError(t, err)
+	assert.Equal(t, jwa.ES256, alg)
+
+	rawP224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	_, err = rawKeySignatureAlgorithm(&rawP224.PublicKey)
+	assert.ErrorContains(t, err, "Unsupported elliptic curve")
+}
@@ -0,0 +1,175 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/events"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// How long to wait before retrying a failed rotation
+const issuerKeyRotationRetry = 5 * time.Minute
+
+// The file of the issuer key retired by the last rotation; its modification time is
+// when it was retired
+func previousIssuerKeyFile(issuerKeyFile string) string {
+	return issuerKeyFile + ".previous"
+}
+
+// Get the issuer key retired by the last rotation and when it stops being published,
+// if it's still within Server.IssuerKeyRotationOverlap
+func getPreviousIssuerPrivateJWK(issuerKeyFile string) (key jwk.Key, expires time.Time, ok bool) {
+	previousFile := previousIssuerKeyFile(issuerKeyFile)
+	info, err := os.Stat(previousFile)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	expires = info.ModTime().Add(param.Server_IssuerKeyRotationOverlap.GetDuration())
+	if !time.Now().Before(expires) {
+		return nil, time.Time{}, false
+	}
+	key, err = parsePrivateJWK(previousFile)
+	if err != nil {
+		log.Warningln("Failed to load the previous issuer key:", err)
+		return nil, time.Time{}, false
+	}
+	return key, expires, true
+}
+
+// Get the issuer key retired by the last rotation and when the overlap, during which it's
+// still published, ends; ok is false once the overlap is over
+func GetPreviousIssuerPrivateJWK() (key jwk.Key, expires time.Time, ok bool) {
+	return getPreviousIssuerPrivateJWK(param.IssuerKey.GetString())
+}
+
+// Replace the issuer key with a newly generated one, keeping the current key as the
+// previous key, which is published for Server.IssuerKeyRotationOverlap
+func RotateIssuerKey() error {
+	issuerKeyFile := param.IssuerKey.GetString()
+	previousKey, err := GetIssuerPrivateJWK()
+	if err != nil {
+		return err
+	}
+
+	// Generate the new key next to the current one, so it can be moved into place at once
+	newKeyFile := issuerKeyFile + ".new"
+	if err = os.Remove(newKeyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "Failed to remove the leftover new issuer key")
+	}
+	if err = GenerateIssuerKey(newKeyFile); err != nil {
+		return errors.Wrap(err, "Failed to generate the new issuer key")
+	}
+	previousFile := previousIssuerKeyFile(issuerKeyFile)
+	if err = os.Rename(issuerKeyFile, previousFile); err != nil {
+		return errors.Wrap(err, "Failed to retire the issuer key")
+	}
+	now := time.Now()
+	if err = os.Chtimes(previousFile, now, now); err != nil {
+		return errors.Wrap(err, "Failed to record when the issuer key was retired")
+	}
+	if err = os.Rename(newKeyFile, issuerKeyFile); err != nil {
+		return errors.Wrap(err, "Failed to move the new issuer key into place")
+	}
+
+	key, err := loadIssuerPrivateJWK(issuerKeyFile)
+	if err != nil {
+		return err
+	}
+	log.Infof("Rotated the issuer key; the new key ID is %s and the previous key %s is published until %s", key.KeyID(),
+		previousKey.KeyID(), now.Add(param.Server_IssuerKeyRotationOverlap.GetDuration()).Format(time.RFC3339))
+	events.Publish(events.IssuerKeyRotated{KeyID: key.KeyID(), PreviousKeyID: previousKey.KeyID()})
+	return nil
+}
+
+// Get when the issuer key is due for rotation, from the age of its file
+func nextIssuerKeyRotation(issuerKeyFile string, interval time.Duration) (time.Time, error) {
+	info, err := os.Stat(issuerKeyFile)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Failed to check the age of the issuer key")
+	}
+	return info.ModTime().Add(interval), nil
+}
+
+// Rotate the issuer key every Server.IssuerKeyRotationInterval, if set, and remove
+// the previous key once it's no longer published
+func launchIssuerKeyRotation(ctx context.Context) error {
+	interval := param.Server_IssuerKeyRotationInterval.GetDuration()
+	if interval <= 0 {
+		return nil
+	}
+	overlap := param.Server_IssuerKeyRotationOverlap.GetDuration()
+	if overlap <= 0 || overlap >= interval {
+		return errors.Errorf("Server.IssuerKeyRotationOverlap (%s) must be positive and shorter than Server.IssuerKeyRotationInterval (%s)",
+			overlap, interval)
+	}
+
+	issuerKeyFile := param.IssuerKey.GetString()
+	egrp, ok := ctx.Value(EgrpKey).(*errgroup.Group)
+	if !ok {
+		egrp = &errgroup.Group{}
+	}
+	egrp.Go(func() error {
+		failed := false
+		for {
+			wait := issuerKeyRotationRetry
+			if next, err := nextIssuerKeyRotation(issuerKeyFile, interval); err != nil {
+				log.Errorln(err)
+			} else if !failed {
+				wait = time.Until(next)
+			}
+			if _, expires, ok := getPreviousIssuerPrivateJWK(issuerKeyFile); ok && time.Until(expires) < wait {
+				wait = time.Until(expires)
+			}
+
+			timer := time.NewTimer(max(wait, 0))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+
+			if _, _, ok := getPreviousIssuerPrivateJWK(issuerKeyFile); !ok {
+				if err := os.Remove(previousIssuerKeyFile(issuerKeyFile)); err == nil {
+					log.Infoln("Removed the previous issuer key as its overlap with the current key is over")
+				} else if !errors.Is(err, os.ErrNotExist) {
+					log.Warningln("Failed to remove the previous issuer key:", err)
+				}
+			}
+			next, err := nextIssuerKeyRotation(issuerKeyFile, interval)
+			if err != nil || time.Now().Before(next) {
+				continue
+			}
+			if err = RotateIssuerKey(); err != nil {
+				log.Errorf("Failed to rotate the issuer key; retrying in %s: %v", issuerKeyRotationRetry, err)
+			}
+			failed = err != nil
+		}
+	})
+	return nil
+}
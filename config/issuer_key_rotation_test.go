@@ -0,0 +1,138 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/events"
+)
+
+func TestRotateIssuerKey(t *testing.T) {
+	viper.Reset()
+	issuerPrivateJWK.Store(nil)
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerPrivateJWK.Store(nil)
+	})
+
+	issuerKeyFile := filepath.Join(t.TempDir(), "issuer.pem")
+	viper.Set("IssuerKey", issuerKeyFile)
+	viper.Set("Server.IssuerKeyRotationOverlap", time.Hour)
+
+	oldKey, err := GetIssuerPrivateJWK()
+	require.NoError(t, err)
+	_, _, ok := GetPreviousIssuerPrivateJWK()
+	assert.False(t, ok)
+
+	rotations := events.Subscribe(events.IssuerKeyRotatedType)
+	defer rotations.Close()
+	require.NoError(t, RotateIssuerKey())
+
+	newKey, err := GetIssuerPrivateJWK()
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKey.KeyID(), newKey.KeyID())
+	select {
+	case envelope := <-rotations.Events():
+		assert.Equal(t, events.IssuerKeyRotated{KeyID: newKey.KeyID(), PreviousKeyID: oldKey.KeyID()}, envelope.Event)
+	case <-time.After(time.Second):
+		t.Fatal("No event was published for the rotation")
+	}
+
+	previous, expires, ok := GetPreviousIssuerPrivateJWK()
+	require.True(t, ok)
+	assert.Equal(t, oldKey.KeyID(), previous.KeyID())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Minute)
+
+	// Both keys are published during the overlap
+	jwks, err := GetIssuerPublicJWKS()
+	require.NoError(t, err)
+	assert.Equal(t, 2, jwks.Len())
+	_, found := jwks.LookupKeyID(oldKey.KeyID())
+	assert.True(t, found)
+	_, found = jwks.LookupKeyID(newKey.KeyID())
+	assert.True(t, found)
+
+	// And only the new one once it's over
+	past := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(previousIssuerKeyFile(issuerKeyFile), past, past))
+	_, _, ok = GetPreviousIssuerPrivateJWK()
+	assert.False(t, ok)
+	jwks, err = GetIssuerPublicJWKS()
+	require.NoError(t, err)
+	assert.Equal(t, 1, jwks.Len())
+	_, found = jwks.LookupKeyID(newKey.KeyID())
+	assert.True(t, found)
+}
+
+func TestLaunchIssuerKeyRotation(t *testing.T) {
+	viper.Reset()
+	issuerPrivateJWK.Store(nil)
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerPrivateJWK.Store(nil)
+	})
+
+	issuerKeyFile := filepath.Join(t.TempDir(), "issuer.pem")
+	viper.Set("IssuerKey", issuerKeyFile)
+
+	t.Run("overlap-too-long", func(t *testing.T) {
+		viper.Set("Server.IssuerKeyRotationInterval", time.Hour)
+		viper.Set("Server.IssuerKeyRotationOverlap", 2*time.Hour)
+		assert.Error(t, launchIssuerKeyRotation(context.Background()))
+	})
+
+	t.Run("rotates-due-key", func(t *testing.T) {
+		viper.Set("Server.IssuerKeyRotationInterval", time.Hour)
+		viper.Set("Server.IssuerKeyRotationOverlap", time.Minute)
+		oldKey, err := GetIssuerPrivateJWK()
+		require.NoError(t, err)
+		// The key is older than the rotation interval, so it's rotated right away
+		past := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(issuerKeyFile, past, past))
+
+		rotations := events.Subscribe(events.IssuerKeyRotatedType)
+		defer rotations.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		egrp := &errgroup.Group{}
+		ctx = context.WithValue(ctx, EgrpKey, egrp)
+		require.NoError(t, launchIssuerKeyRotation(ctx))
+		select {
+		case envelope := <-rotations.Events():
+			assert.Equal(t, oldKey.KeyID(), envelope.Event.(events.IssuerKeyRotated).PreviousKeyID)
+		case <-time.After(5 * time.Second):
+			t.Fatal("The due issuer key wasn't rotated")
+		}
+		cancel()
+		require.NoError(t, egrp.Wait())
+
+		newKey, err := GetIssuerPrivateJWK()
+		require.NoError(t, err)
+		assert.NotEqual(t, oldKey.KeyID(), newKey.KeyID())
+	})
+}
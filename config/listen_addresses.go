@@ -0,0 +1,121 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Get the IP addresses an entry of Server.ListenAddresses stands for: the entry
+// itself if it's an IP address, or the addresses of the network interface it names
+func resolveListenAddress(entry string) ([]net.IP, error) {
+	if ip := net.ParseIP(entry); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	iface, err := net.InterfaceByName(entry)
+	if err != nil {
+		return nil, errors.Errorf("%q in Server.ListenAddresses is neither an IP address nor a network interface of the host", entry)
+	}
+	ifaceAddrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the addresses of network interface %s", entry)
+	}
+	ips := make([]net.IP, 0, len(ifaceAddrs))
+	for _, ifaceAddr := range ifaceAddrs {
+		ipNet, ok := ifaceAddr.(*net.IPNet)
+		// Link-local IPv6 addresses can't be bound to without their zone
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	if len(ips) == 0 {
+		return nil, errors.Errorf("network interface %s in Server.ListenAddresses has no address to listen on", entry)
+	}
+	return ips, nil
+}
+
+// Get the IP addresses in Server.ListenAddresses, with the network interfaces
+// replaced by their addresses; nil if Server.ListenAddresses is empty
+func getListenIPs() ([]net.IP, error) {
+	var ips []net.IP
+	for _, entry := range param.Server_ListenAddresses.GetStringSlice() {
+		entryIPs, err := resolveListenAddress(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range entryIPs {
+			if !slices.ContainsFunc(ips, ip.Equal) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// Get the addresses, as host:port, the web engine listens on at the port: those
+// of Server.ListenAddresses or, if it's empty, Server.WebHost
+func GetListenAddresses(port int) ([]string, error) {
+	ips, err := getListenIPs()
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return []string{net.JoinHostPort(param.Server_WebHost.GetString(), strconv.Itoa(port))}, nil
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	}
+	return addrs, nil
+}
+
+// Check Server.ListenAddresses and warn if the host of Server.ExternalWebUrl, the URL
+// the web engine is advertised at, resolves to none of the addresses it listens on
+func checkListenAddresses() error {
+	ips, err := getListenIPs()
+	if err != nil || len(ips) == 0 {
+		return err
+	}
+	if slices.ContainsFunc(ips, net.IP.IsUnspecified) {
+		return nil
+	}
+	externalWebUrl, err := url.Parse(param.Server_ExternalWebUrl.GetString())
+	if err != nil || externalWebUrl.Hostname() == "" {
+		return nil
+	}
+	hostIPs, err := net.LookupIP(externalWebUrl.Hostname())
+	if err != nil {
+		log.Debugln("Failed to look up the host of Server.ExternalWebUrl to check it against Server.ListenAddresses:", err)
+		return nil
+	}
+	if !slices.ContainsFunc(hostIPs, func(hostIP net.IP) bool { return slices.ContainsFunc(ips, hostIP.Equal) }) {
+		log.Warningf("The host of Server.ExternalWebUrl (%s) resolves to none of the addresses in Server.ListenAddresses; "+
+			"clients following the advertised URL won't reach the web engine", externalWebUrl.String())
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Get the name of the loopback interface and its IPv4 address, skipping the test
+// if the host has none
+func getLoopbackInterface(t *testing.T) (string, net.IP) {
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		require.NoError(t, err)
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				return iface.Name, ipNet.IP
+			}
+		}
+	}
+	t.Skip("The host has no loopback interface with an IPv4 address")
+	return "", nil
+}
+
+func TestGetListenAddresses(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Run("default-to-web-host", func(t *testing.T) {
+		viper.Set("Server.WebHost", "0.0.0.0")
+		addrs, err := GetListenAddresses(8444)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0.0.0.0:8444"}, addrs)
+	})
+
+	t.Run("addresses-and-interfaces", func(t *testing.T) {
+		name, ip := getLoopbackInterface(t)
+		// The interface's address is listed once
+		viper.Set("Server.ListenAddresses", []string{"2001:db8::1", name, ip.String()})
+		addrs, err := GetListenAddresses(8444)
+		require.NoError(t, err)
+		assert.Equal(t, "[2001:db8::1]:8444", addrs[0])
+		count := 0
+		for _, addr := range addrs {
+			if addr == net.JoinHostPort(ip.String(), "8444") {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("unknown-entry", func(t *testing.T) {
+		viper.Set("Server.ListenAddresses", []string{"no-such-interface0"})
+		_, err := GetListenAddresses(8444)
+		assert.ErrorContains(t, err, "neither an IP address nor a network interface")
+	})
+}
+
+func TestCheckListenAddresses(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Server.ExternalWebUrl", "https://127.0.0.1:8444")
+	viper.Set("Server.ListenAddresses", []string{"127.0.0.1"})
+	assert.NoError(t, checkListenAddresses())
+
+	viper.Set("Server.ListenAddresses", []string{"not-an-address"})
+	assert.Error(t, checkListenAddresses())
+}
@@ -0,0 +1,98 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Adds the names of the enabled servers to every log entry as the "server" field so
+// structured logs from a process running several servers can be told apart
+type serverLabelHook struct {
+	enabled atomic.Bool
+}
+
+var (
+	serverLabels        = &serverLabelHook{}
+	installServerLabels sync.Once
+)
+
+func (hook *serverLabelHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook *serverLabelHook) Fire(entry *log.Entry) error {
+	if !hook.enabled.Load() {
+		return nil
+	}
+	if _, ok := entry.Data["server"]; ok {
+		return nil
+	}
+	if servers := GetEnabledServerString(true); len(servers) > 0 {
+		entry.Data["server"] = strings.Join(servers, ",")
+	}
+	return nil
+}
+
+// Return the log formatter for a Logging.Format value: "text" is the human-readable
+// default, while "json" and "logfmt" are for shipping logs to aggregators such as
+// ELK or Loki
+func newLogFormatter(format string) (log.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return &log.TextFormatter{DisableLevelTruncation: true, FullTimestamp: true}, nil
+	case "json":
+		return &log.JSONFormatter{}, nil
+	case "logfmt":
+		return &log.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	default:
+		return nil, errors.Errorf("Invalid Logging.Format %q; accepted values are \"text\", \"json\", and \"logfmt\"", format)
+	}
+}
+
+// Check that Logging.Format names one of the supported log formats
+func validateLogFormat() error {
+	_, err := newLogFormatter(param.Logging_Format.GetString())
+	return err
+}
+
+func SetLogging(logLevel log.Level) {
+	format := param.Logging_Format.GetString()
+	formatter, err := newLogFormatter(format)
+	if err != nil {
+		formatter, _ = newLogFormatter("text")
+	}
+	log.SetFormatter(formatter)
+	log.SetLevel(logLevel)
+
+	// The server labels would only clutter the text format, which is read by people
+	// looking at a single server
+	structured := err == nil && format != "" && !strings.EqualFold(format, "text")
+	serverLabels.enabled.Store(structured)
+	if structured {
+		installServerLabels.Do(func() { log.AddHook(serverLabels) })
+	}
+}
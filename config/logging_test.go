@@ -0,0 +1,90 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Log one entry with the given Logging.Format and return the output
+func logWithFormat(t *testing.T, format string) string {
+	viper.Set("Logging.Format", format)
+	SetLogging(log.InfoLevel)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.WithField("path", "/foo/bar").Info("Served object")
+	return buf.String()
+}
+
+func TestLoggingFormat(t *testing.T) {
+	viper.Reset()
+	oldServers := getEnabledServers()
+	oldLevel := log.GetLevel()
+	oldOut := log.StandardLogger().Out
+	t.Cleanup(func() {
+		viper.Reset()
+		setEnabledServer(oldServers)
+		SetLogging(oldLevel)
+		log.SetOutput(oldOut)
+	})
+	setEnabledServer(OriginType | DirectorType)
+
+	t.Run("json", func(t *testing.T) {
+		line := logWithFormat(t, "json")
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "Served object", entry["msg"])
+		assert.Equal(t, "/foo/bar", entry["path"])
+		assert.Equal(t, "director,origin", entry["server"])
+		assert.NotEmpty(t, entry["time"])
+	})
+
+	t.Run("logfmt", func(t *testing.T) {
+		line := logWithFormat(t, "logfmt")
+		assert.True(t, strings.HasPrefix(line, "time="), line)
+		assert.Contains(t, line, "level=info")
+		assert.Contains(t, line, `msg="Served object"`)
+		assert.Contains(t, line, "path=/foo/bar")
+		assert.Contains(t, line, "server=\"director,origin\"")
+		assert.NotContains(t, line, "\x1b[")
+	})
+
+	t.Run("text", func(t *testing.T) {
+		line := logWithFormat(t, "text")
+		assert.Contains(t, line, "Served object")
+		assert.NotContains(t, line, "server=")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		viper.Set("Logging.Format", "xml")
+		assert.Error(t, validateLogFormat())
+		line := logWithFormat(t, "xml")
+		assert.Contains(t, line, "Served object")
+		assert.NotContains(t, line, "server=")
+	})
+}
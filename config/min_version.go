@@ -0,0 +1,69 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+)
+
+// Check the running version against the minimum versions the federation published in its
+// discovery document. Returns the service ("origin", "cache" or "client") whose minimum the
+// running version is older than and that minimum, or empty strings if it's recent enough.
+func checkMinimumVersion(metadata FederationDiscovery, runningVersion string) (service string, minVersion string) {
+	curVer, err := version.NewVersion(runningVersion)
+	if err != nil {
+		// Development builds don't have a semantic version
+		return "", ""
+	}
+
+	enabledServers := getEnabledServers()
+	candidates := []struct {
+		service    string
+		minVersion string
+		enabled    bool
+	}{
+		{"origin", metadata.MinimumOriginVersion, enabledServers.IsEnabled(OriginType)},
+		{"cache", metadata.MinimumCacheVersion, enabledServers.IsEnabled(CacheType)},
+		{"client", metadata.MinimumClientVersion, enabledServers == 0},
+	}
+	for _, candidate := range candidates {
+		if !candidate.enabled || candidate.minVersion == "" {
+			continue
+		}
+		minVer, err := version.NewVersion(candidate.minVersion)
+		if err != nil {
+			log.Debugf("Ignoring the federation's invalid minimum %s version %q: %v", candidate.service, candidate.minVersion, err)
+			continue
+		}
+		if curVer.LessThan(minVer) {
+			return candidate.service, minVer.String()
+		}
+	}
+	return "", ""
+}
+
+func warnIfBelowMinimumVersion(metadata FederationDiscovery) {
+	if service, minVersion := checkMinimumVersion(metadata, PelicanVersion); service != "" {
+		log.Warningln("**********************************************************************")
+		log.Warningf("This Pelican %s (version %s) is older than the minimum version %s supported by the federation.", service, PelicanVersion, minVersion)
+		log.Warningln("Please upgrade; the federation may stop serving it without further notice.")
+		log.Warningln("**********************************************************************")
+	}
+}
@@ -0,0 +1,65 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMinimumVersion(t *testing.T) {
+	t.Cleanup(enabledServers.Clear)
+	metadata := FederationDiscovery{
+		MinimumOriginVersion: "7.5.0",
+		MinimumCacheVersion:  "7.6.0",
+		MinimumClientVersion: "7.4.0",
+	}
+
+	t.Run("client", func(t *testing.T) {
+		enabledServers.Clear()
+		service, minVersion := checkMinimumVersion(metadata, "7.3.2")
+		assert.Equal(t, "client", service)
+		assert.Equal(t, "7.4.0", minVersion)
+
+		service, _ = checkMinimumVersion(metadata, "7.4.0")
+		assert.Empty(t, service)
+	})
+
+	t.Run("servers", func(t *testing.T) {
+		enabledServers.Clear()
+		enabledServers.SetList([]ServerType{OriginType, CacheType})
+		service, minVersion := checkMinimumVersion(metadata, "7.5.1")
+		assert.Equal(t, "cache", service)
+		assert.Equal(t, "7.6.0", minVersion)
+
+		enabledServers.Clear()
+		enabledServers.SetList([]ServerType{OriginType})
+		service, _ = checkMinimumVersion(metadata, "7.5.1")
+		assert.Empty(t, service)
+	})
+
+	t.Run("unversioned", func(t *testing.T) {
+		enabledServers.Clear()
+		service, _ := checkMinimumVersion(metadata, "dev")
+		assert.Empty(t, service)
+		service, _ = checkMinimumVersion(FederationDiscovery{}, "7.0.0")
+		assert.Empty(t, service)
+	})
+}
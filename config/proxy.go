@@ -0,0 +1,106 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Parse the proxy URL of the parameter name, checking it has one of the schemes and
+// adding the password of Client.ProxyPasswordFile, if set, to its credentials
+func parseProxyUrl(name, proxyUrlStr string, schemes ...string) (*url.URL, error) {
+	proxyUrl, err := url.Parse(proxyUrlStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", name)
+	}
+	if !slices.Contains(schemes, proxyUrl.Scheme) {
+		return nil, errors.Errorf("invalid %s %q: the scheme must be one of %s", name, proxyUrl.Redacted(), strings.Join(schemes, ", "))
+	}
+	if proxyUrl.Host == "" {
+		return nil, errors.Errorf("invalid %s %q: the URL has no host", name, proxyUrl.Redacted())
+	}
+
+	if passwordFile := param.Client_ProxyPasswordFile.GetString(); passwordFile != "" {
+		if proxyUrl.User == nil || proxyUrl.User.Username() == "" {
+			return nil, errors.Errorf("Client.ProxyPasswordFile is set but %s has no username", name)
+		}
+		password, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read Client.ProxyPasswordFile")
+		}
+		proxyUrl.User = url.UserPassword(proxyUrl.User.Username(), strings.TrimSpace(string(password)))
+	}
+	return proxyUrl, nil
+}
+
+// Get the function choosing the proxy of the transport's requests: the proxy of
+// Client.HttpProxy or Client.SocksProxy, bypassed for the hosts of Client.NoProxy
+// (or the no_proxy environment variable), if either is set; else the proxy of the
+// http_proxy, https_proxy and no_proxy environment variables
+func getProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	httpProxy := param.Client_HttpProxy.GetString()
+	socksProxy := param.Client_SocksProxy.GetString()
+	if httpProxy == "" && socksProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	if httpProxy != "" && socksProxy != "" {
+		return nil, errors.New("Client.HttpProxy and Client.SocksProxy can't both be set")
+	}
+
+	var proxyUrl *url.URL
+	var err error
+	if httpProxy != "" {
+		proxyUrl, err = parseProxyUrl("Client.HttpProxy", httpProxy, "http", "https")
+	} else {
+		proxyUrl, err = parseProxyUrl("Client.SocksProxy", socksProxy, "socks5")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	proxyConfig := httpproxy.FromEnvironment()
+	proxyConfig.HTTPProxy = proxyUrl.String()
+	proxyConfig.HTTPSProxy = proxyUrl.String()
+	if noProxy := param.Client_NoProxy.GetStringSlice(); len(noProxy) > 0 {
+		proxyConfig.NoProxy = strings.Join(noProxy, ",")
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// Whether a proxy is configured, either with Client.HttpProxy or Client.SocksProxy
+// or with the http_proxy environment variable
+func IsProxyConfigured() bool {
+	if param.Client_HttpProxy.GetString() != "" || param.Client_SocksProxy.GetString() != "" {
+		return true
+	}
+	_, isSet := os.LookupEnv("http_proxy")
+	return isSet
+}
@@ -0,0 +1,229 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The header carrying the detached JWS (the compact serialization without its payload)
+// over the body of a document the federation publishes for clients, signed with one of
+// the federation's keys at Federation.JwkUrl
+const ResourceSignatureHeader = "X-Pelican-Signature"
+
+var pinnedKeysMutex sync.Mutex
+
+// Check that Client.ResourceSignaturePolicy names one of the supported policies
+func validateResourceSignaturePolicy() error {
+	switch param.Client_ResourceSignaturePolicy.GetString() {
+	case "", "off", "verify", "require":
+		return nil
+	default:
+		return errors.Errorf("Invalid Client.ResourceSignaturePolicy %q; accepted values are \"off\", \"verify\", and \"require\"",
+			param.Client_ResourceSignaturePolicy.GetString())
+	}
+}
+
+// The SHA-256 thumbprint (RFC 7638) identifying a key in the pinned keys file
+func pinnedKeyThumbprint(key jwk.Key) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// Read the pinned keys file, a map from each federation to the thumbprints of its
+// keys when they were first seen; a missing file has no pins
+func loadPinnedKeys(filename string) (map[string][]string, error) {
+	pins := map[string][]string{}
+	contents, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return pins, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the pinned federation keys in %s", filename)
+	}
+	if err = json.Unmarshal(contents, &pins); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the pinned federation keys in %s", filename)
+	}
+	return pins, nil
+}
+
+func savePinnedKeys(filename string, pins map[string][]string) error {
+	contents, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the pinned federation keys")
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
+		return errors.Wrapf(err, "failed to create the directory for %s", filename)
+	}
+	tmpFile := filename + ".tmp"
+	if err = os.WriteFile(tmpFile, contents, 0640); err != nil {
+		return errors.Wrapf(err, "failed to write the pinned federation keys to %s", tmpFile)
+	}
+	if err = os.Rename(tmpFile, filename); err != nil {
+		return errors.Wrapf(err, "failed to move the pinned federation keys into %s", filename)
+	}
+	return nil
+}
+
+// Restrict the federation's keys to the ones pinned the first time the client saw the
+// federation, pinning the current keys if there are none yet (trust on first use)
+func pinFederationKeys(federation string, keys jwk.Set) (jwk.Set, error) {
+	filename := param.Client_PinnedKeysFile.GetString()
+	if filename == "" {
+		return nil, errors.New("Client.PinFederationKeys is set but Client.PinnedKeysFile is not")
+	}
+	pinnedKeysMutex.Lock()
+	defer pinnedKeysMutex.Unlock()
+	pins, err := loadPinnedKeys(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbprints := make([]string, 0, keys.Len())
+	byThumbprint := make(map[string]jwk.Key, keys.Len())
+	for iter := keys.Keys(context.Background()); iter.Next(context.Background()); {
+		key := iter.Pair().Value.(jwk.Key)
+		thumbprint, err := pinnedKeyThumbprint(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compute the thumbprint of a federation key")
+		}
+		thumbprints = append(thumbprints, thumbprint)
+		byThumbprint[thumbprint] = key
+	}
+
+	pinned, ok := pins[federation]
+	if !ok {
+		log.Infof("Pinning the %d public keys of the federation at %s in %s", len(thumbprints), federation, filename)
+		pins[federation] = thumbprints
+		if err = savePinnedKeys(filename, pins); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+
+	trusted := jwk.NewSet()
+	for _, thumbprint := range pinned {
+		if key, ok := byThumbprint[thumbprint]; ok {
+			if err = trusted.AddKey(key); err != nil {
+				return nil, err
+			}
+			delete(byThumbprint, thumbprint)
+		}
+	}
+	if len(byThumbprint) > 0 {
+		log.Warningf("Ignoring %d public keys of the federation at %s that aren't pinned in %s", len(byThumbprint), federation, filename)
+	}
+	if trusted.Len() == 0 {
+		return nil, errors.Errorf("None of the public keys of the federation at %s match the keys pinned in %s; "+
+			"if the federation rotated its keys, remove its entry from the file to pin the new ones", federation, filename)
+	}
+	return trusted, nil
+}
+
+// Fetch the federation's public keys from Federation.JwkUrl, limited to the pinned keys
+// if Client.PinFederationKeys is set
+func getFederationSigningKeys(ctx context.Context) (jwk.Set, error) {
+	jwksUrl := param.Federation_JwkUrl.GetString()
+	if jwksUrl == "" {
+		return nil, errors.New("the federation doesn't publish its public keys; Federation.JwkUrl is not set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksUrl, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failure when creating the request for the federation's public keys at %s", jwksUrl)
+	}
+	req.Header.Set("User-Agent", "pelican/7")
+	httpClient := http.Client{
+		Transport: GetTransport(),
+		Timeout:   time.Second * 5,
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failure when fetching the federation's public keys from %s", jwksUrl)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failure when reading the federation's public keys from %s", jwksUrl)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Fetching the federation's public keys from %s failed with HTTP status %d", jwksUrl, resp.StatusCode)
+	}
+	keys, err := jwk.Parse(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the federation's public keys at %s", jwksUrl)
+	}
+	if keys.Len() == 0 {
+		return nil, errors.Errorf("the federation's public key set at %s is empty", jwksUrl)
+	}
+
+	if !param.Client_PinFederationKeys.GetBool() {
+		return keys, nil
+	}
+	federation := param.Federation_DiscoveryUrl.GetString()
+	if federation == "" {
+		federation = jwksUrl
+	}
+	return pinFederationKeys(strings.TrimSuffix(federation, "/"), keys)
+}
+
+// Verify the detached JWS signature over a document the client fetched from the
+// federation, such as the topology namespaces or the federation's client configuration,
+// according to Client.ResourceSignaturePolicy.  The resource names the document in errors.
+func VerifyFederationResource(ctx context.Context, resource string, body []byte, signature string) error {
+	policy := param.Client_ResourceSignaturePolicy.GetString()
+	if policy == "" || policy == "off" {
+		return nil
+	}
+	if signature == "" {
+		if policy == "require" {
+			return errors.Errorf("%s is not signed, and Client.ResourceSignaturePolicy requires a signature", resource)
+		}
+		log.Debugf("%s is not signed; accepting it without verification", resource)
+		return nil
+	}
+
+	keys, err := getFederationSigningKeys(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to verify the signature of %s", resource)
+	}
+	if _, err = jws.Verify([]byte(signature), jws.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)), jws.WithDetachedPayload(body)); err != nil {
+		return errors.Wrapf(err, "the signature of %s doesn't match any of the federation's keys", resource)
+	}
+	log.Debugf("Verified the signature of %s", resource)
+	return nil
+}
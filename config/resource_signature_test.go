@@ -0,0 +1,167 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Generate an ES256 signing key with a kid
+func newResourceSigningKey(t *testing.T) jwk.Key {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(ecKey)
+	require.NoError(t, err)
+	require.NoError(t, jwk.AssignKeyID(key))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+	return key
+}
+
+// Sign body with key, returning the detached JWS
+func signResource(t *testing.T, key jwk.Key, body []byte) string {
+	signed, err := jws.Sign(nil, jws.WithKey(jwa.ES256, key), jws.WithDetachedPayload(body))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+// Serve the public key of the current signing key as the federation's key set
+func newFederationKeyServer(t *testing.T, mutex *sync.Mutex, current *jwk.Key) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		public, err := (*current).PublicKey()
+		require.NoError(t, err)
+		keys := jwk.NewSet()
+		require.NoError(t, keys.AddKey(public))
+		require.NoError(t, json.NewEncoder(w).Encode(keys))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerifyFederationResource(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	var mutex sync.Mutex
+	key := newResourceSigningKey(t)
+	server := newFederationKeyServer(t, &mutex, &key)
+	viper.Set("Federation.JwkUrl", server.URL+"/.well-known/issuer.jwks")
+
+	body := []byte(`{"namespaces": []}`)
+	signature := signResource(t, key, body)
+	forged := signResource(t, newResourceSigningKey(t), body)
+	ctx := context.Background()
+
+	t.Run("off", func(t *testing.T) {
+		viper.Set("Client.ResourceSignaturePolicy", "off")
+		assert.NoError(t, VerifyFederationResource(ctx, "test", body, ""))
+		assert.NoError(t, VerifyFederationResource(ctx, "test", body, forged))
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		viper.Set("Client.ResourceSignaturePolicy", "verify")
+		assert.NoError(t, VerifyFederationResource(ctx, "test", body, ""))
+		assert.NoError(t, VerifyFederationResource(ctx, "test", body, signature))
+		assert.Error(t, VerifyFederationResource(ctx, "test", body, forged))
+		assert.Error(t, VerifyFederationResource(ctx, "test", []byte(`{"namespaces": [{}]}`), signature))
+	})
+
+	t.Run("require", func(t *testing.T) {
+		viper.Set("Client.ResourceSignaturePolicy", "require")
+		assert.Error(t, VerifyFederationResource(ctx, "test", body, ""))
+		assert.NoError(t, VerifyFederationResource(ctx, "test", body, signature))
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		viper.Set("Client.ResourceSignaturePolicy", "require")
+		viper.Set("Client.PinFederationKeys", true)
+		viper.Set("Client.PinnedKeysFile", filepath.Join(t.TempDir(), "pinned.json"))
+
+		// The first verification pins the federation's key
+		require.NoError(t, VerifyFederationResource(ctx, "test", body, signature))
+		pins, err := loadPinnedKeys(viper.GetString("Client.PinnedKeysFile"))
+		require.NoError(t, err)
+		assert.Len(t, pins[server.URL+"/.well-known/issuer.jwks"], 1)
+		require.NoError(t, VerifyFederationResource(ctx, "test", body, signature))
+
+		// Once the key served at the JWKS URL changes, documents signed with the new key are refused
+		newKey := newResourceSigningKey(t)
+		mutex.Lock()
+		key = newKey
+		mutex.Unlock()
+		err = VerifyFederationResource(ctx, "test", body, signResource(t, newKey, body))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pinned")
+	})
+}
+
+func TestValidateResourceSignaturePolicy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	for _, policy := range []string{"off", "verify", "require"} {
+		viper.Set("Client.ResourceSignaturePolicy", policy)
+		assert.NoError(t, validateResourceSignaturePolicy())
+	}
+	viper.Set("Client.ResourceSignaturePolicy", "always")
+	assert.Error(t, validateResourceSignaturePolicy())
+}
+
+func TestLoadFederationClientConfigSignature(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	var mutex sync.Mutex
+	key := newResourceSigningKey(t)
+	keyServer := newFederationKeyServer(t, &mutex, &key)
+	body := []byte("Client:\n  SlowTransferPolicy: legacy\n")
+	signature := signResource(t, newResourceSigningKey(t), body)
+	configServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ResourceSignatureHeader, signature)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(configServer.Close)
+
+	viper.Set("Federation.JwkUrl", keyServer.URL)
+	viper.Set("Federation.ClientConfigUrl", configServer.URL)
+	viper.Set("Client.ResourceSignaturePolicy", "verify")
+	assert.Error(t, loadFederationClientConfig())
+	assert.Empty(t, viper.GetString("Client.SlowTransferPolicy"))
+
+	signature = signResource(t, key, body)
+	require.NoError(t, loadFederationClientConfig())
+	assert.Equal(t, "legacy", viper.GetString("Client.SlowTransferPolicy"))
+}
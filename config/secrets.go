@@ -0,0 +1,448 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A store of the secrets a server would otherwise read from files, selected
+	// with Secrets.Provider
+	SecretProvider interface {
+		// Get the named secret, or ErrSecretNotFound if the store doesn't have it
+		GetSecret(ctx context.Context, name string) ([]byte, error)
+	}
+
+	envSecretProvider struct{}
+
+	vaultSecretProvider struct {
+		url    string
+		token  string
+		client *http.Client
+
+		once    sync.Once
+		secrets map[string]interface{}
+		err     error
+	}
+
+	awsSecretProvider struct {
+		endpoint     string
+		region       string
+		prefix       string
+		accessKey    string
+		secretKey    string
+		sessionToken string
+		client       *http.Client
+	}
+)
+
+// Returned by a SecretProvider that doesn't have the secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+var (
+	secretProvidersMutex sync.RWMutex
+	secretProviders      = map[string]func() (SecretProvider, error){
+		"env":   newEnvSecretProvider,
+		"vault": newVaultSecretProvider,
+		"aws":   newAWSSecretProvider,
+	}
+
+	// The secrets a provider can supply and the parameters of their files
+	secretFileParams = []struct {
+		name  string
+		param string
+	}{
+		{"issuer-key", "IssuerKey"},
+		{"session-secret", "Server.SessionSecretFile"},
+		{"macaroons-secret", "Xrootd.MacaroonsKeyFile"},
+		{"oidc-client-id", "OIDC.ClientIDFile"},
+		{"oidc-client-secret", "OIDC.ClientSecretFile"},
+	}
+)
+
+// Register a secret provider selectable with Secrets.Provider, replacing any
+// provider of the same name.  The factory is called when a server starts.
+func RegisterSecretProvider(name string, factory func() (SecretProvider, error)) {
+	secretProvidersMutex.Lock()
+	defer secretProvidersMutex.Unlock()
+	secretProviders[name] = factory
+}
+
+// Get the provider of Secrets.Provider, or nil if the secrets are read from their files
+func getSecretProvider() (SecretProvider, error) {
+	name := param.Secrets_Provider.GetString()
+	if name == "" || name == "file" {
+		return nil, nil
+	}
+	secretProvidersMutex.RLock()
+	factory, ok := secretProviders[name]
+	secretProvidersMutex.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("Unknown Secrets.Provider %q", name)
+	}
+	provider, err := factory()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to set up the %s secret provider", name)
+	}
+	return provider, nil
+}
+
+// Write the secrets Secrets.Provider has to a private temporary directory, removed on
+// shutdown, and point their parameters at them.  The secrets are kept in files rather
+// than in memory as XRootD reads its secrets from files.
+func loadSecrets(ctx context.Context) error {
+	provider, err := getSecretProvider()
+	if err != nil || provider == nil {
+		return err
+	}
+	gid, err := GetDaemonGID()
+	if err != nil {
+		return err
+	}
+
+	dir := ""
+	for _, secret := range secretFileParams {
+		contents, err := provider.GetSecret(ctx, secret.name)
+		if errors.Is(err, ErrSecretNotFound) {
+			log.Debugf("The secret provider has no %s; using %s", secret.name, secret.param)
+			continue
+		} else if err != nil {
+			return errors.Wrapf(err, "Failed to get the secret %s", secret.name)
+		}
+
+		if dir == "" {
+			if dir, err = os.MkdirTemp("", "pelican-secrets-*"); err != nil {
+				return errors.Wrap(err, "Failed to create the directory of the secrets")
+			}
+			cleanupDirOnShutdown(dir)
+			if err = chownSecret(dir, gid, 0750); err != nil {
+				return err
+			}
+		}
+		secretPath := filepath.Join(dir, secret.name)
+		if err = os.WriteFile(secretPath, contents, 0640); err != nil {
+			return errors.Wrapf(err, "Failed to write the secret %s", secret.name)
+		}
+		if err = chownSecret(secretPath, gid, 0640); err != nil {
+			return err
+		}
+		viper.Set(secret.param, secretPath)
+		log.Debugf("Loaded the secret %s from the secret provider", secret.name)
+	}
+	return nil
+}
+
+// Give the daemon group access to a secret, as XRootD reads some of them
+func chownSecret(path string, gid int, perm os.FileMode) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		return errors.Wrapf(err, "Failed to set the permissions of %s", path)
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return errors.Wrapf(err, "Failed to change the group of %s to the daemon group", path)
+	}
+	return nil
+}
+
+func newEnvSecretProvider() (SecretProvider, error) {
+	return envSecretProvider{}, nil
+}
+
+// Get the name of the environment variable of a secret, e.g. PELICAN_SECRET_ISSUER_KEY
+func envSecretName(name string) string {
+	return "PELICAN_SECRET_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func (envSecretProvider) GetSecret(_ context.Context, name string) ([]byte, error) {
+	value, ok := os.LookupEnv(envSecretName(name))
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return []byte(value), nil
+}
+
+func newVaultSecretProvider() (SecretProvider, error) {
+	address := param.Secrets_VaultAddress.GetString()
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, errors.New("Secrets.VaultAddress is not set")
+	}
+	token := ""
+	if tokenFile := param.Secrets_VaultTokenFile.GetString(); tokenFile != "" {
+		contents, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read Secrets.VaultTokenFile")
+		}
+		token = strings.TrimSpace(string(contents))
+	} else {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("No Vault token is available; set Secrets.VaultTokenFile or the environment variable VAULT_TOKEN")
+	}
+	secretPath := strings.Trim(param.Secrets_VaultPath.GetString(), "/")
+	if secretPath == "" {
+		return nil, errors.New("Secrets.VaultPath is not set")
+	}
+	return &vaultSecretProvider{
+		url:    strings.TrimSuffix(address, "/") + "/v1/" + secretPath,
+		token:  token,
+		client: &http.Client{Transport: GetTransport()},
+	}, nil
+}
+
+// Read the Vault secret once; its keys are Pelican's secrets
+func (provider *vaultSecretProvider) fetch(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.url, nil)
+	if err != nil {
+		provider.err = err
+		return
+	}
+	req.Header.Set("X-Vault-Token", provider.token)
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		provider.err = errors.Wrap(err, "Failed to read the secrets from Vault")
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		provider.err = errors.Wrap(err, "Failed to read the secrets from Vault")
+		return
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		provider.secrets = map[string]interface{}{}
+		return
+	} else if resp.StatusCode != http.StatusOK {
+		provider.err = errors.Errorf("Vault responded to the request for %s with status %d: %s", provider.url, resp.StatusCode, strings.TrimSpace(string(body)))
+		return
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.Unmarshal(body, &secret); err != nil {
+		provider.err = errors.Wrap(err, "Failed to parse the secrets from Vault")
+		return
+	}
+	provider.secrets = secret.Data
+	// Version 2 of the key/value engine nests the secret's keys along with its metadata
+	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			provider.secrets = data
+		}
+	}
+}
+
+func (provider *vaultSecretProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	provider.once.Do(func() { provider.fetch(ctx) })
+	if provider.err != nil {
+		return nil, provider.err
+	}
+	value, ok := provider.secrets[name]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	str, ok := value.(string)
+	if !ok {
+		return nil, errors.Errorf("the Vault secret's %s is not a string", name)
+	}
+	return []byte(str), nil
+}
+
+func newAWSSecretProvider() (SecretProvider, error) {
+	region := param.Secrets_AWSRegion.GetString()
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("Secrets.AWSRegion is not set")
+	}
+	provider := &awsSecretProvider{
+		endpoint:     param.Secrets_AWSEndpoint.GetString(),
+		region:       region,
+		prefix:       param.Secrets_AWSSecretPrefix.GetString(),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Transport: GetTransport()},
+	}
+	if provider.accessKey == "" || provider.secretKey == "" {
+		return nil, errors.New("No AWS credentials are available; set the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if provider.endpoint == "" {
+		provider.endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+	return provider, nil
+}
+
+func (provider *awsSecretProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": provider.prefix + name})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, provider.region, "secretsmanager", provider.accessKey, provider.secretKey, provider.sessionToken, time.Now())
+
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get the secret from AWS Secrets Manager")
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get the secret from AWS Secrets Manager")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &awsErr)
+		if strings.HasSuffix(awsErr.Type, "ResourceNotFoundException") {
+			return nil, ErrSecretNotFound
+		}
+		return nil, errors.Errorf("AWS Secrets Manager responded with status %d: %s %s", resp.StatusCode, awsErr.Type, awsErr.Message)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+	}
+	if err = json.Unmarshal(respBody, &secret); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the secret from AWS Secrets Manager")
+	}
+	if secret.SecretString != nil {
+		return []byte(*secret.SecretString), nil
+	}
+	return secret.SecretBinary, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Sign a request with AWS Signature Version 4, covering the host and every header
+// of the request
+func signAWSRequest(req *http.Request, body []byte, region, service, accessKey, secretKey, sessionToken string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalUri := req.URL.EscapedPath()
+	if canonicalUri == "" {
+		canonicalUri = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalUri,
+		canonicalAWSQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalAWSQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := []string{}
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Percent-encode everything but the unreserved characters, as AWS signatures require
+func awsEscape(str string) string {
+	return strings.ReplaceAll(url.QueryEscape(str), "+", "%20")
+}
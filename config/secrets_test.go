@@ -0,0 +1,246 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+func TestEnvSecretProvider(t *testing.T) {
+	assert.Equal(t, "PELICAN_SECRET_OIDC_CLIENT_SECRET", envSecretName("oidc-client-secret"))
+
+	t.Setenv("PELICAN_SECRET_SESSION_SECRET", "s3cret")
+	provider, err := newEnvSecretProvider()
+	require.NoError(t, err)
+	secret, err := provider.GetSecret(context.Background(), "session-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", string(secret))
+
+	_, err = provider.GetSecret(context.Background(), "issuer-key")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/pelican":
+			_, _ = w.Write([]byte(`{"data": {"data": {"session-secret": "from-kv2", "issuer-key": 42}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/pelican":
+			_, _ = w.Write([]byte(`{"data": {"session-secret": "from-kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("vault-token\n"), 0600))
+	viper.Set("Secrets.VaultAddress", server.URL)
+	viper.Set("Secrets.VaultTokenFile", tokenFile)
+	viper.Set("Secrets.VaultPath", "secret/data/pelican")
+
+	provider, err := newVaultSecretProvider()
+	require.NoError(t, err)
+	secret, err := provider.GetSecret(context.Background(), "session-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "from-kv2", string(secret))
+	_, err = provider.GetSecret(context.Background(), "oidc-client-id")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = provider.GetSecret(context.Background(), "issuer-key")
+	assert.ErrorContains(t, err, "not a string")
+	// The secret is read once
+	assert.Equal(t, 1, requests)
+
+	viper.Set("Secrets.VaultPath", "/kv/pelican/")
+	provider, err = newVaultSecretProvider()
+	require.NoError(t, err)
+	secret, err = provider.GetSecret(context.Background(), "session-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "from-kv1", string(secret))
+
+	viper.Set("Secrets.VaultPath", "kv/missing")
+	provider, err = newVaultSecretProvider()
+	require.NoError(t, err)
+	_, err = provider.GetSecret(context.Background(), "session-secret")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte("wrong-token"), 0600))
+	provider, err = newVaultSecretProvider()
+	require.NoError(t, err)
+	_, err = provider.GetSecret(context.Background(), "session-secret")
+	assert.ErrorContains(t, err, "status 403")
+
+	viper.Set("Secrets.VaultTokenFile", "")
+	t.Setenv("VAULT_TOKEN", "")
+	_, err = newVaultSecretProvider()
+	assert.Error(t, err)
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	signAWSRequest(req, nil, "us-east-1", "service", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestAWSSecretProvider(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") ||
+			r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body struct{ SecretId string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch body.SecretId {
+		case "prod/session-secret":
+			_, _ = w.Write([]byte(`{"Name": "prod/session-secret", "SecretString": "from-aws"}`))
+		case "prod/issuer-key":
+			_, _ = w.Write([]byte(`{"Name": "prod/issuer-key", "SecretBinary": "a2V5"}`))
+		case "prod/oidc-client-id":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type": "AccessDeniedException", "Message": "denied"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	viper.Set("Secrets.AWSRegion", "us-west-2")
+	viper.Set("Secrets.AWSSecretPrefix", "prod/")
+	viper.Set("Secrets.AWSEndpoint", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+
+	provider, err := newAWSSecretProvider()
+	require.NoError(t, err)
+	secret, err := provider.GetSecret(context.Background(), "session-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "from-aws", string(secret))
+	secret, err = provider.GetSecret(context.Background(), "issuer-key")
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(secret))
+	_, err = provider.GetSecret(context.Background(), "macaroons-secret")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = provider.GetSecret(context.Background(), "oidc-client-id")
+	assert.ErrorContains(t, err, "AccessDeniedException denied")
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = newAWSSecretProvider()
+	assert.Error(t, err)
+}
+
+func TestLoadSecrets(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { _ = CleanupTempResources() })
+
+	issuerKey := filepath.Join(t.TempDir(), "issuer.jwk")
+	sessionSecret := filepath.Join(t.TempDir(), "session-secret")
+	viper.Set("IssuerKey", issuerKey)
+	viper.Set("Server.SessionSecretFile", sessionSecret)
+
+	// Without a provider, the files are used
+	require.NoError(t, loadSecrets(context.Background()))
+	assert.Equal(t, issuerKey, param.IssuerKey.GetString())
+
+	viper.Set("Secrets.Provider", "unknown")
+	assert.ErrorContains(t, loadSecrets(context.Background()), "Unknown Secrets.Provider")
+
+	viper.Set("Secrets.Provider", "env")
+	t.Setenv("PELICAN_SECRET_ISSUER_KEY", "issuer key contents")
+	require.NoError(t, loadSecrets(context.Background()))
+	loadedKey := param.IssuerKey.GetString()
+	assert.NotEqual(t, issuerKey, loadedKey)
+	contents, err := os.ReadFile(loadedKey)
+	require.NoError(t, err)
+	assert.Equal(t, "issuer key contents", string(contents))
+	// Secrets the provider doesn't have keep their files
+	assert.Equal(t, sessionSecret, param.Server_SessionSecretFile.GetString())
+
+	require.NoError(t, CleanupTempResources())
+	_, err = os.Stat(loadedKey)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+type staticSecretProvider map[string]string
+
+func (provider staticSecretProvider) GetSecret(_ context.Context, name string) ([]byte, error) {
+	if secret, ok := provider[name]; ok {
+		return []byte(secret), nil
+	}
+	return nil, ErrSecretNotFound
+}
+
+func TestRegisterSecretProvider(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() {
+		_ = CleanupTempResources()
+		secretProvidersMutex.Lock()
+		delete(secretProviders, "static")
+		secretProvidersMutex.Unlock()
+	})
+
+	RegisterSecretProvider("static", func() (SecretProvider, error) {
+		return staticSecretProvider{"oidc-client-secret": "registered"}, nil
+	})
+	viper.Set("Secrets.Provider", "static")
+	require.NoError(t, loadSecrets(context.Background()))
+	contents, err := os.ReadFile(param.OIDC_ClientSecretFile.GetString())
+	require.NoError(t, err)
+	assert.Equal(t, "registered", string(contents))
+}
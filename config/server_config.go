@@ -0,0 +1,60 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+)
+
+// The state InitServerConfig sets up for the servers of a process.  It's a snapshot taken when
+// InitServerConfig returns; the package-level state it copies is what the servers actually use,
+// and a later InitServerConfig or ResetConfig doesn't update it.
+type ServerConfig struct {
+	// The servers the configuration was initialized for
+	EnabledServers ServerType
+	// The transport for the servers' outgoing requests; it trusts the federation's CA
+	Transport *http.Transport
+	// The private key the servers sign their tokens with
+	IssuerKey jwk.Key
+}
+
+// Check if testServer is one of the servers the configuration was initialized for
+func (cfg *ServerConfig) IsServerEnabled(testServer ServerType) bool {
+	return cfg.EnabledServers.IsEnabled(testServer)
+}
+
+// Reset the configuration along with the process-wide state InitServer and InitClient set up,
+// so a test can initialize a new set of servers from scratch
+func ResetConfig() {
+	viper.Reset()
+	setEnabledServer(ServerType(0))
+
+	transportMutex.Lock()
+	transport = nil
+	transportMutex.Unlock()
+
+	issuerPrivateJWK.Store(nil)
+
+	csrfHanlderMutex.Lock()
+	csrfHanlder = nil
+	csrfHanlderMutex.Unlock()
+}
@@ -0,0 +1,119 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	// The order a shutdown hook runs in; hooks of a lower order run first and hooks
+	// of the same order in the order they were registered
+	ShutdownOrder int
+
+	shutdownHook struct {
+		name    string
+		order   ShutdownOrder
+		timeout time.Duration
+		hook    func(ctx context.Context) error
+	}
+)
+
+const (
+	ShutdownOrderWebEngine ShutdownOrder = iota * 10 // Stop serving requests first
+	ShutdownOrderDaemons                             // Then stop the XRootD daemons using the run directories
+	ShutdownOrderCleanup                             // Then remove the temporary directories
+)
+
+var (
+	shutdownHooks      []shutdownHook
+	shutdownHooksMutex sync.Mutex
+)
+
+// Register a hook run by Shutdown, which waits for it at most timeout.  The hook is
+// given a context expiring after the timeout and should return by then.
+func RegisterShutdownHook(name string, order ShutdownOrder, timeout time.Duration, hook func(ctx context.Context) error) {
+	shutdownHooksMutex.Lock()
+	defer shutdownHooksMutex.Unlock()
+	shutdownHooks = append(shutdownHooks, shutdownHook{name: name, order: order, timeout: timeout, hook: hook})
+}
+
+// Run a shutdown hook, giving up on it after its timeout or once ctx is done
+func runShutdownHook(ctx context.Context, hook shutdownHook) error {
+	hookCtx, cancel := context.WithTimeout(ctx, hook.timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- hook.hook(hookCtx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-hookCtx.Done():
+		return errors.Wrapf(hookCtx.Err(), "gave up waiting for the shutdown hook %q", hook.name)
+	}
+}
+
+// Run the registered shutdown hooks in order, each bounded by its timeout, and
+// unregister them.  All the hooks run even if one fails; the first error is returned.
+func Shutdown(ctx context.Context) error {
+	shutdownHooksMutex.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownHooksMutex.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].order < hooks[j].order
+	})
+	var firstErr error
+	for _, hook := range hooks {
+		log.Debugln("Running the shutdown hook", hook.name)
+		if err := runShutdownHook(ctx, hook); err != nil {
+			log.Errorf("Shutdown hook %q failed: %v", hook.name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Run the shutdown hooks once ctx, the context of the server, is done.  The
+// errgroup of ctx waits for them, so the process exits once they are done.
+func launchShutdownOnDone(ctx context.Context) {
+	egrp, ok := ctx.Value(EgrpKey).(*errgroup.Group)
+	if !ok {
+		egrp = &errgroup.Group{}
+	}
+	egrp.Go(func() error {
+		<-ctx.Done()
+		if err := Shutdown(context.Background()); err != nil {
+			log.Warningln("The server did not shut down cleanly:", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,99 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Cleanup(func() { _ = Shutdown(context.Background()) })
+
+	t.Run("hooks-run-in-order", func(t *testing.T) {
+		var ran []string
+		record := func(name string) func(context.Context) error {
+			return func(context.Context) error {
+				ran = append(ran, name)
+				return nil
+			}
+		}
+		RegisterShutdownHook("cleanup", ShutdownOrderCleanup, time.Second, record("cleanup"))
+		RegisterShutdownHook("daemons", ShutdownOrderDaemons, time.Second, record("daemons"))
+		RegisterShutdownHook("web engine", ShutdownOrderWebEngine, time.Second, record("web engine"))
+		RegisterShutdownHook("more daemons", ShutdownOrderDaemons, time.Second, record("more daemons"))
+
+		require.NoError(t, Shutdown(context.Background()))
+		assert.Equal(t, []string{"web engine", "daemons", "more daemons", "cleanup"}, ran)
+
+		// The hooks only run once
+		ran = nil
+		require.NoError(t, Shutdown(context.Background()))
+		assert.Empty(t, ran)
+	})
+
+	t.Run("failing-and-hung-hooks", func(t *testing.T) {
+		ranLast := false
+		unblock := make(chan struct{})
+		t.Cleanup(func() { close(unblock) })
+		RegisterShutdownHook("failing", ShutdownOrderWebEngine, time.Second, func(context.Context) error {
+			return errors.New("failed")
+		})
+		RegisterShutdownHook("hung", ShutdownOrderDaemons, 50*time.Millisecond, func(context.Context) error {
+			<-unblock
+			return nil
+		})
+		RegisterShutdownHook("last", ShutdownOrderCleanup, time.Second, func(context.Context) error {
+			ranLast = true
+			return nil
+		})
+
+		start := time.Now()
+		err := Shutdown(context.Background())
+		assert.EqualError(t, err, "failed")
+		assert.True(t, ranLast)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestCleanupDirOnShutdown(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pelican-shutdown-test-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	egrp := &errgroup.Group{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), EgrpKey, egrp))
+	launchShutdownOnDone(ctx)
+	cleanupDirOnShutdown(dir)
+	_, err = os.Stat(dir)
+	require.NoError(t, err)
+
+	// The directory is removed by the time the errgroup is done
+	cancel()
+	require.NoError(t, egrp.Wait())
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
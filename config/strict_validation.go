@@ -0,0 +1,155 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Whether the key of the config files is a parameter or, for parameters holding
+// objects, part of one.  Keys of the built-in defaults are taken to be known.
+func isKnownConfigKey(key string, paramTypes map[string]reflect.Type, defaults *viper.Viper) bool {
+	if defaults.InConfig(key) {
+		return true
+	}
+	for name, paramType := range paramTypes {
+		name = strings.ToLower(name)
+		if key == name || (paramType.Kind() == reflect.Interface && strings.HasPrefix(key, name+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that the value can be converted to the parameter's type, as viper silently
+// uses the type's zero value when it can't
+func checkParamType(value interface{}, paramType reflect.Type) (typeName string, ok bool) {
+	var err error
+	switch {
+	case paramType == reflect.TypeOf(time.Duration(0)):
+		typeName = "duration"
+		_, err = cast.ToDurationE(value)
+	case paramType.Kind() == reflect.String:
+		typeName = "string"
+		_, err = cast.ToStringE(value)
+	case paramType.Kind() == reflect.Bool:
+		typeName = "boolean"
+		_, err = cast.ToBoolE(value)
+	case paramType.Kind() == reflect.Int:
+		typeName = "integer"
+		_, err = cast.ToIntE(value)
+	case paramType.Kind() == reflect.Float64:
+		typeName = "number"
+		_, err = cast.ToFloat64E(value)
+	case paramType.Kind() == reflect.Slice:
+		typeName = "list of strings"
+		_, err = cast.ToStringSliceE(value)
+	}
+	return typeName, err == nil
+}
+
+// Check the configuration strictly for the servers (none for the client): the keys of the
+// config files that aren't parameters, the values that don't match the type of their
+// parameter or violate its constraints in docs/parameters.yaml, and the settings that
+// can't work together.  Returns the problems found, sorted by parameter.
+func VerifyConfig(servers ServerType) ([]param.ValidationProblem, error) {
+	defaults, err := loadBuiltInDefaults()
+	if err != nil {
+		return nil, err
+	}
+	problems := []param.ValidationProblem{}
+	paramTypes := param.GetParamTypes()
+
+	for _, key := range viper.AllKeys() {
+		if viper.InConfig(key) && !isKnownConfigKey(key, paramTypes, defaults) {
+			problems = append(problems, param.ValidationProblem{Param: key, Message: "is not a Pelican parameter"})
+		}
+	}
+
+	// The constraints are checked on the values of the right type, as the others don't unmarshal
+	typed := viper.New()
+	for name, paramType := range paramTypes {
+		if !viper.IsSet(name) {
+			continue
+		}
+		value := viper.Get(name)
+		if typeName, ok := checkParamType(value, paramType); !ok {
+			problems = append(problems, param.ValidationProblem{Param: name, Message: fmt.Sprintf("has the value %v, which isn't a valid %s", value, typeName)})
+		} else {
+			typed.Set(name, value)
+		}
+	}
+	rawConfig, err := param.UnmarshalConfigIn(param.NewContextFromViper(typed))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the configuration")
+	}
+	constraintProblems, err := rawConfig.ValidationProblems(validate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to validate the configuration")
+	}
+	problems = append(problems, constraintProblems...)
+
+	for oldName, newName := range param.DeprecatedParams {
+		if newName != "" && viper.InConfig(oldName) && viper.InConfig(newName) {
+			problems = append(problems, param.ValidationProblem{Param: oldName, Message: "is deprecated and set along with " + newName + ", which replaces it"})
+		}
+	}
+	if servers.IsEnabled(OriginType) && servers.IsEnabled(CacheType) {
+		problems = append(problems, param.ValidationProblem{Message: "A cache and an origin can't both be enabled in the same instance"})
+	}
+	if servers != 0 {
+		refreshInterval := param.Monitoring_TokenRefreshInterval.GetDuration()
+		expiresIn := param.Monitoring_TokenExpiresIn.GetDuration()
+		if refreshInterval <= 0 || expiresIn <= 0 || refreshInterval > expiresIn {
+			problems = append(problems, param.ValidationProblem{Param: "Monitoring.TokenRefreshInterval",
+				Message: "must be positive and no longer than Monitoring.TokenExpiresIn"})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return strings.ToLower(problems[i].Param) < strings.ToLower(problems[j].Param)
+	})
+	return problems, nil
+}
+
+// With StrictConfigValidation, fail if VerifyConfig finds any problem with the configuration
+func checkStrictConfig(servers ServerType) error {
+	if !param.StrictConfigValidation.GetBool() {
+		return nil
+	}
+	problems, err := VerifyConfig(servers)
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	report := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		report = append(report, "  - "+problem.String())
+	}
+	return errors.Errorf("The configuration failed strict validation (StrictConfigValidation is set):\n%s", strings.Join(report, "\n"))
+}
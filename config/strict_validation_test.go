@@ -0,0 +1,111 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+func TestVerifyConfig(t *testing.T) {
+	testingPreferredPrefix = "PELICAN"
+	t.Cleanup(func() { testingPreferredPrefix = "" })
+	t.Cleanup(viper.Reset)
+
+	loadConfig := func(t *testing.T, contents string) {
+		viper.Reset()
+		configFile := filepath.Join(t.TempDir(), "pelican.yaml")
+		require.NoError(t, os.WriteFile(configFile, []byte(contents), 0644))
+		viper.Set("config", configFile)
+		InitConfig()
+	}
+	problemParams := func(problems []param.ValidationProblem) []string {
+		params := []string{}
+		for _, problem := range problems {
+			params = append(params, problem.Param)
+		}
+		return params
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		loadConfig(t, `
+Server:
+  WebPort: 8443
+Federation:
+  DirectorUrl: https://director.example.com
+GeoIPOverrides:
+  - IP: 10.0.0.0/24
+    Coordinate:
+      Lat: 43.07
+      Long: -89.38
+`)
+		problems, err := VerifyConfig(OriginType | DirectorType)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+		assert.NoError(t, checkStrictConfig(OriginType))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		loadConfig(t, `
+Server:
+  WebPrt: 8443
+  WebPort: not-a-port
+Federation:
+  DirectorUrl: "https://bad host/"
+  NamespaceUrl: https://registry.example.com
+  RegistryUrl: https://registry.example.com
+Director:
+  DefaultResponse: nowhere
+`)
+		problems, err := VerifyConfig(OriginType | CacheType)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "Director.DefaultResponse", "Federation.DirectorUrl", "Federation.NamespaceUrl", "Server.WebPort", "server.webprt"},
+			problemParams(problems))
+		assert.Contains(t, problems[0].Message, "cache and an origin")
+		assert.Contains(t, problems[4].Message, "isn't a valid integer")
+
+		// Without StrictConfigValidation, the problems are ignored
+		assert.NoError(t, checkStrictConfig(OriginType))
+		viper.Set("StrictConfigValidation", true)
+		err = checkStrictConfig(OriginType)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server.webprt is not a Pelican parameter")
+	})
+
+	t.Run("token-intervals", func(t *testing.T) {
+		loadConfig(t, `
+Monitoring:
+  TokenRefreshInterval: 2h
+  TokenExpiresIn: 1h
+`)
+		problems, err := VerifyConfig(NewServerType())
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+		problems, err = VerifyConfig(DirectorType)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Monitoring.TokenRefreshInterval"}, problemParams(problems))
+	})
+}
@@ -27,12 +27,14 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"os/signal"
 	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/events"
 	"github.com/pelicanplatform/pelican/metrics"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -41,13 +43,21 @@ import (
 
 type (
 	launchInfo struct {
-		ctx    context.Context
-		expiry time.Time
-		pid    int
-		name   string
+		ctx  context.Context
+		pid  int
+		name string
 	}
 )
 
+var (
+	// Requests to restart the daemons launched by LaunchDaemons, with the channel for the result
+	restartRequests   chan chan error
+	restartRequestsMu sync.Mutex
+)
+
+// How long a daemon has to exit after SIGTERM before it's killed
+const daemonStopTimeout = 10 * time.Second
+
 func ForwardCommandToLogger(ctx context.Context, daemonName string, cmdStdout io.ReadCloser, cmdStderr io.ReadCloser) {
 	cmd_logger := log.WithFields(log.Fields{"daemon": daemonName})
 	stdout_scanner := bufio.NewScanner(cmdStdout)
@@ -139,9 +149,8 @@ func (launcher DaemonLauncher) Launch(ctx context.Context) (context.Context, int
 	if err := cmd.Start(); err != nil {
 		return ctx, -1, err
 	}
-	go ForwardCommandToLogger(ctx, launcher.Name(), cmdStdout, cmdStderr)
-
 	ctx_result, cancel := context.WithCancelCause(ctx)
+	go ForwardCommandToLogger(ctx_result, launcher.Name(), cmdStdout, cmdStderr)
 	go func() {
 		cancel(cmd.Wait())
 	}()
@@ -149,10 +158,13 @@ func (launcher DaemonLauncher) Launch(ctx context.Context) (context.Context, int
 }
 
 func LaunchDaemons(ctx context.Context, launchers []Launcher, egrp *errgroup.Group) (err error) {
+	// The daemons are stopped gracefully by the shutdown hook, or once ctx is done,
+	// rather than killed by the cancellation of the context they run in
+	launchCtx := context.WithoutCancel(ctx)
 
 	daemons := make([]launchInfo, len(launchers))
 	for idx, daemon := range launchers {
-		ctx, pid, err := daemon.Launch(ctx)
+		ctx, pid, err := daemon.Launch(launchCtx)
 		if err != nil {
 			err = errors.Wrapf(err, "Failed to launch %s daemon", daemon.Name())
 			// This is secure as long as deamon.Name() is either "xrootd" or "cmsd"
@@ -166,69 +178,164 @@ func LaunchDaemons(ctx context.Context, launchers []Launcher, egrp *errgroup.Gro
 		metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(daemon.Name()), metrics.StatusOK, "")
 	}
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	cases := make([]reflect.SelectCase, len(daemons)+2)
+	stops := make(chan chan error)
+	done := make(chan struct{})
+	restarts := make(chan chan error)
+	restartRequestsMu.Lock()
+	restartRequests = restarts
+	restartRequestsMu.Unlock()
+	cases := make([]reflect.SelectCase, len(daemons)+3)
 	for idx, daemon := range daemons {
 		cases[idx].Dir = reflect.SelectRecv
 		cases[idx].Chan = reflect.ValueOf(daemon.ctx.Done())
 	}
 	cases[len(daemons)].Dir = reflect.SelectRecv
-	cases[len(daemons)].Chan = reflect.ValueOf(sigs)
+	cases[len(daemons)].Chan = reflect.ValueOf(stops)
 	cases[len(daemons)+1].Dir = reflect.SelectRecv
+	cases[len(daemons)+1].Chan = reflect.ValueOf(ctx.Done())
+	cases[len(daemons)+2].Dir = reflect.SelectRecv
+	cases[len(daemons)+2].Chan = reflect.ValueOf(restarts)
+
+	config.RegisterShutdownHook("XRootD daemons", config.ShutdownOrderDaemons, daemonStopTimeout+5*time.Second, func(ctx context.Context) error {
+		result := make(chan error, 1)
+		select {
+		case stops <- result:
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case err := <-result:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 
 	egrp.Go(func() error {
+		defer close(done)
+		defer func() {
+			restartRequestsMu.Lock()
+			if restartRequests == restarts {
+				restartRequests = nil
+			}
+			restartRequestsMu.Unlock()
+		}()
 		for {
-			timer := time.NewTimer(time.Second)
-			cases[len(daemons)+1].Chan = reflect.ValueOf(timer.C)
-
 			chosen, recv, _ := reflect.Select(cases)
-			if chosen == len(daemons) {
-				sys_sig, ok := recv.Interface().(syscall.Signal)
-				if !ok {
-					panic(errors.New("Unable to convert signal to syscall.Signal"))
-				}
-				log.Warnf("Forwarding signal %v to daemons\n", sys_sig)
-				var lastErr error
+			if chosen == len(daemons)+2 {
+				result := recv.Interface().(chan error)
+				err = restartDaemons(launchCtx, launchers, daemons)
 				for idx, daemon := range daemons {
-					if err = syscall.Kill(daemon.pid, sys_sig); err != nil {
-						lastErr = errors.Wrapf(err, "Failed to forward signal to %s process", launchers[idx].Name())
-					}
-					daemon.expiry = time.Now().Add(10 * time.Second)
-					log.Infof("Daemon %q with pid %d was killed", daemon.name, daemon.pid)
+					cases[idx].Chan = reflect.ValueOf(daemon.ctx.Done())
 				}
-				if lastErr != nil {
-					log.Errorln("Last error when killing launched daemons:", lastErr)
-					return lastErr
-				}
-			} else if chosen < len(daemons) {
-				if waitResult := context.Cause(daemons[chosen].ctx); waitResult != nil {
-					if !daemons[chosen].expiry.IsZero() {
-						return nil
-					} else if errors.Is(waitResult, context.Canceled) {
-						return nil
-					}
-					metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(launchers[chosen].Name()), metrics.StatusCritical,
-						"process failed unexpectedly")
-					err = errors.Wrapf(waitResult, "%s process failed unexpectedly", launchers[chosen].Name())
-					log.Errorln(err)
+				result <- err
+				if err != nil {
 					return err
 				}
-				log.Debugln("Daemons have been shut down successfully")
-				return nil
+			} else if chosen == len(daemons) {
+				result := recv.Interface().(chan error)
+				log.Infoln("Stopping the daemons as the server shuts down")
+				err = stopDaemons(launchers, daemons)
+				result <- err
+				return err
+			} else if chosen == len(daemons)+1 {
+				log.Infoln("Stopping the daemons as their context is done")
+				return stopDaemons(launchers, daemons)
 			} else {
-				for idx, daemon := range daemons {
-					if !daemon.expiry.IsZero() && time.Now().After(daemon.expiry) {
-						if err = syscall.Kill(daemon.pid, syscall.SIGKILL); err != nil {
-							err = errors.Wrapf(err, "Failed to SIGKILL the %s process", launchers[idx].Name())
-							log.Errorln(err)
-							return err
-						}
-					}
+				waitResult := context.Cause(daemons[chosen].ctx)
+				if errors.Is(waitResult, context.Canceled) {
+					// The process exited without an error
+					waitResult = errors.New("exited with status 0")
 				}
+				metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(launchers[chosen].Name()), metrics.StatusCritical,
+					"process failed unexpectedly")
+				err = errors.Wrapf(waitResult, "%s process failed unexpectedly", launchers[chosen].Name())
+				log.Errorln(err)
+				return err
 			}
 		}
 	})
 
 	return nil
 }
+
+// Restart the daemons launched by LaunchDaemons, e.g. to apply configuration they
+// only read at startup.  Returns once the daemons are running again.
+func RestartDaemons(ctx context.Context) error {
+	restartRequestsMu.Lock()
+	restarts := restartRequests
+	restartRequestsMu.Unlock()
+	if restarts == nil {
+		return errors.New("no daemons are running")
+	}
+
+	result := make(chan error, 1)
+	select {
+	case restarts <- result:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop the daemons, giving each daemonStopTimeout to exit after SIGTERM before it's killed
+func stopDaemons(launchers []Launcher, daemons []launchInfo) error {
+	for idx := range daemons {
+		name := launchers[idx].Name()
+		select {
+		case <-daemons[idx].ctx.Done():
+			continue
+		default:
+		}
+		log.Infof("Stopping daemon %q with pid %d", name, daemons[idx].pid)
+		if err := syscall.Kill(daemons[idx].pid, syscall.SIGTERM); err != nil {
+			return errors.Wrapf(err, "Failed to stop the %s process", name)
+		}
+		select {
+		case <-daemons[idx].ctx.Done():
+		case <-time.After(daemonStopTimeout):
+			log.Warningf("Daemon %q did not exit within %s; killing it", name, daemonStopTimeout)
+			if err := syscall.Kill(daemons[idx].pid, syscall.SIGKILL); err != nil {
+				return errors.Wrapf(err, "Failed to SIGKILL the %s process", name)
+			}
+			<-daemons[idx].ctx.Done()
+		}
+	}
+	return nil
+}
+
+// Stop the daemons and launch them again, updating daemons with the new processes
+func restartDaemons(ctx context.Context, launchers []Launcher, daemons []launchInfo) error {
+	for idx := range daemons {
+		metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(launchers[idx].Name()), metrics.StatusWarning, "restarting")
+	}
+	if err := stopDaemons(launchers, daemons); err != nil {
+		return errors.Wrap(err, "Failed to stop the daemons to restart them")
+	}
+
+	names := make([]string, 0, len(launchers))
+	for _, launcher := range launchers {
+		names = append(names, launcher.Name())
+	}
+	for idx, launcher := range launchers {
+		daemonCtx, pid, err := launcher.Launch(ctx)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to relaunch %s daemon", launcher.Name())
+			metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(launcher.Name()), metrics.StatusCritical, err.Error())
+			events.Publish(events.XrootdRestarted{Daemons: names, Error: err.Error()})
+			return err
+		}
+		daemons[idx] = launchInfo{ctx: daemonCtx, pid: pid, name: launcher.Name()}
+		log.Infoln("Successfully relaunched", launcher.Name())
+		metrics.SetComponentHealthStatus(metrics.HealthStatusComponent(launcher.Name()), metrics.StatusOK, "")
+	}
+	events.Publish(events.XrootdRestarted{Daemons: names})
+	return nil
+}
@@ -32,6 +32,10 @@ func LaunchDaemons(ctx context.Context, launchers []Launcher, egrp *errgroup.Gro
 	return errors.New("launching daemons is not supported on Windows")
 }
 
+func RestartDaemons(ctx context.Context) error {
+	return errors.New("launching daemons is not supported on Windows")
+}
+
 func (launcher DaemonLauncher) Launch(ctx context.Context) (context.Context, int, error) {
 	return context.Background(), -1, errors.New("launching daemons is not supported on Windows")
 }
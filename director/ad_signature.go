@@ -0,0 +1,186 @@
+/***************************************************************
+ *
+ * Copyright (C) 2023, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+const (
+	// The header carrying the detached JWS over the body of an advertisement
+	AdvertisementSignatureHeader = "X-Pelican-Advertisement-Signature"
+
+	// The protected headers of the signature naming the namespace whose key signed
+	// the advertisement and when it was signed
+	adSignaturePrefixHeader = "pelican_prefix"
+	adSignatureIssuedHeader = "iat"
+
+	// How long a signed advertisement is accepted for, on top of the clock skew tolerance
+	adSignatureLifetime = time.Minute
+)
+
+// Sign the body of an advertisement with the issuer key of the namespace at prefix,
+// returning the detached JWS (the compact serialization without its payload) to send
+// in the AdvertisementSignatureHeader
+func SignAdvertisement(body []byte, prefix string, key jwk.Key) (string, error) {
+	if err := jwk.AssignKeyID(key); err != nil {
+		return "", errors.Wrap(err, "failed to assign a kid to the advertisement signing key")
+	}
+	alg, err := config.KeySignatureAlgorithm(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to determine the algorithm to sign the advertisement with")
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(adSignaturePrefixHeader, prefix); err != nil {
+		return "", err
+	}
+	if err := headers.Set(adSignatureIssuedHeader, time.Now().Unix()); err != nil {
+		return "", err
+	}
+	signed, err := jws.Sign(nil, jws.WithKey(alg, key, jws.WithProtectedHeaders(headers)), jws.WithDetachedPayload(body))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign the advertisement")
+	}
+	return string(signed), nil
+}
+
+// Get the namespace named in the protected headers of an advertisement signature
+func advertisementSignaturePrefix(signature string) (string, error) {
+	msg, err := jws.Parse([]byte(signature))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse the advertisement signature")
+	}
+	if len(msg.Signatures()) != 1 {
+		return "", errors.New("the advertisement signature must have exactly one signature")
+	}
+	prefixValue, ok := msg.Signatures()[0].ProtectedHeaders().Get(adSignaturePrefixHeader)
+	if !ok {
+		return "", errors.Errorf("the advertisement signature has no %s header", adSignaturePrefixHeader)
+	}
+	prefix, ok := prefixValue.(string)
+	if !ok || prefix == "" {
+		return "", errors.Errorf("the %s header of the advertisement signature is not a namespace", adSignaturePrefixHeader)
+	}
+	return prefix, nil
+}
+
+// Verify the detached JWS over the body of an advertisement against the public keys the
+// registry holds for the namespace at prefix, rejecting signatures from revoked keys or
+// signed too long ago to be a fresh advertisement
+func verifyAdvertisementSignature(ctx context.Context, body []byte, signature, prefix string) error {
+	issuerUrl, err := GetNSIssuerURL(prefix)
+	if err != nil {
+		return err
+	}
+	keyLoc, err := GetJWKSURLFromIssuerURL(issuerUrl)
+	if err != nil {
+		return err
+	}
+	keyset, err := getNamespaceKeySet(ctx, prefix, keyLoc)
+	if err != nil {
+		return err
+	}
+
+	msg := jws.NewMessage()
+	if _, err = jws.Verify([]byte(signature), jws.WithKeySet(keyset), jws.WithDetachedPayload(body), jws.WithMessage(msg)); err != nil {
+		return errors.Wrap(err, "the advertisement signature doesn't match its body")
+	}
+	protected := msg.Signatures()[0].ProtectedHeaders()
+	if keyId := protected.KeyID(); isKeyRevoked(prefix, keyId) {
+		return errors.Errorf("the advertisement is signed with key %s, which the registry revoked for %s", keyId, prefix)
+	}
+
+	issuedValue, ok := protected.Get(adSignatureIssuedHeader)
+	if !ok {
+		return errors.Errorf("the advertisement signature has no %s header", adSignatureIssuedHeader)
+	}
+	var issuedUnix int64
+	switch issued := issuedValue.(type) {
+	case float64:
+		issuedUnix = int64(issued)
+	case json.Number:
+		if issuedUnix, err = issued.Int64(); err != nil {
+			return errors.Wrapf(err, "invalid %s header in the advertisement signature", adSignatureIssuedHeader)
+		}
+	default:
+		return errors.Errorf("invalid %s header in the advertisement signature", adSignatureIssuedHeader)
+	}
+	skew := param.Server_ClockSkewTolerance.GetDuration()
+	age := time.Since(time.Unix(issuedUnix, 0))
+	if age > adSignatureLifetime+skew || age < -skew {
+		return errors.Errorf("the advertisement was signed %s ago, outside the accepted window", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Check the signature of the advertisement the server sent to the director, if it
+// sent one.  The signature must come from the key of a namespace the server is
+// advertising (or of the cache's own registration), whose advertise token has
+// already been verified.
+func checkAdvertisementSignature(engineCtx context.Context, ctx *gin.Context, sType common.ServerType, ad common.OriginAdvertiseV2) error {
+	signature := ctx.GetHeader(AdvertisementSignatureHeader)
+	if signature == "" {
+		if param.Director_RequireAdvertisementSignature.GetBool() {
+			return errors.New("the advertisement is not signed")
+		}
+		return nil
+	}
+
+	prefix, err := advertisementSignaturePrefix(signature)
+	if err != nil {
+		return err
+	}
+	allowed := false
+	if sType == common.OriginType {
+		for _, namespace := range ad.Namespaces {
+			allowed = allowed || namespace.Path == prefix
+		}
+		for _, pausedPath := range ad.PausedNamespaces {
+			allowed = allowed || pausedPath == prefix
+		}
+	} else {
+		allowed = prefix == path.Join("/caches", ad.Name)
+	}
+	if !allowed {
+		return errors.Errorf("the advertisement is signed by %s, which the %s doesn't advertise", prefix, strings.ToLower(string(sType)))
+	}
+
+	body, ok := ctx.Get(gin.BodyBytesKey)
+	if !ok {
+		return errors.New("the body of the advertisement wasn't kept")
+	}
+	bodyBytes, ok := body.([]byte)
+	if !ok {
+		return errors.New("the body of the advertisement wasn't kept")
+	}
+	return verifyAdvertisementSignature(engineCtx, bodyBytes, signature, prefix)
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"net/url"
 	"path"
 	"strings"
 	"sync"
@@ -54,6 +55,22 @@ func RecordAd(ad common.ServerAd, namespaceAds *[]common.NamespaceAdV2) {
 	}
 }
 
+// Remove the ads of the server of type sType with the given data URL, e.g. because
+// it reported itself degraded.  Returns whether any ad was removed.
+func removeServerAds(sType common.ServerType, dataURL url.URL) bool {
+	serverAdMutex.Lock()
+	defer serverAdMutex.Unlock()
+
+	removed := false
+	for _, ad := range serverAds.Keys() {
+		if ad.Type == sType && ad.URL == dataURL {
+			serverAds.Delete(ad)
+			removed = true
+		}
+	}
+	return removed
+}
+
 func UpdateLatLong(ad *common.ServerAd) error {
 	if ad == nil {
 		return errors.New("Cannot provide a nil ad to UpdateLatLong")
@@ -165,5 +182,9 @@ func GetAdsForPath(reqPath string) (originNamespace common.NamespaceAdV2, origin
 	if best != nil {
 		originNamespace = *best
 	}
+	// Servers with scheduled maintenance are drained ahead of it
+	now := time.Now()
+	originAds = filterMaintenanceAds(originAds, now)
+	cacheAds = filterMaintenanceAds(cacheAds, now)
 	return
 }
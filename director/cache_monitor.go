@@ -0,0 +1,69 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+const cacheTestEndpoint = "/api/v1.0/director/healthTest"
+
+// Whether the object path is one of the objects the director serves for the cache test
+func isCacheTestObject(objectPath string) bool {
+	objectPath = path.Clean("/" + objectPath)
+	if objectPath == utils.CacheTestObjectPath {
+		return true
+	}
+	return strings.HasPrefix(objectPath, utils.CacheTestObjectPrefix) && strings.HasSuffix(objectPath, ".txt")
+}
+
+// Redirect a cache asking for a cache test object to the director's own copy.
+// Caches follow this redirect exactly as they follow a redirect to an origin.
+func redirectToCacheTestObject(ginCtx *gin.Context, objectPath string) {
+	redirectUrl, err := url.Parse(param.Server_ExternalWebUrl.GetString())
+	if err != nil {
+		log.Errorln("Failed to parse the director's external URL for the cache test redirect:", err)
+		ginCtx.String(http.StatusInternalServerError, "Internal error: Unable to determine the director's URL")
+		return
+	}
+	redirectUrl.Path = cacheTestEndpoint + path.Clean("/"+objectPath)
+	ginCtx.Redirect(http.StatusTemporaryRedirect, redirectUrl.String())
+}
+
+// GET /api/v1.0/director/healthTest/pelican/monitoring/cache-test.txt
+//
+// Serve the known cache test object. The object is public as it has fixed contents.
+// It must not be kept by the cache, so every test cycle exercises the redirect path.
+func serveCacheTestObject(ginCtx *gin.Context) {
+	if !isCacheTestObject(ginCtx.Param("path")) {
+		ginCtx.String(http.StatusNotFound, "Not a cache test object\n")
+		return
+	}
+	ginCtx.Header("Cache-Control", "no-store, max-age=0")
+	ginCtx.String(http.StatusOK, utils.CacheTestBody)
+}
@@ -0,0 +1,66 @@
+package director
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+func TestCacheTestObject(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Server.ExternalWebUrl", "https://director.example.com:8444")
+
+	router := gin.Default()
+	router.Use(ShortcutMiddleware("cache"))
+	RegisterDirector(context.Background(), router.Group("/"))
+
+	objectPath := utils.CacheTestObjectPath
+	doRequest := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", target, nil)
+		req.Header.Set("User-Agent", "pelican-cache/7.6.0")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("redirect-from-shortcut", func(t *testing.T) {
+		w := doRequest(objectPath)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "https://director.example.com:8444"+cacheTestEndpoint+objectPath, w.Header().Get("Location"))
+	})
+
+	t.Run("redirect-from-origin-endpoint", func(t *testing.T) {
+		w := doRequest("/api/v1.0/director/origin" + objectPath)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "https://director.example.com:8444"+cacheTestEndpoint+objectPath, w.Header().Get("Location"))
+	})
+
+	t.Run("serve-object", func(t *testing.T) {
+		w := doRequest(cacheTestEndpoint + objectPath)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CacheTestBody, w.Body.String())
+		assert.Equal(t, "no-store, max-age=0", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("serve-legacy-object", func(t *testing.T) {
+		// Caches running older versions ask for a unique object each cycle
+		w := doRequest(cacheTestEndpoint + utils.CacheTestObjectPrefix + "2024-01-01T00:00:00Z.txt")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utils.CacheTestBody, w.Body.String())
+	})
+
+	t.Run("not-a-test-object", func(t *testing.T) {
+		w := doRequest(cacheTestEndpoint + "/pelican/monitoring/director-test-2024-01-01T00:00:00Z.txt")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.False(t, isCacheTestObject(utils.CacheTestObjectPrefix+"../../foo/bar.txt"))
+	})
+}
@@ -0,0 +1,240 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A split of the redirects of some namespaces between a group of "canary" caches,
+	// e.g. those running a new release, and the rest of the caches
+	CacheRollout struct {
+		Name string `mapstructure:"Name" json:"name"`
+		// The names of the caches in the canary group
+		Caches []string `mapstructure:"Caches" json:"caches,omitempty"`
+		// A constraint on the version caches advertise with, e.g. ">= 7.11.0"; caches
+		// satisfying it are in the canary group
+		Version string `mapstructure:"Version" json:"version,omitempty"`
+		// The percentage of clients sent to the canary group
+		Percent float64 `mapstructure:"Percent" json:"percent"`
+		// The namespaces the rollout applies to; all namespaces if empty
+		Namespaces []string `mapstructure:"Namespaces" json:"namespaces,omitempty"`
+	}
+
+	cacheRolloutStatus struct {
+		CacheRollout
+		// The caches currently in the canary group
+		CanaryCaches []string `json:"canaryCaches"`
+	}
+
+	cacheRolloutMatcher struct {
+		rollout     CacheRollout
+		caches      map[string]bool
+		constraints version.Constraints
+	}
+)
+
+const (
+	cacheRolloutCanary  = "canary"
+	cacheRolloutControl = "control"
+
+	cacheRolloutHeader = "X-Pelican-Rollout"
+)
+
+var (
+	cacheRollouts      []cacheRolloutMatcher
+	cacheRolloutsMutex sync.RWMutex
+)
+
+// Parse and validate Director.CacheRollouts
+func parseCacheRollouts() ([]cacheRolloutMatcher, error) {
+	rollouts := []CacheRollout{}
+	if err := param.Director_CacheRollouts.Unmarshal(&rollouts); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Director.CacheRollouts")
+	}
+	matchers := make([]cacheRolloutMatcher, 0, len(rollouts))
+	names := make(map[string]bool, len(rollouts))
+	for _, rollout := range rollouts {
+		if rollout.Name == "" {
+			return nil, errors.New("every rollout of Director.CacheRollouts needs a Name")
+		}
+		if names[rollout.Name] {
+			return nil, errors.Errorf("the rollout %q of Director.CacheRollouts is defined more than once", rollout.Name)
+		}
+		names[rollout.Name] = true
+		if rollout.Percent < 0 || rollout.Percent > 100 {
+			return nil, errors.Errorf("the Percent of rollout %q is %v; it must be between 0 and 100", rollout.Name, rollout.Percent)
+		}
+		if len(rollout.Caches) == 0 && rollout.Version == "" {
+			return nil, errors.Errorf("the rollout %q selects no caches; set its Caches or Version", rollout.Name)
+		}
+		matcher := cacheRolloutMatcher{rollout: rollout, caches: make(map[string]bool, len(rollout.Caches))}
+		for _, cache := range rollout.Caches {
+			matcher.caches[cache] = true
+		}
+		if rollout.Version != "" {
+			constraints, err := version.NewConstraint(rollout.Version)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid Version %q of rollout %q", rollout.Version, rollout.Name)
+			}
+			matcher.constraints = constraints
+		}
+		for idx, namespace := range rollout.Namespaces {
+			if !strings.HasPrefix(namespace, "/") {
+				return nil, errors.Errorf("the namespace %q of rollout %q must start with /", namespace, rollout.Name)
+			}
+			matcher.rollout.Namespaces[idx] = strings.TrimSuffix(namespace, "/")
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// Check Director.CacheRollouts and start applying it to redirects
+func ValidateCacheRollouts() error {
+	matchers, err := parseCacheRollouts()
+	if err != nil {
+		return err
+	}
+	cacheRolloutsMutex.Lock()
+	defer cacheRolloutsMutex.Unlock()
+	cacheRollouts = matchers
+	for _, matcher := range matchers {
+		log.Infof("Sending %v%% of the clients of rollout %q to its canary caches", matcher.rollout.Percent, matcher.rollout.Name)
+	}
+	return nil
+}
+
+// Check whether a cache is in the canary group of the rollout
+func (matcher *cacheRolloutMatcher) isCanary(ad common.ServerAd) bool {
+	if matcher.caches[ad.Name] {
+		return true
+	}
+	if matcher.constraints == nil {
+		return false
+	}
+	serverVer, err := version.NewVersion(getServerVersion(ad))
+	if err != nil {
+		return false
+	}
+	return matcher.constraints.Check(serverVer)
+}
+
+// Get the rollout with the longest namespace containing reqPath; rollouts without
+// namespaces apply to any path that no other rollout does
+func getCacheRollout(reqPath string) *cacheRolloutMatcher {
+	cacheRolloutsMutex.RLock()
+	defer cacheRolloutsMutex.RUnlock()
+	var best *cacheRolloutMatcher
+	bestLen := -1
+	for idx := range cacheRollouts {
+		matcher := &cacheRollouts[idx]
+		if len(matcher.rollout.Namespaces) == 0 && bestLen < 0 {
+			best = matcher
+			bestLen = 0
+		}
+		for _, namespace := range matcher.rollout.Namespaces {
+			if (reqPath == namespace || strings.HasPrefix(reqPath, namespace+"/")) && len(namespace) > bestLen {
+				best = matcher
+				bestLen = len(namespace)
+			}
+		}
+	}
+	return best
+}
+
+// Assign a client to the canary or control group of a rollout.  The assignment
+// is a hash of the client's IP, so a client consistently sees the same group.
+func getCacheRolloutGroup(rolloutName string, ipAddr netip.Addr, percent float64) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(rolloutName))
+	hash.Write(ipAddr.AsSlice())
+	if float64(hash.Sum32()%10000) < percent*100 {
+		return cacheRolloutCanary
+	}
+	return cacheRolloutControl
+}
+
+// Move the caches of the client's group of the rollout applying to reqPath to
+// the front of the sorted cacheAds.  The other caches remain as fallbacks.
+func applyCacheRollout(ginCtx *gin.Context, reqPath string, ipAddr netip.Addr, cacheAds []common.ServerAd) []common.ServerAd {
+	matcher := getCacheRollout(reqPath)
+	if matcher == nil {
+		return cacheAds
+	}
+	canaryAds := []common.ServerAd{}
+	controlAds := []common.ServerAd{}
+	for _, ad := range cacheAds {
+		if matcher.isCanary(ad) {
+			canaryAds = append(canaryAds, ad)
+		} else {
+			controlAds = append(controlAds, ad)
+		}
+	}
+	// Without a cache in either group, there is nothing to split
+	if len(canaryAds) == 0 || len(controlAds) == 0 {
+		return cacheAds
+	}
+
+	group := getCacheRolloutGroup(matcher.rollout.Name, ipAddr, matcher.rollout.Percent)
+	metrics.PelicanDirectorRolloutRedirectsTotal.WithLabelValues(matcher.rollout.Name, group).Inc()
+	ginCtx.Writer.Header().Set(cacheRolloutHeader, fmt.Sprintf("name=%s, group=%s", matcher.rollout.Name, group))
+	if group == cacheRolloutCanary {
+		return append(canaryAds, controlAds...)
+	}
+	return append(controlAds, canaryAds...)
+}
+
+// GET /api/v1.0/director_ui/rollouts
+//
+// List the cache rollouts and the caches currently in their canary groups
+func listCacheRollouts(ctx *gin.Context) {
+	cacheAds := ListServerAds([]common.ServerType{common.CacheType})
+	cacheRolloutsMutex.RLock()
+	defer cacheRolloutsMutex.RUnlock()
+	statuses := make([]cacheRolloutStatus, 0, len(cacheRollouts))
+	for idx := range cacheRollouts {
+		matcher := &cacheRollouts[idx]
+		status := cacheRolloutStatus{CacheRollout: matcher.rollout, CanaryCaches: []string{}}
+		for _, ad := range cacheAds {
+			if matcher.isCanary(ad) {
+				status.CanaryCaches = append(status.CanaryCaches, ad.Name)
+			}
+		}
+		sort.Strings(status.CanaryCaches)
+		statuses = append(statuses, status)
+	}
+	ctx.JSON(http.StatusOK, statuses)
+}
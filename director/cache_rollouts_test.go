@@ -0,0 +1,159 @@
+package director
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestParseCacheRollouts(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	for _, tc := range []struct {
+		name     string
+		rollouts []map[string]interface{}
+		errMsg   string
+	}{
+		{
+			name:     "valid",
+			rollouts: []map[string]interface{}{{"Name": "new", "Version": ">= 7.11.0", "Percent": 5, "Namespaces": []string{"/foo/"}}},
+		},
+		{
+			name:     "missing-name",
+			rollouts: []map[string]interface{}{{"Caches": []string{"cache-a"}, "Percent": 5}},
+			errMsg:   "needs a Name",
+		},
+		{
+			name:     "duplicate-name",
+			rollouts: []map[string]interface{}{{"Name": "new", "Caches": []string{"cache-a"}}, {"Name": "new", "Caches": []string{"cache-b"}}},
+			errMsg:   "more than once",
+		},
+		{
+			name:     "bad-percent",
+			rollouts: []map[string]interface{}{{"Name": "new", "Caches": []string{"cache-a"}, "Percent": 150}},
+			errMsg:   "between 0 and 100",
+		},
+		{
+			name:     "no-caches",
+			rollouts: []map[string]interface{}{{"Name": "new", "Percent": 5}},
+			errMsg:   "selects no caches",
+		},
+		{
+			name:     "bad-version",
+			rollouts: []map[string]interface{}{{"Name": "new", "Version": "newest", "Percent": 5}},
+			errMsg:   "invalid Version",
+		},
+		{
+			name:     "relative-namespace",
+			rollouts: []map[string]interface{}{{"Name": "new", "Caches": []string{"cache-a"}, "Namespaces": []string{"foo"}}},
+			errMsg:   "must start with /",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("Director.CacheRollouts", tc.rollouts)
+			matchers, err := parseCacheRollouts()
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, matchers, 1)
+			assert.Equal(t, []string{"/foo"}, matchers[0].rollout.Namespaces)
+		})
+	}
+}
+
+func TestCacheRolloutGroup(t *testing.T) {
+	canary := 0
+	for idx := 0; idx < 10000; idx++ {
+		ipAddr := netip.MustParseAddr(fmt.Sprintf("10.%d.%d.1", idx/256, idx%256))
+		group := getCacheRolloutGroup("new", ipAddr, 5)
+		// A client is consistently assigned to the same group
+		assert.Equal(t, group, getCacheRolloutGroup("new", ipAddr, 5))
+		if group == cacheRolloutCanary {
+			canary++
+		}
+	}
+	assert.InDelta(t, 500, canary, 150)
+
+	ipAddr := netip.MustParseAddr("192.168.1.1")
+	assert.Equal(t, cacheRolloutControl, getCacheRolloutGroup("new", ipAddr, 0))
+	assert.Equal(t, cacheRolloutCanary, getCacheRolloutGroup("new", ipAddr, 100))
+}
+
+func TestApplyCacheRollout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() {
+		viper.Reset()
+		cacheRolloutsMutex.Lock()
+		cacheRollouts = nil
+		cacheRolloutsMutex.Unlock()
+	})
+
+	viper.Set("Director.CacheRollouts", []map[string]interface{}{
+		{"Name": "everyone", "Caches": []string{"cache-c"}, "Percent": 100},
+		{"Name": "nobody", "Caches": []string{"cache-a"}, "Percent": 0, "Namespaces": []string{"/foo"}},
+		{"Name": "new-release", "Version": ">= 7.11.0", "Percent": 100, "Namespaces": []string{"/foo/bar"}},
+	})
+	require.NoError(t, ValidateCacheRollouts())
+
+	cacheA, _ := url.Parse("https://cache-a.example.com")
+	cacheB, _ := url.Parse("https://cache-b.example.com")
+	cacheC, _ := url.Parse("https://cache-c.example.com")
+	cacheAds := []common.ServerAd{{Name: "cache-a", URL: *cacheA}, {Name: "cache-b", URL: *cacheB}, {Name: "cache-c", URL: *cacheC}}
+	recordServerVersion(cacheAds[1], "7.11.2")
+	recordServerVersion(cacheAds[2], "7.10.0")
+	t.Cleanup(func() {
+		for _, ad := range cacheAds {
+			deleteServerVersion(ad)
+		}
+	})
+	ipAddr := netip.MustParseAddr("192.168.1.1")
+
+	names := func(ads []common.ServerAd) []string {
+		result := []string{}
+		for _, ad := range ads {
+			result = append(result, ad.Name)
+		}
+		return result
+	}
+
+	for _, tc := range []struct {
+		path     string
+		expected []string
+		header   string
+	}{
+		// The rollout without namespaces applies to the paths of no other rollout
+		{"/baz/obj", []string{"cache-c", "cache-a", "cache-b"}, "name=everyone, group=canary"},
+		{"/foo/obj", []string{"cache-b", "cache-c", "cache-a"}, "name=nobody, group=control"},
+		// Caches are selected by the version they advertised with
+		{"/foo/bar/obj", []string{"cache-b", "cache-a", "cache-c"}, "name=new-release, group=canary"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ads := applyCacheRollout(ctx, tc.path, ipAddr, append([]common.ServerAd{}, cacheAds...))
+			assert.Equal(t, tc.expected, names(ads))
+			assert.Equal(t, tc.header, w.Header().Get(cacheRolloutHeader))
+		})
+	}
+
+	t.Run("one-group-unavailable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ads := applyCacheRollout(ctx, "/baz/obj", ipAddr, cacheAds[:2])
+		assert.Equal(t, []string{"cache-a", "cache-b"}, names(ads))
+		assert.Empty(t, w.Header().Get(cacheRolloutHeader))
+	})
+}
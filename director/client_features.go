@@ -0,0 +1,123 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-version"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+// What a client talking to the director supports, from the headers of its request
+type clientCapabilities struct {
+	service  string           // The Pelican service in the User-Agent, e.g. "client", or empty
+	version  *version.Version // The version of the Pelican service, or nil
+	features map[string]bool
+}
+
+// The features assumed of clients that don't list theirs, which are what the
+// director sent every client before clients could list their features
+var legacyClientFeatures = map[string]bool{
+	common.FeatureLinkFallback: true,
+}
+
+// The services whose versions are counted in the client version metrics; others are
+// counted as "other" with an "unknown" version to bound the number of series
+var knownServices = map[string]bool{
+	"client":   true,
+	"origin":   true,
+	"cache":    true,
+	"director": true,
+	"registry": true,
+}
+
+// Get the capabilities of the client making the request.  Clients list their features
+// in the common.ClientFeaturesHeader; clients that don't are assumed to have the
+// legacy features, whatever their User-Agent.
+func getClientCapabilities(ginCtx *gin.Context) clientCapabilities {
+	caps := clientCapabilities{}
+	// A malformed User-Agent is rejected by the version check; here it's just not a Pelican service
+	if service, reqVer, err := getUserAgentVersion(ginCtx); err == nil && reqVer != nil {
+		caps.service = service
+		caps.version = reqVer
+	}
+
+	headers := ginCtx.Request.Header.Values(common.ClientFeaturesHeader)
+	if len(headers) == 0 {
+		caps.features = legacyClientFeatures
+		return caps
+	}
+	caps.features = map[string]bool{}
+	for _, header := range headers {
+		for _, feature := range strings.Split(header, ",") {
+			if feature = strings.ToLower(strings.TrimSpace(feature)); feature != "" {
+				caps.features[feature] = true
+			}
+		}
+	}
+	return caps
+}
+
+// Check if the client supports the feature
+func (caps clientCapabilities) supports(feature string) bool {
+	return caps.features[feature]
+}
+
+// Note on the response that it depends on the features the client listed, so caches
+// between the client and the director don't serve it to clients with other features
+func varyOnClientFeatures(ginCtx *gin.Context) {
+	ginCtx.Writer.Header().Add("Vary", common.ClientFeaturesHeader)
+}
+
+// Get the version label of the client version metrics.  Versions are bucketed by
+// major.minor release; releases older than the oldest supported client are counted as
+// "older" and releases newer than the director as "newer", so the number of series is
+// bounded by the releases the director knows of whatever clients put in their User-Agent.
+func clientVersionBucket(ver *version.Version) string {
+	// Development builds of the director don't know which releases exist
+	directorVer, err := version.NewVersion(config.PelicanVersion)
+	if err != nil {
+		return "unknown"
+	}
+	if ver.LessThan(minClientVersion) {
+		return "older"
+	}
+	segs, directorSegs := ver.Segments(), directorVer.Segments()
+	if segs[0] > directorSegs[0] || (segs[0] == directorSegs[0] && segs[1] > directorSegs[1]) {
+		return "newer"
+	}
+	return fmt.Sprintf("%d.%d", segs[0], segs[1])
+}
+
+// Count the request in the client version metrics, to show which versions are still
+// in use before they're deprecated
+func recordClientVersion(caps clientCapabilities) {
+	service, ver := "other", "unknown"
+	if caps.version != nil && knownServices[caps.service] {
+		service = caps.service
+		ver = clientVersionBucket(caps.version)
+	}
+	metrics.PelicanDirectorClientRequestsTotal.WithLabelValues(service, ver).Inc()
+}
@@ -0,0 +1,108 @@
+package director
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+func TestGetClientCapabilities(t *testing.T) {
+	newCtx := func(headers map[string]string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/foo/bar", nil)
+		for key, value := range headers {
+			c.Request.Header.Set(key, value)
+		}
+		return c
+	}
+
+	t.Run("legacy-client", func(t *testing.T) {
+		caps := getClientCapabilities(newCtx(map[string]string{"User-Agent": "curl/8.0.1"}))
+		assert.Empty(t, caps.service)
+		assert.Nil(t, caps.version)
+		assert.True(t, caps.supports(common.FeatureLinkFallback))
+		assert.False(t, caps.supports("some-future-feature"))
+	})
+
+	t.Run("listed-features", func(t *testing.T) {
+		caps := getClientCapabilities(newCtx(map[string]string{
+			"User-Agent":                "pelican-client/7.6.1",
+			common.ClientFeaturesHeader: " Link-Fallback ,,some-future-feature",
+		}))
+		assert.Equal(t, "client", caps.service)
+		require.NotNil(t, caps.version)
+		assert.Equal(t, "7.6.1", caps.version.String())
+		assert.True(t, caps.supports(common.FeatureLinkFallback))
+		assert.True(t, caps.supports("some-future-feature"))
+	})
+
+	t.Run("no-features", func(t *testing.T) {
+		// Clients listing their features only get the ones they list
+		caps := getClientCapabilities(newCtx(map[string]string{common.ClientFeaturesHeader: ""}))
+		assert.False(t, caps.supports(common.FeatureLinkFallback))
+	})
+}
+
+func TestRecordClientVersion(t *testing.T) {
+	metrics.PelicanDirectorClientRequestsTotal.Reset()
+	t.Cleanup(metrics.PelicanDirectorClientRequestsTotal.Reset)
+	oldVersion := config.PelicanVersion
+	config.PelicanVersion = "7.6.2"
+	t.Cleanup(func() { config.PelicanVersion = oldVersion })
+
+	record := func(userAgent string) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/foo/bar", nil)
+		c.Request.Header.Set("User-Agent", userAgent)
+		recordClientVersion(getClientCapabilities(c))
+	}
+	record("pelican-client/7.6.1-rc.1")
+	record("pelican-client/7.6.0")
+	record("pelican-origin/7.5.3")
+	record("pelican-client/7.7.0")
+	record("pelican-client/123456.0.0")
+	record("pelican-client/6.9.0")
+	record("pelican-madeup/1.2.3")
+	record("Mozilla/5.0")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("client", "7.6")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("origin", "7.5")))
+	// Versions the director doesn't know of share a series rather than each getting one
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("client", "newer")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("client", "older")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("other", "unknown")))
+
+	// Without a release version, the director can't bound the versions it counts
+	config.PelicanVersion = "dev"
+	record("pelican-client/7.6.0")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.PelicanDirectorClientRequestsTotal.WithLabelValues("client", "unknown")))
+}
+
+func TestVaryOnClientFeatures(t *testing.T) {
+	cacheURL, _ := url.Parse("https://cache.example.com:8443")
+	staleAds := []common.ServerAd{{Name: "cache", URL: *cacheURL, WebURL: *cacheURL}}
+
+	for _, features := range []string{common.FeatureLinkFallback, ""} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/foo/bar", nil)
+		c.Request.Header.Set(common.ClientFeaturesHeader, features)
+		redirectToStaleCache(c, getClientCapabilities(c), "/foo/bar", netip.Addr{}, staleAds)
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		// The Link header depends on the features, whether or not it's sent
+		assert.Equal(t, common.ClientFeaturesHeader, w.Header().Get("Vary"))
+		assert.Equal(t, features != "", w.Header().Get("Link") != "")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pelicanplatform/pelican/common"
@@ -41,6 +42,11 @@ type (
 		Type      common.ServerType `json:"type"`
 		Latitude  float64           `json:"latitude"`
 		Longitude float64           `json:"longitude"`
+		Version   string            `json:"version,omitempty"`     // The Pelican version the server advertised with
+		Outdated  bool              `json:"outdated,omitempty"`    // The version is older than the federation's minimum
+		Skew      string            `json:"versionSkew,omitempty"` // Why the version may not work with the director's, if it may not
+		// "draining" or "active" if the server is drained for or under scheduled maintenance
+		Maintenance string `json:"maintenance,omitempty"`
 	}
 
 	statResponse struct {
@@ -68,13 +74,13 @@ func (req listServerRequest) ToInternalServerType() common.ServerType {
 func listServers(ctx *gin.Context) {
 	queryParams := listServerRequest{}
 	if ctx.ShouldBindQuery(&queryParams) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
 		return
 	}
 	var servers []common.ServerAd
 	if queryParams.ServerType != "" {
 		if !strings.EqualFold(queryParams.ServerType, string(common.OriginType)) && !strings.EqualFold(queryParams.ServerType, string(common.CacheType)) {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid server type"})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid server type")
 			return
 		}
 		servers = ListServerAds([]common.ServerType{common.ServerType(queryParams.ToInternalServerType())})
@@ -84,6 +90,7 @@ func listServers(ctx *gin.Context) {
 	}
 	resList := make([]listServerResponse, 0)
 	for _, server := range servers {
+		serverVersion := getServerVersion(server)
 		res := listServerResponse{
 			Name:      server.Name,
 			AuthURL:   server.AuthURL.String(),
@@ -92,6 +99,11 @@ func listServers(ctx *gin.Context) {
 			Type:      server.Type,
 			Latitude:  server.Latitude,
 			Longitude: server.Longitude,
+			Version:   serverVersion,
+			Outdated:  isServerOutdated(strings.ToLower(string(server.Type)), serverVersion),
+			Skew:      getServerVersionSkew(serverVersion),
+
+			Maintenance: getServerMaintenance(server, time.Now()),
 		}
 		resList = append(resList, res)
 	}
@@ -102,37 +114,37 @@ func queryOrigins(ctx *gin.Context) {
 	pathParam := ctx.Param("path")
 	path := path.Clean(pathParam)
 	if path == "" || strings.HasSuffix(path, "/") {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Path should not be empty or ended with slash '/'"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, "Path should not be empty or ended with slash '/'")
 		return
 	}
 	queryParams := statRequest{}
 	if ctx.ShouldBindQuery(&queryParams) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
 		return
 	}
 	meta, msg, err := NewObjectStat().Query(path, ctx, queryParams.MinResponses, queryParams.MaxResponses)
 	if err != nil {
 		if err == NoPrefixMatchError {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, err.Error())
 			return
 		} else if err == ParameterError {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, err.Error())
 			return
 		} else if err == InsufficientResError {
 			// Insufficient response does not cause a 500 error, but OK field in reponse is false
 			if len(meta) < 1 {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": msg + " If no object is available, please check if the object is in a public namespace."})
+				web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNoServers, msg+" If no object is available, please check if the object is in a public namespace.")
 				return
 			}
 			res := statResponse{Message: msg, Metadata: meta, OK: false}
 			ctx.JSON(http.StatusOK, res)
 		} else {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, err.Error())
 			return
 		}
 	}
 	if len(meta) < 1 {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error() + " If no object is available, please check if the object is in a public namespace."})
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNoServers, err.Error()+" If no object is available, please check if the object is in a public namespace.")
 	}
 	res := statResponse{Message: msg, Metadata: meta, OK: true}
 	ctx.JSON(http.StatusOK, res)
@@ -142,8 +154,85 @@ func RegisterDirectorWebAPI(router *gin.RouterGroup) {
 	directorWebAPI := router.Group("/api/v1.0/director_ui")
 	// Follow RESTful schema
 	{
-		directorWebAPI.GET("/servers", listServers)
-		directorWebAPI.GET("/servers/origins/stat/*path", web_ui.AuthHandler, queryOrigins)
-		directorWebAPI.HEAD("/servers/origins/stat/*path", web_ui.AuthHandler, queryOrigins)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/servers", web_ui.APIDoc{
+			Summary:  "List the origins and caches advertised to the director",
+			Query:    map[string]string{"server_type": `Only list servers of this type, "origin" or "cache"`},
+			Response: []listServerResponse{},
+		}, listServers)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/topology", web_ui.APIDoc{
+			Summary:  "Return the namespaces of the federation and the servers serving them",
+			Query:    map[string]string{"format": `The format of the topology, "json" (the default) or "dot"`},
+			Response: federationTopology{},
+		}, getTopology)
+		statDoc := web_ui.APIDoc{
+			Summary: "Query the origins of the federation for the object and return those that have it",
+			Auth:    web_ui.APIAuthLogin,
+			Query: map[string]string{
+				"min_responses": "The number of origins with the object to wait for",
+				"max_responses": "The maximum number of origins to return",
+			},
+			QueryTypes: map[string]string{"min_responses": "integer", "max_responses": "integer"},
+			Response:   statResponse{},
+		}
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/servers/origins/stat/*path", statDoc, web_ui.AuthHandler, queryOrigins)
+		web_ui.HandleAPI(directorWebAPI, http.MethodHead, "/servers/origins/stat/*path", statDoc, web_ui.AuthHandler, queryOrigins)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/geoip/overrides", web_ui.APIDoc{
+			Summary:  "List the GeoIP overrides of the director",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []geoIPOverrideItem{},
+		}, web_ui.AuthHandler, listGeoIPOverrides)
+		web_ui.HandleAPI(directorWebAPI, http.MethodPost, "/geoip/overrides", web_ui.APIDoc{
+			Summary: "Add a runtime GeoIP override, replacing any existing runtime override for the same IP or CIDR",
+			Auth:    web_ui.APIAuthAdmin,
+			Request: GeoIPOverride{},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, addGeoIPOverride)
+		web_ui.HandleAPI(directorWebAPI, http.MethodDelete, "/geoip/overrides", web_ui.APIDoc{
+			Summary: "Remove a runtime GeoIP override; overrides from the configuration can't be removed",
+			Auth:    web_ui.APIAuthAdmin,
+			Query:   map[string]string{"ip": "The IP or CIDR of the override"},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, deleteGeoIPOverride)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/geoip/resolve", web_ui.APIDoc{
+			Summary:  "Report how the director geolocates a client IP",
+			Auth:     web_ui.APIAuthLogin,
+			Query:    map[string]string{"ip": "The IP address to resolve"},
+			Response: geoIPResolveResponse{},
+		}, web_ui.AuthHandler, resolveGeoIP)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/pins", web_ui.APIDoc{
+			Summary:  "List the active redirect pins",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []RedirectPin{},
+		}, web_ui.AuthHandler, listRedirectPins)
+		web_ui.HandleAPI(directorWebAPI, http.MethodPost, "/pins", web_ui.APIDoc{
+			Summary:  "Pin the redirects of a namespace to a cache or its origins for a while",
+			Auth:     web_ui.APIAuthAdmin,
+			Request:  redirectPinRequest{},
+			Response: RedirectPin{},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, addRedirectPin)
+		web_ui.HandleAPI(directorWebAPI, http.MethodDelete, "/pins", web_ui.APIDoc{
+			Summary: "Remove the redirect pin of a namespace before it expires",
+			Auth:    web_ui.APIAuthAdmin,
+			Query:   map[string]string{"namespace": "The namespace to unpin"},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, deleteRedirectPin)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/rollouts", web_ui.APIDoc{
+			Summary:  "List the cache rollouts of Director.CacheRollouts and the caches in their canary groups",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []cacheRolloutStatus{},
+		}, web_ui.AuthHandler, listCacheRollouts)
+		web_ui.HandleAPI(directorWebAPI, http.MethodGet, "/maintenance", web_ui.APIDoc{
+			Summary:  "List the scheduled maintenance windows that aren't over, soonest first",
+			Response: []maintenanceWindowStatus{},
+		}, listMaintenanceWindows)
+		web_ui.HandleAPI(directorWebAPI, http.MethodPost, "/maintenance", web_ui.APIDoc{
+			Summary:     "Schedule a maintenance window for some servers or a site",
+			Description: "The director stops sending clients to the servers Director.MaintenanceDrainPeriod before the window starts, and sends them clients again once it ends.",
+			Auth:        web_ui.APIAuthAdmin,
+			Request:     maintenanceWindowRequest{},
+			Response:    MaintenanceWindow{},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, addMaintenanceWindow)
+		web_ui.HandleAPI(directorWebAPI, http.MethodDelete, "/maintenance", web_ui.APIDoc{
+			Summary: "Cancel a maintenance window, or end it early",
+			Auth:    web_ui.APIAuthAdmin,
+			Query:   map[string]string{"id": "The ID of the maintenance window"},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, deleteMaintenanceWindow)
 	}
 }
@@ -70,6 +70,10 @@ func ConfigTTLCache(ctx context.Context, egrp *errgroup.Group) {
 	go namespaceKeys.Start()
 
 	serverAds.OnEviction(func(ctx context.Context, er ttlcache.EvictionReason, i *ttlcache.Item[common.ServerAd, []common.NamespaceAdV2]) {
+		deleteDirectorTestResult(i.Key())
+		deleteServerVersion(i.Key())
+		deletePausedNamespaces(i.Key())
+
 		healthTestUtilsMutex.RLock()
 		defer healthTestUtilsMutex.RUnlock()
 		if util, exists := healthTestUtils[i.Key()]; exists {
@@ -1,6 +1,7 @@
 package director
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,8 @@ import (
 	"github.com/jellydator/ttlcache/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/web_ui"
 )
 
 func TestListServers(t *testing.T) {
@@ -125,3 +128,18 @@ func TestListServers(t *testing.T) {
 		require.Equal(t, 400, w.Code)
 	})
 }
+
+// Every API of the director must be in its OpenAPI document
+func TestDirectorAPIDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	RegisterDirector(context.Background(), engine.Group("/"))
+	RegisterDirectorWebAPI(engine.Group("/"))
+	RegisterDirectorAuth(engine.Group("/"))
+
+	assert.Empty(t, web_ui.UndocumentedRoutes(engine.Routes()))
+	doc := web_ui.GetOpenAPIDocument()
+	assert.Contains(t, doc.Paths["/api/v1.0/director/object/{any}"], "get")
+	assert.Contains(t, doc.Paths["/api/v2.0/director/listNamespaces"], "get")
+	assert.Contains(t, doc.Paths["/.well-known/pelican-configuration"], "get")
+}
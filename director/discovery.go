@@ -20,11 +20,17 @@ package director
 
 import (
 	"encoding/json"
+	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 type OpenIdDiscoveryResponse struct {
@@ -36,17 +42,18 @@ const (
 	openIdDiscoveryPath     string = "/.well-known/openid-configuration"
 	federationDiscoveryPath string = "/.well-known/pelican-configuration"
 	directorJWKSPath        string = "/.well-known/issuer.jwks"
+	clientConfigPath        string = "/.well-known/pelican-client-configuration"
 )
 
 func federationDiscoveryHandler(ctx *gin.Context) {
 	directorUrl := param.Federation_DirectorUrl.GetString()
 	if len(directorUrl) == 0 {
-		ctx.JSON(500, gin.H{"error": "Bad server configuration: Director URL is not set"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Bad server configuration: Director URL is not set")
 		return
 	}
 	registryUrl := param.Federation_RegistryUrl.GetString()
 	if len(registryUrl) == 0 {
-		ctx.JSON(500, gin.H{"error": "Bad server configuration: Registry URL is not set"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Bad server configuration: Registry URL is not set")
 		return
 	}
 
@@ -55,10 +62,40 @@ func federationDiscoveryHandler(ctx *gin.Context) {
 		NamespaceRegistrationEndpoint: registryUrl,
 		JwksUri:                       directorUrl + directorJWKSPath,
 	}
+	if param.Director_ClientConfigFile.GetString() != "" {
+		rs.ClientConfigUri = directorUrl + clientConfigPath
+		rs.SupportedFeatures = append(rs.SupportedFeatures, config.FeatureClientConfig)
+	}
+	if minVer := getFederationMinimumVersion("origin"); minVer != nil {
+		rs.MinimumOriginVersion = minVer.String()
+	}
+	if minVer := getFederationMinimumVersion("cache"); minVer != nil {
+		rs.MinimumCacheVersion = minVer.String()
+	}
+	if minVer := getFederationMinimumVersion("client"); minVer != nil {
+		rs.MinimumClientVersion = minVer.String()
+	}
+	if rs.MinimumOriginVersion != "" || rs.MinimumCacheVersion != "" || rs.MinimumClientVersion != "" {
+		rs.SupportedFeatures = append(rs.SupportedFeatures, config.FeatureMinimumVersions)
+	}
+	if brokerUrl := param.Federation_BrokerUrl.GetString(); brokerUrl != "" {
+		rs.BrokerEndpoint = brokerUrl
+		rs.SupportedFeatures = append(rs.SupportedFeatures, config.FeatureBroker)
+	}
+	rs.SupportedFeatures = append(rs.SupportedFeatures, config.FeatureStaleCaches)
+	rs.DisplayName = param.Director_FederationDisplayName.GetString()
+	rs.Contact = param.Director_FederationContact.GetString()
+	extensions, err := getDiscoveryExtensions()
+	if err != nil {
+		log.Errorf("Failed to load the director's discovery extensions: %v", err)
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Bad server configuration: Invalid Director.DiscoveryExtensions")
+		return
+	}
+	rs.Extensions = extensions
 
 	jsonData, err := json.MarshalIndent(rs, "", "  ")
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to marshal federation's discovery response"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to marshal federation's discovery response")
 		return
 	}
 	// Append a new line to the JSON data
@@ -67,12 +104,36 @@ func federationDiscoveryHandler(ctx *gin.Context) {
 	ctx.Data(200, "application/json", jsonData)
 }
 
+// Load the custom fields of the federation's discovery document from Director.DiscoveryExtensions
+func getDiscoveryExtensions() (map[string]json.RawMessage, error) {
+	configured := map[string]interface{}{}
+	if err := param.Director_DiscoveryExtensions.Unmarshal(&configured); err != nil {
+		return nil, err
+	}
+	if len(configured) == 0 {
+		return nil, nil
+	}
+	extensions := make(map[string]json.RawMessage, len(configured))
+	for name, value := range configured {
+		if config.IsKnownDiscoveryField(name) {
+			log.Warningf("Ignoring Director.DiscoveryExtensions field %q; it's a standard field of the discovery document", name)
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode the discovery extension %q", name)
+		}
+		extensions[name] = encoded
+	}
+	return extensions, nil
+}
+
 // Director metadata discovery endpoint for OpenID style
 // token authentication, providing issuer endpoint and director's jwks endpoint
 func openIdDiscoveryHandler(ctx *gin.Context) {
 	directorUrl := param.Federation_DirectorUrl.GetString()
 	if len(directorUrl) == 0 {
-		ctx.JSON(500, gin.H{"error": "Bad server configuration: Director URL is not set"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Bad server configuration: Director URL is not set")
 		return
 	}
 	rs := OpenIdDiscoveryResponse{
@@ -81,7 +142,7 @@ func openIdDiscoveryHandler(ctx *gin.Context) {
 	}
 	jsonData, err := json.MarshalIndent(rs, "", "  ")
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to marshal director's discovery response"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to marshal director's discovery response")
 		return
 	}
 	// Append a new line to the JSON data
@@ -95,11 +156,11 @@ func jwksHandler(ctx *gin.Context) {
 	key, err := config.GetIssuerPublicJWKS()
 	if err != nil {
 		log.Errorf("Failed to load director's public key: %v", err)
-		ctx.JSON(500, gin.H{"error": "Failed to load director's public key"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to load director's public key")
 	} else {
 		jsonData, err := json.MarshalIndent(key, "", "  ")
 		if err != nil {
-			ctx.JSON(500, gin.H{"error": "Failed to marshal director's public key"})
+			web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to marshal director's public key")
 			return
 		}
 		// Append a new line to the JSON data
@@ -109,8 +170,42 @@ func jwksHandler(ctx *gin.Context) {
 	}
 }
 
+// Returns the default client settings the federation publishes in Director.ClientConfigFile
+func clientConfigHandler(ctx *gin.Context) {
+	clientConfigFile := param.Director_ClientConfigFile.GetString()
+	if clientConfigFile == "" {
+		web_ui.WriteProblem(ctx, 404, common.ErrCodeNotFound, "The federation does not publish a client configuration")
+		return
+	}
+	contents, err := os.ReadFile(clientConfigFile)
+	if err != nil {
+		log.Errorf("Failed to read the client configuration file %s: %v", clientConfigFile, err)
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to read the federation's client configuration")
+		return
+	}
+	// Don't hand clients a document they can't parse
+	clientConfig := map[string]interface{}{}
+	if err = yaml.Unmarshal(contents, &clientConfig); err != nil {
+		log.Errorf("Failed to parse the client configuration file %s: %v", clientConfigFile, err)
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to parse the federation's client configuration")
+		return
+	}
+	ctx.Data(200, "application/yaml", contents)
+}
+
 func RegisterDirectorAuth(router *gin.RouterGroup) {
-	router.GET(federationDiscoveryPath, federationDiscoveryHandler)
-	router.GET(openIdDiscoveryPath, openIdDiscoveryHandler)
-	router.GET(directorJWKSPath, jwksHandler)
+	web_ui.HandleAPI(router, http.MethodGet, federationDiscoveryPath, web_ui.APIDoc{
+		Summary:  "Return the URLs of the federation's services",
+		Response: config.FederationDiscovery{},
+	}, federationDiscoveryHandler)
+	web_ui.HandleAPI(router, http.MethodGet, clientConfigPath, web_ui.APIDoc{
+		Summary: "Return the configuration the federation recommends for its clients, in YAML",
+	}, clientConfigHandler)
+	web_ui.HandleAPI(router, http.MethodGet, openIdDiscoveryPath, web_ui.APIDoc{
+		Summary:  "Return the OpenID configuration of the director's issuer",
+		Response: OpenIdDiscoveryResponse{},
+	}, openIdDiscoveryHandler)
+	web_ui.HandleAPI(router, http.MethodGet, directorJWKSPath, web_ui.APIDoc{
+		Summary: "Return the public keys of the director's issuer as a JWK set",
+	}, jwksHandler)
 }
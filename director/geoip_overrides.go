@@ -0,0 +1,299 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+const (
+	geoIPOverrideSourceConfig  = "config"
+	geoIPOverrideSourceRuntime = "runtime"
+)
+
+type (
+	geoIPOverrideItem struct {
+		GeoIPOverride
+		// "config" for overrides from GeoIPOverrides, which can only be changed in the
+		// configuration, or "runtime" for overrides added through the API
+		Source string `json:"source"`
+	}
+
+	geoIPResolveRequest struct {
+		IP string `form:"ip" binding:"required"`
+	}
+
+	geoIPResolveResponse struct {
+		IP        string         `json:"ip"`
+		Latitude  float64        `json:"latitude"`
+		Longitude float64        `json:"longitude"`
+		Source    string         `json:"source"`             // "override" or "maxmind"
+		Override  *GeoIPOverride `json:"override,omitempty"` // The override that matched the IP, if any
+		Error     string         `json:"error,omitempty"`
+	}
+)
+
+var (
+	// Overrides added through the API, persisted to Director.GeoIPOverridesFile.
+	// Protected by geoIPOverridesMutex and loaded along with geoIPOverrides.
+	runtimeGeoIPOverrides []GeoIPOverride
+)
+
+// Check that the override has a valid IP or CIDR and coordinate
+func validateGeoIPOverride(override GeoIPOverride) error {
+	if strings.Contains(override.IP, "/") {
+		if _, _, err := net.ParseCIDR(override.IP); err != nil {
+			return errors.Errorf("Invalid CIDR address %q", override.IP)
+		}
+	} else if net.ParseIP(override.IP) == nil {
+		return errors.Errorf("Invalid IP address %q", override.IP)
+	}
+	if override.Coordinate.Lat < -90 || override.Coordinate.Lat > 90 {
+		return errors.Errorf("Latitude %f is out of range [-90, 90]", override.Coordinate.Lat)
+	}
+	if override.Coordinate.Long < -180 || override.Coordinate.Long > 180 {
+		return errors.Errorf("Longitude %f is out of range [-180, 180]", override.Coordinate.Long)
+	}
+	return nil
+}
+
+// Load the overrides added through the API from Director.GeoIPOverridesFile.
+// A missing file means there are no runtime overrides.
+func loadRuntimeGeoIPOverrides() ([]GeoIPOverride, error) {
+	overridesFile := param.Director_GeoIPOverridesFile.GetString()
+	if overridesFile == "" {
+		return []GeoIPOverride{}, nil
+	}
+	contents, err := os.ReadFile(overridesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return []GeoIPOverride{}, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read GeoIP overrides file %s", overridesFile)
+	}
+	overrides := []GeoIPOverride{}
+	if err = yaml.Unmarshal(contents, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse GeoIP overrides file %s", overridesFile)
+	}
+	return overrides, nil
+}
+
+// Atomically write the overrides added through the API to Director.GeoIPOverridesFile
+func persistRuntimeGeoIPOverrides(overrides []GeoIPOverride) error {
+	overridesFile := param.Director_GeoIPOverridesFile.GetString()
+	if overridesFile == "" {
+		return errors.New("Director.GeoIPOverridesFile is not set; GeoIP overrides can't be persisted")
+	}
+	contents, err := yaml.Marshal(overrides)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal GeoIP overrides")
+	}
+	if err = os.MkdirAll(filepath.Dir(overridesFile), 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create directory for GeoIP overrides file %s", overridesFile)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(overridesFile), filepath.Base(overridesFile)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create temporary GeoIP overrides file")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return errors.Wrapf(err, "Failed to write GeoIP overrides file")
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write GeoIP overrides file")
+	}
+	if err = os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return errors.Wrapf(err, "Failed to set permissions on GeoIP overrides file")
+	}
+	if err = os.Rename(tmpFile.Name(), overridesFile); err != nil {
+		return errors.Wrapf(err, "Failed to move GeoIP overrides file into place at %s", overridesFile)
+	}
+	return nil
+}
+
+// Load the configured and runtime overrides if they haven't been loaded yet.
+// Must be called with geoIPOverridesMutex held.
+func loadGeoIPOverridesLocked() {
+	loaded := geoIPOverrides != nil && runtimeGeoIPOverrides != nil
+	if geoIPOverrides == nil {
+		err := param.GeoIPOverrides.Unmarshal(&geoIPOverrides)
+		if err != nil {
+			log.Warningf("Error while unmarshaling GeoIP Overrides: %v", err)
+		}
+		if geoIPOverrides == nil {
+			geoIPOverrides = []GeoIPOverride{}
+		}
+	}
+	if runtimeGeoIPOverrides == nil {
+		overrides, err := loadRuntimeGeoIPOverrides()
+		if err != nil {
+			log.Warningln("Ignoring GeoIP overrides added at runtime:", err)
+			overrides = []GeoIPOverride{}
+		}
+		runtimeGeoIPOverrides = overrides
+	}
+	if !loaded || geoIPOverrideMatchers.Load() == nil {
+		compileGeoIPOverridesLocked()
+	}
+}
+
+// GET /api/v1.0/director_ui/geoip/overrides
+//
+// List the GeoIP overrides.  Runtime overrides are listed first as they take precedence.
+func listGeoIPOverrides(ctx *gin.Context) {
+	geoIPOverridesMutex.Lock()
+	defer geoIPOverridesMutex.Unlock()
+	loadGeoIPOverridesLocked()
+
+	items := make([]geoIPOverrideItem, 0, len(runtimeGeoIPOverrides)+len(geoIPOverrides))
+	for _, override := range runtimeGeoIPOverrides {
+		items = append(items, geoIPOverrideItem{GeoIPOverride: override, Source: geoIPOverrideSourceRuntime})
+	}
+	for _, override := range geoIPOverrides {
+		items = append(items, geoIPOverrideItem{GeoIPOverride: override, Source: geoIPOverrideSourceConfig})
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+// POST /api/v1.0/director_ui/geoip/overrides
+//
+// Add a runtime override, replacing any existing runtime override for the same IP or CIDR
+func addGeoIPOverride(ctx *gin.Context) {
+	override := GeoIPOverride{}
+	if err := ctx.ShouldBindJSON(&override); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	override.IP = strings.TrimSpace(override.IP)
+	if err := validateGeoIPOverride(override); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	geoIPOverridesMutex.Lock()
+	defer geoIPOverridesMutex.Unlock()
+	loadGeoIPOverridesLocked()
+
+	updated := make([]GeoIPOverride, 0, len(runtimeGeoIPOverrides)+1)
+	for _, existing := range runtimeGeoIPOverrides {
+		if !strings.EqualFold(existing.IP, override.IP) {
+			updated = append(updated, existing)
+		}
+	}
+	updated = append(updated, override)
+	if err := persistRuntimeGeoIPOverrides(updated); err != nil {
+		log.Errorln("Failed to persist GeoIP overrides:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to persist GeoIP override")
+		return
+	}
+	runtimeGeoIPOverrides = updated
+	compileGeoIPOverridesLocked()
+	log.Infof("Added GeoIP override of %s to lat:long %f:%f", override.IP, override.Coordinate.Lat, override.Coordinate.Long)
+	ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
+}
+
+// DELETE /api/v1.0/director_ui/geoip/overrides?ip=<ip or CIDR>
+//
+// Remove a runtime override.  Overrides from the configuration can't be removed.
+func deleteGeoIPOverride(ctx *gin.Context) {
+	ip := strings.TrimSpace(ctx.Query("ip"))
+	if ip == "" {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The ip query parameter is required")
+		return
+	}
+
+	geoIPOverridesMutex.Lock()
+	defer geoIPOverridesMutex.Unlock()
+	loadGeoIPOverridesLocked()
+
+	updated := make([]GeoIPOverride, 0, len(runtimeGeoIPOverrides))
+	for _, existing := range runtimeGeoIPOverrides {
+		if !strings.EqualFold(existing.IP, ip) {
+			updated = append(updated, existing)
+		}
+	}
+	if len(updated) == len(runtimeGeoIPOverrides) {
+		for _, existing := range geoIPOverrides {
+			if strings.EqualFold(existing.IP, ip) {
+				web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The GeoIP override for "+ip+" is set in the configuration (GeoIPOverrides) and can't be removed at runtime")
+				return
+			}
+		}
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "No GeoIP override for "+ip)
+		return
+	}
+	if err := persistRuntimeGeoIPOverrides(updated); err != nil {
+		log.Errorln("Failed to persist GeoIP overrides:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to persist GeoIP override removal")
+		return
+	}
+	runtimeGeoIPOverrides = updated
+	compileGeoIPOverridesLocked()
+	log.Infoln("Removed GeoIP override of", ip)
+	ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
+}
+
+// GET /api/v1.0/director_ui/geoip/resolve?ip=<ip>
+//
+// Report how the director geolocates a client IP
+func resolveGeoIP(ctx *gin.Context) {
+	req := geoIPResolveRequest{}
+	if ctx.ShouldBindQuery(&req) != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The ip query parameter is required")
+		return
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(req.IP))
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid IP address "+req.IP)
+		return
+	}
+
+	res := geoIPResolveResponse{IP: addr.String()}
+	if override := matchOverride(net.IP(addr.AsSlice())); override != nil {
+		res.Source = "override"
+		res.Override = override
+		res.Latitude = override.Coordinate.Lat
+		res.Longitude = override.Coordinate.Long
+		ctx.JSON(http.StatusOK, res)
+		return
+	}
+
+	res.Source = "maxmind"
+	res.Latitude, res.Longitude, err = GetLatLong(addr)
+	if err != nil {
+		res.Error = err.Error()
+	} else if res.Latitude == 0 && res.Longitude == 0 {
+		res.Error = "The GeoIP database has no location for this address; the director will treat the client as having an unknown location"
+	}
+	ctx.JSON(http.StatusOK, res)
+}
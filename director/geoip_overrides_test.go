@@ -0,0 +1,123 @@
+package director
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetGeoIPOverrides() {
+	geoIPOverridesMutex.Lock()
+	defer geoIPOverridesMutex.Unlock()
+	geoIPOverrides = nil
+	runtimeGeoIPOverrides = nil
+	geoIPOverrideMatchers.Store(nil)
+}
+
+func TestGeoIPOverridesAPI(t *testing.T) {
+	viper.Reset()
+	resetGeoIPOverrides()
+	t.Cleanup(func() {
+		viper.Reset()
+		resetGeoIPOverrides()
+	})
+	viper.Set("Director.GeoIPOverridesFile", filepath.Join(t.TempDir(), "geoip-overrides.yaml"))
+	viper.Set("GeoIPOverrides", []map[string]interface{}{
+		{"IP": "10.0.0.0/24", "Coordinate": map[string]interface{}{"Lat": 43.07, "Long": -89.38}},
+	})
+
+	router := gin.Default()
+	router.GET("/geoip/overrides", listGeoIPOverrides)
+	router.POST("/geoip/overrides", addGeoIPOverride)
+	router.DELETE("/geoip/overrides", deleteGeoIPOverride)
+	router.GET("/geoip/resolve", resolveGeoIP)
+
+	doRequest := func(method string, target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listOverrides := func(t *testing.T) []geoIPOverrideItem {
+		w := doRequest("GET", "/geoip/overrides", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		items := []geoIPOverrideItem{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		return items
+	}
+
+	t.Run("add-invalid", func(t *testing.T) {
+		w := doRequest("POST", "/geoip/overrides", `{"ip": "10.0.0./24", "coordinate": {"lat": 1, "long": 1}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doRequest("POST", "/geoip/overrides", `{"ip": "10.0.0.1", "coordinate": {"lat": 100, "long": 1}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("add-and-list", func(t *testing.T) {
+		w := doRequest("POST", "/geoip/overrides", `{"ip": "10.0.0.5", "coordinate": {"lat": 39.83, "long": -98.58}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		items := listOverrides(t)
+		require.Len(t, items, 2)
+		assert.Equal(t, "10.0.0.5", items[0].IP)
+		assert.Equal(t, geoIPOverrideSourceRuntime, items[0].Source)
+		assert.Equal(t, "10.0.0.0/24", items[1].IP)
+		assert.Equal(t, geoIPOverrideSourceConfig, items[1].Source)
+
+		// The runtime override takes precedence over the configured CIDR
+		coordinate := checkOverrides(net.ParseIP("10.0.0.5"))
+		require.NotNil(t, coordinate)
+		assert.Equal(t, 39.83, coordinate.Lat)
+		coordinate = checkOverrides(net.ParseIP("10.0.0.6"))
+		require.NotNil(t, coordinate)
+		assert.Equal(t, 43.07, coordinate.Lat)
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		resetGeoIPOverrides()
+		items := listOverrides(t)
+		require.Len(t, items, 2)
+		assert.Equal(t, "10.0.0.5", items[0].IP)
+		assert.Equal(t, -98.58, items[0].Coordinate.Long)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		w := doRequest("GET", "/geoip/resolve?ip=10.0.0.5", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		res := geoIPResolveResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "override", res.Source)
+		require.NotNil(t, res.Override)
+		assert.Equal(t, "10.0.0.5", res.Override.IP)
+		assert.Equal(t, 39.83, res.Latitude)
+
+		w = doRequest("GET", "/geoip/resolve?ip=not-an-ip", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := doRequest("DELETE", "/geoip/overrides?ip=10.0.0.0/24", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.True(t, strings.Contains(w.Body.String(), "configuration"))
+
+		w = doRequest("DELETE", "/geoip/overrides?ip=10.0.0.9", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = doRequest("DELETE", "/geoip/overrides?ip=10.0.0.5", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		resetGeoIPOverrides()
+		items := listOverrides(t)
+		require.Len(t, items, 1)
+		assert.Equal(t, geoIPOverrideSourceConfig, items[0].Source)
+	})
+}
@@ -0,0 +1,171 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+// A revoked namespace key, as listed in the registry's revocation feed
+type revokedKey struct {
+	ID     int    `json:"id"`
+	Prefix string `json:"prefix"`
+	KeyID  string `json:"key_id"`
+}
+
+var (
+	// The ids of the revoked keys of each namespace, keyed by the namespace prefix
+	revokedKeys      = make(map[string]map[string]bool)
+	revokedKeysMutex = sync.RWMutex{}
+	// The id of the last revocation read from the feed
+	lastRevocationId int
+)
+
+// Whether the registry revoked the key of the namespace
+func isKeyRevoked(prefix, keyId string) bool {
+	revokedKeysMutex.RLock()
+	defer revokedKeysMutex.RUnlock()
+	return revokedKeys[prefix][keyId]
+}
+
+// Get the id of the key that signed the token, or an empty string if it has none
+func tokenKeyID(token string) string {
+	msg, err := jws.Parse([]byte(token))
+	if err != nil || len(msg.Signatures()) == 0 {
+		return ""
+	}
+	return msg.Signatures()[0].ProtectedHeaders().KeyID()
+}
+
+// Read the revocations added to the registry's feed since the last refresh, and drop
+// the cached keys of the namespaces they revoke keys of
+func refreshRevokedKeys(ctx context.Context) error {
+	registryUrl := param.Federation_RegistryUrl.GetString()
+	if registryUrl == "" {
+		return errors.New("federation registry URL is not set and was not discovered")
+	}
+	feedUrl, err := url.JoinPath(registryUrl, "api", "v1.0", "registry", "revocations")
+	if err != nil {
+		return errors.Wrap(err, "failed to construct the revocation feed URL")
+	}
+	revokedKeysMutex.RLock()
+	feedUrl += "?after=" + strconv.Itoa(lastRevocationId)
+	revokedKeysMutex.RUnlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedUrl, nil)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Transport: config.GetTransport(), Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to query the revocation feed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		// Registries older than the feed can't revoke keys
+		log.Debugln("The registry has no key revocation feed at", feedUrl)
+		return nil
+	} else if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("the revocation feed returned HTTP status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the revocation feed")
+	}
+	revocations := []revokedKey{}
+	if err = json.Unmarshal(body, &revocations); err != nil {
+		return errors.Wrap(err, "failed to parse the revocation feed")
+	}
+
+	revokedPrefixes := make(map[string]bool)
+	func() {
+		revokedKeysMutex.Lock()
+		defer revokedKeysMutex.Unlock()
+		for _, revocation := range revocations {
+			if revokedKeys[revocation.Prefix] == nil {
+				revokedKeys[revocation.Prefix] = make(map[string]bool)
+			}
+			revokedKeys[revocation.Prefix][revocation.KeyID] = true
+			revokedPrefixes[revocation.Prefix] = true
+			if revocation.ID > lastRevocationId {
+				lastRevocationId = revocation.ID
+			}
+		}
+	}()
+
+	// The registry no longer serves the revoked keys, so fetch the namespaces' keys anew
+	for prefix := range revokedPrefixes {
+		log.Warningf("The registry revoked a key of namespace %s; advertisements signed with it are refused", prefix)
+		func() {
+			namespaceKeysMutex.Lock()
+			defer namespaceKeysMutex.Unlock()
+			namespaceKeys.Delete(prefix)
+		}()
+		issuerUrl, err := GetNSIssuerURL(prefix)
+		if err != nil {
+			continue
+		}
+		if keyLoc, err := GetJWKSURLFromIssuerURL(issuerUrl); err == nil {
+			utils.InvalidateIssuerJWKS(keyLoc)
+		} else {
+			log.Warningf("Failed to look up the public keys of namespace %s to drop them from the cache: %v", prefix, err)
+		}
+	}
+	return nil
+}
+
+// Periodically read the registry's feed of revoked namespace keys
+func PeriodicRevokedKeysReload(ctx context.Context) {
+	refreshInterval := param.Director_KeyRevocationRefreshInterval.GetDuration()
+	if refreshInterval <= 0 {
+		log.Warningln("Director.KeyRevocationRefreshInterval is not positive; falling back to 1m")
+		refreshInterval = time.Minute
+	}
+	if err := refreshRevokedKeys(ctx); err != nil {
+		log.Warningln("Failed to read the registry's key revocations:", err)
+	}
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := refreshRevokedKeys(ctx); err != nil {
+				log.Warningln("Failed to read the registry's key revocations:", err)
+			}
+		}
+	}
+}
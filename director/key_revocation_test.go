@@ -0,0 +1,76 @@
+package director
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshRevokedKeys(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		revokedKeysMutex.Lock()
+		defer revokedKeysMutex.Unlock()
+		revokedKeys = make(map[string]map[string]bool)
+		lastRevocationId = 0
+	})
+
+	feed := []revokedKey{{ID: 1, Prefix: "/foo", KeyID: "key-1"}}
+	afterParams := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1.0/registry/revocations" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		afterParams = append(afterParams, r.URL.Query().Get("after"))
+		revocations := []revokedKey{}
+		for _, revocation := range feed {
+			if r.URL.Query().Get("after") == "0" || revocation.ID > 1 {
+				revocations = append(revocations, revocation)
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(revocations))
+	}))
+	defer server.Close()
+	viper.Set("Federation.RegistryUrl", server.URL)
+
+	require.NoError(t, refreshRevokedKeys(context.Background()))
+	assert.True(t, isKeyRevoked("/foo", "key-1"))
+	assert.False(t, isKeyRevoked("/foo", "key-2"))
+	assert.False(t, isKeyRevoked("/bar", "key-1"))
+
+	// Later refreshes only ask for the new revocations
+	feed = append(feed, revokedKey{ID: 2, Prefix: "/bar", KeyID: "key-2"})
+	require.NoError(t, refreshRevokedKeys(context.Background()))
+	assert.Equal(t, []string{"0", "1"}, afterParams)
+	assert.True(t, isKeyRevoked("/foo", "key-1"))
+	assert.True(t, isKeyRevoked("/bar", "key-2"))
+}
+
+func TestTokenKeyID(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(privKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, "signing-key"))
+
+	tok, err := jwt.NewBuilder().Issuer("https://issuer.example.com").Build()
+	require.NoError(t, err)
+	signed, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, key))
+	require.NoError(t, err)
+	assert.Equal(t, "signing-key", tokenKeyID(string(signed)))
+	assert.Equal(t, "", tokenKeyID("not-a-token"))
+}
@@ -0,0 +1,280 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// A scheduled maintenance of some servers, during which the director sends no
+	// clients to them.  The servers are drained ahead of the window, for the
+	// Director.MaintenanceDrainPeriod, so transfers in progress can finish.
+	MaintenanceWindow struct {
+		ID int `json:"id"`
+		// The names of the servers under maintenance
+		Servers []string `json:"servers,omitempty"`
+		// The DNS domain of a site; all of the servers with a hostname in it are under maintenance
+		Site      string    `json:"site,omitempty"`
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+		Reason    string    `json:"reason,omitempty"`
+		CreatedBy string    `json:"createdBy"`
+	}
+
+	maintenanceWindowStatus struct {
+		MaintenanceWindow
+		// "scheduled", "draining" or "active"
+		State string `json:"state"`
+		// When the director stops sending clients to the servers
+		DrainStart time.Time `json:"drainStart"`
+	}
+
+	maintenanceWindowRequest struct {
+		Servers []string  `json:"servers"`
+		Site    string    `json:"site"`
+		Start   time.Time `json:"start" binding:"required"`
+		End     time.Time `json:"end" binding:"required"`
+		Reason  string    `json:"reason"`
+	}
+)
+
+const (
+	maintenanceScheduled = "scheduled"
+	maintenanceDraining  = "draining"
+	maintenanceActive    = "active"
+)
+
+var (
+	maintenanceWindows      = make(map[int]MaintenanceWindow)
+	maintenanceWindowNextID = 1
+	maintenanceWindowsMutex sync.RWMutex
+)
+
+func getMaintenanceDrainPeriod() time.Duration {
+	return max(param.Director_MaintenanceDrainPeriod.GetDuration(), 0)
+}
+
+// Whether the server is covered by the window
+func (window *MaintenanceWindow) covers(ad common.ServerAd) bool {
+	for _, name := range window.Servers {
+		if name == ad.Name {
+			return true
+		}
+	}
+	if window.Site == "" {
+		return false
+	}
+	site := strings.ToLower(strings.TrimSuffix(window.Site, "."))
+	for _, serverUrl := range []string{ad.URL.Hostname(), ad.WebURL.Hostname()} {
+		host := strings.ToLower(serverUrl)
+		if host != "" && (host == site || strings.HasSuffix(host, "."+site)) {
+			return true
+		}
+	}
+	return false
+}
+
+// The state of the window at the time, or "" if it's over
+func (window *MaintenanceWindow) state(now time.Time, drainPeriod time.Duration) string {
+	switch {
+	case !now.Before(window.End):
+		return ""
+	case !now.Before(window.Start):
+		return maintenanceActive
+	case !now.Before(window.Start.Add(-drainPeriod)):
+		return maintenanceDraining
+	default:
+		return maintenanceScheduled
+	}
+}
+
+// Get the state of the maintenance the server is draining for or under at the time,
+// or "" if it isn't.  Must be called with the mutex held.
+func serverMaintenanceState(ad common.ServerAd, now time.Time, drainPeriod time.Duration) string {
+	result := ""
+	for _, window := range maintenanceWindows {
+		if !window.covers(ad) {
+			continue
+		}
+		switch window.state(now, drainPeriod) {
+		case maintenanceActive:
+			return maintenanceActive
+		case maintenanceDraining:
+			result = maintenanceDraining
+		}
+	}
+	return result
+}
+
+func getServerMaintenance(ad common.ServerAd, now time.Time) string {
+	maintenanceWindowsMutex.RLock()
+	defer maintenanceWindowsMutex.RUnlock()
+	return serverMaintenanceState(ad, now, getMaintenanceDrainPeriod())
+}
+
+// Remove the servers draining for or under maintenance at the time from the ads.  Once
+// their windows are over, the servers are sent clients again.
+func filterMaintenanceAds(ads []common.ServerAd, now time.Time) []common.ServerAd {
+	maintenanceWindowsMutex.RLock()
+	defer maintenanceWindowsMutex.RUnlock()
+	if len(maintenanceWindows) == 0 || len(ads) == 0 {
+		return ads
+	}
+	drainPeriod := getMaintenanceDrainPeriod()
+	filtered := make([]common.ServerAd, 0, len(ads))
+	for _, ad := range ads {
+		if state := serverMaintenanceState(ad, now, drainPeriod); state != "" {
+			log.Debugf("Not sending clients to %s as it is %s for maintenance", ad.Name, state)
+			continue
+		}
+		filtered = append(filtered, ad)
+	}
+	return filtered
+}
+
+// Forget the windows that are over
+func deleteEndedMaintenanceWindows(now time.Time) {
+	maintenanceWindowsMutex.Lock()
+	defer maintenanceWindowsMutex.Unlock()
+	for id, window := range maintenanceWindows {
+		if !now.Before(window.End) {
+			log.Infof("Maintenance window %d of %s is over", id, describeMaintenanceTargets(window))
+			delete(maintenanceWindows, id)
+		}
+	}
+}
+
+func describeMaintenanceTargets(window MaintenanceWindow) string {
+	targets := append([]string{}, window.Servers...)
+	if window.Site != "" {
+		targets = append(targets, "site "+window.Site)
+	}
+	return strings.Join(targets, ", ")
+}
+
+// GET /api/v1.0/director_ui/maintenance
+//
+// List the maintenance windows that aren't over, soonest first
+func listMaintenanceWindows(ctx *gin.Context) {
+	now := time.Now()
+	deleteEndedMaintenanceWindows(now)
+	drainPeriod := getMaintenanceDrainPeriod()
+
+	maintenanceWindowsMutex.RLock()
+	windows := make([]maintenanceWindowStatus, 0, len(maintenanceWindows))
+	for _, window := range maintenanceWindows {
+		windows = append(windows, maintenanceWindowStatus{
+			MaintenanceWindow: window,
+			State:             window.state(now, drainPeriod),
+			DrainStart:        window.Start.Add(-drainPeriod),
+		})
+	}
+	maintenanceWindowsMutex.RUnlock()
+
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].Start.Equal(windows[j].Start) {
+			return windows[i].Start.Before(windows[j].Start)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+	ctx.JSON(http.StatusOK, windows)
+}
+
+// POST /api/v1.0/director_ui/maintenance
+//
+// Schedule a maintenance window for some servers or a site
+func addMaintenanceWindow(ctx *gin.Context) {
+	req := maintenanceWindowRequest{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	servers := []string{}
+	for _, server := range req.Servers {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	site := strings.TrimSpace(req.Site)
+	if len(servers) == 0 && site == "" {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The window must name some servers or a site")
+		return
+	}
+	if !req.End.After(req.Start) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The window must end after it starts")
+		return
+	}
+	if !req.End.After(time.Now()) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The window is already over")
+		return
+	}
+
+	maintenanceWindowsMutex.Lock()
+	window := MaintenanceWindow{
+		ID:        maintenanceWindowNextID,
+		Servers:   servers,
+		Site:      site,
+		Start:     req.Start,
+		End:       req.End,
+		Reason:    req.Reason,
+		CreatedBy: ctx.GetString("User"),
+	}
+	maintenanceWindowNextID++
+	maintenanceWindows[window.ID] = window
+	maintenanceWindowsMutex.Unlock()
+
+	log.Infof("%s scheduled maintenance window %d of %s from %s to %s: %s", window.CreatedBy, window.ID,
+		describeMaintenanceTargets(window), window.Start.UTC().Format(time.RFC3339), window.End.UTC().Format(time.RFC3339), window.Reason)
+	ctx.JSON(http.StatusOK, window)
+}
+
+// DELETE /api/v1.0/director_ui/maintenance?id=<id>
+//
+// Cancel a maintenance window, or end it early; the servers are sent clients again
+func deleteMaintenanceWindow(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Query("id"))
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The id query parameter must be the ID of a maintenance window")
+		return
+	}
+	maintenanceWindowsMutex.Lock()
+	window, ok := maintenanceWindows[id]
+	delete(maintenanceWindows, id)
+	maintenanceWindowsMutex.Unlock()
+	if !ok {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, fmt.Sprintf("No maintenance window %d", id))
+		return
+	}
+	log.Infof("%s removed maintenance window %d of %s", ctx.GetString("User"), id, describeMaintenanceTargets(window))
+	ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
+}
@@ -0,0 +1,166 @@
+package director
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func resetMaintenanceWindows() {
+	maintenanceWindowsMutex.Lock()
+	defer maintenanceWindowsMutex.Unlock()
+	maintenanceWindows = make(map[int]MaintenanceWindow)
+	maintenanceWindowNextID = 1
+}
+
+func TestMaintenanceWindowState(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	window := MaintenanceWindow{Start: start, End: start.Add(time.Hour)}
+	drain := 15 * time.Minute
+
+	assert.Equal(t, maintenanceScheduled, window.state(start.Add(-time.Hour), drain))
+	assert.Equal(t, maintenanceDraining, window.state(start.Add(-drain), drain))
+	assert.Equal(t, maintenanceActive, window.state(start, drain))
+	assert.Equal(t, maintenanceActive, window.state(start.Add(59*time.Minute), drain))
+	assert.Equal(t, "", window.state(start.Add(time.Hour), drain))
+}
+
+func TestMaintenanceWindowCovers(t *testing.T) {
+	serverUrl, _ := url.Parse("https://cache.site.example.edu:8443")
+	ad := common.ServerAd{Name: "cache-a", URL: *serverUrl}
+
+	assert.True(t, (&MaintenanceWindow{Servers: []string{"cache-b", "cache-a"}}).covers(ad))
+	assert.False(t, (&MaintenanceWindow{Servers: []string{"cache-b"}}).covers(ad))
+	assert.True(t, (&MaintenanceWindow{Site: "site.example.edu"}).covers(ad))
+	assert.True(t, (&MaintenanceWindow{Site: "Example.edu."}).covers(ad))
+	assert.False(t, (&MaintenanceWindow{Site: "other.example.edu"}).covers(ad))
+	assert.False(t, (&MaintenanceWindow{Site: "e.example.edu"}).covers(ad))
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Reset()
+	resetMaintenanceWindows()
+	serverAds.DeleteAll()
+	t.Cleanup(func() {
+		viper.Reset()
+		resetMaintenanceWindows()
+		serverAds.DeleteAll()
+	})
+	viper.Set("Director.MaintenanceDrainPeriod", "15m")
+
+	cacheA, _ := url.Parse("https://cache-a.example.com")
+	cacheB, _ := url.Parse("https://cache-b.other.org")
+	originUrl, _ := url.Parse("https://origin.example.com")
+	nsAds := []common.NamespaceAdV2{{Path: "/foo", PublicRead: true}}
+	serverAds.Set(common.ServerAd{Name: "cache-a", URL: *cacheA, Type: common.CacheType}, nsAds, ttlcache.DefaultTTL)
+	serverAds.Set(common.ServerAd{Name: "cache-b", URL: *cacheB, Type: common.CacheType}, nsAds, ttlcache.DefaultTTL)
+	serverAds.Set(common.ServerAd{Name: "origin", URL: *originUrl, Type: common.OriginType}, nsAds, ttlcache.DefaultTTL)
+
+	router := gin.Default()
+	router.GET("/maintenance", listMaintenanceWindows)
+	router.POST("/maintenance", addMaintenanceWindow)
+	router.DELETE("/maintenance", deleteMaintenanceWindow)
+	router.GET("/servers", listServers)
+
+	doRequest := func(method string, target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	addWindow := func(t *testing.T, body string) MaintenanceWindow {
+		w := doRequest("POST", "/maintenance", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		window := MaintenanceWindow{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &window))
+		return window
+	}
+	cacheNames := func() (names []string) {
+		_, _, cacheAds := GetAdsForPath("/foo/bar")
+		for _, ad := range cacheAds {
+			names = append(names, ad.Name)
+		}
+		return
+	}
+	timeString := func(offset time.Duration) string {
+		return time.Now().Add(offset).UTC().Format(time.RFC3339)
+	}
+
+	t.Run("add-invalid", func(t *testing.T) {
+		w := doRequest("POST", "/maintenance", fmt.Sprintf(`{"start": "%s", "end": "%s"}`, timeString(time.Hour), timeString(2*time.Hour)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doRequest("POST", "/maintenance", fmt.Sprintf(`{"servers": ["cache-a"], "start": "%s", "end": "%s"}`, timeString(2*time.Hour), timeString(time.Hour)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doRequest("POST", "/maintenance", fmt.Sprintf(`{"servers": ["cache-a"], "start": "%s", "end": "%s"}`, timeString(-2*time.Hour), timeString(-time.Hour)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("scheduled-window-keeps-routing", func(t *testing.T) {
+		window := addWindow(t, fmt.Sprintf(`{"servers": ["cache-a"], "start": "%s", "end": "%s", "reason": "disk swap"}`, timeString(time.Hour), timeString(2*time.Hour)))
+		assert.ElementsMatch(t, []string{"cache-a", "cache-b"}, cacheNames())
+
+		w := doRequest("GET", "/maintenance", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		windows := []maintenanceWindowStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &windows))
+		require.Len(t, windows, 1)
+		assert.Equal(t, window.ID, windows[0].ID)
+		assert.Equal(t, maintenanceScheduled, windows[0].State)
+		assert.Equal(t, "disk swap", windows[0].Reason)
+	})
+
+	t.Run("draining-window-drops-server", func(t *testing.T) {
+		addWindow(t, fmt.Sprintf(`{"site": "other.org", "start": "%s", "end": "%s"}`, timeString(10*time.Minute), timeString(time.Hour)))
+		assert.Equal(t, []string{"cache-a"}, cacheNames())
+
+		w := doRequest("GET", "/servers", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		servers := []listServerResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &servers))
+		for _, server := range servers {
+			if server.Name == "cache-b" {
+				assert.Equal(t, maintenanceDraining, server.Maintenance)
+			} else {
+				assert.Empty(t, server.Maintenance)
+			}
+		}
+	})
+
+	t.Run("delete-restores-routing", func(t *testing.T) {
+		w := doRequest("DELETE", "/maintenance?id=2", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.ElementsMatch(t, []string{"cache-a", "cache-b"}, cacheNames())
+
+		w = doRequest("DELETE", "/maintenance?id=2", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("ended-window-restores-routing", func(t *testing.T) {
+		maintenanceWindowsMutex.Lock()
+		maintenanceWindows[10] = MaintenanceWindow{ID: 10, Servers: []string{"origin"}, Start: time.Now().Add(-time.Hour), End: time.Now().Add(-time.Minute)}
+		maintenanceWindowsMutex.Unlock()
+		_, originAds, _ := GetAdsForPath("/foo/bar")
+		assert.Len(t, originAds, 1)
+
+		w := doRequest("GET", "/maintenance", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		windows := []maintenanceWindowStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &windows))
+		require.Len(t, windows, 1)
+		assert.Equal(t, 1, windows[0].ID)
+	})
+}
@@ -0,0 +1,106 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+const (
+	minVersionPolicyReject string = "reject"
+	minVersionPolicyFlag   string = "flag"
+)
+
+// Get the configured value of the federation's minimum version for a service
+// ("origin", "cache" or "client"), or an empty string if there's none
+func getFederationMinimumVersionStr(service string) string {
+	switch service {
+	case "origin":
+		return param.Director_MinimumOriginVersion.GetString()
+	case "cache":
+		return param.Director_MinimumCacheVersion.GetString()
+	case "client":
+		return param.Director_MinimumClientVersion.GetString()
+	}
+	return ""
+}
+
+// Get the oldest version of a service the federation supports, or nil if it didn't set one
+func getFederationMinimumVersion(service string) *version.Version {
+	verStr := getFederationMinimumVersionStr(service)
+	if verStr == "" {
+		return nil
+	}
+	minVer, err := version.NewVersion(verStr)
+	if err != nil {
+		// Caught by ValidateMinimumVersions at startup
+		log.Debugf("Ignoring invalid minimum %s version %q: %v", service, verStr, err)
+		return nil
+	}
+	return minVer
+}
+
+// Check whether a service's version is older than the federation's minimum. Returns the minimum
+// if so, or nil otherwise.
+func belowFederationMinimum(service string, ver *version.Version) *version.Version {
+	if ver == nil {
+		return nil
+	}
+	if minVer := getFederationMinimumVersion(service); minVer != nil && ver.LessThan(minVer) {
+		return minVer
+	}
+	return nil
+}
+
+// Check whether a server that advertised with the given version should be flagged as outdated
+func isServerOutdated(service string, serverVersion string) bool {
+	if serverVersion == "" {
+		return false
+	}
+	ver, err := version.NewVersion(serverVersion)
+	if err != nil {
+		return false
+	}
+	return belowFederationMinimum(service, ver) != nil
+}
+
+func rejectBelowMinimumVersion() bool {
+	return param.Director_MinimumVersionPolicy.GetString() == minVersionPolicyReject
+}
+
+// Check that the federation's minimum versions and the policy enforcing them are valid
+func ValidateMinimumVersions() error {
+	for _, service := range []string{"origin", "cache", "client"} {
+		if verStr := getFederationMinimumVersionStr(service); verStr != "" {
+			if _, err := version.NewVersion(verStr); err != nil {
+				return errors.Wrapf(err, "invalid minimum %s version %q", service, verStr)
+			}
+		}
+	}
+	policy := param.Director_MinimumVersionPolicy.GetString()
+	if policy != minVersionPolicyReject && policy != minVersionPolicyFlag {
+		return errors.Errorf("Director.MinimumVersionPolicy must be either %q or %q, but you provided %q",
+			minVersionPolicyReject, minVersionPolicyFlag, policy)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package director
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationMinimumVersion(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Director.MinimumOriginVersion", "7.5.0")
+	viper.Set("Director.MinimumClientVersion", "7.4.0")
+
+	checkUserAgent := func(userAgent string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/foo/bar", nil)
+		c.Request.Header.Set("User-Agent", userAgent)
+		return versionCompatCheck(c)
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		viper.Set("Director.MinimumVersionPolicy", "reject")
+		require.NoError(t, ValidateMinimumVersions())
+
+		err := checkUserAgent("pelican-origin/7.4.9")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Please update to 7.5.0 or newer")
+		assert.NoError(t, checkUserAgent("pelican-origin/7.5.0"))
+		assert.Error(t, checkUserAgent("pelican-client/7.3.0"))
+		// No minimum was set for caches
+		assert.NoError(t, checkUserAgent("pelican-cache/7.3.0"))
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		viper.Set("Director.MinimumVersionPolicy", "flag")
+		require.NoError(t, ValidateMinimumVersions())
+
+		assert.NoError(t, checkUserAgent("pelican-origin/7.4.9"))
+		assert.NoError(t, checkUserAgent("pelican-client/7.3.0"))
+		assert.True(t, isServerOutdated("origin", "7.4.9"))
+		assert.False(t, isServerOutdated("origin", "7.5.0"))
+		assert.False(t, isServerOutdated("origin", ""))
+	})
+
+	t.Run("invalid-config", func(t *testing.T) {
+		viper.Set("Director.MinimumVersionPolicy", "ignore")
+		assert.Error(t, ValidateMinimumVersions())
+
+		viper.Set("Director.MinimumVersionPolicy", "reject")
+		viper.Set("Director.MinimumCacheVersion", "latest")
+		assert.Error(t, ValidateMinimumVersions())
+	})
+}
@@ -0,0 +1,202 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// A request the director served, and how it responded, to be replayed against
+// the shadow director
+type mirroredRequest struct {
+	method   string
+	path     string
+	uri      string
+	header   http.Header
+	status   int
+	location string
+	latency  time.Duration
+}
+
+const (
+	mirrorMatch            = "match"
+	mirrorStatusMismatch   = "status_mismatch"
+	mirrorRedirectMismatch = "redirect_mismatch"
+	mirrorError            = "error"
+	mirrorDropped          = "dropped"
+)
+
+// Whether the request is a read request the director may mirror to the shadow
+// director.  Writes are never mirrored, nor are requests to the director's APIs
+// other than the object and origin redirects.
+func isMirrorable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	reqPath := req.URL.Path
+	if strings.HasPrefix(reqPath, "/api/") {
+		return strings.HasPrefix(reqPath, "/api/v1.0/director/object/") ||
+			strings.HasPrefix(reqPath, "/api/v1.0/director/origin/")
+	}
+	return !strings.HasPrefix(reqPath, "/.well-known/")
+}
+
+// The host a redirect sends the client to, or an empty string if the response
+// isn't a redirect
+func redirectHost(location string) string {
+	if location == "" {
+		return ""
+	}
+	redirectURL, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return redirectURL.Host
+}
+
+// Compare the shadow director's response to a request with the director's
+func compareMirrorResponses(status int, location string, shadowStatus int, shadowLocation string) string {
+	if status != shadowStatus {
+		return mirrorStatusMismatch
+	}
+	if redirectHost(location) != redirectHost(shadowLocation) {
+		return mirrorRedirectMismatch
+	}
+	return mirrorMatch
+}
+
+// Replay a request against the shadow director and record how its response compares
+// with the director's
+func mirrorRequest(ctx context.Context, client *http.Client, mirrorURL *url.URL, req mirroredRequest) (result string) {
+	defer func() {
+		metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(result).Inc()
+	}()
+
+	target := strings.TrimSuffix(mirrorURL.String(), "/") + req.uri
+	shadowReq, err := http.NewRequestWithContext(ctx, req.method, target, nil)
+	if err != nil {
+		log.Debugf("Failed to create the mirrored request for %s: %v", req.path, err)
+		return mirrorError
+	}
+	shadowReq.Header = req.header
+
+	start := time.Now()
+	resp, err := client.Do(shadowReq)
+	if err != nil {
+		log.Debugf("Failed to mirror the request for %s to the shadow director: %v", req.path, err)
+		return mirrorError
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	shadowLatency := time.Since(start)
+
+	metrics.PelicanDirectorMirrorLatency.WithLabelValues("production").Observe(req.latency.Seconds())
+	metrics.PelicanDirectorMirrorLatency.WithLabelValues("shadow").Observe(shadowLatency.Seconds())
+
+	shadowLocation := resp.Header.Get("Location")
+	result = compareMirrorResponses(req.status, req.location, resp.StatusCode, shadowLocation)
+	if result != mirrorMatch {
+		log.Infof("The shadow director responded to %s %s with status %d, redirecting to %q, while the director responded with status %d, redirecting to %q",
+			req.method, req.path, resp.StatusCode, redirectHost(shadowLocation), req.status, redirectHost(req.location))
+	}
+	return
+}
+
+// Create a middleware mirroring a sample of the director's read requests to the
+// shadow director of Director.Mirror.Url, to validate a new release or sort
+// algorithm with real traffic.  The shadow director's responses are compared with
+// the director's in the background and never reach the clients.
+func MirrorMiddleware(ctx context.Context) (gin.HandlerFunc, error) {
+	mirrorURL, err := url.Parse(param.Director_Mirror_Url.GetString())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse Director.Mirror.Url")
+	}
+	if mirrorURL.Scheme == "" || mirrorURL.Host == "" {
+		return nil, errors.Errorf("Director.Mirror.Url must be an absolute URL, but %q was provided", mirrorURL.String())
+	}
+	percent := param.Director_Mirror_Percent.GetInt()
+	if percent < 0 || percent > 100 {
+		return nil, errors.Errorf("Director.Mirror.Percent must be between 0 and 100, but %d was provided", percent)
+	}
+	maxConcurrency := param.Director_Mirror_MaxConcurrency.GetInt()
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	client := &http.Client{
+		Transport: config.GetTransport(),
+		Timeout:   param.Director_Mirror_Timeout.GetDuration(),
+		// The redirects are the responses being compared
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	inflight := make(chan struct{}, maxConcurrency)
+
+	log.Infof("Mirroring %d%% of the director's read requests to %s", percent, mirrorURL.String())
+	return func(c *gin.Context) {
+		if !isMirrorable(c.Request) || rand.Intn(100) >= percent {
+			c.Next()
+			return
+		}
+
+		// Capture the request before the handlers rewrite its path
+		req := mirroredRequest{
+			method: c.Request.Method,
+			path:   c.Request.URL.Path,
+			uri:    c.Request.URL.RequestURI(),
+			header: c.Request.Header.Clone(),
+		}
+		// The shadow director should sort the caches for the client, not for this director
+		if req.header.Get("X-Real-Ip") == "" {
+			req.header.Set("X-Real-Ip", c.RemoteIP())
+		}
+
+		start := time.Now()
+		c.Next()
+		req.latency = time.Since(start)
+		req.status = c.Writer.Status()
+		req.location = c.Writer.Header().Get("Location")
+
+		select {
+		case inflight <- struct{}{}:
+		default:
+			metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(mirrorDropped).Inc()
+			return
+		}
+		go func() {
+			defer func() { <-inflight }()
+			mirrorRequest(ctx, client, mirrorURL, req)
+		}()
+	}, nil
+}
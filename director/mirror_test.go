@@ -0,0 +1,139 @@
+package director
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+func TestIsMirrorable(t *testing.T) {
+	for _, tc := range []struct {
+		method     string
+		path       string
+		mirrorable bool
+	}{
+		{"GET", "/foo/bar", true},
+		{"HEAD", "/foo/bar", true},
+		{"PUT", "/foo/bar", false},
+		{"DELETE", "/foo/bar", false},
+		{"GET", "/api/v1.0/director/object/foo/bar", true},
+		{"GET", "/api/v1.0/director/origin/foo/bar", true},
+		{"GET", "/api/v1.0/director/listNamespaces", false},
+		{"GET", "/api/v1.0/health", false},
+		{"GET", "/.well-known/openid-configuration", false},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		assert.Equal(t, tc.mirrorable, isMirrorable(req), "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestCompareMirrorResponses(t *testing.T) {
+	assert.Equal(t, mirrorMatch, compareMirrorResponses(307, "https://cache-a.example.com/foo?authz=abc",
+		307, "https://cache-a.example.com/foo?authz=def"))
+	assert.Equal(t, mirrorRedirectMismatch, compareMirrorResponses(307, "https://cache-a.example.com/foo",
+		307, "https://cache-b.example.com/foo"))
+	assert.Equal(t, mirrorStatusMismatch, compareMirrorResponses(307, "https://cache-a.example.com/foo",
+		404, ""))
+	assert.Equal(t, mirrorMatch, compareMirrorResponses(404, "", 404, ""))
+}
+
+func TestMirrorMiddleware(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	gin.SetMode(gin.TestMode)
+
+	type shadowRequest struct {
+		method string
+		uri    string
+		realIP string
+	}
+	received := make(chan shadowRequest, 10)
+	shadowLocation := "https://cache-a.example.com/foo/bar"
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- shadowRequest{r.Method, r.URL.RequestURI(), r.Header.Get("X-Real-Ip")}
+		http.Redirect(w, r, shadowLocation, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(shadow.Close)
+
+	newEngine := func(t *testing.T, percent int) *gin.Engine {
+		viper.Set("Director.Mirror.Url", shadow.URL)
+		viper.Set("Director.Mirror.Percent", percent)
+		viper.Set("Director.Mirror.MaxConcurrency", 10)
+		mirror, err := MirrorMiddleware(context.Background())
+		require.NoError(t, err)
+
+		engine := gin.New()
+		engine.Use(mirror)
+		engine.Use(func(c *gin.Context) {
+			// Mimic the shortcut middleware, which rewrites the path before redirecting
+			c.Request.URL.Path = "/api/v1.0/director/object" + c.Request.URL.Path
+			c.Redirect(http.StatusTemporaryRedirect, "https://cache-a.example.com/foo/bar")
+			c.Abort()
+		})
+		return engine
+	}
+
+	serve := func(engine *gin.Engine, method, target string) {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = "192.0.2.10:12345"
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("mirrors-read-requests", func(t *testing.T) {
+		engine := newEngine(t, 100)
+		matches := testutil.ToFloat64(metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(mirrorMatch))
+
+		serve(engine, "GET", "/foo/bar?authz=abc")
+		shadowReq := <-received
+		assert.Equal(t, "GET", shadowReq.method)
+		assert.Equal(t, "/foo/bar?authz=abc", shadowReq.uri)
+		assert.Equal(t, "192.0.2.10", shadowReq.realIP)
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(mirrorMatch)) == matches+1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("counts-mismatches", func(t *testing.T) {
+		engine := newEngine(t, 100)
+		shadowLocation = "https://cache-b.example.com/foo/bar"
+		t.Cleanup(func() { shadowLocation = "https://cache-a.example.com/foo/bar" })
+		mismatches := testutil.ToFloat64(metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(mirrorRedirectMismatch))
+
+		serve(engine, "HEAD", "/foo/bar")
+		<-received
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.PelicanDirectorMirroredRequestsTotal.WithLabelValues(mirrorRedirectMismatch)) == mismatches+1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("skips-writes-and-unsampled-requests", func(t *testing.T) {
+		serve(newEngine(t, 100), "PUT", "/foo/bar")
+		serve(newEngine(t, 0), "GET", "/foo/bar")
+		select {
+		case shadowReq := <-received:
+			t.Fatalf("Unexpected mirrored request %s %s", shadowReq.method, shadowReq.uri)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("invalid-config", func(t *testing.T) {
+		viper.Set("Director.Mirror.Url", "not-a-url")
+		_, err := MirrorMiddleware(context.Background())
+		assert.Error(t, err)
+
+		viper.Set("Director.Mirror.Url", shadow.URL)
+		viper.Set("Director.Mirror.Percent", 101)
+		_, err = MirrorMiddleware(context.Background())
+		assert.Error(t, err)
+	})
+}
@@ -31,7 +31,6 @@ import (
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
-	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/pkg/errors"
@@ -60,6 +59,19 @@ type NamespaceCache interface {
 	Get(ctx context.Context, u string) (jwk.Set, error)
 }
 
+// The NamespaceCache used unless one is set for the namespace, backed by
+// the issuer JWKS cache shared by the server's token validation
+type issuerJWKSCache struct{}
+
+// The shared cache tracks any JWKS URL it's asked for, so there's nothing to register
+func (issuerJWKSCache) Register(u string, options ...jwk.RegisterOption) error {
+	return nil
+}
+
+func (issuerJWKSCache) Get(ctx context.Context, u string) (jwk.Set, error) {
+	return utils.GetIssuerJWKS(ctx, u)
+}
+
 var (
 	namespaceKeys      = ttlcache.New[string, NamespaceCache](ttlcache.WithTTL[string, NamespaceCache](15 * time.Minute))
 	namespaceKeysMutex = sync.RWMutex{}
@@ -128,20 +140,6 @@ func VerifyAdvertiseToken(ctx context.Context, token, namespace string) (bool, e
 		return false, err
 	}
 
-	var ar NamespaceCache
-
-	// defer statements are scoped to function, not lexical enclosure,
-	// which is why we wrap these defer statements in anon funcs
-	func() {
-		namespaceKeysMutex.RLock()
-		defer namespaceKeysMutex.RUnlock()
-		item := namespaceKeys.Get(namespace)
-		if item != nil {
-			if !item.IsExpired() {
-				ar = item.Value()
-			}
-		}
-	}()
 	regUrlStr := param.Federation_RegistryUrl.GetString()
 	approved, err := checkNamespaceStatus(namespace, regUrlStr)
 	if err != nil {
@@ -151,34 +149,18 @@ func VerifyAdvertiseToken(ctx context.Context, token, namespace string) (bool, e
 		adminApprovalErr = errors.New(namespace + " has not been approved by an administrator.")
 		return false, adminApprovalErr
 	}
-	if ar == nil {
-		ar = jwk.NewCache(ctx)
-		client := &http.Client{Transport: config.GetTransport()}
-		if err = ar.Register(keyLoc, jwk.WithMinRefreshInterval(15*time.Minute), jwk.WithHTTPClient(client)); err != nil {
-			return false, err
-		}
-		namespaceKeysMutex.Lock()
-		defer namespaceKeysMutex.Unlock()
-
-		customTTL := param.Director_AdvertisementTTL.GetDuration()
-		if customTTL == 0 {
-			namespaceKeys.Set(namespace, ar, ttlcache.DefaultTTL)
-		} else {
-			namespaceKeys.Set(namespace, ar, customTTL)
-		}
-
-	}
-	log.Debugln("Attempting to fetch keys from ", keyLoc)
-	keyset, err := ar.Get(ctx, keyLoc)
-
+	keyset, err := getNamespaceKeySet(ctx, namespace, keyLoc)
 	if err != nil {
 		return false, err
 	}
 
-	tok, err := jwt.Parse([]byte(token), jwt.WithKeySet(keyset), jwt.WithValidate(true))
+	tok, err := jwt.Parse([]byte(token), jwt.WithKeySet(keyset), jwt.WithValidate(true), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
 		return false, err
 	}
+	if keyId := tokenKeyID(token); isKeyRevoked(namespace, keyId) {
+		return false, errors.Errorf("the token is signed with key %s, which the registry revoked for %s", keyId, namespace)
+	}
 
 	scope_any, present := tok.Get("scope")
 	if !present {
@@ -199,10 +181,34 @@ func VerifyAdvertiseToken(ctx context.Context, token, namespace string) (bool, e
 	return false, nil
 }
 
+// Fetch the public keys of the namespace from keyLoc, through the namespace's own
+// NamespaceCache if one is set
+func getNamespaceKeySet(ctx context.Context, namespace, keyLoc string) (jwk.Set, error) {
+	var ar NamespaceCache
+
+	// defer statements are scoped to function, not lexical enclosure,
+	// which is why we wrap these defer statements in anon funcs
+	func() {
+		namespaceKeysMutex.RLock()
+		defer namespaceKeysMutex.RUnlock()
+		item := namespaceKeys.Get(namespace)
+		if item != nil {
+			if !item.IsExpired() {
+				ar = item.Value()
+			}
+		}
+	}()
+	if ar == nil {
+		ar = issuerJWKSCache{}
+	}
+	log.Debugln("Attempting to fetch keys from ", keyLoc)
+	return ar.Get(ctx, keyLoc)
+}
+
 // Verify that a token received is a valid token from director
 func VerifyDirectorTestReportToken(strToken string) (bool, error) {
 	directorURL := param.Federation_DirectorUrl.GetString()
-	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false))
+	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
 		return false, err
 	}
@@ -216,7 +222,12 @@ func VerifyDirectorTestReportToken(strToken string) (bool, error) {
 		return false, err
 	}
 
-	tok, err := jwt.Parse([]byte(strToken), jwt.WithKey(jwa.ES256, key), jwt.WithValidate(true))
+	alg, err := config.KeySignatureAlgorithm(key)
+	if err != nil {
+		return false, err
+	}
+
+	tok, err := jwt.Parse([]byte(strToken), jwt.WithKey(alg, key), jwt.WithValidate(true), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
 		return false, err
 	}
@@ -26,6 +26,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -48,6 +49,41 @@ type (
 	}
 )
 
+var (
+	// The result of the most recent director test cycle against each server, keyed
+	// by the server's data URL. The ad recorded in serverAds also carries GeoIP
+	// coordinates that the ad used to launch the test doesn't have.
+	directorTestResults      = make(map[string]DirectorTest)
+	directorTestResultsMutex = sync.RWMutex{}
+)
+
+// Record the result of a director test cycle against a server so that
+// other director APIs can report on the health of the server
+func recordDirectorTestResult(ad common.ServerAd, status string, message string) {
+	directorTestResultsMutex.Lock()
+	defer directorTestResultsMutex.Unlock()
+	directorTestResults[ad.URL.String()] = DirectorTest{
+		Status:    status,
+		Message:   message,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// Get the result of the most recent director test cycle against a server.
+// Returns false if no test has been completed for the server
+func getDirectorTestResult(ad common.ServerAd) (DirectorTest, bool) {
+	directorTestResultsMutex.RLock()
+	defer directorTestResultsMutex.RUnlock()
+	result, ok := directorTestResults[ad.URL.String()]
+	return result, ok
+}
+
+func deleteDirectorTestResult(ad common.ServerAd) {
+	directorTestResultsMutex.Lock()
+	defer directorTestResultsMutex.Unlock()
+	delete(directorTestResults, ad.URL.String())
+}
+
 // Report the health status of test file transfer to origin
 func reportStatusToOrigin(ctx context.Context, originWebUrl string, status string, message string) error {
 	directorUrl, err := url.Parse(param.Server_ExternalWebUrl.GetString())
@@ -124,23 +160,30 @@ func reportStatusToOrigin(ctx context.Context, originWebUrl string, status strin
 	return nil
 }
 
-// Run a periodic test file transfer against an origin to ensure
-// it's talking to the director
-func LaunchPeriodicDirectorTest(ctx context.Context, originAd common.ServerAd) {
-	originName := originAd.Name
-	originUrl := originAd.URL.String()
-	originWebUrl := originAd.WebURL.String()
+// Run a periodic test file transfer against an origin or cache to ensure
+// it's talking to the director. Origins run the upload/download/delete director
+// test and are sent the result. Caches run the download-only cache test, which pulls
+// an object the director serves through the cache's redirect+fetch path
+func LaunchPeriodicDirectorTest(ctx context.Context, serverAd common.ServerAd) {
+	serverName := serverAd.Name
+	serverUrl := serverAd.URL.String()
+	serverWebUrl := serverAd.WebURL.String()
+	serverType := string(serverAd.Type)
+	testType := utils.DirectorFileTest
+	if serverAd.Type == common.CacheType {
+		testType = utils.CacheFileTest
+	}
 
-	log.Debug(fmt.Sprintf("Starting a new director test suite for origin %s at %s", originName, originUrl))
+	log.Debug(fmt.Sprintf("Starting a new director test suite for %s %s at %s", serverType, serverName, serverUrl))
 
 	metrics.PelicanDirectorFileTransferTestSuite.With(
 		prometheus.Labels{
-			"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type),
+			"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType,
 		}).Inc()
 
 	metrics.PelicanDirectorActiveFileTransferTestSuite.With(
 		prometheus.Labels{
-			"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type),
+			"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType,
 		}).Inc()
 
 	customInterval := param.Director_OriginCacheHealthTestInterval.GetDuration()
@@ -155,50 +198,60 @@ func LaunchPeriodicDirectorTest(ctx context.Context, originAd common.ServerAd) {
 
 	defer ticker.Stop()
 
+	// Only origins have an API to receive the test result
+	reportStatus := func(status string, message string) error {
+		if serverAd.Type != common.OriginType {
+			return nil
+		}
+		return reportStatusToOrigin(ctx, serverWebUrl, status, message)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			log.Debug(fmt.Sprintf("End director test suite for origin: %s at %s", originName, originUrl))
+			log.Debug(fmt.Sprintf("End director test suite for %s: %s at %s", serverType, serverName, serverUrl))
 
 			metrics.PelicanDirectorActiveFileTransferTestSuite.With(
 				prometheus.Labels{
-					"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type),
+					"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType,
 				}).Dec()
 
 			return
 		case <-ticker.C:
-			log.Debug(fmt.Sprintf("Starting a director test cycle for origin: %s at %s", originName, originUrl))
+			log.Debug(fmt.Sprintf("Starting a director test cycle for %s: %s at %s", serverType, serverName, serverUrl))
 			fileTests := utils.TestFileTransferImpl{}
-			ok, err := fileTests.RunTests(ctx, originUrl, "", utils.DirectorFileTest)
+			ok, err := fileTests.RunTests(ctx, serverUrl, "", testType)
 			if ok && err == nil {
-				log.Debugln("Director file transfer test cycle succeeded at", time.Now().Format(time.UnixDate), " for origin: ", originUrl)
-				if err := reportStatusToOrigin(ctx, originWebUrl, "ok", "Director test cycle succeeded at "+time.Now().Format(time.RFC3339)); err != nil {
+				log.Debugln("Director file transfer test cycle succeeded at", time.Now().Format(time.UnixDate), " for", serverType, ":", serverUrl)
+				recordDirectorTestResult(serverAd, "ok", "Director test cycle succeeded at "+time.Now().Format(time.RFC3339))
+				if err := reportStatus("ok", "Director test cycle succeeded at "+time.Now().Format(time.RFC3339)); err != nil {
 					log.Warningln("Failed to report director test result to origin:", err)
 					metrics.PelicanDirectorFileTransferTestsRuns.With(
 						prometheus.Labels{
-							"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type), "status": string(metrics.FTXTestSucceeded), "report_status": string(metrics.FTXTestFailed),
+							"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType, "status": string(metrics.FTXTestSucceeded), "report_status": string(metrics.FTXTestFailed),
 						},
 					).Inc()
 				} else {
 					metrics.PelicanDirectorFileTransferTestsRuns.With(
 						prometheus.Labels{
-							"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type), "status": string(metrics.FTXTestSucceeded), "report_status": string(metrics.FTXTestSucceeded),
+							"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType, "status": string(metrics.FTXTestSucceeded), "report_status": string(metrics.FTXTestSucceeded),
 						},
 					).Inc()
 				}
 			} else {
-				log.Warningln("Director file transfer test cycle failed for origin: ", originUrl, " ", err)
-				if err := reportStatusToOrigin(ctx, originWebUrl, "error", "Director file transfer test cycle failed for origin: "+originUrl+" "+err.Error()); err != nil {
+				log.Warningln("Director file transfer test cycle failed for", serverType, ":", serverUrl, " ", err)
+				recordDirectorTestResult(serverAd, "error", "Director file transfer test cycle failed for "+serverType+": "+serverUrl+" "+err.Error())
+				if err := reportStatus("error", "Director file transfer test cycle failed for "+serverType+": "+serverUrl+" "+err.Error()); err != nil {
 					log.Warningln("Failed to report director test result to origin: ", err)
 					metrics.PelicanDirectorFileTransferTestsRuns.With(
 						prometheus.Labels{
-							"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type), "status": string(metrics.FTXTestFailed), "report_status": string(metrics.FTXTestFailed),
+							"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType, "status": string(metrics.FTXTestFailed), "report_status": string(metrics.FTXTestFailed),
 						},
 					).Inc()
 				} else {
 					metrics.PelicanDirectorFileTransferTestsRuns.With(
 						prometheus.Labels{
-							"server_name": originName, "server_web_url": originWebUrl, "server_type": string(originAd.Type), "status": string(metrics.FTXTestFailed), "report_status": string(metrics.FTXTestSucceeded),
+							"server_name": serverName, "server_web_url": serverWebUrl, "server_type": serverType, "status": string(metrics.FTXTestFailed), "report_status": string(metrics.FTXTestSucceeded),
 						},
 					).Inc()
 				}
@@ -30,9 +30,13 @@ import (
 	"sync"
 
 	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/events"
 	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
 	"github.com/pelicanplatform/pelican/token_scopes"
 	"github.com/pelicanplatform/pelican/utils"
+	"github.com/pelicanplatform/pelican/web_ui"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/gin-gonic/gin"
@@ -70,6 +74,13 @@ var (
 
 	originStatUtils      = make(map[url.URL]originStatUtil)
 	originStatUtilsMutex = sync.RWMutex{}
+
+	// The Pelican version each server advertised with, keyed by the server's data URL
+	serverVersions      = make(map[string]string)
+	serverVersionsMutex = sync.RWMutex{}
+	// The namespaces each origin paused for maintenance, keyed by the origin's data URL
+	pausedNamespaces      = make(map[string][]string)
+	pausedNamespacesMutex = sync.RWMutex{}
 )
 
 // The endpoint for director Prometheus instance to discover Pelican servers
@@ -137,14 +148,13 @@ func getFinalRedirectURL(rurl url.URL, authzEscaped string) string {
 	return rurl.String()
 }
 
-func versionCompatCheck(ginCtx *gin.Context) error {
-	// Check that the version of whichever service (eg client, origin, etc) is talking to the Director
-	// is actually something the Director thinks it can communicate with
-
-	// The service/version is sent via User-Agent header in the form "pelican-<service>/<version>"
+// Get the Pelican service and version from the User-Agent header of the request,
+// which is sent in the form "pelican-<service>/<version>". If the request doesn't
+// come from a Pelican service, the returned version is nil.
+func getUserAgentVersion(ginCtx *gin.Context) (service string, reqVer *version.Version, err error) {
 	userAgentSlc := ginCtx.Request.Header["User-Agent"]
 	if len(userAgentSlc) < 1 {
-		return errors.New("No user agent could be found")
+		return "", nil, errors.New("No user agent could be found")
 	}
 
 	// gin gives us a slice of user agents. Since pelican services should only ever
@@ -156,17 +166,29 @@ func versionCompatCheck(ginCtx *gin.Context) error {
 	// let things go without an error. Maybe someone is using curl?
 	uaRegExp := regexp.MustCompile(`^pelican-[^\/]+\/\d+\.\d+\.\d+`)
 	if matches := uaRegExp.MatchString(userAgent); !matches {
-		return nil
+		return "", nil, nil
 	}
 
 	userAgentSplit := strings.Split(userAgent, "/")
 	// Grab the actual service/version that's using the Director. There may be different versioning
 	// requirements between origins, clients, and other services.
-	service := (strings.Split(userAgentSplit[0], "-"))[1]
+	service = (strings.Split(userAgentSplit[0], "-"))[1]
 	reqVerStr := userAgentSplit[1]
-	reqVer, err := version.NewVersion(reqVerStr)
+	reqVer, err = version.NewVersion(reqVerStr)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Could not parse service version as a semantic version: %s\n", reqVerStr)
+	}
+	return service, reqVer, nil
+}
+
+func versionCompatCheck(ginCtx *gin.Context) error {
+	// Check that the version of whichever service (eg client, origin, etc) is talking to the Director
+	// is actually something the Director thinks it can communicate with
+	service, reqVer, err := getUserAgentVersion(ginCtx)
 	if err != nil {
-		return errors.Wrapf(err, "Could not parse service version as a semantic version: %s\n", reqVerStr)
+		return err
+	} else if reqVer == nil {
+		return nil
 	}
 
 	var minCompatVer *version.Version
@@ -185,14 +207,124 @@ func versionCompatCheck(ginCtx *gin.Context) error {
 		return errors.Errorf("The director does not support your %s version (%s). Please update to %s or newer.", service, reqVer.String(), minCompatVer.String())
 	}
 
+	// The federation may also retire versions the director could still communicate with
+	if fedMinVer := belowFederationMinimum(service, reqVer); fedMinVer != nil {
+		// Outdated servers are otherwise flagged when they advertise
+		if rejectBelowMinimumVersion() {
+			return errors.Errorf("The federation no longer supports your %s version (%s). Please update to %s or newer.", service, reqVer.String(), fedMinVer.String())
+		}
+	}
+
 	return nil
 }
 
+// Check the version a server advertised with against the director's own version.
+// Servers with a different major version, or a newer minor version than the director,
+// may advertise features the director doesn't understand. Returns a warning message
+// if the versions are skewed, or an empty string otherwise.
+func checkVersionSkew(serverVer *version.Version) string {
+	directorVer, err := version.NewVersion(config.PelicanVersion)
+	if err != nil || serverVer == nil {
+		// Development builds of the director don't have a semantic version
+		return ""
+	}
+	directorSegs := directorVer.Segments()
+	serverSegs := serverVer.Segments()
+	if serverSegs[0] != directorSegs[0] {
+		return fmt.Sprintf("server major version %s differs from director version %s", serverVer.String(), directorVer.String())
+	}
+	if serverSegs[1] > directorSegs[1] {
+		return fmt.Sprintf("server version %s is newer than director version %s", serverVer.String(), directorVer.String())
+	}
+	return ""
+}
+
+// Record the version a server advertised with.  Returns true if the server hadn't
+// advertised with this version before, so warnings about the version are logged
+// once rather than on every advertisement.
+func recordServerVersion(ad common.ServerAd, serverVersion string) bool {
+	serverVersionsMutex.Lock()
+	defer serverVersionsMutex.Unlock()
+	previous, ok := serverVersions[ad.URL.String()]
+	serverVersions[ad.URL.String()] = serverVersion
+	return !ok || previous != serverVersion
+}
+
+// Get the warning about the version skew between the director and a server that
+// advertised with serverVersion, or an empty string if there's none
+func getServerVersionSkew(serverVersion string) string {
+	if serverVersion == "" {
+		return ""
+	}
+	serverVer, err := version.NewVersion(serverVersion)
+	if err != nil {
+		return ""
+	}
+	return checkVersionSkew(serverVer)
+}
+
+func getServerVersion(ad common.ServerAd) string {
+	serverVersionsMutex.RLock()
+	defer serverVersionsMutex.RUnlock()
+	return serverVersions[ad.URL.String()]
+}
+
+func deleteServerVersion(ad common.ServerAd) {
+	serverVersionsMutex.Lock()
+	defer serverVersionsMutex.Unlock()
+	delete(serverVersions, ad.URL.String())
+}
+
+func recordPausedNamespaces(ad common.ServerAd, paused []string) {
+	pausedNamespacesMutex.Lock()
+	defer pausedNamespacesMutex.Unlock()
+	if len(paused) == 0 {
+		delete(pausedNamespaces, ad.URL.String())
+	} else {
+		pausedNamespaces[ad.URL.String()] = paused
+	}
+}
+
+func deletePausedNamespaces(ad common.ServerAd) {
+	pausedNamespacesMutex.Lock()
+	defer pausedNamespacesMutex.Unlock()
+	delete(pausedNamespaces, ad.URL.String())
+}
+
+// Check whether the path is in a namespace an origin paused. Only meaningful when no
+// origin currently advertises a namespace for the path.
+func isPathPaused(reqPath string) bool {
+	pausedNamespacesMutex.RLock()
+	defer pausedNamespacesMutex.RUnlock()
+	for _, paused := range pausedNamespaces {
+		for _, nsPath := range paused {
+			if reqPath == nsPath || strings.HasPrefix(reqPath, strings.TrimSuffix(nsPath, "/")+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The origins that permit clients to read from them directly when no cache can
+// serve the object, as set by their Origin.EnableFallbackRead
+func getFallbackOrigins(originAds []common.ServerAd) []common.ServerAd {
+	fallbackOrigins := []common.ServerAd{}
+	for _, ad := range originAds {
+		if ad.EnableFallbackRead {
+			fallbackOrigins = append(fallbackOrigins, ad)
+		}
+	}
+	return fallbackOrigins
+}
+
 func RedirectToCache(ginCtx *gin.Context) {
+	caps := getClientCapabilities(ginCtx)
+	recordClientVersion(caps)
 	err := versionCompatCheck(ginCtx)
 	if err != nil {
 		log.Debugf("A version incompatibility was encountered while redirecting to a cache and no response was served: %v", err)
-		ginCtx.JSON(500, gin.H{"error": "Incompatible versions detected: " + fmt.Sprintf("%v", err)})
+		web_ui.WriteProblem(ginCtx, 500, common.ErrCodeIncompatibleVersion, "Incompatible versions detected: "+fmt.Sprintf("%v", err))
 		return
 	}
 
@@ -211,42 +343,82 @@ func RedirectToCache(ginCtx *gin.Context) {
 	// report the lack of path first -- this is most important for the user because it tells them
 	// they're trying to get an object that simply doesn't exist
 	if namespaceAd.Path == "" {
+		if isPathPaused(reqPath) {
+			ginCtx.String(http.StatusServiceUnavailable, "The namespace for this path is paused for maintenance by its origin\n")
+			return
+		}
 		ginCtx.String(404, "No namespace found for path. Either it doesn't exist, or the Director is experiencing problems\n")
 		return
 	}
+	// Admins may pin the namespace's redirects to a single cache or its origins while debugging
+	pin := getRedirectPin(reqPath)
+	if pin != nil {
+		if cacheAds = getPinnedAds(ginCtx, pin, originAds, cacheAds); cacheAds == nil {
+			return
+		}
+	}
+	// Without an origin, only already-cached objects can be served; send the client
+	// to caches that serve them directly instead of failing on a cache miss
+	if pin == nil && len(originAds) == 0 && namespaceAd.PublicRead {
+		if staleAds := getStaleCacheAds(cacheAds); len(staleAds) > 0 {
+			redirectToStaleCache(ginCtx, caps, reqPath, ipAddr, staleAds)
+			return
+		}
+	}
+	// Origins permitting it are read from directly when no cache can serve the object
+	fallbackOrigins := []common.ServerAd{}
+	if pin == nil {
+		fallbackOrigins = getFallbackOrigins(originAds)
+	}
 	// If the namespace prefix DOES exist, then it makes sense to say we couldn't find a valid cache.
 	if len(cacheAds) == 0 {
-		for _, originAd := range originAds {
-			if originAd.EnableFallbackRead {
-				cacheAds = append(cacheAds, originAd)
-				break
-			}
-		}
-		if len(cacheAds) == 0 {
+		if len(fallbackOrigins) == 0 {
 			ginCtx.String(http.StatusNotFound, "No cache found for path")
 			return
 		}
+		log.Debugf("No cache serves %s; redirecting to the %d origins permitting direct reads", reqPath, len(fallbackOrigins))
+		if cacheAds, err = SortServers(ipAddr, fallbackOrigins); err != nil {
+			ginCtx.String(http.StatusInternalServerError, "Failed to determine server ordering")
+			return
+		}
+		fallbackOrigins = nil
 	} else {
 		cacheAds, err = SortServers(ipAddr, cacheAds)
 		if err != nil {
 			ginCtx.String(http.StatusInternalServerError, "Failed to determine server ordering")
 			return
 		}
+		// Canary rollouts of cache software send a share of the clients to the new caches
+		if pin == nil {
+			cacheAds = applyCacheRollout(ginCtx, reqPath, ipAddr, cacheAds)
+		}
 	}
 	redirectURL := getRedirectURL(reqPath, cacheAds[0], !namespaceAd.Caps.PublicRead)
 
-	linkHeader := ""
-	first := true
-	for idx, ad := range cacheAds {
-		if first {
-			first = false
-		} else {
-			linkHeader += ", "
+	// Only clients that fall back to other caches need the rest of the list
+	varyOnClientFeatures(ginCtx)
+	if caps.supports(common.FeatureLinkFallback) {
+		linkHeader := ""
+		first := true
+		for idx, ad := range cacheAds {
+			if first {
+				first = false
+			} else {
+				linkHeader += ", "
+			}
+			redirectURL := getRedirectURL(reqPath, ad, !namespaceAd.Caps.PublicRead)
+			linkHeader += fmt.Sprintf(`<%s>; rel="duplicate"; pri=%d`, redirectURL.String(), idx+1)
 		}
-		redirectURL := getRedirectURL(reqPath, ad, !namespaceAd.Caps.PublicRead)
-		linkHeader += fmt.Sprintf(`<%s>; rel="duplicate"; pri=%d`, redirectURL.String(), idx+1)
+		// Clients that know to only read from them once the caches failed also get the
+		// origins permitting direct reads, after the caches
+		if caps.supports(common.FeatureOriginFallback) {
+			for idx, ad := range fallbackOrigins {
+				redirectURL := getRedirectURL(reqPath, ad, !namespaceAd.Caps.PublicRead)
+				linkHeader += fmt.Sprintf(`, <%s>; rel="duplicate"; pri=%d; fallback="origin"`, redirectURL.String(), len(cacheAds)+idx+1)
+			}
+		}
+		ginCtx.Writer.Header()["Link"] = []string{linkHeader}
 	}
-	ginCtx.Writer.Header()["Link"] = []string{linkHeader}
 	if len(namespaceAd.Issuer) != 0 {
 
 		issStrings := []string{}
@@ -277,8 +449,11 @@ func RedirectToCache(ginCtx *gin.Context) {
 		}
 	}
 
+	// The namespace may only be served by caches while its origins are unavailable
 	var colUrl string
-	if namespaceAd.PublicRead {
+	if len(originAds) == 0 {
+		ginCtx.Writer.Header().Set(common.OriginStatusHeader, "unavailable")
+	} else if namespaceAd.PublicRead {
 		colUrl = originAds[0].URL.String()
 	} else {
 		colUrl = originAds[0].AuthURL.String()
@@ -294,16 +469,23 @@ func RedirectToCache(ginCtx *gin.Context) {
 }
 
 func RedirectToOrigin(ginCtx *gin.Context) {
+	recordClientVersion(getClientCapabilities(ginCtx))
 	err := versionCompatCheck(ginCtx)
 	if err != nil {
 		log.Debugf("A version incompatibility was encountered while redirecting to an origin and no response was served: %v", err)
-		ginCtx.JSON(500, gin.H{"error": "Incompatible versions detected: " + fmt.Sprintf("%v", err)})
+		web_ui.WriteProblem(ginCtx, 500, common.ErrCodeIncompatibleVersion, "Incompatible versions detected: "+fmt.Sprintf("%v", err))
 		return
 	}
 
 	reqPath := path.Clean("/" + ginCtx.Request.URL.Path)
 	reqPath = strings.TrimPrefix(reqPath, "/api/v1.0/director/origin")
 
+	// Caches running the cache test ask for an object that only the director has
+	if ginCtx.Request.Method != "PUT" && isCacheTestObject(reqPath) {
+		redirectToCacheTestObject(ginCtx, reqPath)
+		return
+	}
+
 	// Each namespace may be exported by several origins, so we must still
 	// do the geolocation song and dance if we want to get the closest origin...
 	ipAddr, err := getRealIP(ginCtx)
@@ -313,11 +495,15 @@ func RedirectToOrigin(ginCtx *gin.Context) {
 
 	authzBearerEscaped := getAuthzEscaped(ginCtx.Request)
 
-	namespaceAd, originAds, _ := GetAdsForPath(reqPath)
+	namespaceAd, originAds, cacheAds := GetAdsForPath(reqPath)
 	// if GetAdsForPath doesn't find any ads because the prefix doesn't exist, we should
 	// report the lack of path first -- this is most important for the user because it tells them
 	// they're trying to get an object that simply doesn't exist
 	if namespaceAd.Path == "" {
+		if isPathPaused(reqPath) {
+			ginCtx.String(http.StatusServiceUnavailable, "The namespace for this path is paused for maintenance by its origin\n")
+			return
+		}
 		ginCtx.String(http.StatusNotFound, "No namespace found for path. Either it doesn't exist, or the Director is experiencing problems\n")
 		return
 	}
@@ -346,8 +532,19 @@ func RedirectToOrigin(ginCtx *gin.Context) {
 	var redirectURL url.URL
 	// If we are doing a PUT, check to see if any origins are writeable
 	if ginCtx.Request.Method == "PUT" {
+		if !checkUploadPolicy(ginCtx, namespaceAd, reqPath) || !checkWriteMode(ginCtx, namespaceAd, reqPath) {
+			return
+		}
 		for idx, ad := range originAds {
 			if ad.EnableWrite {
+				// The cache writes the object back to the origins, so it's only offered when one accepts writes
+				if writeBackAds := getWriteBackCacheAds(cacheAds); len(writeBackAds) > 0 {
+					varyOnClientFeatures(ginCtx)
+					if getClientCapabilities(ginCtx).supports(common.FeatureCacheWriteBack) {
+						redirectToWriteBackCache(ginCtx, reqPath, ipAddr, writeBackAds)
+						return
+					}
+				}
 				redirectURL = getRedirectURL(reqPath, originAds[idx], !namespaceAd.PublicRead)
 				ginCtx.Redirect(http.StatusTemporaryRedirect, getFinalRedirectURL(redirectURL, authzBearerEscaped))
 				return
@@ -402,8 +599,9 @@ func ShortcutMiddleware(defaultResponse string) gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		// Regardless of the remainder of the settings, we currently handle a PUT as a query to the origin endpoint
-		if c.Request.Method == "PUT" {
+		// Regardless of the remainder of the settings, we currently handle a PUT as a query to the origin endpoint.
+		// Caches fetching the cache test object also need the origin endpoint, whatever the default response is.
+		if c.Request.Method == "PUT" || isCacheTestObject(c.Request.URL.Path) {
 			c.Request.URL.Path = "/api/v1.0/director/origin" + c.Request.URL.Path
 			RedirectToOrigin(c)
 			c.Abort()
@@ -447,16 +645,23 @@ func ShortcutMiddleware(defaultResponse string) gin.HandlerFunc {
 }
 
 func registerServeAd(engineCtx context.Context, ctx *gin.Context, sType common.ServerType) {
+	// Advertisements are checked against the registry, so a registry running in the same
+	// process must be ready first
+	if !server_utils.IsReady(server_utils.ModuleDirector) {
+		web_ui.WriteProblem(ctx, http.StatusServiceUnavailable, common.ErrCodeUnavailable, "The director is still starting; retry the advertisement later")
+		return
+	}
+
 	tokens, present := ctx.Request.Header["Authorization"]
 	if !present || len(tokens) == 0 {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Bearer token not present in the 'Authorization' header"})
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeMissingToken, "Bearer token not present in the 'Authorization' header")
 		return
 	}
 
 	err := versionCompatCheck(ctx)
 	if err != nil {
 		log.Debugf("A version incompatibility was encountered while registering %s and no response was served: %v", sType, err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Incompatible versions detected: " + fmt.Sprintf("%v", err)})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeIncompatibleVersion, "Incompatible versions detected: "+fmt.Sprintf("%v", err))
 		return
 	}
 
@@ -468,7 +673,7 @@ func registerServeAd(engineCtx context.Context, ctx *gin.Context, sType common.S
 		adV2 = common.OriginAdvertiseV2{}
 		err = ctx.ShouldBindBodyWith(&adV2, binding.JSON)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + sType + " registration"})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid "+string(sType)+" registration")
 			return
 		}
 	} else {
@@ -477,25 +682,46 @@ func registerServeAd(engineCtx context.Context, ctx *gin.Context, sType common.S
 	}
 
 	if sType == common.OriginType {
+		// We're assuming there's only one token in the slice.  A namespace with its own
+		// key is checked against the token signed with that key, if the origin sent one.
+		adToken := strings.TrimPrefix(tokens[0], "Bearer ")
+		namespaceToken := func(nsPath string) string {
+			if token, ok := adV2.NamespaceTokens[nsPath]; ok {
+				return token
+			}
+			return adToken
+		}
 		for _, namespace := range adV2.Namespaces {
-			// We're assuming there's only one token in the slice
-			token := strings.TrimPrefix(tokens[0], "Bearer ")
+			token := namespaceToken(namespace.Path)
 			ok, err := VerifyAdvertiseToken(engineCtx, token, namespace.Path)
 			if err != nil {
 				if err == adminApprovalErr {
 					log.Warningf("Failed to verify advertise token. Namespace %q requires administrator approval", namespace.Path)
-					ctx.JSON(http.StatusForbidden, gin.H{"approval_error": true, "error": fmt.Sprintf("The namespace %q was not approved by an administrator", namespace.Path)})
+					ctx.JSON(http.StatusForbidden, gin.H{"approval_error": true, "code": common.ErrCodeNotApproved, "error": fmt.Sprintf("The namespace %q was not approved by an administrator", namespace.Path)})
 					return
 				} else {
 					log.Warningln("Failed to verify token:", err)
-					ctx.JSON(http.StatusForbidden, gin.H{"error": "Authorization token verification failed"})
+					web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Authorization token verification failed")
 					return
 				}
 			}
 			if !ok {
 				log.Warningf("%s %v advertised to namespace %v without valid token scope\n",
 					sType, adV2.Name, namespace.Path)
-				ctx.JSON(http.StatusForbidden, gin.H{"error": "Authorization token verification failed. Token missing required scope"})
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Authorization token verification failed. Token missing required scope")
+				return
+			}
+			if err := validateNamespaceIdentifiers(namespace); err != nil {
+				log.Warningf("%s %v advertised invalid persistent identifiers for namespace %v: %v", sType, adV2.Name, namespace.Path, err)
+				web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid persistent identifiers: "+err.Error())
+				return
+			}
+		}
+		// An origin may only report the pause of a namespace it could advertise
+		for _, pausedPath := range adV2.PausedNamespaces {
+			if ok, err := VerifyAdvertiseToken(engineCtx, namespaceToken(pausedPath), pausedPath); err != nil || !ok {
+				log.Warningf("%s %v reported paused namespace %v without a valid token: %v", sType, adV2.Name, pausedPath, err)
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Authorization token verification failed for paused namespace "+pausedPath)
 				return
 			}
 		}
@@ -506,32 +732,50 @@ func registerServeAd(engineCtx context.Context, ctx *gin.Context, sType common.S
 		if err != nil {
 			if err == adminApprovalErr {
 				log.Warningf("Failed to verify token. Cache %q was not approved", ad.Name)
-				ctx.JSON(http.StatusForbidden, gin.H{"approval_error": true, "error": fmt.Sprintf("Cache %q was not approved by an administrator", ad.Name)})
+				ctx.JSON(http.StatusForbidden, gin.H{"approval_error": true, "code": common.ErrCodeNotApproved, "error": fmt.Sprintf("Cache %q was not approved by an administrator", ad.Name)})
 				return
 			} else {
 				log.Warningln("Failed to verify token:", err)
-				ctx.JSON(http.StatusForbidden, gin.H{"error": "Authorization token verification failed."})
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Authorization token verification failed.")
 				return
 			}
 		}
 		if !ok {
 			log.Warningf("%s %v advertised without valid token scope\n", sType, adV2.Name)
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "Authorization token verification failed. Token missing required scope"})
+			web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Authorization token verification failed. Token missing required scope")
 			return
 		}
 	}
 
+	// The signature covers the whole advertisement, so it can't be altered by proxies
+	// between the server and the director
+	if err := checkAdvertisementSignature(engineCtx, ctx, sType, adV2); err != nil {
+		log.Warningf("Rejecting the advertisement of %s %v: %v", sType, adV2.Name, err)
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "Advertisement signature verification failed: "+err.Error())
+		return
+	}
+
 	ad_url, err := url.Parse(adV2.DataURL)
 	if err != nil {
 		log.Warningf("Failed to parse %s URL %v: %v\n", sType, adV2.DataURL, err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + sType + " URL"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid "+string(sType)+" URL")
 		return
 	}
 
 	adWebUrl, err := url.Parse(adV2.WebURL)
 	if err != nil && adV2.WebURL != "" { // We allow empty WebURL string for backward compatibility
 		log.Warningf("Failed to parse server Web URL %v: %v\n", adV2.WebURL, err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid server Web URL"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid server Web URL")
+		return
+	}
+
+	if adV2.Degraded != "" {
+		if removeServerAds(sType, *ad_url) {
+			log.Warningf("Removed %s %s from the director as it reported being degraded: %s", sType, adV2.Name, adV2.Degraded)
+		} else {
+			log.Debugf("Not registering %s %s as it reported being degraded: %s", sType, adV2.Name, adV2.Degraded)
+		}
+		ctx.JSON(http.StatusOK, gin.H{"msg": "Degraded " + sType + " is not registered"})
 		return
 	}
 
@@ -543,15 +787,45 @@ func registerServeAd(engineCtx context.Context, ctx *gin.Context, sType common.S
 		Type:               sType,
 		EnableWrite:        adV2.Caps.Write,
 		EnableFallbackRead: adV2.Caps.FallBackRead,
+		ServeStale:         adV2.Caps.ServeStale,
+		WriteBack:          adV2.Caps.WriteBack,
 	}
 
 	RecordAd(sAd, &adV2.Namespaces)
+	recordPausedNamespaces(sAd, adV2.PausedNamespaces)
 
-	// Start director periodic test of origin's health status if origin AD
+	nsPaths := make([]string, 0, len(adV2.Namespaces))
+	for _, nsAd := range adV2.Namespaces {
+		nsPaths = append(nsPaths, nsAd.Path)
+	}
+	events.Publish(events.AdReceived{
+		ServerName: sAd.Name,
+		ServerType: string(sType),
+		URL:        sAd.URL.String(),
+		WebURL:     adV2.WebURL,
+		Namespaces: nsPaths,
+	})
+
+	if service, serverVer, err := getUserAgentVersion(ctx); err == nil && serverVer != nil {
+		// The version skew and outdated state are shown in the server list; only log them
+		// when the server first advertises with the version
+		if recordServerVersion(sAd, serverVer.String()) {
+			if skew := checkVersionSkew(serverVer); skew != "" {
+				log.Warningf("Version skew detected for %s %s: %s", sType, sAd.Name, skew)
+			}
+			if fedMinVer := belowFederationMinimum(service, serverVer); fedMinVer != nil {
+				log.Warningf("The %s %s advertised with version %s, older than the federation's minimum of %s", sType, sAd.Name, serverVer.String(), fedMinVer.String())
+			}
+		}
+	} else {
+		deleteServerVersion(sAd)
+	}
+
+	// Start director periodic test of origin's or cache's health status if the AD
 	// has WebURL field AND it's not already been registered
 	healthTestUtilsMutex.Lock()
 	defer healthTestUtilsMutex.Unlock()
-	if sAd.Type == common.OriginType && adV2.WebURL != "" {
+	if adV2.WebURL != "" {
 		if existingUtil, ok := healthTestUtils[sAd]; ok {
 			// Existing registration
 			if existingUtil != nil {
@@ -643,7 +917,7 @@ func DiscoverOriginCache(ctx *gin.Context) {
 	ok := utils.CheckAnyAuth(ctx, authOption)
 	if !ok {
 		log.Warningf("Invalid token for accessing director's sevice discovery")
-		ctx.JSON(401, gin.H{"error": "Invalid token for accessing director's sevice discovery"})
+		web_ui.WriteProblem(ctx, 401, common.ErrCodeInvalidToken, "Invalid token for accessing director's sevice discovery")
 		return
 	}
 
@@ -696,15 +970,56 @@ func ListNamespacesV2(ctx *gin.Context) {
 
 func RegisterDirector(ctx context.Context, router *gin.RouterGroup) {
 	// Establish the routes used for cache/origin redirection
-	router.GET("/api/v1.0/director/object/*any", RedirectToCache)
-	router.GET("/api/v1.0/director/origin/*any", RedirectToOrigin)
-	router.PUT("/api/v1.0/director/origin/*any", RedirectToOrigin)
-	router.POST("/api/v1.0/director/registerOrigin", func(gctx *gin.Context) { RegisterOrigin(ctx, gctx) })
+	web_ui.HandleAPI(router, http.MethodGet, "/api/v1.0/director/object/*any", web_ui.APIDoc{
+		Summary:   "Redirect a read of the object to the best cache serving its namespace",
+		Query:     map[string]string{"authz": "The token authorizing the read, passed on to the cache"},
+		Responses: map[int]string{http.StatusTemporaryRedirect: "Redirect to a cache", http.StatusNotFound: "No namespace or cache serves the object"},
+	}, RedirectToCache)
+	web_ui.HandleAPI(router, http.MethodGet, "/api/v1.0/director/origin/*any", web_ui.APIDoc{
+		Summary:   "Redirect a read of the object to an origin exporting its namespace",
+		Query:     map[string]string{"authz": "The token authorizing the read, passed on to the origin"},
+		Responses: map[int]string{http.StatusTemporaryRedirect: "Redirect to an origin", http.StatusNotFound: "No origin exports the object's namespace"},
+	}, RedirectToOrigin)
+	web_ui.HandleAPI(router, http.MethodPut, "/api/v1.0/director/origin/*any", web_ui.APIDoc{
+		Summary:   "Redirect a write of the object to an origin accepting writes to its namespace",
+		Query:     map[string]string{"authz": "The token authorizing the write, passed on to the origin"},
+		Responses: map[int]string{http.StatusTemporaryRedirect: "Redirect to an origin", http.StatusNotFound: "No origin accepts writes to the object's namespace"},
+	}, RedirectToOrigin)
+	web_ui.HandleAPI(router, http.MethodPost, "/api/v1.0/director/registerOrigin", web_ui.APIDoc{
+		Summary: "Advertise an origin and the namespaces it exports to the director",
+		Auth:    web_ui.APIAuthBearer,
+		Request: common.OriginAdvertiseV2{},
+	}, func(gctx *gin.Context) { RegisterOrigin(ctx, gctx) })
 	// In the foreseeable feature, director will scrape all servers in Pelican ecosystem (including registry)
 	// so that director can be our point of contact for collecting system-level metrics.
 	// Rename the endpoint to reflect such plan.
-	router.GET(DirectorServerDiscoveryEndpoint, DiscoverOriginCache)
-	router.POST("/api/v1.0/director/registerCache", func(gctx *gin.Context) { RegisterCache(ctx, gctx) })
-	router.GET("/api/v1.0/director/listNamespaces", ListNamespacesV1)
-	router.GET("/api/v2.0/director/listNamespaces", ListNamespacesV2)
+	web_ui.HandleAPI(router, http.MethodGet, DirectorServerDiscoveryEndpoint, web_ui.APIDoc{
+		Summary:  "List the servers of the federation as Prometheus service discovery targets",
+		Auth:     web_ui.APIAuthBearer,
+		Response: []PromDiscoveryItem{},
+	}, DiscoverOriginCache)
+	web_ui.HandleAPI(router, http.MethodPost, "/api/v1.0/director/registerCache", web_ui.APIDoc{
+		Summary: "Advertise a cache to the director",
+		Auth:    web_ui.APIAuthBearer,
+		Request: common.OriginAdvertiseV2{},
+	}, func(gctx *gin.Context) { RegisterCache(ctx, gctx) })
+	web_ui.HandleAPI(router, http.MethodGet, "/api/v1.0/director/listNamespaces", web_ui.APIDoc{
+		Summary:  "List the namespaces advertised by the origins, in the version 1 advertisement format",
+		Response: []common.NamespaceAdV1{},
+	}, ListNamespacesV1)
+	web_ui.HandleAPI(router, http.MethodGet, "/api/v2.0/director/listNamespaces", web_ui.APIDoc{
+		Summary:  "List the namespaces advertised by the origins, in the version 2 advertisement format",
+		Response: []common.NamespaceAdV2{},
+	}, ListNamespacesV2)
+	web_ui.HandleAPI(router, http.MethodGet, "/api/v1.0/director/resolve", web_ui.APIDoc{
+		Summary:   "Resolve a persistent identifier, such as a DOI, to the federation path it was assigned to",
+		Query:     map[string]string{"identifier": "The identifier, e.g. doi:10.1234/abc or ark:12345/abc"},
+		Response:  common.PersistentIdentifier{},
+		Responses: map[int]string{http.StatusNotFound: "No namespace advertises the identifier", http.StatusConflict: "Namespaces advertise the identifier for different paths"},
+	}, resolveIdentifier)
+	healthTestDoc := web_ui.APIDoc{
+		Summary: "Serve the test objects the director asks caches to fetch to check their health",
+	}
+	web_ui.HandleAPI(router, http.MethodGet, cacheTestEndpoint+"/*path", healthTestDoc, serveCacheTestObject)
+	web_ui.HandleAPI(router, http.MethodHead, cacheTestEndpoint+"/*path", healthTestDoc, serveCacheTestObject)
 }
@@ -0,0 +1,205 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jellydator/ttlcache/v3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// A temporary rule sending every client redirect of a namespace to one server,
+	// so admins can reproduce a problem against a single cache or the origin
+	RedirectPin struct {
+		Namespace string    `json:"namespace"`
+		Server    string    `json:"server"` // The name of a cache, or "origin"
+		Reason    string    `json:"reason,omitempty"`
+		CreatedBy string    `json:"createdBy"`
+		Expires   time.Time `json:"expires"`
+	}
+
+	redirectPinRequest struct {
+		Namespace string `json:"namespace" binding:"required"`
+		Server    string `json:"server" binding:"required"`
+		TTL       string `json:"ttl"`
+		Reason    string `json:"reason"`
+	}
+)
+
+const (
+	// The server of a pin that sends clients straight to the namespace's origins
+	redirectPinOrigin = "origin"
+
+	defaultRedirectPinTTL = time.Hour
+	maxRedirectPinTTL     = 7 * 24 * time.Hour
+
+	redirectPinHeader = "X-Pelican-Redirect-Pin"
+)
+
+// The active pins, keyed by namespace; each expires with its TTL
+var redirectPins = ttlcache.New[string, RedirectPin]()
+
+// Get the pin of the longest namespace containing reqPath, if any
+func getRedirectPin(reqPath string) *RedirectPin {
+	var best *RedirectPin
+	for _, item := range redirectPins.Items() {
+		if item.IsExpired() {
+			continue
+		}
+		pin := item.Value()
+		prefix := strings.TrimSuffix(pin.Namespace, "/")
+		if reqPath != prefix && !strings.HasPrefix(reqPath, prefix+"/") {
+			continue
+		}
+		if best == nil || len(pin.Namespace) > len(best.Namespace) {
+			best = &pin
+		}
+	}
+	return best
+}
+
+// Get the servers a pin lets clients be redirected to.  If none are available, the
+// error response is sent and nil is returned.
+func getPinnedAds(ginCtx *gin.Context, pin *RedirectPin, originAds []common.ServerAd, cacheAds []common.ServerAd) []common.ServerAd {
+	ginCtx.Writer.Header().Set(redirectPinHeader, fmt.Sprintf("namespace=%s, server=%s, expires=%s",
+		pin.Namespace, pin.Server, pin.Expires.UTC().Format(time.RFC3339)))
+
+	pinned := []common.ServerAd{}
+	if pin.Server == redirectPinOrigin {
+		pinned = append(pinned, originAds...)
+	} else {
+		for _, ad := range cacheAds {
+			if ad.Name == pin.Server {
+				pinned = append(pinned, ad)
+			}
+		}
+	}
+	if len(pinned) == 0 {
+		ginCtx.String(http.StatusServiceUnavailable, "Redirects for %s are pinned to %s, which is currently unavailable\n", pin.Namespace, pin.Server)
+		return nil
+	}
+	log.Debugf("Redirecting within %s to %s as pinned by %s", pin.Namespace, pin.Server, pin.CreatedBy)
+	return pinned
+}
+
+// GET /api/v1.0/director_ui/pins
+//
+// List the active redirect pins
+func listRedirectPins(ctx *gin.Context) {
+	redirectPins.DeleteExpired()
+	pins := []RedirectPin{}
+	for _, item := range redirectPins.Items() {
+		if !item.IsExpired() {
+			pins = append(pins, item.Value())
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Namespace < pins[j].Namespace
+	})
+	ctx.JSON(http.StatusOK, pins)
+}
+
+// POST /api/v1.0/director_ui/pins
+//
+// Pin the redirects of a namespace to a cache or its origins for a while, replacing
+// any existing pin of the namespace
+func addRedirectPin(ctx *gin.Context) {
+	req := redirectPinRequest{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ttl := defaultRedirectPinTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ttl "+req.TTL+"; expected a positive duration such as 30m")
+			return
+		}
+	}
+	if ttl > maxRedirectPinTTL {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Pins can't last longer than %s", maxRedirectPinTTL))
+		return
+	}
+
+	namespace := path.Clean("/" + req.Namespace)
+	namespaceAd, originAds, cacheAds := GetAdsForPath(namespace)
+	if namespaceAd.Path == "" {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "No namespace found for "+namespace)
+		return
+	}
+	if req.Server != redirectPinOrigin {
+		found := false
+		for _, ad := range cacheAds {
+			if ad.Name == req.Server {
+				found = true
+				break
+			}
+		}
+		if !found {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeNoServers, "No cache named "+req.Server+" serves namespace "+namespaceAd.Path)
+			return
+		}
+	} else if len(originAds) == 0 {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeNoServers, "No origin currently exports namespace "+namespaceAd.Path)
+		return
+	}
+
+	pin := RedirectPin{
+		Namespace: namespace,
+		Server:    req.Server,
+		Reason:    req.Reason,
+		CreatedBy: ctx.GetString("User"),
+		Expires:   time.Now().Add(ttl),
+	}
+	redirectPins.Set(namespace, pin, ttl)
+	log.Infof("%s pinned the redirects of %s to %s for %s: %s", pin.CreatedBy, namespace, pin.Server, ttl, pin.Reason)
+	ctx.JSON(http.StatusOK, pin)
+}
+
+// DELETE /api/v1.0/director_ui/pins?namespace=<namespace>
+//
+// Remove the redirect pin of a namespace before it expires
+func deleteRedirectPin(ctx *gin.Context) {
+	namespace := ctx.Query("namespace")
+	if namespace == "" {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The namespace query parameter is required")
+		return
+	}
+	namespace = path.Clean("/" + namespace)
+	item := redirectPins.Get(namespace)
+	if item == nil || item.IsExpired() {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "No redirect pin for "+namespace)
+		return
+	}
+	redirectPins.Delete(namespace)
+	log.Infof("%s removed the redirect pin of %s", ctx.GetString("User"), namespace)
+	ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
+}
@@ -0,0 +1,71 @@
+package director
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestRedirectPins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() {
+		redirectPins.DeleteAll()
+	})
+
+	redirectPins.Set("/foo", RedirectPin{Namespace: "/foo", Server: "cache-a", Expires: time.Now().Add(time.Hour)}, time.Hour)
+	redirectPins.Set("/foo/bar", RedirectPin{Namespace: "/foo/bar", Server: redirectPinOrigin, Expires: time.Now().Add(time.Hour)}, time.Hour)
+	redirectPins.Set("/expired", RedirectPin{Namespace: "/expired", Server: "cache-a"}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	t.Run("longest-prefix", func(t *testing.T) {
+		pin := getRedirectPin("/foo/bar/baz.txt")
+		require.NotNil(t, pin)
+		assert.Equal(t, "/foo/bar", pin.Namespace)
+
+		pin = getRedirectPin("/foo/baz.txt")
+		require.NotNil(t, pin)
+		assert.Equal(t, "/foo", pin.Namespace)
+
+		assert.Nil(t, getRedirectPin("/foobar/baz.txt"))
+		assert.Nil(t, getRedirectPin("/expired/baz.txt"))
+	})
+
+	cacheA, _ := url.Parse("https://cache-a.example.com")
+	cacheB, _ := url.Parse("https://cache-b.example.com")
+	origin, _ := url.Parse("https://origin.example.com")
+	cacheAds := []common.ServerAd{{Name: "cache-a", URL: *cacheA}, {Name: "cache-b", URL: *cacheB}}
+	originAds := []common.ServerAd{{Name: "origin", URL: *origin}}
+
+	t.Run("pinned-to-cache", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ads := getPinnedAds(ctx, getRedirectPin("/foo/baz.txt"), originAds, cacheAds)
+		require.Len(t, ads, 1)
+		assert.Equal(t, "cache-a", ads[0].Name)
+		assert.Contains(t, w.Header().Get(redirectPinHeader), "server=cache-a")
+	})
+
+	t.Run("pinned-to-origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ads := getPinnedAds(ctx, getRedirectPin("/foo/bar/baz.txt"), originAds, cacheAds)
+		require.Len(t, ads, 1)
+		assert.Equal(t, "origin", ads[0].Name)
+	})
+
+	t.Run("pinned-server-unavailable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ads := getPinnedAds(ctx, getRedirectPin("/foo/baz.txt"), originAds, cacheAds[1:])
+		assert.Nil(t, ads)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
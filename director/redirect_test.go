@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-version"
 	"github.com/jellydator/ttlcache/v3"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -249,6 +250,85 @@ func TestDirectorRegistration(t *testing.T) {
 		teardown()
 	})
 
+	t.Run("namespace-token-V2", func(t *testing.T) {
+		c, r, w := setupContext()
+		pKey, nsToken, issuerURL := generateToken()
+		publicKey, err := jwk.PublicKeyOf(pKey)
+		assert.NoError(t, err, "Error creating public key from private key")
+
+		ar := setupMockCache(t, publicKey)
+		useMockCache(ar, issuerURL)
+
+		// The origin's own token is signed with a key the registry doesn't know for the namespace
+		_, originToken, _ := generateToken()
+
+		isurl := url.URL{}
+		isurl.Path = ts.URL
+
+		ad := common.OriginAdvertiseV2{
+			DataURL: "https://or-url.org",
+			Name:    "test",
+			Namespaces: []common.NamespaceAdV2{{
+				Path:   "/foo/bar",
+				Issuer: []common.TokenIssuer{{IssuerUrl: isurl}},
+			}},
+			NamespaceTokens: map[string]string{"/foo/bar": nsToken},
+		}
+
+		jsonad, err := json.Marshal(ad)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+
+		setupRequest(c, r, jsonad, originToken)
+
+		r.ServeHTTP(w, c.Request)
+
+		// The namespace is checked against its own token
+		assert.Equal(t, 200, w.Result().StatusCode, "Expected status code of 200")
+		assert.True(t, NamespaceAdContainsPath(ListNamespacesFromOrigins(), "/foo/bar"), "Couldn't find namespace in the director cache.")
+		teardown()
+	})
+
+	t.Run("degraded-V2-removes-registration", func(t *testing.T) {
+		pKey, token, issuerURL := generateToken()
+		publicKey, err := jwk.PublicKeyOf(pKey)
+		assert.NoError(t, err, "Error creating public key from private key")
+
+		ar := setupMockCache(t, publicKey)
+		useMockCache(ar, issuerURL)
+
+		isurl := url.URL{}
+		isurl.Path = ts.URL
+
+		ad := common.OriginAdvertiseV2{
+			DataURL: "https://or-url.org",
+			Name:    "test",
+			Namespaces: []common.NamespaceAdV2{{
+				Path:   "/foo/bar",
+				Issuer: []common.TokenIssuer{{IssuerUrl: isurl}},
+			}},
+		}
+		jsonad, err := json.Marshal(ad)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+
+		c, r, w := setupContext()
+		setupRequest(c, r, jsonad, token)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 200, w.Result().StatusCode, "Expected status code of 200")
+		assert.True(t, NamespaceAdContainsPath(ListNamespacesFromOrigins(), "/foo/bar"), "Coudln't find namespace in the director cache.")
+
+		// The same origin failing its health checks is no longer registered
+		ad.Degraded = "XRootD isn't responding"
+		jsonad, err = json.Marshal(ad)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+
+		c, r, w = setupContext()
+		setupRequest(c, r, jsonad, token)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 200, w.Result().StatusCode, "Expected status code of 200")
+		assert.False(t, NamespaceAdContainsPath(ListNamespacesFromOrigins(), "/foo/bar"), "The degraded origin's namespace is still in the director cache")
+		teardown()
+	})
+
 	// Now repeat the above test, but with an invalid token
 	t.Run("invalid-token-V1", func(t *testing.T) {
 		c, r, w := setupContext()
@@ -275,7 +355,9 @@ func TestDirectorRegistration(t *testing.T) {
 
 		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode, "Expected failing status code of 403")
 		body, _ := io.ReadAll(w.Result().Body)
-		assert.Equal(t, `{"error":"Authorization token verification failed"}`, string(body), "Failure wasn't because token verification failed")
+		problem := test_utils.ParseProblem(t, body)
+		assert.Equal(t, "Authorization token verification failed", problem.Detail, "Failure wasn't because token verification failed")
+		assert.Equal(t, common.ErrCodeInvalidToken, problem.Code)
 
 		namaspaceADs := ListNamespacesFromOrigins()
 		assert.False(t, NamespaceAdContainsPath(namaspaceADs, "/foo/bar"), "Found namespace in the director cache even if the token validation failed.")
@@ -310,7 +392,9 @@ func TestDirectorRegistration(t *testing.T) {
 
 		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode, "Expected failing status code of 403")
 		body, _ := io.ReadAll(w.Result().Body)
-		assert.Equal(t, `{"error":"Authorization token verification failed"}`, string(body), "Failure wasn't because token verification failed")
+		problem := test_utils.ParseProblem(t, body)
+		assert.Equal(t, "Authorization token verification failed", problem.Detail, "Failure wasn't because token verification failed")
+		assert.Equal(t, common.ErrCodeInvalidToken, problem.Code)
 
 		namaspaceADs := ListNamespacesFromOrigins()
 		assert.False(t, NamespaceAdContainsPath(namaspaceADs, "/foo/bar"), "Found namespace in the director cache even if the token validation failed.")
@@ -425,6 +509,76 @@ func TestDirectorRegistration(t *testing.T) {
 		assert.Equal(t, "", serverAds.Keys()[0].WebURL.String(), "WebURL in serverAds isn't empty with no WebURL provided in registration")
 		teardown()
 	})
+
+	t.Run("signed-ad-V2", func(t *testing.T) {
+		pKey, token, issuerURL := generateToken()
+		publicKey, err := jwk.PublicKeyOf(pKey)
+		assert.NoError(t, err, "Error creating public key from private key")
+		ar := setupMockCache(t, publicKey)
+		useMockCache(ar, issuerURL)
+
+		isurl := url.URL{}
+		isurl.Path = ts.URL
+		ad := common.OriginAdvertiseV2{DataURL: "https://or-url.org", Name: "test", Namespaces: []common.NamespaceAdV2{{Path: "/foo/bar",
+			Issuer: []common.TokenIssuer{{IssuerUrl: isurl}}}}}
+		jsonad, err := json.Marshal(ad)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+		signature, err := SignAdvertisement(jsonad, "/foo/bar", pKey)
+		require.NoError(t, err)
+
+		c, r, w := setupContext()
+		setupRequest(c, r, jsonad, token)
+		c.Request.Header.Set(AdvertisementSignatureHeader, signature)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 200, w.Result().StatusCode, "Expected status code of 200")
+		teardown()
+
+		// A proxy altering the advertisement invalidates the signature
+		tampered := ad
+		tampered.DataURL = "https://attacker.org"
+		tamperedAd, err := json.Marshal(tampered)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+		c, r, w = setupContext()
+		setupRequest(c, r, tamperedAd, token)
+		c.Request.Header.Set(AdvertisementSignatureHeader, signature)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 403, w.Result().StatusCode, "Expected status code of 403")
+		assert.Equal(t, 0, len(serverAds.Keys()), "Tampered advertisement was registered")
+
+		// The signature must come from a namespace the origin advertises
+		otherSignature, err := SignAdvertisement(jsonad, "/other", pKey)
+		require.NoError(t, err)
+		c, r, w = setupContext()
+		setupRequest(c, r, jsonad, token)
+		c.Request.Header.Set(AdvertisementSignatureHeader, otherSignature)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 403, w.Result().StatusCode, "Expected status code of 403")
+		teardown()
+	})
+
+	t.Run("unsigned-ad-rejected-when-required", func(t *testing.T) {
+		viper.Set("Director.RequireAdvertisementSignature", true)
+		defer viper.Set("Director.RequireAdvertisementSignature", false)
+		pKey, token, issuerURL := generateToken()
+		publicKey, err := jwk.PublicKeyOf(pKey)
+		assert.NoError(t, err, "Error creating public key from private key")
+		ar := setupMockCache(t, publicKey)
+		useMockCache(ar, issuerURL)
+
+		isurl := url.URL{}
+		isurl.Path = ts.URL
+		ad := common.OriginAdvertiseV2{DataURL: "https://or-url.org", Name: "test", Namespaces: []common.NamespaceAdV2{{Path: "/foo/bar",
+			Issuer: []common.TokenIssuer{{IssuerUrl: isurl}}}}}
+		jsonad, err := json.Marshal(ad)
+		assert.NoError(t, err, "Error marshalling OriginAdvertise")
+
+		c, r, w := setupContext()
+		setupRequest(c, r, jsonad, token)
+		r.ServeHTTP(w, c.Request)
+		assert.Equal(t, 403, w.Result().StatusCode, "Expected status code of 403")
+		assert.Equal(t, 0, len(serverAds.Keys()), "Unsigned advertisement was registered")
+		teardown()
+	})
 }
 
 func TestGetAuthzEscaped(t *testing.T) {
@@ -609,7 +763,7 @@ func TestDiscoverOriginCache(t *testing.T) {
 		r.ServeHTTP(w, req)
 
 		assert.Equal(t, 401, w.Code)
-		assert.Equal(t, `{"error":"Invalid token for accessing director's sevice discovery"}`, w.Body.String())
+		assert.Equal(t, "Invalid token for accessing director's sevice discovery", test_utils.ParseProblem(t, w.Body.Bytes()).Detail)
 	})
 	t.Run("token-present-with-wrong-issuer-should-give-401", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "/test", nil)
@@ -623,7 +777,7 @@ func TestDiscoverOriginCache(t *testing.T) {
 		r.ServeHTTP(w, req)
 
 		assert.Equal(t, 401, w.Code)
-		assert.Equal(t, `{"error":"Invalid token for accessing director's sevice discovery"}`, w.Body.String())
+		assert.Equal(t, "Invalid token for accessing director's sevice discovery", test_utils.ParseProblem(t, w.Body.Bytes()).Detail)
 	})
 	t.Run("token-present-valid-should-give-200-and-empty-array", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "/test", nil)
@@ -860,3 +1014,146 @@ func TestRedirects(t *testing.T) {
 		viper.Reset()
 	})
 }
+
+func TestCheckVersionSkew(t *testing.T) {
+	oldVersion := config.PelicanVersion
+	t.Cleanup(func() {
+		config.PelicanVersion = oldVersion
+	})
+
+	mustVersion := func(v string) *version.Version {
+		ver, err := version.NewVersion(v)
+		require.NoError(t, err)
+		return ver
+	}
+
+	config.PelicanVersion = "7.5.2"
+	assert.Empty(t, checkVersionSkew(mustVersion("7.5.0")))
+	assert.Empty(t, checkVersionSkew(mustVersion("7.4.8")))
+	assert.Contains(t, checkVersionSkew(mustVersion("7.6.0")), "newer than director version")
+	assert.Contains(t, checkVersionSkew(mustVersion("8.0.0")), "major version")
+	assert.Contains(t, checkVersionSkew(mustVersion("6.9.0")), "major version")
+
+	// Development builds of the director don't warn
+	config.PelicanVersion = "dev"
+	assert.Empty(t, checkVersionSkew(mustVersion("8.0.0")))
+}
+
+func TestRecordServerVersion(t *testing.T) {
+	oldVersion := config.PelicanVersion
+	t.Cleanup(func() {
+		config.PelicanVersion = oldVersion
+	})
+	config.PelicanVersion = "7.5.2"
+
+	ad := common.ServerAd{URL: url.URL{Scheme: "https", Host: "origin.example.com"}}
+	t.Cleanup(func() { deleteServerVersion(ad) })
+
+	// Warnings are only due when the server first advertises with a version
+	assert.True(t, recordServerVersion(ad, "7.6.0"))
+	assert.False(t, recordServerVersion(ad, "7.6.0"))
+	assert.True(t, recordServerVersion(ad, "7.5.1"))
+	assert.Equal(t, "7.5.1", getServerVersion(ad))
+
+	assert.Empty(t, getServerVersionSkew("7.5.1"))
+	assert.Contains(t, getServerVersionSkew("7.6.0"), "newer than director version")
+	assert.Empty(t, getServerVersionSkew(""))
+}
+
+func TestPausedNamespaces(t *testing.T) {
+	originAd := common.ServerAd{
+		Name: "test-origin",
+		URL:  url.URL{Scheme: "https", Host: "origin.example.com:8443"},
+		Type: common.OriginType,
+	}
+	t.Cleanup(func() {
+		deletePausedNamespaces(originAd)
+	})
+
+	recordPausedNamespaces(originAd, []string{"/paused/ns"})
+	assert.True(t, isPathPaused("/paused/ns"))
+	assert.True(t, isPathPaused("/paused/ns/baz.txt"))
+	assert.False(t, isPathPaused("/paused/nsfoo"))
+	assert.False(t, isPathPaused("/paused"))
+
+	// The namespace isn't advertised, so the director reports it as paused instead of missing
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/paused/ns/baz.txt", nil)
+	c.Request.Header.Set("X-Real-Ip", "128.104.153.60")
+	c.Request.Header.Set("User-Agent", "pelican-client/7.6.0")
+	RedirectToCache(c)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Resuming the namespace clears the paused state
+	recordPausedNamespaces(originAd, nil)
+	assert.False(t, isPathPaused("/paused/ns"))
+}
+
+func TestOriginFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serverAds.DeleteAll()
+	t.Cleanup(serverAds.DeleteAll)
+
+	nsAd := common.NamespaceAdV2{
+		PublicRead: true,
+		Caps:       common.Capabilities{PublicRead: true, Read: true, FallBackRead: true},
+		Path:       "/fallback",
+	}
+	originAd := common.ServerAd{
+		Name:               "origin",
+		URL:                url.URL{Scheme: "https", Host: "origin.example.com:8443"},
+		Type:               common.OriginType,
+		EnableFallbackRead: true,
+	}
+	cacheAd := common.ServerAd{
+		Name: "cache",
+		URL:  url.URL{Scheme: "https", Host: "cache.example.com:8443"},
+		Type: common.CacheType,
+	}
+	serverAds.Set(originAd, []common.NamespaceAdV2{nsAd}, ttlcache.DefaultTTL)
+	serverAds.Set(cacheAd, []common.NamespaceAdV2{nsAd}, ttlcache.DefaultTTL)
+
+	redirect := func(features string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1.0/director/object/fallback/foo.txt", nil)
+		c.Request.Header.Set("X-Real-Ip", "128.104.153.60")
+		c.Request.Header.Set("User-Agent", "pelican-client/7.6.0")
+		c.Request.Header.Set(common.ClientFeaturesHeader, features)
+		RedirectToCache(c)
+		return w
+	}
+
+	t.Run("listed-after-the-caches", func(t *testing.T) {
+		w := redirect("link-fallback, origin-fallback")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "cache.example.com:8443")
+		assert.Equal(t, `<http://cache.example.com:8443/fallback/foo.txt>; rel="duplicate"; pri=1, `+
+			`<http://origin.example.com:8443/fallback/foo.txt>; rel="duplicate"; pri=2; fallback="origin"`, w.Header().Get("Link"))
+	})
+
+	t.Run("left-out-for-older-clients", func(t *testing.T) {
+		w := redirect("link-fallback")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.NotContains(t, w.Header().Get("Link"), "origin.example.com")
+	})
+
+	serverAds.Delete(cacheAd)
+
+	t.Run("redirected-to-without-caches", func(t *testing.T) {
+		w := redirect("link-fallback")
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "origin.example.com:8443")
+		assert.NotContains(t, w.Header().Get("Link"), "fallback=")
+	})
+
+	t.Run("not-permitted", func(t *testing.T) {
+		serverAds.Delete(originAd)
+		originAd.EnableFallbackRead = false
+		serverAds.Set(originAd, []common.NamespaceAdV2{nsAd}, ttlcache.DefaultTTL)
+
+		w := redirect("link-fallback, origin-fallback")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,90 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// Check the persistent identifiers an origin advertised for a namespace; each must be in the
+// normalized form and resolve to a path within the namespace, as only the namespace's owner
+// may assign identifiers to its objects
+func validateNamespaceIdentifiers(namespace common.NamespaceAdV2) error {
+	prefix := strings.TrimSuffix(path.Clean(namespace.Path), "/")
+	for _, pid := range namespace.Identifiers {
+		if identifier, err := common.ParsePersistentIdentifier(pid.Identifier); err != nil {
+			return err
+		} else if identifier != pid.Identifier {
+			return fmt.Errorf("persistent identifier %s isn't normalized as %s", pid.Identifier, identifier)
+		}
+		if pid.Path != path.Clean(pid.Path) || (pid.Path != prefix && !strings.HasPrefix(pid.Path, prefix+"/")) {
+			return fmt.Errorf("the path %s of persistent identifier %s isn't within the namespace %s", pid.Path, pid.Identifier, namespace.Path)
+		}
+	}
+	return nil
+}
+
+// Find the paths the identifier was advertised for, sorted; more than one means namespaces
+// disagree on what the identifier refers to
+func lookupIdentifier(identifier string) []string {
+	paths := map[string]bool{}
+	for _, namespace := range ListNamespacesFromOrigins() {
+		for _, pid := range namespace.Identifiers {
+			if pid.Identifier == identifier {
+				paths[pid.Path] = true
+			}
+		}
+	}
+	found := make([]string, 0, len(paths))
+	for objectPath := range paths {
+		found = append(found, objectPath)
+	}
+	sort.Strings(found)
+	return found
+}
+
+// GET /api/v1.0/director/resolve?identifier=<identifier>
+//
+// Resolve a persistent identifier, such as a DOI, to the federation path its namespace's owner assigned it to
+func resolveIdentifier(ctx *gin.Context) {
+	identifier, err := common.ParsePersistentIdentifier(ctx.Query("identifier"))
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	paths := lookupIdentifier(identifier)
+	switch len(paths) {
+	case 0:
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "No namespace in the federation advertises the persistent identifier "+identifier)
+	case 1:
+		ctx.JSON(http.StatusOK, common.PersistentIdentifier{Identifier: identifier, Path: paths[0]})
+	default:
+		web_ui.WriteProblem(ctx, http.StatusConflict, common.ErrCodeConflict, fmt.Sprintf("The persistent identifier %s is advertised for more than one path: %s",
+			identifier, strings.Join(paths, ", ")))
+	}
+}
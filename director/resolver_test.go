@@ -0,0 +1,92 @@
+package director
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+func TestValidateNamespaceIdentifiers(t *testing.T) {
+	namespace := common.NamespaceAdV2{Path: "/foo", Identifiers: []common.PersistentIdentifier{
+		{Identifier: "doi:10.1234/abc", Path: "/foo/abc"},
+		{Identifier: "ark:12345/abc", Path: "/foo"},
+	}}
+	assert.NoError(t, validateNamespaceIdentifiers(namespace))
+
+	namespace.Identifiers = []common.PersistentIdentifier{{Identifier: "doi:10.1234/abc", Path: "/foobar/abc"}}
+	assert.ErrorContains(t, validateNamespaceIdentifiers(namespace), "isn't within the namespace")
+
+	namespace.Identifiers = []common.PersistentIdentifier{{Identifier: "doi:10.1234/abc", Path: "/foo/../bar"}}
+	assert.ErrorContains(t, validateNamespaceIdentifiers(namespace), "isn't within the namespace")
+
+	namespace.Identifiers = []common.PersistentIdentifier{{Identifier: "DOI:10.1234/ABC", Path: "/foo/abc"}}
+	assert.ErrorContains(t, validateNamespaceIdentifiers(namespace), "isn't normalized")
+
+	namespace.Identifiers = []common.PersistentIdentifier{{Identifier: "urn:isbn:12345", Path: "/foo/abc"}}
+	assert.Error(t, validateNamespaceIdentifiers(namespace))
+}
+
+func TestResolveIdentifier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serverAds.DeleteAll()
+	t.Cleanup(serverAds.DeleteAll)
+
+	originA, _ := url.Parse("https://origin-a.example.com")
+	originB, _ := url.Parse("https://origin-b.example.com")
+	serverAds.Set(common.ServerAd{Name: "origin-a", URL: *originA, Type: common.OriginType}, []common.NamespaceAdV2{{
+		Path: "/foo",
+		Identifiers: []common.PersistentIdentifier{
+			{Identifier: "doi:10.1234/abc", Path: "/foo/abc"},
+			{Identifier: "doi:10.1234/contested", Path: "/foo/contested"},
+		},
+	}}, ttlcache.DefaultTTL)
+	serverAds.Set(common.ServerAd{Name: "origin-b", URL: *originB, Type: common.OriginType}, []common.NamespaceAdV2{{
+		Path:        "/bar",
+		Identifiers: []common.PersistentIdentifier{{Identifier: "doi:10.1234/contested", Path: "/bar/contested"}},
+	}}, ttlcache.DefaultTTL)
+
+	router := gin.New()
+	router.GET("/api/v1.0/director/resolve", resolveIdentifier)
+	resolve := func(identifier string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1.0/director/resolve?identifier="+url.QueryEscape(identifier), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("resolves-case-insensitive-doi", func(t *testing.T) {
+		w := resolve("DOI:10.1234/ABC")
+		require.Equal(t, http.StatusOK, w.Code)
+		resolved := common.PersistentIdentifier{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resolved))
+		assert.Equal(t, common.PersistentIdentifier{Identifier: "doi:10.1234/abc", Path: "/foo/abc"}, resolved)
+	})
+
+	t.Run("unknown-identifier", func(t *testing.T) {
+		w := resolve("doi:10.1234/missing")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, common.ErrCodeNotFound, test_utils.ParseProblem(t, w.Body.Bytes()).Code)
+	})
+
+	t.Run("contested-identifier", func(t *testing.T) {
+		w := resolve("doi:10.1234/contested")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		problem := test_utils.ParseProblem(t, w.Body.Bytes())
+		assert.Contains(t, problem.Detail, "/bar/contested, /foo/contested")
+	})
+
+	t.Run("invalid-identifier", func(t *testing.T) {
+		w := resolve("doi:abc")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,241 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// The requests of a client site, i.e. the clients in one subnet
+	siteUsage struct {
+		// The site's requests the director is serving
+		active int
+		// The requests the site sent in the current and the previous window
+		current  int
+		previous int
+	}
+
+	// Tracks the redirect and stat requests in flight and recently sent by each
+	// client site, so the requests of a site flooding the director don't starve
+	// the other sites when the director is saturated
+	siteFairness struct {
+		// The requests in flight above which the director is saturated
+		limit int
+		// The length of the windows the sites' recent requests are counted over
+		window      time.Duration
+		windowStart time.Time
+		ipv4Bits    int
+		ipv6Bits    int
+
+		active        int
+		currentTotal  int
+		previousTotal int
+		sites         map[netip.Prefix]*siteUsage
+		mutex         sync.Mutex
+	}
+)
+
+// Create the tracker from the Director.Fairness parameters; returns nil if
+// Director.Fairness.MaxConcurrentRequests disables it
+func newSiteFairness() *siteFairness {
+	limit := param.Director_Fairness_MaxConcurrentRequests.GetInt()
+	if limit <= 0 {
+		return nil
+	}
+	window := param.Director_Fairness_Window.GetDuration()
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &siteFairness{
+		limit:       limit,
+		window:      window,
+		windowStart: time.Now(),
+		ipv4Bits:    min(max(param.Director_Fairness_IPv4PrefixLength.GetInt(), 0), 32),
+		ipv6Bits:    min(max(param.Director_Fairness_IPv6PrefixLength.GetInt(), 0), 128),
+		sites:       make(map[netip.Prefix]*siteUsage),
+	}
+}
+
+// The site, i.e. the subnet, of a client address
+func (f *siteFairness) siteOf(addr netip.Addr) netip.Prefix {
+	addr = addr.Unmap()
+	bits := f.ipv6Bits
+	if addr.Is4() {
+		bits = f.ipv4Bits
+	}
+	site, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return site
+}
+
+// Start new windows if the current one is over, forgetting the sites that went quiet.
+// Must be called with the mutex held.
+func (f *siteFairness) rotate(now time.Time) {
+	elapsed := now.Sub(f.windowStart)
+	if elapsed < f.window {
+		return
+	}
+	// After more than a full window without requests, the previous window was empty too
+	skipped := elapsed >= 2*f.window
+	f.previousTotal = 0
+	if !skipped {
+		f.previousTotal = f.currentTotal
+	}
+	f.currentTotal = 0
+	for site, usage := range f.sites {
+		usage.previous = 0
+		if !skipped {
+			usage.previous = usage.current
+		}
+		usage.current = 0
+		if usage.active == 0 && usage.previous == 0 {
+			delete(f.sites, site)
+		}
+	}
+	f.windowStart = now.Add(-(elapsed % f.window))
+}
+
+// The requests sent over the last window, interpolating the previous window's share
+func (f *siteFairness) recent(current, previous int, now time.Time) float64 {
+	remaining := 1 - float64(now.Sub(f.windowStart))/float64(f.window)
+	return float64(current) + float64(previous)*math.Max(remaining, 0)
+}
+
+// The number of requests a site may have in flight while the director is saturated:
+// an equal share of the limit among the sites with requests in flight, scaled down
+// for sites that sent more requests than the average site over the last window
+func (f *siteFairness) fairShare(usage *siteUsage, now time.Time) int {
+	activeSites := 0
+	for _, other := range f.sites {
+		if other.active > 0 || other == usage {
+			activeSites++
+		}
+	}
+	share := float64(f.limit) / float64(activeSites)
+	siteRecent := f.recent(usage.current, usage.previous, now)
+	averageRecent := f.recent(f.currentTotal, f.previousTotal, now) / float64(len(f.sites))
+	if siteRecent > averageRecent && siteRecent > 0 {
+		share *= averageRecent / siteRecent
+	}
+	return max(int(share), 1)
+}
+
+// Count a request from the site and decide whether the director serves it.  The
+// caller must call release for the site once it's done with an admitted request.
+func (f *siteFairness) admit(site netip.Prefix, now time.Time) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rotate(now)
+	usage, ok := f.sites[site]
+	if !ok {
+		usage = &siteUsage{}
+		f.sites[site] = usage
+	}
+	usage.current++
+	f.currentTotal++
+	if f.active >= f.limit && usage.active >= f.fairShare(usage, now) {
+		return false
+	}
+	usage.active++
+	f.active++
+	return true
+}
+
+func (f *siteFairness) release(site netip.Prefix) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if usage, ok := f.sites[site]; ok && usage.active > 0 {
+		usage.active--
+		f.active--
+	}
+}
+
+// Whether the request is one of the redirect or stat requests the fairness applies to;
+// the director's other APIs are cheap or served to administrators
+func isFairnessLimited(req *http.Request) bool {
+	reqPath := req.URL.Path
+	if strings.HasPrefix(reqPath, "/api/") {
+		return strings.HasPrefix(reqPath, "/api/v1.0/director/object/") ||
+			strings.HasPrefix(reqPath, "/api/v1.0/director/origin/") ||
+			strings.HasPrefix(reqPath, "/api/v1.0/director_ui/servers/origins/stat/")
+	}
+	return !strings.HasPrefix(reqPath, "/.well-known/")
+}
+
+// The address of the client, as reported by the proxy in front of the director if any
+func clientAddr(c *gin.Context) (netip.Addr, bool) {
+	if realIPs := c.Request.Header["X-Real-Ip"]; len(realIPs) > 0 {
+		addr, err := netip.ParseAddr(realIPs[0])
+		return addr, err == nil
+	}
+	addr, err := netip.ParseAddr(c.RemoteIP())
+	return addr, err == nil
+}
+
+// Create the middleware sharing the director between client sites when it's saturated,
+// or nil if Director.Fairness.MaxConcurrentRequests disables it.  Requests over a site's
+// fair share are answered with 429 Too Many Requests and a Retry-After of one window.
+func FairnessMiddleware() gin.HandlerFunc {
+	fairness := newSiteFairness()
+	if fairness == nil {
+		return nil
+	}
+	retryAfter := strconv.Itoa(int(math.Ceil(fairness.window.Seconds())))
+	log.Infof("Sharing the director between client sites once %d redirect and stat requests are in flight", fairness.limit)
+	return func(c *gin.Context) {
+		if !isFairnessLimited(c.Request) {
+			c.Next()
+			return
+		}
+		addr, ok := clientAddr(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		site := fairness.siteOf(addr)
+		if !fairness.admit(site, time.Now()) {
+			log.Debugf("Director is saturated; refusing a request from site %s, which is over its fair share", site)
+			metrics.PelicanDirectorFairnessRejectionsTotal.Inc()
+			c.Header("Retry-After", retryAfter)
+			web_ui.WriteProblem(c, http.StatusTooManyRequests, common.ErrCodeTooManyRequests,
+				"The director is busy and the requests from your site are over their fair share; retry after "+retryAfter+" seconds")
+			c.Abort()
+			return
+		}
+		defer fairness.release(site)
+		c.Next()
+	}
+}
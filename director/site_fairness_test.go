@@ -0,0 +1,166 @@
+package director
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSiteFairness(t *testing.T, limit int) *siteFairness {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Director.Fairness.MaxConcurrentRequests", limit)
+	viper.Set("Director.Fairness.Window", "10s")
+	viper.Set("Director.Fairness.IPv4PrefixLength", 24)
+	viper.Set("Director.Fairness.IPv6PrefixLength", 48)
+	fairness := newSiteFairness()
+	require.NotNil(t, fairness)
+	return fairness
+}
+
+func TestSiteOf(t *testing.T) {
+	fairness := newTestSiteFairness(t, 10)
+	assert.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), fairness.siteOf(netip.MustParseAddr("192.0.2.17")))
+	assert.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), fairness.siteOf(netip.MustParseAddr("::ffff:192.0.2.200")))
+	assert.Equal(t, netip.MustParsePrefix("2001:db8:1::/48"), fairness.siteOf(netip.MustParseAddr("2001:db8:1:2::1")))
+
+	viper.Set("Director.Fairness.MaxConcurrentRequests", 0)
+	assert.Nil(t, newSiteFairness())
+}
+
+func TestSiteFairnessAdmit(t *testing.T) {
+	heavy := netip.MustParsePrefix("192.0.2.0/24")
+	light := netip.MustParsePrefix("198.51.100.0/24")
+	now := time.Now()
+
+	t.Run("below-limit", func(t *testing.T) {
+		fairness := newTestSiteFairness(t, 4)
+		// A single site may use the whole director while it's not saturated
+		for i := 0; i < 4; i++ {
+			assert.True(t, fairness.admit(heavy, now))
+		}
+		// Once saturated, a site alone gets the whole limit as its share
+		assert.False(t, fairness.admit(heavy, now))
+		fairness.release(heavy)
+		assert.True(t, fairness.admit(heavy, now))
+	})
+
+	t.Run("saturated", func(t *testing.T) {
+		fairness := newTestSiteFairness(t, 4)
+		for i := 0; i < 100; i++ {
+			fairness.admit(heavy, now)
+		}
+		assert.Equal(t, 4, fairness.active)
+
+		// The light site is under its share, so it's served although the director is saturated
+		assert.True(t, fairness.admit(light, now))
+		assert.True(t, fairness.admit(light, now))
+		// The heavy site sent far more than the average site, so its share shrinks
+		fairness.release(heavy)
+		assert.False(t, fairness.admit(heavy, now))
+
+		// Once the director isn't saturated, the heavy site is served again
+		fairness.release(heavy)
+		fairness.release(light)
+		fairness.release(light)
+		assert.True(t, fairness.admit(heavy, now))
+	})
+
+	t.Run("windows", func(t *testing.T) {
+		fairness := newTestSiteFairness(t, 4)
+		for i := 0; i < 10; i++ {
+			fairness.admit(heavy, now)
+		}
+		for i := 0; i < 4; i++ {
+			fairness.release(heavy)
+		}
+		assert.Equal(t, 10, fairness.sites[heavy].current)
+
+		fairness.admit(heavy, now.Add(15*time.Second))
+		fairness.release(heavy)
+		assert.Equal(t, 10, fairness.sites[heavy].previous)
+		assert.Equal(t, 1, fairness.sites[heavy].current)
+
+		// Sites that went quiet for a whole window are forgotten
+		fairness.admit(light, now.Add(45*time.Second))
+		assert.NotContains(t, fairness.sites, heavy)
+		assert.Contains(t, fairness.sites, light)
+		assert.Zero(t, fairness.previousTotal)
+		assert.Equal(t, 1, fairness.currentTotal)
+	})
+}
+
+func TestIsFairnessLimited(t *testing.T) {
+	for _, tc := range []struct {
+		path    string
+		limited bool
+	}{
+		{"/foo/bar", true},
+		{"/api/v1.0/director/object/foo/bar", true},
+		{"/api/v1.0/director/origin/foo/bar", true},
+		{"/api/v1.0/director_ui/servers/origins/stat/foo/bar", true},
+		{"/api/v1.0/director/registerOrigin", false},
+		{"/api/v1.0/director_ui/servers", false},
+		{"/.well-known/openid-configuration", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		assert.Equal(t, tc.limited, isFairnessLimited(req), tc.path)
+	}
+}
+
+func TestFairnessMiddleware(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	gin.SetMode(gin.TestMode)
+	viper.Set("Director.Fairness.MaxConcurrentRequests", 1)
+	viper.Set("Director.Fairness.Window", "10s")
+	viper.Set("Director.Fairness.IPv4PrefixLength", 24)
+
+	fairness := FairnessMiddleware()
+	require.NotNil(t, fairness)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	engine := gin.New()
+	engine.Use(fairness)
+	engine.GET("/api/v1.0/director/object/*path", func(c *gin.Context) {
+		if c.Param("path") == "/block" {
+			started <- struct{}{}
+			<-unblock
+		}
+		c.Redirect(http.StatusTemporaryRedirect, "https://cache.example.com"+c.Param("path"))
+	})
+
+	request := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1.0/director/object"+path, nil)
+		req.Header.Set("X-Real-Ip", ip)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Saturate the director with a request from the first site
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- request("/block", "192.0.2.1") }()
+	<-started
+
+	// Another client of the same site is over the site's share
+	w := request("/foo", "192.0.2.2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	// A client of another site still gets its share
+	w = request("/foo", "198.51.100.1")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	close(unblock)
+	assert.Equal(t, http.StatusTemporaryRedirect, (<-done).Code)
+	w = request("/foo", "192.0.2.2")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+}
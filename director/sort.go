@@ -20,10 +20,14 @@ package director
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -33,6 +37,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -42,14 +47,14 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
 )
 
-const (
-	maxMindURL string = "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-City&license_key=%s&suffix=tar.gz"
-)
-
 var (
+	// A variable rather than a constant so that tests can point it at a mock server
+	maxMindURL = "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-City&license_key=%s&suffix=tar.gz"
+
 	maxMindReader atomic.Pointer[geoip2.Reader]
 )
 
@@ -63,18 +68,30 @@ type (
 )
 
 type Coordinate struct {
-	Lat  float64 `mapstructure:"lat"`
-	Long float64 `mapstructure:"long"`
+	Lat  float64 `mapstructure:"lat" json:"lat" yaml:"Lat"`
+	Long float64 `mapstructure:"long" json:"long" yaml:"Long"`
 }
 
 type GeoIPOverride struct {
-	IP         string     `mapstructure:"IP"`
-	Coordinate Coordinate `mapstructure:"Coordinate"`
+	IP         string     `mapstructure:"IP" json:"ip" yaml:"IP"`
+	Coordinate Coordinate `mapstructure:"Coordinate" json:"coordinate" yaml:"Coordinate"`
+}
+
+type geoIPOverrideMatcher struct {
+	prefix   netip.Prefix // Single IPs are stored as a full-length prefix
+	override GeoIPOverride
 }
 
 var invalidOverrideLogOnce = map[string]bool{}
 var geoIPOverrides []GeoIPOverride
 
+// Protects geoIPOverrides, runtimeGeoIPOverrides, and invalidOverrideLogOnce
+var geoIPOverridesMutex sync.Mutex
+
+// The parsed overrides, in match order, rebuilt whenever the overrides change so
+// that looking up an address on each redirect needs neither the lock nor parsing
+var geoIPOverrideMatchers atomic.Pointer[[]geoIPOverrideMatcher]
+
 func (me SwapMaps) Len() int {
 	return len(me)
 }
@@ -93,48 +110,72 @@ func (me SwapMaps) Swap(left, right int) {
 // NOTE: We don't return an error because if checkOverrides encounters an issue,
 // we still have GeoIP to fall back on.
 func checkOverrides(addr net.IP) (coordinate *Coordinate) {
-	// Unmarshal the values, but only the first time we run through this block
-	if geoIPOverrides == nil {
-		err := param.GeoIPOverrides.Unmarshal(&geoIPOverrides)
-		if err != nil {
-			log.Warningf("Error while unmarshaling GeoIP Overrides: %v", err)
-		}
+	if override := matchOverride(addr); override != nil {
+		return &override.Coordinate
 	}
+	return nil
+}
 
-	for _, geoIPOverride := range geoIPOverrides {
-		// Check for regular IP addresses before CIDR
-		overrideIP := net.ParseIP(geoIPOverride.IP)
-		if overrideIP == nil {
-			// The IP is malformed
-			if !invalidOverrideLogOnce[geoIPOverride.IP] && !strings.Contains(geoIPOverride.IP, "/") {
-				// Don't return here, because we have more to check.
-				// Do provide a notice to the user, however.
-				log.Warningf("Failed to parse configured GeoIPOverride address (%s). Unable to use for GeoIP resolution!", geoIPOverride.IP)
-				invalidOverrideLogOnce[geoIPOverride.IP] = true
-			}
-		}
-		if overrideIP.Equal(addr) {
-			return &geoIPOverride.Coordinate
+// Find the override matching the address, if any.  Overrides added at runtime
+// take precedence over the ones in the configuration.
+func matchOverride(addr net.IP) *GeoIPOverride {
+	matchers := geoIPOverrideMatchers.Load()
+	if matchers == nil {
+		// Unmarshal the values, but only the first time we run through this block
+		geoIPOverridesMutex.Lock()
+		loadGeoIPOverridesLocked()
+		matchers = geoIPOverrideMatchers.Load()
+		geoIPOverridesMutex.Unlock()
+	}
+
+	ip, ok := netip.AddrFromSlice(addr)
+	if !ok {
+		return nil
+	}
+	ip = ip.Unmap()
+	for _, matcher := range *matchers {
+		if matcher.prefix.Contains(ip) {
+			override := matcher.override
+			return &override
 		}
+	}
+	return nil
+}
 
-		// Alternatively, we can match by CIDR blocks
+// Parse the runtime and configured overrides into a new matcher snapshot.
+// Must be called with geoIPOverridesMutex held.
+func compileGeoIPOverridesLocked() {
+	matchers := make([]geoIPOverrideMatcher, 0, len(runtimeGeoIPOverrides)+len(geoIPOverrides))
+	for _, geoIPOverride := range append(append([]GeoIPOverride{}, runtimeGeoIPOverrides...), geoIPOverrides...) {
+		var prefix netip.Prefix
 		if strings.Contains(geoIPOverride.IP, "/") {
 			_, ipNet, err := net.ParseCIDR(geoIPOverride.IP)
 			if err != nil {
 				if !invalidOverrideLogOnce[geoIPOverride.IP] {
-					// Same reason as above for not returning.
 					log.Warningf("Failed to parse configured GeoIPOverride CIDR address (%s): %v. Unable to use for GeoIP resolution!", geoIPOverride.IP, err)
 					invalidOverrideLogOnce[geoIPOverride.IP] = true
 				}
 				continue
 			}
-			if ipNet.Contains(addr) {
-				return &geoIPOverride.Coordinate
+			addr, _ := netip.AddrFromSlice(ipNet.IP)
+			ones, _ := ipNet.Mask.Size()
+			prefix = netip.PrefixFrom(addr.Unmap(), ones)
+		} else {
+			overrideIP := net.ParseIP(geoIPOverride.IP)
+			if overrideIP == nil {
+				if !invalidOverrideLogOnce[geoIPOverride.IP] {
+					log.Warningf("Failed to parse configured GeoIPOverride address (%s). Unable to use for GeoIP resolution!", geoIPOverride.IP)
+					invalidOverrideLogOnce[geoIPOverride.IP] = true
+				}
+				continue
 			}
+			addr, _ := netip.AddrFromSlice(overrideIP)
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
 		}
+		matchers = append(matchers, geoIPOverrideMatcher{prefix: prefix, override: geoIPOverride})
 	}
-
-	return nil
+	geoIPOverrideMatchers.Store(&matchers)
 }
 
 func GetLatLong(addr netip.Addr) (lat float64, long float64, err error) {
@@ -176,8 +217,10 @@ func SortServers(addr netip.Addr, ads []common.ServerAd) ([]common.ServerAd, err
 			// going to be sorted after valid distances.
 			distances[idx] = SwapMap{1 + rand.Float64(), idx}
 		} else {
-			distances[idx] = SwapMap{distanceOnSphere(lat, long, ad.Latitude, ad.Longitude),
-				idx}
+			// Deprioritize caches that have been slow recently.  Capping the weighted distance at 1
+			// keeps it ahead of the servers without a valid distance.
+			distance := distanceOnSphere(lat, long, ad.Latitude, ad.Longitude) * throughputDistanceFactor(ad)
+			distances[idx] = SwapMap{math.Min(distance, 1), idx}
 		}
 	}
 	sort.Sort(distances)
@@ -188,42 +231,94 @@ func SortServers(addr netip.Addr, ads []common.ServerAd) ([]common.ServerAd, err
 	return resultAds, nil
 }
 
-func DownloadDB(localFile string) error {
-	err := os.MkdirAll(filepath.Dir(localFile), 0755)
-	if err != nil {
-		return err
-	}
-
-	var licenseKey string
+// Get the MaxMind license key from Director.MaxMindKeyFile or the PELICAN_MAXMINDKEY
+// environment variable
+func getMaxMindLicenseKey() (string, error) {
 	keyFile := param.Director_MaxMindKeyFile.GetString()
 	keyFromEnv := viper.GetString("MAXMINDKEY")
 	if keyFile != "" {
 		contents, err := os.ReadFile(keyFile)
 		if err != nil {
-			return err
+			return "", err
 		}
-		licenseKey = strings.TrimSpace(string(contents))
+		return strings.TrimSpace(string(contents)), nil
 	} else if keyFromEnv != "" {
-		licenseKey = keyFromEnv
-	} else {
-		return errors.New("A MaxMind key file must be specified in the config (Director.MaxMindKeyFile), in the environment (PELICAN_DIRECTOR_MAXMINDKEYFILE), or the key must be provided via the environment variable PELICAN_MAXMINDKEY)")
+		return keyFromEnv, nil
+	}
+	return "", errors.New("A MaxMind key file must be specified in the config (Director.MaxMindKeyFile), in the environment (PELICAN_DIRECTOR_MAXMINDKEYFILE), or the key must be provided via the environment variable PELICAN_MAXMINDKEY)")
+}
+
+// Get the expected SHA-256 checksum of the database archive.  MaxMind publishes it
+// in the `sha256sum` format, i.e. "<hex digest>  <archive name>"
+func getMaxMindChecksum(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url + ".sha256")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("Checksum download failed with status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", errors.New("Checksum file is empty")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// Download the GeoLite2 City database to localFile.  The archive's checksum is
+// verified and the extracted database is checked to be readable before it
+// replaces any existing database at localFile.
+func DownloadDB(localFile string) error {
+	err := os.MkdirAll(filepath.Dir(localFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	licenseKey, err := getMaxMindLicenseKey()
+	if err != nil {
+		return err
 	}
 
 	url := fmt.Sprintf(maxMindURL, licenseKey)
+	client := &http.Client{Transport: config.GetTransport(), Timeout: 10 * time.Minute}
+	expectedChecksum, err := getMaxMindChecksum(client, url)
+	if err != nil {
+		return errors.Wrap(err, "Failed to download the GeoIP database checksum")
+	}
+
 	localDir := filepath.Dir(localFile)
 	fileHandle, err := os.CreateTemp(localDir, filepath.Base(localFile)+".tmp")
 	if err != nil {
 		return err
 	}
 	defer fileHandle.Close()
-	resp, err := http.Get(url)
+	defer os.Remove(fileHandle.Name())
+	resp, err := client.Get(url)
 	if err != nil {
-		os.Remove(fileHandle.Name())
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("GeoIP database download failed with status %d", resp.StatusCode)
+	}
+
+	// The archive is small enough (~40MB) to hold in memory while its checksum is verified
+	archive, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(archive)
+	if hex.EncodeToString(checksum[:]) != expectedChecksum {
+		return errors.Errorf("Checksum of the downloaded GeoIP database (%s) does not match the expected checksum (%s)",
+			hex.EncodeToString(checksum[:]), expectedChecksum)
+	}
 
-	gz, err := gzip.NewReader(resp.Body)
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
 	if err != nil {
 		return err
 	}
@@ -241,7 +336,6 @@ func DownloadDB(localFile string) error {
 			continue
 		}
 		if _, err = io.Copy(fileHandle, tr); err != nil {
-			os.Remove(fileHandle.Name())
 			return err
 		}
 		foundDB = true
@@ -250,32 +344,87 @@ func DownloadDB(localFile string) error {
 	if !foundDB {
 		return errors.New("GeoIP database not found in downloaded resource")
 	}
+	if err = fileHandle.Close(); err != nil {
+		return err
+	}
+
+	// Don't replace a working database with one we can't read
+	reader, err := geoip2.Open(fileHandle.Name())
+	if err != nil {
+		return errors.Wrap(err, "Downloaded GeoIP database is not readable")
+	}
+	reader.Close()
+
 	if err = os.Rename(fileHandle.Name(), localFile); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Swap in a new GeoIP database reader.  The old reader is closed after a grace
+// period so that in-flight lookups against it can complete.
+func swapMaxMindReader(reader *geoip2.Reader) {
+	if oldReader := maxMindReader.Swap(reader); oldReader != nil {
+		time.AfterFunc(time.Minute, func() {
+			if err := oldReader.Close(); err != nil {
+				log.Debugln("Failed to close the old GeoIP database:", err)
+			}
+		})
+	}
+}
+
+// Download a fresh copy of the GeoIP database and hot-swap it into use
+func refreshMaxMindDB(localFile string) error {
+	if err := DownloadDB(localFile); err != nil {
+		return errors.Wrap(err, "Failed to download GeoIP database")
+	}
+	localReader, err := geoip2.Open(localFile)
+	if err != nil {
+		return errors.Wrap(err, "Failed to re-open GeoIP database")
+	}
+	swapMaxMindReader(localReader)
+	log.Infoln("Refreshed the GeoIP database at", localFile)
+	return nil
+}
+
+// The time until the database at localFile is due for a refresh, based on its modification time
+func timeUntilMaxMindRefresh(localFile string, refreshInterval time.Duration) time.Duration {
+	info, err := os.Stat(localFile)
+	if err != nil {
+		return 0
+	}
+	untilRefresh := time.Until(info.ModTime().Add(refreshInterval))
+	if untilRefresh < 0 {
+		return 0
+	}
+	return untilRefresh
+}
+
 func PeriodicMaxMindReload(ctx context.Context) {
-	// The MaxMindDB updates Tuesday/Thursday. While a free API key
-	// does get 1000 downloads a month, we might still want to change
-	// this eventually to guarantee we only update on those days...
+	// The MaxMindDB updates Tuesday/Thursday. A free API key gets a limited
+	// number of downloads a day, so the default refresh is weekly.
+	refreshInterval := param.Director_GeoIPRefreshInterval.GetDuration()
+	if refreshInterval <= 0 {
+		log.Warningln("Director.GeoIPRefreshInterval is not positive; the GeoIP database will not be refreshed")
+		return
+	}
+	if _, err := getMaxMindLicenseKey(); err != nil {
+		log.Infoln("No MaxMind license key is configured; the GeoIP database will not be refreshed automatically")
+		return
+	}
 
-	// Update once every other day
-	ticker := time.NewTicker(48 * time.Hour)
+	localFile := param.Director_GeoIPLocation.GetString()
+	timer := time.NewTimer(timeUntilMaxMindRefresh(localFile, refreshInterval))
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
-			localFile := param.Director_GeoIPLocation.GetString()
-			if err := DownloadDB(localFile); err != nil {
-				log.Warningln("Failed to download GeoIP database:", err)
+		case <-timer.C:
+			if err := refreshMaxMindDB(localFile); err != nil {
+				// Retry sooner than the full interval so a transient failure doesn't leave a stale database for a week
+				log.Warningln(err)
+				timer.Reset(time.Hour)
 			} else {
-				localReader, err := geoip2.Open(localFile)
-				if err != nil {
-					log.Warningln("Failed to re-open GeoIP database:", err)
-				} else {
-					maxMindReader.Store(localReader)
-				}
+				timer.Reset(refreshInterval)
 			}
 		case <-ctx.Done():
 			return
@@ -284,7 +433,6 @@ func PeriodicMaxMindReload(ctx context.Context) {
 }
 
 func InitializeDB(ctx context.Context) {
-	go PeriodicMaxMindReload(ctx)
 	localFile := param.Director_GeoIPLocation.GetString()
 	localReader, err := geoip2.Open(localFile)
 	if err != nil {
@@ -292,13 +440,12 @@ func InitializeDB(ctx context.Context) {
 		err = DownloadDB(localFile)
 		if err != nil {
 			log.Errorln("Failed to download GeoIP database!  Will not be available:", err)
-			return
-		}
-		localReader, err = geoip2.Open(localFile)
-		if err != nil {
+		} else if localReader, err = geoip2.Open(localFile); err != nil {
 			log.Errorln("Failed to reopen GeoIP database!  Will not be available:", err)
-			return
 		}
 	}
-	maxMindReader.Store(localReader)
+	if err == nil {
+		swapMaxMindReader(localReader)
+	}
+	go PeriodicMaxMindReload(ctx)
 }
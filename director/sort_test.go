@@ -1,14 +1,24 @@
 package director
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,6 +29,8 @@ var yamlMockup string
 
 func TestCheckOverrides(t *testing.T) {
 	viper.Reset()
+	resetGeoIPOverrides()
+	t.Cleanup(resetGeoIPOverrides)
 
 	// We'll also check that our logging feature responsibly reports
 	// what Pelican is telling the user.
@@ -105,5 +117,95 @@ func TestCheckOverrides(t *testing.T) {
 		require.Equal(t, expectedCoordinate.Long, coordinate.Long)
 	})
 
+	t.Run("test-runtime-override-update", func(t *testing.T) {
+		// Overrides added at runtime take effect on the next lookup and take precedence
+		geoIPOverridesMutex.Lock()
+		runtimeGeoIPOverrides = []GeoIPOverride{{IP: "10.0.0.0/28", Coordinate: Coordinate{Lat: 1, Long: 2}}}
+		compileGeoIPOverridesLocked()
+		geoIPOverridesMutex.Unlock()
+
+		coordinate := checkOverrides(net.ParseIP("10.0.0.5"))
+		require.NotNil(t, coordinate)
+		assert.Equal(t, 1.0, coordinate.Lat)
+		coordinate = checkOverrides(net.ParseIP("10.0.0.136"))
+		require.NotNil(t, coordinate)
+		assert.Equal(t, 43.073904, coordinate.Lat)
+	})
+
 	viper.Reset()
 }
+
+func TestDownloadDB(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("MAXMINDKEY", "test-key")
+
+	// An archive that contains a file with the database's name, but isn't a readable database
+	archiveBuf := &bytes.Buffer{}
+	gz := gzip.NewWriter(archiveBuf)
+	tw := tar.NewWriter(gz)
+	contents := []byte("not a maxmind database")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "GeoLite2-City_20240101/GeoLite2-City.mmdb", Mode: 0644, Size: int64(len(contents))}))
+	_, err := tw.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	archive := archiveBuf.Bytes()
+	digest := sha256.Sum256(archive)
+
+	checksum := hex.EncodeToString(digest[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.URL.Query().Get("license_key"))
+		switch r.URL.Query().Get("suffix") {
+		case "tar.gz":
+			_, _ = w.Write(archive)
+		case "tar.gz.sha256":
+			if checksum == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(checksum + "  GeoLite2-City_20240101.tar.gz\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	oldURL := maxMindURL
+	maxMindURL = server.URL + "/geoip_download?edition_id=GeoLite2-City&license_key=%s&suffix=tar.gz"
+	t.Cleanup(func() { maxMindURL = oldURL })
+
+	localFile := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	require.NoError(t, os.WriteFile(localFile, []byte("existing database"), 0644))
+
+	// In every failure case, the existing database must be left in place
+	assertUnchanged := func(t *testing.T) {
+		existing, err := os.ReadFile(localFile)
+		require.NoError(t, err)
+		assert.Equal(t, "existing database", string(existing))
+		entries, err := os.ReadDir(filepath.Dir(localFile))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "temporary download files should be cleaned up")
+	}
+
+	t.Run("unreadable-database", func(t *testing.T) {
+		err := DownloadDB(localFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not readable")
+		assertUnchanged(t)
+	})
+
+	t.Run("checksum-mismatch", func(t *testing.T) {
+		checksum = fmt.Sprintf("%064x", 0)
+		t.Cleanup(func() { checksum = hex.EncodeToString(digest[:]) })
+		err := DownloadDB(localFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not match")
+		assertUnchanged(t)
+	})
+
+	t.Run("missing-checksum", func(t *testing.T) {
+		checksum = ""
+		t.Cleanup(func() { checksum = hex.EncodeToString(digest[:]) })
+		err := DownloadDB(localFile)
+		require.Error(t, err)
+		assertUnchanged(t)
+	})
+}
@@ -0,0 +1,78 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+// Return the caches that serve already-cached objects while their origin is unavailable
+func getStaleCacheAds(cacheAds []common.ServerAd) []common.ServerAd {
+	staleAds := []common.ServerAd{}
+	for _, ad := range cacheAds {
+		if ad.ServeStale && ad.WebURL.Host != "" {
+			staleAds = append(staleAds, ad)
+		}
+	}
+	return staleAds
+}
+
+// The URL of the object at the cache's stale object API
+func getStaleRedirectURL(reqPath string, ad common.ServerAd) url.URL {
+	staleURL := ad.WebURL
+	staleURL.Path = strings.TrimSuffix(staleURL.Path, "/") + common.StaleObjectAPIPath + path.Clean("/"+reqPath)
+	staleURL.RawQuery = ""
+	return staleURL
+}
+
+// Redirect a client to the caches that serve objects of a namespace without an
+// available origin.  Cache misses are rejected by the cache rather than by the
+// director since only the cache knows what it holds.
+func redirectToStaleCache(ginCtx *gin.Context, caps clientCapabilities, reqPath string, ipAddr netip.Addr, staleAds []common.ServerAd) {
+	staleAds, err := SortServers(ipAddr, staleAds)
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, "Failed to determine server ordering")
+		return
+	}
+
+	varyOnClientFeatures(ginCtx)
+	if caps.supports(common.FeatureLinkFallback) {
+		linkHeader := []string{}
+		for idx, ad := range staleAds {
+			staleURL := getStaleRedirectURL(reqPath, ad)
+			linkHeader = append(linkHeader, fmt.Sprintf(`<%s>; rel="duplicate"; pri=%d`, staleURL.String(), idx+1))
+		}
+		ginCtx.Writer.Header()["Link"] = []string{strings.Join(linkHeader, ", ")}
+	}
+	ginCtx.Writer.Header().Set(common.OriginStatusHeader, "unavailable")
+
+	log.Debugf("No origin is available for %s; redirecting to %d caches serving cached objects", reqPath, len(staleAds))
+	redirectURL := getStaleRedirectURL(reqPath, staleAds[0])
+	ginCtx.Redirect(http.StatusTemporaryRedirect, redirectURL.String())
+}
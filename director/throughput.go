@@ -0,0 +1,158 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type cacheThroughputStats struct {
+	// The recent average download throughput, in bytes per second, of each cache keyed by the cache's URL
+	throughputs map[string]float64
+	// The median of the throughputs, which is the baseline caches are compared against
+	median float64
+}
+
+const (
+	// Bounds on a cache's throughput relative to the median, so that an outlier
+	// observation can't move a cache arbitrarily far in the ranking
+	minThroughputRatio = 0.1
+	maxThroughputRatio = 10.0
+)
+
+var (
+	cacheThroughputs      cacheThroughputStats
+	cacheThroughputsMutex sync.RWMutex
+
+	// A variable rather than a function so that tests can mock the monitoring data
+	queryCacheThroughputs = queryCacheThroughputsFromPrometheus
+)
+
+// Query the monitoring data the director's embedded Prometheus scrapes from the caches for
+// each cache's average download throughput over the window
+func queryCacheThroughputsFromPrometheus(ctx context.Context, window time.Duration) (map[string]float64, error) {
+	promWindow := fmt.Sprintf("%ds", int(window.Seconds()))
+	query := fmt.Sprintf(`sum by (server_url) (increase(xrootd_transfer_completed_read_bytes{server_type="%[1]s"}[%[2]s]))`+
+		` / (sum by (server_url) (increase(xrootd_transfer_completed_read_seconds{server_type="%[1]s"}[%[2]s])) > 0)`,
+		common.CacheType, promWindow)
+	vector, err := web_ui.QueryEmbeddedPrometheus(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	throughputs := make(map[string]float64, len(vector))
+	for _, sample := range vector {
+		serverUrl := sample.Metric.Get("server_url")
+		if serverUrl == "" || math.IsNaN(sample.F) || math.IsInf(sample.F, 0) || sample.F <= 0 {
+			continue
+		}
+		throughputs[serverUrl] = sample.F
+	}
+	return throughputs, nil
+}
+
+func setCacheThroughputs(throughputs map[string]float64) {
+	stats := cacheThroughputStats{throughputs: throughputs}
+	if len(throughputs) > 0 {
+		values := make([]float64, 0, len(throughputs))
+		for _, throughput := range throughputs {
+			values = append(values, throughput)
+		}
+		sort.Float64s(values)
+		if len(values)%2 == 1 {
+			stats.median = values[len(values)/2]
+		} else {
+			stats.median = (values[len(values)/2-1] + values[len(values)/2]) / 2
+		}
+	}
+
+	cacheThroughputsMutex.Lock()
+	defer cacheThroughputsMutex.Unlock()
+	cacheThroughputs = stats
+}
+
+func refreshCacheThroughputs(ctx context.Context) {
+	if param.Director_LoadWeighting_ThroughputWeight.GetInt() <= 0 {
+		setCacheThroughputs(nil)
+		return
+	}
+	throughputs, err := queryCacheThroughputs(ctx, param.Director_LoadWeighting_ThroughputWindow.GetDuration())
+	if err != nil {
+		// Keep the previous observations rather than forgetting every cache's history
+		log.Warningln("Failed to get the caches' throughput from the monitoring data; cache ranking will use the previous observations:", err)
+		return
+	}
+	log.Debugf("Updated the download throughput of %d caches", len(throughputs))
+	setCacheThroughputs(throughputs)
+}
+
+// Periodically recompute the caches' download throughput until the context is canceled
+func PeriodicThroughputReload(ctx context.Context) {
+	refreshInterval := param.Director_LoadWeighting_RefreshInterval.GetDuration()
+	if refreshInterval <= 0 {
+		log.Warningln("Director.LoadWeighting.RefreshInterval is not positive; falling back to 5m")
+		refreshInterval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshCacheThroughputs(ctx)
+		}
+	}
+}
+
+// Get the factor by which to scale the server's distance from the client based on its
+// throughput relative to the other caches.  Servers that are slower than the median are
+// pushed back and faster ones are pulled forward; servers without throughput
+// observations aren't affected.
+func throughputDistanceFactor(ad common.ServerAd) float64 {
+	if ad.Type != common.CacheType {
+		return 1
+	}
+	weight := float64(param.Director_LoadWeighting_ThroughputWeight.GetInt()) / 100
+	if weight <= 0 {
+		return 1
+	}
+
+	cacheThroughputsMutex.RLock()
+	throughput, ok := cacheThroughputs.throughputs[ad.URL.String()]
+	median := cacheThroughputs.median
+	cacheThroughputsMutex.RUnlock()
+	if !ok || median <= 0 {
+		return 1
+	}
+
+	ratio := math.Min(math.Max(throughput/median, minThroughputRatio), maxThroughputRatio)
+	return math.Pow(1/ratio, weight)
+}
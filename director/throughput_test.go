@@ -0,0 +1,104 @@
+package director
+
+import (
+	"context"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestThroughputWeightedSort(t *testing.T) {
+	viper.Reset()
+	geoIPOverridesMutex.Lock()
+	// Put the client in Madison, WI
+	geoIPOverrides = []GeoIPOverride{{IP: "192.0.2.1", Coordinate: Coordinate{Lat: 43.073904, Long: -89.384859}}}
+	runtimeGeoIPOverrides = []GeoIPOverride{}
+	compileGeoIPOverridesLocked()
+	geoIPOverridesMutex.Unlock()
+	t.Cleanup(func() {
+		resetGeoIPOverrides()
+		setCacheThroughputs(nil)
+		viper.Reset()
+	})
+
+	nearCache := common.ServerAd{
+		Name:      "milwaukee",
+		URL:       url.URL{Scheme: "https", Host: "milwaukee.example.com:8443"},
+		Type:      common.CacheType,
+		Latitude:  43.038902,
+		Longitude: -87.906471,
+	}
+	farCache := common.ServerAd{
+		Name:      "chicago",
+		URL:       url.URL{Scheme: "https", Host: "chicago.example.com:8443"},
+		Type:      common.CacheType,
+		Latitude:  41.878113,
+		Longitude: -87.629799,
+	}
+	unobservedCache := common.ServerAd{
+		Name:      "denver",
+		URL:       url.URL{Scheme: "https", Host: "denver.example.com:8443"},
+		Type:      common.CacheType,
+		Latitude:  39.739235,
+		Longitude: -104.990250,
+	}
+	ads := []common.ServerAd{unobservedCache, farCache, nearCache}
+	clientAddr := netip.MustParseAddr("192.0.2.1")
+
+	sortedNames := func() []string {
+		sorted, err := SortServers(clientAddr, ads)
+		require.NoError(t, err)
+		names := make([]string, 0, len(sorted))
+		for _, ad := range sorted {
+			names = append(names, ad.Name)
+		}
+		return names
+	}
+
+	t.Run("no-observations-sorts-by-distance", func(t *testing.T) {
+		viper.Set("Director.LoadWeighting.ThroughputWeight", 50)
+		assert.Equal(t, []string{"milwaukee", "chicago", "denver"}, sortedNames())
+	})
+
+	t.Run("slow-cache-is-deprioritized", func(t *testing.T) {
+		viper.Set("Director.LoadWeighting.ThroughputWeight", 50)
+		setCacheThroughputs(map[string]float64{
+			nearCache.URL.String(): 1e6,
+			farCache.URL.String():  1e8,
+		})
+		assert.Equal(t, []string{"chicago", "milwaukee", "denver"}, sortedNames())
+		// Origins aren't affected by the cache throughputs
+		assert.Equal(t, 1.0, throughputDistanceFactor(common.ServerAd{URL: nearCache.URL, Type: common.OriginType}))
+	})
+
+	t.Run("zero-weight-disables-weighting", func(t *testing.T) {
+		viper.Set("Director.LoadWeighting.ThroughputWeight", 0)
+		assert.Equal(t, []string{"milwaukee", "chicago", "denver"}, sortedNames())
+	})
+
+	t.Run("failed-refresh-keeps-observations", func(t *testing.T) {
+		viper.Set("Director.LoadWeighting.ThroughputWeight", 50)
+		oldQuery := queryCacheThroughputs
+		t.Cleanup(func() { queryCacheThroughputs = oldQuery })
+
+		queryCacheThroughputs = func(ctx context.Context, window time.Duration) (map[string]float64, error) {
+			return nil, errors.New("Prometheus is not running")
+		}
+		refreshCacheThroughputs(context.Background())
+		assert.Equal(t, []string{"chicago", "milwaukee", "denver"}, sortedNames())
+
+		queryCacheThroughputs = func(ctx context.Context, window time.Duration) (map[string]float64, error) {
+			return map[string]float64{nearCache.URL.String(): 1e8, farCache.URL.String(): 1e6}, nil
+		}
+		refreshCacheThroughputs(context.Background())
+		assert.Equal(t, []string{"milwaukee", "chicago", "denver"}, sortedNames())
+	})
+}
@@ -0,0 +1,203 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	topologyNodeType string
+
+	topologyRelation string
+
+	topologyRequest struct {
+		Format string `form:"format"` // "json" or "dot"
+	}
+
+	// A node in the federation topology graph. It is either a server
+	// (origin or cache) or a namespace prefix
+	topologyNode struct {
+		ID        string           `json:"id"`
+		Type      topologyNodeType `json:"type"`
+		Name      string           `json:"name"`
+		URL       string           `json:"url,omitempty"`
+		WebURL    string           `json:"webUrl,omitempty"`
+		Latitude  float64          `json:"latitude,omitempty"`
+		Longitude float64          `json:"longitude,omitempty"`
+		// For servers, the result of the most recent director test.
+		// One of "ok", "error", or "unknown"
+		Health        string `json:"health,omitempty"`
+		HealthMessage string `json:"healthMessage,omitempty"`
+		PublicRead    bool   `json:"publicRead,omitempty"`
+	}
+
+	// A directed edge from a server to a namespace
+	topologyEdge struct {
+		From     string           `json:"from"`
+		To       string           `json:"to"`
+		Relation topologyRelation `json:"relation"`
+	}
+
+	federationTopology struct {
+		Nodes []topologyNode `json:"nodes"`
+		Edges []topologyEdge `json:"edges"`
+	}
+)
+
+const (
+	topologyOriginNode    topologyNodeType = "origin"
+	topologyCacheNode     topologyNodeType = "cache"
+	topologyNamespaceNode topologyNodeType = "namespace"
+
+	topologyExports topologyRelation = "exports" // origin -> namespace
+	topologyServes  topologyRelation = "serves"  // cache -> namespace
+)
+
+func topologyServerID(ad common.ServerAd) string {
+	return strings.ToLower(string(ad.Type)) + ":" + ad.Name
+}
+
+func topologyNamespaceID(prefix string) string {
+	return "namespace:" + prefix
+}
+
+// Build the current federation topology from the server ads known to the director
+func getFederationTopology() federationTopology {
+	serverAdMutex.RLock()
+	items := serverAds.Items()
+	serverAdMutex.RUnlock()
+
+	topo := federationTopology{
+		Nodes: make([]topologyNode, 0),
+		Edges: make([]topologyEdge, 0),
+	}
+	namespaces := make(map[string]topologyNode)
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		ad := item.Key()
+		node := topologyNode{
+			ID:        topologyServerID(ad),
+			Name:      ad.Name,
+			URL:       ad.URL.String(),
+			WebURL:    ad.WebURL.String(),
+			Latitude:  ad.Latitude,
+			Longitude: ad.Longitude,
+			Health:    "unknown",
+		}
+		relation := topologyServes
+		if ad.Type == common.OriginType {
+			node.Type = topologyOriginNode
+			relation = topologyExports
+		} else {
+			node.Type = topologyCacheNode
+		}
+		if result, ok := getDirectorTestResult(ad); ok {
+			node.Health = result.Status
+			node.HealthMessage = result.Message
+		}
+		topo.Nodes = append(topo.Nodes, node)
+
+		for _, ns := range item.Value() {
+			nsID := topologyNamespaceID(ns.Path)
+			if _, exists := namespaces[nsID]; !exists {
+				namespaces[nsID] = topologyNode{
+					ID:         nsID,
+					Type:       topologyNamespaceNode,
+					Name:       ns.Path,
+					PublicRead: ns.Caps.PublicRead,
+				}
+			}
+			topo.Edges = append(topo.Edges, topologyEdge{From: node.ID, To: nsID, Relation: relation})
+		}
+	}
+	for _, nsNode := range namespaces {
+		topo.Nodes = append(topo.Nodes, nsNode)
+	}
+
+	// Sort the result so that the output is stable across requests
+	sort.Slice(topo.Nodes, func(i, j int) bool { return topo.Nodes[i].ID < topo.Nodes[j].ID })
+	sort.Slice(topo.Edges, func(i, j int) bool {
+		if topo.Edges[i].From == topo.Edges[j].From {
+			return topo.Edges[i].To < topo.Edges[j].To
+		}
+		return topo.Edges[i].From < topo.Edges[j].From
+	})
+	return topo
+}
+
+// Render the topology in the graphviz DOT language
+func (topo federationTopology) toDOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph federation {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	for _, node := range topo.Nodes {
+		shape := "ellipse"
+		color := "black"
+		switch node.Type {
+		case topologyOriginNode:
+			shape = "box"
+		case topologyCacheNode:
+			shape = "component"
+		case topologyNamespaceNode:
+			shape = "folder"
+		}
+		switch node.Health {
+		case "ok":
+			color = "green"
+		case "error":
+			color = "red"
+		}
+		sb.WriteString(fmt.Sprintf("\t%q [label=%q, shape=%s, color=%s];\n", node.ID, node.Name, shape, color))
+	}
+	for _, edge := range topo.Edges {
+		sb.WriteString(fmt.Sprintf("\t%q -> %q [label=%q];\n", edge.From, edge.To, string(edge.Relation)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Export the federation topology as JSON (default) or in graphviz DOT format
+//
+// GET /api/v1.0/director_ui/topology?format=dot
+func getTopology(ctx *gin.Context) {
+	queryParams := topologyRequest{}
+	if ctx.ShouldBindQuery(&queryParams) != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
+		return
+	}
+	topo := getFederationTopology()
+	switch strings.ToLower(queryParams.Format) {
+	case "", "json":
+		ctx.JSON(http.StatusOK, topo)
+	case "dot":
+		ctx.Data(http.StatusOK, "text/vnd.graphviz", []byte(topo.toDOT()))
+	default:
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid format. Supported formats are 'json' and 'dot'")
+	}
+}
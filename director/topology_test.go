@@ -0,0 +1,82 @@
+package director
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTopology(t *testing.T) {
+	router := gin.Default()
+	router.GET("/topology", getTopology)
+
+	// Director test results are keyed by server URL, so the mock ads need distinct ones
+	originAd := mockOriginServerAd
+	originAd.URL = url.URL{Scheme: "https", Host: "origin.example.com"}
+	cacheAd := mockCacheServerAd
+	cacheAd.URL = url.URL{Scheme: "https", Host: "cache.example.com"}
+
+	func() {
+		serverAdMutex.Lock()
+		defer serverAdMutex.Unlock()
+		serverAds.DeleteAll()
+		serverAds.Set(originAd, mockNamespaceAds(2, "origin1"), ttlcache.DefaultTTL)
+		serverAds.Set(cacheAd, mockNamespaceAds(1, "origin1"), ttlcache.DefaultTTL)
+	}()
+	recordDirectorTestResult(originAd, "ok", "")
+	t.Cleanup(func() {
+		deleteDirectorTestResult(originAd)
+		serverAdMutex.Lock()
+		defer serverAdMutex.Unlock()
+		serverAds.DeleteAll()
+	})
+
+	t.Run("json-format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/topology", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, 200, w.Code)
+
+		topo := federationTopology{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &topo))
+		// One origin, one cache, and two distinct namespaces
+		assert.Len(t, topo.Nodes, 4)
+		assert.Len(t, topo.Edges, 3)
+
+		for _, node := range topo.Nodes {
+			switch node.Type {
+			case topologyOriginNode:
+				assert.Equal(t, "ok", node.Health)
+			case topologyCacheNode:
+				assert.Equal(t, "unknown", node.Health)
+			}
+		}
+	})
+
+	t.Run("dot-format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/topology?format=dot", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, 200, w.Code)
+
+		body := w.Body.String()
+		assert.True(t, strings.HasPrefix(body, "digraph federation {"))
+		assert.Contains(t, body, `"origin:test-origin-server" -> "namespace:/foo/bar/origin1/0" [label="exports"];`)
+		assert.Contains(t, body, `"cache:test-cache-server" -> "namespace:/foo/bar/origin1/0" [label="serves"];`)
+	})
+
+	t.Run("invalid-format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/topology?format=xml", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	})
+}
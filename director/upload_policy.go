@@ -0,0 +1,77 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// Check whether the namespace's upload policy permits writing the object at reqPath. On a
+// violation, the error is sent to the client and false is returned.
+func checkUploadPolicy(ginCtx *gin.Context, namespaceAd common.NamespaceAdV2, reqPath string) bool {
+	policy := namespaceAd.UploadPolicy
+	if policy == nil {
+		return true
+	}
+
+	if !policy.AllowsName(namespaceAd.Path, reqPath) {
+		web_ui.WriteProblem(ginCtx, http.StatusForbidden, common.ErrCodeUploadPolicyViolated, fmt.Sprintf("The namespace %s only accepts objects whose names match one of: %s",
+			namespaceAd.Path, strings.Join(policy.AllowedNamePatterns, ", ")))
+		return false
+	}
+
+	if policy.MaxObjectSize > 0 {
+		size := ginCtx.Request.ContentLength
+		if sizeStr := ginCtx.GetHeader(common.ObjectSizeHeader); sizeStr != "" {
+			var err error
+			size, err = strconv.ParseInt(sizeStr, 10, 64)
+			if err != nil || size < 0 {
+				web_ui.WriteProblem(ginCtx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid %s header: %q", common.ObjectSizeHeader, sizeStr))
+				return false
+			}
+		}
+		if !policy.AllowsSize(size) {
+			web_ui.WriteProblem(ginCtx, http.StatusRequestEntityTooLarge, common.ErrCodeUploadPolicyViolated, fmt.Sprintf("The object is %d bytes but the namespace %s accepts objects of at most %d bytes",
+				size, namespaceAd.Path, policy.MaxObjectSize))
+			return false
+		}
+	}
+	return true
+}
+
+// Check whether the namespace's write mode permits writing the object at reqPath.  The versions
+// of the objects in a versioned namespace are made by its origin, so clients can't write them.
+// Overwrites of objects in an immutable namespace are refused by the origin, which knows what
+// objects exist.  On a violation, the error is sent to the client and false is returned.
+func checkWriteMode(ginCtx *gin.Context, namespaceAd common.NamespaceAdV2, reqPath string) bool {
+	if namespaceAd.WriteMode == common.WriteModeVersioned && common.IsObjectVersionPath(reqPath) {
+		web_ui.WriteProblem(ginCtx, http.StatusConflict, common.ErrCodeConflict, fmt.Sprintf("The namespace %s is versioned; %s names a version of an object, which is kept by the origin when the object is written",
+			namespaceAd.Path, reqPath))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package director
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+func TestCheckUploadPolicy(t *testing.T) {
+	nsAd := common.NamespaceAdV2{
+		Path: "/foo/uploads",
+		UploadPolicy: &common.UploadPolicy{
+			Prefix:              "/foo/uploads",
+			MaxObjectSize:       1024,
+			AllowedNamePatterns: []string{"*.csv", "results/*.tar.gz"},
+		},
+	}
+
+	check := func(reqPath string, contentLength int64, sizeHeader string) (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("PUT", "/api/v1.0/director/origin"+reqPath, strings.NewReader(""))
+		c.Request.ContentLength = contentLength
+		if sizeHeader != "" {
+			c.Request.Header.Set(common.ObjectSizeHeader, sizeHeader)
+		}
+		return checkUploadPolicy(c, nsAd, reqPath), w
+	}
+
+	t.Run("allowed-names", func(t *testing.T) {
+		ok, _ := check("/foo/uploads/data.csv", 0, "")
+		assert.True(t, ok)
+		ok, _ = check("/foo/uploads/some/dir/data.csv", 0, "")
+		assert.True(t, ok)
+		ok, _ = check("/foo/uploads/results/run1.tar.gz", 0, "")
+		assert.True(t, ok)
+	})
+
+	t.Run("denied-names", func(t *testing.T) {
+		ok, w := check("/foo/uploads/movie.mkv", 0, "")
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "*.csv, results/*.tar.gz")
+
+		// Patterns with a slash are anchored at the export
+		ok, w = check("/foo/uploads/other/results/run1.tar.gz", 0, "")
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("object-size", func(t *testing.T) {
+		ok, _ := check("/foo/uploads/data.csv", 1024, "")
+		assert.True(t, ok)
+
+		ok, w := check("/foo/uploads/data.csv", 1025, "")
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		// The client-reported size takes precedence over the (usually empty) request body
+		ok, w = check("/foo/uploads/data.csv", 0, "4096")
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		ok, w = check("/foo/uploads/data.csv", 0, "lots")
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no-policy", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("PUT", "/api/v1.0/director/origin/foo/bar/movie.mkv", nil)
+		assert.True(t, checkUploadPolicy(c, common.NamespaceAdV2{Path: "/foo/bar"}, "/foo/bar/movie.mkv"))
+	})
+}
+
+func TestCheckWriteMode(t *testing.T) {
+	check := func(writeMode common.WriteMode, reqPath string) (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("PUT", "/api/v1.0/director/origin"+reqPath, nil)
+		return checkWriteMode(c, common.NamespaceAdV2{Path: "/foo", WriteMode: writeMode}, reqPath), w
+	}
+
+	ok, _ := check(common.WriteModeVersioned, "/foo/data.csv")
+	assert.True(t, ok)
+	ok, w := check(common.WriteModeVersioned, "/foo/data.csv.v2")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, common.ErrCodeConflict, test_utils.ParseProblem(t, w.Body.Bytes()).Code)
+
+	// Only versioned namespaces have versions
+	ok, _ = check(common.WriteModeMutable, "/foo/data.csv.v2")
+	assert.True(t, ok)
+	ok, _ = check("", "/foo/data.csv.v2")
+	assert.True(t, ok)
+}
@@ -0,0 +1,66 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+// Return the caches that accept uploads they write back to the origin
+func getWriteBackCacheAds(cacheAds []common.ServerAd) []common.ServerAd {
+	writeBackAds := []common.ServerAd{}
+	for _, ad := range cacheAds {
+		if ad.WriteBack && ad.WebURL.Host != "" {
+			writeBackAds = append(writeBackAds, ad)
+		}
+	}
+	return writeBackAds
+}
+
+// The URL of the object at the cache's write-back API
+func getWriteBackRedirectURL(reqPath string, ad common.ServerAd) url.URL {
+	writeBackURL := ad.WebURL
+	writeBackURL.Path = strings.TrimSuffix(writeBackURL.Path, "/") + common.WriteBackAPIPath + path.Clean("/"+reqPath)
+	writeBackURL.RawQuery = ""
+	return writeBackURL
+}
+
+// Redirect an upload to the nearest cache writing objects back to their origin.  Unlike
+// the origins, the cache's write-back API takes the token in the Authorization header only,
+// so the redirect has no authz query.
+func redirectToWriteBackCache(ginCtx *gin.Context, reqPath string, ipAddr netip.Addr, writeBackAds []common.ServerAd) {
+	writeBackAds, err := SortServers(ipAddr, writeBackAds)
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, "Failed to determine server ordering")
+		return
+	}
+
+	log.Debugf("Redirecting the upload of %s to cache %s for write-back", reqPath, writeBackAds[0].Name)
+	redirectURL := getWriteBackRedirectURL(reqPath, writeBackAds[0])
+	ginCtx.Redirect(http.StatusTemporaryRedirect, redirectURL.String())
+}
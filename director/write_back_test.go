@@ -0,0 +1,47 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package director
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestWriteBackRedirect(t *testing.T) {
+	cacheAds := []common.ServerAd{
+		{Name: "plain", WebURL: url.URL{Scheme: "https", Host: "plain.example.com:8444"}},
+		{Name: "no-web-url", WriteBack: true},
+		{Name: "write-back", WriteBack: true, WebURL: url.URL{Scheme: "https", Host: "cache.example.com:8444", RawQuery: "foo=bar"}},
+	}
+
+	writeBackAds := getWriteBackCacheAds(cacheAds)
+	require.Len(t, writeBackAds, 1)
+	assert.Equal(t, "write-back", writeBackAds[0].Name)
+	assert.Empty(t, getWriteBackCacheAds(cacheAds[:1]))
+
+	redirectURL := getWriteBackRedirectURL("/foo/bar/../data.csv", writeBackAds[0])
+	assert.Equal(t, "https://cache.example.com:8444"+common.WriteBackAPIPath+"/foo/data.csv", redirectURL.String())
+	// The ad itself is left alone
+	assert.Equal(t, "", writeBackAds[0].WebURL.Path)
+}
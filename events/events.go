@@ -0,0 +1,189 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+// Package events is the publish/subscribe bus the components of a server process
+// announce what happened through, so that e.g. the web UI can follow a registry
+// approval or an XRootD restart without reaching into the package doing it.
+package events
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// The type of an event, also the SSE event name the web UI streams it under
+	Type string
+
+	// An event published on the bus; one of the typed events below
+	Event interface {
+		Type() Type
+	}
+
+	// A published event with the time it was published at
+	Envelope struct {
+		Type  Type      `json:"type"`
+		Time  time.Time `json:"time"`
+		Event Event     `json:"data"`
+	}
+
+	// The director received the advertisement of an origin or cache
+	AdReceived struct {
+		ServerName string   `json:"serverName"`
+		ServerType string   `json:"serverType"`
+		URL        string   `json:"url"`
+		WebURL     string   `json:"webUrl,omitempty"`
+		Namespaces []string `json:"namespaces"`
+	}
+
+	// A registry administrator approved the registration of a namespace
+	NamespaceApproved struct {
+		ID         int    `json:"id"`
+		Prefix     string `json:"prefix"`
+		ApprovedBy string `json:"approvedBy"`
+	}
+
+	// The XRootD daemons, e.g. xrootd and cmsd, were restarted
+	XrootdRestarted struct {
+		Daemons []string `json:"daemons"`
+		Error   string   `json:"error,omitempty"` // Set if a daemon failed to relaunch
+	}
+
+	// The health status of a server component changed
+	HealthChanged struct {
+		Component      string `json:"component"`
+		Status         string `json:"status"`
+		PreviousStatus string `json:"previousStatus,omitempty"` // Empty if the component had no status yet
+		Message        string `json:"message,omitempty"`
+	}
+
+	// The server's issuer key was replaced with a new one; the previous key is
+	// still published for Server.IssuerKeyRotationOverlap
+	IssuerKeyRotated struct {
+		KeyID         string `json:"keyId"`
+		PreviousKeyID string `json:"previousKeyId"`
+	}
+
+	// A bus delivering the events published on it to its subscribers.  Publishing
+	// never blocks: a subscriber too slow to keep up misses events.
+	Bus struct {
+		mutex       sync.RWMutex
+		subscribers map[*Subscription]struct{}
+	}
+
+	// The events of the types a subscriber asked for; Close must be called once
+	// the subscriber is done with it
+	Subscription struct {
+		bus   *Bus
+		types map[Type]struct{} // Nil for all types
+		ch    chan Envelope
+		once  sync.Once
+	}
+)
+
+const (
+	AdReceivedType        Type = "ad_received"
+	NamespaceApprovedType Type = "namespace_approved"
+	XrootdRestartedType   Type = "xrootd_restarted"
+	HealthChangedType     Type = "health_changed"
+	IssuerKeyRotatedType  Type = "issuer_key_rotated"
+)
+
+// The number of events buffered for a subscriber before it misses events
+const subscriptionBuffer = 64
+
+var defaultBus = NewBus()
+
+func (AdReceived) Type() Type        { return AdReceivedType }
+func (NamespaceApproved) Type() Type { return NamespaceApprovedType }
+func (XrootdRestarted) Type() Type   { return XrootdRestartedType }
+func (HealthChanged) Type() Type     { return HealthChangedType }
+func (IssuerKeyRotated) Type() Type  { return IssuerKeyRotatedType }
+
+// All the event types, e.g. to validate the types a client asks to subscribe to
+func Types() []Type {
+	return []Type{AdReceivedType, NamespaceApprovedType, XrootdRestartedType, HealthChangedType, IssuerKeyRotatedType}
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Deliver the event to the subscribers of its type
+func (bus *Bus) Publish(event Event) {
+	envelope := Envelope{Type: event.Type(), Time: time.Now(), Event: event}
+
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+	for sub := range bus.subscribers {
+		if sub.types != nil {
+			if _, ok := sub.types[envelope.Type]; !ok {
+				continue
+			}
+		}
+		select {
+		case sub.ch <- envelope:
+		default:
+			log.Debugf("Dropped %s event for a subscriber not keeping up with the event bus", envelope.Type)
+		}
+	}
+}
+
+// Subscribe to the events of the given types, or to all events if none are given
+func (bus *Bus) Subscribe(types ...Type) *Subscription {
+	sub := &Subscription{bus: bus, ch: make(chan Envelope, subscriptionBuffer)}
+	if len(types) > 0 {
+		sub.types = make(map[Type]struct{}, len(types))
+		for _, eventType := range types {
+			sub.types[eventType] = struct{}{}
+		}
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// The channel the subscribed events are delivered on; closed by Close
+func (sub *Subscription) Events() <-chan Envelope {
+	return sub.ch
+}
+
+// Stop the delivery of events and close the events channel
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		sub.bus.mutex.Lock()
+		defer sub.bus.mutex.Unlock()
+		delete(sub.bus.subscribers, sub)
+		close(sub.ch)
+	})
+}
+
+// Publish the event on the bus of the process
+func Publish(event Event) {
+	defaultBus.Publish(event)
+}
+
+// Subscribe to the events of the given types published on the bus of the process,
+// or to all of them if no type is given
+func Subscribe(types ...Type) *Subscription {
+	return defaultBus.Subscribe(types...)
+}
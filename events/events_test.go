@@ -0,0 +1,97 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, sub *Subscription) Envelope {
+	select {
+	case envelope, ok := <-sub.Events():
+		require.True(t, ok, "The subscription was closed")
+		return envelope
+	case <-time.After(time.Second):
+		require.Fail(t, "Timed out waiting for an event")
+	}
+	return Envelope{}
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("all-types", func(t *testing.T) {
+		bus := NewBus()
+		sub := bus.Subscribe()
+		defer sub.Close()
+
+		bus.Publish(HealthChanged{Component: "xrootd", Status: "ok"})
+		bus.Publish(NamespaceApproved{ID: 1, Prefix: "/foo", ApprovedBy: "admin"})
+
+		envelope := receive(t, sub)
+		assert.Equal(t, HealthChangedType, envelope.Type)
+		assert.Equal(t, HealthChanged{Component: "xrootd", Status: "ok"}, envelope.Event)
+		assert.WithinDuration(t, time.Now(), envelope.Time, time.Minute)
+		envelope = receive(t, sub)
+		assert.Equal(t, NamespaceApprovedType, envelope.Type)
+	})
+
+	t.Run("filtered-types", func(t *testing.T) {
+		bus := NewBus()
+		sub := bus.Subscribe(XrootdRestartedType)
+		defer sub.Close()
+
+		bus.Publish(HealthChanged{Component: "xrootd", Status: "ok"})
+		bus.Publish(XrootdRestarted{Daemons: []string{"xrootd", "cmsd"}})
+
+		envelope := receive(t, sub)
+		assert.Equal(t, XrootdRestarted{Daemons: []string{"xrootd", "cmsd"}}, envelope.Event)
+		assert.Empty(t, sub.Events())
+	})
+
+	t.Run("slow-subscriber-misses-events", func(t *testing.T) {
+		bus := NewBus()
+		sub := bus.Subscribe()
+		defer sub.Close()
+
+		// Publishing must not block on a subscriber not reading its events
+		for i := 0; i < subscriptionBuffer+10; i++ {
+			bus.Publish(AdReceived{ServerName: "cache"})
+		}
+		assert.Len(t, sub.Events(), subscriptionBuffer)
+	})
+}
+
+func TestClose(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe()
+	other := bus.Subscribe()
+	defer other.Close()
+
+	sub.Close()
+	sub.Close() // Closing twice is harmless
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+
+	// The other subscribers still get the events
+	bus.Publish(HealthChanged{Component: "xrootd", Status: "critical"})
+	assert.Equal(t, HealthChangedType, receive(t, other).Type)
+}
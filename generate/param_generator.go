@@ -19,6 +19,7 @@ import (
 type GoField struct {
 	Name         string
 	Type         string
+	Tag          string
 	NestedFields map[string]*GoField
 }
 
@@ -64,6 +65,9 @@ func GenParamEnum() {
 	boolParamMap := make(map[string]string)
 	durationParamMap := make(map[string]string)
 	objectParamMap := make(map[string]string)
+	deprecatedParamMap := make(map[string]string)
+	secretParamMap := make(map[string]bool)
+	paramNames := make(map[string]bool)
 
 	// Skip the first parameter (ConfigBase is special)
 	// Save the first parameter seperately in order to do "<pname> Param = iota" for the enums
@@ -98,6 +102,16 @@ func GenParamEnum() {
 
 		rawName := entry["name"].(string)
 		name := strings.ReplaceAll(rawName, ".", "_")
+		paramNames[rawName] = true
+		replacedBy, hasReplacement := entry["replacedBy"].(string)
+		if deprecated, ok := entry["deprecated"].(bool); ok && deprecated {
+			deprecatedParamMap[rawName] = replacedBy
+		} else if hasReplacement {
+			panic(fmt.Sprintf("Parameter entry '%s' has a replacedBy key but isn't deprecated", rawName))
+		}
+		if secret, ok := entry["secret"].(bool); ok && secret {
+			secretParamMap[rawName] = true
+		}
 		pType := entry["type"].(string)
 		switch pType {
 		case "url":
@@ -123,6 +137,12 @@ func GenParamEnum() {
 		}
 	}
 
+	for oldName, newName := range deprecatedParamMap {
+		if newName != "" && !paramNames[newName] {
+			panic(fmt.Sprintf("Deprecated parameter '%s' is replaced by '%s', which doesn't exist", oldName, newName))
+		}
+	}
+
 	// Create the file to be generated
 	f, err := os.Create("../param/parameters.go")
 	if err != nil {
@@ -138,7 +158,9 @@ func GenParamEnum() {
 		BoolMap        map[string]string
 		DurationMap    map[string]string
 		ObjectMap      map[string]string
-	}{StringMap: stringParamMap, StringSliceMap: stringSliceParamMap, IntMap: intParamMap, BoolMap: boolParamMap, DurationMap: durationParamMap, ObjectMap: objectParamMap})
+		DeprecatedMap  map[string]string
+		SecretMap      map[string]bool
+	}{StringMap: stringParamMap, StringSliceMap: stringSliceParamMap, IntMap: intParamMap, BoolMap: boolParamMap, DurationMap: durationParamMap, ObjectMap: objectParamMap, DeprecatedMap: deprecatedParamMap, SecretMap: secretParamMap})
 
 	if err != nil {
 		panic(err)
@@ -184,7 +206,7 @@ func generateGoStructCode(field *GoField, indent string) string {
 	// If it has type, it should be a leaf node as parent node
 	// does not have a type
 	if field.Type != "" {
-		return fmt.Sprintf("%s%s %s\n", indent, field.Name, field.Type)
+		return fmt.Sprintf("%s%s %s `%s`\n", indent, field.Name, field.Type, field.Tag)
 	}
 	code := fmt.Sprintf("%s%s struct {\n", indent, field.Name)
 	keys := make([]string, 0, len(field.NestedFields))
@@ -197,16 +219,55 @@ func generateGoStructCode(field *GoField, indent string) string {
 		nested := field.NestedFields[key]
 		code += generateGoStructCode(nested, indent+"	")
 	}
-	code += fmt.Sprintf("%s}\n", indent)
+	// The root is the config struct itself and has no name to tag
+	if field.Name == "" {
+		code += fmt.Sprintf("%s}\n", indent)
+	} else {
+		code += fmt.Sprintf("%s} `mapstructure:\"%s\"`\n", indent, field.Name)
+	}
 	return code
 }
 
+// Build the struct tags of a parameter from its entry in parameters.yaml.  The
+// validation rules come from the optional "required" and "options" keys and from
+// the parameter's type.
+func generateFieldTag(name string, entry map[string]interface{}) string {
+	rules := []string{}
+	if options, ok := entry["options"].([]interface{}); ok && len(options) > 0 {
+		values := make([]string, 0, len(options))
+		for _, option := range options {
+			value := fmt.Sprint(option)
+			if strings.ContainsAny(value, " ,|") {
+				panic(fmt.Sprintf("Parameter %s has the option %q; options can't contain spaces, commas or '|'", name, value))
+			}
+			values = append(values, value)
+		}
+		rules = append(rules, "oneof="+strings.Join(values, " "))
+	}
+	// Several url parameters accept a bare hostname (with an optional port) that
+	// Pelican completes with the https scheme
+	if entry["type"] == "url" {
+		rules = append(rules, "url|hostname_port|hostname_rfc1123")
+	}
+
+	tag := fmt.Sprintf(`mapstructure:"%s"`, name)
+	if required, ok := entry["required"].(bool); ok && required {
+		rules = append([]string{"required"}, rules...)
+	} else if len(rules) > 0 {
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	if len(rules) > 0 {
+		tag += fmt.Sprintf(` validate:"%s"`, strings.Join(rules, ","))
+	}
+	return tag
+}
+
 // Recursively generate the struct code given the root of the GoField
 func generateGoStructWithTypeCode(field *GoField, indent string) string {
 	// If it has type, it should be a leaf node as parent node
 	// does not have a type
 	if field.Type != "" {
-		return fmt.Sprintf("%s%s struct { Type string; Value %s }\n", indent, field.Name, field.Type)
+		return fmt.Sprintf("%s%s struct { Type string; Value %s; Source string `json:\",omitempty\"` }\n", indent, field.Name, field.Type)
 	}
 	code := fmt.Sprintf("%s%s struct {\n", indent, field.Name)
 	keys := make([]string, 0, len(field.NestedFields))
@@ -300,6 +361,7 @@ func GenParamStruct() {
 			current = current.NestedFields[part]
 		}
 		current.Type = goType
+		current.Tag = generateFieldTag(parts[len(parts)-1], entry)
 	}
 
 	// Manually added this config to reflect what ConfigBase was meant to be
@@ -309,6 +371,7 @@ func GenParamStruct() {
 		Name:         "ConfigDir",
 		NestedFields: make(map[string]*GoField),
 		Type:         "string",
+		Tag:          `mapstructure:"ConfigDir"`,
 	}
 
 	data := TemplateData{
@@ -338,8 +401,6 @@ package param
 
 import (
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 type StringParam struct {
@@ -367,27 +428,57 @@ type ObjectParam struct {
 }
 
 func (sP StringParam) GetString() string {
-	return viper.GetString(sP.name)
+	return sP.GetStringIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (sP StringParam) GetStringIn(pc *Context) string {
+	return pc.Viper().GetString(sP.name)
 }
 
 func (slP StringSliceParam) GetStringSlice() []string {
-	return viper.GetStringSlice(slP.name)
+	return slP.GetStringSliceIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (slP StringSliceParam) GetStringSliceIn(pc *Context) []string {
+	return pc.Viper().GetStringSlice(slP.name)
 }
 
 func (iP IntParam) GetInt() int {
-	return viper.GetInt(iP.name)
+	return iP.GetIntIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (iP IntParam) GetIntIn(pc *Context) int {
+	return pc.Viper().GetInt(iP.name)
 }
 
 func (bP BoolParam) GetBool() bool {
-	return viper.GetBool(bP.name)
+	return bP.GetBoolIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP BoolParam) GetBoolIn(pc *Context) bool {
+	return pc.Viper().GetBool(bP.name)
 }
 
 func (bP DurationParam) GetDuration() time.Duration {
-	return viper.GetDuration(bP.name)
+	return bP.GetDurationIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP DurationParam) GetDurationIn(pc *Context) time.Duration {
+	return pc.Viper().GetDuration(bP.name)
 }
 
 func (bP ObjectParam) Unmarshal(rawVal any) error {
-	return viper.UnmarshalKey(bP.name, rawVal)
+	return bP.UnmarshalIn(nil, rawVal)
+}
+
+// Unmarshal the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP ObjectParam) UnmarshalIn(pc *Context, rawVal any) error {
+	return pc.Viper().UnmarshalKey(bP.name, rawVal)
 }
 
 var ({{range $key, $value := .StringMap}}
@@ -419,6 +510,20 @@ var ({{range $key, $value := .ObjectMap}}
 	{{$key}} = ObjectParam{{"{"}}{{printf "%q" $value}}{{"}"}}
 	{{- end}}
 )
+
+// The deprecated parameters, mapped to the parameters replacing them; a deprecated
+// parameter without a replacement maps to the empty string
+var DeprecatedParams = map[string]string{ {{- range $key, $value := .DeprecatedMap}}
+	{{printf "%q" $key}}: {{printf "%q" $value}},
+	{{- end}}
+}
+
+// The parameters holding credentials (see the "secret" key in docs/parameters.yaml),
+// whose values are redacted wherever the configuration is shown
+var SecretParams = map[string]bool{ {{- range $key, $value := .SecretMap}}
+	{{printf "%q" $key}}: {{$value}},
+	{{- end}}
+}
 `))
 
 var structTemplate = template.Must(template.New("").Parse(`// Code generated by go generate; DO NOT EDIT.
@@ -426,9 +531,52 @@ var structTemplate = template.Must(template.New("").Parse(`// Code generated by
 package param
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
 )
 
 {{.GeneratedConfig}}
 
-{{.GeneratedConfigWithType}}`))
+{{.GeneratedConfigWithType}}
+
+// Validate the configuration against the constraints declared in docs/parameters.yaml
+func (cfg *config) Validate() error {
+	problems, err := cfg.ValidationProblems(validator.New())
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	descriptions := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		descriptions = append(descriptions, problem.String())
+	}
+	return errors.New("Invalid configuration: " + strings.Join(descriptions, "; "))
+}
+
+// Check the configuration against the constraints declared in docs/parameters.yaml
+// with the validator v, returning each constraint it violates
+func (cfg *config) ValidationProblems(v *validator.Validate) ([]ValidationProblem, error) {
+	err := v.Struct(cfg)
+	validationErrs := validator.ValidationErrors{}
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+	problems := make([]ValidationProblem, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		problem := ValidationProblem{Param: strings.TrimPrefix(fieldErr.Namespace(), "config.")}
+		switch fieldErr.Tag() {
+		case "required":
+			problem.Message = "must be set"
+		case "oneof":
+			problem.Message = fmt.Sprintf("is %q but must be one of: %s", fieldErr.Value(), fieldErr.Param())
+		default:
+			problem.Message = fmt.Sprintf("has the invalid value %q", fieldErr.Value())
+		}
+		problems = append(problems, problem)
+	}
+	return problems, nil
+}
+`))
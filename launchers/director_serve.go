@@ -50,6 +50,12 @@ func DirectorServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group
 
 	director.ConfigTTLCache(ctx, egrp)
 
+	// Rank caches using their recent throughput from the monitoring data
+	go director.PeriodicThroughputReload(ctx)
+
+	// Refuse advertisements signed with namespace keys the registry revoked
+	go director.PeriodicRevokedKeysReload(ctx)
+
 	// Configure the shortcut middleware to either redirect to a cache
 	// or to an origin
 	defaultResponse := param.Director_DefaultResponse.GetString()
@@ -58,9 +64,29 @@ func DirectorServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group
 			" but you provided %q. Was there a typo?", defaultResponse)
 	}
 	log.Debugf("The director will redirect to %ss by default", defaultResponse)
+	if err := director.ValidateMinimumVersions(); err != nil {
+		return err
+	}
+	if err := director.ValidateCacheRollouts(); err != nil {
+		return err
+	}
+	// The routes of the group below inherit the middleware, so it must be installed first
+	if fairness := director.FairnessMiddleware(); fairness != nil {
+		engine.Use(fairness)
+	}
 	rootGroup := engine.Group("/")
 	director.RegisterDirectorAuth(rootGroup)
 	director.RegisterDirectorWebAPI(rootGroup)
+	if param.Director_Mirror_Url.GetString() != "" {
+		mirror, err := director.MirrorMiddleware(ctx)
+		if err != nil {
+			return err
+		}
+		// The shortcut middleware only sees the requests matching no route, so the
+		// redirect routes registered below need the mirror as well
+		engine.Use(mirror)
+		rootGroup.Use(mirror)
+	}
 	engine.Use(director.ShortcutMiddleware(defaultResponse))
 	director.RegisterDirector(ctx, rootGroup)
 
@@ -0,0 +1,247 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package launchers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
+)
+
+type (
+	// The outcome of one of the checks of a dry run
+	DryRunCheck struct {
+		Name  string `json:"name"`
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	// The checks done by a dry run of the serve commands, in the order they ran
+	DryRunReport struct {
+		Modules string        `json:"modules"`
+		Checks  []DryRunCheck `json:"checks"`
+	}
+)
+
+// Certificates expiring sooner than this fail the dry run
+const dryRunCertExpiry = 7 * 24 * time.Hour
+
+// Create the report of a dry run of the modules
+func NewDryRunReport(modules config.ServerType) *DryRunReport {
+	names := []string{}
+	for _, sType := range []config.ServerType{config.CacheType, config.OriginType, config.DirectorType, config.RegistryType} {
+		if modules.IsEnabled(sType) {
+			names = append(names, strings.ToLower(sType.String()))
+		}
+	}
+	return &DryRunReport{Modules: strings.Join(names, ", ")}
+}
+
+// Record the outcome of the named check, returning whether it passed
+func (report *DryRunReport) Check(name string, err error) bool {
+	check := DryRunCheck{Name: name, Ok: err == nil}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	report.Checks = append(report.Checks, check)
+	return err == nil
+}
+
+// Whether any of the checks failed
+func (report *DryRunReport) Failed() bool {
+	for _, check := range report.Checks {
+		if !check.Ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (report *DryRunReport) Print(w io.Writer) {
+	fmt.Fprintln(w, "Dry run of the", report.Modules, "modules:")
+	for _, check := range report.Checks {
+		if check.Ok {
+			fmt.Fprintf(w, "  [ok]   %s\n", check.Name)
+		} else {
+			fmt.Fprintf(w, "  [FAIL] %s: %s\n", check.Name, check.Error)
+		}
+	}
+	if report.Failed() {
+		fmt.Fprintln(w, "The server would fail to start or run correctly; fix the failed checks above")
+	} else {
+		fmt.Fprintln(w, "All checks passed")
+	}
+}
+
+// Check the origin's export configuration is complete for its mode
+func checkOriginExports() error {
+	mode := param.Origin_Mode.GetString()
+	switch mode {
+	case "posix", "hsm":
+		if param.Origin_ExportVolume.GetString() == "" && (param.Xrootd_Mount.GetString() == "" || param.Origin_NamespacePrefix.GetString() == "") {
+			return errors.Errorf(`
+	Export information was not provided.
+	Add the command line flag:
+
+		-v /mnt/foo:/bar
+
+	to export the directory /mnt/foo to the namespace prefix /bar in the data federation. Alternatively, specify Origin.ExportVolume in the parameters.yaml file:
+
+		Origin:
+			ExportVolume: /mnt/foo:/bar
+
+	Or, specify Xrootd.Mount and Origin.NamespacePrefix in the parameters.yaml file:
+
+		Xrootd:
+			Mount: /mnt/foo
+		Origin:
+			NamespacePrefix: /bar`)
+		}
+	case "s3":
+		if param.Origin_S3Bucket.GetString() == "" || param.Origin_S3Region.GetString() == "" ||
+			param.Origin_S3ServiceName.GetString() == "" || param.Origin_S3ServiceUrl.GetString() == "" {
+			return errors.Errorf("The S3 origin is missing configuration options to run properly." +
+				" You must specify a bucket, a region, a service name and a service URL via the command line or via" +
+				" your configuration file.")
+		}
+	default:
+		return errors.Errorf("Currently-supported origin modes include posix, s3 and hsm.")
+	}
+	return nil
+}
+
+// Check the directory of the registry's database exists or can be created
+func checkRegistryDbLocation() error {
+	dbPath := param.Registry_DbLocation.GetString()
+	if dbPath == "" {
+		return errors.New("Registry.DbLocation is not set")
+	}
+	return errors.Wrap(os.MkdirAll(filepath.Dir(dbPath), 0755), "failed to create the directory of the registry database")
+}
+
+// Check the server's TLS certificate and key load, match, and aren't about to expire
+func CheckTLSCredentials() error {
+	certFile := param.Server_TLSCertificate.GetString()
+	keyFile := param.Server_TLSKey.GetString()
+	if certFile == "" || keyFile == "" {
+		return errors.New("Server.TLSCertificate and Server.TLSKey must both be set")
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the TLS certificate and key")
+	}
+	if len(pair.Certificate) == 0 {
+		return errors.Errorf("%s has no certificate", certFile)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the certificate in %s", certFile)
+	}
+	if time.Now().Before(cert.NotBefore) {
+		return errors.Errorf("the certificate in %s isn't valid until %s", certFile, cert.NotBefore.Format(time.RFC3339))
+	}
+	if time.Until(cert.NotAfter) < dryRunCertExpiry {
+		return errors.Errorf("the certificate in %s expires at %s", certFile, cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Check the federation service at serviceUrl answers its health endpoint
+func CheckServiceReachable(ctx context.Context, serviceUrl string) error {
+	if serviceUrl == "" {
+		return errors.New("the service URL is not set and was not discovered")
+	}
+	healthUrl, err := url.JoinPath(serviceUrl, "api", "v1.0", "health")
+	if err != nil {
+		return errors.Wrapf(err, "invalid service URL %s", serviceUrl)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "pelican/"+config.PelicanVersion)
+	client := http.Client{Transport: config.GetTransport(), Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to reach %s", serviceUrl)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s responded with HTTP status %d", healthUrl, resp.StatusCode)
+	}
+	return nil
+}
+
+// Do the initialization of the serve command for the modules -- configuration, key
+// and certificate generation, rendering the XRootD configuration, and reaching the
+// federation's services -- without binding any ports or launching any daemons
+func DryRunModules(ctx context.Context, modules config.ServerType) *DryRunReport {
+	// Stop the background tasks the initialization starts once the checks are done
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := NewDryRunReport(modules)
+	if !report.Check("server configuration", config.InitServer(ctx, modules)) {
+		return report
+	}
+	_, err := config.GetIssuerPrivateJWK()
+	report.Check("issuer key", err)
+	report.Check("TLS certificate", CheckTLSCredentials())
+
+	if modules.IsEnabled(config.RegistryType) {
+		viper.Set("Federation.RegistryURL", param.Server_ExternalWebUrl.GetString())
+		report.Check("registry database directory", checkRegistryDbLocation())
+	}
+	if modules.IsEnabled(config.DirectorType) {
+		viper.Set("Federation.DirectorURL", param.Server_ExternalWebUrl.GetString())
+	}
+
+	if modules.IsEnabled(config.OriginType) {
+		report.Check("hostnames", server_utils.CheckHostnames())
+		if report.Check("origin exports", checkOriginExports()) {
+			report.Check("XRootD configuration", dryRunOriginXrootd(ctx))
+		}
+		// The origin registers with the registry and advertises to the director
+		if !modules.IsEnabled(config.RegistryType) {
+			report.Check("registry connectivity", CheckServiceReachable(ctx, param.Federation_RegistryUrl.GetString()))
+		}
+		if !modules.IsEnabled(config.DirectorType) {
+			report.Check("director connectivity", CheckServiceReachable(ctx, param.Federation_DirectorUrl.GetString()))
+		}
+	} else if modules.IsEnabled(config.DirectorType) && !modules.IsEnabled(config.RegistryType) {
+		// The director verifies advertisements against the registry
+		report.Check("registry connectivity", CheckServiceReachable(ctx, param.Federation_RegistryUrl.GetString()))
+	}
+	return report
+}
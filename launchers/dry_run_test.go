@@ -0,0 +1,116 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package launchers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/config"
+)
+
+// Write a self-signed certificate valid until notAfter and its key, configuring the
+// server to use them
+func writeTestCert(t *testing.T, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	viper.Set("Server.TLSCertificate", certFile)
+	viper.Set("Server.TLSKey", keyFile)
+}
+
+func TestCheckTLSCredentials(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	writeTestCert(t, time.Now().Add(90*24*time.Hour))
+	assert.NoError(t, CheckTLSCredentials())
+
+	writeTestCert(t, time.Now().Add(24*time.Hour))
+	assert.ErrorContains(t, CheckTLSCredentials(), "expires at")
+
+	viper.Set("Server.TLSKey", filepath.Join(t.TempDir(), "missing.key"))
+	assert.Error(t, CheckTLSCredentials())
+}
+
+func TestCheckServiceReachable(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1.0/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, CheckServiceReachable(context.Background(), server.URL))
+	assert.ErrorContains(t, CheckServiceReachable(context.Background(), server.URL+"/other"), "HTTP status 404")
+	assert.Error(t, CheckServiceReachable(context.Background(), ""))
+}
+
+func TestDryRunReport(t *testing.T) {
+	report := NewDryRunReport(config.OriginType | config.DirectorType)
+	assert.Equal(t, "origin, director", report.Modules)
+
+	assert.True(t, report.Check("first", nil))
+	assert.False(t, report.Failed())
+	assert.False(t, report.Check("second", errors.New("something is wrong")))
+	assert.True(t, report.Failed())
+
+	output := bytes.Buffer{}
+	report.Print(&output)
+	assert.Contains(t, output.String(), "[ok]   first")
+	assert.Contains(t, output.String(), "[FAIL] second: something is wrong")
+}
@@ -31,6 +31,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/local_cache"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/server_ui"
 	"github.com/pelicanplatform/pelican/server_utils"
@@ -41,6 +42,35 @@ var (
 	ErrExitOnSignal error = errors.New("Exit program on signal")
 )
 
+// Build the graph of the readiness of the modules run by the process: the registry
+// must be ready before the director accepts advertisements, and the web engine, registry
+// and director before the origin registers and advertises
+func registerModuleReadiness(modules config.ServerType) error {
+	server_utils.ResetModules()
+	if err := server_utils.RegisterModule(server_utils.ModuleWebEngine); err != nil {
+		return err
+	}
+	deps := []server_utils.Module{server_utils.ModuleWebEngine}
+	if modules.IsEnabled(config.RegistryType) {
+		if err := server_utils.RegisterModule(server_utils.ModuleRegistry, deps...); err != nil {
+			return err
+		}
+		deps = append(deps, server_utils.ModuleRegistry)
+	}
+	if modules.IsEnabled(config.DirectorType) {
+		if err := server_utils.RegisterModule(server_utils.ModuleDirector, deps...); err != nil {
+			return err
+		}
+		deps = append(deps, server_utils.ModuleDirector)
+	}
+	if modules.IsEnabled(config.OriginType) {
+		if err := server_utils.RegisterModule(server_utils.ModuleOrigin, deps...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func LaunchModules(ctx context.Context, modules config.ServerType) (context.CancelFunc, error) {
 	egrp, ok := ctx.Value(config.EgrpKey).(*errgroup.Group)
 	if !ok {
@@ -63,6 +93,24 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		}
 	})
 
+	// The local cache is a client of the federation rather than a server; it runs alone
+	if modules.IsEnabled(config.LocalCacheType) {
+		if modules != config.LocalCacheType {
+			return shutdownCancel, errors.New("The localcache module can't be combined with other modules")
+		}
+		if err := config.InitClient(); err != nil {
+			return shutdownCancel, errors.Wrap(err, "Failure when configuring the local cache")
+		}
+		if err := local_cache.LaunchLocalCache(ctx, egrp); err != nil {
+			return shutdownCancel, err
+		}
+		return shutdownCancel, nil
+	}
+
+	if err := registerModuleReadiness(modules); err != nil {
+		return shutdownCancel, err
+	}
+
 	engine, err := web_ui.GetEngine()
 	if err != nil {
 		return shutdownCancel, err
@@ -84,6 +132,7 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		if err = RegistryServe(ctx, engine, egrp); err != nil {
 			return shutdownCancel, err
 		}
+		server_utils.MarkStarted(server_utils.ModuleRegistry)
 	}
 
 	if modules.IsEnabled(config.DirectorType) {
@@ -95,41 +144,13 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		if err = DirectorServe(ctx, engine, egrp); err != nil {
 			return shutdownCancel, err
 		}
+		server_utils.MarkStarted(server_utils.ModuleDirector)
 	}
 
 	servers := make([]server_utils.XRootDServer, 0)
 	if modules.IsEnabled(config.OriginType) {
-		mode := param.Origin_Mode.GetString()
-		switch mode {
-		case "posix":
-			if param.Origin_ExportVolume.GetString() == "" && (param.Xrootd_Mount.GetString() == "" || param.Origin_NamespacePrefix.GetString() == "") {
-				return shutdownCancel, errors.Errorf(`
-	Export information was not provided.
-	Add the command line flag:
-
-		-v /mnt/foo:/bar
-
-	to export the directory /mnt/foo to the namespace prefix /bar in the data federation. Alternatively, specify Origin.ExportVolume in the parameters.yaml file:
-
-		Origin:
-			ExportVolume: /mnt/foo:/bar
-
-	Or, specify Xrootd.Mount and Origin.NamespacePrefix in the parameters.yaml file:
-
-		Xrootd:
-			Mount: /mnt/foo
-		Origin:
-			NamespacePrefix: /bar`)
-			}
-		case "s3":
-			if param.Origin_S3Bucket.GetString() == "" || param.Origin_S3Region.GetString() == "" ||
-				param.Origin_S3ServiceName.GetString() == "" || param.Origin_S3ServiceUrl.GetString() == "" {
-				return shutdownCancel, errors.Errorf("The S3 origin is missing configuration options to run properly." +
-					" You must specify a bucket, a region, a service name and a service URL via the command line or via" +
-					" your configuration file.")
-			}
-		default:
-			return shutdownCancel, errors.Errorf("Currently-supported origin modes include posix and s3.")
+		if err := checkOriginExports(); err != nil {
+			return shutdownCancel, err
 		}
 
 		server, err := OriginServe(ctx, engine, egrp)
@@ -138,8 +159,8 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		}
 		servers = append(servers, server)
 
-		switch mode {
-		case "posix":
+		switch param.Origin_Mode.GetString() {
+		case "posix", "hsm":
 			err = server_utils.WaitUntilWorking(ctx, "GET", param.Origin_Url.GetString()+"/.well-known/openid-configuration", "Origin", http.StatusOK)
 			if err != nil {
 				return shutdownCancel, err
@@ -153,6 +174,7 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 				return shutdownCancel, err
 			}
 		}
+		server_utils.MarkStarted(server_utils.ModuleOrigin)
 	}
 
 	log.Info("Starting web engine...")
@@ -170,8 +192,21 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		log.Errorln("Web engine startup appears to have failed:", err)
 		return shutdownCancel, err
 	}
+	server_utils.MarkStarted(server_utils.ModuleWebEngine)
+
+	// The director is the federation's reference clock, so it has no skew to check
+	if !modules.IsEnabled(config.DirectorType) {
+		if err = server_utils.LaunchClockSkewCheck(ctx, egrp); err != nil {
+			return shutdownCancel, err
+		}
+	}
 
 	if modules.IsEnabled(config.OriginType) {
+		// The origin registers its namespaces with, and advertises to, the registry and
+		// director of the process once they're ready
+		if err = server_utils.WaitReady(ctx, server_utils.ModuleOrigin); err != nil {
+			return shutdownCancel, err
+		}
 		log.Debug("Finishing origin server configuration")
 		if err = OriginServeFinish(ctx, egrp); err != nil {
 			return shutdownCancel, err
@@ -180,6 +215,7 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 
 	// Include cache here just in case, although we currently don't use launcher to launch cache
 	if modules.IsEnabled(config.OriginType) || modules.IsEnabled(config.CacheType) {
+		server_utils.LaunchHostnameCheck(ctx, egrp)
 		log.Debug("Launching periodic advertise")
 		if err := server_ui.LaunchPeriodicAdvertise(ctx, egrp, servers); err != nil {
 			return shutdownCancel, err
@@ -190,6 +226,9 @@ func LaunchModules(ctx context.Context, modules config.ServerType) (context.Canc
 		if err = web_ui.ConfigureEmbeddedPrometheus(ctx, engine); err != nil {
 			return shutdownCancel, errors.Wrap(err, "Failed to configure embedded prometheus instance")
 		}
+		if err = web_ui.LaunchAlerting(ctx, egrp); err != nil {
+			return shutdownCancel, errors.Wrap(err, "Failed to launch alerting")
+		}
 
 		log.Info("Starting web login...")
 		egrp.Go(func() error { return web_ui.InitServerWebLogin(ctx) })
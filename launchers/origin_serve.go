@@ -43,20 +43,33 @@ func OriginServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group)
 		return nil, err
 	}
 
+	// Exports paused before a restart stay paused; this must happen before the
+	// XRootD authorization is first generated
+	if err = origin_ui.LoadPausedExports(); err != nil {
+		return nil, err
+	}
+
 	originServer := &origin_ui.OriginServer{}
 	err = server_ui.CheckDefaults(originServer)
 	if err != nil {
 		return nil, err
 	}
+	origin_ui.SetExportPauseHook(func() error {
+		if err := xrootd.EmitAuthfile(originServer); err != nil {
+			return err
+		}
+		return xrootd.EmitScitokensConfig(originServer)
+	})
 
 	// Set up the APIs unrelated to UI, which only contains director-based health test reporting endpoint for now
 	if err = origin_ui.ConfigureOriginAPI(engine, ctx, egrp); err != nil {
 		return nil, err
 	}
+	server_ui.RegisterRegistrationStatusAPI(engine)
 
-	// In posix mode, we rely on xrootd to export keys. When we run the origin with
+	// In posix and hsm mode, we rely on xrootd to export keys. When we run the origin with
 	// different backends, we instead export the keys via the Pelican process
-	if param.Origin_Mode.GetString() != "posix" {
+	if mode := param.Origin_Mode.GetString(); mode != "posix" && mode != "hsm" {
 		if err = origin_ui.ConfigIssJWKS(engine.Group("/.well-known")); err != nil {
 			return nil, err
 		}
@@ -76,6 +89,9 @@ func OriginServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group)
 	if param.Origin_SelfTest.GetBool() {
 		egrp.Go(func() error { return origin_ui.PeriodicSelfTest(ctx) })
 	}
+	// The director's tests also write into the monitoring directory, so clean it up
+	// even when the origin's own self-test is disabled
+	egrp.Go(func() error { return origin_ui.PeriodicTestFileCleanup(ctx) })
 
 	xrootd.LaunchXrootdMaintenance(ctx, originServer, 2*time.Minute)
 
@@ -105,3 +121,16 @@ func OriginServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group)
 func OriginServeFinish(ctx context.Context, egrp *errgroup.Group) error {
 	return server_ui.RegisterNamespaceWithRetry(ctx, egrp)
 }
+
+// Check the origin's XRootD environment and render its configuration, as OriginServe
+// does, without launching XRootD
+func dryRunOriginXrootd(ctx context.Context) error {
+	if err := origin_ui.LoadPausedExports(); err != nil {
+		return err
+	}
+	if err := server_ui.CheckDefaults(&origin_ui.OriginServer{}); err != nil {
+		return err
+	}
+	_, err := xrootd.ConfigXrootd(ctx, true)
+	return err
+}
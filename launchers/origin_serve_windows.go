@@ -36,3 +36,7 @@ func OriginServe(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group)
 func OriginServeFinish(ctx context.Context, egrp *errgroup.Group) error {
 	return errors.New("Origin module is not supported on Windows")
 }
+
+func dryRunOriginXrootd(ctx context.Context) error {
+	return errors.New("Origin module is not supported on Windows")
+}
@@ -0,0 +1,279 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+// Package local_cache implements the on-node cache that batch jobs download
+// federation objects through.  It keeps the objects in the client's local cache
+// (see Client.LocalCacheLocation), so the jobs on a node share a single download
+// of their common inputs.
+package local_cache
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/client"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+// Replaced by the unit tests to avoid contacting a federation
+var downloadObject = client.DownloadObject
+
+type LocalCache struct {
+	// Where the in-progress responses are staged
+	requestDir string
+
+	// The objects being downloaded; concurrent requests for the same object wait
+	// for the first download and are then served from disk
+	inflightMutex sync.Mutex
+	inflight      map[string]chan struct{}
+
+	// The objects the federation recently reported missing, remembered for
+	// LocalCache.NegativeCacheTTL; nil if disabled
+	missing *ttlcache.Cache[string, struct{}]
+}
+
+// Create the local cache from the LocalCache.* configuration
+func NewLocalCache() (*LocalCache, error) {
+	dataLocation := param.LocalCache_DataLocation.GetString()
+	if dataLocation == "" {
+		return nil, errors.New("LocalCache.DataLocation is not set")
+	}
+	requestDir := filepath.Join(dataLocation, "requests")
+	// Responses staged by a previous run are never served
+	if err := os.RemoveAll(requestDir); err != nil {
+		return nil, errors.Wrap(err, "failed to clean up the local cache's staged responses")
+	}
+	if err := os.MkdirAll(requestDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create the local cache's data directory")
+	}
+
+	// Downloads go through the client's local cache in the same directory.  The local
+	// cache serves whoever asks, so it must never use its own credentials.
+	viper.Set("Client.LocalCacheLocation", filepath.Join(dataLocation, "objects"))
+	viper.Set("Client.LocalCacheSize", param.LocalCache_Size.GetInt())
+	client.ObjectClientOptions.NoTokens = true
+
+	lc := &LocalCache{
+		requestDir: requestDir,
+		inflight:   make(map[string]chan struct{}),
+	}
+	if ttl := param.LocalCache_NegativeCacheTTL.GetDuration(); ttl > 0 {
+		lc.missing = ttlcache.New[string, struct{}](
+			ttlcache.WithTTL[string, struct{}](ttl),
+			ttlcache.WithDisableTouchOnHit[string, struct{}](),
+		)
+	}
+	return lc, nil
+}
+
+// Whether the federation recently reported the object missing
+func (lc *LocalCache) isMissing(objectPath string) bool {
+	return lc.missing != nil && lc.missing.Get(objectPath) != nil
+}
+
+// Forget that the object, or any object under it if it's a directory, is missing, so
+// the next request for it goes to the federation
+func (lc *LocalCache) forgetMissing(objectPath string) (forgotten int) {
+	if lc.missing == nil {
+		return
+	}
+	prefix := strings.TrimSuffix(objectPath, "/") + "/"
+	for _, key := range lc.missing.Keys() {
+		if key == objectPath || strings.HasPrefix(key, prefix) {
+			lc.missing.Delete(key)
+			forgotten++
+		}
+	}
+	return
+}
+
+// Download objectPath to dest, either from disk or from the federation.  Only one
+// download of an object is in progress at a time.
+func (lc *LocalCache) fetch(ctx context.Context, objectPath string, dest string) error {
+	for {
+		lc.inflightMutex.Lock()
+		done, busy := lc.inflight[objectPath]
+		if !busy {
+			done = make(chan struct{})
+			lc.inflight[objectPath] = done
+			lc.inflightMutex.Unlock()
+			break
+		}
+		lc.inflightMutex.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		lc.inflightMutex.Lock()
+		defer lc.inflightMutex.Unlock()
+		close(lc.inflight[objectPath])
+		delete(lc.inflight, objectPath)
+	}()
+
+	// Requests that waited on a download of a missing object don't repeat it
+	if lc.isMissing(objectPath) {
+		return client.ErrObjectNotFound
+	}
+	_, err := downloadObject(ctx, objectPath, dest)
+	if errors.Is(err, client.ErrObjectNotFound) && lc.missing != nil {
+		lc.missing.Set(objectPath, struct{}{}, ttlcache.DefaultTTL)
+	}
+	return err
+}
+
+// GET/HEAD /*path
+//
+// Respond with the federation object at path
+func (lc *LocalCache) serveObject(ctx *gin.Context) {
+	objectPath := path.Clean("/" + ctx.Param("path"))
+	if objectPath == "/" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No object was requested"})
+		return
+	}
+
+	stagingDir, err := os.MkdirTemp(lc.requestDir, "request-")
+	if err != nil {
+		log.Errorln("Failed to create a directory for the local cache's response:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stage the object"})
+		return
+	}
+	defer os.RemoveAll(stagingDir)
+	dest := filepath.Join(stagingDir, path.Base(objectPath))
+
+	start := time.Now()
+	if err = lc.fetch(ctx.Request.Context(), objectPath, dest); err != nil {
+		log.Warningf("Failed to download %s: %v", objectPath, err)
+		if errors.Is(err, client.ErrObjectNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": objectPath + " doesn't exist in the federation"})
+		} else if errors.Is(err, client.ErrTokenRequired) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "The local cache only serves objects of namespaces that don't require a token to read; download " + objectPath + " from the federation directly"})
+		} else if errors.Is(err, client.ErrTransferDeadlineExceeded) {
+			ctx.JSON(http.StatusGatewayTimeout, gin.H{"error": "Timed out downloading " + objectPath})
+		} else {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to download " + objectPath + ": " + err.Error()})
+		}
+		return
+	}
+	log.Debugf("Retrieved %s in %s", objectPath, time.Since(start))
+
+	file, err := os.Open(dest)
+	if err != nil {
+		log.Errorf("Failed to open the staged copy of %s: %v", objectPath, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the object"})
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Errorf("Failed to stat the staged copy of %s: %v", objectPath, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the object"})
+		return
+	}
+	http.ServeContent(ctx.Writer, ctx.Request, path.Base(objectPath), info.ModTime(), file)
+}
+
+// DELETE /*path
+//
+// Forget that the object at path, or the objects under it, are missing, e.g. because
+// they were just written to the federation
+func (lc *LocalCache) invalidateMissing(ctx *gin.Context) {
+	objectPath := path.Clean("/" + ctx.Param("path"))
+	forgotten := lc.forgetMissing(objectPath)
+	log.Debugf("Forgot %d missing objects at %s", forgotten, objectPath)
+	ctx.JSON(http.StatusOK, gin.H{"forgotten": forgotten})
+}
+
+func (lc *LocalCache) Register(router gin.IRoutes) {
+	router.GET("/*path", lc.serveObject)
+	router.HEAD("/*path", lc.serveObject)
+	router.DELETE("/*path", lc.invalidateMissing)
+}
+
+// Run the local cache until ctx is cancelled
+func LaunchLocalCache(ctx context.Context, egrp *errgroup.Group) error {
+	lc, err := NewLocalCache()
+	if err != nil {
+		return err
+	}
+	if lc.missing != nil {
+		go lc.missing.Start()
+		egrp.Go(func() error {
+			<-ctx.Done()
+			lc.missing.Stop()
+			return nil
+		})
+	}
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	lc.Register(engine)
+
+	listeners := []net.Listener{}
+	listener, err := utils.ListenUnixSocket(param.LocalCache_Socket.GetString(), param.LocalCache_SocketMode.GetString())
+	if err != nil {
+		return errors.Wrap(err, "local cache failed to listen on LocalCache.Socket")
+	}
+	log.Infoln("Local cache is listening on", param.LocalCache_Socket.GetString())
+	listeners = append(listeners, listener)
+	if port := param.LocalCache_Port.GetInt(); port > 0 {
+		// Only jobs on the node may use the local cache
+		address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+		if listener, err = net.Listen("tcp", address); err != nil {
+			listeners[0].Close()
+			return errors.Wrapf(err, "failed to listen on %s", address)
+		}
+		log.Infoln("Local cache is listening on", "http://"+address)
+		listeners = append(listeners, listener)
+	}
+
+	for _, listener := range listeners {
+		server := &http.Server{Handler: engine}
+		listener := listener
+		egrp.Go(func() error {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return errors.Wrap(err, "local cache failed to serve requests")
+			}
+			return nil
+		})
+		egrp.Go(func() error {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		})
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package local_cache
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/client"
+)
+
+func TestLocalCache(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	tmpDir := t.TempDir()
+	socketPath := filepath.Join(tmpDir, "localcache.sock")
+	viper.Set("LocalCache.Socket", socketPath)
+	viper.Set("LocalCache.SocketMode", "0666")
+	viper.Set("LocalCache.DataLocation", filepath.Join(tmpDir, "data"))
+	viper.Set("LocalCache.Size", 1)
+	viper.Set("LocalCache.NegativeCacheTTL", "1m")
+
+	// Pretend to download objects, tracking how many downloads run at once
+	var inProgress, maxInProgress, missingDownloads atomic.Int32
+	downloadObject = func(ctx context.Context, objectPath string, dest string) ([]client.TransferResults, error) {
+		if n := inProgress.Add(1); n > maxInProgress.Load() {
+			maxInProgress.Store(n)
+		}
+		defer inProgress.Add(-1)
+		time.Sleep(50 * time.Millisecond)
+		if objectPath == "/protected/secret" {
+			return nil, client.ErrTokenRequired
+		}
+		if objectPath == "/public/missing/output.txt" {
+			missingDownloads.Add(1)
+			return nil, errors.Wrap(client.ErrObjectNotFound, "failed to download with HTTP")
+		}
+		return nil, os.WriteFile(dest, []byte("contents of "+objectPath), 0644)
+	}
+	t.Cleanup(func() { downloadObject = client.DownloadObject })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	egrp := &errgroup.Group{}
+	require.NoError(t, LaunchLocalCache(ctx, egrp))
+	defer func() {
+		cancel()
+		require.NoError(t, egrp.Wait())
+	}()
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0666), info.Mode().Perm())
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	get := func(objectPath string) (int, string) {
+		resp, err := httpClient.Get("http://localhost" + objectPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("concurrent-requests-are-serialized", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status, body := get("/public/input.tar.gz")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "contents of /public/input.tar.gz", body)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), maxInProgress.Load())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		status, _ := get("/protected/secret")
+		assert.Equal(t, http.StatusForbidden, status)
+		status, _ = get("/")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("missing-objects-are-remembered", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			status, _ := get("/public/missing/output.txt")
+			assert.Equal(t, http.StatusNotFound, status)
+		}
+		assert.Equal(t, int32(1), missingDownloads.Load())
+
+		req, err := http.NewRequest(http.MethodDelete, "http://localhost/public/missing", nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"forgotten": 1}`, string(body))
+
+		status, _ := get("/public/missing/output.txt")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, int32(2), missingDownloads.Load())
+	})
+
+	// Nothing is left behind once the responses are sent
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "data", "requests"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
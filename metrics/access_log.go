@@ -0,0 +1,137 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package metrics
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// An access to an object, as reported when the object was closed
+	AccessLogEntry struct {
+		Time       time.Time `json:"time"`
+		Path       string    `json:"path"`
+		Site       string    `json:"site"` // The client's domain or network, never its address
+		ReadBytes  uint64    `json:"readBytes"`
+		WriteBytes uint64    `json:"writeBytes"`
+	}
+)
+
+var (
+	accessLogMutex sync.Mutex
+	// The recorded accesses, oldest first
+	accessLog []AccessLogEntry
+)
+
+// Reduce a client host, as reported by XRootD, to its site: the registered domain
+// of a hostname, or the /24 (IPv4) or /48 (IPv6) network of an address
+func getClientSite(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		bits := 48
+		if addr.Is4() {
+			bits = 24
+		}
+		if prefix, err := addr.Prefix(bits); err == nil {
+			return prefix.String()
+		}
+		return "unknown"
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		return "unknown"
+	}
+	return site
+}
+
+// Drop the accesses older than Monitoring.AccessLogRetention.
+// Must be called with accessLogMutex held.
+func pruneAccessLogLocked(now time.Time) {
+	oldest := now.Add(-param.Monitoring_AccessLogRetention.GetDuration())
+	idx := 0
+	for idx < len(accessLog) && accessLog[idx].Time.Before(oldest) {
+		idx++
+	}
+	if idx > 0 {
+		accessLog = append([]AccessLogEntry{}, accessLog[idx:]...)
+	}
+}
+
+// Record an access to the object at objectPath by a client on host
+func recordAccess(objectPath, host string, readBytes, writeBytes uint64) {
+	if !param.Monitoring_EnableAccessLogs.GetBool() || objectPath == "" {
+		return
+	}
+	entry := AccessLogEntry{
+		Time:       time.Now().UTC().Truncate(time.Second),
+		Path:       objectPath,
+		Site:       getClientSite(host),
+		ReadBytes:  readBytes,
+		WriteBytes: writeBytes,
+	}
+
+	accessLogMutex.Lock()
+	defer accessLogMutex.Unlock()
+	maxEntries := param.Monitoring_AccessLogMaxEntries.GetInt()
+	if maxEntries <= 0 {
+		return
+	}
+	if len(accessLog) >= maxEntries {
+		// Drop the oldest in bulk so the slice isn't copied on every access
+		drop := len(accessLog) - maxEntries + 1 + maxEntries/10
+		if drop > len(accessLog) {
+			drop = len(accessLog)
+		}
+		accessLog = append([]AccessLogEntry{}, accessLog[drop:]...)
+	}
+	accessLog = append(accessLog, entry)
+}
+
+// Get the most recent accesses, newest first, to the objects under prefix since
+// the given time.  At most limit accesses are returned if limit is positive.
+func GetAccessLog(prefix string, since time.Time, limit int) []AccessLogEntry {
+	prefix = strings.TrimSuffix(prefix, "/")
+	entries := []AccessLogEntry{}
+
+	accessLogMutex.Lock()
+	defer accessLogMutex.Unlock()
+	pruneAccessLogLocked(time.Now())
+	for idx := len(accessLog) - 1; idx >= 0; idx-- {
+		entry := accessLog[idx]
+		if entry.Time.Before(since) {
+			break
+		}
+		if prefix != "" && entry.Path != prefix && !strings.HasPrefix(entry.Path, prefix+"/") {
+			continue
+		}
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+	return entries
+}
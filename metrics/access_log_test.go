@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClientSite(t *testing.T) {
+	for host, site := range map[string]string{
+		"node12.hep.example.edu":  "example.edu",
+		"worker.cam.ac.uk":        "cam.ac.uk",
+		"Worker.Example.COM":      "example.com",
+		"[::ffff:172.17.0.2]":     "172.17.0.0/24",
+		"192.0.2.77":              "192.0.2.0/24",
+		"[2001:db8:1234:5678::1]": "2001:db8:1234::/48",
+		"localhost":               "unknown",
+		"":                        "unknown",
+	} {
+		assert.Equal(t, site, getClientSite(host), host)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Reset()
+		accessLogMutex.Lock()
+		accessLog = nil
+		accessLogMutex.Unlock()
+	})
+	viper.Set("Monitoring.EnableAccessLogs", true)
+	viper.Set("Monitoring.AccessLogRetention", "1h")
+	viper.Set("Monitoring.AccessLogMaxEntries", 100)
+
+	recordAccess("/foo/a", "node1.example.edu", 10, 0)
+	recordAccess("/foobar/b", "node2.example.edu", 20, 0)
+	recordAccess("/foo/bar/c", "192.0.2.5", 0, 30)
+	// An access without a path isn't recorded
+	recordAccess("", "192.0.2.5", 40, 0)
+
+	t.Run("prefix", func(t *testing.T) {
+		entries := GetAccessLog("/foo", time.Time{}, 0)
+		if assert.Len(t, entries, 2) {
+			// Newest first
+			assert.Equal(t, "/foo/bar/c", entries[0].Path)
+			assert.Equal(t, "192.0.2.0/24", entries[0].Site)
+			assert.Equal(t, uint64(30), entries[0].WriteBytes)
+			assert.Equal(t, "/foo/a", entries[1].Path)
+			assert.Equal(t, "example.edu", entries[1].Site)
+		}
+		assert.Len(t, GetAccessLog("/", time.Time{}, 0), 3)
+	})
+
+	t.Run("limit-and-since", func(t *testing.T) {
+		assert.Len(t, GetAccessLog("/", time.Time{}, 1), 1)
+		assert.Empty(t, GetAccessLog("/", time.Now().Add(time.Minute), 0))
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		accessLogMutex.Lock()
+		accessLog[0].Time = time.Now().Add(-2 * time.Hour)
+		accessLogMutex.Unlock()
+		entries := GetAccessLog("/foo", time.Time{}, 0)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "/foo/bar/c", entries[0].Path)
+		}
+	})
+
+	t.Run("max-entries", func(t *testing.T) {
+		for idx := 0; idx < 150; idx++ {
+			recordAccess("/foo/many", "node1.example.edu", 1, 0)
+		}
+		accessLogMutex.Lock()
+		assert.LessOrEqual(t, len(accessLog), 100)
+		accessLogMutex.Unlock()
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		viper.Set("Monitoring.EnableAccessLogs", false)
+		recordAccess("/disabled", "node1.example.edu", 1, 0)
+		assert.Empty(t, GetAccessLog("/disabled", time.Time{}, 0))
+	})
+}
@@ -0,0 +1,269 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// The bytes transferred on behalf of a token subject on one (UTC) day
+	AccountingUsage struct {
+		Date       string `json:"date,omitempty"` // YYYY-MM-DD; empty in totals over several days
+		Issuer     string `json:"issuer"`
+		Subject    string `json:"subject"`
+		ReadBytes  uint64 `json:"readBytes"`
+		WriteBytes uint64 `json:"writeBytes"`
+	}
+
+	// The filter of an accounting query; empty fields match everything
+	AccountingFilter struct {
+		From    string // YYYY-MM-DD, inclusive
+		To      string // YYYY-MM-DD, inclusive
+		Issuer  string
+		Subject string
+	}
+
+	AccountingReport struct {
+		From   string            `json:"from"`
+		To     string            `json:"to"`
+		Daily  []AccountingUsage `json:"daily"`
+		Totals []AccountingUsage `json:"totals"`
+	}
+
+	accountingKey struct {
+		Date    string
+		Issuer  string
+		Subject string
+	}
+)
+
+const (
+	accountingDateFormat    = "2006-01-02"
+	accountingFlushInterval = time.Minute
+)
+
+var (
+	accountingMutex sync.Mutex
+	accountingUsage = make(map[accountingKey]*AccountingUsage)
+	// Set when accountingUsage has changed since it was last persisted
+	accountingDirty bool
+	accountingNow   = time.Now
+)
+
+// Add the bytes of a transfer by the session with the given authentication
+// protocol, issuer (org) and subject (DN) to today's usage.  Only transfers
+// authenticated with a token are accounted for.
+func recordAccounting(protocol, issuer, subject string, readBytes, writeBytes int64) {
+	if !param.Monitoring_EnableAccounting.GetBool() || protocol != "ztn" || (issuer == "" && subject == "") {
+		return
+	}
+	if readBytes <= 0 && writeBytes <= 0 {
+		return
+	}
+	key := accountingKey{
+		Date:    accountingNow().UTC().Format(accountingDateFormat),
+		Issuer:  issuer,
+		Subject: subject,
+	}
+
+	accountingMutex.Lock()
+	defer accountingMutex.Unlock()
+	usage, ok := accountingUsage[key]
+	if !ok {
+		usage = &AccountingUsage{Date: key.Date, Issuer: issuer, Subject: subject}
+		accountingUsage[key] = usage
+	}
+	if readBytes > 0 {
+		usage.ReadBytes += uint64(readBytes)
+	}
+	if writeBytes > 0 {
+		usage.WriteBytes += uint64(writeBytes)
+	}
+	accountingDirty = true
+}
+
+// Return the daily usage and the per-subject totals matching the filter,
+// sorted by date, issuer and subject
+func GetAccountingReport(filter AccountingFilter) AccountingReport {
+	report := AccountingReport{From: filter.From, To: filter.To, Daily: []AccountingUsage{}, Totals: []AccountingUsage{}}
+	totals := make(map[accountingKey]*AccountingUsage)
+
+	accountingMutex.Lock()
+	for key, usage := range accountingUsage {
+		if (filter.From != "" && key.Date < filter.From) || (filter.To != "" && key.Date > filter.To) ||
+			(filter.Issuer != "" && key.Issuer != filter.Issuer) || (filter.Subject != "" && key.Subject != filter.Subject) {
+			continue
+		}
+		report.Daily = append(report.Daily, *usage)
+		totalKey := accountingKey{Issuer: key.Issuer, Subject: key.Subject}
+		total, ok := totals[totalKey]
+		if !ok {
+			total = &AccountingUsage{Issuer: key.Issuer, Subject: key.Subject}
+			totals[totalKey] = total
+		}
+		total.ReadBytes += usage.ReadBytes
+		total.WriteBytes += usage.WriteBytes
+	}
+	accountingMutex.Unlock()
+
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+	sortAccountingUsage(report.Daily)
+	sortAccountingUsage(report.Totals)
+	return report
+}
+
+func sortAccountingUsage(usage []AccountingUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Date != usage[j].Date {
+			return usage[i].Date < usage[j].Date
+		}
+		if usage[i].Issuer != usage[j].Issuer {
+			return usage[i].Issuer < usage[j].Issuer
+		}
+		return usage[i].Subject < usage[j].Subject
+	})
+}
+
+// Drop the usage of the days older than Monitoring.AccountingRetention.
+// Must be called with accountingMutex held.
+func pruneAccountingLocked() {
+	retention := param.Monitoring_AccountingRetention.GetDuration()
+	if retention <= 0 {
+		return
+	}
+	oldest := accountingNow().UTC().Add(-retention).Format(accountingDateFormat)
+	for key := range accountingUsage {
+		if key.Date < oldest {
+			delete(accountingUsage, key)
+			accountingDirty = true
+		}
+	}
+}
+
+// Load the usage recorded before the server restarted from Monitoring.AccountingFile.
+// A missing file means no usage has been recorded.
+func loadAccounting() error {
+	usages := []AccountingUsage{}
+	if accountingFile := param.Monitoring_AccountingFile.GetString(); accountingFile != "" {
+		contents, err := os.ReadFile(accountingFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrapf(err, "Failed to read the accounting file %s", accountingFile)
+		} else if err == nil {
+			if err = json.Unmarshal(contents, &usages); err != nil {
+				return errors.Wrapf(err, "Failed to parse the accounting file %s", accountingFile)
+			}
+		}
+	}
+
+	accountingMutex.Lock()
+	defer accountingMutex.Unlock()
+	accountingUsage = make(map[accountingKey]*AccountingUsage, len(usages))
+	for idx := range usages {
+		usage := usages[idx]
+		accountingUsage[accountingKey{Date: usage.Date, Issuer: usage.Issuer, Subject: usage.Subject}] = &usage
+	}
+	pruneAccountingLocked()
+	return nil
+}
+
+// Atomically write the recorded usage to Monitoring.AccountingFile if it has
+// changed since it was last written
+func persistAccounting() error {
+	accountingFile := param.Monitoring_AccountingFile.GetString()
+	if accountingFile == "" {
+		return errors.New("Monitoring.AccountingFile is not set; the accounting data can't be persisted")
+	}
+
+	accountingMutex.Lock()
+	defer accountingMutex.Unlock()
+	pruneAccountingLocked()
+	if !accountingDirty {
+		return nil
+	}
+	usages := make([]AccountingUsage, 0, len(accountingUsage))
+	for _, usage := range accountingUsage {
+		usages = append(usages, *usage)
+	}
+	sortAccountingUsage(usages)
+	contents, err := json.Marshal(usages)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal the accounting data")
+	}
+	if err = os.MkdirAll(filepath.Dir(accountingFile), 0750); err != nil {
+		return errors.Wrapf(err, "Failed to create the directory for the accounting file %s", accountingFile)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(accountingFile), filepath.Base(accountingFile)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create a temporary accounting file")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Failed to write the accounting file")
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to write the accounting file")
+	}
+	if err = os.Rename(tmpFile.Name(), accountingFile); err != nil {
+		return errors.Wrapf(err, "Failed to move the accounting file into place at %s", accountingFile)
+	}
+	accountingDirty = false
+	return nil
+}
+
+// Load the persisted accounting data and periodically write it back to
+// Monitoring.AccountingFile, as well as at shutdown
+func launchAccounting(ctx context.Context, egrp *errgroup.Group) error {
+	if err := loadAccounting(); err != nil {
+		return err
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(accountingFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := persistAccounting(); err != nil {
+					log.Errorln("Failed to persist the accounting data at shutdown:", err)
+				}
+				return nil
+			case <-ticker.C:
+				if err := persistAccounting(); err != nil {
+					log.Warningln("Failed to persist the accounting data:", err)
+				}
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetAccounting(t *testing.T, now time.Time) {
+	viper.Reset()
+	viper.Set("Monitoring.EnableAccounting", true)
+	viper.Set("Monitoring.AccountingFile", filepath.Join(t.TempDir(), "accounting.json"))
+	viper.Set("Monitoring.AccountingRetention", "240h")
+	accountingMutex.Lock()
+	accountingUsage = make(map[accountingKey]*AccountingUsage)
+	accountingDirty = false
+	accountingNow = func() time.Time { return now }
+	accountingMutex.Unlock()
+	t.Cleanup(func() {
+		viper.Reset()
+		accountingMutex.Lock()
+		accountingUsage = make(map[accountingKey]*AccountingUsage)
+		accountingDirty = false
+		accountingNow = time.Now
+		accountingMutex.Unlock()
+	})
+}
+
+func setAccountingNow(now time.Time) {
+	accountingMutex.Lock()
+	defer accountingMutex.Unlock()
+	accountingNow = func() time.Time { return now }
+}
+
+func TestRecordAccounting(t *testing.T) {
+	day1 := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	resetAccounting(t, day1)
+
+	recordAccounting("ztn", "https://issuer.example", "alice", 100, 0)
+	recordAccounting("ztn", "https://issuer.example", "alice", 50, 10)
+	recordAccounting("ztn", "https://issuer.example", "bob", 7, 0)
+	// Not authenticated with a token
+	recordAccounting("https", "", "/CN=carol", 1000, 0)
+	// Nothing transferred
+	recordAccounting("ztn", "https://issuer.example", "dave", 0, -5)
+	setAccountingNow(day2)
+	recordAccounting("ztn", "https://issuer.example", "alice", 1, 2)
+
+	t.Run("all", func(t *testing.T) {
+		report := GetAccountingReport(AccountingFilter{})
+		assert.Equal(t, []AccountingUsage{
+			{Date: "2024-05-01", Issuer: "https://issuer.example", Subject: "alice", ReadBytes: 150, WriteBytes: 10},
+			{Date: "2024-05-01", Issuer: "https://issuer.example", Subject: "bob", ReadBytes: 7},
+			{Date: "2024-05-02", Issuer: "https://issuer.example", Subject: "alice", ReadBytes: 1, WriteBytes: 2},
+		}, report.Daily)
+		assert.Equal(t, []AccountingUsage{
+			{Issuer: "https://issuer.example", Subject: "alice", ReadBytes: 151, WriteBytes: 12},
+			{Issuer: "https://issuer.example", Subject: "bob", ReadBytes: 7},
+		}, report.Totals)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		report := GetAccountingReport(AccountingFilter{From: "2024-05-02", Subject: "alice"})
+		assert.Equal(t, []AccountingUsage{
+			{Date: "2024-05-02", Issuer: "https://issuer.example", Subject: "alice", ReadBytes: 1, WriteBytes: 2},
+		}, report.Daily)
+		report = GetAccountingReport(AccountingFilter{Issuer: "https://other.example"})
+		assert.Empty(t, report.Daily)
+		assert.Empty(t, report.Totals)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		viper.Set("Monitoring.EnableAccounting", false)
+		defer viper.Set("Monitoring.EnableAccounting", true)
+		recordAccounting("ztn", "https://issuer.example", "erin", 100, 0)
+		assert.Empty(t, GetAccountingReport(AccountingFilter{Subject: "erin"}).Daily)
+	})
+}
+
+func TestPersistAccounting(t *testing.T) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+	resetAccounting(t, now.AddDate(0, 0, -15))
+	recordAccounting("ztn", "https://issuer.example", "alice", 100, 0)
+	setAccountingNow(now)
+	recordAccounting("ztn", "https://issuer.example", "alice", 5, 6)
+
+	require.NoError(t, persistAccounting())
+
+	accountingMutex.Lock()
+	accountingUsage = make(map[accountingKey]*AccountingUsage)
+	accountingMutex.Unlock()
+	require.NoError(t, loadAccounting())
+
+	// The usage older than the retention was pruned before it was written
+	report := GetAccountingReport(AccountingFilter{})
+	assert.Equal(t, []AccountingUsage{
+		{Date: "2024-05-20", Issuer: "https://issuer.example", Subject: "alice", ReadBytes: 5, WriteBytes: 6},
+	}, report.Daily)
+
+	// A missing file means no usage was recorded
+	viper.Set("Monitoring.AccountingFile", filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, loadAccounting())
+	assert.Empty(t, GetAccountingReport(AccountingFilter{}).Daily)
+}
@@ -47,4 +47,30 @@ var (
 		Name: "pelican_director_total_ftx_test_runs",
 		Help: "The number of file transfer test runs the director issued. A test run is a cycle of upload/download/delete test file, which is executed per 15s per origin (by defult)",
 	}, []string{"server_name", "server_web_url", "server_type", "status", "report_status"})
+
+	PelicanDirectorClientRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_director_client_requests_total",
+		Help: "The number of redirect requests the director received, by the Pelican service and major.minor version in the request's User-Agent. Versions older than the oldest supported client are counted as \"older\" and versions newer than the director as \"newer\"; requests from other user agents are counted as \"other\" service and \"unknown\" version",
+	}, []string{"service", "version"})
+
+	PelicanDirectorRolloutRedirectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_director_rollout_redirects_total",
+		Help: "The number of redirects to caches split by a rollout of Director.CacheRollouts, by the rollout and the group (\"canary\" or \"control\") the client was sent to",
+	}, []string{"rollout", "group"})
+
+	PelicanDirectorMirroredRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_director_mirrored_requests_total",
+		Help: "The number of requests mirrored to the shadow director of Director.Mirror.Url, by the result of comparing its response with the director's (\"match\", \"status_mismatch\", \"redirect_mismatch\", \"error\" or \"dropped\")",
+	}, []string{"result"})
+
+	PelicanDirectorMirrorLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pelican_director_mirror_latency_seconds",
+		Help:    "The latency of the requests mirrored to the shadow director, by the director (\"production\" or \"shadow\") that responded",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"director"})
+
+	PelicanDirectorFairnessRejectionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pelican_director_fairness_rejections_total",
+		Help: "The number of redirect and stat requests the director refused with 429 Too Many Requests while saturated, because the client's site was over its fair share of Director.Fairness.MaxConcurrentRequests",
+	})
 )
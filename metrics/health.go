@@ -24,6 +24,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pelicanplatform/pelican/events"
 )
 
 type (
@@ -71,7 +73,10 @@ const (
 	OriginCache_Federation    HealthStatusComponent = "federation" // Advertise to the director
 	OriginCache_Director      HealthStatusComponent = "director"   // File transfer with director
 	DirectorRegistry_Topology HealthStatusComponent = "topology"   // Fetch data from OSDF topology
+	Origin_Scrubber           HealthStatusComponent = "scrubber"   // Verify stored checksums of exported objects
 	Server_WebUI              HealthStatusComponent = "web-ui"
+	Server_Clock              HealthStatusComponent = "clock"    // Local clock against the director's
+	OriginCache_Hostname      HealthStatusComponent = "hostname" // Hostname and advertised URLs against the TLS certificate
 )
 
 var (
@@ -107,10 +112,18 @@ func (component HealthStatusComponent) String() string {
 // Add/update the component health status. If you have a new component to record,
 // please go to metrics/health and register your component as a new constant of
 // type HealthStatusComponent. Also note that StatusUnknown is mostly for internal
-// use only, please try to avoid setting this as your component status
+// use only, please try to avoid setting this as your component status.
+// A HealthChanged event is published when the status of the component changes.
 func SetComponentHealthStatus(name HealthStatusComponent, state HealthStatusEnum, msg string) {
 	now := time.Now()
-	healthStatus.Store(name.String(), componentStatusInternal{state, msg, now})
+	previous, loaded := healthStatus.Swap(name.String(), componentStatusInternal{state, msg, now})
+	if prevStatus, ok := previous.(componentStatusInternal); !loaded || !ok || prevStatus.Status != state {
+		event := events.HealthChanged{Component: name.String(), Status: state.String(), Message: msg}
+		if ok {
+			event.PreviousStatus = prevStatus.Status.String()
+		}
+		events.Publish(event)
+	}
 
 	PelicanHealthStatus.With(
 		prometheus.Labels{"component": name.String()}).
@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/events"
 )
 
 func TestHealthStatusString(t *testing.T) {
@@ -18,3 +20,26 @@ func TestHealthStatusString(t *testing.T) {
 		require.Equal(t, statusIndexErrorMessage, HealthStatusEnum(invalidIndex).String())
 	})
 }
+
+func TestSetComponentHealthStatusPublishesChanges(t *testing.T) {
+	component := HealthStatusComponent("test-component")
+	t.Cleanup(func() { DeleteComponentHealthStatus(component) })
+	sub := events.Subscribe(events.HealthChangedType)
+	defer sub.Close()
+
+	SetComponentHealthStatus(component, StatusWarning, "starting")
+	SetComponentHealthStatus(component, StatusWarning, "still starting") // Unchanged status
+	SetComponentHealthStatus(component, StatusOK, "")
+
+	var changes []events.HealthChanged
+	for len(sub.Events()) > 0 {
+		envelope := <-sub.Events()
+		if change := envelope.Event.(events.HealthChanged); change.Component == component.String() {
+			changes = append(changes, change)
+		}
+	}
+	assert.Equal(t, []events.HealthChanged{
+		{Component: "test-component", Status: "warning", Message: "starting"},
+		{Component: "test-component", Status: "ok", PreviousStatus: "warning"},
+	}, changes)
+}
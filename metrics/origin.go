@@ -0,0 +1,46 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	PelicanOriginScrubbedObjects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pelican_origin_scrubbed_objects_total",
+		Help: "The number of objects whose stored checksums the origin's scrubber verified",
+	})
+
+	PelicanOriginScrubbedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pelican_origin_scrubbed_bytes_total",
+		Help: "The number of bytes the origin's scrubber read to verify stored checksums",
+	})
+
+	PelicanOriginCorruptObjects = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pelican_origin_corrupt_objects",
+		Help: "The number of exported objects whose contents don't match their stored checksums",
+	})
+
+	PelicanOriginDSCPMarkedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_origin_dscp_marked_bytes_total",
+		Help: "The number of response bytes the origin's web engine sent marked with an export's DSCP",
+	}, []string{"prefix", "dscp"})
+)
@@ -0,0 +1,37 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	PelicanRegistryDBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pelican_registry_db_query_duration_seconds",
+		Help:    "The latency of queries against the registry database, by operation (query, exec, begin, commit, rollback)",
+		Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	PelicanRegistryDBQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_registry_db_query_errors_total",
+		Help: "The number of failed queries against the registry database, by operation and whether the failure was a timeout",
+	}, []string{"operation", "timeout"})
+)
@@ -0,0 +1,41 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	PelicanTLSCertificateExpiry = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pelican_server_tls_certificate_expiry_timestamp_seconds",
+		Help: "The Unix time the server's TLS certificate expires at",
+	})
+
+	PelicanRegistrationAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pelican_server_registration_attempts_total",
+		Help: "The attempts of the origin or cache to register its namespace with the registry, by result (success or failure)",
+	}, []string{"result"})
+
+	PelicanRegistrationState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pelican_server_registration_state",
+		Help: "The state of the registration of the server's namespace: 1 for the current state (pending, failed or complete), 0 for the others",
+	}, []string{"state"})
+)
@@ -62,6 +62,7 @@ type (
 		Role                   string
 		Org                    string
 		Groups                 []string
+		Host                   string // The client's host, from its login
 	}
 
 	FileId struct {
@@ -70,7 +71,8 @@ type (
 
 	FileRecord struct {
 		UserId     UserId
-		Path       string
+		Path       string // The monitoring prefix (see Monitoring.AggregatePrefixes) of the file
+		Lfn        string // The path of the file
 		ReadOps    uint32
 		ReadvOps   uint32
 		WriteOps   uint32
@@ -78,6 +80,7 @@ type (
 		ReadBytes  uint64
 		ReadvBytes uint64
 		WriteBytes uint64
+		OpenTime   time.Time
 	}
 
 	PathList struct {
@@ -186,10 +189,13 @@ type (
 		Size int `xml:"size"`
 		Used int `xml:"used"`
 		Wq   int `xml:"wq"`
+		// For buff stats, <mem> holds the bytes allocated to buffers as its text
+		Bytes string `xml:",chardata"`
 	}
 
 	SummaryStat struct {
 		Id      SummaryStatType    `xml:"id,attr"`
+		Num     int                `xml:"num"` // For link stats, the current connections
 		Total   int                `xml:"tot"`
 		In      int                `xml:"in"`
 		Out     int                `xml:"out"`
@@ -198,11 +204,14 @@ type (
 		Paths   SummaryPath        `xml:"paths"` // For Oss Summary Data
 		Store   SummaryCacheStore  `xml:"store"`
 		Memory  SummaryCacheMemory `xml:"mem"`
+		Reqs    int                `xml:"reqs"`  // For buff stats, the buffer requests
+		Buffs   int                `xml:"buffs"` // For buff stats, the allocated buffers
 	}
 
 	SummaryStatistics struct {
 		Version string        `xml:"ver,attr"`
 		Program string        `xml:"pgm,attr"`
+		Source  string        `xml:"src,attr"` // The host:port of the reporting server
 		Stats   []SummaryStat `xml:"stats"`
 	}
 )
@@ -223,6 +232,7 @@ const (
 	SchedStat SummaryStatType = "sched" // https://xrootd.slac.stanford.edu/doc/dev55/xrd_monitoring.htm#_Toc99653745
 	OssStat   SummaryStatType = "oss"   // https://xrootd.slac.stanford.edu/doc/dev55/xrd_monitoring.htm#_Toc99653741
 	CacheStat SummaryStatType = "cache" // https://xrootd.slac.stanford.edu/doc/dev55/xrd_monitoring.htm#_Toc99653733
+	BuffStat  SummaryStatType = "buff"  // https://xrootd.slac.stanford.edu/doc/dev55/xrd_monitoring.htm#_Toc99653731
 )
 
 var (
@@ -261,11 +271,53 @@ var (
 		Help: "Number of bytes read into the server",
 	}, []string{"direction"})
 
+	CompletedReadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "xrootd_transfer_completed_read_bytes",
+		Help: "Bytes read by completed transfers; together with xrootd_transfer_completed_read_seconds gives the server's average read throughput",
+	})
+
+	CompletedReadSeconds = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "xrootd_transfer_completed_read_seconds",
+		Help: "Total time, from open to close, of completed transfers that read data",
+	})
+
 	StorageVolume = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "xrootd_storage_volume_bytes",
 		Help: "Storage volume usage on the server",
 	}, []string{"ns", "type", "server_type"}) // type: total/free; server_type: origin/cache
 
+	// The gauges below report the latest summary of each xrootd server, labeled by
+	// the server's host:port, so the servers of a process can be told apart
+	ServerConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_connections",
+		Help: "Current number of connections to the xrootd server",
+	}, []string{"server"})
+
+	ServerLinkBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_link_bytes",
+		Help: "Bytes received (rx) and sent (tx) by the xrootd server since start-up",
+	}, []string{"server", "direction"})
+
+	ServerThreads = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_threads",
+		Help: "Number of scheduler threads of the xrootd server",
+	}, []string{"server", "state"})
+
+	ServerBufferBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_buffer_bytes",
+		Help: "Bytes of memory allocated to buffers by the xrootd server",
+	}, []string{"server"})
+
+	ServerBuffers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_buffers",
+		Help: "Number of buffers allocated by the xrootd server",
+	}, []string{"server"})
+
+	ServerBufferRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xrootd_server_buffer_requests",
+		Help: "Buffer requests made to the xrootd server since start-up",
+	}, []string{"server"})
+
 	lastStats SummaryStat
 
 	// Maps the connection identifier with a user record
@@ -314,6 +366,12 @@ func ConfigureMonitoring(ctx context.Context, egrp *errgroup.Group) (int, error)
 		return -1, err
 	}
 
+	if param.Monitoring_EnableAccounting.GetBool() {
+		if err = launchAccounting(ctx, egrp); err != nil {
+			return -1, err
+		}
+	}
+
 	// Start ttl cache automatic eviction of expired items
 	go sessions.Start()
 	go userids.Start()
@@ -547,7 +605,7 @@ func HandlePacket(packet []byte) error {
 		}
 		path := computePrefix(rest, monitorPaths)
 		if useridItem := userids.Get(xrdUserId); useridItem != nil {
-			transfers.Set(fileid, FileRecord{UserId: useridItem.Value(), Path: path}, ttlcache.DefaultTTL)
+			transfers.Set(fileid, FileRecord{UserId: useridItem.Value(), Path: path, OpenTime: time.Now()}, ttlcache.DefaultTTL)
 		}
 	case 'f':
 		log.Debug("HandlePacket: Received a f-stream packet")
@@ -587,11 +645,13 @@ func HandlePacket(packet []byte) error {
 				var oldReadBytes uint64 = 0
 				var oldReadvBytes uint64 = 0
 				var oldWriteBytes uint64 = 0
+				clientHost := ""
 				if xferRecord != nil {
 					userRecord := sessions.Get(xferRecord.Value().UserId)
 					sessions.Delete(xferRecord.Value().UserId)
 					labels["path"] = xferRecord.Value().Path
 					if userRecord != nil {
+						clientHost = userRecord.Value().Host
 						labels["ap"] = userRecord.Value().AuthenticationProtocol
 						labels["dn"] = userRecord.Value().DN
 						labels["role"] = userRecord.Value().Role
@@ -644,14 +704,30 @@ func HandlePacket(packet []byte) error {
 				counter.Add(float64(int64(binary.BigEndian.Uint64(
 					packet[offset+xfrOffset+16:offset+xfrOffset+24]) -
 					oldWriteBytes)))
+				recordAccounting(labels["ap"], labels["org"], labels["dn"],
+					int64(binary.BigEndian.Uint64(packet[offset+xfrOffset:offset+xfrOffset+8])-oldReadBytes)+
+						int64(binary.BigEndian.Uint64(packet[offset+xfrOffset+8:offset+xfrOffset+16])-oldReadvBytes),
+					int64(binary.BigEndian.Uint64(packet[offset+xfrOffset+16:offset+xfrOffset+24])-oldWriteBytes))
+				// Record the throughput of downloads; the director uses it to rank caches
+				readBytes := binary.BigEndian.Uint64(packet[offset+xfrOffset:offset+xfrOffset+8]) +
+					binary.BigEndian.Uint64(packet[offset+xfrOffset+8:offset+xfrOffset+16])
+				if xferRecord != nil && !xferRecord.Value().OpenTime.IsZero() && readBytes > 0 {
+					CompletedReadBytes.Add(float64(readBytes))
+					CompletedReadSeconds.Add(time.Since(xferRecord.Value().OpenTime).Seconds())
+				}
+				if xferRecord != nil {
+					recordAccess(xferRecord.Value().Lfn, clientHost, readBytes,
+						binary.BigEndian.Uint64(packet[offset+xfrOffset+16:offset+xfrOffset+24]))
+				}
 			case isOpen: // XrdXrootdMonFileHdr::isOpen
 				log.Debug("MonPacket: Received a f-stream file-open packet")
 				fileid := FileId{Id: fileHdr.FileId}
 				path := ""
+				lfn := ""
 				userId := UserId{}
 				if fileHdr.RecFlag&0x01 == 0x01 { // hasLFN
 					lfnSize := uint32(fileHdr.RecSize - 20)
-					lfn := NullTermToString(packet[offset+20 : offset+lfnSize+20])
+					lfn = NullTermToString(packet[offset+20 : offset+lfnSize+20])
 					// path has been difined
 					path = computePrefix(lfn, monitorPaths)
 					log.Debugf("MonPacket: User LFN %v matches prefix %v",
@@ -659,7 +735,7 @@ func HandlePacket(packet []byte) error {
 					// UserId is part of LFN
 					userId = UserId{Id: binary.BigEndian.Uint32(packet[offset+16 : offset+20])}
 				}
-				transfers.Set(fileid, FileRecord{UserId: userId, Path: path},
+				transfers.Set(fileid, FileRecord{UserId: userId, Path: path, Lfn: lfn, OpenTime: time.Now()},
 					ttlcache.DefaultTTL)
 			case isTime: // XrdXrootdMonFileHdr::isTime
 				log.Debug("MonPacket: Received a f-stream time packet")
@@ -722,6 +798,9 @@ func HandlePacket(packet []byte) error {
 				} else {
 					log.Debug("File-transfer WriteByte is less than previous value")
 				}
+				recordAccounting(labels["ap"], labels["org"], labels["dn"],
+					int64(readBytes-record.ReadBytes)+int64(readvBytes-record.ReadvBytes),
+					int64(writeBytes-record.WriteBytes))
 				record.ReadBytes = readBytes
 				record.ReadvBytes = readvBytes
 				record.WriteBytes = writeBytes
@@ -769,6 +848,7 @@ func HandlePacket(packet []byte) error {
 			if len(record.AuthenticationProtocol) > 0 {
 				record.User = xrdUserId.User
 			}
+			record.Host = xrdUserId.Host
 			sessions.Set(UserId{Id: dictid}, record, ttlcache.DefaultTTL)
 			userids.Set(xrdUserId, UserId{Id: dictid}, ttlcache.DefaultTTL)
 		} else {
@@ -782,6 +862,10 @@ func HandlePacket(packet []byte) error {
 			if err != nil {
 				return err
 			}
+			// The token replaces the identity of the login session, but not its host
+			if session := sessions.Get(userId); session != nil {
+				userRecord.Host = session.Value().Host
+			}
 			sessions.Set(userId, userRecord, ttlcache.DefaultTTL)
 		} else {
 			return err
@@ -929,10 +1013,18 @@ func HandleSummaryPacket(packet []byte) error {
 		// We only care about the xrootd summary packets
 		return nil
 	}
+	server := summaryStats.Source
+	if server == "" {
+		server = "unknown"
+	}
 	for _, stat := range summaryStats.Stats {
 		switch stat.Id {
 
 		case LinkStat:
+			ServerConnections.With(prometheus.Labels{"server": server}).Set(float64(stat.Num))
+			ServerLinkBytes.With(prometheus.Labels{"server": server, "direction": "rx"}).Set(float64(stat.In))
+			ServerLinkBytes.With(prometheus.Labels{"server": server, "direction": "tx"}).Set(float64(stat.Out))
+
 			// When stats tag has id="link", the following definitions are valid:
 			// stat.Total: Connections since start-up.
 			// stat.In: Bytes received
@@ -964,6 +1056,16 @@ func HandleSummaryPacket(packet []byte) error {
 			Threads.With(prometheus.Labels{"state": "idle"}).Set(float64(stat.Idle))
 			Threads.With(prometheus.Labels{"state": "running"}).Set(float64(stat.Threads -
 				stat.Idle))
+			ServerThreads.With(prometheus.Labels{"server": server, "state": "idle"}).Set(float64(stat.Idle))
+			ServerThreads.With(prometheus.Labels{"server": server, "state": "running"}).Set(float64(stat.Threads - stat.Idle))
+		case BuffStat:
+			ServerBufferRequests.With(prometheus.Labels{"server": server}).Set(float64(stat.Reqs))
+			ServerBuffers.With(prometheus.Labels{"server": server}).Set(float64(stat.Buffs))
+			if memBytes, err := strconv.Atoi(strings.TrimSpace(stat.Memory.Bytes)); err == nil {
+				ServerBufferBytes.With(prometheus.Labels{"server": server}).Set(float64(memBytes))
+			} else {
+				log.Debugln("Ignoring the invalid buffer memory in a summary packet:", stat.Memory.Bytes)
+			}
 		case OssStat: // Oss stat should only appear on origin servers
 			for _, pathStat := range stat.Paths.Stats {
 				noQuoteLp := strings.Replace(pathStat.Lp, "\"", "", 2)
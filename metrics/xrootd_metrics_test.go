@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -382,6 +383,49 @@ func TestHandlePacket(t *testing.T) {
 		}
 	})
 
+	t.Run("record-per-server-gauges-from-summary-packet", func(t *testing.T) {
+		ServerConnections.Reset()
+		ServerLinkBytes.Reset()
+		ServerThreads.Reset()
+		ServerBufferBytes.Reset()
+		ServerBuffers.Reset()
+		ServerBufferRequests.Reset()
+
+		// A packet as sent by xrootd, without any whitespace between the tags
+		packet := `<statistics tod="1687524138" ver="v5.2.0" src="origin.example.com:8443" tos="1687523538" pgm="xrootd" ins="anon" pid="3852923" site="origin.example.com">` +
+			`<stats id="buff"><reqs>2</reqs><mem>1049600</mem><buffs>3</buffs><adj>0</adj><xlreqs>0</xlreqs><xlmem>0</xlmem><xlbuffs>0</xlbuffs></stats>` +
+			`<stats id="link"><num>4</num><maxn>9</maxn><tot>12</tot><in>474</in><out>1117</out><ctime>0</ctime><tmo>0</tmo><stall>0</stall><sfps>0</sfps></stats>` +
+			`<stats id="sched"><jobs>188</jobs><inq>0</inq><maxinq>5</maxinq><threads>7</threads><idle>5</idle><tcr>7</tcr><tde>0</tde><tlimr>0</tlimr></stats>` +
+			`</statistics>`
+		require.NoError(t, HandlePacket([]byte(packet)))
+
+		expected := `
+		# HELP xrootd_server_connections Current number of connections to the xrootd server
+		# TYPE xrootd_server_connections gauge
+		xrootd_server_connections{server="origin.example.com:8443"} 4
+		# HELP xrootd_server_link_bytes Bytes received (rx) and sent (tx) by the xrootd server since start-up
+		# TYPE xrootd_server_link_bytes gauge
+		xrootd_server_link_bytes{direction="rx",server="origin.example.com:8443"} 474
+		xrootd_server_link_bytes{direction="tx",server="origin.example.com:8443"} 1117
+		# HELP xrootd_server_threads Number of scheduler threads of the xrootd server
+		# TYPE xrootd_server_threads gauge
+		xrootd_server_threads{server="origin.example.com:8443",state="idle"} 5
+		xrootd_server_threads{server="origin.example.com:8443",state="running"} 2
+		# HELP xrootd_server_buffer_bytes Bytes of memory allocated to buffers by the xrootd server
+		# TYPE xrootd_server_buffer_bytes gauge
+		xrootd_server_buffer_bytes{server="origin.example.com:8443"} 1.0496e+06
+		# HELP xrootd_server_buffers Number of buffers allocated by the xrootd server
+		# TYPE xrootd_server_buffers gauge
+		xrootd_server_buffers{server="origin.example.com:8443"} 3
+		# HELP xrootd_server_buffer_requests Buffer requests made to the xrootd server since start-up
+		# TYPE xrootd_server_buffer_requests gauge
+		xrootd_server_buffer_requests{server="origin.example.com:8443"} 2
+		`
+		registry := prometheus.NewPedanticRegistry()
+		registry.MustRegister(ServerConnections, ServerLinkBytes, ServerThreads, ServerBufferBytes, ServerBuffers, ServerBufferRequests)
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+	})
+
 	t.Run("auth-packet-u-should-register-correct-info", func(t *testing.T) {
 		mockUserRecord := UserRecord{
 			AuthenticationProtocol: "https",
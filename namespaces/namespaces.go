@@ -27,6 +27,7 @@ package namespaces
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
@@ -66,6 +67,8 @@ type DirectorCache struct {
 	EndpointUrl  string
 	Priority     int
 	AuthedReq    bool
+	// The namespace's origin, which permits reading from it directly once the caches failed
+	FallbackOrigin bool
 }
 
 // Credential generation information
@@ -208,6 +211,9 @@ func downloadNamespace() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = config.VerifyFederationResource(context.Background(), "the namespaces at "+topoNamespaceUrl, out.Bytes(), resp.Header.Get(config.ResourceSignatureHeader)); err != nil {
+		return nil, err
+	}
 	return out.Bytes(), nil
 }
 
@@ -79,8 +79,11 @@ func AcquireToken(issuerUrl string, entry *config.PrefixEntry, credentialGen *na
 		return nil, fmt.Errorf("Issuer at %s for prefix %s does not support device flow", issuerUrl, entry.Prefix)
 	}
 
-	// Always trim the filename off the path
-	osdfPath = path.Dir(osdfPath)
+	// Trim the filename off the path, so the token serves the other objects in the
+	// directory too, except for shared URLs, whose tokens are scoped to the object alone
+	if !opts.Operation.IsShared() {
+		osdfPath = path.Dir(osdfPath)
+	}
 
 	pathCleaned := path.Clean(osdfPath)[len(entry.Prefix):]
 	// The credential generation object provides various hints and guidance about how
@@ -92,18 +95,13 @@ func AcquireToken(issuerUrl string, entry *config.PrefixEntry, credentialGen *na
 		}
 
 		// Potentially increase the coarseness of the token
-		if opts.Operation != config.TokenSharedWrite && opts.Operation != config.TokenSharedRead && credentialGen.MaxScopeDepth != nil && *credentialGen.MaxScopeDepth >= 0 {
+		if !opts.Operation.IsShared() && credentialGen.MaxScopeDepth != nil && *credentialGen.MaxScopeDepth >= 0 {
 			pathCleaned = trimPath(pathCleaned, *credentialGen.MaxScopeDepth)
 		}
 	}
 
-	var storageScope string
-	if opts.Operation == config.TokenSharedWrite || opts.Operation == config.TokenWrite {
-		storageScope = "storage.create:"
-	} else {
-		storageScope = "storage.read:"
-	}
-	storageScope += pathCleaned
+	// Request only the scope the operation needs
+	storageScope := opts.Operation.StorageScope() + ":" + pathCleaned
 	log.Debugln("Requesting a credential with the following scope:", storageScope)
 
 	oauth2Config := Config{
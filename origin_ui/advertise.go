@@ -57,15 +57,35 @@ func (server *OriginServer) CreateAdvertisement(name string, originUrlStr string
 	if err != nil {
 		return ad, errors.New("Invalid Origin Url")
 	}
+	uploadPolicy, err := getUploadPolicy(prefix)
+	if err != nil {
+		return ad, err
+	}
+	identifiers, err := getPersistentIdentifiers(prefix)
+	if err != nil {
+		return ad, err
+	}
+	// A namespace with its own key is its own issuer
+	nsIssuerUrl := issuerUrl
+	if hasKey, err := config.HasNamespaceIssuerKey(prefix); err != nil {
+		return ad, err
+	} else if hasKey {
+		nsUrl, err := server_utils.GetNamespaceIssuerURL(prefix)
+		if err != nil {
+			return ad, err
+		}
+		nsIssuerUrl = *nsUrl
+	}
 	// TODO: Need to figure out where to get some of these values
 	// 		 so that they aren't hardcoded...
 
 	nsAd := common.NamespaceAdV2{
 		PublicRead: param.Origin_EnablePublicReads.GetBool(),
 		Caps: common.Capabilities{
-			PublicRead: param.Origin_EnablePublicReads.GetBool(),
-			Read:       true,
-			Write:      param.Origin_EnableWrite.GetBool(),
+			PublicRead:   param.Origin_EnablePublicReads.GetBool(),
+			Read:         true,
+			Write:        param.Origin_EnableWrite.GetBool(),
+			FallBackRead: param.Origin_EnableFallbackRead.GetBool(),
 		},
 		Path: prefix,
 		Generation: []common.TokenGen{{
@@ -75,14 +95,23 @@ func (server *OriginServer) CreateAdvertisement(name string, originUrlStr string
 		}},
 		Issuer: []common.TokenIssuer{{
 			BasePaths: []string{prefix},
-			IssuerUrl: issuerUrl,
+			IssuerUrl: nsIssuerUrl,
 		}},
+		UploadPolicy: uploadPolicy,
+		WriteMode:    getWriteMode(prefix),
+		Identifiers:  identifiers,
+		CachePolicy:  getCachePolicy(prefix),
+	}
+	namespaces := []common.NamespaceAdV2{nsAd}
+	if IsExportPaused(prefix) {
+		namespaces = []common.NamespaceAdV2{}
 	}
 	ad = common.OriginAdvertiseV2{
-		Name:       name,
-		DataURL:    originUrlStr,
-		WebURL:     originWebUrl,
-		Namespaces: []common.NamespaceAdV2{nsAd},
+		Name:             name,
+		DataURL:          originUrlStr,
+		WebURL:           originWebUrl,
+		Namespaces:       namespaces,
+		PausedNamespaces: getPausedExports(),
 		Caps: common.Capabilities{
 			PublicRead:   param.Origin_EnablePublicReads.GetBool(),
 			Read:         true,
@@ -100,13 +129,17 @@ func (server *OriginServer) CreateAdvertisement(name string, originUrlStr string
 
 // Return a list of paths where the origin's issuer is authoritative.
 //
-// Used to calculate the base_paths in the scitokens.cfg, for eaxmple
+// Used to calculate the base_paths in the scitokens.cfg, for eaxmple.
+// Paused exports are left out so the origin refuses tokens for them.
 func (server *OriginServer) GetAuthorizedPrefixes() []string {
 	// For now, just a single path.  In the future, we will allow
 	// multiple.
 	if param.Origin_EnablePublicReads.GetBool() {
 		return []string{}
 	}
+	if IsExportPaused(param.Origin_NamespacePrefix.GetString()) {
+		return []string{}
+	}
 
 	return []string{param.Origin_NamespacePrefix.GetString()}
 }
@@ -0,0 +1,396 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file manages the checksums of the objects exported by a POSIX origin.
+// Checksums are kept in extended attributes using the layout of XRootD's
+// checksum manager, so checksums computed by either XRootD or Pelican are
+// usable by both.
+//
+
+package origin_ui
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+const (
+	// Size of XRootD's XrdCksData structure:
+	// Name[16], fmTime (int64), csTime (int32), Rsvd1 (int16), Rsvd2 (byte), Length (byte), Value[64]
+	xrdCksDataSize      = 96
+	xrdCksNameSize      = 16
+	xrdCksValueSize     = 64
+	xrdCksValueOffset   = xrdCksDataSize - xrdCksValueSize
+	checksumQueueLength = 1000
+)
+
+type (
+	checksumRequest struct {
+		path      string
+		algorithm string
+	}
+
+	checksumResponse struct {
+		Checksums map[string]string `json:"checksums"`
+		Pending   []string          `json:"pending,omitempty"` // Algorithms queued for computation
+	}
+)
+
+var (
+	errNoChecksumXattr  = errors.New("No checksum extended attribute")
+	errXattrUnsupported = errors.New("Extended attributes are not supported")
+
+	// Checksums are computed by a pool of background workers; requests for
+	// the same object and algorithm that are already queued are dropped
+	checksumQueue        chan checksumRequest
+	checksumPending      = make(map[checksumRequest]bool)
+	checksumPendingMutex = sync.Mutex{}
+)
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "adler32":
+		return adler32.New(), nil
+	case "md5":
+		return md5.New(), nil
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	}
+	return nil, errors.Errorf("Unsupported checksum algorithm %q", algorithm)
+}
+
+// Format the checksum for a Digest header, following the conventions of XRootD's HTTP server
+func formatDigest(algorithm string, value []byte) string {
+	if algorithm == "md5" {
+		return "md5=" + base64.StdEncoding.EncodeToString(value)
+	}
+	return algorithm + "=" + hex.EncodeToString(value)
+}
+
+// Encode a checksum in XRootD's XrdCksData layout.  Like XRootD, the trailing
+// unused bytes of the value are not stored.
+func encodeChecksumXattr(algorithm string, value []byte, modTime time.Time, computedAt time.Time) ([]byte, error) {
+	if len(algorithm) >= xrdCksNameSize {
+		return nil, errors.Errorf("Checksum algorithm name %q is too long", algorithm)
+	}
+	if len(value) > xrdCksValueSize {
+		return nil, errors.Errorf("Checksum value of %d bytes is too long", len(value))
+	}
+	buf := make([]byte, xrdCksValueOffset+len(value))
+	copy(buf[0:xrdCksNameSize], algorithm)
+	binary.BigEndian.PutUint64(buf[16:24], uint64(modTime.Unix()))
+	binary.BigEndian.PutUint32(buf[24:28], uint32(computedAt.Unix()-modTime.Unix()))
+	buf[31] = byte(len(value))
+	copy(buf[xrdCksValueOffset:], value)
+	return buf, nil
+}
+
+// Decode a checksum stored in XRootD's XrdCksData layout, returning the algorithm,
+// the checksum value, and the modification time of the file when it was computed
+func decodeChecksumXattr(buf []byte) (algorithm string, value []byte, modTime time.Time, err error) {
+	if len(buf) < xrdCksValueOffset {
+		err = errors.Errorf("Checksum extended attribute is too short (%d bytes)", len(buf))
+		return
+	}
+	algorithm = strings.TrimRight(string(buf[0:xrdCksNameSize]), "\x00")
+	modTime = time.Unix(int64(binary.BigEndian.Uint64(buf[16:24])), 0)
+	length := int(buf[31])
+	if length > xrdCksValueSize || xrdCksValueOffset+length > len(buf) {
+		err = errors.Errorf("Checksum extended attribute has an invalid length %d", length)
+		return
+	}
+	value = make([]byte, length)
+	copy(value, buf[xrdCksValueOffset:xrdCksValueOffset+length])
+	return
+}
+
+// Get the checksum of the file from its extended attributes.  Returns errNoChecksumXattr
+// if there is no checksum or it was computed before the file was last modified.
+func getStoredChecksum(filePath string, algorithm string) ([]byte, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := getXattr(filePath, checksumXattrName(algorithm))
+	if err != nil {
+		return nil, err
+	}
+	storedAlgorithm, value, modTime, err := decodeChecksumXattr(buf)
+	if err != nil {
+		return nil, err
+	}
+	if storedAlgorithm != algorithm || modTime.Unix() != info.ModTime().Unix() {
+		return nil, errNoChecksumXattr
+	}
+	return value, nil
+}
+
+// Compute the checksum of the file and store it in the file's extended attributes
+func computeChecksum(filePath string, algorithm string) ([]byte, error) {
+	hasher, err := newChecksumHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	before, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(hasher, file); err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", filePath)
+	}
+	value := hasher.Sum(nil)
+
+	// Don't record a checksum for a file that changed while we read it
+	after, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		return nil, errors.Errorf("%s was modified while its checksum was computed", filePath)
+	}
+
+	buf, err := encodeChecksumXattr(algorithm, value, before.ModTime(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err = setXattr(filePath, checksumXattrName(algorithm), buf); err != nil {
+		return value, errors.Wrapf(err, "Failed to store the %s checksum of %s", algorithm, filePath)
+	}
+	return value, nil
+}
+
+// Queue the checksum for computation by the background workers.  Returns false
+// if the workers aren't running or the queue is full.
+func queueChecksum(filePath string, algorithm string) bool {
+	if checksumQueue == nil {
+		return false
+	}
+	req := checksumRequest{path: filePath, algorithm: algorithm}
+	checksumPendingMutex.Lock()
+	defer checksumPendingMutex.Unlock()
+	if checksumPending[req] {
+		return true
+	}
+	select {
+	case checksumQueue <- req:
+		checksumPending[req] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Check that every algorithm in Origin.ChecksumAlgorithms is supported
+func validateChecksumAlgorithms() error {
+	for _, algorithm := range param.Origin_ChecksumAlgorithms.GetStringSlice() {
+		if _, err := newChecksumHash(strings.ToLower(algorithm)); err != nil {
+			return errors.Wrap(err, "Invalid Origin.ChecksumAlgorithms; supported algorithms are adler32, md5, and crc32c")
+		}
+	}
+	return nil
+}
+
+// Launch the pool of workers that compute missing checksums in the background
+func LaunchChecksumWorkers(ctx context.Context, egrp *errgroup.Group) {
+	workers := param.Origin_ChecksumWorkers.GetInt()
+	if workers <= 0 {
+		log.Infoln("Origin.ChecksumWorkers is not positive; missing checksums will not be computed")
+		return
+	}
+	checksumQueue = make(chan checksumRequest, checksumQueueLength)
+	for idx := 0; idx < workers; idx++ {
+		egrp.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case req := <-checksumQueue:
+					// XRootD may have computed the checksum since the request was queued
+					if _, err := getStoredChecksum(req.path, req.algorithm); err != nil {
+						if _, err = computeChecksum(req.path, req.algorithm); err != nil {
+							log.Warningf("Failed to compute the %s checksum of %s: %v", req.algorithm, req.path, err)
+						} else {
+							log.Debugf("Computed the %s checksum of %s", req.algorithm, req.path)
+						}
+					}
+					checksumPendingMutex.Lock()
+					delete(checksumPending, req)
+					checksumPendingMutex.Unlock()
+				}
+			}
+		})
+	}
+}
+
+// Parse the algorithms requested by a Want-Digest header (RFC 3230), in order of preference.
+// Algorithms with a q-value of 0 are excluded.
+func parseWantDigest(header string) []string {
+	type wantedDigest struct {
+		algorithm string
+		q         float64
+	}
+	wanted := []wantedDigest{}
+	for _, entry := range strings.Split(header, ",") {
+		parts := strings.Split(entry, ";")
+		algorithm := strings.ToLower(strings.TrimSpace(parts[0]))
+		if algorithm == "" {
+			continue
+		}
+		q := 1.0
+		for _, attr := range parts[1:] {
+			key, val, found := strings.Cut(strings.TrimSpace(attr), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			wanted = append(wanted, wantedDigest{algorithm: algorithm, q: q})
+		}
+	}
+	sort.SliceStable(wanted, func(i, j int) bool { return wanted[i].q > wanted[j].q })
+	algorithms := make([]string, 0, len(wanted))
+	for _, want := range wanted {
+		algorithms = append(algorithms, want.algorithm)
+	}
+	return algorithms
+}
+
+// Map an object path in the federation namespace to its file in the origin's export
+func objectFilePath(objectPath string) (string, error) {
+	objectPath = path.Clean("/" + objectPath)
+	prefix := path.Clean("/" + param.Origin_NamespacePrefix.GetString())
+	if objectPath != prefix && !strings.HasPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/") {
+		return "", errors.Errorf("%s is not exported by this origin", objectPath)
+	}
+	// XRootD's oss.localroot prepends the mount to the full object path
+	return filepath.Join(param.Xrootd_Mount.GetString(), filepath.FromSlash(objectPath)), nil
+}
+
+// GET/HEAD /api/v1.0/origin-api/checksums/*path
+//
+// Return the stored checksums of an object for the algorithms in the request's
+// Want-Digest header in a Digest header.  Missing checksums are queued for
+// computation and the response is 202 Accepted if none are available yet.
+func getObjectChecksums(ctx *gin.Context) {
+	if !param.Origin_EnablePublicReads.GetBool() {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Checksums are only available from the origin API for publicly readable namespaces")
+		return
+	}
+	filePath, err := objectFilePath(ctx.Param("path"))
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, err.Error())
+		return
+	}
+	if info, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "Object not found")
+		return
+	} else if err != nil {
+		log.Errorf("Failed to stat %s: %v", filePath, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to look up the object")
+		return
+	} else if info.IsDir() {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Checksums are only available for objects, not collections")
+		return
+	}
+
+	configured := make(map[string]bool)
+	defaults := []string{}
+	for _, algorithm := range param.Origin_ChecksumAlgorithms.GetStringSlice() {
+		configured[strings.ToLower(algorithm)] = true
+		defaults = append(defaults, strings.ToLower(algorithm))
+	}
+	wanted := parseWantDigest(ctx.GetHeader("Want-Digest"))
+	if len(wanted) == 0 {
+		wanted = defaults
+	}
+
+	res := checksumResponse{Checksums: make(map[string]string)}
+	digests := []string{}
+	supported := false
+	for _, algorithm := range wanted {
+		if !configured[algorithm] {
+			continue
+		}
+		supported = true
+		value, err := getStoredChecksum(filePath, algorithm)
+		if errors.Is(err, errXattrUnsupported) {
+			web_ui.WriteProblem(ctx, http.StatusNotImplemented, common.ErrCodeNotImplemented, "The origin's export filesystem doesn't support extended attributes")
+			return
+		} else if err != nil {
+			if !errors.Is(err, errNoChecksumXattr) {
+				log.Debugf("Failed to read the %s checksum of %s: %v", algorithm, filePath, err)
+			}
+			if queueChecksum(filePath, algorithm) {
+				res.Pending = append(res.Pending, algorithm)
+			}
+			continue
+		}
+		digest := formatDigest(algorithm, value)
+		digests = append(digests, digest)
+		_, res.Checksums[algorithm], _ = strings.Cut(digest, "=")
+	}
+
+	if len(digests) == 0 {
+		if !supported {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "None of the requested digest algorithms are supported; supported algorithms are "+
+				strings.Join(defaults, ", "))
+			return
+		} else if len(res.Pending) == 0 {
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The requested checksums are not available")
+			return
+		}
+		ctx.Header("Retry-After", "5")
+		ctx.JSON(http.StatusAccepted, res)
+		return
+	}
+	ctx.Header("Digest", strings.Join(digests, ","))
+	ctx.JSON(http.StatusOK, res)
+}
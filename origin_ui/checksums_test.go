@@ -0,0 +1,129 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumXattrEncoding(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+	value, err := hex.DecodeString("0a1b2c3d")
+	require.NoError(t, err)
+
+	buf, err := encodeChecksumXattr("adler32", value, modTime, modTime.Add(time.Minute))
+	require.NoError(t, err)
+	// XRootD stores the fixed-size header plus only the used bytes of the value
+	assert.Len(t, buf, xrdCksValueOffset+4)
+
+	algorithm, decoded, decodedModTime, err := decodeChecksumXattr(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "adler32", algorithm)
+	assert.Equal(t, value, decoded)
+	assert.True(t, modTime.Equal(decodedModTime))
+
+	_, _, _, err = decodeChecksumXattr(buf[:10])
+	assert.Error(t, err)
+}
+
+func TestParseWantDigest(t *testing.T) {
+	assert.Equal(t, []string{"adler32"}, parseWantDigest("ADLER32"))
+	assert.Equal(t, []string{"md5", "adler32"}, parseWantDigest("adler32;q=0.3, md5"))
+	assert.Equal(t, []string{"crc32c"}, parseWantDigest("crc32c, md5;q=0"))
+	assert.Empty(t, parseWantDigest(""))
+}
+
+func TestGetObjectChecksums(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.EnablePublicReads", true)
+	viper.Set("Origin.ChecksumAlgorithms", []string{"adler32", "md5"})
+
+	filePath := filepath.Join(mount, "test", "hello.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, os.WriteFile(filePath, []byte("Hello, World!"), 0644))
+
+	// Not every filesystem supports user extended attributes
+	if _, err := computeChecksum(filePath, "adler32"); errors.Is(err, errXattrUnsupported) {
+		t.Skip("Extended attributes are not supported on the test filesystem")
+	} else {
+		require.NoError(t, err)
+	}
+
+	router := gin.Default()
+	router.GET("/checksums/*path", getObjectChecksums)
+	doRequest := func(target string, wantDigest string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", target, nil)
+		if wantDigest != "" {
+			req.Header.Set("Want-Digest", wantDigest)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("stored-checksum", func(t *testing.T) {
+		w := doRequest("/checksums/test/hello.txt", "adler32")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "adler32=1f9e046a", w.Header().Get("Digest"))
+	})
+
+	t.Run("missing-checksum", func(t *testing.T) {
+		// The workers aren't running, so the md5 checksum is never available
+		w := doRequest("/checksums/test/hello.txt", "md5")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doRequest("/checksums/test/hello.txt", "sha512")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		_, err := computeChecksum(filePath, "md5")
+		require.NoError(t, err)
+		w = doRequest("/checksums/test/hello.txt", "md5, adler32;q=0.5")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "md5=ZajifYh5KDgxtmS9i38K1A==,adler32=1f9e046a", w.Header().Get("Digest"))
+	})
+
+	t.Run("stale-checksum", func(t *testing.T) {
+		newModTime := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(filePath, newModTime, newModTime))
+		_, err := getStoredChecksum(filePath, "adler32")
+		assert.ErrorIs(t, err, errNoChecksumXattr)
+	})
+
+	t.Run("outside-export", func(t *testing.T) {
+		w := doRequest("/checksums/other/hello.txt", "adler32")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doRequest("/checksums/test/../../etc/passwd", "adler32")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
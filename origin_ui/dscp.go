@@ -0,0 +1,76 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/utils"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// Find the export of objectPath with the longest prefix among those with a DSCP
+func matchExportDSCP(dscps map[string]int, objectPath string) (exportPath string, dscp int, found bool) {
+	objectPath = path.Clean("/" + objectPath)
+	for prefix, candidate := range dscps {
+		if len(prefix) <= len(exportPath) {
+			continue
+		}
+		if objectPath == prefix || strings.HasPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/") {
+			exportPath, dscp, found = prefix, candidate, true
+		}
+	}
+	return
+}
+
+// Mark the responses for objects of an export with a DSCP in Origin.Exports, so
+// the network can give the export's traffic its own class of service.  The marking
+// is cleared once the response is sent, as the connection may be reused for other
+// exports, and the bytes sent marked are counted by export and DSCP.
+func markExportTraffic(dscps map[string]int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		exportPath, dscp, found := matchExportDSCP(dscps, ctx.Param("path"))
+		conn := web_ui.GetConn(ctx)
+		if !found || conn == nil {
+			ctx.Next()
+			return
+		}
+		if err := utils.SetConnDSCP(conn, dscp); err != nil {
+			log.Debugf("Failed to mark the response for %s with DSCP %d: %v", ctx.Param("path"), dscp, err)
+			ctx.Next()
+			return
+		}
+		defer func() {
+			if err := utils.SetConnDSCP(conn, 0); err != nil {
+				log.Debugln("Failed to clear the DSCP of a connection:", err)
+			}
+		}()
+
+		ctx.Next()
+		if size := ctx.Writer.Size(); size > 0 {
+			metrics.PelicanOriginDSCPMarkedBytes.WithLabelValues(exportPath, strconv.Itoa(dscp)).Add(float64(size))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchExportDSCP(t *testing.T) {
+	dscps := map[string]int{"/data": 8, "/data/priority": 46}
+
+	exportPath, dscp, found := matchExportDSCP(dscps, "/data/run1/file.root")
+	assert.True(t, found)
+	assert.Equal(t, "/data", exportPath)
+	assert.Equal(t, 8, dscp)
+
+	exportPath, dscp, found = matchExportDSCP(dscps, "/data/priority/file.root")
+	assert.True(t, found)
+	assert.Equal(t, "/data/priority", exportPath)
+	assert.Equal(t, 46, dscp)
+
+	exportPath, _, found = matchExportDSCP(dscps, "data/priority")
+	assert.True(t, found)
+	assert.Equal(t, "/data/priority", exportPath)
+
+	_, _, found = matchExportDSCP(dscps, "/database/file.root")
+	assert.False(t, found)
+}
+
+func TestMarkExportTrafficWithoutConn(t *testing.T) {
+	// Requests that didn't come through the web engine have no connection to mark,
+	// but must still be served
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/objects/*path", markExportTraffic(map[string]int{"/data": 8}), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "contents")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/objects/data/file.root", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "contents", w.Body.String())
+}
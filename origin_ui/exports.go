@@ -0,0 +1,242 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	exportStatus struct {
+		Path   string `json:"path"`
+		Paused bool   `json:"paused"`
+	}
+
+	exportPauseRequest struct {
+		Path string `json:"path" binding:"required"`
+	}
+)
+
+var (
+	// Exports paused through the API. A paused export is left out of the origin's
+	// advertisement, so the director stops sending clients to it, and out of the
+	// XRootD authorization, so the origin refuses requests for it. The paused
+	// exports are persisted to Origin.PausedExportsFile and survive a restart.
+	pausedExports      = make(map[string]bool)
+	pausedExportsMutex = sync.RWMutex{}
+
+	// Regenerates the XRootD authorization after an export is paused or resumed
+	exportPauseHook func() error
+)
+
+// Set the function called after an export is paused or resumed.  The origin
+// uses it to rewrite its authfile and scitokens.cfg.
+func SetExportPauseHook(hook func() error) {
+	pausedExportsMutex.Lock()
+	defer pausedExportsMutex.Unlock()
+	exportPauseHook = hook
+}
+
+// Load the exports paused before the origin restarted from Origin.PausedExportsFile.
+// A missing file means no exports are paused.
+func LoadPausedExports() error {
+	paused := []string{}
+	if pausedFile := param.Origin_PausedExportsFile.GetString(); pausedFile != "" {
+		contents, err := os.ReadFile(pausedFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrapf(err, "Failed to read the paused exports file %s", pausedFile)
+		} else if err == nil {
+			if err = yaml.Unmarshal(contents, &paused); err != nil {
+				return errors.Wrapf(err, "Failed to parse the paused exports file %s", pausedFile)
+			}
+		}
+	}
+
+	pausedExportsMutex.Lock()
+	defer pausedExportsMutex.Unlock()
+	pausedExports = make(map[string]bool, len(paused))
+	for _, exportPath := range paused {
+		pausedExports[path.Clean("/"+exportPath)] = true
+	}
+	if len(paused) > 0 {
+		log.Warningln("The following exports are paused and will not be served until resumed:", paused)
+	}
+	return nil
+}
+
+// Atomically write the paused exports to Origin.PausedExportsFile.
+// Must be called with pausedExportsMutex held.
+func persistPausedExportsLocked(paused map[string]bool) error {
+	pausedFile := param.Origin_PausedExportsFile.GetString()
+	if pausedFile == "" {
+		return errors.New("Origin.PausedExportsFile is not set; paused exports can't be persisted")
+	}
+	exportPaths := make([]string, 0, len(paused))
+	for exportPath := range paused {
+		exportPaths = append(exportPaths, exportPath)
+	}
+	sort.Strings(exportPaths)
+	contents, err := yaml.Marshal(exportPaths)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal the paused exports")
+	}
+	if err = os.MkdirAll(filepath.Dir(pausedFile), 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create the directory for the paused exports file %s", pausedFile)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(pausedFile), filepath.Base(pausedFile)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create a temporary paused exports file")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "Failed to write the paused exports file")
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to write the paused exports file")
+	}
+	if err = os.Rename(tmpFile.Name(), pausedFile); err != nil {
+		return errors.Wrapf(err, "Failed to move the paused exports file into place at %s", pausedFile)
+	}
+	return nil
+}
+
+// The namespace prefixes the origin exports
+func getExportPaths() []string {
+	return []string{param.Origin_NamespacePrefix.GetString()}
+}
+
+// Whether the export was paused through the API
+func IsExportPaused(exportPath string) bool {
+	pausedExportsMutex.RLock()
+	defer pausedExportsMutex.RUnlock()
+	return pausedExports[exportPath]
+}
+
+// Get the exports that are currently paused, sorted by path
+func getPausedExports() []string {
+	pausedExportsMutex.RLock()
+	defer pausedExportsMutex.RUnlock()
+	paused := make([]string, 0, len(pausedExports))
+	for exportPath := range pausedExports {
+		paused = append(paused, exportPath)
+	}
+	sort.Strings(paused)
+	return paused
+}
+
+// Pause or resume an export and persist the change. Returns false if the
+// origin doesn't export the path.
+func setExportPaused(exportPath string, paused bool) (bool, error) {
+	exportPath = path.Clean("/" + exportPath)
+	found := false
+	for _, export := range getExportPaths() {
+		if export == exportPath {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	pausedExportsMutex.Lock()
+	defer pausedExportsMutex.Unlock()
+	updated := make(map[string]bool, len(pausedExports)+1)
+	for existing := range pausedExports {
+		updated[existing] = true
+	}
+	if paused {
+		updated[exportPath] = true
+	} else {
+		delete(updated, exportPath)
+	}
+	if err := persistPausedExportsLocked(updated); err != nil {
+		return true, err
+	}
+	pausedExports = updated
+	return true, nil
+}
+
+// Run the hook that regenerates the XRootD authorization, if one is set
+func runExportPauseHook() error {
+	pausedExportsMutex.RLock()
+	hook := exportPauseHook
+	pausedExportsMutex.RUnlock()
+	if hook == nil {
+		return nil
+	}
+	return hook()
+}
+
+// GET /api/v1.0/origin_ui/exports
+func listExports(ctx *gin.Context) {
+	exports := []exportStatus{}
+	for _, exportPath := range getExportPaths() {
+		exports = append(exports, exportStatus{Path: exportPath, Paused: IsExportPaused(exportPath)})
+	}
+	ctx.JSON(http.StatusOK, exports)
+}
+
+func handleExportPause(paused bool) gin.HandlerFunc {
+	action := "resume"
+	if paused {
+		action = "pause"
+	}
+	return func(ctx *gin.Context) {
+		req := exportPauseRequest{}
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		found, err := setExportPaused(req.Path, paused)
+		if !found {
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The origin does not export "+req.Path)
+			return
+		} else if err != nil {
+			log.Errorf("Failed to %s export %s: %v", action, req.Path, err)
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to persist the paused state of "+req.Path)
+			return
+		}
+		log.Infof("Export %s was %sd by user %s", req.Path, action, ctx.GetString("User"))
+		if err = runExportPauseHook(); err != nil {
+			log.Errorf("Failed to update the XRootD authorization after the export %s was %sd: %v", req.Path, action, err)
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "The export was "+action+"d, but the origin's authorization could not be updated")
+			return
+		}
+		// Tell the director right away rather than waiting for the next advertisement
+		server_utils.TriggerAdvertise()
+		ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
+	}
+}
@@ -0,0 +1,100 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResumeExports(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		pausedExportsMutex.Lock()
+		pausedExports = make(map[string]bool)
+		pausedExportsMutex.Unlock()
+	})
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.PausedExportsFile", filepath.Join(t.TempDir(), "paused-exports.yaml"))
+	viper.Set("Server.Hostname", "origin.example.com")
+	viper.Set("Xrootd.Port", 8443)
+	hookCalls := 0
+	SetExportPauseHook(func() error {
+		hookCalls++
+		return nil
+	})
+	t.Cleanup(func() { SetExportPauseHook(nil) })
+
+	router := gin.Default()
+	router.GET("/exports", listExports)
+	router.POST("/exports/pause", handleExportPause(true))
+	router.POST("/exports/resume", handleExportPause(false))
+	doRequest := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	server := &OriginServer{}
+
+	w := doRequest("POST", "/exports/pause", `{"path": "/not-exported"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest("POST", "/exports/pause", `{"path": "/test"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest("GET", "/exports", "")
+	assert.JSONEq(t, `[{"path": "/test", "paused": true}]`, w.Body.String())
+
+	ad, err := server.CreateAdvertisement("test-origin", "https://origin.example.com:8443", "https://origin.example.com:8444")
+	require.NoError(t, err)
+	assert.Empty(t, ad.Namespaces)
+	assert.Equal(t, []string{"/test"}, ad.PausedNamespaces)
+	// The origin's authorization is regenerated without the paused export
+	assert.Equal(t, 1, hookCalls)
+	assert.Empty(t, server.GetAuthorizedPrefixes())
+
+	// The paused state survives a restart
+	pausedExportsMutex.Lock()
+	pausedExports = make(map[string]bool)
+	pausedExportsMutex.Unlock()
+	require.NoError(t, LoadPausedExports())
+	assert.True(t, IsExportPaused("/test"))
+
+	w = doRequest("POST", "/exports/resume", `{"path": "/test"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, hookCalls)
+	assert.Equal(t, []string{"/test"}, server.GetAuthorizedPrefixes())
+	ad, err = server.CreateAdvertisement("test-origin", "https://origin.example.com:8443", "https://origin.example.com:8444")
+	require.NoError(t, err)
+	require.Len(t, ad.Namespaces, 1)
+	assert.Equal(t, "/test", ad.Namespaces[0].Path)
+	assert.Empty(t, ad.PausedNamespaces)
+
+	require.NoError(t, LoadPausedExports())
+	assert.False(t, IsExportPaused("/test"))
+}
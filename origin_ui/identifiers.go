@@ -0,0 +1,74 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Get the persistent identifiers configured in Origin.PersistentIdentifiers for objects and
+// collections of an export, with the identifiers normalized
+func getPersistentIdentifiers(exportPath string) ([]common.PersistentIdentifier, error) {
+	configured := []common.PersistentIdentifier{}
+	if err := param.Origin_PersistentIdentifiers.Unmarshal(&configured); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Origin.PersistentIdentifiers")
+	}
+
+	exportPath = path.Clean(exportPath)
+	identifiers := []common.PersistentIdentifier{}
+	seen := map[string]string{}
+	for _, pid := range configured {
+		identifier, err := common.ParsePersistentIdentifier(pid.Identifier)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid entry of Origin.PersistentIdentifiers")
+		}
+		if pid.Path == "" {
+			return nil, errors.Errorf("the persistent identifier %s in Origin.PersistentIdentifiers has no Path", identifier)
+		}
+		objectPath := path.Clean("/" + pid.Path)
+		if existing, ok := seen[identifier]; ok && existing != objectPath {
+			return nil, errors.Errorf("Origin.PersistentIdentifiers assigns %s to both %s and %s", identifier, existing, objectPath)
+		}
+		seen[identifier] = objectPath
+		if !isWithinExport(objectPath, exportPath) {
+			found := false
+			for _, otherExport := range getExportPaths() {
+				found = found || isWithinExport(objectPath, otherExport)
+			}
+			if !found {
+				return nil, errors.Errorf("the path %s of the persistent identifier %s isn't within any of the origin's exports", objectPath, identifier)
+			}
+			continue
+		}
+		identifiers = append(identifiers, common.PersistentIdentifier{Identifier: identifier, Path: objectPath})
+	}
+	return identifiers, nil
+}
+
+// Whether objectPath is the export at exportPath or within it
+func isWithinExport(objectPath, exportPath string) bool {
+	exportPath = path.Clean(exportPath)
+	return objectPath == exportPath || strings.HasPrefix(objectPath, strings.TrimSuffix(exportPath, "/")+"/")
+}
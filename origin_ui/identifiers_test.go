@@ -0,0 +1,69 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestGetPersistentIdentifiers(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Origin.NamespacePrefix", "/test")
+
+	identifiers, err := getPersistentIdentifiers("/test")
+	require.NoError(t, err)
+	assert.Empty(t, identifiers)
+
+	viper.Set("Origin.PersistentIdentifiers", []map[string]interface{}{
+		{"Identifier": "DOI:10.1234/Dataset", "Path": "/test/dataset"},
+		{"Identifier": "ark:/12345/collection", "Path": "/test/"},
+	})
+	identifiers, err = getPersistentIdentifiers("/test")
+	require.NoError(t, err)
+	assert.Equal(t, []common.PersistentIdentifier{
+		{Identifier: "doi:10.1234/dataset", Path: "/test/dataset"},
+		{Identifier: "ark:12345/collection", Path: "/test"},
+	}, identifiers)
+
+	viper.Set("Origin.PersistentIdentifiers", []map[string]interface{}{
+		{"Identifier": "doi:10.1234/dataset", "Path": "/elsewhere/dataset"},
+	})
+	_, err = getPersistentIdentifiers("/test")
+	assert.ErrorContains(t, err, "isn't within any of the origin's exports")
+
+	viper.Set("Origin.PersistentIdentifiers", []map[string]interface{}{
+		{"Identifier": "doi:10.1234/dataset", "Path": "/test/a"},
+		{"Identifier": "doi:10.1234/DATASET", "Path": "/test/b"},
+	})
+	_, err = getPersistentIdentifiers("/test")
+	assert.ErrorContains(t, err, "assigns doi:10.1234/dataset to both")
+
+	viper.Set("Origin.PersistentIdentifiers", []map[string]interface{}{
+		{"Identifier": "10.1234/dataset", "Path": "/test/dataset"},
+	})
+	_, err = getPersistentIdentifiers("/test")
+	assert.Error(t, err)
+}
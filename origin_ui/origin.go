@@ -23,19 +23,28 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
+	"github.com/pelicanplatform/pelican/web_ui"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
 
+// The directory backing the /pelican/monitoring export used by file transfer tests
+func monitoringDirPath() string {
+	return filepath.Join(param.Xrootd_RunLocation.GetString(), "export", "pelican", "monitoring")
+}
+
 // Configure XrootD directory for both self-based and director-based file transfer tests
 func ConfigureXrootdMonitoringDir() error {
-	pelicanMonitoringPath := filepath.Join(param.Xrootd_RunLocation.GetString(),
-		"export", "pelican", "monitoring")
+	pelicanMonitoringPath := monitoringDirPath()
 
 	uid, err := config.GetDaemonUID()
 	if err != nil {
@@ -68,8 +77,15 @@ func ConfigIssJWKS(router *gin.RouterGroup) error {
 		return errors.New("Origin configuration passed a nil pointer")
 	}
 
-	router.GET("/openid-configuration", ExportOpenIDConfig)
-	router.GET("/issuer.jwks", ExportIssuerJWKS)
+	web_ui.HandleAPI(router, http.MethodGet, "/openid-configuration", web_ui.APIDoc{
+		Summary: "Return the OpenID configuration of the origin's issuer",
+	}, ExportOpenIDConfig)
+	web_ui.HandleAPI(router, http.MethodGet, "/issuer.jwks", web_ui.APIDoc{
+		Summary: "Return the public keys of the origin's issuer as a JWK set",
+	}, ExportIssuerJWKS)
+	web_ui.HandleAPI(router, http.MethodGet, "/namespaces/*path", web_ui.APIDoc{
+		Summary: "Return the OpenID configuration or public keys of the issuer of a namespace with its own key",
+	}, ExportNamespaceIssuerMetadata)
 	return nil
 }
 
@@ -90,3 +106,37 @@ func ExportIssuerJWKS(c *gin.Context) {
 
 	c.Data(http.StatusOK, "application/json; charset=utf-8", buf)
 }
+
+// Serve <prefix>/.well-known/openid-configuration and <prefix>/.well-known/issuer.jwks
+// for the issuers of the namespaces with their own keys in Origin.Exports
+func ExportNamespaceIssuerMetadata(c *gin.Context) {
+	reqPath := c.Param("path")
+	prefix, file := path.Split(reqPath)
+	prefix = strings.TrimSuffix(path.Clean(prefix), "/.well-known")
+	if hasKey, err := config.HasNamespaceIssuerKey(prefix); err != nil || !hasKey {
+		web_ui.WriteProblem(c, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "No issuer for namespace "+prefix)
+		return
+	}
+	switch file {
+	case "openid-configuration":
+		issuerUrl, err := server_utils.GetNamespaceIssuerURL(prefix)
+		if err != nil {
+			web_ui.WriteProblem(c, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to determine the issuer of namespace "+prefix)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"issuer":   issuerUrl.String(),
+			"jwks_uri": issuerUrl.JoinPath(".well-known", "issuer.jwks").String(),
+		})
+	case "issuer.jwks":
+		keys, err := config.GetNamespaceIssuerPublicJWKS(prefix)
+		if err != nil {
+			web_ui.WriteProblem(c, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to load the public keys of namespace "+prefix)
+			return
+		}
+		buf, _ := json.MarshalIndent(keys, "", " ")
+		c.Data(http.StatusOK, "application/json; charset=utf-8", buf)
+	default:
+		web_ui.WriteProblem(c, http.StatusNotFound, common.ErrCodeNotFound, "No such issuer metadata: "+reqPath)
+	}
+}
@@ -21,13 +21,18 @@ package origin_ui
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/director"
 	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -69,13 +74,13 @@ func directorRequestAuthHandler(ctx *gin.Context) {
 	// Check if the Authorization header was provided
 	if authHeader == "" {
 		// Use AbortWithStatusJSON to stop invoking the next chain
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "Authorization header is missing"})
+		web_ui.AbortWithProblem(ctx, 401, common.ErrCodeMissingToken, "Authorization header is missing")
 		return
 	}
 
 	// Check if the Authorization type is Bearer
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "Authorization header is not Bearer type"})
+		web_ui.AbortWithProblem(ctx, 401, common.ErrCodeUnauthenticated, "Authorization header is not Bearer type")
 		return
 	}
 
@@ -85,13 +90,13 @@ func directorRequestAuthHandler(ctx *gin.Context) {
 
 	if err != nil {
 		log.Warningln(fmt.Sprintf("Error when verifying Bearer token: %s", err))
-		ctx.AbortWithStatusJSON(401, gin.H{"error": fmt.Sprintf("Error when verifying Bearer token: %s", err)})
+		web_ui.AbortWithProblem(ctx, 401, common.ErrCodeInvalidToken, fmt.Sprintf("Error when verifying Bearer token: %s", err))
 		return
 	}
 
 	if !valid {
 		log.Warningln("Can't validate Bearer token")
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "Can't validate Bearer token"})
+		web_ui.AbortWithProblem(ctx, 401, common.ErrCodeInvalidToken, "Can't validate Bearer token")
 		return
 	}
 	ctx.Next()
@@ -128,7 +133,7 @@ func directorTestResponse(ctx *gin.Context) {
 	dt := director.DirectorTest{}
 	if err := ctx.ShouldBind(&dt); err != nil {
 		log.Errorf("Invalid director test response")
-		ctx.JSON(400, gin.H{"error": "Invalid director test response"})
+		web_ui.WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Invalid director test response")
 		return
 	}
 	// We will let the timer go timeout if director didn't send a valid json request
@@ -141,7 +146,7 @@ func directorTestResponse(ctx *gin.Context) {
 		ctx.JSON(200, gin.H{"msg": "Success"})
 	} else {
 		log.Errorf("Invalid director test response, status: %s", dt.Status)
-		ctx.JSON(400, gin.H{"error": fmt.Sprintf("Invalid director test response status: %s", dt.Status)})
+		web_ui.WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid director test response status: %s", dt.Status))
 	}
 }
 
@@ -151,12 +156,90 @@ func ConfigureOriginAPI(router *gin.Engine, ctx context.Context, egrp *errgroup.
 		return errors.New("Origin configuration passed a nil pointer")
 	}
 
+	// Catch a bad upload policy at startup rather than at the first advertisement
+	for _, exportPath := range getExportPaths() {
+		if _, err := getUploadPolicy(exportPath); err != nil {
+			return err
+		}
+	}
+	launchWriteModeUpdates(ctx, egrp)
+	if err := launchUploadPolicyEnforcement(ctx, egrp); err != nil {
+		return err
+	}
+
 	metrics.SetComponentHealthStatus(metrics.OriginCache_Director, metrics.StatusWarning, "Initializing origin, unknown status for director")
 	// start the timer for the director test report timeout
 	LaunchPeriodicDirectorTimeout(ctx, egrp)
 
 	group := router.Group("/api/v1.0/origin-api")
-	group.POST("/directorTest", directorRequestAuthHandler, directorTestResponse)
+	dscps, err := config.GetExportDSCPs()
+	if err != nil {
+		return err
+	}
+	if len(dscps) > 0 {
+		group.Use(markExportTraffic(dscps))
+	}
+	web_ui.HandleAPI(group, http.MethodPost, "/directorTest", web_ui.APIDoc{
+		Summary: "Report the result of the director's test of the origin",
+		Auth:    web_ui.APIAuthBearer,
+		Request: director.DirectorTest{},
+	}, directorRequestAuthHandler, directorTestResponse)
+
+	exportsGroup := router.Group("/api/v1.0/origin_ui/exports")
+	web_ui.HandleAPI(exportsGroup, http.MethodGet, "", web_ui.APIDoc{
+		Summary:  "List the exports of the origin and whether they're paused",
+		Auth:     web_ui.APIAuthLogin,
+		Response: []exportStatus{},
+	}, web_ui.AuthHandler, listExports)
+	web_ui.HandleAPI(exportsGroup, http.MethodPost, "/pause", web_ui.APIDoc{
+		Summary: "Pause an export, so the director stops sending clients to it",
+		Auth:    web_ui.APIAuthAdmin,
+		Request: exportPauseRequest{},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, handleExportPause(true))
+	web_ui.HandleAPI(exportsGroup, http.MethodPost, "/resume", web_ui.APIDoc{
+		Summary: "Resume a paused export",
+		Auth:    web_ui.APIAuthAdmin,
+		Request: exportPauseRequest{},
+	}, web_ui.AuthHandler, web_ui.AdminAuthHandler, handleExportPause(false))
+
+	// Checksums are kept in extended attributes of the exported files, so they're only available for POSIX exports
+	if param.Origin_Mode.GetString() == "posix" {
+		if err := validateChecksumAlgorithms(); err != nil {
+			return err
+		}
+		LaunchChecksumWorkers(ctx, egrp)
+		checksumsDoc := web_ui.APIDoc{
+			Summary:     "Return the stored checksums of the object in a Digest header",
+			Description: "The algorithms are requested in the Want-Digest header.  Missing checksums are queued for computation.",
+			Responses:   map[int]string{http.StatusOK: "The Digest header has the checksums", http.StatusAccepted: "None of the checksums are available yet"},
+			Response:    checksumResponse{},
+		}
+		web_ui.HandleAPI(group, http.MethodGet, "/checksums/*path", checksumsDoc, getObjectChecksums)
+		web_ui.HandleAPI(group, http.MethodHead, "/checksums/*path", checksumsDoc, getObjectChecksums)
+
+		if param.Origin_EnableScrubber.GetBool() {
+			web_ui.HandleAPI(&router.RouterGroup, http.MethodGet, "/api/v1.0/origin_ui/scrub", web_ui.APIDoc{
+				Summary:  "Report the progress of the scrubber and the corrupt objects it found",
+				Auth:     web_ui.APIAuthLogin,
+				Response: scrubStatus{},
+			}, web_ui.AuthHandler, getScrubStatus)
+			egrp.Go(func() error { return PeriodicScrub(ctx) })
+		}
+	}
+
+	if param.Origin_Mode.GetString() == "hsm" {
+		configureStaging(ctx, group)
+	}
+
+	if param.Origin_Mode.GetString() == "s3" {
+		if err := configureS3Encryption(ctx); err != nil {
+			return err
+		}
+		web_ui.HandleAPI(group, http.MethodGet, "/stat/*path", web_ui.APIDoc{
+			Summary:  "Report the size, modification time and server-side encryption of an object in the S3 bucket",
+			Response: s3ObjectStat{},
+		}, getS3ObjectStat)
+	}
 
 	return nil
 }
@@ -0,0 +1,57 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+func TestOriginAPIDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, mode := range []string{"posix", "hsm"} {
+		t.Run(mode, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			t.Cleanup(func() { stageCtx = context.Background() })
+			viper.Set("Origin.NamespacePrefix", "/test")
+			viper.Set("Origin.Mode", mode)
+			viper.Set("Origin.EnableScrubber", true)
+
+			// The background workers exit right away on the cancelled context
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			egrp := &errgroup.Group{}
+			engine := gin.New()
+			require.NoError(t, ConfigureOriginAPI(engine, ctx, egrp))
+			require.NoError(t, ConfigIssJWKS(engine.Group("/.well-known")))
+			require.NoError(t, egrp.Wait())
+
+			assert.Empty(t, web_ui.UndocumentedRoutes(engine.Routes()))
+		})
+	}
+}
@@ -0,0 +1,205 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/sse"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// The server-side encryption of an S3 object or the default of a bucket
+	s3Encryption struct {
+		// "AES256" for SSE-S3, "aws:kms" for SSE-KMS, or empty if unencrypted
+		Algorithm string `json:"algorithm"`
+		KMSKeyId  string `json:"kmsKeyId,omitempty"`
+	}
+
+	s3ObjectStat struct {
+		Path       string       `json:"path"`
+		Size       int64        `json:"size"`
+		Modified   time.Time    `json:"modified"`
+		Encryption s3Encryption `json:"encryption"`
+	}
+
+	// The part of the S3 API the origin uses, which *minio.Client implements
+	s3Client interface {
+		GetBucketEncryption(ctx context.Context, bucketName string) (*sse.Configuration, error)
+		SetBucketEncryption(ctx context.Context, bucketName string, config *sse.Configuration) error
+		StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	}
+)
+
+const (
+	s3AlgorithmSSES3  = "AES256"
+	s3AlgorithmSSEKMS = "aws:kms"
+)
+
+// Connect to the S3 service of an origin in "s3" mode; a variable so tests can replace it
+var newS3Client = func() (s3Client, error) {
+	serviceUrl, err := url.Parse(param.Origin_S3ServiceUrl.GetString())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Origin.S3ServiceUrl")
+	}
+	creds := credentials.NewStaticV4("", "", "")
+	if accessKeyFile := param.Origin_S3AccessKeyfile.GetString(); accessKeyFile != "" {
+		accessKey, err := os.ReadFile(accessKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read Origin.S3AccessKeyfile")
+		}
+		secretKey, err := os.ReadFile(param.Origin_S3SecretKeyfile.GetString())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read Origin.S3SecretKeyfile")
+		}
+		creds = credentials.NewStaticV4(strings.TrimSpace(string(accessKey)), strings.TrimSpace(string(secretKey)), "")
+	}
+	return minio.New(serviceUrl.Host, &minio.Options{
+		Creds:     creds,
+		Secure:    serviceUrl.Scheme != "http",
+		Region:    param.Origin_S3Region.GetString(),
+		Transport: config.GetTransport(),
+	})
+}
+
+// Get the default encryption Origin.S3ServerSideEncryption asks for, or nil if the bucket's
+// own setting is left alone
+func getWantedS3Encryption() (*s3Encryption, error) {
+	keyId := param.Origin_S3KMSKeyId.GetString()
+	switch mode := param.Origin_S3ServerSideEncryption.GetString(); mode {
+	case "":
+		if keyId != "" {
+			return nil, errors.New("Origin.S3KMSKeyId is only used with Origin.S3ServerSideEncryption set to SSE-KMS")
+		}
+		return nil, nil
+	case "SSE-S3":
+		if keyId != "" {
+			return nil, errors.New("Origin.S3KMSKeyId is only used with Origin.S3ServerSideEncryption set to SSE-KMS")
+		}
+		return &s3Encryption{Algorithm: s3AlgorithmSSES3}, nil
+	case "SSE-KMS":
+		if keyId == "" {
+			return nil, errors.New("Origin.S3ServerSideEncryption set to SSE-KMS needs the key to encrypt with in Origin.S3KMSKeyId")
+		}
+		return &s3Encryption{Algorithm: s3AlgorithmSSEKMS, KMSKeyId: keyId}, nil
+	default:
+		return nil, errors.Errorf("unknown Origin.S3ServerSideEncryption %q; must be SSE-S3 or SSE-KMS", mode)
+	}
+}
+
+// Get the default encryption set in a bucket's encryption configuration
+func getBucketEncryption(config *sse.Configuration) s3Encryption {
+	if config == nil || len(config.Rules) == 0 {
+		return s3Encryption{}
+	}
+	return s3Encryption{
+		Algorithm: config.Rules[0].Apply.SSEAlgorithm,
+		KMSKeyId:  config.Rules[0].Apply.KmsMasterKeyID,
+	}
+}
+
+// Make the origin's bucket encrypt new objects as Origin.S3ServerSideEncryption asks.  The
+// XRootD S3 plugin doesn't ask for encryption itself, so the uploads through the federation
+// are encrypted by the bucket's default encryption, which is set if it doesn't match.
+func configureS3Encryption(ctx context.Context) error {
+	wanted, err := getWantedS3Encryption()
+	if err != nil || wanted == nil {
+		return err
+	}
+	client, err := newS3Client()
+	if err != nil {
+		return err
+	}
+	bucket := param.Origin_S3Bucket.GetString()
+	// A bucket without default encryption answers with an error
+	if current, err := client.GetBucketEncryption(ctx, bucket); err == nil && getBucketEncryption(current) == *wanted {
+		log.Infof("Bucket %s already encrypts new objects with %s", bucket, wanted.Algorithm)
+		return nil
+	}
+
+	encryptionConfig := sse.NewConfigurationSSES3()
+	if wanted.Algorithm == s3AlgorithmSSEKMS {
+		encryptionConfig = sse.NewConfigurationSSEKMS(wanted.KMSKeyId)
+	}
+	if err = client.SetBucketEncryption(ctx, bucket, encryptionConfig); err != nil {
+		return errors.Wrapf(err, "failed to set the default encryption of bucket %s to %s; the origin's credentials need the "+
+			"s3:PutEncryptionConfiguration permission, or the bucket's default encryption must be set beforehand", bucket, wanted.Algorithm)
+	}
+	log.Infof("Set the default encryption of bucket %s to %s", bucket, wanted.Algorithm)
+	return nil
+}
+
+// GET /api/v1.0/origin-api/stat/*path
+//
+// Return the size, modification time and server-side encryption of an object of an
+// origin in "s3" mode
+func getS3ObjectStat(ctx *gin.Context) {
+	if !param.Origin_EnablePublicReads.GetBool() {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Object information is only available from the origin API for publicly readable namespaces")
+		return
+	}
+	objectPath := path.Clean("/" + ctx.Param("path"))
+	prefix := path.Clean(param.Origin_NamespacePrefix.GetString())
+	key := strings.TrimPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/")
+	if key == objectPath {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, objectPath+" is not exported by this origin")
+		return
+	}
+	client, err := newS3Client()
+	if err != nil {
+		log.Errorln("Failed to connect to the S3 service:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to connect to the S3 service")
+		return
+	}
+	info, err := client.StatObject(ctx.Request.Context(), param.Origin_S3Bucket.GetString(), key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "Object not found")
+			return
+		}
+		log.Errorf("Failed to stat %s in S3: %v", key, err)
+		web_ui.WriteProblem(ctx, http.StatusBadGateway, common.ErrCodeUnavailable, "Failed to look up the object in S3")
+		return
+	}
+	ctx.JSON(http.StatusOK, s3ObjectStat{
+		Path:     objectPath,
+		Size:     info.Size,
+		Modified: info.LastModified,
+		Encryption: s3Encryption{
+			Algorithm: info.Metadata.Get("X-Amz-Server-Side-Encryption"),
+			KMSKeyId:  info.Metadata.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"),
+		},
+	})
+}
@@ -0,0 +1,181 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/sse"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeS3Client struct {
+	encryption *sse.Configuration
+	objects    map[string]minio.ObjectInfo
+	setCalls   int
+}
+
+func (c *fakeS3Client) GetBucketEncryption(ctx context.Context, bucketName string) (*sse.Configuration, error) {
+	if c.encryption == nil {
+		return nil, minio.ErrorResponse{StatusCode: http.StatusNotFound, Code: "ServerSideEncryptionConfigurationNotFoundError"}
+	}
+	return c.encryption, nil
+}
+
+func (c *fakeS3Client) SetBucketEncryption(ctx context.Context, bucketName string, config *sse.Configuration) error {
+	c.setCalls++
+	c.encryption = config
+	return nil
+}
+
+func (c *fakeS3Client) StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	info, ok := c.objects[objectName]
+	if !ok {
+		return minio.ObjectInfo{}, minio.ErrorResponse{StatusCode: http.StatusNotFound, Code: "NoSuchKey"}
+	}
+	return info, nil
+}
+
+func useFakeS3Client(t *testing.T, client *fakeS3Client) {
+	oldNewS3Client := newS3Client
+	newS3Client = func() (s3Client, error) { return client, nil }
+	t.Cleanup(func() { newS3Client = oldNewS3Client })
+}
+
+func TestGetWantedS3Encryption(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	wanted, err := getWantedS3Encryption()
+	require.NoError(t, err)
+	assert.Nil(t, wanted)
+
+	viper.Set("Origin.S3ServerSideEncryption", "SSE-S3")
+	wanted, err = getWantedS3Encryption()
+	require.NoError(t, err)
+	assert.Equal(t, &s3Encryption{Algorithm: s3AlgorithmSSES3}, wanted)
+
+	viper.Set("Origin.S3ServerSideEncryption", "SSE-KMS")
+	_, err = getWantedS3Encryption()
+	assert.Error(t, err)
+	viper.Set("Origin.S3KMSKeyId", "pelican-key")
+	wanted, err = getWantedS3Encryption()
+	require.NoError(t, err)
+	assert.Equal(t, &s3Encryption{Algorithm: s3AlgorithmSSEKMS, KMSKeyId: "pelican-key"}, wanted)
+
+	viper.Set("Origin.S3ServerSideEncryption", "SSE-S3")
+	_, err = getWantedS3Encryption()
+	assert.Error(t, err)
+
+	viper.Set("Origin.S3ServerSideEncryption", "SSE-C")
+	_, err = getWantedS3Encryption()
+	assert.Error(t, err)
+}
+
+func TestConfigureS3Encryption(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Origin.S3Bucket", "test-bucket")
+	ctx := context.Background()
+
+	t.Run("unconfigured", func(t *testing.T) {
+		client := &fakeS3Client{}
+		useFakeS3Client(t, client)
+		require.NoError(t, configureS3Encryption(ctx))
+		assert.Equal(t, 0, client.setCalls)
+	})
+
+	t.Run("sets-missing-encryption", func(t *testing.T) {
+		viper.Set("Origin.S3ServerSideEncryption", "SSE-KMS")
+		viper.Set("Origin.S3KMSKeyId", "pelican-key")
+		client := &fakeS3Client{}
+		useFakeS3Client(t, client)
+		require.NoError(t, configureS3Encryption(ctx))
+		assert.Equal(t, 1, client.setCalls)
+		assert.Equal(t, s3Encryption{Algorithm: s3AlgorithmSSEKMS, KMSKeyId: "pelican-key"}, getBucketEncryption(client.encryption))
+
+		// Once the bucket matches, it's left alone
+		require.NoError(t, configureS3Encryption(ctx))
+		assert.Equal(t, 1, client.setCalls)
+	})
+
+	t.Run("replaces-mismatched-encryption", func(t *testing.T) {
+		viper.Set("Origin.S3ServerSideEncryption", "SSE-S3")
+		viper.Set("Origin.S3KMSKeyId", "")
+		client := &fakeS3Client{encryption: sse.NewConfigurationSSEKMS("pelican-key")}
+		useFakeS3Client(t, client)
+		require.NoError(t, configureS3Encryption(ctx))
+		assert.Equal(t, 1, client.setCalls)
+		assert.Equal(t, s3Encryption{Algorithm: s3AlgorithmSSES3}, getBucketEncryption(client.encryption))
+	})
+}
+
+func TestGetS3ObjectStat(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.S3Bucket", "test-bucket")
+
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	metadata := http.Header{}
+	metadata.Set("X-Amz-Server-Side-Encryption", s3AlgorithmSSEKMS)
+	metadata.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", "pelican-key")
+	useFakeS3Client(t, &fakeS3Client{objects: map[string]minio.ObjectInfo{
+		"dir/hello.txt": {Size: 13, LastModified: modified, Metadata: metadata},
+	}})
+
+	router := gin.Default()
+	router.GET("/stat/*path", getS3ObjectStat)
+	doRequest := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", target, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only publicly readable namespaces are served
+	w := doRequest("/stat/test/dir/hello.txt")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	viper.Set("Origin.EnablePublicReads", true)
+
+	w = doRequest("/stat/test/dir/hello.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	var stat s3ObjectStat
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stat))
+	assert.Equal(t, s3ObjectStat{
+		Path:       "/test/dir/hello.txt",
+		Size:       13,
+		Modified:   modified,
+		Encryption: s3Encryption{Algorithm: s3AlgorithmSSEKMS, KMSKeyId: "pelican-key"},
+	}, stat)
+
+	w = doRequest("/stat/test/dir/missing.txt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest("/stat/other/dir/hello.txt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,344 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file implements the origin's checksum scrubber, which slowly re-reads
+// the exported objects and verifies them against their stored checksums to
+// detect silent disk corruption.
+//
+
+package origin_ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	corruptObject struct {
+		Path       string    `json:"path"`
+		Algorithm  string    `json:"algorithm"`
+		Expected   string    `json:"expected"`
+		Actual     string    `json:"actual"`
+		DetectedAt time.Time `json:"detected_at"`
+	}
+
+	scrubStatus struct {
+		Running           bool            `json:"running"`
+		PassStarted       time.Time       `json:"pass_started,omitempty"`
+		LastPassCompleted time.Time       `json:"last_pass_completed,omitempty"`
+		ObjectsScrubbed   int64           `json:"objects_scrubbed"` // In the current or last pass
+		BytesScrubbed     int64           `json:"bytes_scrubbed"`   // In the current or last pass
+		CorruptObjects    []corruptObject `json:"corrupt_objects"`
+	}
+
+	// Paces reads to an average rate over a scrub pass
+	scrubPacer struct {
+		bandwidth int64 // Bytes per second; 0 for no limit
+		start     time.Time
+		read      int64
+	}
+
+	pacedReader struct {
+		ctx    context.Context
+		reader io.Reader
+		pacer  *scrubPacer
+	}
+)
+
+var (
+	scrubState = scrubStatus{}
+	// Corrupt objects keyed by file path and then algorithm
+	corruptObjects = make(map[string]map[string]corruptObject)
+	scrubMutex     = sync.Mutex{}
+)
+
+// Wait until reading n more bytes keeps the average rate within the bandwidth
+func (pacer *scrubPacer) wait(ctx context.Context, n int) error {
+	pacer.read += int64(n)
+	if pacer.bandwidth <= 0 {
+		return nil
+	}
+	expected := time.Duration(float64(pacer.read) / float64(pacer.bandwidth) * float64(time.Second))
+	if delay := expected - time.Since(pacer.start); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+func (reader *pacedReader) Read(buf []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	// Read in small chunks so the pacing is smooth
+	if chunk := int(reader.pacer.bandwidth / 10); chunk >= 4096 && len(buf) > chunk {
+		buf = buf[:chunk]
+	}
+	n, err := reader.reader.Read(buf)
+	if waitErr := reader.pacer.wait(reader.ctx, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+func updateCorruptObjectsLocked() {
+	metrics.PelicanOriginCorruptObjects.Set(float64(len(corruptObjects)))
+	if len(corruptObjects) == 0 {
+		metrics.SetComponentHealthStatus(metrics.Origin_Scrubber, metrics.StatusOK, "")
+	} else {
+		metrics.SetComponentHealthStatus(metrics.Origin_Scrubber, metrics.StatusCritical,
+			"Found exported objects whose contents don't match their stored checksums; see /api/v1.0/origin_ui/scrub")
+	}
+}
+
+// Record the outcome of verifying a file's checksum for the algorithm; a nil corruption
+// means the file matched (or changed, so the stored checksum no longer applies)
+func recordScrubResult(filePath string, algorithm string, corruption *corruptObject) {
+	scrubMutex.Lock()
+	defer scrubMutex.Unlock()
+	if corruption == nil {
+		if byAlgorithm, ok := corruptObjects[filePath]; ok {
+			delete(byAlgorithm, algorithm)
+			if len(byAlgorithm) == 0 {
+				delete(corruptObjects, filePath)
+			}
+		}
+	} else {
+		if _, ok := corruptObjects[filePath]; !ok {
+			corruptObjects[filePath] = make(map[string]corruptObject)
+		}
+		corruptObjects[filePath][algorithm] = *corruption
+	}
+	updateCorruptObjectsLocked()
+}
+
+// Verify the file against its stored checksums, returning the number of bytes read
+func scrubFile(ctx context.Context, pacer *scrubPacer, filePath string, objectPath string) (int64, error) {
+	stored := make(map[string][]byte)
+	hashers := make(map[string]hash.Hash)
+	writers := []io.Writer{}
+	for _, algorithm := range param.Origin_ChecksumAlgorithms.GetStringSlice() {
+		algorithm = strings.ToLower(algorithm)
+		value, err := getStoredChecksum(filePath, algorithm)
+		if errors.Is(err, errNoChecksumXattr) {
+			// The file was modified since its checksum was computed, so it can't be verified
+			recordScrubResult(filePath, algorithm, nil)
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		hasher, err := newChecksumHash(algorithm)
+		if err != nil {
+			return 0, err
+		}
+		stored[algorithm] = value
+		hashers[algorithm] = hasher
+		writers = append(writers, hasher)
+	}
+	if len(writers) == 0 {
+		return 0, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	before, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	read, err := io.Copy(io.MultiWriter(writers...), &pacedReader{ctx: ctx, reader: file, pacer: pacer})
+	if err != nil {
+		return read, errors.Wrapf(err, "Failed to read %s", filePath)
+	}
+	// A file that changed while it was read gets a new checksum rather than being corrupt
+	if after, err := os.Stat(filePath); err != nil || !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		return read, nil
+	}
+
+	for algorithm, hasher := range hashers {
+		actual := hasher.Sum(nil)
+		if bytes.Equal(actual, stored[algorithm]) {
+			recordScrubResult(filePath, algorithm, nil)
+			continue
+		}
+		log.Errorf("Object %s is corrupt: its %s checksum is %s but %s was stored when it was written",
+			objectPath, algorithm, hex.EncodeToString(actual), hex.EncodeToString(stored[algorithm]))
+		recordScrubResult(filePath, algorithm, &corruptObject{
+			Path:       objectPath,
+			Algorithm:  algorithm,
+			Expected:   hex.EncodeToString(stored[algorithm]),
+			Actual:     hex.EncodeToString(actual),
+			DetectedAt: time.Now(),
+		})
+	}
+	return read, nil
+}
+
+// Make one pass of the scrubber over the origin's exported objects
+func scrubExports(ctx context.Context) error {
+	mount := param.Xrootd_Mount.GetString()
+	exportDir, err := objectFilePath(param.Origin_NamespacePrefix.GetString())
+	if err != nil {
+		return err
+	}
+	pacer := &scrubPacer{bandwidth: int64(param.Origin_ScrubBandwidth.GetInt()), start: time.Now()}
+
+	scrubMutex.Lock()
+	scrubState.Running = true
+	scrubState.PassStarted = pacer.start
+	scrubState.ObjectsScrubbed = 0
+	scrubState.BytesScrubbed = 0
+	// Objects that were removed since the last pass are no longer corrupt
+	for filePath := range corruptObjects {
+		if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+			delete(corruptObjects, filePath)
+		}
+	}
+	updateCorruptObjectsLocked()
+	scrubMutex.Unlock()
+	defer func() {
+		scrubMutex.Lock()
+		scrubState.Running = false
+		scrubMutex.Unlock()
+	}()
+
+	log.Infoln("Starting a scrub of the objects exported from", exportDir)
+	err = filepath.WalkDir(exportDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Keep scrubbing the rest of the export if part of it isn't readable
+			log.Warningln("Failed to scrub part of the export:", err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(mount, filePath)
+		if err != nil {
+			return nil
+		}
+		objectPath := "/" + filepath.ToSlash(relPath)
+		read, err := scrubFile(ctx, pacer, filePath, objectPath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if errors.Is(err, errXattrUnsupported) {
+			return errors.Wrap(err, "The scrubber requires extended attributes on the export")
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warningf("Failed to scrub %s: %v", objectPath, err)
+		}
+		metrics.PelicanOriginScrubbedBytes.Add(float64(read))
+		scrubMutex.Lock()
+		scrubState.BytesScrubbed += read
+		if read > 0 {
+			scrubState.ObjectsScrubbed++
+			metrics.PelicanOriginScrubbedObjects.Inc()
+		}
+		scrubMutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	scrubMutex.Lock()
+	scrubState.LastPassCompleted = time.Now()
+	log.Infof("Finished scrubbing %d objects (%d bytes) in %s", scrubState.ObjectsScrubbed, scrubState.BytesScrubbed,
+		scrubState.LastPassCompleted.Sub(scrubState.PassStarted).Round(time.Second).String())
+	scrubMutex.Unlock()
+	return nil
+}
+
+// Periodically scrub the origin's exported objects until the context is canceled
+func PeriodicScrub(ctx context.Context) error {
+	interval := param.Origin_ScrubInterval.GetDuration()
+	metrics.SetComponentHealthStatus(metrics.Origin_Scrubber, metrics.StatusOK, "")
+	for {
+		start := time.Now()
+		if err := scrubExports(ctx); ctx.Err() != nil {
+			return nil
+		} else if errors.Is(err, errXattrUnsupported) {
+			log.Errorln("Disabling the scrubber:", err)
+			metrics.SetComponentHealthStatus(metrics.Origin_Scrubber, metrics.StatusWarning, err.Error())
+			return nil
+		} else if err != nil {
+			log.Warningln("Failed to scrub the exported objects:", err)
+		}
+
+		wait := interval - time.Since(start)
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// GET /api/v1.0/origin_ui/scrub
+//
+// Report the progress of the scrubber and the corrupt objects it found
+func getScrubStatus(ctx *gin.Context) {
+	scrubMutex.Lock()
+	status := scrubState
+	status.CorruptObjects = make([]corruptObject, 0, len(corruptObjects))
+	for _, byAlgorithm := range corruptObjects {
+		for _, corruption := range byAlgorithm {
+			status.CorruptObjects = append(status.CorruptObjects, corruption)
+		}
+	}
+	scrubMutex.Unlock()
+
+	sort.Slice(status.CorruptObjects, func(i, j int) bool {
+		if status.CorruptObjects[i].Path != status.CorruptObjects[j].Path {
+			return status.CorruptObjects[i].Path < status.CorruptObjects[j].Path
+		}
+		return status.CorruptObjects[i].Algorithm < status.CorruptObjects[j].Algorithm
+	})
+	ctx.JSON(http.StatusOK, status)
+}
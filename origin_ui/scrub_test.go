@@ -0,0 +1,125 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrubPacer(t *testing.T) {
+	pacer := &scrubPacer{bandwidth: 1000, start: time.Now()}
+	require.NoError(t, pacer.wait(context.Background(), 200))
+	// 200 bytes at 1000 bytes/s should take about 200ms
+	assert.GreaterOrEqual(t, time.Since(pacer.start), 190*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, pacer.wait(ctx, 10000), context.Canceled)
+
+	unlimited := &scrubPacer{start: time.Now()}
+	require.NoError(t, unlimited.wait(context.Background(), 1<<30))
+	assert.Less(t, time.Since(unlimited.start), time.Second)
+}
+
+func TestScrubExports(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		scrubMutex.Lock()
+		corruptObjects = make(map[string]map[string]corruptObject)
+		scrubState = scrubStatus{}
+		scrubMutex.Unlock()
+	})
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.ChecksumAlgorithms", []string{"adler32", "md5"})
+	viper.Set("Origin.ScrubBandwidth", 0)
+
+	goodPath := filepath.Join(mount, "test", "good.txt")
+	badPath := filepath.Join(mount, "test", "sub", "bad.txt")
+	uncheckedPath := filepath.Join(mount, "test", "unchecked.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(badPath), 0755))
+	require.NoError(t, os.WriteFile(goodPath, []byte("Hello, World!"), 0644))
+	require.NoError(t, os.WriteFile(badPath, []byte("Hello, Pelican!"), 0644))
+	require.NoError(t, os.WriteFile(uncheckedPath, []byte("No checksums here"), 0644))
+
+	// Not every filesystem supports user extended attributes
+	if _, err := computeChecksum(goodPath, "adler32"); errors.Is(err, errXattrUnsupported) {
+		t.Skip("Extended attributes are not supported on the test filesystem")
+	} else {
+		require.NoError(t, err)
+	}
+	for _, filePath := range []string{goodPath, badPath} {
+		for _, algorithm := range []string{"adler32", "md5"} {
+			_, err := computeChecksum(filePath, algorithm)
+			require.NoError(t, err)
+		}
+	}
+
+	getStatus := func() scrubStatus {
+		router := gin.Default()
+		router.GET("/scrub", getScrubStatus)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/scrub", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		status := scrubStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status
+	}
+
+	require.NoError(t, scrubExports(context.Background()))
+	status := getStatus()
+	assert.False(t, status.Running)
+	assert.Equal(t, int64(2), status.ObjectsScrubbed)
+	assert.Empty(t, status.CorruptObjects)
+
+	// Silently corrupt the file: same size and modification time
+	info, err := os.Stat(badPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(badPath, []byte("Hello, PelicaN!"), 0644))
+	require.NoError(t, os.Chtimes(badPath, info.ModTime(), info.ModTime()))
+
+	require.NoError(t, scrubExports(context.Background()))
+	status = getStatus()
+	require.Len(t, status.CorruptObjects, 2)
+	assert.Equal(t, "/test/sub/bad.txt", status.CorruptObjects[0].Path)
+	assert.Equal(t, "adler32", status.CorruptObjects[0].Algorithm)
+	assert.Equal(t, "md5", status.CorruptObjects[1].Algorithm)
+
+	// Restoring the object from a good copy clears the report
+	require.NoError(t, os.WriteFile(badPath, []byte("Hello, Pelican!"), 0644))
+	require.NoError(t, os.Chtimes(badPath, info.ModTime(), info.ModTime()))
+	require.NoError(t, scrubExports(context.Background()))
+	assert.Empty(t, getStatus().CorruptObjects)
+}
@@ -20,6 +20,9 @@ package origin_ui
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pelicanplatform/pelican/metrics"
@@ -64,3 +67,69 @@ func PeriodicSelfTest(ctx context.Context) error {
 		}
 	}
 }
+
+// Remove the self-test and director-test objects in the monitoring directory that are
+// older than `retention`. Tests delete their objects on success, so these are left
+// behind by failed tests. Returns the number of objects removed.
+func cleanupTestFiles(monitoringDir string, retention time.Duration) (int, error) {
+	entries, err := os.ReadDir(monitoringDir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	cutoff := time.Now().Add(-retention)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		if !strings.HasPrefix(name, utils.OriginSelfFileTest.String()+"-") && !strings.HasPrefix(name, utils.DirectorFileTest.String()+"-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed since we listed the directory
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(monitoringDir, name)); err != nil && !os.IsNotExist(err) {
+			log.Warningln("Failed to remove stale test file:", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Periodically remove test objects that outlived Monitoring.TestFileRetention
+// so that failed tests don't slowly fill the origin's storage
+func PeriodicTestFileCleanup(ctx context.Context) error {
+	retention := param.Monitoring_TestFileRetention.GetDuration()
+	if retention <= 0 {
+		log.Debugln("Monitoring.TestFileRetention is not positive; test file cleanup is disabled")
+		return nil
+	}
+	monitoringDir := monitoringDirPath()
+	// Sweep several times per retention period so objects don't linger much past it
+	interval := retention / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := cleanupTestFiles(monitoringDir, retention)
+			if err != nil {
+				log.Warningln("Failed to clean up test files in the monitoring directory:", err)
+			} else if removed > 0 {
+				log.Infof("Removed %d test files older than %s from %s", removed, retention.String(), monitoringDir)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
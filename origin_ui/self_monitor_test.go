@@ -0,0 +1,63 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{
+		// name: whether the file should be removed
+		"self-test-2024-01-01T00:00:00Z.txt":     true,
+		"director-test-2024-01-01T00:00:00Z.txt": true,
+		"self-test-recent.txt":                   false,
+		"other-file.txt":                         false,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+		if name != "self-test-recent.txt" {
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+
+	removed, err := cleanupTestFiles(dir, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	for name, shouldRemove := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if shouldRemove {
+			assert.True(t, os.IsNotExist(err), "%s should have been removed", name)
+		} else {
+			assert.NoError(t, err, "%s should have been kept", name)
+		}
+	}
+
+	_, err = cleanupTestFiles(filepath.Join(dir, "missing"), time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file stages objects from tape for an origin in "hsm" mode.  The origin's
+// export is the disk cache of the tape system; an object is online when its disk
+// copy exists.  Offline objects are brought online by Origin.HsmStageCommand,
+// which XRootD also runs when an offline object is read.
+//
+
+package origin_ui
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	stageRequest struct {
+		started time.Time
+		done    bool
+		err     error
+	}
+
+	stageResponse struct {
+		Path    string `json:"path"`
+		Online  bool   `json:"online"`
+		Staging bool   `json:"staging,omitempty"`
+	}
+)
+
+const (
+	// Seconds a client is asked to wait before checking on a staging object again
+	stageRetryAfter = "30"
+)
+
+var (
+	// The staging requests made through the API, keyed by the object's file path.
+	// Requests are dropped once their object is online or their failure is reported.
+	stageRequests      = make(map[string]*stageRequest)
+	stageRequestsMutex = sync.Mutex{}
+	stageCtx           = context.Background()
+)
+
+// Whether the object at filePath is online, i.e. its disk copy exists
+func isObjectOnline(filePath string) (bool, error) {
+	info, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// Run the stage command for the object in the background, unless it's already being staged
+func startStaging(objectPath, filePath string) {
+	stageRequestsMutex.Lock()
+	defer stageRequestsMutex.Unlock()
+	if req, ok := stageRequests[filePath]; ok && !req.done {
+		return
+	}
+	req := &stageRequest{started: time.Now()}
+	stageRequests[filePath] = req
+
+	go func() {
+		err := runStageCommand(objectPath, filePath)
+		if err != nil {
+			log.Warningf("Failed to stage %s: %v", objectPath, err)
+		} else {
+			log.Debugf("Staged %s in %s", objectPath, time.Since(req.started).Truncate(time.Second))
+		}
+		stageRequestsMutex.Lock()
+		defer stageRequestsMutex.Unlock()
+		req.done = true
+		req.err = err
+	}()
+}
+
+// Run Origin.HsmStageCommand with the object's path in the namespace and the path
+// of its disk copy, the same arguments XRootD passes to its oss.stagecmd
+func runStageCommand(objectPath, filePath string) error {
+	cmdLine := strings.Fields(param.Origin_HsmStageCommand.GetString())
+	if len(cmdLine) == 0 {
+		return errors.New("Origin.HsmStageCommand is not set")
+	}
+	ctx := stageCtx
+	if timeout := param.Origin_HsmStageTimeout.GetDuration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(stageCtx, timeout)
+		defer cancel()
+	}
+	args := append(cmdLine[1:], objectPath, filePath)
+	output, err := exec.CommandContext(ctx, cmdLine[0], args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("the stage command didn't finish within Origin.HsmStageTimeout (%s)", param.Origin_HsmStageTimeout.GetDuration())
+	} else if err != nil {
+		return errors.Wrapf(err, "the stage command failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Get the status of the object's staging request, clearing it if it's finished
+func popStageResult(filePath string) (staging bool, err error) {
+	stageRequestsMutex.Lock()
+	defer stageRequestsMutex.Unlock()
+	req, ok := stageRequests[filePath]
+	if !ok {
+		return false, nil
+	}
+	if !req.done {
+		return true, nil
+	}
+	delete(stageRequests, filePath)
+	return false, req.err
+}
+
+// GET/POST /api/v1.0/origin-api/stage/*path
+//
+// Report whether an object is online.  A POST also brings an offline object online
+// with Origin.HsmStageCommand.  The response is 202 Accepted, with a Retry-After
+// header, while the object is being staged.
+func handleStage(ctx *gin.Context) {
+	if !param.Origin_EnablePublicReads.GetBool() {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Staging is only available from the origin API for publicly readable namespaces")
+		return
+	}
+	objectPath := ctx.Param("path")
+	filePath, err := objectFilePath(objectPath)
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, err.Error())
+		return
+	}
+	res := stageResponse{Path: objectPath}
+	online, err := isObjectOnline(filePath)
+	if err != nil {
+		log.Errorf("Failed to stat %s: %v", filePath, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to look up the object")
+		return
+	}
+	if online {
+		// Drop the finished request; the object is online regardless of how it got there
+		_, _ = popStageResult(filePath)
+		res.Online = true
+		ctx.JSON(http.StatusOK, res)
+		return
+	}
+
+	staging, err := popStageResult(filePath)
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to stage the object: "+err.Error())
+		return
+	}
+	if !staging && ctx.Request.Method == http.MethodPost {
+		if param.Origin_HsmStageCommand.GetString() == "" {
+			web_ui.WriteProblem(ctx, http.StatusNotImplemented, common.ErrCodeNotImplemented, "The origin has no stage command configured; objects are only staged when they're read")
+			return
+		}
+		startStaging(objectPath, filePath)
+		staging = true
+	}
+	if staging {
+		res.Staging = true
+		ctx.Header("Retry-After", stageRetryAfter)
+		ctx.JSON(http.StatusAccepted, res)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Set up staging through the origin API; stage commands are killed once ctx is done
+func configureStaging(ctx context.Context, group *gin.RouterGroup) {
+	stageCtx = ctx
+	web_ui.HandleAPI(group, http.MethodGet, "/stage/*path", web_ui.APIDoc{
+		Summary:   "Return whether the object is online or being staged from tape",
+		Responses: map[int]string{http.StatusOK: "The object is online or not being staged", http.StatusAccepted: "The object is being staged"},
+		Response:  stageResponse{},
+	}, handleStage)
+	web_ui.HandleAPI(group, http.MethodPost, "/stage/*path", web_ui.APIDoc{
+		Summary: "Start staging the object from tape if it's offline",
+		Responses: map[int]string{
+			http.StatusOK:             "The object is online",
+			http.StatusAccepted:       "The object is being staged; check again after Retry-After",
+			http.StatusNotImplemented: "The origin has no stage command",
+		},
+		Response: stageResponse{},
+	}, handleStage)
+}
@@ -0,0 +1,97 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStage(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	tape := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.EnablePublicReads", true)
+	viper.Set("Origin.HsmStageTimeout", "1m")
+
+	// The "tape system" copies objects from the tape directory into the disk cache
+	require.NoError(t, os.WriteFile(filepath.Join(tape, "hello.txt"), []byte("Hello, World!"), 0644))
+	script := filepath.Join(t.TempDir(), "stage.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nmkdir -p \"$(dirname \"$2\")\" && cp \""+tape+"/$(basename \"$1\")\" \"$2\"\n"), 0755))
+	viper.Set("Origin.HsmStageCommand", "/bin/sh "+script)
+
+	router := gin.Default()
+	router.GET("/stage/*path", handleStage)
+	router.POST("/stage/*path", handleStage)
+	doRequest := func(method, target string) (*httptest.ResponseRecorder, stageResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, nil)
+		router.ServeHTTP(w, req)
+		res := stageResponse{}
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w, res
+	}
+
+	// Checking on an offline object doesn't stage it
+	w, res := doRequest("GET", "/stage/test/hello.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, res.Online)
+	assert.False(t, res.Staging)
+
+	w, res = doRequest("POST", "/stage/test/hello.txt")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.True(t, res.Staging)
+	assert.Equal(t, stageRetryAfter, w.Header().Get("Retry-After"))
+
+	require.Eventually(t, func() bool {
+		w, res = doRequest("GET", "/stage/test/hello.txt")
+		return w.Code == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.True(t, res.Online)
+	content, err := os.ReadFile(filepath.Join(mount, "test", "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!", string(content))
+
+	// A failed stage is reported once, then the object can be requested again
+	w, _ = doRequest("POST", "/stage/test/missing.txt")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Eventually(t, func() bool {
+		w, _ = doRequest("GET", "/stage/test/missing.txt")
+		return w.Code != http.StatusAccepted
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w, _ = doRequest("GET", "/stage/test/missing.txt")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, _ = doRequest("POST", "/stage/other/hello.txt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,264 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A command or webhook run on the uploads to or deletions from an export, as configured
+	// in Origin.StorageHooks
+	StorageHook struct {
+		Prefix    string        `mapstructure:"Prefix"`
+		Events    []string      `mapstructure:"Events"`
+		Stage     string        `mapstructure:"Stage"`
+		Command   []string      `mapstructure:"Command"`
+		Webhook   string        `mapstructure:"Webhook"`
+		Timeout   time.Duration `mapstructure:"Timeout"`
+		OnFailure string        `mapstructure:"OnFailure"`
+	}
+
+	// The event a storage hook is run for, passed to webhooks as a JSON document
+	storageHookEvent struct {
+		Event string    `json:"event"`
+		Stage string    `json:"stage"`
+		Path  string    `json:"path"`
+		Size  int64     `json:"size"`
+		Time  time.Time `json:"time"`
+	}
+)
+
+const (
+	storageHookUpload = "upload"
+	storageHookDelete = "delete"
+
+	storageHookPre  = "pre"
+	storageHookPost = "post"
+
+	storageHookIgnore = "ignore"
+	storageHookReject = "reject"
+
+	defaultStorageHookTimeout = 30 * time.Second
+)
+
+// Bounds the number of objects whose hooks run at the same time
+var storageHookSlots = make(chan struct{}, 8)
+
+// Get the storage hooks configured in Origin.StorageHooks, with their defaults filled in
+func getStorageHooks() ([]StorageHook, error) {
+	hooks := []StorageHook{}
+	if err := param.Origin_StorageHooks.Unmarshal(&hooks); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Origin.StorageHooks")
+	}
+	for idx := range hooks {
+		hook := &hooks[idx]
+		if hook.Prefix == "" {
+			return nil, errors.New("every entry of Origin.StorageHooks must have a Prefix")
+		}
+		hook.Prefix = path.Clean(hook.Prefix)
+		if len(hook.Events) == 0 {
+			return nil, errors.Errorf("the storage hook for %s must list the Events it runs on", hook.Prefix)
+		}
+		for _, event := range hook.Events {
+			if event != storageHookUpload && event != storageHookDelete {
+				return nil, errors.Errorf("unknown event %q in the storage hook for %s; must be %q or %q", event, hook.Prefix, storageHookUpload, storageHookDelete)
+			}
+		}
+		if (len(hook.Command) == 0) == (hook.Webhook == "") {
+			return nil, errors.Errorf("the storage hook for %s must have exactly one of a Command or a Webhook", hook.Prefix)
+		}
+		if hook.Stage == "" {
+			hook.Stage = storageHookPost
+		}
+		switch hook.Stage {
+		case storageHookPre:
+			// XRootD reports deletions once they're done, so they can't be vetted
+			if hook.runsOn(storageHookDelete) {
+				return nil, errors.Errorf("the storage hook for %s can't run before deletions; only uploads have a %q stage", hook.Prefix, storageHookPre)
+			}
+		case storageHookPost:
+		default:
+			return nil, errors.Errorf("unknown stage %q in the storage hook for %s; must be %q or %q", hook.Stage, hook.Prefix, storageHookPre, storageHookPost)
+		}
+		if hook.OnFailure == "" {
+			hook.OnFailure = storageHookIgnore
+		}
+		if hook.OnFailure != storageHookIgnore && hook.OnFailure != storageHookReject {
+			return nil, errors.Errorf("unknown OnFailure %q in the storage hook for %s; must be %q or %q", hook.OnFailure, hook.Prefix, storageHookIgnore, storageHookReject)
+		}
+		if hook.OnFailure == storageHookReject && hook.Stage != storageHookPre {
+			return nil, errors.Errorf("the storage hook for %s can only reject objects in the %q stage", hook.Prefix, storageHookPre)
+		}
+		if hook.Timeout < 0 {
+			return nil, errors.Errorf("the Timeout of the storage hook for %s must not be negative", hook.Prefix)
+		} else if hook.Timeout == 0 {
+			hook.Timeout = defaultStorageHookTimeout
+		}
+	}
+	return hooks, nil
+}
+
+// Whether the hook runs on the event
+func (hook *StorageHook) runsOn(event string) bool {
+	for _, hookEvent := range hook.Events {
+		if hookEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Run the hook for the event on the object stored in the file at filePath, failing if the
+// command exits with an error or the webhook doesn't answer with a 2xx status
+func (hook *StorageHook) run(ctx context.Context, event storageHookEvent, filePath string) error {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	if hook.Webhook != "" {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Webhook, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		client := http.Client{Transport: config.GetTransport()}
+		resp, err := client.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "webhook %s failed", hook.Webhook)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return errors.Errorf("webhook %s answered with HTTP status %d", hook.Webhook, resp.StatusCode)
+		}
+		return nil
+	}
+
+	// The arguments may name the object with {path} and the file holding it with {file}
+	args := make([]string, len(hook.Command))
+	for idx, arg := range hook.Command {
+		args[idx] = strings.NewReplacer("{path}", event.Path, "{file}", filePath).Replace(arg)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"PELICAN_HOOK_EVENT="+event.Event,
+		"PELICAN_HOOK_STAGE="+event.Stage,
+		"PELICAN_OBJECT_PATH="+event.Path,
+		"PELICAN_OBJECT_FILE="+filePath,
+		"PELICAN_OBJECT_SIZE="+strconv.FormatInt(event.Size, 10),
+	)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("command %s didn't finish within %s", args[0], hook.Timeout)
+	} else if err != nil {
+		return errors.Wrapf(err, "command %s failed: %s", args[0], strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Run the storage hooks of the export holding objectPath for the event XRootD reported on
+// it: first those of the "pre" stage, any of which may reject an upload by having the
+// object removed, then those of the "post" stage.  Returns whether the object was rejected.
+func runStorageHooks(ctx context.Context, event string, objectPath string) (rejected bool) {
+	objectPath = path.Clean("/" + objectPath)
+	hooks, err := getStorageHooks()
+	if err != nil {
+		log.Errorln("Failed to get the storage hooks:", err)
+		return false
+	} else if len(hooks) == 0 {
+		return false
+	}
+	exportPath := ""
+	for _, candidate := range getExportPaths() {
+		prefix := path.Clean(candidate)
+		if objectPath == prefix || strings.HasPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/") {
+			exportPath = prefix
+			break
+		}
+	}
+	filePath, err := objectFilePath(objectPath)
+	if exportPath == "" || err != nil {
+		return false
+	}
+
+	hookEvent := storageHookEvent{Event: event, Path: objectPath, Time: time.Now()}
+	if info, err := os.Stat(filePath); err == nil {
+		// An upload may have been removed for violating the export's upload policy, while
+		// a deleted object may have been restored for being protected by its write mode
+		if event == storageHookDelete {
+			return false
+		}
+		hookEvent.Size = info.Size()
+	} else if event == storageHookUpload {
+		return false
+	}
+
+	for _, stage := range []string{storageHookPre, storageHookPost} {
+		hookEvent.Stage = stage
+		for idx := range hooks {
+			hook := &hooks[idx]
+			if hook.Prefix != exportPath || hook.Stage != stage || !hook.runsOn(event) {
+				continue
+			}
+			err := hook.run(ctx, hookEvent, filePath)
+			if err == nil {
+				continue
+			}
+			if hook.OnFailure != storageHookReject {
+				log.Warningf("The %s hook of the %s of %s failed: %v", stage, event, objectPath, err)
+				continue
+			}
+			if removeErr := os.Remove(filePath); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				log.Errorf("Failed to remove %s, which a storage hook rejected (%v): %v", objectPath, err, removeErr)
+			} else {
+				log.Warningf("Removed %s as a storage hook rejected it: %v", objectPath, err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Run the storage hooks for an event in the background, so slow hooks don't hold up the
+// reading of further events
+func startStorageHooks(event string, objectPath string) {
+	go func() {
+		storageHookSlots <- struct{}{}
+		defer func() { <-storageHookSlots }()
+		runStorageHooks(context.Background(), event, objectPath)
+	}()
+}
@@ -0,0 +1,144 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStorageHooks(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Origin.StorageHooks", []map[string]interface{}{
+		{"Prefix": "/test/", "Events": []string{"upload"}, "Command": []string{"true"}},
+		{"Prefix": "/test", "Events": []string{"upload"}, "Stage": "pre", "Webhook": "https://hooks.example.com", "Timeout": "5m", "OnFailure": "reject"},
+	})
+	hooks, err := getStorageHooks()
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	assert.Equal(t, "/test", hooks[0].Prefix)
+	assert.Equal(t, storageHookPost, hooks[0].Stage)
+	assert.Equal(t, storageHookIgnore, hooks[0].OnFailure)
+	assert.Equal(t, defaultStorageHookTimeout, hooks[0].Timeout)
+	assert.Equal(t, 5*time.Minute, hooks[1].Timeout)
+
+	invalid := []map[string]interface{}{
+		{"Events": []string{"upload"}, "Command": []string{"true"}},
+		{"Prefix": "/test", "Command": []string{"true"}},
+		{"Prefix": "/test", "Events": []string{"rename"}, "Command": []string{"true"}},
+		{"Prefix": "/test", "Events": []string{"upload"}},
+		{"Prefix": "/test", "Events": []string{"upload"}, "Command": []string{"true"}, "Webhook": "https://hooks.example.com"},
+		{"Prefix": "/test", "Events": []string{"delete"}, "Stage": "pre", "Command": []string{"true"}},
+		{"Prefix": "/test", "Events": []string{"upload"}, "OnFailure": "reject", "Command": []string{"true"}},
+		{"Prefix": "/test", "Events": []string{"upload"}, "OnFailure": "retry", "Command": []string{"true"}},
+	}
+	for _, hook := range invalid {
+		viper.Set("Origin.StorageHooks", []map[string]interface{}{hook})
+		_, err := getStorageHooks()
+		assert.Error(t, err, "hook %v should be invalid", hook)
+	}
+}
+
+func TestRunStorageHooks(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	require.NoError(t, os.MkdirAll(filepath.Join(mount, "test"), 0755))
+	ctx := context.Background()
+
+	events := make(chan storageHookEvent, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := storageHookEvent{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	t.Cleanup(webhook.Close)
+
+	// The command sees the object through its arguments and environment
+	scanned := filepath.Join(t.TempDir(), "scanned")
+	viper.Set("Origin.StorageHooks", []map[string]interface{}{
+		{"Prefix": "/test", "Events": []string{"upload"}, "Stage": "pre", "OnFailure": "reject",
+			"Command": []string{"sh", "-c", `test "$PELICAN_OBJECT_SIZE" -le 4 && echo "$1 $PELICAN_OBJECT_PATH" > ` + scanned, "hook", "{file}"}},
+		{"Prefix": "/test", "Events": []string{"upload", "delete"}, "Webhook": webhook.URL},
+	})
+
+	t.Run("accepted-upload", func(t *testing.T) {
+		filePath := filepath.Join(mount, "test", "ok.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+		assert.False(t, runStorageHooks(ctx, storageHookUpload, "/test/ok.txt"))
+		assert.FileExists(t, filePath)
+
+		contents, err := os.ReadFile(scanned)
+		require.NoError(t, err)
+		assert.Equal(t, filePath+" /test/ok.txt\n", string(contents))
+		event := <-events
+		assert.Equal(t, storageHookUpload, event.Event)
+		assert.Equal(t, storageHookPost, event.Stage)
+		assert.Equal(t, "/test/ok.txt", event.Path)
+		assert.Equal(t, int64(4), event.Size)
+	})
+
+	t.Run("rejected-upload", func(t *testing.T) {
+		filePath := filepath.Join(mount, "test", "large.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("too large"), 0644))
+		assert.True(t, runStorageHooks(ctx, storageHookUpload, "/test/large.txt"))
+		assert.NoFileExists(t, filePath)
+		// The post hooks aren't run for a rejected object
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.False(t, runStorageHooks(ctx, storageHookDelete, "/test/gone.txt"))
+		event := <-events
+		assert.Equal(t, storageHookDelete, event.Event)
+		assert.Equal(t, "/test/gone.txt", event.Path)
+
+		// An object restored after its deletion wasn't deleted after all
+		require.NoError(t, os.WriteFile(filepath.Join(mount, "test", "restored.txt"), []byte("data"), 0644))
+		assert.False(t, runStorageHooks(ctx, storageHookDelete, "/test/restored.txt"))
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		viper.Set("Origin.StorageHooks", []map[string]interface{}{
+			{"Prefix": "/test", "Events": []string{"upload"}, "Stage": "pre", "OnFailure": "reject", "Timeout": "100ms",
+				"Command": []string{"sleep", "5"}},
+		})
+		filePath := filepath.Join(mount, "test", "slow.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+		start := time.Now()
+		assert.True(t, runStorageHooks(ctx, storageHookUpload, "/test/slow.txt"))
+		assert.Less(t, time.Since(start), 4*time.Second)
+		assert.NoFileExists(t, filePath)
+	})
+}
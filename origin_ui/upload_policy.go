@@ -0,0 +1,273 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// The named pipe XRootD reports finished writes to, or empty if the upload
+// policies aren't enforced at the origin
+var uploadEventsPipe string
+
+// Get the upload policy configured in Origin.UploadPolicies for an export, or nil
+// if the export's writes are unrestricted
+func getUploadPolicy(exportPath string) (*common.UploadPolicy, error) {
+	policies := []common.UploadPolicy{}
+	if err := param.Origin_UploadPolicies.Unmarshal(&policies); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Origin.UploadPolicies")
+	}
+
+	var found *common.UploadPolicy
+	for idx := range policies {
+		policy := policies[idx]
+		if policy.Prefix == "" {
+			return nil, errors.New("every entry of Origin.UploadPolicies must have a Prefix")
+		}
+		if policy.MaxObjectSize < 0 {
+			return nil, errors.Errorf("the MaxObjectSize of the upload policy for %s must not be negative", policy.Prefix)
+		}
+		for _, pattern := range policy.AllowedNamePatterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid name pattern %q in the upload policy for %s", pattern, policy.Prefix)
+			}
+		}
+		if path.Clean(policy.Prefix) != path.Clean(exportPath) {
+			continue
+		}
+		if found != nil {
+			return nil, errors.Errorf("Origin.UploadPolicies has more than one entry for %s", exportPath)
+		}
+		policy.Prefix = path.Clean(policy.Prefix)
+		found = &policy
+	}
+	return found, nil
+}
+
+// Get the named pipe XRootD should report finished writes to, so the origin can enforce
+// Origin.UploadPolicies; empty if there's nothing to enforce
+func GetUploadEventsPipe() string {
+	return uploadEventsPipe
+}
+
+// Get the upload policy of the export holding the object at objectPath
+func getObjectUploadPolicy(objectPath string) (exportPath string, policy *common.UploadPolicy, err error) {
+	for _, exportPath = range getExportPaths() {
+		prefix := path.Clean(exportPath)
+		if objectPath != prefix && !strings.HasPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/") {
+			continue
+		}
+		policy, err = getUploadPolicy(exportPath)
+		return prefix, policy, err
+	}
+	return "", nil, nil
+}
+
+// Remove the object XRootD just finished writing to objectPath if it violates the upload
+// policy of its export.  The director checks the policy before a client is redirected to
+// the origin, but writes sent straight to the origin, or whose size wasn't known to the
+// director, are only caught here.
+func enforceUploadPolicy(objectPath string) {
+	objectPath = path.Clean("/" + objectPath)
+	exportPath, policy, err := getObjectUploadPolicy(objectPath)
+	if err != nil {
+		log.Errorf("Failed to get the upload policy for %s: %v", objectPath, err)
+		return
+	} else if policy == nil {
+		return
+	}
+	filePath, err := objectFilePath(objectPath)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	var violation string
+	if !policy.AllowsName(exportPath, objectPath) {
+		violation = "its name matches none of " + strings.Join(policy.AllowedNamePatterns, ", ")
+	} else if !policy.AllowsSize(info.Size()) {
+		violation = fmt.Sprintf("it's %d bytes but at most %d are allowed", info.Size(), policy.MaxObjectSize)
+	} else {
+		return
+	}
+	if err = os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Errorf("Failed to remove %s, which violates the upload policy of %s (%s): %v", objectPath, exportPath, violation, err)
+		return
+	}
+	log.Warningf("Removed %s as it violates the upload policy of %s: %s", objectPath, exportPath, violation)
+}
+
+// Get the object path from an event XRootD sent for ofs.notify, which has the form
+// "<client> closew <object path>" optionally followed by the object's size
+func parseCloseWriteEvent(event string) (objectPath string, ok bool) {
+	fields := strings.Fields(event)
+	if len(fields) < 3 || fields[1] != "closew" {
+		return "", false
+	}
+	return fields[2], true
+}
+
+// Get the new path from an event XRootD sent for ofs.notify when an object or collection
+// was renamed, which has the form "<client> mv <old path> <new path>"
+func parseRenameEvent(event string) (newPath string, ok bool) {
+	fields := strings.Fields(event)
+	if len(fields) < 4 || fields[1] != "mv" {
+		return "", false
+	}
+	return fields[3], true
+}
+
+// Enforce the upload policy and write mode on whatever a WebDAV MOVE put at newPath, as
+// if it had been uploaded there; otherwise renaming an object would sidestep the checks
+// done on its upload.  A renamed collection has each object in it checked.
+func enforceRenamePolicies(newPath string) {
+	newPath = path.Clean("/" + newPath)
+	filePath, err := objectFilePath(newPath)
+	if err != nil {
+		return
+	}
+	err = filepath.WalkDir(filePath, func(walkPath string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(filePath, walkPath)
+		if err != nil {
+			return nil
+		}
+		objectPath := path.Join(newPath, filepath.ToSlash(relPath))
+		enforceUploadPolicy(objectPath)
+		enforceWriteMode(objectPath)
+		return nil
+	})
+	if err != nil {
+		log.Warningf("Failed to check the objects renamed to %s: %v", newPath, err)
+	}
+}
+
+// Enforce the upload policies and write modes on each write, deletion and rename reported
+// in the events until they run out
+func readUploadEvents(events io.Reader) error {
+	scanner := bufio.NewScanner(events)
+	for scanner.Scan() {
+		if objectPath, ok := parseCloseWriteEvent(scanner.Text()); ok {
+			enforceUploadPolicy(objectPath)
+			enforceWriteMode(objectPath)
+			startStorageHooks(storageHookUpload, objectPath)
+		} else if objectPath, ok := parseRemovalEvent(scanner.Text()); ok {
+			enforceRemovalWriteMode(objectPath)
+			if newPath, ok := parseRenameEvent(scanner.Text()); ok {
+				enforceRenamePolicies(newPath)
+			} else {
+				startStorageHooks(storageHookDelete, objectPath)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Create the named pipe XRootD reports finished writes, deletions and renames to and launch
+// the goroutine that enforces Origin.UploadPolicies and the write modes of the namespaces on
+// them and runs Origin.StorageHooks.  These can only be enforced for exports XRootD writes
+// to the local filesystem.
+func launchUploadPolicyEnforcement(ctx context.Context, egrp *errgroup.Group) error {
+	hasPolicy := false
+	for _, exportPath := range getExportPaths() {
+		if policy, err := getUploadPolicy(exportPath); err != nil {
+			return err
+		} else if policy != nil {
+			hasPolicy = true
+		}
+	}
+	// The write modes are set at the registry, so any writable origin may need to enforce them
+	hasWriteModes := param.Origin_EnableWrite.GetBool() && param.Federation_RegistryUrl.GetString() != ""
+	hooks, err := getStorageHooks()
+	if err != nil {
+		return err
+	}
+	if !hasPolicy && !hasWriteModes && len(hooks) == 0 {
+		return nil
+	}
+	if mode := param.Origin_Mode.GetString(); mode != "posix" && mode != "hsm" {
+		if hasPolicy {
+			log.Warningf("Origin.UploadPolicies are only enforced by the director for an origin in %s mode", mode)
+		}
+		if len(hooks) > 0 {
+			log.Warningf("Origin.StorageHooks aren't run for an origin in %s mode", mode)
+		}
+		return nil
+	}
+
+	uid, err := config.GetDaemonUID()
+	if err != nil {
+		return err
+	}
+	gid, err := config.GetDaemonGID()
+	if err != nil {
+		return err
+	}
+	runDir := param.Xrootd_RunLocation.GetString()
+	if err = config.MkdirAll(runDir, 0755, uid, gid); err != nil {
+		return errors.Wrapf(err, "Unable to create runtime directory %v", runDir)
+	}
+	pipePath := filepath.Join(runDir, "upload-events")
+	if err = os.Remove(pipePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to remove the old upload events pipe")
+	}
+	if err = makeFifo(pipePath, uid, gid); err != nil {
+		return errors.Wrap(err, "failed to create the upload events pipe")
+	}
+	// Opening the pipe for writing too means it neither blocks until XRootD opens it nor
+	// reaches the end of file when XRootD restarts
+	pipe, err := os.OpenFile(pipePath, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the upload events pipe")
+	}
+	uploadEventsPipe = pipePath
+
+	egrp.Go(func() error {
+		<-ctx.Done()
+		return pipe.Close()
+	})
+	egrp.Go(func() error {
+		if err := readUploadEvents(pipe); err != nil && ctx.Err() == nil {
+			log.Errorln("Stopped enforcing the upload policies after failing to read the upload events:", err)
+		}
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,165 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestParseCloseWriteEvent(t *testing.T) {
+	objectPath, ok := parseCloseWriteEvent("user.1234:5@host closew /test/uploads/data.csv 1024")
+	assert.True(t, ok)
+	assert.Equal(t, "/test/uploads/data.csv", objectPath)
+
+	objectPath, ok = parseCloseWriteEvent("user.1234:5@host closew /test/uploads/data.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "/test/uploads/data.csv", objectPath)
+
+	_, ok = parseCloseWriteEvent("user.1234:5@host closer /test/uploads/data.csv")
+	assert.False(t, ok)
+	_, ok = parseCloseWriteEvent("")
+	assert.False(t, ok)
+}
+
+func TestEnforceUploadPolicy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.UploadPolicies", []map[string]interface{}{
+		{"Prefix": "/test", "MaxObjectSize": 8, "AllowedNamePatterns": []string{"*.csv"}},
+	})
+
+	writeObject := func(name string, size int) string {
+		filePath := filepath.Join(mount, "test", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, make([]byte, size), 0644))
+		return filePath
+	}
+
+	allowed := writeObject("ok.csv", 8)
+	enforceUploadPolicy("/test/ok.csv")
+	assert.FileExists(t, allowed)
+
+	tooLarge := writeObject("large.csv", 9)
+	enforceUploadPolicy("/test/large.csv")
+	assert.NoFileExists(t, tooLarge)
+
+	badName := writeObject("dump.bin", 1)
+	enforceUploadPolicy("/test/dump.bin")
+	assert.NoFileExists(t, badName)
+
+	// Objects outside of the export are left alone
+	outside := filepath.Join(mount, "elsewhere", "dump.bin")
+	require.NoError(t, os.MkdirAll(filepath.Dir(outside), 0755))
+	require.NoError(t, os.WriteFile(outside, make([]byte, 16), 0644))
+	enforceUploadPolicy("/elsewhere/dump.bin")
+	assert.FileExists(t, outside)
+}
+
+func TestReadUploadEvents(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.UploadPolicies", []map[string]interface{}{
+		{"Prefix": "/test", "MaxObjectSize": 4},
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(mount, "test"), 0755))
+	small := filepath.Join(mount, "test", "small")
+	large := filepath.Join(mount, "test", "large")
+	require.NoError(t, os.WriteFile(small, make([]byte, 4), 0644))
+	require.NoError(t, os.WriteFile(large, make([]byte, 5), 0644))
+
+	// Only finished writes are checked; a read of a violating object doesn't remove it
+	events := "user.1:2@host closer /test/large\nuser.1:2@host closew /test/small 4\n"
+	require.NoError(t, readUploadEvents(strings.NewReader(events)))
+	assert.FileExists(t, small)
+	assert.FileExists(t, large)
+
+	require.NoError(t, readUploadEvents(strings.NewReader("user.1:2@host closew /test/large 5\n")))
+	assert.NoFileExists(t, large)
+}
+
+func TestParseRenameEvent(t *testing.T) {
+	newPath, ok := parseRenameEvent("user.1234:5@host mv /test/old.csv /test/new.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "/test/new.csv", newPath)
+
+	_, ok = parseRenameEvent("user.1234:5@host rm /test/old.csv")
+	assert.False(t, ok)
+	_, ok = parseRenameEvent("user.1234:5@host mv /test/old.csv")
+	assert.False(t, ok)
+}
+
+func TestReadRenameEvents(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.UploadPolicies", []map[string]interface{}{
+		{"Prefix": "/test", "AllowedNamePatterns": []string{"*.csv"}},
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(mount, "test", "dir"), 0755))
+	renamed := filepath.Join(mount, "test", "dump.bin")
+	require.NoError(t, os.WriteFile(renamed, []byte("data"), 0644))
+	allowed := filepath.Join(mount, "test", "dir", "ok.csv")
+	disallowed := filepath.Join(mount, "test", "dir", "dump.bin")
+	require.NoError(t, os.WriteFile(allowed, []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(disallowed, []byte("data"), 0644))
+
+	// An object renamed to a name the upload policy forbids is removed, as is one
+	// in a renamed collection
+	events := "user.1:2@host mv /test/ok.csv /test/dump.bin\nuser.1:2@host mv /test/olddir /test/dir\n"
+	require.NoError(t, readUploadEvents(strings.NewReader(events)))
+	assert.NoFileExists(t, renamed)
+	assert.NoFileExists(t, disallowed)
+	assert.FileExists(t, allowed)
+}
+
+func TestLaunchUploadPolicyEnforcementWithoutPolicies(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	runDir := t.TempDir()
+	viper.Set("Xrootd.RunLocation", runDir)
+	uploadEventsPipe = ""
+
+	ctx, cancel := context.WithCancel(context.Background())
+	egrp, ctx := errgroup.WithContext(ctx)
+	require.NoError(t, launchUploadPolicyEnforcement(ctx, egrp))
+	cancel()
+	require.NoError(t, egrp.Wait())
+
+	// With nothing to enforce, XRootD isn't asked to report writes
+	assert.Empty(t, GetUploadEventsPipe())
+	assert.NoFileExists(t, filepath.Join(runDir, "upload-events"))
+}
@@ -0,0 +1,327 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/apiclient"
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// How often the write modes and cache policies of the exports are refreshed from the registry
+const writeModeRefreshInterval = 5 * time.Minute
+
+// The directory of Xrootd.Mount keeping a hard link to each object that may not be deleted,
+// so the origin can restore the object if a client deletes or renames it.  It's outside of
+// the exports, so clients can't reach it.
+const keptObjectsDir = ".pelican-kept-objects"
+
+var (
+	// The write modes of the exports, as set in their registrations
+	exportWriteModes      = map[string]common.WriteMode{}
+	exportWriteModesMutex sync.RWMutex
+
+	// The cache policies of the exports, as set in their registrations
+	exportCachePolicies      = map[string]*common.CachePolicy{}
+	exportCachePoliciesMutex sync.RWMutex
+)
+
+// Get the write mode of the export, as last fetched from the registry
+func getWriteMode(exportPath string) common.WriteMode {
+	exportWriteModesMutex.RLock()
+	defer exportWriteModesMutex.RUnlock()
+	return exportWriteModes[path.Clean(exportPath)]
+}
+
+func setWriteMode(exportPath string, mode common.WriteMode) {
+	exportWriteModesMutex.Lock()
+	defer exportWriteModesMutex.Unlock()
+	exportWriteModes[path.Clean(exportPath)] = mode
+}
+
+// Get the cache policy of the export, as last fetched from the registry; nil if the
+// registration sets none
+func getCachePolicy(exportPath string) *common.CachePolicy {
+	exportCachePoliciesMutex.RLock()
+	defer exportCachePoliciesMutex.RUnlock()
+	return exportCachePolicies[path.Clean(exportPath)]
+}
+
+func setCachePolicy(exportPath string, policy *common.CachePolicy) {
+	exportCachePoliciesMutex.Lock()
+	defer exportCachePoliciesMutex.Unlock()
+	exportCachePolicies[path.Clean(exportPath)] = policy
+}
+
+// Get the write mode of the export holding the object at objectPath
+func getObjectWriteMode(objectPath string) common.WriteMode {
+	for _, exportPath := range getExportPaths() {
+		prefix := path.Clean(exportPath)
+		if objectPath == prefix || strings.HasPrefix(objectPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return getWriteMode(prefix)
+		}
+	}
+	return ""
+}
+
+// Fetch the write modes and cache policies of the exports from the registry.  If the
+// registry can't be reached, the last known modes are kept, so the exports don't become
+// mutable while the registry is down.
+func updateWriteModes(ctx context.Context) error {
+	client, err := apiclient.NewRegistryClient(param.Federation_RegistryUrl.GetString(), nil)
+	if err != nil {
+		return err
+	}
+	for _, exportPath := range getExportPaths() {
+		policies, err := client.GetNamespacePolicies(ctx, exportPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the write mode and cache policy of %s from the registry", exportPath)
+		}
+		mode := policies.WriteMode
+		if !mode.IsValid() {
+			return errors.Errorf("the registry set the unknown write mode %q for %s", mode, exportPath)
+		}
+		if previous := getWriteMode(exportPath); previous != mode {
+			log.Infof("The write mode of %s changed from %q to %q", exportPath, previous, mode)
+		}
+		setWriteMode(exportPath, mode)
+		if previous := getCachePolicy(exportPath); previous.CacheControl() != policies.CachePolicy.CacheControl() {
+			log.Infof("The cache policy of %s changed from %q to %q", exportPath, previous.CacheControl(), policies.CachePolicy.CacheControl())
+		}
+		setCachePolicy(exportPath, policies.CachePolicy)
+	}
+	return nil
+}
+
+// Launch the goroutine that periodically fetches the write modes and cache policies of
+// the exports from the registry
+func launchWriteModeUpdates(ctx context.Context, egrp *errgroup.Group) {
+	if param.Federation_RegistryUrl.GetString() == "" {
+		return
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(writeModeRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := updateWriteModes(ctx); err != nil && ctx.Err() == nil {
+				log.Warningln("Failed to update the write modes of the exports:", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// The path of the kept link of the object at objectPath
+func keptObjectPath(objectPath string) string {
+	return filepath.Join(param.Xrootd_Mount.GetString(), keptObjectsDir, filepath.FromSlash(path.Clean("/"+objectPath)))
+}
+
+// Make the object at objectPath, whose file is at filePath, read-only and keep a link to it.
+// XRootD runs unprivileged, so it can no longer open the object for writing, and the origin
+// restores the object from its kept link if it's deleted.
+func protectObject(objectPath, filePath string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	} else if !info.Mode().IsRegular() {
+		return nil
+	}
+	if err = os.Chmod(filePath, info.Mode().Perm()&^0222); err != nil {
+		return errors.Wrapf(err, "failed to make %s read-only", objectPath)
+	}
+	keptPath := keptObjectPath(objectPath)
+	if err = os.MkdirAll(filepath.Dir(keptPath), 0700); err != nil {
+		return errors.Wrap(err, "failed to create the directory of the kept objects")
+	}
+	if err = os.Link(filePath, keptPath); err != nil && !errors.Is(err, os.ErrExist) {
+		return errors.Wrapf(err, "failed to keep a link to %s, so it can't be restored if deleted", objectPath)
+	}
+	return nil
+}
+
+// Restore the object at objectPath from its kept link if a client deleted, renamed or
+// replaced it.  Returns true if the object was restored.
+func restoreObject(objectPath, filePath string) (bool, error) {
+	keptInfo, err := os.Stat(keptObjectPath(objectPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if info, err := os.Stat(filePath); err == nil && os.SameFile(info, keptInfo) {
+		return false, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return false, err
+	}
+	// Link then rename, so whatever replaced the object is atomically swapped out
+	tmpPath := filePath + ".pelican-restore"
+	if err = os.Remove(tmpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err = os.Link(keptObjectPath(objectPath), tmpPath); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get the number of the next version of the object whose file is at filePath
+func nextObjectVersion(filePath string) (int, error) {
+	entries, err := os.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		return 0, err
+	}
+	prefix := filepath.Base(filePath) + ".v"
+	latest := 0
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), prefix)); err == nil && version > latest {
+			latest = version
+		}
+	}
+	return latest + 1, nil
+}
+
+// Keep a copy of the object XRootD just finished writing to objectPath as its next version.
+// The object itself stays writable; the version is protected like an immutable object.
+func saveObjectVersion(objectPath, filePath string) (string, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	// Copy to a hidden file first, so the version only appears once it's complete
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.pelican-version")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return "", errors.Wrapf(err, "failed to copy %s", objectPath)
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Chmod(tmp.Name(), 0444); err != nil {
+		return "", err
+	}
+
+	// Another write of the object may claim the version number first
+	for {
+		version, err := nextObjectVersion(filePath)
+		if err != nil {
+			return "", err
+		}
+		versionPath := common.ObjectVersionPath(objectPath, version)
+		versionFile, err := objectFilePath(versionPath)
+		if err != nil {
+			return "", err
+		}
+		if err = os.Link(tmp.Name(), versionFile); errors.Is(err, os.ErrExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return versionPath, protectObject(versionPath, versionFile)
+	}
+}
+
+// Enforce the write mode of its namespace on the object XRootD just finished writing to
+// objectPath: objects of immutable namespaces are protected from changes, and objects of
+// versioned namespaces are kept as a new version.
+func enforceWriteMode(objectPath string) {
+	objectPath = path.Clean("/" + objectPath)
+	mode := getObjectWriteMode(objectPath)
+	if mode != common.WriteModeImmutable && mode != common.WriteModeVersioned {
+		return
+	}
+	filePath, err := objectFilePath(objectPath)
+	if err != nil {
+		return
+	}
+
+	// A protected object may have been replaced before the origin restored it
+	if restored, err := restoreObject(objectPath, filePath); err != nil {
+		log.Errorf("Failed to restore %s in the %s namespace: %v", objectPath, mode, err)
+		return
+	} else if restored {
+		log.Warningf("Restored %s, which was replaced in the %s namespace", objectPath, mode)
+		return
+	}
+	if mode == common.WriteModeVersioned && !common.IsObjectVersionPath(objectPath) {
+		if versionPath, err := saveObjectVersion(objectPath, filePath); err != nil {
+			log.Errorf("Failed to keep a version of %s: %v", objectPath, err)
+		} else {
+			log.Debugf("Kept %s as %s", objectPath, versionPath)
+		}
+		return
+	}
+	if err = protectObject(objectPath, filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Errorf("Failed to protect %s in the %s namespace: %v", objectPath, mode, err)
+	}
+}
+
+// Restore the object at objectPath if a client deleted or renamed it while it was protected
+// by the write mode of its namespace
+func enforceRemovalWriteMode(objectPath string) {
+	objectPath = path.Clean("/" + objectPath)
+	filePath, err := objectFilePath(objectPath)
+	if err != nil {
+		return
+	}
+	if restored, err := restoreObject(objectPath, filePath); err != nil {
+		log.Errorf("Failed to restore %s: %v", objectPath, err)
+	} else if restored {
+		log.Warningf("Restored %s, which may not be deleted or renamed in the %s namespace", objectPath, getObjectWriteMode(objectPath))
+	}
+}
+
+// Get the object path from an event XRootD sent for ofs.notify when an object was deleted
+// or renamed, which has the form "<client> rm <object path>" or "<client> mv <old path> <new path>"
+func parseRemovalEvent(event string) (objectPath string, ok bool) {
+	fields := strings.Fields(event)
+	if len(fields) < 3 || (fields[1] != "rm" && fields[1] != "mv") {
+		return "", false
+	}
+	return fields[2], true
+}
@@ -0,0 +1,140 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func setupWriteModeTest(t *testing.T, mode common.WriteMode) string {
+	viper.Reset()
+	mount := t.TempDir()
+	viper.Set("Xrootd.Mount", mount)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	setWriteMode("/test", mode)
+	t.Cleanup(func() {
+		viper.Reset()
+		exportWriteModesMutex.Lock()
+		exportWriteModes = map[string]common.WriteMode{}
+		exportWriteModesMutex.Unlock()
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(mount, "test"), 0755))
+	return mount
+}
+
+func TestParseRemovalEvent(t *testing.T) {
+	objectPath, ok := parseRemovalEvent("user.1234:5@host rm /test/data.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "/test/data.csv", objectPath)
+
+	objectPath, ok = parseRemovalEvent("user.1234:5@host mv /test/data.csv /test/moved.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "/test/data.csv", objectPath)
+
+	_, ok = parseRemovalEvent("user.1234:5@host closew /test/data.csv")
+	assert.False(t, ok)
+	_, ok = parseRemovalEvent("user.1234:5@host rm")
+	assert.False(t, ok)
+}
+
+func TestEnforceImmutableWriteMode(t *testing.T) {
+	mount := setupWriteModeTest(t, common.WriteModeImmutable)
+	filePath := filepath.Join(mount, "test", "data.csv")
+	require.NoError(t, os.WriteFile(filePath, []byte("original"), 0644))
+
+	enforceWriteMode("/test/data.csv")
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0222, "immutable objects should be read-only")
+	assert.FileExists(t, keptObjectPath("/test/data.csv"))
+
+	// Deleting the object restores it
+	require.NoError(t, os.Remove(filePath))
+	enforceRemovalWriteMode("/test/data.csv")
+	contents, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(contents))
+
+	// Replacing the object restores it too
+	require.NoError(t, os.Remove(filePath))
+	require.NoError(t, os.WriteFile(filePath, []byte("replaced"), 0644))
+	enforceWriteMode("/test/data.csv")
+	contents, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(contents))
+}
+
+func TestEnforceMutableWriteMode(t *testing.T) {
+	mount := setupWriteModeTest(t, "")
+	filePath := filepath.Join(mount, "test", "data.csv")
+	require.NoError(t, os.WriteFile(filePath, []byte("original"), 0644))
+
+	enforceWriteMode("/test/data.csv")
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	assert.NoFileExists(t, keptObjectPath("/test/data.csv"))
+
+	require.NoError(t, os.Remove(filePath))
+	enforceRemovalWriteMode("/test/data.csv")
+	assert.NoFileExists(t, filePath)
+}
+
+func TestEnforceVersionedWriteMode(t *testing.T) {
+	mount := setupWriteModeTest(t, common.WriteModeVersioned)
+	filePath := filepath.Join(mount, "test", "data.csv")
+
+	require.NoError(t, os.WriteFile(filePath, []byte("first"), 0644))
+	enforceWriteMode("/test/data.csv")
+	require.NoError(t, os.WriteFile(filePath, []byte("second"), 0644))
+	enforceWriteMode("/test/data.csv")
+
+	// The object itself stays writable and each write is kept as a version
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	for version, expected := range map[int]string{1: "first", 2: "second"} {
+		versionFile := filepath.Join(mount, "test", fmt.Sprintf("data.csv.v%d", version))
+		contents, err := os.ReadFile(versionFile)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(contents))
+		info, err := os.Stat(versionFile)
+		require.NoError(t, err)
+		assert.Zero(t, info.Mode().Perm()&0222, "versions should be read-only")
+	}
+
+	// Versions can't be deleted
+	require.NoError(t, os.Remove(filepath.Join(mount, "test", "data.csv.v1")))
+	enforceRemovalWriteMode("/test/data.csv.v1")
+	assert.FileExists(t, filepath.Join(mount, "test", "data.csv.v1"))
+
+	// No temporary copies are left behind
+	entries, err := os.ReadDir(filepath.Join(mount, "test"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
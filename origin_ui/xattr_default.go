@@ -0,0 +1,33 @@
+//go:build !linux
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+func checksumXattrName(algorithm string) string {
+	return "XrdCks." + algorithm
+}
+
+func getXattr(path string, name string) ([]byte, error) {
+	return nil, errXattrUnsupported
+}
+
+func setXattr(path string, name string, value []byte) error {
+	return errXattrUnsupported
+}
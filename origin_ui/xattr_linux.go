@@ -0,0 +1,53 @@
+//go:build linux
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package origin_ui
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// XRootD's checksum manager stores checksums in the user namespace on Linux
+func checksumXattrName(algorithm string) string {
+	return "user.XrdCks." + algorithm
+}
+
+func getXattr(path string, name string) ([]byte, error) {
+	buf := make([]byte, xrdCksDataSize)
+	size, err := syscall.Getxattr(path, name, buf)
+	if errors.Is(err, syscall.ENODATA) {
+		return nil, errNoChecksumXattr
+	} else if errors.Is(err, syscall.ENOTSUP) {
+		return nil, errXattrUnsupported
+	} else if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+func setXattr(path string, name string, value []byte) error {
+	err := syscall.Setxattr(path, name, value, 0)
+	if errors.Is(err, syscall.ENOTSUP) {
+		return errXattrUnsupported
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package param
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+)
+
+// A configuration context holds the parameter values of a single server instance or federation,
+// so several of them can be configured independently within the same process.
+//
+// Only code that reads parameters through the getters' In variants, passing the context along,
+// sees its values.  So far that's client.DownloadObject; the servers, their launchers and the
+// rest of the client still read the global configuration.
+type Context struct {
+	v *viper.Viper
+}
+
+type contextKey struct{}
+
+// Create an empty configuration context, independent of the global configuration
+func NewContext() *Context {
+	return &Context{v: viper.New()}
+}
+
+// Create a configuration context backed by an existing viper instance
+func NewContextFromViper(v *viper.Viper) *Context {
+	return &Context{v: v}
+}
+
+// Return the viper instance backing the configuration context. A nil context, or one without
+// a viper instance, refers to the global configuration.
+func (pc *Context) Viper() *viper.Viper {
+	if pc == nil || pc.v == nil {
+		// Looked up on every call since viper.Reset replaces the global instance
+		return viper.GetViper()
+	}
+	return pc.v
+}
+
+// Set the value of a parameter in the configuration context
+func (pc *Context) Set(key string, value any) {
+	pc.Viper().Set(key, value)
+}
+
+// Set the default value of a parameter in the configuration context
+func (pc *Context) SetDefault(key string, value any) {
+	pc.Viper().SetDefault(key, value)
+}
+
+// Check if a parameter is set in the configuration context
+func (pc *Context) IsSet(key string) bool {
+	return pc.Viper().IsSet(key)
+}
+
+// Attach a configuration context to ctx, so code running on behalf of a server instance
+// reads that instance's parameters
+func WithContext(ctx context.Context, pc *Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, pc)
+}
+
+// Return the configuration context attached to ctx. If there is none, the returned nil
+// context refers to the global configuration.
+func FromContext(ctx context.Context) *Context {
+	if ctx == nil {
+		return nil
+	}
+	pc, _ := ctx.Value(contextKey{}).(*Context)
+	return pc
+}
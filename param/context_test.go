@@ -0,0 +1,45 @@
+package param
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextIsolation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Federation.DirectorUrl", "https://global.example.com")
+	first := NewContext()
+	first.Set("Federation.DirectorUrl", "https://first.example.com")
+	first.Set("Transport.DialerTimeout", "5s")
+	second := NewContext()
+	second.Set("Federation.DirectorUrl", "https://second.example.com")
+
+	assert.Equal(t, "https://global.example.com", Federation_DirectorUrl.GetString())
+	assert.Equal(t, "https://first.example.com", Federation_DirectorUrl.GetStringIn(first))
+	assert.Equal(t, "https://second.example.com", Federation_DirectorUrl.GetStringIn(second))
+	assert.Equal(t, 5*time.Second, Transport_DialerTimeout.GetDurationIn(first))
+	assert.Zero(t, Transport_DialerTimeout.GetDurationIn(second))
+	assert.False(t, second.IsSet("Transport.DialerTimeout"))
+
+	// A nil context follows the global configuration, even across a reset
+	viper.Reset()
+	viper.Set("Federation.DirectorUrl", "https://reset.example.com")
+	assert.Equal(t, "https://reset.example.com", Federation_DirectorUrl.GetStringIn(nil))
+	assert.Equal(t, "https://first.example.com", Federation_DirectorUrl.GetStringIn(first))
+}
+
+func TestContextPropagation(t *testing.T) {
+	pc := NewContext()
+	ctx := WithContext(context.Background(), pc)
+	assert.Same(t, pc, FromContext(ctx))
+	assert.Nil(t, FromContext(context.Background()))
+
+	pc.Set("Origin.NamespacePrefix", "/test")
+	assert.Equal(t, "/test", Origin_NamespacePrefix.GetStringIn(FromContext(ctx)))
+}
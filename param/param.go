@@ -8,6 +8,22 @@ import (
 	"github.com/spf13/viper"
 )
 
+type (
+	// The configuration with the type of each parameter next to its value, as returned
+	// by ConvertToConfigWithType
+	ConfigWithType = configWithType
+
+	// A parameter's value violating the constraints of docs/parameters.yaml, or a
+	// problem with the configuration as a whole if Param is empty
+	ValidationProblem struct {
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	}
+)
+
+// Shown instead of the values of the secret parameters
+const RedactedValue = "REDACTED"
+
 var (
 	viperConfig *config
 	configMutex sync.RWMutex
@@ -36,6 +52,67 @@ func GetUnmarshaledConfig() (*config, error) {
 	return viperConfig, nil
 }
 
+// Unmarshal the configuration of the context pc into a new config struct, leaving the one
+// returned by GetUnmarshaledConfig alone.  Unlike viper's Unmarshal, this includes the
+// parameters set only through environment variables.
+func UnmarshalConfigIn(pc *Context) (*config, error) {
+	v := pc.Viper()
+	merged := viper.New()
+	for _, name := range GetParamNames() {
+		if v.IsSet(name) {
+			merged.Set(name, v.Get(name))
+		}
+	}
+	cfg := new(config)
+	if err := merged.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Get the names of all the parameters, e.g. "Server.WebPort", in the order of the config struct
+func GetParamNames() []string {
+	names := []string{}
+	walkParams(func(name string, _ reflect.Type) {
+		names = append(names, name)
+	})
+	return names
+}
+
+// Get the Go type of each parameter's value, keyed by the parameter's name; the type
+// of object parameters is interface{}
+func GetParamTypes() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	walkParams(func(name string, paramType reflect.Type) {
+		types[name] = paramType
+	})
+	return types
+}
+
+// Call visit with the name and type of each parameter, in the order of the config struct
+func walkParams(visit func(name string, paramType reflect.Type)) {
+	var walk func(structType reflect.Type, prefix string)
+	walk = func(structType reflect.Type, prefix string) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			name := prefix + field.Tag.Get("mapstructure")
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, name+".")
+			} else {
+				visit(name, field.Type)
+			}
+		}
+	}
+	walk(reflect.TypeOf(config{}), "")
+}
+
+func (problem ValidationProblem) String() string {
+	if problem.Param == "" {
+		return problem.Message
+	}
+	return problem.Param + " " + problem.Message
+}
+
 // Helper function to set a parameter field entry in configWithType
 func setField(fieldType reflect.Type, value interface{}) reflect.Value {
 	field := reflect.New(fieldType).Elem()
@@ -57,8 +134,10 @@ func setField(fieldType reflect.Type, value interface{}) reflect.Value {
 	return field
 }
 
-// Helper function to convert config struct to configWithType struct using reflection
-func convertStruct(srcVal, destVal reflect.Value) {
+// Helper function to convert config struct to configWithType struct using reflection.
+// The parameter names of the fields are prefixed with namePrefix; if getSource is set,
+// it gives the source of each parameter's value.
+func convertStruct(srcVal, destVal reflect.Value, namePrefix string, getSource func(name string) string) {
 	// If the source or destination is a pointer, get the underlying element
 	if srcVal.Kind() == reflect.Ptr {
 		srcVal = srcVal.Elem()
@@ -81,25 +160,47 @@ func convertStruct(srcVal, destVal reflect.Value) {
 				nestedDest = reflect.New(nestedDest.Type()).Elem()
 			}
 
-			convertStruct(nestedSrc, nestedDest)
+			convertStruct(nestedSrc, nestedDest, namePrefix+srcVal.Type().Field(i).Name+".", getSource)
 			destField.Set(nestedDest) // Set the converted struct back
 		} else {
 			// Handle non-struct fields
 			if destField.CanSet() {
+				name := namePrefix + srcVal.Type().Field(i).Name
 				destFieldType := destField.Type()
 				convertedField := setField(destFieldType, srcField.Interface())
+				if getSource != nil {
+					convertedField.FieldByName("Source").SetString(getSource(name))
+				}
+				if SecretParams[name] && !srcField.IsZero() {
+					redactField(convertedField.FieldByName("Value"))
+				}
 				destField.Set(convertedField)
 			}
 		}
 	}
 }
 
-// Convert a config struct to configWithType struct
+// Hide the value of a secret parameter; strings show RedactedValue, other types their zero value
+func redactField(value reflect.Value) {
+	if value.Kind() == reflect.String {
+		value.SetString(RedactedValue)
+	} else {
+		value.Set(reflect.Zero(value.Type()))
+	}
+}
+
+// Convert a config struct to configWithType struct; the values of secret parameters are redacted
 func ConvertToConfigWithType(rawConfig *config) *configWithType {
+	return ConvertToConfigWithSource(rawConfig, nil)
+}
+
+// Like ConvertToConfigWithType, annotating each parameter with the source of its value as
+// given by getSource, which is called with the parameter's name, e.g. "Server.WebPort"
+func ConvertToConfigWithSource(rawConfig *config, getSource func(name string) string) *configWithType {
 	typedConfig := configWithType{}
 
 	srcVal := reflect.ValueOf(rawConfig).Elem()
 	destVal := reflect.ValueOf(&typedConfig).Elem()
-	convertStruct(srcVal, destVal)
+	convertStruct(srcVal, destVal, "", getSource)
 	return &typedConfig
 }
@@ -0,0 +1,98 @@
+package param
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Run("valid-config", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("Origin.Mode", "s3")
+		viper.Set("Federation.DiscoveryUrl", "osg-htc.org")
+		viper.Set("Federation.DirectorUrl", "https://director.example.com:8444")
+		viper.Set("Xrootd.ManagerHost", "manager.example.com:1213")
+		cfg, err := UnmarshalConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "s3", cfg.Origin.Mode)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("unset-params-pass", func(t *testing.T) {
+		viper.Reset()
+		cfg, err := UnmarshalConfig()
+		require.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("Director.DefaultResponse", "nearest")
+		cfg, err := UnmarshalConfig()
+		require.NoError(t, err)
+		err = cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `Director.DefaultResponse is "nearest" but must be one of: cache origin`)
+	})
+
+	t.Run("invalid-url", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("Federation.RegistryUrl", "https://registry example.com")
+		viper.Set("Origin.Mode", "ftp")
+		cfg, err := UnmarshalConfig()
+		require.NoError(t, err)
+		err = cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Federation.RegistryUrl has the invalid value")
+		assert.Contains(t, err.Error(), "Origin.Mode")
+	})
+}
+
+func TestUnmarshalConfigIn(t *testing.T) {
+	v := viper.New()
+	v.SetEnvPrefix("PELICAN")
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.Set("Server.WebPort", 8444)
+	// viper's Unmarshal skips parameters only set in the environment
+	t.Setenv("PELICAN_SERVER_WEBHOST", "1.1.1.1")
+
+	cfg, err := UnmarshalConfigIn(NewContextFromViper(v))
+	require.NoError(t, err)
+	assert.Equal(t, 8444, cfg.Server.WebPort)
+	assert.Equal(t, "1.1.1.1", cfg.Server.WebHost)
+
+	names := GetParamNames()
+	assert.Contains(t, names, "Server.WebPort")
+	assert.Contains(t, names, "Logging.Origin.Scitokens")
+	assert.NotContains(t, names, "Server")
+}
+
+func TestConvertToConfigWithType(t *testing.T) {
+	v := viper.New()
+	v.Set("Plugin.Token", "secret-token")
+	v.Set("Server.WebPort", 8444)
+	cfg, err := UnmarshalConfigIn(NewContextFromViper(v))
+	require.NoError(t, err)
+
+	typed := ConvertToConfigWithSource(cfg, func(name string) string {
+		if v.IsSet(name) {
+			return "test"
+		}
+		return ""
+	})
+	assert.Equal(t, RedactedValue, typed.Plugin.Token.Value)
+	assert.Equal(t, "test", typed.Plugin.Token.Source)
+	assert.Equal(t, 8444, typed.Server.WebPort.Value)
+	assert.Equal(t, "int", typed.Server.WebPort.Type)
+	assert.Empty(t, typed.Server.WebHost.Source)
+	// The original config is left alone
+	assert.Equal(t, "secret-token", cfg.Plugin.Token)
+}
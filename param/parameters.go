@@ -4,8 +4,6 @@ package param
 
 import (
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 type StringParam struct {
@@ -33,37 +31,98 @@ type ObjectParam struct {
 }
 
 func (sP StringParam) GetString() string {
-	return viper.GetString(sP.name)
+	return sP.GetStringIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (sP StringParam) GetStringIn(pc *Context) string {
+	return pc.Viper().GetString(sP.name)
 }
 
 func (slP StringSliceParam) GetStringSlice() []string {
-	return viper.GetStringSlice(slP.name)
+	return slP.GetStringSliceIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (slP StringSliceParam) GetStringSliceIn(pc *Context) []string {
+	return pc.Viper().GetStringSlice(slP.name)
 }
 
 func (iP IntParam) GetInt() int {
-	return viper.GetInt(iP.name)
+	return iP.GetIntIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (iP IntParam) GetIntIn(pc *Context) int {
+	return pc.Viper().GetInt(iP.name)
 }
 
 func (bP BoolParam) GetBool() bool {
-	return viper.GetBool(bP.name)
+	return bP.GetBoolIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP BoolParam) GetBoolIn(pc *Context) bool {
+	return pc.Viper().GetBool(bP.name)
 }
 
 func (bP DurationParam) GetDuration() time.Duration {
-	return viper.GetDuration(bP.name)
+	return bP.GetDurationIn(nil)
+}
+
+// Get the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP DurationParam) GetDurationIn(pc *Context) time.Duration {
+	return pc.Viper().GetDuration(bP.name)
 }
 
 func (bP ObjectParam) Unmarshal(rawVal any) error {
-	return viper.UnmarshalKey(bP.name, rawVal)
+	return bP.UnmarshalIn(nil, rawVal)
+}
+
+// Unmarshal the parameter's value from the configuration context pc; a nil pc is the global configuration
+func (bP ObjectParam) UnmarshalIn(pc *Context, rawVal any) error {
+	return pc.Viper().UnmarshalKey(bP.name, rawVal)
 }
 
 var (
+	Cache_BlockSize = StringParam{"Cache.BlockSize"}
 	Cache_DataLocation = StringParam{"Cache.DataLocation"}
 	Cache_ExportLocation = StringParam{"Cache.ExportLocation"}
+	Cache_MaxRequestSize = StringParam{"Cache.MaxRequestSize"}
+	Cache_RamSize = StringParam{"Cache.RamSize"}
+	Cache_WriteBackLocation = StringParam{"Cache.WriteBackLocation"}
+	Cache_WriteBackMaxSpoolSize = StringParam{"Cache.WriteBackMaxSpoolSize"}
 	Cache_XRootDPrefix = StringParam{"Cache.XRootDPrefix"}
+	Client_EncryptionKey = StringParam{"Client.EncryptionKey"}
+	Client_EncryptionKeyFile = StringParam{"Client.EncryptionKeyFile"}
+	Client_HttpProxy = StringParam{"Client.HttpProxy"}
+	Client_LocalCacheLocation = StringParam{"Client.LocalCacheLocation"}
+	Client_PinnedKeysFile = StringParam{"Client.PinnedKeysFile"}
+	Client_ProxyPasswordFile = StringParam{"Client.ProxyPasswordFile"}
+	Client_ResourceSignaturePolicy = StringParam{"Client.ResourceSignaturePolicy"}
+	Client_SelfUpdateChannel = StringParam{"Client.SelfUpdateChannel"}
+	Client_SelfUpdatePublicKey = StringParam{"Client.SelfUpdatePublicKey"}
+	Client_SelfUpdateUrl = StringParam{"Client.SelfUpdateUrl"}
+	Client_SlowTransferPolicy = StringParam{"Client.SlowTransferPolicy"}
+	Client_SocksProxy = StringParam{"Client.SocksProxy"}
+	Client_StaticFederationFile = StringParam{"Client.StaticFederationFile"}
+	Client_WriteBackAck = StringParam{"Client.WriteBackAck"}
+	Director_ClientConfigFile = StringParam{"Director.ClientConfigFile"}
 	Director_DefaultResponse = StringParam{"Director.DefaultResponse"}
+	Director_FederationContact = StringParam{"Director.FederationContact"}
+	Director_FederationDisplayName = StringParam{"Director.FederationDisplayName"}
 	Director_GeoIPLocation = StringParam{"Director.GeoIPLocation"}
+	Director_GeoIPOverridesFile = StringParam{"Director.GeoIPOverridesFile"}
 	Director_MaxMindKeyFile = StringParam{"Director.MaxMindKeyFile"}
+	Director_MinimumCacheVersion = StringParam{"Director.MinimumCacheVersion"}
+	Director_MinimumClientVersion = StringParam{"Director.MinimumClientVersion"}
+	Director_MinimumOriginVersion = StringParam{"Director.MinimumOriginVersion"}
+	Director_MinimumVersionPolicy = StringParam{"Director.MinimumVersionPolicy"}
+	Director_Mirror_Url = StringParam{"Director.Mirror.Url"}
+	Federation_BrokerUrl = StringParam{"Federation.BrokerUrl"}
+	Federation_ClientConfigUrl = StringParam{"Federation.ClientConfigUrl"}
 	Federation_DirectorUrl = StringParam{"Federation.DirectorUrl"}
+	Federation_DiscoveryCacheFile = StringParam{"Federation.DiscoveryCacheFile"}
 	Federation_DiscoveryUrl = StringParam{"Federation.DiscoveryUrl"}
 	Federation_JwkUrl = StringParam{"Federation.JwkUrl"}
 	Federation_NamespaceUrl = StringParam{"Federation.NamespaceUrl"}
@@ -74,14 +133,19 @@ var (
 	Issuer_AuthenticationSource = StringParam{"Issuer.AuthenticationSource"}
 	Issuer_GroupFile = StringParam{"Issuer.GroupFile"}
 	Issuer_GroupSource = StringParam{"Issuer.GroupSource"}
+	Issuer_KeyAlgorithm = StringParam{"Issuer.KeyAlgorithm"}
 	Issuer_OIDCAuthenticationUserClaim = StringParam{"Issuer.OIDCAuthenticationUserClaim"}
 	Issuer_QDLLocation = StringParam{"Issuer.QDLLocation"}
 	Issuer_ScitokensServerLocation = StringParam{"Issuer.ScitokensServerLocation"}
 	Issuer_TomcatLocation = StringParam{"Issuer.TomcatLocation"}
+	LocalCache_DataLocation = StringParam{"LocalCache.DataLocation"}
+	LocalCache_Socket = StringParam{"LocalCache.Socket"}
+	LocalCache_SocketMode = StringParam{"LocalCache.SocketMode"}
 	Logging_Cache_Ofs = StringParam{"Logging.Cache.Ofs"}
 	Logging_Cache_Pss = StringParam{"Logging.Cache.Pss"}
 	Logging_Cache_Scitokens = StringParam{"Logging.Cache.Scitokens"}
 	Logging_Cache_Xrd = StringParam{"Logging.Cache.Xrd"}
+	Logging_Format = StringParam{"Logging.Format"}
 	Logging_Level = StringParam{"Logging.Level"}
 	Logging_LogLocation = StringParam{"Logging.LogLocation"}
 	Logging_Origin_Cms = StringParam{"Logging.Origin.Cms"}
@@ -89,6 +153,11 @@ var (
 	Logging_Origin_Pss = StringParam{"Logging.Origin.Pss"}
 	Logging_Origin_Scitokens = StringParam{"Logging.Origin.Scitokens"}
 	Logging_Origin_Xrootd = StringParam{"Logging.Origin.Xrootd"}
+	Monitoring_AccountingFile = StringParam{"Monitoring.AccountingFile"}
+	Monitoring_Alerting_Email_From = StringParam{"Monitoring.Alerting.Email.From"}
+	Monitoring_Alerting_Email_PasswordFile = StringParam{"Monitoring.Alerting.Email.PasswordFile"}
+	Monitoring_Alerting_Email_SmtpServer = StringParam{"Monitoring.Alerting.Email.SmtpServer"}
+	Monitoring_Alerting_Email_Username = StringParam{"Monitoring.Alerting.Email.Username"}
 	Monitoring_DataLocation = StringParam{"Monitoring.DataLocation"}
 	OIDC_AuthorizationEndpoint = StringParam{"OIDC.AuthorizationEndpoint"}
 	OIDC_ClientID = StringParam{"OIDC.ClientID"}
@@ -100,12 +169,16 @@ var (
 	OIDC_TokenEndpoint = StringParam{"OIDC.TokenEndpoint"}
 	OIDC_UserInfoEndpoint = StringParam{"OIDC.UserInfoEndpoint"}
 	Origin_ExportVolume = StringParam{"Origin.ExportVolume"}
+	Origin_HsmStageCommand = StringParam{"Origin.HsmStageCommand"}
 	Origin_Mode = StringParam{"Origin.Mode"}
 	Origin_NamespacePrefix = StringParam{"Origin.NamespacePrefix"}
+	Origin_PausedExportsFile = StringParam{"Origin.PausedExportsFile"}
 	Origin_S3AccessKeyfile = StringParam{"Origin.S3AccessKeyfile"}
 	Origin_S3Bucket = StringParam{"Origin.S3Bucket"}
+	Origin_S3KMSKeyId = StringParam{"Origin.S3KMSKeyId"}
 	Origin_S3Region = StringParam{"Origin.S3Region"}
 	Origin_S3SecretKeyfile = StringParam{"Origin.S3SecretKeyfile"}
+	Origin_S3ServerSideEncryption = StringParam{"Origin.S3ServerSideEncryption"}
 	Origin_S3ServiceName = StringParam{"Origin.S3ServiceName"}
 	Origin_S3ServiceUrl = StringParam{"Origin.S3ServiceUrl"}
 	Origin_ScitokensDefaultUser = StringParam{"Origin.ScitokensDefaultUser"}
@@ -116,11 +189,22 @@ var (
 	Plugin_Token = StringParam{"Plugin.Token"}
 	Registry_DbLocation = StringParam{"Registry.DbLocation"}
 	Registry_InstitutionsUrl = StringParam{"Registry.InstitutionsUrl"}
+	Registry_TermsOfServiceUrl = StringParam{"Registry.TermsOfServiceUrl"}
+	Registry_TermsOfServiceVersion = StringParam{"Registry.TermsOfServiceVersion"}
+	Secrets_AWSEndpoint = StringParam{"Secrets.AWSEndpoint"}
+	Secrets_AWSRegion = StringParam{"Secrets.AWSRegion"}
+	Secrets_AWSSecretPrefix = StringParam{"Secrets.AWSSecretPrefix"}
+	Secrets_Provider = StringParam{"Secrets.Provider"}
+	Secrets_VaultAddress = StringParam{"Secrets.VaultAddress"}
+	Secrets_VaultPath = StringParam{"Secrets.VaultPath"}
+	Secrets_VaultTokenFile = StringParam{"Secrets.VaultTokenFile"}
+	Server_AcceptedTermsOfService = StringParam{"Server.AcceptedTermsOfService"}
 	Server_ExternalWebUrl = StringParam{"Server.ExternalWebUrl"}
 	Server_Hostname = StringParam{"Server.Hostname"}
 	Server_IssuerHostname = StringParam{"Server.IssuerHostname"}
 	Server_IssuerJwks = StringParam{"Server.IssuerJwks"}
 	Server_IssuerUrl = StringParam{"Server.IssuerUrl"}
+	Server_RegistrationStateFile = StringParam{"Server.RegistrationStateFile"}
 	Server_SessionSecretFile = StringParam{"Server.SessionSecretFile"}
 	Server_TLSCACertificateDirectory = StringParam{"Server.TLSCACertificateDirectory"}
 	Server_TLSCACertificateFile = StringParam{"Server.TLSCACertificateFile"}
@@ -129,6 +213,8 @@ var (
 	Server_TLSKey = StringParam{"Server.TLSKey"}
 	Server_UIActivationCodeFile = StringParam{"Server.UIActivationCodeFile"}
 	Server_UIPasswordFile = StringParam{"Server.UIPasswordFile"}
+	Server_UnixSocket = StringParam{"Server.UnixSocket"}
+	Server_UnixSocketMode = StringParam{"Server.UnixSocketMode"}
 	Server_WebHost = StringParam{"Server.WebHost"}
 	Shoveler_AMQPExchange = StringParam{"Shoveler.AMQPExchange"}
 	Shoveler_AMQPTokenLocation = StringParam{"Shoveler.AMQPTokenLocation"}
@@ -157,28 +243,53 @@ var (
 )
 
 var (
+	Client_NoProxy = StringSliceParam{"Client.NoProxy"}
 	Director_CacheResponseHostnames = StringSliceParam{"Director.CacheResponseHostnames"}
 	Director_OriginResponseHostnames = StringSliceParam{"Director.OriginResponseHostnames"}
+	Includes = StringSliceParam{"Includes"}
 	Issuer_GroupRequirements = StringSliceParam{"Issuer.GroupRequirements"}
 	Monitoring_AggregatePrefixes = StringSliceParam{"Monitoring.AggregatePrefixes"}
+	Monitoring_Alerting_Email_To = StringSliceParam{"Monitoring.Alerting.Email.To"}
+	Monitoring_Alerting_WebhookUrls = StringSliceParam{"Monitoring.Alerting.WebhookUrls"}
+	Origin_ChecksumAlgorithms = StringSliceParam{"Origin.ChecksumAlgorithms"}
 	Origin_ScitokensRestrictedPaths = StringSliceParam{"Origin.ScitokensRestrictedPaths"}
 	Registry_AdminUsers = StringSliceParam{"Registry.AdminUsers"}
+	Server_ListenAddresses = StringSliceParam{"Server.ListenAddresses"}
 	Server_Modules = StringSliceParam{"Server.Modules"}
 	Shoveler_OutputDestinations = StringSliceParam{"Shoveler.OutputDestinations"}
 )
 
 var (
 	Cache_Port = IntParam{"Cache.Port"}
+	Cache_PrefetchBlocks = IntParam{"Cache.PrefetchBlocks"}
+	Cache_WriteBackRetries = IntParam{"Cache.WriteBackRetries"}
+	Cache_WriteBackWorkers = IntParam{"Cache.WriteBackWorkers"}
+	Client_CircuitBreakerThreshold = IntParam{"Client.CircuitBreakerThreshold"}
+	Client_LocalCacheSize = IntParam{"Client.LocalCacheSize"}
 	Client_MinimumDownloadSpeed = IntParam{"Client.MinimumDownloadSpeed"}
 	Client_SlowTransferRampupTime = IntParam{"Client.SlowTransferRampupTime"}
 	Client_SlowTransferWindow = IntParam{"Client.SlowTransferWindow"}
 	Client_StoppedTransferTimeout = IntParam{"Client.StoppedTransferTimeout"}
+	Director_Fairness_IPv4PrefixLength = IntParam{"Director.Fairness.IPv4PrefixLength"}
+	Director_Fairness_IPv6PrefixLength = IntParam{"Director.Fairness.IPv6PrefixLength"}
+	Director_Fairness_MaxConcurrentRequests = IntParam{"Director.Fairness.MaxConcurrentRequests"}
+	Director_LoadWeighting_ThroughputWeight = IntParam{"Director.LoadWeighting.ThroughputWeight"}
 	Director_MaxStatResponse = IntParam{"Director.MaxStatResponse"}
 	Director_MinStatResponse = IntParam{"Director.MinStatResponse"}
+	Director_Mirror_MaxConcurrency = IntParam{"Director.Mirror.MaxConcurrency"}
+	Director_Mirror_Percent = IntParam{"Director.Mirror.Percent"}
 	Director_StatConcurrencyLimit = IntParam{"Director.StatConcurrencyLimit"}
+	Federation_DiscoveryRetries = IntParam{"Federation.DiscoveryRetries"}
+	LocalCache_Port = IntParam{"LocalCache.Port"}
+	LocalCache_Size = IntParam{"LocalCache.Size"}
 	MinimumDownloadSpeed = IntParam{"MinimumDownloadSpeed"}
+	Monitoring_AccessLogMaxEntries = IntParam{"Monitoring.AccessLogMaxEntries"}
 	Monitoring_PortHigher = IntParam{"Monitoring.PortHigher"}
 	Monitoring_PortLower = IntParam{"Monitoring.PortLower"}
+	Origin_ChecksumWorkers = IntParam{"Origin.ChecksumWorkers"}
+	Origin_ScrubBandwidth = IntParam{"Origin.ScrubBandwidth"}
+	Registry_DbMaxIdleConnections = IntParam{"Registry.DbMaxIdleConnections"}
+	Registry_DbMaxOpenConnections = IntParam{"Registry.DbMaxOpenConnections"}
 	Server_IssuerPort = IntParam{"Server.IssuerPort"}
 	Server_WebPort = IntParam{"Server.WebPort"}
 	Shoveler_PortHigher = IntParam{"Shoveler.PortHigher"}
@@ -188,19 +299,30 @@ var (
 )
 
 var (
+	Cache_EnableIssuerValidation = BoolParam{"Cache.EnableIssuerValidation"}
 	Cache_EnableVoms = BoolParam{"Cache.EnableVoms"}
+	Cache_EnableWriteBack = BoolParam{"Cache.EnableWriteBack"}
+	Cache_ServeStaleOnOriginOutage = BoolParam{"Cache.ServeStaleOnOriginOutage"}
+	Client_DisableFederationConfig = BoolParam{"Client.DisableFederationConfig"}
 	Client_DisableHttpProxy = BoolParam{"Client.DisableHttpProxy"}
 	Client_DisableProxyFallback = BoolParam{"Client.DisableProxyFallback"}
+	Client_EnableWriteBackUploads = BoolParam{"Client.EnableWriteBackUploads"}
+	Client_PinFederationKeys = BoolParam{"Client.PinFederationKeys"}
 	Debug = BoolParam{"Debug"}
+	Director_RequireAdvertisementSignature = BoolParam{"Director.RequireAdvertisementSignature"}
 	DisableHttpProxy = BoolParam{"DisableHttpProxy"}
 	DisableProxyFallback = BoolParam{"DisableProxyFallback"}
 	Logging_DisableProgressBars = BoolParam{"Logging.DisableProgressBars"}
+	Monitoring_Alerting_DisableDefaultRules = BoolParam{"Monitoring.Alerting.DisableDefaultRules"}
+	Monitoring_EnableAccessLogs = BoolParam{"Monitoring.EnableAccessLogs"}
+	Monitoring_EnableAccounting = BoolParam{"Monitoring.EnableAccounting"}
 	Monitoring_MetricAuthorization = BoolParam{"Monitoring.MetricAuthorization"}
 	Origin_EnableCmsd = BoolParam{"Origin.EnableCmsd"}
 	Origin_EnableDirListing = BoolParam{"Origin.EnableDirListing"}
 	Origin_EnableFallbackRead = BoolParam{"Origin.EnableFallbackRead"}
 	Origin_EnableIssuer = BoolParam{"Origin.EnableIssuer"}
 	Origin_EnablePublicReads = BoolParam{"Origin.EnablePublicReads"}
+	Origin_EnableScrubber = BoolParam{"Origin.EnableScrubber"}
 	Origin_EnableUI = BoolParam{"Origin.EnableUI"}
 	Origin_EnableVoms = BoolParam{"Origin.EnableVoms"}
 	Origin_EnableWrite = BoolParam{"Origin.EnableWrite"}
@@ -210,23 +332,64 @@ var (
 	Registry_RequireCacheApproval = BoolParam{"Registry.RequireCacheApproval"}
 	Registry_RequireKeyChaining = BoolParam{"Registry.RequireKeyChaining"}
 	Registry_RequireOriginApproval = BoolParam{"Registry.RequireOriginApproval"}
+	Server_AdvertiseHealthChecks = BoolParam{"Server.AdvertiseHealthChecks"}
 	Server_EnableUI = BoolParam{"Server.EnableUI"}
+	Server_FailOnClockSkew = BoolParam{"Server.FailOnClockSkew"}
 	Shoveler_Enable = BoolParam{"Shoveler.Enable"}
 	Shoveler_VerifyHeader = BoolParam{"Shoveler.VerifyHeader"}
 	StagePlugin_Hook = BoolParam{"StagePlugin.Hook"}
+	StrictConfigValidation = BoolParam{"StrictConfigValidation"}
 	TLSSkipVerify = BoolParam{"TLSSkipVerify"}
 )
 
 var (
+	Cache_CachePolicyCheckInterval = DurationParam{"Cache.CachePolicyCheckInterval"}
+	Cache_ConfigRestartDelay = DurationParam{"Cache.ConfigRestartDelay"}
+	Cache_IssuerMetadataRefreshInterval = DurationParam{"Cache.IssuerMetadataRefreshInterval"}
+	Cache_IssuerNegativeCacheTTL = DurationParam{"Cache.IssuerNegativeCacheTTL"}
+	Cache_WriteBackRetryInterval = DurationParam{"Cache.WriteBackRetryInterval"}
+	Client_CircuitBreakerCooldown = DurationParam{"Client.CircuitBreakerCooldown"}
+	Client_MaxRetryAfter = DurationParam{"Client.MaxRetryAfter"}
+	Client_StageTimeout = DurationParam{"Client.StageTimeout"}
+	Client_TransferTimeout = DurationParam{"Client.TransferTimeout"}
 	Director_AdvertisementTTL = DurationParam{"Director.AdvertisementTTL"}
+	Director_Fairness_Window = DurationParam{"Director.Fairness.Window"}
+	Director_GeoIPRefreshInterval = DurationParam{"Director.GeoIPRefreshInterval"}
+	Director_KeyRevocationRefreshInterval = DurationParam{"Director.KeyRevocationRefreshInterval"}
+	Director_LoadWeighting_RefreshInterval = DurationParam{"Director.LoadWeighting.RefreshInterval"}
+	Director_LoadWeighting_ThroughputWindow = DurationParam{"Director.LoadWeighting.ThroughputWindow"}
+	Director_MaintenanceDrainPeriod = DurationParam{"Director.MaintenanceDrainPeriod"}
+	Director_Mirror_Timeout = DurationParam{"Director.Mirror.Timeout"}
 	Director_OriginCacheHealthTestInterval = DurationParam{"Director.OriginCacheHealthTestInterval"}
 	Director_StatTimeout = DurationParam{"Director.StatTimeout"}
+	Federation_DiscoveryCacheTTL = DurationParam{"Federation.DiscoveryCacheTTL"}
 	Federation_TopologyReloadInterval = DurationParam{"Federation.TopologyReloadInterval"}
+	LocalCache_NegativeCacheTTL = DurationParam{"LocalCache.NegativeCacheTTL"}
+	Monitoring_AccessLogRetention = DurationParam{"Monitoring.AccessLogRetention"}
+	Monitoring_AccountingRetention = DurationParam{"Monitoring.AccountingRetention"}
+	Monitoring_Alerting_EvaluationInterval = DurationParam{"Monitoring.Alerting.EvaluationInterval"}
+	Monitoring_Alerting_RepeatInterval = DurationParam{"Monitoring.Alerting.RepeatInterval"}
+	Monitoring_TestFileRetention = DurationParam{"Monitoring.TestFileRetention"}
 	Monitoring_TokenExpiresIn = DurationParam{"Monitoring.TokenExpiresIn"}
 	Monitoring_TokenRefreshInterval = DurationParam{"Monitoring.TokenRefreshInterval"}
+	Origin_HsmStageTimeout = DurationParam{"Origin.HsmStageTimeout"}
+	Origin_ScrubInterval = DurationParam{"Origin.ScrubInterval"}
 	Origin_SelfTestInterval = DurationParam{"Origin.SelfTestInterval"}
+	Registry_DbConnectionMaxLifetime = DurationParam{"Registry.DbConnectionMaxLifetime"}
+	Registry_DbQueryTimeout = DurationParam{"Registry.DbQueryTimeout"}
 	Registry_InstitutionsUrlReloadMinutes = DurationParam{"Registry.InstitutionsUrlReloadMinutes"}
+	Registry_NamespaceSnapshotLifetime = DurationParam{"Registry.NamespaceSnapshotLifetime"}
+	Server_ClockSkewCheckInterval = DurationParam{"Server.ClockSkewCheckInterval"}
+	Server_ClockSkewTolerance = DurationParam{"Server.ClockSkewTolerance"}
+	Server_HostnameCheckInterval = DurationParam{"Server.HostnameCheckInterval"}
+	Server_IssuerJwksMaxStaleness = DurationParam{"Server.IssuerJwksMaxStaleness"}
+	Server_IssuerJwksRefreshInterval = DurationParam{"Server.IssuerJwksRefreshInterval"}
+	Server_IssuerKeyRotationInterval = DurationParam{"Server.IssuerKeyRotationInterval"}
+	Server_IssuerKeyRotationOverlap = DurationParam{"Server.IssuerKeyRotationOverlap"}
+	Server_RegistrationCheckInterval = DurationParam{"Server.RegistrationCheckInterval"}
+	Server_RegistrationMaxRetryInterval = DurationParam{"Server.RegistrationMaxRetryInterval"}
 	Server_RegistrationRetryInterval = DurationParam{"Server.RegistrationRetryInterval"}
+	Transport_DialerAttemptDelay = DurationParam{"Transport.DialerAttemptDelay"}
 	Transport_DialerKeepAlive = DurationParam{"Transport.DialerKeepAlive"}
 	Transport_DialerTimeout = DurationParam{"Transport.DialerTimeout"}
 	Transport_ExpectContinueTimeout = DurationParam{"Transport.ExpectContinueTimeout"}
@@ -236,10 +399,31 @@ var (
 )
 
 var (
+	Director_CacheRollouts = ObjectParam{"Director.CacheRollouts"}
+	Director_DiscoveryExtensions = ObjectParam{"Director.DiscoveryExtensions"}
 	GeoIPOverrides = ObjectParam{"GeoIPOverrides"}
 	Issuer_AuthorizationTemplates = ObjectParam{"Issuer.AuthorizationTemplates"}
 	Issuer_OIDCAuthenticationRequirements = ObjectParam{"Issuer.OIDCAuthenticationRequirements"}
+	Monitoring_Alerting_Rules = ObjectParam{"Monitoring.Alerting.Rules"}
+	Origin_Exports = ObjectParam{"Origin.Exports"}
+	Origin_PersistentIdentifiers = ObjectParam{"Origin.PersistentIdentifiers"}
+	Origin_StorageHooks = ObjectParam{"Origin.StorageHooks"}
+	Origin_UploadPolicies = ObjectParam{"Origin.UploadPolicies"}
 	Registry_CustomRegistrationFields = ObjectParam{"Registry.CustomRegistrationFields"}
 	Registry_Institutions = ObjectParam{"Registry.Institutions"}
 	Shoveler_IPMapping = ObjectParam{"Shoveler.IPMapping"}
 )
+
+// The deprecated parameters, mapped to the parameters replacing them; a deprecated
+// parameter without a replacement maps to the empty string
+var DeprecatedParams = map[string]string{
+	"Federation.NamespaceUrl": "Federation.RegistryUrl",
+}
+
+// The parameters holding credentials (see the "secret" key in docs/parameters.yaml),
+// whose values are redacted wherever the configuration is shown
+var SecretParams = map[string]bool{
+	"Client.EncryptionKey": true,
+	"Plugin.Token": true,
+	"Shoveler.StompPassword": true,
+}
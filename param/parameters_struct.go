@@ -3,426 +3,781 @@
 package param
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
 )
 
 type config struct {
 	Cache struct {
-		DataLocation string
-		EnableVoms bool
-		ExportLocation string
-		Port int
-		XRootDPrefix string
-	}
+		BlockSize string `mapstructure:"BlockSize"`
+		CachePolicyCheckInterval time.Duration `mapstructure:"CachePolicyCheckInterval"`
+		ConfigRestartDelay time.Duration `mapstructure:"ConfigRestartDelay"`
+		DataLocation string `mapstructure:"DataLocation"`
+		EnableIssuerValidation bool `mapstructure:"EnableIssuerValidation"`
+		EnableVoms bool `mapstructure:"EnableVoms"`
+		EnableWriteBack bool `mapstructure:"EnableWriteBack"`
+		ExportLocation string `mapstructure:"ExportLocation"`
+		IssuerMetadataRefreshInterval time.Duration `mapstructure:"IssuerMetadataRefreshInterval"`
+		IssuerNegativeCacheTTL time.Duration `mapstructure:"IssuerNegativeCacheTTL"`
+		MaxRequestSize string `mapstructure:"MaxRequestSize"`
+		Port int `mapstructure:"Port"`
+		PrefetchBlocks int `mapstructure:"PrefetchBlocks"`
+		RamSize string `mapstructure:"RamSize"`
+		ServeStaleOnOriginOutage bool `mapstructure:"ServeStaleOnOriginOutage"`
+		WriteBackLocation string `mapstructure:"WriteBackLocation"`
+		WriteBackMaxSpoolSize string `mapstructure:"WriteBackMaxSpoolSize"`
+		WriteBackRetries int `mapstructure:"WriteBackRetries"`
+		WriteBackRetryInterval time.Duration `mapstructure:"WriteBackRetryInterval"`
+		WriteBackWorkers int `mapstructure:"WriteBackWorkers"`
+		XRootDPrefix string `mapstructure:"XRootDPrefix"`
+	} `mapstructure:"Cache"`
 	Client struct {
-		DisableHttpProxy bool
-		DisableProxyFallback bool
-		MinimumDownloadSpeed int
-		SlowTransferRampupTime int
-		SlowTransferWindow int
-		StoppedTransferTimeout int
-	}
-	ConfigDir string
-	Debug bool
+		CircuitBreakerCooldown time.Duration `mapstructure:"CircuitBreakerCooldown"`
+		CircuitBreakerThreshold int `mapstructure:"CircuitBreakerThreshold"`
+		DisableFederationConfig bool `mapstructure:"DisableFederationConfig"`
+		DisableHttpProxy bool `mapstructure:"DisableHttpProxy"`
+		DisableProxyFallback bool `mapstructure:"DisableProxyFallback"`
+		EnableWriteBackUploads bool `mapstructure:"EnableWriteBackUploads"`
+		EncryptionKey string `mapstructure:"EncryptionKey"`
+		EncryptionKeyFile string `mapstructure:"EncryptionKeyFile"`
+		HttpProxy string `mapstructure:"HttpProxy" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		LocalCacheLocation string `mapstructure:"LocalCacheLocation"`
+		LocalCacheSize int `mapstructure:"LocalCacheSize"`
+		MaxRetryAfter time.Duration `mapstructure:"MaxRetryAfter"`
+		MinimumDownloadSpeed int `mapstructure:"MinimumDownloadSpeed"`
+		NoProxy []string `mapstructure:"NoProxy"`
+		PinFederationKeys bool `mapstructure:"PinFederationKeys"`
+		PinnedKeysFile string `mapstructure:"PinnedKeysFile"`
+		ProxyPasswordFile string `mapstructure:"ProxyPasswordFile"`
+		ResourceSignaturePolicy string `mapstructure:"ResourceSignaturePolicy"`
+		SelfUpdateChannel string `mapstructure:"SelfUpdateChannel"`
+		SelfUpdatePublicKey string `mapstructure:"SelfUpdatePublicKey"`
+		SelfUpdateUrl string `mapstructure:"SelfUpdateUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		SlowTransferPolicy string `mapstructure:"SlowTransferPolicy"`
+		SlowTransferRampupTime int `mapstructure:"SlowTransferRampupTime"`
+		SlowTransferWindow int `mapstructure:"SlowTransferWindow"`
+		SocksProxy string `mapstructure:"SocksProxy" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		StageTimeout time.Duration `mapstructure:"StageTimeout"`
+		StaticFederationFile string `mapstructure:"StaticFederationFile"`
+		StoppedTransferTimeout int `mapstructure:"StoppedTransferTimeout"`
+		TransferTimeout time.Duration `mapstructure:"TransferTimeout"`
+		WriteBackAck string `mapstructure:"WriteBackAck"`
+	} `mapstructure:"Client"`
+	ConfigDir string `mapstructure:"ConfigDir"`
+	Debug bool `mapstructure:"Debug"`
 	Director struct {
-		AdvertisementTTL time.Duration
-		CacheResponseHostnames []string
-		DefaultResponse string
-		GeoIPLocation string
-		MaxMindKeyFile string
-		MaxStatResponse int
-		MinStatResponse int
-		OriginCacheHealthTestInterval time.Duration
-		OriginResponseHostnames []string
-		StatConcurrencyLimit int
-		StatTimeout time.Duration
-	}
-	DisableHttpProxy bool
-	DisableProxyFallback bool
+		AdvertisementTTL time.Duration `mapstructure:"AdvertisementTTL"`
+		CacheResponseHostnames []string `mapstructure:"CacheResponseHostnames"`
+		CacheRollouts interface{} `mapstructure:"CacheRollouts"`
+		ClientConfigFile string `mapstructure:"ClientConfigFile"`
+		DefaultResponse string `mapstructure:"DefaultResponse" validate:"omitempty,oneof=cache origin"`
+		DiscoveryExtensions interface{} `mapstructure:"DiscoveryExtensions"`
+		Fairness struct {
+			IPv4PrefixLength int `mapstructure:"IPv4PrefixLength"`
+			IPv6PrefixLength int `mapstructure:"IPv6PrefixLength"`
+			MaxConcurrentRequests int `mapstructure:"MaxConcurrentRequests"`
+			Window time.Duration `mapstructure:"Window"`
+		} `mapstructure:"Fairness"`
+		FederationContact string `mapstructure:"FederationContact"`
+		FederationDisplayName string `mapstructure:"FederationDisplayName"`
+		GeoIPLocation string `mapstructure:"GeoIPLocation"`
+		GeoIPOverridesFile string `mapstructure:"GeoIPOverridesFile"`
+		GeoIPRefreshInterval time.Duration `mapstructure:"GeoIPRefreshInterval"`
+		KeyRevocationRefreshInterval time.Duration `mapstructure:"KeyRevocationRefreshInterval"`
+		LoadWeighting struct {
+			RefreshInterval time.Duration `mapstructure:"RefreshInterval"`
+			ThroughputWeight int `mapstructure:"ThroughputWeight"`
+			ThroughputWindow time.Duration `mapstructure:"ThroughputWindow"`
+		} `mapstructure:"LoadWeighting"`
+		MaintenanceDrainPeriod time.Duration `mapstructure:"MaintenanceDrainPeriod"`
+		MaxMindKeyFile string `mapstructure:"MaxMindKeyFile"`
+		MaxStatResponse int `mapstructure:"MaxStatResponse"`
+		MinStatResponse int `mapstructure:"MinStatResponse"`
+		MinimumCacheVersion string `mapstructure:"MinimumCacheVersion"`
+		MinimumClientVersion string `mapstructure:"MinimumClientVersion"`
+		MinimumOriginVersion string `mapstructure:"MinimumOriginVersion"`
+		MinimumVersionPolicy string `mapstructure:"MinimumVersionPolicy"`
+		Mirror struct {
+			MaxConcurrency int `mapstructure:"MaxConcurrency"`
+			Percent int `mapstructure:"Percent"`
+			Timeout time.Duration `mapstructure:"Timeout"`
+			Url string `mapstructure:"Url" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		} `mapstructure:"Mirror"`
+		OriginCacheHealthTestInterval time.Duration `mapstructure:"OriginCacheHealthTestInterval"`
+		OriginResponseHostnames []string `mapstructure:"OriginResponseHostnames"`
+		RequireAdvertisementSignature bool `mapstructure:"RequireAdvertisementSignature"`
+		StatConcurrencyLimit int `mapstructure:"StatConcurrencyLimit"`
+		StatTimeout time.Duration `mapstructure:"StatTimeout"`
+	} `mapstructure:"Director"`
+	DisableHttpProxy bool `mapstructure:"DisableHttpProxy"`
+	DisableProxyFallback bool `mapstructure:"DisableProxyFallback"`
 	Federation struct {
-		DirectorUrl string
-		DiscoveryUrl string
-		JwkUrl string
-		NamespaceUrl string
-		RegistryUrl string
-		TopologyNamespaceUrl string
-		TopologyReloadInterval time.Duration
-		TopologyUrl string
-	}
-	GeoIPOverrides interface{}
+		BrokerUrl string `mapstructure:"BrokerUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		ClientConfigUrl string `mapstructure:"ClientConfigUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		DirectorUrl string `mapstructure:"DirectorUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		DiscoveryCacheFile string `mapstructure:"DiscoveryCacheFile"`
+		DiscoveryCacheTTL time.Duration `mapstructure:"DiscoveryCacheTTL"`
+		DiscoveryRetries int `mapstructure:"DiscoveryRetries"`
+		DiscoveryUrl string `mapstructure:"DiscoveryUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		JwkUrl string `mapstructure:"JwkUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		NamespaceUrl string `mapstructure:"NamespaceUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		RegistryUrl string `mapstructure:"RegistryUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		TopologyNamespaceUrl string `mapstructure:"TopologyNamespaceUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		TopologyReloadInterval time.Duration `mapstructure:"TopologyReloadInterval"`
+		TopologyUrl string `mapstructure:"TopologyUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+	} `mapstructure:"Federation"`
+	GeoIPOverrides interface{} `mapstructure:"GeoIPOverrides"`
+	Includes []string `mapstructure:"Includes"`
 	Issuer struct {
-		AuthenticationSource string
-		AuthorizationTemplates interface{}
-		GroupFile string
-		GroupRequirements []string
-		GroupSource string
-		OIDCAuthenticationRequirements interface{}
-		OIDCAuthenticationUserClaim string
-		QDLLocation string
-		ScitokensServerLocation string
-		TomcatLocation string
-	}
-	IssuerKey string
+		AuthenticationSource string `mapstructure:"AuthenticationSource"`
+		AuthorizationTemplates interface{} `mapstructure:"AuthorizationTemplates"`
+		GroupFile string `mapstructure:"GroupFile"`
+		GroupRequirements []string `mapstructure:"GroupRequirements"`
+		GroupSource string `mapstructure:"GroupSource" validate:"omitempty,oneof=none file"`
+		KeyAlgorithm string `mapstructure:"KeyAlgorithm" validate:"omitempty,oneof=EC-P256 EC-P384 EC-P521 RSA-2048 RSA-4096"`
+		OIDCAuthenticationRequirements interface{} `mapstructure:"OIDCAuthenticationRequirements"`
+		OIDCAuthenticationUserClaim string `mapstructure:"OIDCAuthenticationUserClaim"`
+		QDLLocation string `mapstructure:"QDLLocation"`
+		ScitokensServerLocation string `mapstructure:"ScitokensServerLocation"`
+		TomcatLocation string `mapstructure:"TomcatLocation"`
+	} `mapstructure:"Issuer"`
+	IssuerKey string `mapstructure:"IssuerKey"`
+	LocalCache struct {
+		DataLocation string `mapstructure:"DataLocation"`
+		NegativeCacheTTL time.Duration `mapstructure:"NegativeCacheTTL"`
+		Port int `mapstructure:"Port"`
+		Size int `mapstructure:"Size"`
+		Socket string `mapstructure:"Socket"`
+		SocketMode string `mapstructure:"SocketMode"`
+	} `mapstructure:"LocalCache"`
 	Logging struct {
 		Cache struct {
-			Ofs string
-			Pss string
-			Scitokens string
-			Xrd string
-		}
-		DisableProgressBars bool
-		Level string
-		LogLocation string
+			Ofs string `mapstructure:"Ofs"`
+			Pss string `mapstructure:"Pss"`
+			Scitokens string `mapstructure:"Scitokens"`
+			Xrd string `mapstructure:"Xrd"`
+		} `mapstructure:"Cache"`
+		DisableProgressBars bool `mapstructure:"DisableProgressBars"`
+		Format string `mapstructure:"Format"`
+		Level string `mapstructure:"Level"`
+		LogLocation string `mapstructure:"LogLocation"`
 		Origin struct {
-			Cms string
-			Pfc string
-			Pss string
-			Scitokens string
-			Xrootd string
-		}
-	}
-	MinimumDownloadSpeed int
+			Cms string `mapstructure:"Cms"`
+			Pfc string `mapstructure:"Pfc"`
+			Pss string `mapstructure:"Pss"`
+			Scitokens string `mapstructure:"Scitokens"`
+			Xrootd string `mapstructure:"Xrootd"`
+		} `mapstructure:"Origin"`
+	} `mapstructure:"Logging"`
+	MinimumDownloadSpeed int `mapstructure:"MinimumDownloadSpeed"`
 	Monitoring struct {
-		AggregatePrefixes []string
-		DataLocation string
-		MetricAuthorization bool
-		PortHigher int
-		PortLower int
-		TokenExpiresIn time.Duration
-		TokenRefreshInterval time.Duration
-	}
+		AccessLogMaxEntries int `mapstructure:"AccessLogMaxEntries"`
+		AccessLogRetention time.Duration `mapstructure:"AccessLogRetention"`
+		AccountingFile string `mapstructure:"AccountingFile"`
+		AccountingRetention time.Duration `mapstructure:"AccountingRetention"`
+		AggregatePrefixes []string `mapstructure:"AggregatePrefixes"`
+		Alerting struct {
+			DisableDefaultRules bool `mapstructure:"DisableDefaultRules"`
+			Email struct {
+				From string `mapstructure:"From"`
+				PasswordFile string `mapstructure:"PasswordFile"`
+				SmtpServer string `mapstructure:"SmtpServer"`
+				To []string `mapstructure:"To"`
+				Username string `mapstructure:"Username"`
+			} `mapstructure:"Email"`
+			EvaluationInterval time.Duration `mapstructure:"EvaluationInterval"`
+			RepeatInterval time.Duration `mapstructure:"RepeatInterval"`
+			Rules interface{} `mapstructure:"Rules"`
+			WebhookUrls []string `mapstructure:"WebhookUrls"`
+		} `mapstructure:"Alerting"`
+		DataLocation string `mapstructure:"DataLocation"`
+		EnableAccessLogs bool `mapstructure:"EnableAccessLogs"`
+		EnableAccounting bool `mapstructure:"EnableAccounting"`
+		MetricAuthorization bool `mapstructure:"MetricAuthorization"`
+		PortHigher int `mapstructure:"PortHigher"`
+		PortLower int `mapstructure:"PortLower"`
+		TestFileRetention time.Duration `mapstructure:"TestFileRetention"`
+		TokenExpiresIn time.Duration `mapstructure:"TokenExpiresIn"`
+		TokenRefreshInterval time.Duration `mapstructure:"TokenRefreshInterval"`
+	} `mapstructure:"Monitoring"`
 	OIDC struct {
-		AuthorizationEndpoint string
-		ClientID string
-		ClientIDFile string
-		ClientRedirectHostname string
-		ClientSecretFile string
-		DeviceAuthEndpoint string
-		Issuer string
-		TokenEndpoint string
-		UserInfoEndpoint string
-	}
+		AuthorizationEndpoint string `mapstructure:"AuthorizationEndpoint" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		ClientID string `mapstructure:"ClientID"`
+		ClientIDFile string `mapstructure:"ClientIDFile"`
+		ClientRedirectHostname string `mapstructure:"ClientRedirectHostname"`
+		ClientSecretFile string `mapstructure:"ClientSecretFile"`
+		DeviceAuthEndpoint string `mapstructure:"DeviceAuthEndpoint" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		Issuer string `mapstructure:"Issuer" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		TokenEndpoint string `mapstructure:"TokenEndpoint" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		UserInfoEndpoint string `mapstructure:"UserInfoEndpoint" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+	} `mapstructure:"OIDC"`
 	Origin struct {
-		EnableCmsd bool
-		EnableDirListing bool
-		EnableFallbackRead bool
-		EnableIssuer bool
-		EnablePublicReads bool
-		EnableUI bool
-		EnableVoms bool
-		EnableWrite bool
-		ExportVolume string
-		Mode string
-		Multiuser bool
-		NamespacePrefix string
-		S3AccessKeyfile string
-		S3Bucket string
-		S3Region string
-		S3SecretKeyfile string
-		S3ServiceName string
-		S3ServiceUrl string
-		ScitokensDefaultUser string
-		ScitokensMapSubject bool
-		ScitokensNameMapFile string
-		ScitokensRestrictedPaths []string
-		ScitokensUsernameClaim string
-		SelfTest bool
-		SelfTestInterval time.Duration
-		Url string
-		XRootDPrefix string
-	}
+		ChecksumAlgorithms []string `mapstructure:"ChecksumAlgorithms"`
+		ChecksumWorkers int `mapstructure:"ChecksumWorkers"`
+		EnableCmsd bool `mapstructure:"EnableCmsd"`
+		EnableDirListing bool `mapstructure:"EnableDirListing"`
+		EnableFallbackRead bool `mapstructure:"EnableFallbackRead"`
+		EnableIssuer bool `mapstructure:"EnableIssuer"`
+		EnablePublicReads bool `mapstructure:"EnablePublicReads"`
+		EnableScrubber bool `mapstructure:"EnableScrubber"`
+		EnableUI bool `mapstructure:"EnableUI"`
+		EnableVoms bool `mapstructure:"EnableVoms"`
+		EnableWrite bool `mapstructure:"EnableWrite"`
+		ExportVolume string `mapstructure:"ExportVolume"`
+		Exports interface{} `mapstructure:"Exports"`
+		HsmStageCommand string `mapstructure:"HsmStageCommand"`
+		HsmStageTimeout time.Duration `mapstructure:"HsmStageTimeout"`
+		Mode string `mapstructure:"Mode" validate:"omitempty,oneof=posix s3 hsm"`
+		Multiuser bool `mapstructure:"Multiuser"`
+		NamespacePrefix string `mapstructure:"NamespacePrefix"`
+		PausedExportsFile string `mapstructure:"PausedExportsFile"`
+		PersistentIdentifiers interface{} `mapstructure:"PersistentIdentifiers"`
+		S3AccessKeyfile string `mapstructure:"S3AccessKeyfile"`
+		S3Bucket string `mapstructure:"S3Bucket"`
+		S3KMSKeyId string `mapstructure:"S3KMSKeyId"`
+		S3Region string `mapstructure:"S3Region"`
+		S3SecretKeyfile string `mapstructure:"S3SecretKeyfile"`
+		S3ServerSideEncryption string `mapstructure:"S3ServerSideEncryption"`
+		S3ServiceName string `mapstructure:"S3ServiceName"`
+		S3ServiceUrl string `mapstructure:"S3ServiceUrl"`
+		ScitokensDefaultUser string `mapstructure:"ScitokensDefaultUser"`
+		ScitokensMapSubject bool `mapstructure:"ScitokensMapSubject"`
+		ScitokensNameMapFile string `mapstructure:"ScitokensNameMapFile"`
+		ScitokensRestrictedPaths []string `mapstructure:"ScitokensRestrictedPaths"`
+		ScitokensUsernameClaim string `mapstructure:"ScitokensUsernameClaim"`
+		ScrubBandwidth int `mapstructure:"ScrubBandwidth"`
+		ScrubInterval time.Duration `mapstructure:"ScrubInterval"`
+		SelfTest bool `mapstructure:"SelfTest"`
+		SelfTestInterval time.Duration `mapstructure:"SelfTestInterval"`
+		StorageHooks interface{} `mapstructure:"StorageHooks"`
+		UploadPolicies interface{} `mapstructure:"UploadPolicies"`
+		Url string `mapstructure:"Url" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		XRootDPrefix string `mapstructure:"XRootDPrefix"`
+	} `mapstructure:"Origin"`
 	Plugin struct {
-		Token string
-	}
+		Token string `mapstructure:"Token"`
+	} `mapstructure:"Plugin"`
 	Registry struct {
-		AdminUsers []string
-		CustomRegistrationFields interface{}
-		DbLocation string
-		Institutions interface{}
-		InstitutionsUrl string
-		InstitutionsUrlReloadMinutes time.Duration
-		RequireCacheApproval bool
-		RequireKeyChaining bool
-		RequireOriginApproval bool
-	}
+		AdminUsers []string `mapstructure:"AdminUsers"`
+		CustomRegistrationFields interface{} `mapstructure:"CustomRegistrationFields"`
+		DbConnectionMaxLifetime time.Duration `mapstructure:"DbConnectionMaxLifetime"`
+		DbLocation string `mapstructure:"DbLocation"`
+		DbMaxIdleConnections int `mapstructure:"DbMaxIdleConnections"`
+		DbMaxOpenConnections int `mapstructure:"DbMaxOpenConnections"`
+		DbQueryTimeout time.Duration `mapstructure:"DbQueryTimeout"`
+		Institutions interface{} `mapstructure:"Institutions"`
+		InstitutionsUrl string `mapstructure:"InstitutionsUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		InstitutionsUrlReloadMinutes time.Duration `mapstructure:"InstitutionsUrlReloadMinutes"`
+		NamespaceSnapshotLifetime time.Duration `mapstructure:"NamespaceSnapshotLifetime"`
+		RequireCacheApproval bool `mapstructure:"RequireCacheApproval"`
+		RequireKeyChaining bool `mapstructure:"RequireKeyChaining"`
+		RequireOriginApproval bool `mapstructure:"RequireOriginApproval"`
+		TermsOfServiceUrl string `mapstructure:"TermsOfServiceUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		TermsOfServiceVersion string `mapstructure:"TermsOfServiceVersion"`
+	} `mapstructure:"Registry"`
+	Secrets struct {
+		AWSEndpoint string `mapstructure:"AWSEndpoint" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		AWSRegion string `mapstructure:"AWSRegion"`
+		AWSSecretPrefix string `mapstructure:"AWSSecretPrefix"`
+		Provider string `mapstructure:"Provider"`
+		VaultAddress string `mapstructure:"VaultAddress" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		VaultPath string `mapstructure:"VaultPath"`
+		VaultTokenFile string `mapstructure:"VaultTokenFile"`
+	} `mapstructure:"Secrets"`
 	Server struct {
-		EnableUI bool
-		ExternalWebUrl string
-		Hostname string
-		IssuerHostname string
-		IssuerJwks string
-		IssuerPort int
-		IssuerUrl string
-		Modules []string
-		RegistrationRetryInterval time.Duration
-		SessionSecretFile string
-		TLSCACertificateDirectory string
-		TLSCACertificateFile string
-		TLSCAKey string
-		TLSCertificate string
-		TLSKey string
-		UIActivationCodeFile string
-		UIPasswordFile string
-		WebHost string
-		WebPort int
-	}
+		AcceptedTermsOfService string `mapstructure:"AcceptedTermsOfService"`
+		AdvertiseHealthChecks bool `mapstructure:"AdvertiseHealthChecks"`
+		ClockSkewCheckInterval time.Duration `mapstructure:"ClockSkewCheckInterval"`
+		ClockSkewTolerance time.Duration `mapstructure:"ClockSkewTolerance"`
+		EnableUI bool `mapstructure:"EnableUI"`
+		ExternalWebUrl string `mapstructure:"ExternalWebUrl" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		FailOnClockSkew bool `mapstructure:"FailOnClockSkew"`
+		Hostname string `mapstructure:"Hostname"`
+		HostnameCheckInterval time.Duration `mapstructure:"HostnameCheckInterval"`
+		IssuerHostname string `mapstructure:"IssuerHostname"`
+		IssuerJwks string `mapstructure:"IssuerJwks"`
+		IssuerJwksMaxStaleness time.Duration `mapstructure:"IssuerJwksMaxStaleness"`
+		IssuerJwksRefreshInterval time.Duration `mapstructure:"IssuerJwksRefreshInterval"`
+		IssuerKeyRotationInterval time.Duration `mapstructure:"IssuerKeyRotationInterval"`
+		IssuerKeyRotationOverlap time.Duration `mapstructure:"IssuerKeyRotationOverlap"`
+		IssuerPort int `mapstructure:"IssuerPort"`
+		IssuerUrl string `mapstructure:"IssuerUrl"`
+		ListenAddresses []string `mapstructure:"ListenAddresses"`
+		Modules []string `mapstructure:"Modules"`
+		RegistrationCheckInterval time.Duration `mapstructure:"RegistrationCheckInterval"`
+		RegistrationMaxRetryInterval time.Duration `mapstructure:"RegistrationMaxRetryInterval"`
+		RegistrationRetryInterval time.Duration `mapstructure:"RegistrationRetryInterval"`
+		RegistrationStateFile string `mapstructure:"RegistrationStateFile"`
+		SessionSecretFile string `mapstructure:"SessionSecretFile"`
+		TLSCACertificateDirectory string `mapstructure:"TLSCACertificateDirectory"`
+		TLSCACertificateFile string `mapstructure:"TLSCACertificateFile"`
+		TLSCAKey string `mapstructure:"TLSCAKey"`
+		TLSCertificate string `mapstructure:"TLSCertificate"`
+		TLSKey string `mapstructure:"TLSKey"`
+		UIActivationCodeFile string `mapstructure:"UIActivationCodeFile"`
+		UIPasswordFile string `mapstructure:"UIPasswordFile"`
+		UnixSocket string `mapstructure:"UnixSocket"`
+		UnixSocketMode string `mapstructure:"UnixSocketMode"`
+		WebHost string `mapstructure:"WebHost"`
+		WebPort int `mapstructure:"WebPort"`
+	} `mapstructure:"Server"`
 	Shoveler struct {
-		AMQPExchange string
-		AMQPTokenLocation string
-		Enable bool
-		IPMapping interface{}
-		MessageQueueProtocol string
-		OutputDestinations []string
-		PortHigher int
-		PortLower int
-		QueueDirectory string
-		StompCert string
-		StompCertKey string
-		StompPassword string
-		StompUsername string
-		Topic string
-		URL string
-		VerifyHeader bool
-	}
+		AMQPExchange string `mapstructure:"AMQPExchange"`
+		AMQPTokenLocation string `mapstructure:"AMQPTokenLocation"`
+		Enable bool `mapstructure:"Enable"`
+		IPMapping interface{} `mapstructure:"IPMapping"`
+		MessageQueueProtocol string `mapstructure:"MessageQueueProtocol"`
+		OutputDestinations []string `mapstructure:"OutputDestinations"`
+		PortHigher int `mapstructure:"PortHigher"`
+		PortLower int `mapstructure:"PortLower"`
+		QueueDirectory string `mapstructure:"QueueDirectory"`
+		StompCert string `mapstructure:"StompCert"`
+		StompCertKey string `mapstructure:"StompCertKey"`
+		StompPassword string `mapstructure:"StompPassword"`
+		StompUsername string `mapstructure:"StompUsername"`
+		Topic string `mapstructure:"Topic"`
+		URL string `mapstructure:"URL" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		VerifyHeader bool `mapstructure:"VerifyHeader"`
+	} `mapstructure:"Shoveler"`
 	StagePlugin struct {
-		Hook bool
-		MountPrefix string
-		OriginPrefix string
-		ShadowOriginPrefix string
-	}
-	TLSSkipVerify bool
+		Hook bool `mapstructure:"Hook"`
+		MountPrefix string `mapstructure:"MountPrefix"`
+		OriginPrefix string `mapstructure:"OriginPrefix"`
+		ShadowOriginPrefix string `mapstructure:"ShadowOriginPrefix"`
+	} `mapstructure:"StagePlugin"`
+	StrictConfigValidation bool `mapstructure:"StrictConfigValidation"`
+	TLSSkipVerify bool `mapstructure:"TLSSkipVerify"`
 	Transport struct {
-		DialerKeepAlive time.Duration
-		DialerTimeout time.Duration
-		ExpectContinueTimeout time.Duration
-		IdleConnTimeout time.Duration
-		MaxIdleConns int
-		ResponseHeaderTimeout time.Duration
-		TLSHandshakeTimeout time.Duration
-	}
+		DialerAttemptDelay time.Duration `mapstructure:"DialerAttemptDelay"`
+		DialerKeepAlive time.Duration `mapstructure:"DialerKeepAlive"`
+		DialerTimeout time.Duration `mapstructure:"DialerTimeout"`
+		ExpectContinueTimeout time.Duration `mapstructure:"ExpectContinueTimeout"`
+		IdleConnTimeout time.Duration `mapstructure:"IdleConnTimeout"`
+		MaxIdleConns int `mapstructure:"MaxIdleConns"`
+		ResponseHeaderTimeout time.Duration `mapstructure:"ResponseHeaderTimeout"`
+		TLSHandshakeTimeout time.Duration `mapstructure:"TLSHandshakeTimeout"`
+	} `mapstructure:"Transport"`
 	Xrootd struct {
-		Authfile string
-		DetailedMonitoringHost string
-		LocalMonitoringHost string
-		MacaroonsKeyFile string
-		ManagerHost string
-		Mount string
-		Port int
-		RobotsTxtFile string
-		RunLocation string
-		ScitokensConfig string
-		Sitename string
-		SummaryMonitoringHost string
-	}
+		Authfile string `mapstructure:"Authfile"`
+		DetailedMonitoringHost string `mapstructure:"DetailedMonitoringHost" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		LocalMonitoringHost string `mapstructure:"LocalMonitoringHost" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		MacaroonsKeyFile string `mapstructure:"MacaroonsKeyFile"`
+		ManagerHost string `mapstructure:"ManagerHost" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+		Mount string `mapstructure:"Mount"`
+		Port int `mapstructure:"Port"`
+		RobotsTxtFile string `mapstructure:"RobotsTxtFile"`
+		RunLocation string `mapstructure:"RunLocation"`
+		ScitokensConfig string `mapstructure:"ScitokensConfig"`
+		Sitename string `mapstructure:"Sitename"`
+		SummaryMonitoringHost string `mapstructure:"SummaryMonitoringHost" validate:"omitempty,url|hostname_port|hostname_rfc1123"`
+	} `mapstructure:"Xrootd"`
 }
 
 
 type configWithType struct {
 	Cache struct {
-		DataLocation struct { Type string; Value string }
-		EnableVoms struct { Type string; Value bool }
-		ExportLocation struct { Type string; Value string }
-		Port struct { Type string; Value int }
-		XRootDPrefix struct { Type string; Value string }
+		BlockSize struct { Type string; Value string; Source string `json:",omitempty"` }
+		CachePolicyCheckInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		ConfigRestartDelay struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		DataLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		EnableIssuerValidation struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableVoms struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableWriteBack struct { Type string; Value bool; Source string `json:",omitempty"` }
+		ExportLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		IssuerMetadataRefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerNegativeCacheTTL struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		MaxRequestSize struct { Type string; Value string; Source string `json:",omitempty"` }
+		Port struct { Type string; Value int; Source string `json:",omitempty"` }
+		PrefetchBlocks struct { Type string; Value int; Source string `json:",omitempty"` }
+		RamSize struct { Type string; Value string; Source string `json:",omitempty"` }
+		ServeStaleOnOriginOutage struct { Type string; Value bool; Source string `json:",omitempty"` }
+		WriteBackLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		WriteBackMaxSpoolSize struct { Type string; Value string; Source string `json:",omitempty"` }
+		WriteBackRetries struct { Type string; Value int; Source string `json:",omitempty"` }
+		WriteBackRetryInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		WriteBackWorkers struct { Type string; Value int; Source string `json:",omitempty"` }
+		XRootDPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
 	Client struct {
-		DisableHttpProxy struct { Type string; Value bool }
-		DisableProxyFallback struct { Type string; Value bool }
-		MinimumDownloadSpeed struct { Type string; Value int }
-		SlowTransferRampupTime struct { Type string; Value int }
-		SlowTransferWindow struct { Type string; Value int }
-		StoppedTransferTimeout struct { Type string; Value int }
-	}
-	ConfigDir struct { Type string; Value string }
-	Debug struct { Type string; Value bool }
+		CircuitBreakerCooldown struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		CircuitBreakerThreshold struct { Type string; Value int; Source string `json:",omitempty"` }
+		DisableFederationConfig struct { Type string; Value bool; Source string `json:",omitempty"` }
+		DisableHttpProxy struct { Type string; Value bool; Source string `json:",omitempty"` }
+		DisableProxyFallback struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableWriteBackUploads struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EncryptionKey struct { Type string; Value string; Source string `json:",omitempty"` }
+		EncryptionKeyFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		HttpProxy struct { Type string; Value string; Source string `json:",omitempty"` }
+		LocalCacheLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		LocalCacheSize struct { Type string; Value int; Source string `json:",omitempty"` }
+		MaxRetryAfter struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		MinimumDownloadSpeed struct { Type string; Value int; Source string `json:",omitempty"` }
+		NoProxy struct { Type string; Value []string; Source string `json:",omitempty"` }
+		PinFederationKeys struct { Type string; Value bool; Source string `json:",omitempty"` }
+		PinnedKeysFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		ProxyPasswordFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		ResourceSignaturePolicy struct { Type string; Value string; Source string `json:",omitempty"` }
+		SelfUpdateChannel struct { Type string; Value string; Source string `json:",omitempty"` }
+		SelfUpdatePublicKey struct { Type string; Value string; Source string `json:",omitempty"` }
+		SelfUpdateUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		SlowTransferPolicy struct { Type string; Value string; Source string `json:",omitempty"` }
+		SlowTransferRampupTime struct { Type string; Value int; Source string `json:",omitempty"` }
+		SlowTransferWindow struct { Type string; Value int; Source string `json:",omitempty"` }
+		SocksProxy struct { Type string; Value string; Source string `json:",omitempty"` }
+		StageTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		StaticFederationFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		StoppedTransferTimeout struct { Type string; Value int; Source string `json:",omitempty"` }
+		TransferTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		WriteBackAck struct { Type string; Value string; Source string `json:",omitempty"` }
+	}
+	ConfigDir struct { Type string; Value string; Source string `json:",omitempty"` }
+	Debug struct { Type string; Value bool; Source string `json:",omitempty"` }
 	Director struct {
-		AdvertisementTTL struct { Type string; Value time.Duration }
-		CacheResponseHostnames struct { Type string; Value []string }
-		DefaultResponse struct { Type string; Value string }
-		GeoIPLocation struct { Type string; Value string }
-		MaxMindKeyFile struct { Type string; Value string }
-		MaxStatResponse struct { Type string; Value int }
-		MinStatResponse struct { Type string; Value int }
-		OriginCacheHealthTestInterval struct { Type string; Value time.Duration }
-		OriginResponseHostnames struct { Type string; Value []string }
-		StatConcurrencyLimit struct { Type string; Value int }
-		StatTimeout struct { Type string; Value time.Duration }
-	}
-	DisableHttpProxy struct { Type string; Value bool }
-	DisableProxyFallback struct { Type string; Value bool }
+		AdvertisementTTL struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		CacheResponseHostnames struct { Type string; Value []string; Source string `json:",omitempty"` }
+		CacheRollouts struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		ClientConfigFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		DefaultResponse struct { Type string; Value string; Source string `json:",omitempty"` }
+		DiscoveryExtensions struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		Fairness struct {
+			IPv4PrefixLength struct { Type string; Value int; Source string `json:",omitempty"` }
+			IPv6PrefixLength struct { Type string; Value int; Source string `json:",omitempty"` }
+			MaxConcurrentRequests struct { Type string; Value int; Source string `json:",omitempty"` }
+			Window struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		}
+		FederationContact struct { Type string; Value string; Source string `json:",omitempty"` }
+		FederationDisplayName struct { Type string; Value string; Source string `json:",omitempty"` }
+		GeoIPLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		GeoIPOverridesFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		GeoIPRefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		KeyRevocationRefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		LoadWeighting struct {
+			RefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+			ThroughputWeight struct { Type string; Value int; Source string `json:",omitempty"` }
+			ThroughputWindow struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		}
+		MaintenanceDrainPeriod struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		MaxMindKeyFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		MaxStatResponse struct { Type string; Value int; Source string `json:",omitempty"` }
+		MinStatResponse struct { Type string; Value int; Source string `json:",omitempty"` }
+		MinimumCacheVersion struct { Type string; Value string; Source string `json:",omitempty"` }
+		MinimumClientVersion struct { Type string; Value string; Source string `json:",omitempty"` }
+		MinimumOriginVersion struct { Type string; Value string; Source string `json:",omitempty"` }
+		MinimumVersionPolicy struct { Type string; Value string; Source string `json:",omitempty"` }
+		Mirror struct {
+			MaxConcurrency struct { Type string; Value int; Source string `json:",omitempty"` }
+			Percent struct { Type string; Value int; Source string `json:",omitempty"` }
+			Timeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+			Url struct { Type string; Value string; Source string `json:",omitempty"` }
+		}
+		OriginCacheHealthTestInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		OriginResponseHostnames struct { Type string; Value []string; Source string `json:",omitempty"` }
+		RequireAdvertisementSignature struct { Type string; Value bool; Source string `json:",omitempty"` }
+		StatConcurrencyLimit struct { Type string; Value int; Source string `json:",omitempty"` }
+		StatTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+	}
+	DisableHttpProxy struct { Type string; Value bool; Source string `json:",omitempty"` }
+	DisableProxyFallback struct { Type string; Value bool; Source string `json:",omitempty"` }
 	Federation struct {
-		DirectorUrl struct { Type string; Value string }
-		DiscoveryUrl struct { Type string; Value string }
-		JwkUrl struct { Type string; Value string }
-		NamespaceUrl struct { Type string; Value string }
-		RegistryUrl struct { Type string; Value string }
-		TopologyNamespaceUrl struct { Type string; Value string }
-		TopologyReloadInterval struct { Type string; Value time.Duration }
-		TopologyUrl struct { Type string; Value string }
-	}
-	GeoIPOverrides struct { Type string; Value interface{} }
+		BrokerUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		ClientConfigUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		DirectorUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		DiscoveryCacheFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		DiscoveryCacheTTL struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		DiscoveryRetries struct { Type string; Value int; Source string `json:",omitempty"` }
+		DiscoveryUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		JwkUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		NamespaceUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		RegistryUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		TopologyNamespaceUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		TopologyReloadInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		TopologyUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+	}
+	GeoIPOverrides struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+	Includes struct { Type string; Value []string; Source string `json:",omitempty"` }
 	Issuer struct {
-		AuthenticationSource struct { Type string; Value string }
-		AuthorizationTemplates struct { Type string; Value interface{} }
-		GroupFile struct { Type string; Value string }
-		GroupRequirements struct { Type string; Value []string }
-		GroupSource struct { Type string; Value string }
-		OIDCAuthenticationRequirements struct { Type string; Value interface{} }
-		OIDCAuthenticationUserClaim struct { Type string; Value string }
-		QDLLocation struct { Type string; Value string }
-		ScitokensServerLocation struct { Type string; Value string }
-		TomcatLocation struct { Type string; Value string }
+		AuthenticationSource struct { Type string; Value string; Source string `json:",omitempty"` }
+		AuthorizationTemplates struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		GroupFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		GroupRequirements struct { Type string; Value []string; Source string `json:",omitempty"` }
+		GroupSource struct { Type string; Value string; Source string `json:",omitempty"` }
+		KeyAlgorithm struct { Type string; Value string; Source string `json:",omitempty"` }
+		OIDCAuthenticationRequirements struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		OIDCAuthenticationUserClaim struct { Type string; Value string; Source string `json:",omitempty"` }
+		QDLLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScitokensServerLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		TomcatLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+	}
+	IssuerKey struct { Type string; Value string; Source string `json:",omitempty"` }
+	LocalCache struct {
+		DataLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		NegativeCacheTTL struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		Port struct { Type string; Value int; Source string `json:",omitempty"` }
+		Size struct { Type string; Value int; Source string `json:",omitempty"` }
+		Socket struct { Type string; Value string; Source string `json:",omitempty"` }
+		SocketMode struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
-	IssuerKey struct { Type string; Value string }
 	Logging struct {
 		Cache struct {
-			Ofs struct { Type string; Value string }
-			Pss struct { Type string; Value string }
-			Scitokens struct { Type string; Value string }
-			Xrd struct { Type string; Value string }
+			Ofs struct { Type string; Value string; Source string `json:",omitempty"` }
+			Pss struct { Type string; Value string; Source string `json:",omitempty"` }
+			Scitokens struct { Type string; Value string; Source string `json:",omitempty"` }
+			Xrd struct { Type string; Value string; Source string `json:",omitempty"` }
 		}
-		DisableProgressBars struct { Type string; Value bool }
-		Level struct { Type string; Value string }
-		LogLocation struct { Type string; Value string }
+		DisableProgressBars struct { Type string; Value bool; Source string `json:",omitempty"` }
+		Format struct { Type string; Value string; Source string `json:",omitempty"` }
+		Level struct { Type string; Value string; Source string `json:",omitempty"` }
+		LogLocation struct { Type string; Value string; Source string `json:",omitempty"` }
 		Origin struct {
-			Cms struct { Type string; Value string }
-			Pfc struct { Type string; Value string }
-			Pss struct { Type string; Value string }
-			Scitokens struct { Type string; Value string }
-			Xrootd struct { Type string; Value string }
+			Cms struct { Type string; Value string; Source string `json:",omitempty"` }
+			Pfc struct { Type string; Value string; Source string `json:",omitempty"` }
+			Pss struct { Type string; Value string; Source string `json:",omitempty"` }
+			Scitokens struct { Type string; Value string; Source string `json:",omitempty"` }
+			Xrootd struct { Type string; Value string; Source string `json:",omitempty"` }
 		}
 	}
-	MinimumDownloadSpeed struct { Type string; Value int }
+	MinimumDownloadSpeed struct { Type string; Value int; Source string `json:",omitempty"` }
 	Monitoring struct {
-		AggregatePrefixes struct { Type string; Value []string }
-		DataLocation struct { Type string; Value string }
-		MetricAuthorization struct { Type string; Value bool }
-		PortHigher struct { Type string; Value int }
-		PortLower struct { Type string; Value int }
-		TokenExpiresIn struct { Type string; Value time.Duration }
-		TokenRefreshInterval struct { Type string; Value time.Duration }
+		AccessLogMaxEntries struct { Type string; Value int; Source string `json:",omitempty"` }
+		AccessLogRetention struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		AccountingFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		AccountingRetention struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		AggregatePrefixes struct { Type string; Value []string; Source string `json:",omitempty"` }
+		Alerting struct {
+			DisableDefaultRules struct { Type string; Value bool; Source string `json:",omitempty"` }
+			Email struct {
+				From struct { Type string; Value string; Source string `json:",omitempty"` }
+				PasswordFile struct { Type string; Value string; Source string `json:",omitempty"` }
+				SmtpServer struct { Type string; Value string; Source string `json:",omitempty"` }
+				To struct { Type string; Value []string; Source string `json:",omitempty"` }
+				Username struct { Type string; Value string; Source string `json:",omitempty"` }
+			}
+			EvaluationInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+			RepeatInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+			Rules struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+			WebhookUrls struct { Type string; Value []string; Source string `json:",omitempty"` }
+		}
+		DataLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		EnableAccessLogs struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableAccounting struct { Type string; Value bool; Source string `json:",omitempty"` }
+		MetricAuthorization struct { Type string; Value bool; Source string `json:",omitempty"` }
+		PortHigher struct { Type string; Value int; Source string `json:",omitempty"` }
+		PortLower struct { Type string; Value int; Source string `json:",omitempty"` }
+		TestFileRetention struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		TokenExpiresIn struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		TokenRefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
 	}
 	OIDC struct {
-		AuthorizationEndpoint struct { Type string; Value string }
-		ClientID struct { Type string; Value string }
-		ClientIDFile struct { Type string; Value string }
-		ClientRedirectHostname struct { Type string; Value string }
-		ClientSecretFile struct { Type string; Value string }
-		DeviceAuthEndpoint struct { Type string; Value string }
-		Issuer struct { Type string; Value string }
-		TokenEndpoint struct { Type string; Value string }
-		UserInfoEndpoint struct { Type string; Value string }
+		AuthorizationEndpoint struct { Type string; Value string; Source string `json:",omitempty"` }
+		ClientID struct { Type string; Value string; Source string `json:",omitempty"` }
+		ClientIDFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		ClientRedirectHostname struct { Type string; Value string; Source string `json:",omitempty"` }
+		ClientSecretFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		DeviceAuthEndpoint struct { Type string; Value string; Source string `json:",omitempty"` }
+		Issuer struct { Type string; Value string; Source string `json:",omitempty"` }
+		TokenEndpoint struct { Type string; Value string; Source string `json:",omitempty"` }
+		UserInfoEndpoint struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
 	Origin struct {
-		EnableCmsd struct { Type string; Value bool }
-		EnableDirListing struct { Type string; Value bool }
-		EnableFallbackRead struct { Type string; Value bool }
-		EnableIssuer struct { Type string; Value bool }
-		EnablePublicReads struct { Type string; Value bool }
-		EnableUI struct { Type string; Value bool }
-		EnableVoms struct { Type string; Value bool }
-		EnableWrite struct { Type string; Value bool }
-		ExportVolume struct { Type string; Value string }
-		Mode struct { Type string; Value string }
-		Multiuser struct { Type string; Value bool }
-		NamespacePrefix struct { Type string; Value string }
-		S3AccessKeyfile struct { Type string; Value string }
-		S3Bucket struct { Type string; Value string }
-		S3Region struct { Type string; Value string }
-		S3SecretKeyfile struct { Type string; Value string }
-		S3ServiceName struct { Type string; Value string }
-		S3ServiceUrl struct { Type string; Value string }
-		ScitokensDefaultUser struct { Type string; Value string }
-		ScitokensMapSubject struct { Type string; Value bool }
-		ScitokensNameMapFile struct { Type string; Value string }
-		ScitokensRestrictedPaths struct { Type string; Value []string }
-		ScitokensUsernameClaim struct { Type string; Value string }
-		SelfTest struct { Type string; Value bool }
-		SelfTestInterval struct { Type string; Value time.Duration }
-		Url struct { Type string; Value string }
-		XRootDPrefix struct { Type string; Value string }
+		ChecksumAlgorithms struct { Type string; Value []string; Source string `json:",omitempty"` }
+		ChecksumWorkers struct { Type string; Value int; Source string `json:",omitempty"` }
+		EnableCmsd struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableDirListing struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableFallbackRead struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableIssuer struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnablePublicReads struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableScrubber struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableUI struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableVoms struct { Type string; Value bool; Source string `json:",omitempty"` }
+		EnableWrite struct { Type string; Value bool; Source string `json:",omitempty"` }
+		ExportVolume struct { Type string; Value string; Source string `json:",omitempty"` }
+		Exports struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		HsmStageCommand struct { Type string; Value string; Source string `json:",omitempty"` }
+		HsmStageTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		Mode struct { Type string; Value string; Source string `json:",omitempty"` }
+		Multiuser struct { Type string; Value bool; Source string `json:",omitempty"` }
+		NamespacePrefix struct { Type string; Value string; Source string `json:",omitempty"` }
+		PausedExportsFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		PersistentIdentifiers struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		S3AccessKeyfile struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3Bucket struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3KMSKeyId struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3Region struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3SecretKeyfile struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3ServerSideEncryption struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3ServiceName struct { Type string; Value string; Source string `json:",omitempty"` }
+		S3ServiceUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScitokensDefaultUser struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScitokensMapSubject struct { Type string; Value bool; Source string `json:",omitempty"` }
+		ScitokensNameMapFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScitokensRestrictedPaths struct { Type string; Value []string; Source string `json:",omitempty"` }
+		ScitokensUsernameClaim struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScrubBandwidth struct { Type string; Value int; Source string `json:",omitempty"` }
+		ScrubInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		SelfTest struct { Type string; Value bool; Source string `json:",omitempty"` }
+		SelfTestInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		StorageHooks struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		UploadPolicies struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		Url struct { Type string; Value string; Source string `json:",omitempty"` }
+		XRootDPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
 	Plugin struct {
-		Token struct { Type string; Value string }
+		Token struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
 	Registry struct {
-		AdminUsers struct { Type string; Value []string }
-		CustomRegistrationFields struct { Type string; Value interface{} }
-		DbLocation struct { Type string; Value string }
-		Institutions struct { Type string; Value interface{} }
-		InstitutionsUrl struct { Type string; Value string }
-		InstitutionsUrlReloadMinutes struct { Type string; Value time.Duration }
-		RequireCacheApproval struct { Type string; Value bool }
-		RequireKeyChaining struct { Type string; Value bool }
-		RequireOriginApproval struct { Type string; Value bool }
+		AdminUsers struct { Type string; Value []string; Source string `json:",omitempty"` }
+		CustomRegistrationFields struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		DbConnectionMaxLifetime struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		DbLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		DbMaxIdleConnections struct { Type string; Value int; Source string `json:",omitempty"` }
+		DbMaxOpenConnections struct { Type string; Value int; Source string `json:",omitempty"` }
+		DbQueryTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		Institutions struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		InstitutionsUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		InstitutionsUrlReloadMinutes struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		NamespaceSnapshotLifetime struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		RequireCacheApproval struct { Type string; Value bool; Source string `json:",omitempty"` }
+		RequireKeyChaining struct { Type string; Value bool; Source string `json:",omitempty"` }
+		RequireOriginApproval struct { Type string; Value bool; Source string `json:",omitempty"` }
+		TermsOfServiceUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		TermsOfServiceVersion struct { Type string; Value string; Source string `json:",omitempty"` }
+	}
+	Secrets struct {
+		AWSEndpoint struct { Type string; Value string; Source string `json:",omitempty"` }
+		AWSRegion struct { Type string; Value string; Source string `json:",omitempty"` }
+		AWSSecretPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
+		Provider struct { Type string; Value string; Source string `json:",omitempty"` }
+		VaultAddress struct { Type string; Value string; Source string `json:",omitempty"` }
+		VaultPath struct { Type string; Value string; Source string `json:",omitempty"` }
+		VaultTokenFile struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
 	Server struct {
-		EnableUI struct { Type string; Value bool }
-		ExternalWebUrl struct { Type string; Value string }
-		Hostname struct { Type string; Value string }
-		IssuerHostname struct { Type string; Value string }
-		IssuerJwks struct { Type string; Value string }
-		IssuerPort struct { Type string; Value int }
-		IssuerUrl struct { Type string; Value string }
-		Modules struct { Type string; Value []string }
-		RegistrationRetryInterval struct { Type string; Value time.Duration }
-		SessionSecretFile struct { Type string; Value string }
-		TLSCACertificateDirectory struct { Type string; Value string }
-		TLSCACertificateFile struct { Type string; Value string }
-		TLSCAKey struct { Type string; Value string }
-		TLSCertificate struct { Type string; Value string }
-		TLSKey struct { Type string; Value string }
-		UIActivationCodeFile struct { Type string; Value string }
-		UIPasswordFile struct { Type string; Value string }
-		WebHost struct { Type string; Value string }
-		WebPort struct { Type string; Value int }
+		AcceptedTermsOfService struct { Type string; Value string; Source string `json:",omitempty"` }
+		AdvertiseHealthChecks struct { Type string; Value bool; Source string `json:",omitempty"` }
+		ClockSkewCheckInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		ClockSkewTolerance struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		EnableUI struct { Type string; Value bool; Source string `json:",omitempty"` }
+		ExternalWebUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		FailOnClockSkew struct { Type string; Value bool; Source string `json:",omitempty"` }
+		Hostname struct { Type string; Value string; Source string `json:",omitempty"` }
+		HostnameCheckInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerHostname struct { Type string; Value string; Source string `json:",omitempty"` }
+		IssuerJwks struct { Type string; Value string; Source string `json:",omitempty"` }
+		IssuerJwksMaxStaleness struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerJwksRefreshInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerKeyRotationInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerKeyRotationOverlap struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IssuerPort struct { Type string; Value int; Source string `json:",omitempty"` }
+		IssuerUrl struct { Type string; Value string; Source string `json:",omitempty"` }
+		ListenAddresses struct { Type string; Value []string; Source string `json:",omitempty"` }
+		Modules struct { Type string; Value []string; Source string `json:",omitempty"` }
+		RegistrationCheckInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		RegistrationMaxRetryInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		RegistrationRetryInterval struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		RegistrationStateFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		SessionSecretFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		TLSCACertificateDirectory struct { Type string; Value string; Source string `json:",omitempty"` }
+		TLSCACertificateFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		TLSCAKey struct { Type string; Value string; Source string `json:",omitempty"` }
+		TLSCertificate struct { Type string; Value string; Source string `json:",omitempty"` }
+		TLSKey struct { Type string; Value string; Source string `json:",omitempty"` }
+		UIActivationCodeFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		UIPasswordFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		UnixSocket struct { Type string; Value string; Source string `json:",omitempty"` }
+		UnixSocketMode struct { Type string; Value string; Source string `json:",omitempty"` }
+		WebHost struct { Type string; Value string; Source string `json:",omitempty"` }
+		WebPort struct { Type string; Value int; Source string `json:",omitempty"` }
 	}
 	Shoveler struct {
-		AMQPExchange struct { Type string; Value string }
-		AMQPTokenLocation struct { Type string; Value string }
-		Enable struct { Type string; Value bool }
-		IPMapping struct { Type string; Value interface{} }
-		MessageQueueProtocol struct { Type string; Value string }
-		OutputDestinations struct { Type string; Value []string }
-		PortHigher struct { Type string; Value int }
-		PortLower struct { Type string; Value int }
-		QueueDirectory struct { Type string; Value string }
-		StompCert struct { Type string; Value string }
-		StompCertKey struct { Type string; Value string }
-		StompPassword struct { Type string; Value string }
-		StompUsername struct { Type string; Value string }
-		Topic struct { Type string; Value string }
-		URL struct { Type string; Value string }
-		VerifyHeader struct { Type string; Value bool }
+		AMQPExchange struct { Type string; Value string; Source string `json:",omitempty"` }
+		AMQPTokenLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		Enable struct { Type string; Value bool; Source string `json:",omitempty"` }
+		IPMapping struct { Type string; Value interface{}; Source string `json:",omitempty"` }
+		MessageQueueProtocol struct { Type string; Value string; Source string `json:",omitempty"` }
+		OutputDestinations struct { Type string; Value []string; Source string `json:",omitempty"` }
+		PortHigher struct { Type string; Value int; Source string `json:",omitempty"` }
+		PortLower struct { Type string; Value int; Source string `json:",omitempty"` }
+		QueueDirectory struct { Type string; Value string; Source string `json:",omitempty"` }
+		StompCert struct { Type string; Value string; Source string `json:",omitempty"` }
+		StompCertKey struct { Type string; Value string; Source string `json:",omitempty"` }
+		StompPassword struct { Type string; Value string; Source string `json:",omitempty"` }
+		StompUsername struct { Type string; Value string; Source string `json:",omitempty"` }
+		Topic struct { Type string; Value string; Source string `json:",omitempty"` }
+		URL struct { Type string; Value string; Source string `json:",omitempty"` }
+		VerifyHeader struct { Type string; Value bool; Source string `json:",omitempty"` }
 	}
 	StagePlugin struct {
-		Hook struct { Type string; Value bool }
-		MountPrefix struct { Type string; Value string }
-		OriginPrefix struct { Type string; Value string }
-		ShadowOriginPrefix struct { Type string; Value string }
+		Hook struct { Type string; Value bool; Source string `json:",omitempty"` }
+		MountPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
+		OriginPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
+		ShadowOriginPrefix struct { Type string; Value string; Source string `json:",omitempty"` }
 	}
-	TLSSkipVerify struct { Type string; Value bool }
+	StrictConfigValidation struct { Type string; Value bool; Source string `json:",omitempty"` }
+	TLSSkipVerify struct { Type string; Value bool; Source string `json:",omitempty"` }
 	Transport struct {
-		DialerKeepAlive struct { Type string; Value time.Duration }
-		DialerTimeout struct { Type string; Value time.Duration }
-		ExpectContinueTimeout struct { Type string; Value time.Duration }
-		IdleConnTimeout struct { Type string; Value time.Duration }
-		MaxIdleConns struct { Type string; Value int }
-		ResponseHeaderTimeout struct { Type string; Value time.Duration }
-		TLSHandshakeTimeout struct { Type string; Value time.Duration }
+		DialerAttemptDelay struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		DialerKeepAlive struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		DialerTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		ExpectContinueTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		IdleConnTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		MaxIdleConns struct { Type string; Value int; Source string `json:",omitempty"` }
+		ResponseHeaderTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
+		TLSHandshakeTimeout struct { Type string; Value time.Duration; Source string `json:",omitempty"` }
 	}
 	Xrootd struct {
-		Authfile struct { Type string; Value string }
-		DetailedMonitoringHost struct { Type string; Value string }
-		LocalMonitoringHost struct { Type string; Value string }
-		MacaroonsKeyFile struct { Type string; Value string }
-		ManagerHost struct { Type string; Value string }
-		Mount struct { Type string; Value string }
-		Port struct { Type string; Value int }
-		RobotsTxtFile struct { Type string; Value string }
-		RunLocation struct { Type string; Value string }
-		ScitokensConfig struct { Type string; Value string }
-		Sitename struct { Type string; Value string }
-		SummaryMonitoringHost struct { Type string; Value string }
+		Authfile struct { Type string; Value string; Source string `json:",omitempty"` }
+		DetailedMonitoringHost struct { Type string; Value string; Source string `json:",omitempty"` }
+		LocalMonitoringHost struct { Type string; Value string; Source string `json:",omitempty"` }
+		MacaroonsKeyFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		ManagerHost struct { Type string; Value string; Source string `json:",omitempty"` }
+		Mount struct { Type string; Value string; Source string `json:",omitempty"` }
+		Port struct { Type string; Value int; Source string `json:",omitempty"` }
+		RobotsTxtFile struct { Type string; Value string; Source string `json:",omitempty"` }
+		RunLocation struct { Type string; Value string; Source string `json:",omitempty"` }
+		ScitokensConfig struct { Type string; Value string; Source string `json:",omitempty"` }
+		Sitename struct { Type string; Value string; Source string `json:",omitempty"` }
+		SummaryMonitoringHost struct { Type string; Value string; Source string `json:",omitempty"` }
+	}
+}
+
+
+// Validate the configuration against the constraints declared in docs/parameters.yaml
+func (cfg *config) Validate() error {
+	problems, err := cfg.ValidationProblems(validator.New())
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	descriptions := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		descriptions = append(descriptions, problem.String())
+	}
+	return errors.New("Invalid configuration: " + strings.Join(descriptions, "; "))
+}
+
+// Check the configuration against the constraints declared in docs/parameters.yaml
+// with the validator v, returning each constraint it violates
+func (cfg *config) ValidationProblems(v *validator.Validate) ([]ValidationProblem, error) {
+	err := v.Struct(cfg)
+	validationErrs := validator.ValidationErrors{}
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+	problems := make([]ValidationProblem, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		problem := ValidationProblem{Param: strings.TrimPrefix(fieldErr.Namespace(), "config.")}
+		switch fieldErr.Tag() {
+		case "required":
+			problem.Message = "must be set"
+		case "oneof":
+			problem.Message = fmt.Sprintf("is %q but must be one of: %s", fieldErr.Value(), fieldErr.Param())
+		default:
+			problem.Message = fmt.Sprintf("has the invalid value %q", fieldErr.Value())
+		}
+		problems = append(problems, problem)
 	}
+	return problems, nil
 }
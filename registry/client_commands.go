@@ -20,10 +20,11 @@ package registry
 
 import (
 	"bufio"
-	"crypto/ecdsa"
+	"crypto"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"time"
 
@@ -31,7 +32,9 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/director"
+	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/token_scopes"
 	"github.com/pelicanplatform/pelican/utils"
 )
@@ -108,7 +111,11 @@ func NamespaceRegister(privateKey jwk.Key, namespaceRegistryEndpoint string, acc
 	if err != nil {
 		return errors.Wrap(err, "Failed to assign key ID to public key")
 	}
-	if err = publicKey.Set("alg", "ES256"); err != nil {
+	alg, err := config.KeySignatureAlgorithm(privateKey)
+	if err != nil {
+		return errors.Wrap(err, "Failed to determine the signature algorithm of the private key")
+	}
+	if err = publicKey.Set("alg", alg); err != nil {
 		return errors.Wrap(err, "Failed to assign signature algorithm to public key")
 	}
 	keySet := jwk.NewSet()
@@ -155,11 +162,15 @@ func NamespaceRegister(privateKey jwk.Key, namespaceRegistryEndpoint string, acc
 	clientPayload := clientNonce + respData.ServerNonce
 
 	// Sign the payload
-	privateKeyRaw := &ecdsa.PrivateKey{}
-	if err = privateKey.Raw(privateKeyRaw); err != nil {
-		return errors.Wrap(err, "Failed to get an ECDSA private key")
+	var privateKeyRaw interface{}
+	if err = privateKey.Raw(&privateKeyRaw); err != nil {
+		return errors.Wrap(err, "Failed to get the raw private key")
+	}
+	signer, ok := privateKeyRaw.(crypto.Signer)
+	if !ok {
+		return errors.Errorf("Unsupported private key type %T for namespace registration", privateKeyRaw)
 	}
-	signature, err := signPayload([]byte(clientPayload), privateKeyRaw)
+	signature, err := signPayload([]byte(clientPayload), signer)
 	if err != nil {
 		return errors.Wrap(err, "Failed to sign payload")
 	}
@@ -176,6 +187,8 @@ func NamespaceRegister(privateKey jwk.Key, namespaceRegistryEndpoint string, acc
 		"prefix":            prefix,
 		"access_token":      accessToken,
 		"identity_required": "false",
+		// Registries that require it refuse registrations not accepting their terms of service
+		"accepted_terms_version": param.Server_AcceptedTermsOfService.GetString(),
 	}
 
 	// Send the second POST request
@@ -223,6 +236,109 @@ func NamespaceGet(endpoint string) error {
 	return nil
 }
 
+// The result of checking a namespace prefix against the registry
+type NamespaceCheckResult struct {
+	Prefix     string `json:"prefix"`
+	Registered bool   `json:"registered"`
+	KeyMatch   bool   `json:"key_match"`
+	Approved   bool   `json:"approved"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Fetch the namespaces from the registry list endpoint (/api/v1.0/registry),
+// filtered by the query parameters (status, prefix, institution, server_type).
+// If accessToken is non-empty, it's passed to the registry as a bearer token.
+func NamespaceFetch(endpoint string, query url.Values, accessToken string) ([]*Namespace, error) {
+	if len(query) > 0 {
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	var headers map[string]string
+	if accessToken != "" {
+		headers = map[string]string{"Authorization": "Bearer " + accessToken}
+	}
+	respData, err := utils.MakeRequest(endpoint, "GET", nil, headers)
+	var respErr clientResponseData
+	if err != nil {
+		if jsonErr := json.Unmarshal(respData, &respErr); jsonErr == nil { // Error creating json
+			return nil, errors.Wrapf(err, "Failed to make request: %v", respErr.Error)
+		}
+		return nil, errors.Wrap(err, "Failed to make request")
+	}
+	namespaces := []*Namespace{}
+	if err := json.Unmarshal(respData, &namespaces); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the namespaces returned by the registry")
+	}
+	return namespaces, nil
+}
+
+// Search the namespaces at the registry search endpoint (/api/v1.0/registry/search)
+// with the query parameters (q, match, status, server_type, page, page_size)
+func NamespaceSearch(endpoint string, query url.Values) (*NamespaceSearchResult, error) {
+	if len(query) > 0 {
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	respData, err := utils.MakeRequest(endpoint, "GET", nil, nil)
+	var respErr clientResponseData
+	if err != nil {
+		if jsonErr := json.Unmarshal(respData, &respErr); jsonErr == nil { // Error creating json
+			return nil, errors.Wrapf(err, "Failed to make request: %v", respErr.Error)
+		}
+		return nil, errors.Wrap(err, "Failed to make request")
+	}
+	result := &NamespaceSearchResult{}
+	if err := json.Unmarshal(respData, result); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the search result returned by the registry")
+	}
+	return result, nil
+}
+
+// Check if the prefix is registered at the registry endpoint (/api/v1.0/registry),
+// whether the registered public key matches the public key of privateKey, and whether
+// the registration is approved by the federation administrator.
+func NamespaceCheck(endpoint string, privateKey jwk.Key, prefix string) (*NamespaceCheckResult, error) {
+	result := &NamespaceCheckResult{Prefix: prefix}
+	publicKey, err := privateKey.PublicKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate public key from the private key")
+	}
+	pubkeyStr, err := json.Marshal(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal the public key")
+	}
+
+	existsData := map[string]interface{}{"prefix": prefix, "pubkey": string(pubkeyStr)}
+	respData, err := utils.MakeRequest(endpoint+"/checkNamespaceExists", "POST", existsData, nil)
+	if err != nil {
+		var respErr clientResponseData
+		if jsonErr := json.Unmarshal(respData, &respErr); jsonErr == nil && respErr.Error != "" {
+			return nil, errors.Wrapf(err, "Failed to check if the namespace exists: %v", respErr.Error)
+		}
+		return nil, errors.Wrap(err, "Failed to check if the namespace exists")
+	}
+	existsRes := checkNamespaceExistsRes{}
+	if err := json.Unmarshal(respData, &existsRes); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the response from the registry")
+	}
+	result.Registered = existsRes.PrefixExists
+	result.KeyMatch = existsRes.KeyMatch
+	result.Message = existsRes.Message
+	if !result.Registered {
+		return result, nil
+	}
+
+	statusData := map[string]interface{}{"prefix": prefix}
+	respData, err = utils.MakeRequest(endpoint+"/checkNamespaceStatus", "POST", statusData, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check the namespace approval status")
+	}
+	statusRes := checkStatusRes{}
+	if err := json.Unmarshal(respData, &statusRes); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the response from the registry")
+	}
+	result.Approved = statusRes.Approved
+	return result, nil
+}
+
 func NamespaceDelete(endpoint string, prefix string) error {
 	// First we create a token for the registry to check that the deletion
 	// request is valid
@@ -276,3 +392,46 @@ func NamespaceDelete(endpoint string, prefix string) error {
 	fmt.Println(string(respData))
 	return nil
 }
+
+// Replace the public keys of the namespace registered at prefix with keySet, proving the
+// caller may do so with signingKey, one of the namespace's registered keys. endpoint is
+// the registry's updateNamespaceKey endpoint.
+func NamespaceUpdateKey(endpoint string, prefix string, signingKey jwk.Key, keySet jwk.Set) error {
+	issuerURL, err := director.GetNSIssuerURL(prefix)
+	if err != nil {
+		return errors.Wrap(err, "Failed to determine prefix's issuer URL for creating the key update token")
+	}
+	pubkey, err := json.Marshal(keySet)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal the new public keys")
+	}
+	updateTokenCfg := utils.TokenConfig{
+		TokenProfile: utils.WLCG,
+		Lifetime:     time.Minute,
+		Issuer:       issuerURL,
+		Audience:     []string{"registry"},
+		Version:      "1.0",
+		Subject:      "origin",
+		Claims: map[string]string{
+			"scope":            token_scopes.Pelican_NamespaceKeyUpdate.String(),
+			namespaceKeysClaim: string(pubkey),
+		},
+	}
+	tok, err := updateTokenCfg.CreateTokenWithKey(signingKey)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create the namespace key update token")
+	}
+
+	authHeader := map[string]string{
+		"Authorization": "Bearer " + tok,
+	}
+	respData, err := utils.MakeRequest(endpoint, "POST", map[string]interface{}{"prefix": prefix}, authHeader)
+	var respErr clientResponseData
+	if err != nil {
+		if unmarshalErr := json.Unmarshal(respData, &respErr); unmarshalErr == nil && respErr.Error != "" {
+			return errors.Wrapf(err, "Failed to make request: %v", respErr.Error)
+		}
+		return errors.Wrap(err, "Failed to make request")
+	}
+	return nil
+}
@@ -21,13 +21,18 @@ package registry
 import (
 	"context"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/test_utils"
+	"github.com/pelicanplatform/pelican/token_scopes"
+	"github.com/pelicanplatform/pelican/utils"
 	"github.com/spf13/viper"
 
 	"github.com/stretchr/testify/assert"
@@ -102,6 +107,54 @@ func TestServeNamespaceRegistry(t *testing.T) {
 		assert.Contains(t, stdoutCapture, `"prefix":"/foo/bar"`)
 	})
 
+	t.Run("Test namespace fetch with filters", func(t *testing.T) {
+		tokenCfg := utils.TokenConfig{Issuer: param.Server_ExternalWebUrl.GetString(), Lifetime: time.Minute, Subject: "admin", TokenProfile: utils.None}
+		tokenCfg.AddScopes([]token_scopes.TokenScope{token_scopes.WebUi_Access})
+		token, err := tokenCfg.CreateToken()
+		require.NoError(t, err)
+
+		namespaces, err := NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"prefix": []string{"/foo"}}, token)
+		require.NoError(t, err)
+		require.Len(t, namespaces, 1)
+		assert.Equal(t, "/foo/bar", namespaces[0].Prefix)
+
+		namespaces, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"prefix": []string{"/fo"}}, token)
+		require.NoError(t, err)
+		assert.Len(t, namespaces, 0)
+
+		namespaces, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"status": []string{"Denied"}}, token)
+		require.NoError(t, err)
+		assert.Len(t, namespaces, 0)
+
+		_, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"status": []string{"invalid"}}, token)
+		assert.Error(t, err)
+
+		// Without a token, the pending registration isn't listed
+		namespaces, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"prefix": []string{"/foo"}}, "")
+		require.NoError(t, err)
+		assert.Len(t, namespaces, 0)
+		_, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"status": []string{"Pending"}}, "")
+		assert.Error(t, err)
+
+		// A token the registry didn't issue is rejected
+		tokenCfg.Issuer = "https://other-issuer.example.com"
+		otherToken, err := tokenCfg.CreateToken()
+		require.NoError(t, err)
+		_, err = NamespaceFetch(svr.URL+"/api/v1.0/registry", url.Values{"prefix": []string{"/foo"}}, otherToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test namespace check", func(t *testing.T) {
+		result, err := NamespaceCheck(svr.URL+"/api/v1.0/registry", privKey, "/foo/bar")
+		require.NoError(t, err)
+		assert.True(t, result.Registered)
+		assert.True(t, result.KeyMatch)
+
+		result, err = NamespaceCheck(svr.URL+"/api/v1.0/registry", privKey, "/not/registered")
+		require.NoError(t, err)
+		assert.False(t, result.Registered)
+	})
+
 	t.Run("Test namespace delete", func(t *testing.T) {
 		//Test functionality of namespace delete
 		err = NamespaceDelete(svr.URL+"/api/v1.0/registry/foo/bar", "/foo/bar")
@@ -0,0 +1,203 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A managed connection pool to the registry database.  Queries and transactions
+	// started through the pool are bounded by the configured query timeout
+	// (Registry.DbQueryTimeout) and are recorded in the registry's database metrics.
+	//
+	// The pool wraps database/sql, so it is agnostic to the database backend.
+	dbPool struct {
+		*sql.DB
+		queryTimeout   time.Duration
+		statsCollector prometheus.Collector
+	}
+
+	// Rows from a query through the pool; closing the rows releases the query's context
+	dbRows struct {
+		*sql.Rows
+		cancel context.CancelFunc
+	}
+
+	// A single row from a query through the pool; scanning the row releases the query's context
+	dbRow struct {
+		*sql.Row
+		ctx    context.Context
+		cancel context.CancelFunc
+		start  time.Time
+	}
+
+	// A transaction started through the pool; committing or rolling back the transaction
+	// releases its context.  A transaction that runs past the query timeout is rolled back.
+	dbTx struct {
+		*sql.Tx
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+)
+
+// Wrap an opened database handle in a pool using the query timeout from the configuration
+func newDBPool(sqlDB *sql.DB) *dbPool {
+	return &dbPool{
+		DB:           sqlDB,
+		queryTimeout: param.Registry_DbQueryTimeout.GetDuration(),
+	}
+}
+
+// Apply the configured connection limits to the pool and export the pool's statistics
+// (open, in-use, and idle connections, wait counts, etc.) to Prometheus
+func (pool *dbPool) configure() error {
+	maxOpen := param.Registry_DbMaxOpenConnections.GetInt()
+	maxIdle := param.Registry_DbMaxIdleConnections.GetInt()
+	maxLifetime := param.Registry_DbConnectionMaxLifetime.GetDuration()
+	if maxOpen < 0 || maxIdle < 0 || maxLifetime < 0 || pool.queryTimeout < 0 {
+		return errors.New("Registry database pool limits (Registry.DbMaxOpenConnections, Registry.DbMaxIdleConnections, " +
+			"Registry.DbConnectionMaxLifetime, Registry.DbQueryTimeout) can't be negative")
+	}
+	pool.SetMaxOpenConns(maxOpen)
+	pool.SetMaxIdleConns(maxIdle)
+	pool.SetConnMaxLifetime(maxLifetime)
+	log.Debugf("Registry database pool configured with %d max open connections, %d max idle connections, %s max connection lifetime, and %s query timeout",
+		maxOpen, maxIdle, maxLifetime.String(), pool.queryTimeout.String())
+
+	// The pool is still usable without its statistics, e.g., if another pool was
+	// opened in this process without being closed
+	collector := collectors.NewDBStatsCollector(pool.DB, "registry")
+	if err := prometheus.Register(collector); err != nil {
+		log.Warningln("Failed to register the registry database pool metrics:", err)
+	} else {
+		pool.statsCollector = collector
+	}
+	return nil
+}
+
+// Close the pool and stop exporting its statistics
+func (pool *dbPool) Close() error {
+	if pool.statsCollector != nil {
+		prometheus.Unregister(pool.statsCollector)
+		pool.statsCollector = nil
+	}
+	return pool.DB.Close()
+}
+
+func (pool *dbPool) queryContext() (context.Context, context.CancelFunc) {
+	if pool.queryTimeout > 0 {
+		return context.WithTimeout(context.Background(), pool.queryTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Record the latency and outcome of a database operation
+func observeDBOperation(ctx context.Context, operation string, start time.Time, err error) {
+	metrics.PelicanRegistryDBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	// An empty result or finishing an already-finished transaction isn't a database failure
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, sql.ErrTxDone) {
+		return
+	}
+	// Drivers don't always wrap the context's error when a query is interrupted
+	timeout := errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+	metrics.PelicanRegistryDBQueryErrors.WithLabelValues(operation, strconv.FormatBool(timeout)).Inc()
+	if timeout {
+		log.Warningf("Registry database %s timed out after %s", operation, time.Since(start).String())
+	}
+}
+
+func (pool *dbPool) Exec(query string, args ...any) (sql.Result, error) {
+	ctx, cancel := pool.queryContext()
+	defer cancel()
+	start := time.Now()
+	result, err := pool.DB.ExecContext(ctx, query, args...)
+	observeDBOperation(ctx, "exec", start, err)
+	return result, err
+}
+
+func (pool *dbPool) Query(query string, args ...any) (*dbRows, error) {
+	ctx, cancel := pool.queryContext()
+	start := time.Now()
+	rows, err := pool.DB.QueryContext(ctx, query, args...)
+	observeDBOperation(ctx, "query", start, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &dbRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (pool *dbPool) QueryRow(query string, args ...any) *dbRow {
+	ctx, cancel := pool.queryContext()
+	start := time.Now()
+	return &dbRow{Row: pool.DB.QueryRowContext(ctx, query, args...), ctx: ctx, cancel: cancel, start: start}
+}
+
+func (pool *dbPool) Begin() (*dbTx, error) {
+	ctx, cancel := pool.queryContext()
+	start := time.Now()
+	tx, err := pool.DB.BeginTx(ctx, nil)
+	observeDBOperation(ctx, "begin", start, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &dbTx{Tx: tx, ctx: ctx, cancel: cancel}, nil
+}
+
+func (rows *dbRows) Close() error {
+	err := rows.Rows.Close()
+	rows.cancel()
+	return err
+}
+
+func (row *dbRow) Scan(dest ...any) error {
+	defer row.cancel()
+	err := row.Row.Scan(dest...)
+	observeDBOperation(row.ctx, "query", row.start, err)
+	return err
+}
+
+func (tx *dbTx) Commit() error {
+	defer tx.cancel()
+	start := time.Now()
+	err := tx.Tx.Commit()
+	observeDBOperation(tx.ctx, "commit", start, err)
+	return err
+}
+
+func (tx *dbTx) Rollback() error {
+	defer tx.cancel()
+	start := time.Now()
+	err := tx.Tx.Rollback()
+	observeDBOperation(tx.ctx, "rollback", start, err)
+	return err
+}
@@ -0,0 +1,93 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package registry
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+func TestDBPool(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	// In-memory databases are per-connection
+	sqlDB.SetMaxOpenConns(1)
+	pool := newDBPool(sqlDB)
+	pool.queryTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { assert.NoError(t, pool.Close()) })
+
+	_, err = pool.Exec(`CREATE TABLE test (id INTEGER PRIMARY KEY, value TEXT)`)
+	require.NoError(t, err)
+
+	t.Run("transaction-and-query", func(t *testing.T) {
+		tx, err := pool.Begin()
+		require.NoError(t, err)
+		_, err = tx.Exec(`INSERT INTO test (value) VALUES (?)`, "foo")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		// Rolling back a committed transaction is a no-op for callers
+		assert.ErrorIs(t, tx.Rollback(), sql.ErrTxDone)
+
+		rows, err := pool.Query(`SELECT value FROM test`)
+		require.NoError(t, err)
+		values := []string{}
+		for rows.Next() {
+			var value string
+			require.NoError(t, rows.Scan(&value))
+			values = append(values, value)
+		}
+		require.NoError(t, rows.Close())
+		assert.Equal(t, []string{"foo"}, values)
+	})
+
+	t.Run("no-rows-is-not-an-error", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("query", "false"))
+		var value string
+		err := pool.QueryRow(`SELECT value FROM test WHERE id = ?`, 100).Scan(&value)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.Equal(t, before, testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("query", "false")))
+
+		before = testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("exec", "false"))
+		_, err = pool.Exec(`SELECT * FROM nonexistent`)
+		assert.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("exec", "false")))
+	})
+
+	t.Run("query-timeout", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("query", "true"))
+		// A query that never finishes on its own
+		var count int
+		err := pool.QueryRow(`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c`).Scan(&count)
+		require.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.PelicanRegistryDBQueryErrors.WithLabelValues("query", "true")))
+
+		// The connection is usable after the timeout
+		err = pool.QueryRow(`SELECT count(*) FROM test`).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
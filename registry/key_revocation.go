@@ -0,0 +1,259 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file implements the revocation of compromised namespace keys.  The owner
+// of a namespace or a registry admin marks a key of the namespace as revoked with
+// a reason; the registry then leaves the key out of every key set it serves or
+// checks against, and publishes the revocations as a feed that the director and
+// caches poll so they stop trusting the key before their cached key sets expire.
+//
+
+package registry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// The revocation of a namespace key
+type KeyRevocation struct {
+	ID          int       `json:"id"`
+	NamespaceID int       `json:"namespace_id"`
+	Prefix      string    `json:"prefix"`
+	KeyID       string    `json:"key_id"`
+	Reason      string    `json:"reason"`
+	RevokedBy   string    `json:"revoked_by"`
+	RevokedAt   time.Time `json:"revoked_at"`
+}
+
+type revokeKeyReq struct {
+	KeyID  string `json:"key_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+var errKeyRevoked = errors.New("the key is already revoked")
+
+func createKeyRevocationTable() {
+	query := `
+    CREATE TABLE IF NOT EXISTS key_revocation (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        namespace_id INTEGER NOT NULL,
+        prefix TEXT NOT NULL,
+        key_id TEXT NOT NULL,
+        reason TEXT NOT NULL,
+        revoked_by TEXT NOT NULL,
+        revoked_at INTEGER NOT NULL,
+        UNIQUE (prefix, key_id)
+    );`
+
+	if _, err := db.Exec(query); err != nil {
+		log.Fatalf("Failed to create key revocation table: %v", err)
+	}
+}
+
+const keyRevocationColumns = `id, namespace_id, prefix, key_id, reason, revoked_by, revoked_at`
+
+func scanKeyRevocation(row rowScanner) (*KeyRevocation, error) {
+	revocation := &KeyRevocation{}
+	var revokedAt int64
+	err := row.Scan(&revocation.ID, &revocation.NamespaceID, &revocation.Prefix, &revocation.KeyID,
+		&revocation.Reason, &revocation.RevokedBy, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	revocation.RevokedAt = time.Unix(revokedAt, 0)
+	return revocation, nil
+}
+
+// Record the revocation of a key, failing with errKeyRevoked if the key of the
+// namespace is already revoked
+func addKeyRevocation(revocation *KeyRevocation) error {
+	revocation.RevokedAt = time.Now()
+	query := `INSERT INTO key_revocation (namespace_id, prefix, key_id, reason, revoked_by, revoked_at) VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT (prefix, key_id) DO NOTHING`
+	result, err := db.Exec(query, revocation.NamespaceID, revocation.Prefix, revocation.KeyID, revocation.Reason,
+		revocation.RevokedBy, revocation.RevokedAt.Unix())
+	if err != nil {
+		return errors.Wrap(err, "Failed to insert the key revocation")
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errKeyRevoked
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get the id of the key revocation")
+	}
+	revocation.ID = int(id)
+	return nil
+}
+
+func queryKeyRevocations(query string, args ...any) ([]*KeyRevocation, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revocations := make([]*KeyRevocation, 0)
+	for rows.Next() {
+		revocation, err := scanKeyRevocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		revocations = append(revocations, revocation)
+	}
+	return revocations, rows.Err()
+}
+
+// Get the revocations recorded after the one with the given id, oldest first
+func getKeyRevocations(afterId int) ([]*KeyRevocation, error) {
+	query := `SELECT ` + keyRevocationColumns + ` FROM key_revocation WHERE id > ? ORDER BY id ASC`
+	return queryKeyRevocations(query, afterId)
+}
+
+// Get the revocations of the keys of a namespace, oldest first
+func getNamespaceKeyRevocations(prefix string) ([]*KeyRevocation, error) {
+	query := `SELECT ` + keyRevocationColumns + ` FROM key_revocation WHERE prefix = ? ORDER BY id ASC`
+	return queryKeyRevocations(query, prefix)
+}
+
+// Remove the revoked keys of the namespace from its key set
+func excludeRevokedKeys(prefix string, keySet jwk.Set) (jwk.Set, error) {
+	revocations, err := getNamespaceKeyRevocations(prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the revoked keys of the namespace")
+	}
+	for _, revocation := range revocations {
+		if key, ok := keySet.LookupKeyID(revocation.KeyID); ok {
+			if err = keySet.RemoveKey(key); err != nil {
+				return nil, errors.Wrapf(err, "failed to remove the revoked key %s", revocation.KeyID)
+			}
+		}
+	}
+	return keySet, nil
+}
+
+// Revoke a key of a namespace, such as one that was compromised. Only the owner of
+// the namespace or an admin can revoke its keys, and revoked keys are excluded from
+// the namespace's key set for good.
+//
+// POST /namespaces/:id/revocations
+func revokeNamespaceKey(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok || !checkNamespacePermission(ctx, id, user, "revoke its keys") {
+		return
+	}
+
+	reqData := revokeKeyReq{}
+	if err := ctx.ShouldBindJSON(&reqData); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ns, err := getNamespaceById(id)
+	if err != nil {
+		log.Error("Error getting namespace: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespace")
+		return
+	}
+	// Look the key up in the stored key set, which still has the keys revoked before
+	keySet, err := jwk.ParseString(ns.Pubkey)
+	if err != nil {
+		log.Errorf("Failed to parse the public keys of namespace %s: %v", ns.Prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to parse the namespace's public keys")
+		return
+	}
+	if _, ok := keySet.LookupKeyID(reqData.KeyID); !ok {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The namespace has no key with the ID "+reqData.KeyID)
+		return
+	}
+
+	revocation := &KeyRevocation{
+		NamespaceID: id,
+		Prefix:      ns.Prefix,
+		KeyID:       reqData.KeyID,
+		Reason:      reqData.Reason,
+		RevokedBy:   user,
+	}
+	if err = addKeyRevocation(revocation); errors.Is(err, errKeyRevoked) {
+		web_ui.WriteProblem(ctx, http.StatusConflict, common.ErrCodeConflict, "The key is already revoked")
+		return
+	} else if err != nil {
+		log.Errorf("Failed to revoke key %s of namespace %s: %v", reqData.KeyID, ns.Prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to revoke the key")
+		return
+	}
+	log.Warningf("User %s revoked key %s of namespace %s: %s", user, revocation.KeyID, ns.Prefix, revocation.Reason)
+	ctx.JSON(http.StatusCreated, revocation)
+}
+
+// List the revoked keys of a namespace
+//
+// GET /namespaces/:id/revocations
+func listNamespaceKeyRevocations(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok || !checkNamespacePermission(ctx, id, user, "list its revoked keys") {
+		return
+	}
+	ns, err := getNamespaceById(id)
+	if err != nil {
+		log.Error("Error getting namespace: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespace")
+		return
+	}
+	revocations, err := getNamespaceKeyRevocations(ns.Prefix)
+	if err != nil {
+		log.Error("Error getting the key revocations: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting the key revocations")
+		return
+	}
+	ctx.JSON(http.StatusOK, revocations)
+}
+
+// GET /api/v1.0/registry/revocations?after=<id>
+//
+// Respond with the feed of key revocations, oldest first. Consumers polling the feed
+// pass the largest id they have seen as "after" to only get the new revocations.
+func keyRevocationFeedHandler(ctx *gin.Context) {
+	afterId := 0
+	if after := ctx.Query("after"); after != "" {
+		var err error
+		if afterId, err = strconv.Atoi(after); err != nil || afterId < 0 {
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid after parameter; it must be a non-negative integer")
+			return
+		}
+	}
+	revocations, err := getKeyRevocations(afterId)
+	if err != nil {
+		log.Errorln("Failed to get the key revocations:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the key revocations")
+		return
+	}
+	ctx.JSON(http.StatusOK, revocations)
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRevocation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	keySet := jwk.NewSet()
+	keyIds := []string{}
+	for i := 0; i < 2; i++ {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		pubKey, err := jwk.FromRaw(&privKey.PublicKey)
+		require.NoError(t, err)
+		require.NoError(t, jwk.AssignKeyID(pubKey))
+		require.NoError(t, keySet.AddKey(pubKey))
+		keyIds = append(keyIds, pubKey.KeyID())
+	}
+	keySetBytes, err := json.Marshal(keySet)
+	require.NoError(t, err)
+
+	require.NoError(t, insertMockDBData([]Namespace{
+		mockNamespace("/revoke", string(keySetBytes), "", AdminMetadata{UserID: "alice", Status: Approved}),
+	}))
+	id, err := getLastNamespaceId()
+	require.NoError(t, err)
+	nsPath := fmt.Sprintf("/namespaces/%d/revocations", id)
+
+	router := gin.Default()
+	asUser := func(ctx *gin.Context) {
+		ctx.Set("User", ctx.GetHeader("X-Test-User"))
+	}
+	router.GET("/namespaces/:id/revocations", asUser, listNamespaceKeyRevocations)
+	router.POST("/namespaces/:id/revocations", asUser, revokeNamespaceKey)
+	router.GET("/api/v1.0/registry/*wildcard", wildcardHandler)
+
+	doRequest := func(method, path, user string, body any) *httptest.ResponseRecorder {
+		var reqBody []byte
+		if body != nil {
+			reqBody, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, path, bytes.NewReader(reqBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	getFeed := func(after int) []KeyRevocation {
+		w := doRequest("GET", fmt.Sprintf("/api/v1.0/registry/revocations?after=%d", after), "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		revocations := []KeyRevocation{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revocations))
+		return revocations
+	}
+
+	t.Run("revoke", func(t *testing.T) {
+		w := doRequest("POST", nsPath, "mallory", revokeKeyReq{KeyID: keyIds[0], Reason: "compromised"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest("POST", nsPath, "alice", revokeKeyReq{KeyID: keyIds[0]})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doRequest("POST", nsPath, "alice", revokeKeyReq{KeyID: "unknown", Reason: "compromised"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = doRequest("POST", nsPath, "alice", revokeKeyReq{KeyID: keyIds[0], Reason: "compromised"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = doRequest("POST", nsPath, "admin", revokeKeyReq{KeyID: keyIds[0], Reason: "compromised"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = doRequest("GET", nsPath, "alice", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		revocations := []KeyRevocation{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revocations))
+		require.Len(t, revocations, 1)
+		assert.Equal(t, "alice", revocations[0].RevokedBy)
+		assert.Equal(t, "compromised", revocations[0].Reason)
+		assert.False(t, revocations[0].RevokedAt.IsZero())
+	})
+
+	t.Run("excluded-from-jwks", func(t *testing.T) {
+		w := doRequest("GET", "/api/v1.0/registry/revoke/.well-known/issuer.jwks", "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		served, err := jwk.Parse(w.Body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, 1, served.Len())
+		_, found := served.LookupKeyID(keyIds[0])
+		assert.False(t, found)
+		_, found = served.LookupKeyID(keyIds[1])
+		assert.True(t, found)
+
+		byId, err := getNamespaceJwksById(id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, byId.Len())
+	})
+
+	t.Run("feed", func(t *testing.T) {
+		revocations := getFeed(0)
+		require.Len(t, revocations, 1)
+		assert.Equal(t, "/revoke", revocations[0].Prefix)
+		assert.Equal(t, keyIds[0], revocations[0].KeyID)
+		assert.Empty(t, getFeed(revocations[0].ID))
+
+		w := doRequest("GET", "/api/v1.0/registry/revocations?after=abc", "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
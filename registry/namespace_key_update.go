@@ -0,0 +1,163 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+//
+// This file implements the replacement of a namespace's public keys by the origin
+// or cache serving it, such as when the server rotates its issuer key.  The server
+// proves it holds one of the namespace's registered keys by signing a short-lived
+// token with it, and the token carries the namespace's new key set, which must
+// still hold the signing key so the server can't lock itself out.
+//
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/token_scopes"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type updateNamespaceKeyReq struct {
+	Prefix string `json:"prefix" binding:"required"`
+}
+
+// The claim of a key update token holding the namespace's new key set, as a JWKS
+const namespaceKeysClaim = "pubkey"
+
+// Replace the public keys of the namespace with pubkey, a JWKS
+func updateNamespacePubkey(prefix string, pubkey string) error {
+	result, err := db.Exec(`UPDATE namespace SET pubkey = ? WHERE prefix = ?`, pubkey, prefix)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update the namespace's public keys")
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errors.New("prefix not found in database")
+	}
+	return nil
+}
+
+// Get the key set a key update token replaces the namespace's keys with, leaving out
+// any private parts of the keys
+func parseNamespaceKeysClaim(tok jwt.Token) (jwk.Set, error) {
+	claim, ok := tok.Get(namespaceKeysClaim)
+	if !ok {
+		return nil, errors.Errorf("the token has no %s claim", namespaceKeysClaim)
+	}
+	claimStr, ok := claim.(string)
+	if !ok {
+		return nil, errors.Errorf("the %s claim of the token is not string-valued", namespaceKeysClaim)
+	}
+	keySet, err := jwk.ParseString(claimStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "the new public keys are not a valid JWKS")
+	}
+	if keySet.Len() == 0 {
+		return nil, errors.New("the new JWKS has no keys")
+	}
+	for idx := 0; idx < keySet.Len(); idx++ {
+		if key, _ := keySet.Key(idx); key.KeyID() == "" {
+			return nil, errors.Errorf("key %d of the new JWKS has no key ID", idx)
+		}
+	}
+	return jwk.PublicSetOf(keySet)
+}
+
+// Replace the public keys of a namespace. The request carries a bearer token signed by
+// one of the namespace's registered, unrevoked keys, with the new JWKS in its "pubkey"
+// claim. The new keys must include the one that signed the token.
+//
+// POST /api/v1.0/registry/updateNamespaceKey
+func updateNamespaceKeyHandler(ctx *gin.Context) {
+	reqData := updateNamespaceKeyReq{}
+	if err := ctx.ShouldBindJSON(&reqData); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	prefix := reqData.Prefix
+	tokenStr := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if tokenStr == "" {
+		web_ui.WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeMissingToken, "A bearer token signed by a key of the namespace is required")
+		return
+	}
+
+	exists, err := namespaceExistsByPrefix(prefix)
+	if err != nil {
+		log.Errorf("Failed to check if namespace %s exists: %v", prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to check if the namespace exists")
+		return
+	}
+	if !exists {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "The namespace "+prefix+" is not registered")
+		return
+	}
+	// The revoked keys are left out, so a revoked key can't replace the namespace's keys
+	namespaceJwks, _, err := getNamespaceJwksByPrefix(prefix)
+	if err != nil {
+		log.Errorf("Failed to get the public keys of namespace %s: %v", prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the namespace's public keys")
+		return
+	}
+
+	tok, err := jwt.Parse([]byte(tokenStr), jwt.WithKeySet(namespaceJwks),
+		jwt.WithValidator(token_scopes.CreateScopeValidator([]string{token_scopes.Pelican_NamespaceKeyUpdate.String()}, true)),
+		jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
+	if err != nil {
+		log.Warningf("Refusing to update the keys of namespace %s: %v", prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "The token is not signed by a key of the namespace or doesn't permit updating its keys: "+err.Error())
+		return
+	}
+	newKeys, err := parseNamespaceKeysClaim(tok)
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, err.Error())
+		return
+	}
+
+	signingKeyId := ""
+	if msg, err := jws.Parse([]byte(tokenStr)); err == nil && len(msg.Signatures()) > 0 {
+		signingKeyId = msg.Signatures()[0].ProtectedHeaders().KeyID()
+	}
+	if _, ok := newKeys.LookupKeyID(signingKeyId); !ok {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, "The new keys must include the key that signed the token")
+		return
+	}
+
+	pubkey, err := json.Marshal(newKeys)
+	if err != nil {
+		log.Errorf("Failed to marshal the new public keys of namespace %s: %v", prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to store the new public keys")
+		return
+	}
+	if err = updateNamespacePubkey(prefix, string(pubkey)); err != nil {
+		log.Errorf("Failed to update the public keys of namespace %s: %v", prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to store the new public keys")
+		return
+	}
+	log.Infof("Replaced the public keys of namespace %s with %d keys, as requested with key %s", prefix, newKeys.Len(), signingKeyId)
+	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
+}
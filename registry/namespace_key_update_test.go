@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/token_scopes"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+func TestUpdateNamespaceKey(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	privKeys := []jwk.Key{}
+	for i := 0; i < 3; i++ {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(privKey)
+		require.NoError(t, err)
+		require.NoError(t, jwk.AssignKeyID(key))
+		require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+		privKeys = append(privKeys, key)
+	}
+	publicSet := func(keys ...jwk.Key) jwk.Set {
+		keySet := jwk.NewSet()
+		for _, key := range keys {
+			pubKey, err := jwk.PublicKeyOf(key)
+			require.NoError(t, err)
+			require.NoError(t, keySet.AddKey(pubKey))
+		}
+		return keySet
+	}
+	keySetBytes, err := json.Marshal(publicSet(privKeys[0]))
+	require.NoError(t, err)
+	require.NoError(t, insertMockDBData([]Namespace{
+		mockNamespace("/rotate", string(keySetBytes), "", AdminMetadata{UserID: "alice", Status: Approved}),
+	}))
+
+	router := gin.Default()
+	router.POST("/api/v1.0/registry/updateNamespaceKey", updateNamespaceKeyHandler)
+
+	createToken := func(signingKey jwk.Key, scope token_scopes.TokenScope, keySet jwk.Set) string {
+		pubkey, err := json.Marshal(keySet)
+		require.NoError(t, err)
+		tokenCfg := utils.TokenConfig{
+			TokenProfile: utils.WLCG,
+			Lifetime:     time.Minute,
+			Issuer:       "https://registry.example.com/api/v1.0/registry/rotate",
+			Audience:     []string{"registry"},
+			Version:      "1.0",
+			Subject:      "origin",
+			Claims:       map[string]string{"scope": scope.String(), namespaceKeysClaim: string(pubkey)},
+		}
+		tok, err := tokenCfg.CreateTokenWithKey(signingKey)
+		require.NoError(t, err)
+		return tok
+	}
+	doRequest := func(prefix string, token string) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(updateNamespaceKeyReq{Prefix: prefix})
+		require.NoError(t, err)
+		req, err := http.NewRequest("POST", "/api/v1.0/registry/updateNamespaceKey", bytes.NewReader(reqBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	registeredKeyIds := func() []string {
+		keySet, _, err := getNamespaceJwksByPrefix("/rotate")
+		require.NoError(t, err)
+		keyIds := []string{}
+		for idx := 0; idx < keySet.Len(); idx++ {
+			key, _ := keySet.Key(idx)
+			keyIds = append(keyIds, key.KeyID())
+		}
+		return keyIds
+	}
+
+	t.Run("refused", func(t *testing.T) {
+		newKeys := publicSet(privKeys[0], privKeys[1])
+		w := doRequest("/rotate", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = doRequest("/unknown", createToken(privKeys[0], token_scopes.Pelican_NamespaceKeyUpdate, newKeys))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		// Signed by a key the namespace doesn't have
+		w = doRequest("/rotate", createToken(privKeys[1], token_scopes.Pelican_NamespaceKeyUpdate, newKeys))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest("/rotate", createToken(privKeys[0], token_scopes.Pelican_NamespaceDelete, newKeys))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		// The new keys leave out the signing key
+		w = doRequest("/rotate", createToken(privKeys[0], token_scopes.Pelican_NamespaceKeyUpdate, publicSet(privKeys[1])))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, []string{privKeys[0].KeyID()}, registeredKeyIds())
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		w := doRequest("/rotate", createToken(privKeys[0], token_scopes.Pelican_NamespaceKeyUpdate, publicSet(privKeys[1], privKeys[0])))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.ElementsMatch(t, []string{privKeys[0].KeyID(), privKeys[1].KeyID()}, registeredKeyIds())
+
+		// Once the overlap is over, the new key drops the previous one
+		w = doRequest("/rotate", createToken(privKeys[1], token_scopes.Pelican_NamespaceKeyUpdate, publicSet(privKeys[1])))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []string{privKeys[1].KeyID()}, registeredKeyIds())
+
+		// The private parts of the keys are never stored
+		ns, err := getNamespaceByPrefix("/rotate")
+		require.NoError(t, err)
+		stored, err := jwk.ParseString(ns.Pubkey)
+		require.NoError(t, err)
+		key, _ := stored.Key(0)
+		_, isPrivate := key.(jwk.ECDSAPrivateKey)
+		assert.False(t, isPrivate)
+	})
+
+	t.Run("revoked-key", func(t *testing.T) {
+		ns, err := getNamespaceByPrefix("/rotate")
+		require.NoError(t, err)
+		require.NoError(t, addKeyRevocation(&KeyRevocation{NamespaceID: ns.ID, Prefix: "/rotate", KeyID: privKeys[1].KeyID(),
+			Reason: "compromised", RevokedBy: "alice"}))
+		w := doRequest("/rotate", createToken(privKeys[1], token_scopes.Pelican_NamespaceKeyUpdate, publicSet(privKeys[1], privKeys[2])))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,186 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file implements the signed namespace snapshots.  A snapshot lists every
+// registered namespace with its public keys and is signed with the registry's
+// issuer key as a compact JWS, so services and clients holding a copy (and the
+// registry's public keys) can keep validating namespace keys while the registry
+// is unreachable.
+//
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	// The registration of a namespace as recorded in a snapshot
+	SnapshotNamespace struct {
+		Prefix string          `json:"prefix"`
+		Pubkey json.RawMessage `json:"pubkey"` // The namespace's JWKS
+		Status string          `json:"status"`
+	}
+
+	// The payload of a signed namespace snapshot
+	NamespaceSnapshot struct {
+		Issuer     string              `json:"issuer"`
+		IssuedAt   time.Time           `json:"issued_at"`
+		Expires    time.Time           `json:"expires"`
+		Namespaces []SnapshotNamespace `json:"namespaces"`
+	}
+)
+
+// Returned by VerifyNamespaceSnapshot for a correctly signed snapshot that has expired
+var ErrSnapshotExpired = errors.New("the namespace snapshot has expired")
+
+// Create a snapshot of every registered namespace, signed with the registry's issuer key
+func createNamespaceSnapshot() ([]byte, error) {
+	namespaces, err := getAllNamespaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the namespaces")
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	snapshot := NamespaceSnapshot{
+		Issuer:     param.Server_ExternalWebUrl.GetString(),
+		IssuedAt:   now,
+		Expires:    now.Add(param.Registry_NamespaceSnapshotLifetime.GetDuration()),
+		Namespaces: make([]SnapshotNamespace, 0, len(namespaces)),
+	}
+	for _, ns := range namespaces {
+		keySet, err := jwk.ParseString(ns.Pubkey)
+		if err != nil {
+			log.Warningf("Leaving namespace %s out of the snapshot; its public key isn't a valid JWKS: %v", ns.Prefix, err)
+			continue
+		}
+		if keySet, err = excludeRevokedKeys(ns.Prefix, keySet); err != nil {
+			return nil, err
+		}
+		pubkey, err := json.Marshal(keySet)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal the public keys of namespace %s", ns.Prefix)
+		}
+		status := ns.AdminMetadata.Status
+		if status == "" {
+			status = Unknown
+		}
+		snapshot.Namespaces = append(snapshot.Namespaces, SnapshotNamespace{
+			Prefix: ns.Prefix,
+			Pubkey: json.RawMessage(pubkey),
+			Status: status.String(),
+		})
+	}
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := config.GetIssuerPrivateJWK()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the registry's issuer key")
+	}
+	signed, err := jws.Sign(payload, jws.WithKey(key.Algorithm(), key))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign the namespace snapshot")
+	}
+	return signed, nil
+}
+
+// Verify a namespace snapshot against the registry's public keys and return its
+// contents.  Fails with ErrSnapshotExpired if the snapshot is past its expiration.
+func VerifyNamespaceSnapshot(snapshot []byte, registryKeys jwk.Set) (*NamespaceSnapshot, error) {
+	payload, err := jws.Verify(snapshot, jws.WithKeySet(registryKeys, jws.WithInferAlgorithmFromKey(true)))
+	if err != nil {
+		return nil, errors.Wrap(err, "the namespace snapshot's signature is invalid")
+	}
+	result := &NamespaceSnapshot{}
+	if err = json.Unmarshal(payload, result); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the namespace snapshot")
+	}
+	if time.Now().After(result.Expires) {
+		return result, ErrSnapshotExpired
+	}
+	return result, nil
+}
+
+// Get the public keys of a namespace in the snapshot with the exact prefix
+func (snapshot *NamespaceSnapshot) GetNamespaceKeys(prefix string) (jwk.Set, error) {
+	for _, ns := range snapshot.Namespaces {
+		if ns.Prefix == prefix {
+			return jwk.Parse(ns.Pubkey)
+		}
+	}
+	return nil, errors.Errorf("namespace %s is not in the snapshot", prefix)
+}
+
+// Fetch the signed namespace snapshot and the registry's public keys from the registry
+// endpoint (/api/v1.0/registry)
+func NamespaceSnapshotFetch(endpoint string) (snapshot []byte, registryKeys jwk.Set, err error) {
+	if snapshot, err = utils.MakeRequest(endpoint+"/snapshot", "GET", nil, nil); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to get the namespace snapshot")
+	}
+	keyData, err := utils.MakeRequest(endpoint+"/snapshot/keys", "GET", nil, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to get the registry's public keys")
+	}
+	if registryKeys, err = jwk.Parse(keyData); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse the registry's public keys")
+	}
+	return
+}
+
+// GET /api/v1.0/registry/snapshot
+//
+// Respond with a signed snapshot of every registered namespace (a compact JWS)
+func namespaceSnapshotHandler(ctx *gin.Context) {
+	snapshot, err := createNamespaceSnapshot()
+	if err != nil {
+		log.Errorln("Failed to create the namespace snapshot:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to create the namespace snapshot")
+		return
+	}
+	ctx.Data(http.StatusOK, "application/jose", snapshot)
+}
+
+// GET /api/v1.0/registry/snapshot/keys
+//
+// Respond with the public keys verifying the namespace snapshots
+func namespaceSnapshotKeysHandler(ctx *gin.Context) {
+	keys, err := config.GetIssuerPublicJWKS()
+	if err != nil {
+		log.Errorln("Failed to load the registry's public keys:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to load the registry's public keys")
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+func TestNamespaceSnapshot(t *testing.T) {
+	viper.Reset()
+	ctx, cancel, egrp := test_utils.TestContext(context.Background(), t)
+	defer func() { require.NoError(t, egrp.Wait()) }()
+	defer cancel()
+	t.Cleanup(viper.Reset)
+
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	viper.Set("ConfigDir", t.TempDir())
+	require.NoError(t, config.InitServer(ctx, config.OriginType))
+	require.NoError(t, config.GeneratePrivateKey(param.IssuerKey.GetString(), elliptic.P256()))
+
+	nsKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	nsJwk, err := jwk.FromRaw(nsKey.Public())
+	require.NoError(t, err)
+	require.NoError(t, jwk.AssignKeyID(nsJwk))
+	nsJwks := jwk.NewSet()
+	require.NoError(t, nsJwks.AddKey(nsJwk))
+	nsJwksBytes, err := json.Marshal(nsJwks)
+	require.NoError(t, err)
+
+	require.NoError(t, insertMockDBData([]Namespace{
+		mockNamespace("/foo", string(nsJwksBytes), "", AdminMetadata{Status: Approved}),
+		mockNamespace("/bar", string(nsJwksBytes), "", AdminMetadata{Status: Pending}),
+		mockNamespace("/broken", "not-a-jwks", "", AdminMetadata{Status: Approved}),
+	}))
+
+	router := gin.Default()
+	router.GET("/api/v1.0/registry/*wildcard", wildcardHandler)
+	getSnapshot := func(t *testing.T) ([]byte, jwk.Set) {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1.0/registry/snapshot", nil)
+		require.NoError(t, err)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/jose", w.Header().Get("Content-Type"))
+		snapshot := w.Body.Bytes()
+
+		w = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/api/v1.0/registry/snapshot/keys", nil)
+		require.NoError(t, err)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		keys, err := jwk.Parse(w.Body.Bytes())
+		require.NoError(t, err)
+		return snapshot, keys
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		snapshot, keys := getSnapshot(t)
+		result, err := VerifyNamespaceSnapshot(snapshot, keys)
+		require.NoError(t, err)
+		assert.True(t, result.Expires.After(time.Now().Add(24*time.Hour)))
+		require.Len(t, result.Namespaces, 2)
+		assert.Equal(t, "/foo", result.Namespaces[0].Prefix)
+		assert.Equal(t, Approved.String(), result.Namespaces[0].Status)
+		assert.Equal(t, Pending.String(), result.Namespaces[1].Status)
+
+		fooKeys, err := result.GetNamespaceKeys("/foo")
+		require.NoError(t, err)
+		_, found := fooKeys.LookupKeyID(nsJwk.KeyID())
+		assert.True(t, found)
+		_, err = result.GetNamespaceKeys("/broken")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong-key", func(t *testing.T) {
+		snapshot, _ := getSnapshot(t)
+		_, err := VerifyNamespaceSnapshot(snapshot, nsJwks)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		snapshot, keys := getSnapshot(t)
+		tampered := append([]byte{}, snapshot...)
+		// Flip a character of the payload, the middle part of the compact JWS
+		idx := len(tampered) / 2
+		if tampered[idx] == 'A' {
+			tampered[idx] = 'B'
+		} else {
+			tampered[idx] = 'A'
+		}
+		_, err := VerifyNamespaceSnapshot(tampered, keys)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		viper.Set("Registry.NamespaceSnapshotLifetime", -time.Minute)
+		defer viper.Set("Registry.NamespaceSnapshotLifetime", 168*time.Hour)
+		snapshot, keys := getSnapshot(t)
+		result, err := VerifyNamespaceSnapshot(snapshot, keys)
+		assert.ErrorIs(t, err, ErrSnapshotExpired)
+		assert.NotNil(t, result)
+	})
+}
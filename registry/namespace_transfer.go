@@ -0,0 +1,485 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file implements the transfer of a namespace between owners.  The current
+// owner or a registry admin initiates a transfer to another user, who accepts it
+// by proving possession of the key the namespace will be registered with through
+// the same key-sign challenge used for registration.  The namespace's key and
+// owner are then replaced at once, and every transfer is kept as history.
+//
+
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type TransferStatus string
+
+// A request to hand a namespace over to a new owner
+type NamespaceTransfer struct {
+	ID             int            `json:"id"`
+	NamespaceID    int            `json:"namespace_id"`
+	Prefix         string         `json:"prefix"`
+	FromUserID     string         `json:"from_user_id"`
+	ToUserID       string         `json:"to_user_id"`
+	InitiatedBy    string         `json:"initiated_by"`
+	Status         TransferStatus `json:"status"`
+	PreviousPubkey string         `json:"previous_pubkey,omitempty"` // The key the namespace had before a completed transfer
+	CreatedAt      time.Time      `json:"created_at"`
+	ResolvedBy     string         `json:"resolved_by,omitempty"` // The user who completed or cancelled the transfer
+	ResolvedAt     time.Time      `json:"resolved_at"`
+}
+
+type initiateTransferReq struct {
+	ToUserID string `json:"to_user_id" binding:"required"`
+}
+
+const (
+	TransferPending   TransferStatus = "Pending"
+	TransferCompleted TransferStatus = "Completed"
+	TransferCancelled TransferStatus = "Cancelled"
+)
+
+var errTransferPending = errors.New("the namespace already has a pending transfer")
+
+func createNamespaceTransferTable() {
+	query := `
+    CREATE TABLE IF NOT EXISTS namespace_transfer (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        namespace_id INTEGER NOT NULL,
+        prefix TEXT NOT NULL,
+        from_user_id TEXT NOT NULL,
+        to_user_id TEXT NOT NULL,
+        initiated_by TEXT NOT NULL,
+        status TEXT NOT NULL,
+        previous_pubkey TEXT NOT NULL DEFAULT '',
+        created_at INTEGER NOT NULL,
+        resolved_by TEXT NOT NULL DEFAULT '',
+        resolved_at INTEGER NOT NULL DEFAULT 0
+    );`
+
+	if _, err := db.Exec(query); err != nil {
+		log.Fatalf("Failed to create namespace transfer table: %v", err)
+	}
+}
+
+const namespaceTransferColumns = `id, namespace_id, prefix, from_user_id, to_user_id, initiated_by, status, previous_pubkey, created_at, resolved_by, resolved_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNamespaceTransfer(row rowScanner) (*NamespaceTransfer, error) {
+	transfer := &NamespaceTransfer{}
+	var createdAt, resolvedAt int64
+	err := row.Scan(&transfer.ID, &transfer.NamespaceID, &transfer.Prefix, &transfer.FromUserID, &transfer.ToUserID,
+		&transfer.InitiatedBy, &transfer.Status, &transfer.PreviousPubkey, &createdAt, &transfer.ResolvedBy, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	transfer.CreatedAt = time.Unix(createdAt, 0)
+	if resolvedAt != 0 {
+		transfer.ResolvedAt = time.Unix(resolvedAt, 0)
+	}
+	return transfer, nil
+}
+
+// Record a new pending transfer, failing with errTransferPending if the
+// namespace already has one
+func addNamespaceTransfer(transfer *NamespaceTransfer) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func() {
+		if errRoll := tx.Rollback(); errRoll != nil {
+			log.Errorln("Failed to rollback transaction:", errRoll)
+		}
+	}
+
+	var pending int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM namespace_transfer WHERE namespace_id = ? AND status = ?`,
+		transfer.NamespaceID, TransferPending).Scan(&pending)
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to check for pending transfers")
+	}
+	if pending > 0 {
+		rollback()
+		return errTransferPending
+	}
+
+	transfer.Status = TransferPending
+	transfer.CreatedAt = time.Now()
+	query := `INSERT INTO namespace_transfer (namespace_id, prefix, from_user_id, to_user_id, initiated_by, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, transfer.NamespaceID, transfer.Prefix, transfer.FromUserID, transfer.ToUserID,
+		transfer.InitiatedBy, transfer.Status, transfer.CreatedAt.Unix())
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to insert the transfer")
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to get the id of the transfer")
+	}
+	transfer.ID = int(id)
+	return tx.Commit()
+}
+
+// Get the pending transfer of a namespace, or nil if there's none
+func getPendingNamespaceTransfer(namespaceId int) (*NamespaceTransfer, error) {
+	query := `SELECT ` + namespaceTransferColumns + ` FROM namespace_transfer WHERE namespace_id = ? AND status = ?`
+	transfer, err := scanNamespaceTransfer(db.QueryRow(query, namespaceId, TransferPending))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return transfer, err
+}
+
+// Get every transfer of a namespace, oldest first
+func getNamespaceTransfers(namespaceId int) ([]*NamespaceTransfer, error) {
+	query := `SELECT ` + namespaceTransferColumns + ` FROM namespace_transfer WHERE namespace_id = ? ORDER BY id ASC`
+	rows, err := db.Query(query, namespaceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]*NamespaceTransfer, 0)
+	for rows.Next() {
+		transfer, err := scanNamespaceTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+func cancelNamespaceTransfer(transferId int, user string) error {
+	query := `UPDATE namespace_transfer SET status = ?, resolved_by = ?, resolved_at = ? WHERE id = ? AND status = ?`
+	result, err := db.Exec(query, TransferCancelled, user, time.Now().Unix(), transferId, TransferPending)
+	if err != nil {
+		return errors.Wrap(err, "Failed to cancel the transfer")
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errors.New("the transfer is no longer pending")
+	}
+	return nil
+}
+
+// Complete a pending transfer, replacing the namespace's key and owner and
+// recording the previous key in the transfer, all in one transaction
+func completeNamespaceTransfer(transfer *NamespaceTransfer, pubkey string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func() {
+		if errRoll := tx.Rollback(); errRoll != nil {
+			log.Errorln("Failed to rollback transaction:", errRoll)
+		}
+	}
+
+	var previousPubkey, adminMetadataStr string
+	err = tx.QueryRow(`SELECT pubkey, admin_metadata FROM namespace WHERE id = ?`, transfer.NamespaceID).Scan(&previousPubkey, &adminMetadataStr)
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to get the namespace")
+	}
+	adminMetadata := AdminMetadata{}
+	// For backward compatibility, if adminMetadata is an empty string, don't unmarshal json
+	if adminMetadataStr != "" {
+		if err := json.Unmarshal([]byte(adminMetadataStr), &adminMetadata); err != nil {
+			rollback()
+			return errors.Wrap(err, "Failed to parse the namespace's admin metadata")
+		}
+	}
+	now := time.Now()
+	adminMetadata.UserID = transfer.ToUserID
+	adminMetadata.UpdatedAt = now
+	adminMetadataByte, err := json.Marshal(adminMetadata)
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Error marshaling admin metadata")
+	}
+
+	query := `UPDATE namespace_transfer SET status = ?, previous_pubkey = ?, resolved_by = ?, resolved_at = ? WHERE id = ? AND status = ?`
+	result, err := tx.Exec(query, TransferCompleted, previousPubkey, transfer.ToUserID, now.Unix(), transfer.ID, TransferPending)
+	if err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to update the transfer")
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		rollback()
+		return errors.New("the transfer is no longer pending")
+	}
+	if _, err = tx.Exec(`UPDATE namespace SET pubkey = ?, admin_metadata = ? WHERE id = ?`, pubkey, string(adminMetadataByte), transfer.NamespaceID); err != nil {
+		rollback()
+		return errors.Wrap(err, "Failed to update the namespace")
+	}
+	return tx.Commit()
+}
+
+// Parse the namespace id of the request, writing an error response if it's invalid
+// or the namespace doesn't exist
+func getNamespaceIdParam(ctx *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ID format. ID must a non-zero integer")
+		return 0, false
+	}
+	exists, err := namespaceExistsById(id)
+	if err != nil {
+		log.Error("Error checking if namespace exists: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace exists")
+		return 0, false
+	}
+	if !exists {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Namespace not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// Check that the user is an admin or owns the namespace, writing an error response
+// saying that only they can perform the action if not
+func checkNamespacePermission(ctx *gin.Context, id int, user, action string) bool {
+	if isAdmin, _ := web_ui.CheckAdmin(user); isAdmin {
+		return true
+	}
+	found, err := namespaceBelongsToUserId(id, user)
+	if err != nil {
+		log.Error("Error checking if namespace belongs to the user: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace belongs to the user")
+		return false
+	}
+	if !found {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Only the owner of the namespace or an admin can "+action)
+		return false
+	}
+	return true
+}
+
+// Start transferring a namespace to another user. The current owner or an admin can
+// initiate a transfer, and a namespace can only have one pending transfer at a time.
+//
+// POST /namespaces/:id/transfer
+func initiateNamespaceTransfer(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok || !checkNamespacePermission(ctx, id, user, "manage its transfers") {
+		return
+	}
+
+	reqData := initiateTransferReq{}
+	if err := ctx.ShouldBindJSON(&reqData); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ns, err := getNamespaceById(id)
+	if err != nil {
+		log.Error("Error getting namespace: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespace")
+		return
+	}
+	if reqData.ToUserID == ns.AdminMetadata.UserID {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The namespace is already owned by "+reqData.ToUserID)
+		return
+	}
+
+	transfer := &NamespaceTransfer{
+		NamespaceID: id,
+		Prefix:      ns.Prefix,
+		FromUserID:  ns.AdminMetadata.UserID,
+		ToUserID:    reqData.ToUserID,
+		InitiatedBy: user,
+	}
+	if err = addNamespaceTransfer(transfer); errors.Is(err, errTransferPending) {
+		web_ui.WriteProblem(ctx, http.StatusConflict, common.ErrCodeConflict, "The namespace already has a pending transfer; cancel it before starting another")
+		return
+	} else if err != nil {
+		log.Errorf("Failed to initiate the transfer of namespace %s: %v", ns.Prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to initiate the transfer")
+		return
+	}
+	log.Infof("User %s initiated the transfer of namespace %s from %q to %q", user, ns.Prefix, transfer.FromUserID, transfer.ToUserID)
+	ctx.JSON(http.StatusCreated, transfer)
+}
+
+// Cancel the pending transfer of a namespace. Besides the owner and admins, the
+// recipient can cancel a transfer to decline it.
+//
+// DELETE /namespaces/:id/transfer
+func cancelNamespaceTransferHandler(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok {
+		return
+	}
+	transfer, err := getPendingNamespaceTransfer(id)
+	if err != nil {
+		log.Error("Error getting the pending transfer: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting the pending transfer")
+		return
+	}
+	if transfer == nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The namespace has no pending transfer")
+		return
+	}
+	if user != transfer.ToUserID && !checkNamespacePermission(ctx, id, user, "manage its transfers") {
+		return
+	}
+
+	if err = cancelNamespaceTransfer(transfer.ID, user); err != nil {
+		log.Errorf("Failed to cancel the transfer of namespace %s: %v", transfer.Prefix, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to cancel the transfer")
+		return
+	}
+	log.Infof("User %s cancelled the transfer of namespace %s to %q", user, transfer.Prefix, transfer.ToUserID)
+	ctx.JSON(http.StatusOK, gin.H{"msg": "ok"})
+}
+
+// List the transfers of a namespace, including completed and cancelled ones
+//
+// GET /namespaces/:id/transfers
+func listNamespaceTransfers(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok || !checkNamespacePermission(ctx, id, user, "manage its transfers") {
+		return
+	}
+	transfers, err := getNamespaceTransfers(id)
+	if err != nil {
+		log.Error("Error getting the namespace transfers: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting the namespace transfers")
+		return
+	}
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+// Accept the pending transfer of a namespace as its recipient. The request body is
+// a key-sign challenge, as for registration, proving the recipient holds the private
+// key of the public key the namespace is registered with once the transfer completes.
+//
+// POST /namespaces/:id/transfer/accept
+func acceptNamespaceTransfer(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	id, ok := getNamespaceIdParam(ctx)
+	if !ok {
+		return
+	}
+	transfer, err := getPendingNamespaceTransfer(id)
+	if err != nil {
+		log.Error("Error getting the pending transfer: ", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting the pending transfer")
+		return
+	}
+	if transfer == nil {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The namespace has no pending transfer")
+		return
+	}
+	if user != transfer.ToUserID {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "Only the recipient of the transfer can accept it")
+		return
+	}
+	if !requireTermsAccepted(ctx, user, "take over a namespace") {
+		return
+	}
+
+	var reqData registrationData
+	if err := ctx.ShouldBindJSON(&reqData); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	reqData.Prefix = transfer.Prefix
+	if err = keySignChallenge(ctx, &reqData, "transfer"); err != nil {
+		if !ctx.Writer.Written() {
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error during key-sign challenge: "+err.Error())
+		}
+		log.Warningf("Failed to complete key sign challenge to accept the transfer of %s: %v", transfer.Prefix, err)
+	}
+}
+
+// Complete the pending transfer of the request's namespace once the recipient passed
+// the key-sign challenge with the given key
+func acceptTransferHandler(ctx *gin.Context, data *registrationData, key jwk.Key) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return errors.Wrap(err, "invalid namespace id")
+	}
+	transfer, err := getPendingNamespaceTransfer(id)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the pending transfer")
+	}
+	if transfer == nil || transfer.ToUserID != ctx.GetString("User") {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The namespace has no pending transfer to the user")
+		return errors.New("no pending transfer to the user")
+	}
+
+	// The owner of an enclosing namespace has to permit the new key, as for registration
+	if param.Registry_RequireKeyChaining.GetBool() {
+		superspaces, _, _, err := namespaceSupSubChecks(transfer.Prefix)
+		if err != nil {
+			return errors.Wrap(err, "failed to check for superspaces")
+		}
+		enclosing := make([]string, 0, len(superspaces))
+		for _, superspace := range superspaces {
+			if superspace != transfer.Prefix {
+				enclosing = append(enclosing, superspace)
+			}
+		}
+		if len(enclosing) > 0 {
+			matched, err := matchKeys(key, enclosing)
+			if err != nil {
+				return errors.Wrap(err, "failed to check the new key against the enclosing namespaces")
+			}
+			if !matched {
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "The new key must match a key of a namespace enclosing "+transfer.Prefix)
+				return errors.New("the new key doesn't match a key of an enclosing namespace")
+			}
+		}
+	}
+
+	pubkeyData, err := json.Marshal(data.Pubkey)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to marshal the pubkey for prefix %s", transfer.Prefix)
+	}
+	if err = completeNamespaceTransfer(transfer, string(pubkeyData)); err != nil {
+		return errors.Wrapf(err, "Failed to complete the transfer of prefix %s", transfer.Prefix)
+	}
+	log.Infof("Namespace %s was transferred from %q to %q", transfer.Prefix, transfer.FromUserID, transfer.ToUserID)
+	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
+	return nil
+}
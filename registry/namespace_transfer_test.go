@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+func TestNamespaceTransfer(t *testing.T) {
+	viper.Reset()
+	ctx, cancel, egrp := test_utils.TestContext(context.Background(), t)
+	defer func() { require.NoError(t, egrp.Wait()) }()
+	defer cancel()
+	t.Cleanup(viper.Reset)
+
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	viper.Set("ConfigDir", t.TempDir())
+	require.NoError(t, config.InitServer(ctx, config.OriginType))
+	require.NoError(t, config.GeneratePrivateKey(param.IssuerKey.GetString(), elliptic.P256()))
+
+	require.NoError(t, insertMockDBData([]Namespace{
+		mockNamespace("/transfer", "old-pubkey", "", AdminMetadata{UserID: "alice", Status: Approved}),
+	}))
+	id, err := getLastNamespaceId()
+	require.NoError(t, err)
+	nsPath := fmt.Sprintf("/namespaces/%d", id)
+
+	router := gin.Default()
+	asUser := func(ctx *gin.Context) {
+		ctx.Set("User", ctx.GetHeader("X-Test-User"))
+	}
+	router.GET("/namespaces/:id/transfers", asUser, listNamespaceTransfers)
+	router.POST("/namespaces/:id/transfer", asUser, initiateNamespaceTransfer)
+	router.DELETE("/namespaces/:id/transfer", asUser, cancelNamespaceTransferHandler)
+	router.POST("/namespaces/:id/transfer/accept", asUser, acceptNamespaceTransfer)
+
+	doRequest := func(method, path, user string, body any) *httptest.ResponseRecorder {
+		var reqBody []byte
+		if body != nil {
+			reqBody, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, path, bytes.NewReader(reqBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("initiate", func(t *testing.T) {
+		w := doRequest("POST", nsPath+"/transfer", "mallory", initiateTransferReq{ToUserID: "mallory"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest("POST", nsPath+"/transfer", "alice", initiateTransferReq{ToUserID: "alice"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doRequest("POST", nsPath+"/transfer", "alice", initiateTransferReq{ToUserID: "bob"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		w = doRequest("POST", nsPath+"/transfer", "admin", initiateTransferReq{ToUserID: "carol"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	// The recipient's key, which the namespace is registered with after the transfer
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pubKey, err := jwk.FromRaw(&privKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, pubKey.Set("alg", "ES256"))
+	keySet := jwk.NewSet()
+	require.NoError(t, keySet.AddKey(pubKey))
+	keySetBytes, err := json.Marshal(keySet)
+	require.NoError(t, err)
+
+	acceptTransfer := func(user string) *httptest.ResponseRecorder {
+		clientNonce, err := generateNonce()
+		require.NoError(t, err)
+		w := doRequest("POST", nsPath+"/transfer/accept", user, map[string]any{"client_nonce": clientNonce, "pubkey": keySet})
+		if w.Code != http.StatusOK {
+			return w
+		}
+		challenge := registrationData{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+
+		clientPayload := clientNonce + challenge.ServerNonce
+		signature, err := signPayload([]byte(clientPayload), privKey)
+		require.NoError(t, err)
+		return doRequest("POST", nsPath+"/transfer/accept", user, map[string]any{
+			"client_nonce":     clientNonce,
+			"server_nonce":     challenge.ServerNonce,
+			"pubkey":           keySet,
+			"client_payload":   clientPayload,
+			"client_signature": hex.EncodeToString(signature),
+			"server_payload":   challenge.ServerPayload,
+			"server_signature": challenge.ServerSignature,
+		})
+	}
+
+	t.Run("only-recipient-accepts", func(t *testing.T) {
+		w := acceptTransfer("alice")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		w := acceptTransfer("bob")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		ns, err := getNamespaceById(id)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", ns.AdminMetadata.UserID)
+		assert.Equal(t, Approved, ns.AdminMetadata.Status)
+		assert.JSONEq(t, string(keySetBytes), ns.Pubkey)
+
+		// The new owner manages the namespace from now on
+		w = doRequest("GET", nsPath+"/transfers", "alice", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest("GET", nsPath+"/transfers", "bob", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		transfers := []NamespaceTransfer{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfers))
+		require.Len(t, transfers, 1)
+		assert.Equal(t, TransferCompleted, transfers[0].Status)
+		assert.Equal(t, "alice", transfers[0].FromUserID)
+		assert.Equal(t, "old-pubkey", transfers[0].PreviousPubkey)
+		assert.False(t, transfers[0].ResolvedAt.IsZero())
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		w := doRequest("POST", nsPath+"/transfer", "admin", initiateTransferReq{ToUserID: "carol"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		w = doRequest("DELETE", nsPath+"/transfer", "mallory", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest("DELETE", nsPath+"/transfer", "carol", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		w = acceptTransfer("carol")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		transfers, err := getNamespaceTransfers(id)
+		require.NoError(t, err)
+		require.Len(t, transfers, 2)
+		assert.Equal(t, TransferCancelled, transfers[1].Status)
+		assert.Equal(t, "carol", transfers[1].ResolvedBy)
+	})
+}
@@ -20,7 +20,7 @@
 //
 //   - It handles the logic to spin up a "registry" server for namespace management,
 //     including a web UI for interactive namespace registration, approval, and browsing.
-//   - It provides a CLI tool `./pelican namespace <command> <args>` to list, register, and delete a namespace
+//   - It provides a CLI tool `./pelican namespace <command> <args>` to list, get, check, register, and delete a namespace
 //
 // To register a namespace, first spin up registry server by `./pelican registry serve -p <your-port-number>`, and then use either
 // the CLI tool or go to registry web UI at `https://localhost:<your-port-number>/view/`, and follow instructions for next steps.
@@ -30,8 +30,11 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -44,10 +47,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/oauth2"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/token_scopes"
+	"github.com/pelicanplatform/pelican/utils"
+	"github.com/pelicanplatform/pelican/web_ui"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
@@ -70,7 +76,7 @@ var OIDC struct {
 var (
 	// Loading of public/private keys for signing challenges
 	serverCredsLoad    sync.Once
-	serverCredsPrivKey *ecdsa.PrivateKey
+	serverCredsPrivKey crypto.Signer
 	serverCredsErr     error
 )
 
@@ -96,12 +102,43 @@ type checkNamespaceExistsRes struct {
 	Error        string `json:"error"`
 }
 
+type cliListNamespacesReq struct {
+	Status      string `form:"status"`
+	Prefix      string `form:"prefix"`
+	Institution string `form:"institution"`
+	ServerType  string `form:"server_type"`
+}
+
+type searchNamespacesReq struct {
+	Query      string `form:"q"`
+	Match      string `form:"match"` // "prefix" (default) or "substring"
+	Status     string `form:"status"`
+	ServerType string `form:"server_type"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// The response of the namespace search endpoint
+type NamespaceSearchResult struct {
+	Namespaces []NamespaceWOPubkey `json:"namespaces"`
+	Total      int                 `json:"total"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
+}
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
 type checkStatusReq struct {
 	Prefix string `json:"prefix"`
 }
 
 type checkStatusRes struct {
-	Approved bool `json:"approved"`
+	Approved    bool                `json:"approved"`
+	WriteMode   common.WriteMode    `json:"write_mode,omitempty"`
+	CachePolicy *common.CachePolicy `json:"cache_policy,omitempty"`
 }
 
 // Various auxiliary functions used for client-server security handshakes
@@ -127,6 +164,8 @@ type registrationData struct {
 	IdentityRequired string          `json:"identity_required"`
 	DeviceCode       string          `json:"device_code"`
 	Prefix           string          `json:"prefix"`
+	// The version of the federation's terms of service the registrant accepts
+	AcceptedTermsOfService string `json:"accepted_terms_version"`
 }
 
 func matchKeys(incomingKey jwk.Key, registeredNamespaces []string) (bool, error) {
@@ -179,7 +218,7 @@ func keySignChallenge(ctx *gin.Context, data *registrationData, action string) e
 		}
 
 	} else {
-		ctx.JSON(http.StatusMultipleChoices, gin.H{"error": "MISSING PARAMETERS"})
+		web_ui.WriteProblem(ctx, http.StatusMultipleChoices, common.ErrCodeInvalidRequest, "MISSING PARAMETERS")
 		return errors.New("key sign challenge was missing parameters")
 	}
 	return nil
@@ -194,34 +233,73 @@ func generateNonce() (string, error) {
 	return hex.EncodeToString(nonce), nil
 }
 
-func loadServerKeys() (*ecdsa.PrivateKey, error) {
+func loadServerKeys() (crypto.Signer, error) {
 	// Note: go 1.21 introduces `OnceValues` which automates this procedure.
 	// TODO: Reimplement the function once we switch to a minimum of 1.21
 	serverCredsLoad.Do(func() {
 		issuerFileName := param.IssuerKey.GetString()
-		serverCredsPrivKey, serverCredsErr = config.LoadPrivateKey(issuerFileName)
+		serverCredsPrivKey, serverCredsErr = config.LoadSigningKey(issuerFileName)
 	})
 	return serverCredsPrivKey, serverCredsErr
 }
 
-func signPayload(payload []byte, privateKey *ecdsa.PrivateKey) ([]byte, error) {
-	hash := sha256.Sum256(payload)
-	signature, err := privateKey.Sign(rand.Reader, hash[:], crypto.SHA256) // Use crypto.SHA256 instead of the hash[:]
+// Get the hash the key-sign challenge uses with publicKey, the one of the JWS algorithm
+// for its type and curve, along with the digest of payload under it
+func digestPayload(payload []byte, publicKey crypto.PublicKey) (crypto.Hash, []byte, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			digest := sha256.Sum256(payload)
+			return crypto.SHA256, digest[:], nil
+		case elliptic.P384():
+			digest := sha512.Sum384(payload)
+			return crypto.SHA384, digest[:], nil
+		case elliptic.P521():
+			digest := sha512.Sum512(payload)
+			return crypto.SHA512, digest[:], nil
+		}
+		return 0, nil, errors.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		return crypto.SHA256, digest[:], nil
+	}
+	return 0, nil, errors.Errorf("unsupported public key type %T", publicKey)
+}
+
+// Sign payload with an ECDSA (ASN.1 signature) or RSA (PKCS #1 v1.5 signature) private key
+func signPayload(payload []byte, privateKey crypto.Signer) ([]byte, error) {
+	hash, digest, err := digestPayload(payload, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	signature, err := privateKey.Sign(rand.Reader, digest, hash)
 	if err != nil {
 		return nil, err
 	}
 	return signature, nil
 }
 
-func verifySignature(payload []byte, signature []byte, publicKey *ecdsa.PublicKey) bool {
-	hash := sha256.Sum256(payload)
-	return ecdsa.VerifyASN1(publicKey, hash[:], signature)
+// Verify a signature made by signPayload, dispatching on the type of publicKey
+func verifySignature(payload []byte, signature []byte, publicKey crypto.PublicKey) bool {
+	hash, digest, err := digestPayload(payload, publicKey)
+	if err != nil {
+		log.Debugln("Unable to verify the key-sign challenge signature:", err)
+		return false
+	}
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	}
+	return false
 }
 
 func keySignChallengeInit(ctx *gin.Context, data *registrationData) error {
 	serverNonce, err := generateNonce()
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to generate nonce for key sign challenge"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to generate nonce for key sign challenge")
 		return errors.Wrap(err, "Failed to generate nonce for key-sign challenge")
 	}
 
@@ -229,13 +307,13 @@ func keySignChallengeInit(ctx *gin.Context, data *registrationData) error {
 
 	privateKey, err := loadServerKeys()
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Server is unable to generate a key sign challenge"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Server is unable to generate a key sign challenge")
 		return errors.Wrap(err, "Failed to load the server's private key")
 	}
 
 	serverSignature, err := signPayload(serverPayload, privateKey)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failure when signing the challenge"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failure when signing the challenge")
 		return errors.Wrap(err, "Failed to sign payload for key-sign challenge")
 	}
 
@@ -263,29 +341,28 @@ func keySignChallengeCommit(ctx *gin.Context, data *registrationData, action str
 	clientPayload := []byte(data.ClientNonce + data.ServerNonce)
 	clientSignature, err := hex.DecodeString(data.ClientSignature)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to decode client's signature"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to decode client's signature")
 		return errors.Wrap(err, "Failed to decode the client's signature")
 	}
-	clientVerified := verifySignature(clientPayload, clientSignature, (rawkey).(*ecdsa.PublicKey))
+	clientVerified := verifySignature(clientPayload, clientSignature, rawkey)
 	serverPayload, err := hex.DecodeString(data.ServerPayload)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to decode the server's payload"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to decode the server's payload")
 		return errors.Wrap(err, "Failed to decode the server's payload")
 	}
 
 	serverSignature, err := hex.DecodeString(data.ServerSignature)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to decode the server's signature"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to decode the server's signature")
 		return errors.Wrap(err, "Failed to decode the server's signature")
 	}
 
 	serverPrivateKey, err := loadServerKeys()
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to load server's private key"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to load server's private key")
 		return errors.Wrap(err, "Failed to decode the server's private key")
 	}
-	serverPubkey := serverPrivateKey.PublicKey
-	serverVerified := verifySignature(serverPayload, serverSignature, &serverPubkey)
+	serverVerified := verifySignature(serverPayload, serverSignature, serverPrivateKey.Public())
 
 	if clientVerified && serverVerified {
 		if action == "register" {
@@ -317,24 +394,30 @@ func keySignChallengeCommit(ctx *gin.Context, data *registrationData, action str
 			valErr, sysErr := validateKeyChaining(reqPrefix, key)
 			if valErr != nil {
 				log.Errorln(err)
-				ctx.JSON(http.StatusForbidden, gin.H{"error": valErr})
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidPublicKey, valErr.Error())
 				return valErr
 			}
 			if sysErr != nil {
 				log.Errorln(err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": sysErr})
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, sysErr.Error())
 				return sysErr
 			}
 
+			if accepted, err := checkRegistrationTerms(ctx, data); !accepted {
+				return err
+			}
+
 			err = addNamespaceHandler(ctx, data)
 			if err != nil {
-				ctx.JSON(500, gin.H{"error": "The server encountered an error while attempting to add the prefix to its database"})
+				web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "The server encountered an error while attempting to add the prefix to its database")
 				return errors.Wrapf(err, "Failed while trying to add to database")
 			}
 			return nil
+		} else if action == "transfer" {
+			return acceptTransferHandler(ctx, data, key)
 		}
 	} else {
-		ctx.JSON(500, gin.H{"error": "Server was either unable to verify the client's public key, or an encountered an error with its own"})
+		web_ui.WriteProblem(ctx, 500, common.ErrCodeInternal, "Server was either unable to verify the client's public key, or an encountered an error with its own")
 		return errors.Errorf("Either the server or the client could not be verified: "+
 			"server verified:%t, client verified:%t", serverVerified, clientVerified)
 	}
@@ -347,7 +430,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 	var reqData registrationData
 	if err := ctx.BindJSON(&reqData); err != nil {
 		log.Errorln("Bad request: ", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Bad Request")
 		return
 	}
 
@@ -359,14 +442,14 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 		oidcConfig, err := oauth2.ServerOIDCClient()
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server has malformed OIDC configuration"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server has malformed OIDC configuration")
 			log.Errorf("Failed to load OIDC information for registration with identity: %v", err)
 			return
 		}
 
 		resp, err := client.PostForm(oidcConfig.Endpoint.UserInfoURL, payload)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error making request to user info endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error making request to user info endpoint")
 			log.Errorf("Failed to execute post form to user info endpoint %s: %v", oidcConfig.Endpoint.UserInfoURL, err)
 			return
 		}
@@ -374,14 +457,14 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 		// Check the status code
 		if resp.StatusCode != 200 {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server received non-200 status from user info endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server received non-200 status from user info endpoint")
 			log.Errorf("The user info endpoint %s responded with status code %d", oidcConfig.Endpoint.UserInfoURL, resp.StatusCode)
 			return
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Server encountered an error reading response from user info endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Server encountered an error reading response from user info endpoint")
 			log.Errorf("Failed to read body from user info endpoint %s: %v", oidcConfig.Endpoint.UserInfoURL, err)
 			return
 		}
@@ -389,7 +472,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 		reqData.Identity = string(body)
 		err = keySignChallenge(ctx, &reqData, "register")
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error during key-sign challenge: " + err.Error()})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error during key-sign challenge: "+err.Error())
 			log.Warningf("Failed to complete key sign challenge with identity requirement: %v", err)
 		}
 		return
@@ -398,7 +481,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 	if reqData.IdentityRequired == "false" || reqData.IdentityRequired == "" {
 		err := keySignChallenge(ctx, &reqData, "register")
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error during key-sign challenge: " + err.Error()})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error during key-sign challenge: "+err.Error())
 			log.Warningf("Failed to complete key sign challenge without identity requirement: %v", err)
 		}
 		return
@@ -406,7 +489,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 	oidcConfig, err := oauth2.ServerOIDCClient()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server has malformed OIDC configuration"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server has malformed OIDC configuration")
 		log.Errorf("Failed to load OIDC information for registration with identity: %v", err)
 		return
 	}
@@ -420,7 +503,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 		response, err := client.PostForm(oidcConfig.Endpoint.DeviceAuthURL, payload)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered error requesting device code"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered error requesting device code")
 			log.Errorf("Failed to execute post form to device auth endpoint %s: %v", oidcConfig.Endpoint.DeviceAuthURL, err)
 			return
 		}
@@ -428,20 +511,20 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 		// Check the response code
 		if response.StatusCode != 200 {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server received non-200 status code from OIDC device auth endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server received non-200 status code from OIDC device auth endpoint")
 			log.Errorf("The device auth endpoint %s responded with status code %d", oidcConfig.Endpoint.DeviceAuthURL, response.StatusCode)
 			return
 		}
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered error reading response from device auth endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered error reading response from device auth endpoint")
 			log.Errorf("Failed to read body from device auth endpoint %s: %v", oidcConfig.Endpoint.DeviceAuthURL, err)
 			return
 		}
 		var res Response
 		err = json.Unmarshal(body, &res)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server could not parse response from device auth endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server could not parse response from device auth endpoint")
 			log.Errorf("Failed to unmarshal body from device auth endpoint %s: %v", oidcConfig.Endpoint.DeviceAuthURL, err)
 			return
 		}
@@ -462,7 +545,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 
 		response, err := client.PostForm(oidcConfig.Endpoint.TokenURL, payload)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error while making request to token endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error while making request to token endpoint")
 			log.Errorf("Failed to execute post form to token endpoint %s: %v", oidcConfig.Endpoint.TokenURL, err)
 			return
 		}
@@ -471,14 +554,14 @@ func cliRegisterNamespace(ctx *gin.Context) {
 		// Check the status code
 		// We accept either a 200, or a 400.
 		if response.StatusCode != 200 && response.StatusCode != 400 {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server received bad status code from token endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server received bad status code from token endpoint")
 			log.Errorf("The token endpoint %s responded with status code %d", oidcConfig.Endpoint.TokenURL, response.StatusCode)
 			return
 		}
 
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error reading response from token endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error reading response from token endpoint")
 			log.Errorf("Failed to read body from token endpoint %s: %v", oidcConfig.Endpoint.TokenURL, err)
 			return
 		}
@@ -486,7 +569,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 		var tokenResponse TokenResponse
 		err = json.Unmarshal(body, &tokenResponse)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server could not parse error from token endpoint"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server could not parse error from token endpoint")
 			log.Errorf("Failed to unmarshal body from token endpoint %s: %v", oidcConfig.Endpoint.TokenURL, err)
 			return
 		}
@@ -499,7 +582,7 @@ func cliRegisterNamespace(ctx *gin.Context) {
 					"status": "PENDING",
 				})
 			} else {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered unknown error waiting for token"})
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered unknown error waiting for token")
 				log.Errorf("Token endpoint did not provide a token, and responded with unkown error: %s", string(body))
 				return
 			}
@@ -549,19 +632,19 @@ func deleteNamespaceHandler(ctx *gin.Context) {
 	prefix := ctx.Param("wildcard")
 	log.Debug("Attempting to delete namespace prefix ", prefix)
 	if prefix == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required to delete"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, "prefix is required to delete")
 		return
 	}
 
 	// Check if prefix exists before trying to delete it
 	exists, err := namespaceExists(prefix)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error checking if namespace already exists"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error checking if namespace already exists")
 		log.Errorf("Failed to check if the namespace already exists: %v", err)
 		return
 	}
 	if !exists {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "the prefix does not exist so it cannot be deleted"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeNamespaceNotFound, "the prefix does not exist so it cannot be deleted")
 		log.Errorln("prefix could not be deleted because it does not exist")
 	}
 
@@ -576,15 +659,15 @@ func deleteNamespaceHandler(ctx *gin.Context) {
 	// Have the token, now we need to load the JWKS for the prefix
 	originJwks, _, err := getNamespaceJwksByPrefix(prefix)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error loading the prefix's stored jwks"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error loading the prefix's stored jwks")
 		log.Errorf("Failed to get prefix's stored jwks: %v", err)
 		return
 	}
 
 	// Use the JWKS to verify the token -- verification means signature integrity
-	parsed, err := jwt.Parse([]byte(delTokenStr), jwt.WithKeySet(originJwks))
+	parsed, err := jwt.Parse([]byte(delTokenStr), jwt.WithKeySet(originJwks), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server could not verify/parse the provided deletion token"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server could not verify/parse the provided deletion token")
 		log.Errorf("Failed to parse the token: %v", err)
 		return
 	}
@@ -612,8 +695,8 @@ func deleteNamespaceHandler(ctx *gin.Context) {
 		}
 		return jwt.NewValidationError(errors.New("Token does not contain namespace deletion authorization"))
 	})
-	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server could not validate the provided deletion token"})
+	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration())); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server could not validate the provided deletion token")
 		log.Errorf("Failed to validate the token: %v", err)
 		return
 	}
@@ -621,7 +704,7 @@ func deleteNamespaceHandler(ctx *gin.Context) {
 	// If we get to this point in the code, we've passed all the security checks and we're ready to delete
 	err = deleteNamespace(prefix)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error deleting namespace from database"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error deleting namespace from database")
 		log.Errorf("Failed to delete namespace from database: %v", err)
 		return
 	}
@@ -629,29 +712,148 @@ func deleteNamespaceHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
-/**
- * Commenting out until we're ready to use it.  -BB
-func cliListNamespaces(c *gin.Context) {
-	prefix := c.Param("prefix")
-	log.Debugf("Trying to get namespace data for prefix %s", prefix)
-	ns, err := getNamespace(prefix)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// Check if a namespace matches the filters from a list request. An empty filter matches all.
+func (req cliListNamespacesReq) matches(ns *Namespace) bool {
+	if req.Status != "" {
+		status := ns.AdminMetadata.Status
+		// Legacy registrations without admin metadata have an empty status
+		if status == "" {
+			status = Unknown
+		}
+		if status != RegistrationStatus(req.Status) {
+			return false
+		}
 	}
-
-	c.JSON(http.StatusOK, ns)
+	if req.Prefix != "" {
+		filterPrefix := strings.TrimSuffix(req.Prefix, "/")
+		if ns.Prefix != filterPrefix && !strings.HasPrefix(ns.Prefix, filterPrefix+"/") {
+			return false
+		}
+	}
+	if req.Institution != "" && ns.AdminMetadata.Institution != req.Institution {
+		return false
+	}
+	isCache := strings.HasPrefix(ns.Prefix, "/caches/")
+	if req.ServerType == string(CacheType) && !isCache {
+		return false
+	}
+	if req.ServerType == string(OriginType) && isCache {
+		return false
+	}
+	return true
 }
-*/
 
-func getAllNamespacesHandler(ctx *gin.Context) {
+// List the registered namespaces, optionally filtered by registration status,
+// prefix, institution, and server type. The prefix filter matches the prefix
+// itself and any namespaces nested under it.
+//
+// Like the web UI's list, only approved namespaces are listed unless the request
+// carries a token issued by the registry with the web_ui.access scope, either as
+// a bearer token or as the login cookie.
+//
+// GET /api/v1.0/registry?status=Approved&prefix=/foo&institution=<id>&server_type=origin
+func cliListNamespaces(ctx *gin.Context) {
+	req := cliListNamespacesReq{}
+	if ctx.ShouldBindQuery(&req) != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
+		return
+	}
+	authOption := utils.AuthOption{
+		Sources: []utils.TokenSource{utils.Header, utils.Cookie},
+		Issuers: []utils.TokenIssuer{utils.Issuer},
+		Scopes:  []string{token_scopes.WebUi_Access.String()},
+	}
+	if !utils.CheckAnyAuth(ctx, authOption) {
+		// Tell clients their token is rejected rather than silently listing fewer namespaces
+		if ctx.GetHeader("Authorization") != "" {
+			web_ui.WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeInvalidToken, "The token is invalid, wasn't issued by the registry, or lacks the web_ui.access scope")
+			return
+		}
+		if req.Status != "" && req.Status != Approved.String() {
+			web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "You don't have permission to filter non-approved namespace registrations")
+			return
+		}
+		req.Status = Approved.String()
+	}
+	if req.Status != "" && !IsValidRegStatus(req.Status) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: status must be one of 'Pending', 'Approved', 'Denied', 'Unknown'")
+		return
+	}
+	if req.ServerType != "" && req.ServerType != string(OriginType) && req.ServerType != string(CacheType) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid server type")
+		return
+	}
+
 	nss, err := getAllNamespaces()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error trying to list all namespaces"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error trying to list all namespaces")
 		log.Errorln("Failed to get all namespaces: ", err)
 		return
 	}
-	ctx.JSON(http.StatusOK, nss)
+	filtered := make([]*Namespace, 0, len(nss))
+	for _, ns := range nss {
+		if req.matches(ns) {
+			filtered = append(filtered, ns)
+		}
+	}
+	ctx.JSON(http.StatusOK, filtered)
+}
+
+// Search the registered namespaces by prefix for the web UI typeahead and the CLI.
+// By default, namespaces whose prefix starts with q match; with match=substring,
+// namespaces containing q anywhere in their prefix (ignoring case) match.
+//
+// GET /api/v1.0/registry/search?q=/foo&match=prefix&status=Approved&server_type=origin&page=1&page_size=20
+func searchNamespacesHandler(ctx *gin.Context) {
+	req := searchNamespacesReq{}
+	if ctx.ShouldBindQuery(&req) != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
+		return
+	}
+	if req.Status != "" && !IsValidRegStatus(req.Status) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: status must be one of 'Pending', 'Approved', 'Denied', 'Unknown'")
+		return
+	}
+	if req.ServerType != "" && req.ServerType != string(OriginType) && req.ServerType != string(CacheType) {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid server type")
+		return
+	}
+	if req.Match != "" && req.Match != "prefix" && req.Match != "substring" {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: match must be one of 'prefix', 'substring'")
+		return
+	}
+	if req.Page < 0 || req.PageSize < 0 {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: page and page_size must be positive")
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = defaultSearchPageSize
+	} else if req.PageSize > maxSearchPageSize {
+		req.PageSize = maxSearchPageSize
+	}
+
+	substring := req.Match == "substring"
+	query := strings.TrimSpace(req.Query)
+	// Every prefix starts with a slash, so users can leave it out while typing
+	if !substring && query != "" && !strings.HasPrefix(query, "/") {
+		query = "/" + query
+	}
+
+	nss, total, err := searchNamespaces(query, substring, RegistrationStatus(req.Status), ServerType(req.ServerType), req.PageSize, (req.Page-1)*req.PageSize)
+	if err != nil {
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error trying to search namespaces")
+		log.Errorln("Failed to search namespaces:", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, NamespaceSearchResult{
+		Namespaces: excludePubKey(nss),
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+	})
 }
 
 // Gin requires no wildcard match and exact match fall under the same
@@ -665,6 +867,23 @@ func wildcardHandler(ctx *gin.Context) {
 	// new / here!
 	path := ctx.Param("wildcard")
 
+	if path == "/search" {
+		searchNamespacesHandler(ctx)
+		return
+	}
+	if path == "/snapshot" {
+		namespaceSnapshotHandler(ctx)
+		return
+	}
+	if path == "/snapshot/keys" {
+		namespaceSnapshotKeysHandler(ctx)
+		return
+	}
+	if path == "/revocations" {
+		keyRevocationFeedHandler(ctx)
+		return
+	}
+
 	// Get the prefix's JWKS
 	// Avoid using filepath.Base for path matching, as filepath format depends on OS
 	// while HTTP path is always slash (/)
@@ -673,17 +892,17 @@ func wildcardHandler(ctx *gin.Context) {
 		found, err := namespaceExistsByPrefix(prefix)
 		if err != nil {
 			log.Error("Error checking if prefix ", prefix, " exists: ", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error trying to check if the namespace exists"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error trying to check if the namespace exists")
 			return
 		}
 		if !found {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("namespace prefix '%s', was not found", prefix)})
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, fmt.Sprintf("namespace prefix '%s', was not found", prefix))
 			return
 		}
 
 		jwks, adminMetadata, err := getNamespaceJwksByPrefix(prefix)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server encountered an error trying to get jwks for prefix"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "server encountered an error trying to get jwks for prefix")
 			log.Errorf("Failed to load jwks for prefix %s: %v", prefix, err)
 			return
 		}
@@ -691,13 +910,13 @@ func wildcardHandler(ctx *gin.Context) {
 			if strings.HasPrefix(prefix, "/caches/") { // Caches
 				if param.Registry_RequireCacheApproval.GetBool() {
 					// Use 403 to distinguish between server error
-					ctx.JSON(http.StatusForbidden, gin.H{"error": "The cache has not been approved by federation administrator"})
+					web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeNotApproved, "The cache has not been approved by federation administrator")
 					return
 				}
 			} else { // Origins
 				if param.Registry_RequireOriginApproval.GetBool() {
 					// Use 403 to distinguish between server error
-					ctx.JSON(http.StatusForbidden, gin.H{"error": "The origin has not been approved by federation administrator"})
+					web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeNotApproved, "The origin has not been approved by federation administrator")
 					return
 				}
 			}
@@ -709,12 +928,12 @@ func wildcardHandler(ctx *gin.Context) {
 		prefix := strings.TrimSuffix(path, "/.well-known/openid-configuration")
 		exists, err := namespaceExists(prefix)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Server encountered an error while checking if the prefix exists"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Server encountered an error while checking if the prefix exists")
 			log.Errorf("Error while checking for existence of prefix %s: %v", prefix, err)
 			return
 		}
 		if !exists {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("The requested prefix %s does not exist in the registry's database", prefix)})
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, fmt.Sprintf("The requested prefix %s does not exist in the registry's database", prefix))
 		}
 		// Construct the openid-configuration JSON and return to the requester
 		// For a given namespace "foo", the jwks should be located at <registry url>/api/v1.0/registry/foo/.well-known/issuer.jwks
@@ -749,37 +968,37 @@ func checkNamespaceExistsHandler(ctx *gin.Context) {
 	req := checkNamespaceExistsReq{}
 	if err := ctx.ShouldBind(&req); err != nil {
 		log.Debug("Failed to parse request body for namespace exits check: ", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Failed to parse request body")
 		return
 	}
 	if req.Prefix == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, "prefix is required")
 		return
 	}
 	if req.PubKey == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "pubkey is required"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, "pubkey is required")
 		return
 	}
 	jwksReq, err := jwk.ParseString(req.PubKey)
 	if err != nil {
 		log.Debug("pubkey is not a valid JWK string:", req.PubKey, err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pubkey is not a valid JWK string: %s", req.PubKey)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, fmt.Sprintf("pubkey is not a valid JWK string: %s", req.PubKey))
 		return
 	}
 	if jwksReq.Len() != 1 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pubkey is a jwks with multiple or zero key: %s", req.PubKey)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, fmt.Sprintf("pubkey is a jwks with multiple or zero key: %s", req.PubKey))
 		return
 	}
 	jwkReq, exists := jwksReq.Key(0)
 	if !exists {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the first key from the pubkey does not exist: %s", req.PubKey)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, fmt.Sprintf("the first key from the pubkey does not exist: %s", req.PubKey))
 		return
 	}
 
 	found, err := namespaceExistsByPrefix(req.Prefix)
 	if err != nil {
 		log.Debugln("Failed to check if namespace exists by prefix", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check if the namespace exists"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to check if the namespace exists")
 		return
 	}
 	if !found {
@@ -792,7 +1011,7 @@ func checkNamespaceExistsHandler(ctx *gin.Context) {
 	// Just to check if the key matches. We don't care about approval status
 	jwksDb, _, err := getNamespaceJwksByPrefix(req.Prefix)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, err.Error())
 		return
 	}
 
@@ -816,39 +1035,43 @@ func checkNamespaceStatusHandler(ctx *gin.Context) {
 	req := checkStatusReq{}
 	if err := ctx.ShouldBind(&req); err != nil {
 		log.Debug("Failed to parse request body for namespace status check: ", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Failed to parse request body")
 		return
 	}
 	if req.Prefix == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, "prefix is required")
 		return
 	}
 	ns, err := getNamespaceByPrefix(req.Prefix)
 	if err != nil || ns == nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting namespace"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespace")
 		return
 	}
 	emptyMetadata := AdminMetadata{}
+	// The origin enforces the write mode, so it's reported whatever the approval status;
+	// so is the cache policy, which the origin passes on to the caches
+	writeMode := ns.AdminMetadata.WriteMode
+	cachePolicy := ns.AdminMetadata.CachePolicy()
 	// If Registry.RequireCacheApproval or Registry.RequireOriginApproval is false
 	// we return Approved == true
 	if ns.AdminMetadata != emptyMetadata {
 		// Caches
 		if strings.HasPrefix(req.Prefix, "/caches") && param.Registry_RequireCacheApproval.GetBool() {
-			res := checkStatusRes{Approved: ns.AdminMetadata.Status == Approved}
+			res := checkStatusRes{Approved: ns.AdminMetadata.Status == Approved, WriteMode: writeMode, CachePolicy: cachePolicy}
 			ctx.JSON(http.StatusOK, res)
 			return
 		} else if !param.Registry_RequireCacheApproval.GetBool() {
-			res := checkStatusRes{Approved: true}
+			res := checkStatusRes{Approved: true, WriteMode: writeMode, CachePolicy: cachePolicy}
 			ctx.JSON(http.StatusOK, res)
 			return
 		} else {
 			// Origins
 			if param.Registry_RequireOriginApproval.GetBool() {
-				res := checkStatusRes{Approved: ns.AdminMetadata.Status == Approved}
+				res := checkStatusRes{Approved: ns.AdminMetadata.Status == Approved, WriteMode: writeMode, CachePolicy: cachePolicy}
 				ctx.JSON(http.StatusOK, res)
 				return
 			} else {
-				res := checkStatusRes{Approved: true}
+				res := checkStatusRes{Approved: true, WriteMode: writeMode, CachePolicy: cachePolicy}
 				ctx.JSON(http.StatusOK, res)
 				return
 			}
@@ -867,13 +1090,79 @@ func RegisterRegistryAPI(router *gin.RouterGroup) {
 	// It will cause duplicated route error. Use wildcardHandler to handle such
 	// routing if needed.
 	{
-		registryAPI.POST("", cliRegisterNamespace)
-		registryAPI.GET("", getAllNamespacesHandler)
+		web_ui.HandleAPI(registryAPI, http.MethodPost, "", web_ui.APIDoc{
+			Summary:     "Register a namespace",
+			Description: "The registration is a key-sign challenge in two requests, proving the caller holds the private key of the namespace's public key",
+			Responses:   map[int]string{http.StatusOK: "The challenge", http.StatusCreated: "The namespace was registered"},
+			Request:     registrationData{},
+		}, cliRegisterNamespace)
+		web_ui.HandleAPI(registryAPI, http.MethodGet, "", web_ui.APIDoc{
+			Summary: "List the registered namespaces",
+			Query: map[string]string{
+				"status":      `Only list namespaces with this status, e.g. "Approved"`,
+				"prefix":      "Only list namespaces under this prefix",
+				"institution": "Only list namespaces of the institution with this ID",
+				"server_type": `Only list namespaces of this server type, "origin" or "cache"`,
+			},
+			Response: []Namespace{},
+		}, cliListNamespaces)
 
 		// Handle everything under "/" route with GET method
-		registryAPI.GET("/*wildcard", wildcardHandler)
-		registryAPI.POST("/checkNamespaceExists", checkNamespaceExistsHandler)
-		registryAPI.POST("/checkNamespaceStatus", checkNamespaceStatusHandler)
-		registryAPI.DELETE("/*wildcard", deleteNamespaceHandler)
+		web_ui.HandleAPI(registryAPI, http.MethodGet, "/*wildcard", web_ui.APIDoc{
+			Summary:     "Return the public keys or the OpenID configuration of a namespace",
+			Description: "The path is the namespace prefix followed by /.well-known/issuer.jwks or /.well-known/openid-configuration",
+			Responses: map[int]string{
+				http.StatusOK:        "OK",
+				http.StatusForbidden: "The namespace is not approved",
+				http.StatusNotFound:  "The namespace is not registered",
+			},
+		}, wildcardHandler)
+		// Routes handled by wildcardHandler
+		web_ui.DocumentAPI(http.MethodGet, "/api/v1.0/registry/search", web_ui.APIDoc{
+			Summary: "Search the registered namespaces, one page at a time",
+			Query: map[string]string{
+				"q":           "The prefix or substring of the namespaces to find",
+				"match":       `How to match q, "prefix" (the default) or "substring"`,
+				"status":      `Only find namespaces with this status, e.g. "Approved"`,
+				"server_type": `Only find namespaces of this server type, "origin" or "cache"`,
+				"page":        "The page of results to return, starting at 1",
+				"page_size":   "The number of results per page",
+			},
+			QueryTypes: map[string]string{"page": "integer", "page_size": "integer"},
+			Response:   NamespaceSearchResult{},
+		})
+		web_ui.DocumentAPI(http.MethodGet, "/api/v1.0/registry/snapshot", web_ui.APIDoc{
+			Summary: "Return a signed snapshot of every registered namespace, as a compact JWS",
+		})
+		web_ui.DocumentAPI(http.MethodGet, "/api/v1.0/registry/snapshot/keys", web_ui.APIDoc{
+			Summary: "Return the public keys verifying the namespace snapshots",
+		})
+		web_ui.DocumentAPI(http.MethodGet, "/api/v1.0/registry/revocations", web_ui.APIDoc{
+			Summary:    "Return the feed of namespace key revocations, oldest first",
+			Query:      map[string]string{"after": "Only return the revocations after the one with this ID"},
+			QueryTypes: map[string]string{"after": "integer"},
+			Response:   []KeyRevocation{},
+		})
+
+		web_ui.HandleAPI(registryAPI, http.MethodPost, "/checkNamespaceExists", web_ui.APIDoc{
+			Summary:  "Check if a namespace prefix is registered with the given public key",
+			Request:  checkNamespaceExistsReq{},
+			Response: checkNamespaceExistsRes{},
+		}, checkNamespaceExistsHandler)
+		web_ui.HandleAPI(registryAPI, http.MethodPost, "/checkNamespaceStatus", web_ui.APIDoc{
+			Summary:  "Check if a namespace prefix was approved by a registry administrator and get its write mode",
+			Request:  checkStatusReq{},
+			Response: checkStatusRes{},
+		}, checkNamespaceStatusHandler)
+		web_ui.HandleAPI(registryAPI, http.MethodPost, "/updateNamespaceKey", web_ui.APIDoc{
+			Summary:     "Replace the public keys of a namespace",
+			Description: `The bearer token is signed by one of the namespace's registered keys and holds the new JWKS in its "pubkey" claim`,
+			Auth:        web_ui.APIAuthBearer,
+			Request:     updateNamespaceKeyReq{},
+		}, updateNamespaceKeyHandler)
+		web_ui.HandleAPI(registryAPI, http.MethodDelete, "/*wildcard", web_ui.APIDoc{
+			Summary: "Delete the namespace registration of the prefix",
+			Auth:    web_ui.APIAuthBearer,
+		}, deleteNamespaceHandler)
 	}
 }
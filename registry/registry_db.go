@@ -36,7 +36,9 @@ import (
 	// _ "github.com/mattn/go-sqlite3" // SQLite driver
 	_ "modernc.org/sqlite"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/events"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/utils"
 )
@@ -64,6 +66,9 @@ type AdminMetadata struct {
 	SiteName              string             `json:"site_name"`
 	Institution           string             `json:"institution" validate:"required"` // the unique identifier of the institution
 	SecurityContactUserID string             `json:"security_contact_user_id"`        // "sub" claim of user who is responsible for taking security concern
+	WriteMode             common.WriteMode   `json:"write_mode"`                      // Whether the namespace's objects may be overwritten and deleted; enforced by its origin
+	CacheMaxAge           int                `json:"cache_max_age"`                   // The longest time, in seconds, caches may keep the namespace's objects; 0 for no limit
+	CacheMustRevalidate   bool               `json:"cache_must_revalidate"`           // Whether caches must not serve the namespace's objects without checking with the origin
 	Status                RegistrationStatus `json:"status" post:"exclude"`
 	ApproverID            string             `json:"approver_id" post:"exclude"` // "sub" claim of user JWT who approved registration
 	ApprovedAt            time.Time          `json:"approved_at" post:"exclude"`
@@ -103,14 +108,17 @@ const (
 )
 
 /*
-Declare the DB handle as an unexported global so that all
+Declare the DB connection pool as an unexported global so that all
 functions in the package can access it without having to
 pass it around. This simplifies the HTTP handlers, and
-the handle is already thread-safe! The approach being used
+the pool is thread-safe! The approach being used
 is based off of 1.b from
 https://www.alexedwards.net/blog/organising-database-access
+
+The pool is only set by InitializeDB; all queries go through it
+so they are bounded by Registry.DbQueryTimeout and instrumented.
 */
-var db *sql.DB
+var db *dbPool
 
 func (st ServerType) String() string {
 	return string(st)
@@ -137,6 +145,15 @@ func (a AdminMetadata) Equal(b AdminMetadata) bool {
 		a.UpdatedAt.Equal(b.UpdatedAt)
 }
 
+// The cache policy set in the registration, passed on to caches through the namespace's
+// advertisement; nil if the registration leaves it to the caches
+func (a AdminMetadata) CachePolicy() *common.CachePolicy {
+	if a.CacheMaxAge <= 0 && !a.CacheMustRevalidate {
+		return nil
+	}
+	return &common.CachePolicy{MaxAge: int64(a.CacheMaxAge), MustRevalidate: a.CacheMustRevalidate}
+}
+
 func IsValidRegStatus(s string) bool {
 	return s == "Pending" || s == "Approved" || s == "Denied" || s == "Unknown"
 }
@@ -199,6 +216,23 @@ func createNamespaceTable() {
 		log.Info("Column 'custom_fields' already exists.")
 	}
 
+	createNamespaceIndexes()
+}
+
+// The registration status of a namespace, as stored in its admin_metadata. Legacy
+// registrations have an empty admin_metadata, which isn't valid JSON. The metadata is
+// written as a blob, which the JSON functions only accept once cast to text.
+//
+// The expression must match the one in namespace_status_idx exactly for SQLite to use the index
+const namespaceStatusExpr = `(CASE WHEN json_valid(CAST(admin_metadata AS TEXT)) THEN json_extract(CAST(admin_metadata AS TEXT), '$.status') ELSE '' END)`
+
+// Create the indexes used by namespace search. Prefix matches use the index
+// SQLite creates for the UNIQUE constraint on prefix.
+func createNamespaceIndexes() {
+	query := `CREATE INDEX IF NOT EXISTS namespace_status_idx ON namespace ` + namespaceStatusExpr + `;`
+	if _, err := db.Exec(query); err != nil {
+		log.Fatalf("Failed to create namespace status index: %v", err)
+	}
 }
 
 func createTopologyTable() {
@@ -367,10 +401,11 @@ func namespaceBelongsToUserId(id int, userId string) (bool, error) {
 	return false, nil
 }
 
+// Get the public keys of the namespace, leaving out its revoked keys
 func getNamespaceJwksById(id int) (jwk.Set, error) {
-	jwksQuery := `SELECT pubkey FROM namespace WHERE id = ?`
-	var pubkeyStr string
-	err := db.QueryRow(jwksQuery, id).Scan(&pubkeyStr)
+	jwksQuery := `SELECT prefix, pubkey FROM namespace WHERE id = ?`
+	var prefix, pubkeyStr string
+	err := db.QueryRow(jwksQuery, id).Scan(&prefix, &pubkeyStr)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("prefix not found in database")
@@ -383,9 +418,10 @@ func getNamespaceJwksById(id int) (jwk.Set, error) {
 		return nil, errors.Wrap(err, "Failed to parse pubkey as a jwks")
 	}
 
-	return set, nil
+	return excludeRevokedKeys(prefix, set)
 }
 
+// Get the public keys and admin metadata of the namespace, leaving out its revoked keys
 func getNamespaceJwksByPrefix(prefix string) (jwk.Set, *AdminMetadata, error) {
 	var pubkeyStr string
 	var adminMetadataStr string
@@ -412,6 +448,9 @@ func getNamespaceJwksByPrefix(prefix string) (jwk.Set, *AdminMetadata, error) {
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "Failed to parse pubkey as a jwks")
 	}
+	if set, err = excludeRevokedKeys(prefix, set); err != nil {
+		return nil, nil, err
+	}
 
 	return set, &adminMetadata, nil
 }
@@ -614,6 +653,85 @@ func getNamespacesByFilter(filterNs Namespace, serverType ServerType) ([]*Namesp
 	return namespaces, nil
 }
 
+// The smallest string greater than every string starting with prefix, so that
+// `prefix >= ? AND prefix < ?` matches by prefix using the index on prefix
+func prefixUpperBound(prefix string) string {
+	bound := []byte(prefix)
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] < 0xff {
+			bound[i]++
+			return string(bound[:i+1])
+		}
+	}
+	// Only reachable for an empty prefix or one made of 0xff bytes; nothing sorts after it
+	return ""
+}
+
+// Search the namespaces by prefix. If substring is true, namespaces containing
+// query anywhere in their prefix (ignoring case) match; otherwise, namespaces whose
+// prefix starts with query match. Results are sorted by prefix and paginated with
+// limit and offset. Returns the page of namespaces and the total number of matches.
+func searchNamespaces(query string, substring bool, status RegistrationStatus, serverType ServerType, limit int, offset int) ([]*Namespace, int, error) {
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	if query != "" {
+		if substring {
+			where += ` AND instr(lower(prefix), lower(?)) > 0`
+			args = append(args, query)
+		} else if upper := prefixUpperBound(query); upper != "" {
+			where += ` AND prefix >= ? AND prefix < ?`
+			args = append(args, query, upper)
+		} else {
+			where += ` AND prefix >= ?`
+			args = append(args, query)
+		}
+	}
+	if status == Unknown {
+		where += ` AND (` + namespaceStatusExpr + ` IS NULL OR ` + namespaceStatusExpr + ` IN ('', ?))`
+		args = append(args, Unknown.String())
+	} else if status != "" {
+		where += ` AND ` + namespaceStatusExpr + ` = ?`
+		args = append(args, status.String())
+	}
+	if serverType == CacheType {
+		// Refer to the cache prefix name in cmd/cache_serve
+		where += ` AND prefix LIKE '/caches/%'`
+	} else if serverType == OriginType {
+		where += ` AND NOT prefix LIKE '/caches/%'`
+	} else if serverType != "" {
+		return nil, 0, errors.New(fmt.Sprint("Can't search namespaces: unsupported server type: ", serverType))
+	}
+
+	total := 0
+	if err := db.QueryRow(`SELECT COUNT(*) FROM namespace`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT id, prefix, pubkey, identity, admin_metadata FROM namespace`+where+` ORDER BY prefix ASC LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	namespaces := make([]*Namespace, 0)
+	for rows.Next() {
+		ns := &Namespace{}
+		adminMetadataStr := ""
+		if err := rows.Scan(&ns.ID, &ns.Prefix, &ns.Pubkey, &ns.Identity, &adminMetadataStr); err != nil {
+			return nil, 0, err
+		}
+		// For backward compatibility, if adminMetadata is an empty string, don't unmarshal json
+		if adminMetadataStr != "" {
+			if err := json.Unmarshal([]byte(adminMetadataStr), &ns.AdminMetadata); err != nil {
+				return nil, 0, err
+			}
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, total, rows.Err()
+}
+
 /*
 Some generic functions for CRUD actions on namespaces,
 used BY the registry (as opposed to the parallel
@@ -737,7 +855,13 @@ func updateNamespaceStatusById(id int, status RegistrationStatus, approverId str
 		}
 		return errors.Wrap(err, "Failed to execute update query")
 	}
-	return tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	if status == Approved {
+		events.Publish(events.NamespaceApproved{ID: ns.ID, Prefix: ns.Prefix, ApprovedBy: approverId})
+	}
+	return nil
 }
 
 func deleteNamespace(prefix string) error {
@@ -820,12 +944,21 @@ func InitializeDB(ctx context.Context) error {
 
 	dbName := "file:" + dbPath + "?_busy_timeout=5000&_journal_mode=WAL"
 	log.Debugln("Opening connection to sqlite DB", dbName)
-	db, err = sql.Open("sqlite", dbName)
+	sqlDB, err := sql.Open("sqlite", dbName)
 	if err != nil {
 		return errors.Wrapf(err, "Failed to open the database with path: %s", dbPath)
 	}
+	pool := newDBPool(sqlDB)
+	if err = pool.configure(); err != nil {
+		sqlDB.Close()
+		return err
+	}
+	db = pool
 
 	createNamespaceTable()
+	createNamespaceTransferTable()
+	createKeyRevocationTable()
+	createTermsAcceptanceTable()
 	return db.Ping()
 }
 
@@ -851,6 +984,9 @@ func modifyTopologyTable(prefixes []string, mode string) error {
 
 	stmt, err := tx.Prepare(query)
 	if err != nil {
+		if errRoll := tx.Rollback(); errRoll != nil {
+			log.Errorln("Failed to rollback transaction:", errRoll)
+		}
 		return err
 	}
 	defer stmt.Close()
@@ -43,10 +43,13 @@ import (
 
 func setupMockRegistryDB(t *testing.T) {
 	mockDB, err := sql.Open("sqlite", ":memory:")
-	db = mockDB
 	require.NoError(t, err, "Error setting up mock namespace DB")
+	db = newDBPool(mockDB)
 	createNamespaceTable()
 	createTopologyTable()
+	createNamespaceTransferTable()
+	createKeyRevocationTable()
+	createTermsAcceptanceTable()
 }
 
 func resetNamespaceDB(t *testing.T) {
@@ -877,3 +880,69 @@ func TestRegistryTopology(t *testing.T) {
 
 	viper.Reset()
 }
+
+func TestSearchNamespaces(t *testing.T) {
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	err := insertMockDBData([]Namespace{
+		mockNamespace("/foo/bar", "pubkey1", "", AdminMetadata{Status: Approved}),
+		mockNamespace("/foo/baz", "pubkey2", "", AdminMetadata{Status: Pending}),
+		mockNamespace("/foobar", "pubkey3", "", AdminMetadata{Status: Approved}),
+		mockNamespace("/other/FOO", "pubkey4", "", AdminMetadata{Status: Denied}),
+		mockNamespace("/caches/foo-cache", "pubkey5", "", AdminMetadata{Status: Approved}),
+	})
+	require.NoError(t, err)
+	// A legacy registration without admin metadata
+	_, err = db.Exec(`INSERT INTO namespace (prefix, pubkey, identity, admin_metadata) VALUES ('/foo/legacy', 'pubkey6', '', '')`)
+	require.NoError(t, err)
+
+	prefixes := func(nss []*Namespace) []string {
+		result := []string{}
+		for _, ns := range nss {
+			result = append(result, ns.Prefix)
+		}
+		return result
+	}
+
+	t.Run("prefix-match", func(t *testing.T) {
+		nss, total, err := searchNamespaces("/foo", false, "", "", 20, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 4, total)
+		assert.Equal(t, []string{"/foo/bar", "/foo/baz", "/foo/legacy", "/foobar"}, prefixes(nss))
+	})
+
+	t.Run("substring-match", func(t *testing.T) {
+		nss, total, err := searchNamespaces("foo", true, "", "", 20, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 6, total)
+		assert.Contains(t, prefixes(nss), "/other/FOO")
+	})
+
+	t.Run("status-and-server-type-filters", func(t *testing.T) {
+		nss, _, err := searchNamespaces("/", false, Approved, OriginType, 20, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/foo/bar", "/foobar"}, prefixes(nss))
+
+		nss, _, err = searchNamespaces("/", false, Unknown, "", 20, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/foo/legacy"}, prefixes(nss))
+
+		nss, _, err = searchNamespaces("", false, "", CacheType, 20, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/caches/foo-cache"}, prefixes(nss))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		nss, total, err := searchNamespaces("/foo", false, "", "", 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 4, total)
+		assert.Equal(t, []string{"/foo/legacy", "/foobar"}, prefixes(nss))
+	})
+
+	t.Run("status-index-exists", func(t *testing.T) {
+		var name string
+		err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'index' AND name = 'namespace_status_idx'`).Scan(&name)
+		require.NoError(t, err)
+	})
+}
@@ -1,10 +1,17 @@
 package registry
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +19,8 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/web_ui"
 )
 
 func TestHandleWildcard(t *testing.T) {
@@ -143,3 +152,54 @@ func TestHandleWildcard(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryAPIDocumented(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	// The web API needs the session secret for its CSRF handler
+	secretFile := filepath.Join(t.TempDir(), "session-secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("secret"), 0600))
+	viper.Set("Server.SessionSecretFile", secretFile)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	RegisterRegistryAPI(engine.Group("/"))
+	require.NoError(t, RegisterRegistryWebAPI(engine.Group("/")))
+
+	assert.Empty(t, web_ui.UndocumentedRoutes(engine.Routes()))
+}
+
+func TestKeySignChallengeSignatures(t *testing.T) {
+	payload := []byte("client-nonce" + "server-nonce")
+	newECDSA := func(curve elliptic.Curve) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) { return ecdsa.GenerateKey(curve, rand.Reader) }
+	}
+	for name, generate := range map[string]func() (crypto.Signer, error){
+		"P-256":    newECDSA(elliptic.P256()),
+		"P-384":    newECDSA(elliptic.P384()),
+		"P-521":    newECDSA(elliptic.P521()),
+		"RSA-2048": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			key, err := generate()
+			require.NoError(t, err)
+			signature, err := signPayload(payload, key)
+			require.NoError(t, err)
+
+			assert.True(t, verifySignature(payload, signature, key.Public()))
+			assert.False(t, verifySignature([]byte("tampered"), signature, key.Public()))
+
+			// A signature doesn't verify against a different key
+			other, err := generate()
+			require.NoError(t, err)
+			assert.False(t, verifySignature(payload, signature, other.Public()))
+		})
+	}
+
+	// Keys of an unsupported kind are rejected rather than trusted
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	_, err = signPayload(payload, p224)
+	assert.Error(t, err)
+	assert.False(t, verifySignature(payload, []byte("signature"), &p224.PublicKey))
+}
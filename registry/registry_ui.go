@@ -33,6 +33,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jellydator/ttlcache/v3"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/utils"
@@ -139,10 +140,33 @@ func populateRegistrationFields(prefix string, data interface{}) []registrationF
 			Required:      strings.Contains(field.Tag.Get("validate"), "required"),
 		}
 
+		// The write mode is a string, but only takes the known modes
+		if field.Type == reflect.TypeOf(common.WriteMode("")) {
+			regField.Type = Enum
+			regField.Options = []registrationFieldOption{
+				{Name: "Mutable", ID: string(common.WriteModeMutable)},
+				{Name: "Immutable", ID: string(common.WriteModeImmutable)},
+				{Name: "Versioned", ID: string(common.WriteModeVersioned)},
+			}
+			regField.Description = "Whether objects may be overwritten and deleted once written, or are kept as immutable versions"
+			fields = append(fields, regField)
+			continue
+		}
+
+		switch field.Name {
+		case "CacheMaxAge":
+			regField.Description = "The longest time, in seconds, caches may keep a copy of an object before fetching it again from the origin; 0 for no limit"
+		case "CacheMustRevalidate":
+			regField.Description = "Whether caches must not serve copies of objects without checking with the origin, even while the origin is unavailable"
+		}
+
 		switch field.Type.Kind() {
 		case reflect.Int:
 			regField.Type = Int
 			fields = append(fields, regField)
+		case reflect.Bool:
+			regField.Type = Boolean
+			fields = append(fields, regField)
 		case reflect.String:
 			regField.Type = String
 			fields = append(fields, regField)
@@ -303,26 +327,26 @@ func listNamespaces(ctx *gin.Context) {
 	// Directly call GetUser as we want this endpoint to also be able to serve unauthed users
 	user, err := web_ui.GetUser(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user login status"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to check user login status")
 		return
 	}
 	ctx.Set("User", user)
 	isAuthed := user != ""
 	queryParams := listNamespaceRequest{}
 	if ctx.ShouldBindQuery(&queryParams) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
 		return
 	}
 
 	// For unauthed user with non-empty Status query != Approved, return 403
 	if !isAuthed && queryParams.Status != "" && queryParams.Status != Approved.String() {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to filter non-approved namespace registrations"})
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "You don't have permission to filter non-approved namespace registrations")
 		return
 	}
 
 	// Filter ns by server type
 	if queryParams.ServerType != "" && queryParams.ServerType != string(OriginType) && queryParams.ServerType != string(CacheType) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid server type"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid server type")
 		return
 	}
 
@@ -335,7 +359,7 @@ func listNamespaces(ctx *gin.Context) {
 			if IsValidRegStatus(queryParams.Status) {
 				filterNs.AdminMetadata.Status = RegistrationStatus(queryParams.Status)
 			} else {
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: status must be one of  'Pending', 'Approved', 'Denied', 'Unknown'"})
+				web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: status must be one of  'Pending', 'Approved', 'Denied', 'Unknown'")
 			}
 		}
 	} else {
@@ -345,7 +369,7 @@ func listNamespaces(ctx *gin.Context) {
 	namespaces, err := getNamespacesByFilter(filterNs, ServerType(queryParams.ServerType))
 	if err != nil {
 		log.Error("Failed to get namespaces by server type: ", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Server encountered an error trying to list namespaces"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Server encountered an error trying to list namespaces")
 		return
 	}
 	nssWOPubkey := excludePubKey(namespaces)
@@ -360,12 +384,12 @@ func listNamespaces(ctx *gin.Context) {
 func listNamespacesForUser(ctx *gin.Context) {
 	user := ctx.GetString("User")
 	if user == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "You need to login to perform this action"})
+		web_ui.WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "You need to login to perform this action")
 		return
 	}
 	queryParams := listNamespacesForUserRequest{}
 	if ctx.ShouldBindQuery(&queryParams) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters")
 		return
 	}
 
@@ -375,14 +399,14 @@ func listNamespacesForUser(ctx *gin.Context) {
 		if IsValidRegStatus(queryParams.Status) {
 			filterNs.AdminMetadata.Status = RegistrationStatus(queryParams.Status)
 		} else {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: status must be one of  'Pending', 'Approved', 'Denied', 'Unknown'"})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid query parameters: status must be one of  'Pending', 'Approved', 'Denied', 'Unknown'")
 		}
 	}
 
 	namespaces, err := getNamespacesByFilter(filterNs, "")
 	if err != nil {
 		log.Error("Error getting namespaces for user ", user)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting namespaces by user ID"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespaces by user ID")
 		return
 	}
 	ctx.JSON(http.StatusOK, namespaces)
@@ -406,7 +430,7 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 	user := ctx.GetString("User")
 	id := 0 // namespace ID when doing update, will be populated later
 	if user == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "You need to login to perform this action"})
+		web_ui.WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "You need to login to perform this action")
 		return
 	}
 	if isUpdate {
@@ -415,14 +439,22 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 		id, err = strconv.Atoi(idStr)
 		if err != nil || id <= 0 {
 			// Handle the error if id is not a valid integer
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. ID must a positive integer"})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ID format. ID must a positive integer")
+			return
+		}
+	}
+
+	// Registrants must accept the current terms of service, while admins may update the
+	// namespaces of others regardless
+	if isAdmin, _ := web_ui.CheckAdmin(user); !isUpdate || !isAdmin {
+		if !requireTermsAccepted(ctx, user, "register or update namespaces") {
 			return
 		}
 	}
 
 	ns := Namespace{}
 	if ctx.ShouldBindJSON(&ns) != nil {
-		ctx.JSON(400, gin.H{"error": "Invalid create or update namespace request"})
+		web_ui.WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Invalid create or update namespace request")
 		return
 	}
 	// Assign ID from path param because the request data doesn't have ID set
@@ -430,13 +462,13 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 	// Basic validation (type, required, etc)
 	errs := config.GetValidate().Struct(ns)
 	if errs != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint(errs)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprint(errs))
 		return
 	}
 	// Check that Prefix is a valid prefix
 	updated_prefix, err := validatePrefix(ns.Prefix)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Error: Field validation for prefix failed:", err)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, fmt.Sprint("Error: Field validation for prefix failed:", err))
 		return
 	}
 	ns.Prefix = updated_prefix
@@ -446,18 +478,18 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 		exists, err := namespaceExists(ns.Prefix)
 		if err != nil {
 			log.Errorf("Failed to check if namespace already exists: %v", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Server encountered an error checking if namespace already exists"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Server encountered an error checking if namespace already exists")
 			return
 		}
 		if exists {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The prefix %s is already registered", ns.Prefix)})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeNamespaceExists, fmt.Sprintf("The prefix %s is already registered", ns.Prefix))
 			return
 		}
 	}
 	// Check if pubKey is a valid JWK
 	pubkey, err := validateJwks(ns.Pubkey)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Error: Field validation for pubkey failed:", err)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, fmt.Sprint("Error: Field validation for pubkey failed:", err))
 		return
 	}
 
@@ -465,30 +497,42 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 	valErr, sysErr := validateKeyChaining(ns.Prefix, pubkey)
 	if valErr != nil {
 		log.Errorln(valErr)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": valErr})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPublicKey, valErr.Error())
 		return
 	}
 	if sysErr != nil {
 		log.Errorln(sysErr)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": sysErr})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, sysErr.Error())
 		return
 	}
 
 	if validInst, err := validateInstitution(ns.AdminMetadata.Institution); !validInst {
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error validating institution: %v", err)})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Error validating institution: %v", err))
 			return
 		}
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Institution \"%s\" is not in the list of available institutions to register.", ns.AdminMetadata.Institution)})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Institution \"%s\" is not in the list of available institutions to register.", ns.AdminMetadata.Institution))
+		return
+	}
+
+	if !ns.AdminMetadata.WriteMode.IsValid() {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid write mode %q; it must be one of %q, %q or %q",
+			ns.AdminMetadata.WriteMode, common.WriteModeMutable, common.WriteModeImmutable, common.WriteModeVersioned))
+		return
+	}
+
+	if ns.AdminMetadata.CacheMaxAge < 0 {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid cache max age %d; it must be a number of seconds, or 0 for no limit",
+			ns.AdminMetadata.CacheMaxAge))
 		return
 	}
 
 	if validCF, err := validateCustomFields(ns.CustomFields, true); !validCF {
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error validating custom fields: %v", err)})
+			web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Error validating custom fields: %v", err))
 			return
 		}
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid custom field: %s", err.Error())})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid custom field: %s", err.Error()))
 		return
 	}
 
@@ -498,7 +542,7 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 		ns.AdminMetadata.Status = Pending
 		if err := addNamespace(&ns); err != nil {
 			log.Errorf("Failed to insert namespace with id %d. %v", ns.ID, err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Fail to insert namespace"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Fail to insert namespace")
 			return
 		}
 		ctx.JSON(http.StatusOK, gin.H{"msg": "success"})
@@ -507,12 +551,12 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 		exists, err := namespaceExistsById(ns.ID)
 		if err != nil {
 			log.Error("Failed to get namespace by ID:", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Fail to find if namespace exists"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Fail to find if namespace exists")
 			return
 		}
 
 		if !exists { // Return 404 is the namespace does not exists
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Can't update namespace: namespace not found"})
+			web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Can't update namespace: namespace not found")
 			return
 		}
 
@@ -522,30 +566,30 @@ func createUpdateNamespace(ctx *gin.Context, isUpdate bool) {
 			found, err := namespaceBelongsToUserId(ns.ID, user)
 			if err != nil {
 				log.Error("Error checking if namespace belongs to the user: ", err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking if namespace belongs to the user"})
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace belongs to the user")
 				return
 			}
 			if !found {
 				log.Errorf("Namespace not found for id: %d", ns.ID)
-				ctx.JSON(http.StatusNotFound, gin.H{"error": "Namespace not found. Check the id or if you own the namespace"})
+				web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Namespace not found. Check the id or if you own the namespace")
 				return
 			}
 			existingStatus, err := getNamespaceStatusById(ns.ID)
 			if err != nil {
 				log.Error("Error checking namespace status: ", err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking namespace status"})
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking namespace status")
 				return
 			}
 			if existingStatus == Approved {
 				log.Errorf("User '%s' is trying to modify approved namespace registration with id=%d", user, ns.ID)
-				ctx.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to modify an approved registration. Please contact your federation administrator"})
+				web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, "You don't have permission to modify an approved registration. Please contact your federation administrator")
 				return
 			}
 		}
 		// If the user has previlege to udpate, go ahead
 		if err := updateNamespace(&ns); err != nil {
 			log.Errorf("Failed to update namespace with id %d. %v", ns.ID, err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Fail to update namespace"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Fail to update namespace")
 			return
 		}
 	}
@@ -561,18 +605,18 @@ func getNamespace(ctx *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
 		// Handle the error if id is not a valid integer
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. ID must a non-zero integer"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ID format. ID must a non-zero integer")
 		return
 	}
 	exists, err := namespaceExistsById(id)
 	if err != nil {
 		log.Error("Error checking if namespace exists: ", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking if namespace exists"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace exists")
 		return
 	}
 	if !exists {
 		log.Errorf("Namespace not found for id: %d", id)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Namespace not found"})
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Namespace not found")
 		return
 	}
 
@@ -581,12 +625,12 @@ func getNamespace(ctx *gin.Context) {
 		found, err := namespaceBelongsToUserId(id, user)
 		if err != nil {
 			log.Error("Error checking if namespace belongs to the user: ", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking if namespace belongs to the user"})
+			web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace belongs to the user")
 			return
 		}
 		if !found { // If the user doen's own the namespace, they can't update it
 			log.Errorf("Namespace not found for id: %d", id)
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "Namespace not found. Check the id or if you own the namespace"})
+			web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeNamespaceNotFound, "Namespace not found. Check the id or if you own the namespace")
 			return
 		}
 	}
@@ -594,7 +638,7 @@ func getNamespace(ctx *gin.Context) {
 	ns, err := getNamespaceById(id)
 	if err != nil {
 		log.Error("Error getting namespace: ", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting namespace"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error getting namespace")
 		return
 	}
 	ctx.JSON(http.StatusOK, ns)
@@ -606,24 +650,24 @@ func updateNamespaceStatus(ctx *gin.Context, status RegistrationStatus) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
 		// Handle the error if id is not a valid integer
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. ID must a non-zero integer"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ID format. ID must a non-zero integer")
 		return
 	}
 	exists, err := namespaceExistsById(id)
 	if err != nil {
 		log.Error("Error checking if namespace exists: ", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking if namespace exists"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error checking if namespace exists")
 		return
 	}
 	if !exists {
 		log.Errorf("Namespace not found for id: %d", id)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Namespace not found"})
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Namespace not found")
 		return
 	}
 
 	if err = updateNamespaceStatusById(id, status, user); err != nil {
 		log.Error("Error updating namespace status by ID:", id, " to status:", status)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update namespace"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to update namespace")
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"msg": "ok"})
@@ -634,26 +678,26 @@ func getNamespaceJWKS(ctx *gin.Context) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
 		// Handle the error if id is not a valid integer
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. ID must a non-zero integer"})
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid ID format. ID must a non-zero integer")
 		return
 	}
 	found, err := namespaceExistsById(id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error checking id:", err)})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error checking id:", err))
 		return
 	}
 	if !found {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Namespace not found"})
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNamespaceNotFound, "Namespace not found")
 		return
 	}
 	jwks, err := getNamespaceJwksById(id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error getting jwks by id:", err)})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error getting jwks by id:", err))
 		return
 	}
 	jsonData, err := json.MarshalIndent(jwks, "", "  ")
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal JWKS"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to marshal JWKS")
 		return
 	}
 	// Append a new line to the JSON data
@@ -667,7 +711,7 @@ func listInstitutions(ctx *gin.Context) {
 	institutions := []Institution{}
 	if err := param.Registry_Institutions.Unmarshal(&institutions); err != nil {
 		log.Error("Fail to read server configuration of institutions", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Fail to read server configuration of institutions"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Fail to read server configuration of institutions")
 		return
 	}
 
@@ -684,7 +728,7 @@ func listInstitutions(ctx *gin.Context) {
 				log.Error(intErr)
 			}
 			if extErr != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": extErr.Error()})
+				web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, extErr.Error())
 			}
 			return
 		}
@@ -695,7 +739,7 @@ func listInstitutions(ctx *gin.Context) {
 	// When both are unset
 	if len(institutions) == 0 {
 		log.Error("Server didn't configure Registry.Institutions")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Server didn't configure Registry.Institutions"})
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Server didn't configure Registry.Institutions")
 		return
 	}
 }
@@ -746,28 +790,130 @@ func RegisterRegistryWebAPI(router *gin.RouterGroup) error {
 	registryWebAPI.Use(csrfHandler)
 	// Follow RESTful schema
 	{
-		registryWebAPI.GET("/namespaces", listNamespaces)
-		registryWebAPI.OPTIONS("/namespaces", web_ui.AuthHandler, getNamespaceRegFields)
-		registryWebAPI.POST("/namespaces", web_ui.AuthHandler, func(ctx *gin.Context) {
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces", web_ui.APIDoc{
+			Summary: "List the registered namespaces",
+			Query: map[string]string{
+				"server_type": `Only list namespaces of this server type, "origin" or "cache"`,
+				"status":      `Only list namespaces with this status, e.g. "Pending"; requires login`,
+			},
+			Response: []NamespaceWOPubkey{},
+		}, listNamespaces)
+		web_ui.HandleAPI(registryWebAPI, http.MethodOptions, "/namespaces", web_ui.APIDoc{
+			Summary:  "List the fields of a namespace registration",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []registrationField{},
+		}, web_ui.AuthHandler, getNamespaceRegFields)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPost, "/namespaces", web_ui.APIDoc{
+			Summary: "Register a namespace",
+			Auth:    web_ui.APIAuthLogin,
+			Request: Namespace{},
+		}, web_ui.AuthHandler, func(ctx *gin.Context) {
 			createUpdateNamespace(ctx, false)
 		})
 
-		registryWebAPI.GET("/namespaces/user", web_ui.AuthHandler, listNamespacesForUser)
-
-		registryWebAPI.GET("/namespaces/:id", web_ui.AuthHandler, getNamespace)
-		registryWebAPI.PUT("/namespaces/:id", web_ui.AuthHandler, func(ctx *gin.Context) {
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces/user", web_ui.APIDoc{
+			Summary:  "List the namespaces registered by the caller",
+			Auth:     web_ui.APIAuthLogin,
+			Query:    map[string]string{"status": `Only list namespaces with this status, e.g. "Pending"`},
+			Response: []Namespace{},
+		}, web_ui.AuthHandler, listNamespacesForUser)
+
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces/:id", web_ui.APIDoc{
+			Summary:  "Return the namespace with the ID",
+			Auth:     web_ui.APIAuthLogin,
+			Response: Namespace{},
+		}, web_ui.AuthHandler, getNamespace)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPut, "/namespaces/:id", web_ui.APIDoc{
+			Summary: "Update the namespace with the ID",
+			Auth:    web_ui.APIAuthLogin,
+			Request: Namespace{},
+		}, web_ui.AuthHandler, func(ctx *gin.Context) {
 			createUpdateNamespace(ctx, true)
 		})
-		registryWebAPI.GET("/namespaces/:id/pubkey", getNamespaceJWKS)
-		registryWebAPI.PATCH("/namespaces/:id/approve", web_ui.AuthHandler, web_ui.AdminAuthHandler, func(ctx *gin.Context) {
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces/:id/pubkey", web_ui.APIDoc{
+			Summary: "Download the public keys of the namespace with the ID, as a JWK set",
+		}, getNamespaceJWKS)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPatch, "/namespaces/:id/approve", web_ui.APIDoc{
+			Summary: `Update the status of the namespace with the ID to "Approved"`,
+			Auth:    web_ui.APIAuthAdmin,
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, func(ctx *gin.Context) {
 			updateNamespaceStatus(ctx, Approved)
 		})
-		registryWebAPI.PATCH("/namespaces/:id/deny", web_ui.AuthHandler, web_ui.AdminAuthHandler, func(ctx *gin.Context) {
+		web_ui.HandleAPI(registryWebAPI, http.MethodPatch, "/namespaces/:id/deny", web_ui.APIDoc{
+			Summary: `Update the status of the namespace with the ID to "Denied"`,
+			Auth:    web_ui.APIAuthAdmin,
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, func(ctx *gin.Context) {
 			updateNamespaceStatus(ctx, Denied)
 		})
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces/:id/transfers", web_ui.APIDoc{
+			Summary:  "List the transfers of the namespace, including completed and cancelled ones",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []NamespaceTransfer{},
+		}, web_ui.AuthHandler, listNamespaceTransfers)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPost, "/namespaces/:id/transfer", web_ui.APIDoc{
+			Summary:   "Start transferring the namespace to another user",
+			Auth:      web_ui.APIAuthLogin,
+			Request:   initiateTransferReq{},
+			Response:  NamespaceTransfer{},
+			Responses: map[int]string{http.StatusCreated: "The pending transfer"},
+		}, web_ui.AuthHandler, initiateNamespaceTransfer)
+		web_ui.HandleAPI(registryWebAPI, http.MethodDelete, "/namespaces/:id/transfer", web_ui.APIDoc{
+			Summary: "Cancel or decline the pending transfer of the namespace",
+			Auth:    web_ui.APIAuthLogin,
+		}, web_ui.AuthHandler, cancelNamespaceTransferHandler)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPost, "/namespaces/:id/transfer/accept", web_ui.APIDoc{
+			Summary:     "Accept the pending transfer of the namespace",
+			Description: "The recipient passes a key-sign challenge, as for registration, with the key the namespace is registered with once the transfer completes",
+			Auth:        web_ui.APIAuthLogin,
+			Request:     registrationData{},
+		}, web_ui.AuthHandler, acceptNamespaceTransfer)
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/namespaces/:id/revocations", web_ui.APIDoc{
+			Summary:  "List the revoked keys of the namespace",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []KeyRevocation{},
+		}, web_ui.AuthHandler, listNamespaceKeyRevocations)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPost, "/namespaces/:id/revocations", web_ui.APIDoc{
+			Summary:   "Revoke a key of the namespace",
+			Auth:      web_ui.APIAuthLogin,
+			Request:   revokeKeyReq{},
+			Response:  KeyRevocation{},
+			Responses: map[int]string{http.StatusCreated: "The revocation"},
+		}, web_ui.AuthHandler, revokeNamespaceKey)
+	}
+	{
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/terms", web_ui.APIDoc{
+			Summary:  "Return the current terms of service of the federation and whether the caller accepted them",
+			Auth:     web_ui.APIAuthLogin,
+			Response: termsOfService{},
+		}, web_ui.AuthHandler, getTermsOfService)
+		web_ui.HandleAPI(registryWebAPI, http.MethodPost, "/terms/accept", web_ui.APIDoc{
+			Summary:   "Accept the current terms of service, which is needed to register namespaces",
+			Auth:      web_ui.APIAuthLogin,
+			Request:   acceptTermsReq{},
+			Response:  TermsAcceptance{},
+			Responses: map[int]string{http.StatusCreated: "The acceptance"},
+		}, web_ui.AuthHandler, acceptTermsOfService)
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/terms/acceptances", web_ui.APIDoc{
+			Summary: "List the recorded acceptances of the terms of service",
+			Auth:    web_ui.APIAuthAdmin,
+			Query: map[string]string{
+				"user_id": "Only list the acceptances of this user",
+				"version": "Only list the acceptances of this version",
+			},
+			Response: []TermsAcceptance{},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, listTermsAcceptances)
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/terms/status", web_ui.APIDoc{
+			Summary:  "List whether the registrant of each namespace accepted the current terms of service",
+			Auth:     web_ui.APIAuthAdmin,
+			Response: []namespaceTermsStatus{},
+		}, web_ui.AuthHandler, web_ui.AdminAuthHandler, listNamespaceTermsStatus)
 	}
 	{
-		registryWebAPI.GET("/institutions", web_ui.AuthHandler, listInstitutions)
+		web_ui.HandleAPI(registryWebAPI, http.MethodGet, "/institutions", web_ui.APIDoc{
+			Summary:  "List the institutions available to select for a namespace registration",
+			Auth:     web_ui.APIAuthLogin,
+			Response: []Institution{},
+		}, web_ui.AuthHandler, listInstitutions)
 	}
 	return nil
 }
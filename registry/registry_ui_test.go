@@ -20,6 +20,7 @@ import (
 	"github.com/jellydator/ttlcache/v3"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/test_utils"
@@ -693,7 +694,7 @@ func TestCreateNamespace(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error":"Invalid create or update namespace request"}`, string(body))
+		assert.Equal(t, "Invalid create or update namespace request", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("missing-required-fields-returns-400", func(t *testing.T) {
@@ -888,7 +889,7 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Invalid ID format. ID must a positive integer"}`, string(body))
+		assert.Equal(t, "Invalid ID format. ID must a positive integer", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("ng-id-returns-400", func(t *testing.T) {
@@ -899,7 +900,7 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Invalid ID format. ID must a positive integer"}`, string(body))
+		assert.Equal(t, "Invalid ID format. ID must a positive integer", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("zero-id-returns-400", func(t *testing.T) {
@@ -910,7 +911,7 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Invalid ID format. ID must a positive integer"}`, string(body))
+		assert.Equal(t, "Invalid ID format. ID must a positive integer", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("valid-request-but-ns-dne-returns-404", func(t *testing.T) {
@@ -933,7 +934,9 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Can't update namespace: namespace not found"}`, string(body))
+		problem := test_utils.ParseProblem(t, body)
+		assert.Equal(t, "Can't update namespace: namespace not found", problem.Detail)
+		assert.Equal(t, common.ErrCodeNamespaceNotFound, problem.Code)
 	})
 
 	t.Run("valid-request-not-owner-gives-404", func(t *testing.T) {
@@ -963,7 +966,7 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Namespace not found. Check the id or if you own the namespace"}`, string(body))
+		assert.Equal(t, "Namespace not found. Check the id or if you own the namespace", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("reg-user-cant-change-after-approv", func(t *testing.T) {
@@ -1001,7 +1004,7 @@ func TestUpdateNamespaceHandler(t *testing.T) {
 		body, err := io.ReadAll(w.Result().Body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "You don't have permission to modify an approved registration. Please contact your federation administrator"}`, string(body))
+		assert.Equal(t, "You don't have permission to modify an approved registration. Please contact your federation administrator", test_utils.ParseProblem(t, body).Detail)
 	})
 
 	t.Run("reg-user-success-change", func(t *testing.T) {
@@ -1121,7 +1124,7 @@ func TestListInsitutions(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
-		assert.JSONEq(t, `{"error": "Server didn't configure Registry.Institutions"}`, string(bytes))
+		assert.Equal(t, "Server didn't configure Registry.Institutions", test_utils.ParseProblem(t, bytes).Detail)
 	})
 
 	t.Run("cache-hit-returns", func(t *testing.T) {
@@ -1218,6 +1221,13 @@ func TestListInsitutions(t *testing.T) {
 func TestPopulateRegistrationFields(t *testing.T) {
 	result := populateRegistrationFields("", Namespace{})
 	assert.NotEqual(t, 0, len(result))
+
+	fieldTypes := map[string]registrationFieldType{}
+	for _, field := range result {
+		fieldTypes[field.Name] = field.Type
+	}
+	assert.Equal(t, Int, fieldTypes["admin_metadata.cache_max_age"])
+	assert.Equal(t, Boolean, fieldTypes["admin_metadata.cache_must_revalidate"])
 }
 
 func TestGetCachedInstitutions(t *testing.T) {
@@ -0,0 +1,347 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file implements the tracking of acceptances of the federation's terms of
+// service.  Once Registry.TermsOfServiceVersion is set, registrants must accept
+// that version before registering namespaces: users of the web UI accept it through
+// the API, while the key-sign registrations of the command line and of origins and
+// caches carry the version they accept.  Every acceptance is kept, so changing the
+// version makes registrants accept again, and admins can query who accepted which.
+//
+
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+// The acceptance of a version of the terms of service by a registrant
+type TermsAcceptance struct {
+	ID      int    `json:"id"`
+	Version string `json:"version"`
+	UserID  string `json:"user_id"` // The "sub" of the user, if the registrant is known
+	Prefix  string `json:"prefix"`  // The namespace registered along with the acceptance, if any
+	// The time of the acceptance
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// The terms of service as seen by the calling user
+type termsOfService struct {
+	Version    string     `json:"version"`
+	Url        string     `json:"url"`
+	Accepted   bool       `json:"accepted"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type acceptTermsReq struct {
+	Version string `json:"version" binding:"required"`
+}
+
+// Whether the registrant of a namespace accepted the current terms of service
+type namespaceTermsStatus struct {
+	NamespaceID     int        `json:"namespace_id"`
+	Prefix          string     `json:"prefix"`
+	Registrant      string     `json:"registrant"`
+	AcceptedVersion string     `json:"accepted_version"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	Current         bool       `json:"current"`
+}
+
+func createTermsAcceptanceTable() {
+	query := `
+    CREATE TABLE IF NOT EXISTS terms_acceptance (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        version TEXT NOT NULL,
+        user_id TEXT NOT NULL DEFAULT '',
+        prefix TEXT NOT NULL DEFAULT '',
+        accepted_at INTEGER NOT NULL
+    );`
+
+	if _, err := db.Exec(query); err != nil {
+		log.Fatalf("Failed to create terms acceptance table: %v", err)
+	}
+}
+
+const termsAcceptanceColumns = `id, version, user_id, prefix, accepted_at`
+
+func scanTermsAcceptance(row rowScanner) (*TermsAcceptance, error) {
+	acceptance := &TermsAcceptance{}
+	var acceptedAt int64
+	if err := row.Scan(&acceptance.ID, &acceptance.Version, &acceptance.UserID, &acceptance.Prefix, &acceptedAt); err != nil {
+		return nil, err
+	}
+	acceptance.AcceptedAt = time.Unix(acceptedAt, 0)
+	return acceptance, nil
+}
+
+func addTermsAcceptance(acceptance *TermsAcceptance) error {
+	acceptance.AcceptedAt = time.Now()
+	query := `INSERT INTO terms_acceptance (version, user_id, prefix, accepted_at) VALUES (?, ?, ?, ?)`
+	result, err := db.Exec(query, acceptance.Version, acceptance.UserID, acceptance.Prefix, acceptance.AcceptedAt.Unix())
+	if err != nil {
+		return errors.Wrap(err, "Failed to insert the terms acceptance")
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get the id of the terms acceptance")
+	}
+	acceptance.ID = int(id)
+	return nil
+}
+
+// Get the acceptances of the user and of the version, either of which may be empty
+// to not filter on it, oldest first
+func getTermsAcceptances(userId, version string) ([]*TermsAcceptance, error) {
+	query := `SELECT ` + termsAcceptanceColumns + ` FROM terms_acceptance WHERE 1=1`
+	args := []any{}
+	if userId != "" {
+		query += ` AND user_id = ?`
+		args = append(args, userId)
+	}
+	if version != "" {
+		query += ` AND version = ?`
+		args = append(args, version)
+	}
+	rows, err := db.Query(query+` ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acceptances := make([]*TermsAcceptance, 0)
+	for rows.Next() {
+		acceptance, err := scanTermsAcceptance(rows)
+		if err != nil {
+			return nil, err
+		}
+		acceptances = append(acceptances, acceptance)
+	}
+	return acceptances, rows.Err()
+}
+
+// Get the latest acceptance of the user, or nil if they never accepted the terms
+func getLatestTermsAcceptance(userId string) (*TermsAcceptance, error) {
+	query := `SELECT ` + termsAcceptanceColumns + ` FROM terms_acceptance WHERE user_id = ? ORDER BY id DESC LIMIT 1`
+	acceptance, err := scanTermsAcceptance(db.QueryRow(query, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return acceptance, err
+}
+
+// Get the "sub" of the OIDC user info a namespace was registered with, if any
+func identitySubject(identity string) string {
+	userInfo := struct {
+		Sub string `json:"sub"`
+	}{}
+	if identity == "" || json.Unmarshal([]byte(identity), &userInfo) != nil {
+		return ""
+	}
+	return userInfo.Sub
+}
+
+func termsNotAcceptedDetail(action string) string {
+	detail := fmt.Sprintf("Version %s of the federation's terms of service must be accepted to %s", param.Registry_TermsOfServiceVersion.GetString(), action)
+	if termsUrl := param.Registry_TermsOfServiceUrl.GetString(); termsUrl != "" {
+		detail += "; read them at " + termsUrl
+	}
+	return detail
+}
+
+// Check that the user accepted the current terms of service, responding with a
+// problem if they didn't
+func requireTermsAccepted(ctx *gin.Context, user, action string) bool {
+	version := param.Registry_TermsOfServiceVersion.GetString()
+	if version == "" {
+		return true
+	}
+	acceptance, err := getLatestTermsAcceptance(user)
+	if err != nil {
+		log.Errorf("Failed to get the terms acceptance of user %s: %v", user, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to check the acceptance of the terms of service")
+		return false
+	}
+	if acceptance == nil || acceptance.Version != version {
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeTermsNotAccepted, termsNotAcceptedDetail(action))
+		return false
+	}
+	return true
+}
+
+// Check the terms of service accepted with a key-sign registration, recording the acceptance.
+// Responds with a problem and returns false if the current version wasn't accepted.
+func checkRegistrationTerms(ctx *gin.Context, data *registrationData) (bool, error) {
+	version := param.Registry_TermsOfServiceVersion.GetString()
+	if version == "" {
+		return true, nil
+	}
+	if data.AcceptedTermsOfService != version {
+		log.Infof("Refusing the registration of %s as it doesn't accept version %s of the terms of service", data.Prefix, version)
+		web_ui.WriteProblem(ctx, http.StatusForbidden, common.ErrCodeTermsNotAccepted,
+			termsNotAcceptedDetail("register a namespace")+fmt.Sprintf("; accept them with the --accept-terms %s option of `pelican namespace register` or by setting Server.AcceptedTermsOfService", version))
+		return false, nil
+	}
+	acceptance := &TermsAcceptance{
+		Version: version,
+		UserID:  identitySubject(data.Identity),
+		Prefix:  data.Prefix,
+	}
+	if err := addTermsAcceptance(acceptance); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to record the acceptance of the terms of service")
+		return false, err
+	}
+	return true, nil
+}
+
+// Return the current terms of service and whether the caller accepted them
+//
+// GET /terms
+func getTermsOfService(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	terms := termsOfService{
+		Version: param.Registry_TermsOfServiceVersion.GetString(),
+		Url:     param.Registry_TermsOfServiceUrl.GetString(),
+	}
+	if terms.Version == "" {
+		terms.Accepted = true
+		ctx.JSON(http.StatusOK, terms)
+		return
+	}
+	acceptance, err := getLatestTermsAcceptance(user)
+	if err != nil {
+		log.Errorf("Failed to get the terms acceptance of user %s: %v", user, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the acceptance of the terms of service")
+		return
+	}
+	if acceptance != nil && acceptance.Version == terms.Version {
+		terms.Accepted = true
+		terms.AcceptedAt = &acceptance.AcceptedAt
+	}
+	ctx.JSON(http.StatusOK, terms)
+}
+
+// Accept the current terms of service as the caller.  The version accepted must be
+// the current one, so a user can't accept terms they weren't shown.
+//
+// POST /terms/accept
+func acceptTermsOfService(ctx *gin.Context) {
+	user := ctx.GetString("User")
+	reqData := acceptTermsReq{}
+	if err := ctx.ShouldBindJSON(&reqData); err != nil {
+		web_ui.WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	version := param.Registry_TermsOfServiceVersion.GetString()
+	if version == "" {
+		web_ui.WriteProblem(ctx, http.StatusNotFound, common.ErrCodeNotFound, "The registry has no terms of service to accept")
+		return
+	}
+	if reqData.Version != version {
+		web_ui.WriteProblem(ctx, http.StatusConflict, common.ErrCodeConflict, fmt.Sprintf("Version %s isn't the current version of the terms of service, %s", reqData.Version, version))
+		return
+	}
+	acceptance := &TermsAcceptance{Version: version, UserID: user}
+	if err := addTermsAcceptance(acceptance); err != nil {
+		log.Errorf("Failed to record the terms acceptance of user %s: %v", user, err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to record the acceptance of the terms of service")
+		return
+	}
+	log.Infof("User %s accepted version %s of the terms of service", user, version)
+	ctx.JSON(http.StatusCreated, acceptance)
+}
+
+// List the recorded acceptances of the terms of service, optionally of a user or version
+//
+// GET /terms/acceptances
+func listTermsAcceptances(ctx *gin.Context) {
+	acceptances, err := getTermsAcceptances(ctx.Query("user_id"), ctx.Query("version"))
+	if err != nil {
+		log.Errorln("Failed to get the terms acceptances:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the terms acceptances")
+		return
+	}
+	ctx.JSON(http.StatusOK, acceptances)
+}
+
+// List whether the registrant of each namespace accepted the current terms of service.
+// The registrant is the user who registered the namespace or, for key-sign registrations
+// without an identity, whoever registered the prefix.
+//
+// GET /terms/status
+func listNamespaceTermsStatus(ctx *gin.Context) {
+	namespaces, err := getAllNamespaces()
+	if err != nil {
+		log.Errorln("Failed to get the namespaces:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the namespaces")
+		return
+	}
+	acceptances, err := getTermsAcceptances("", "")
+	if err != nil {
+		log.Errorln("Failed to get the terms acceptances:", err)
+		web_ui.WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to get the terms acceptances")
+		return
+	}
+	// Acceptances are oldest first, so the latest of each user and prefix wins
+	byUser := map[string]*TermsAcceptance{}
+	byPrefix := map[string]*TermsAcceptance{}
+	for _, acceptance := range acceptances {
+		if acceptance.UserID != "" {
+			byUser[acceptance.UserID] = acceptance
+		}
+		if acceptance.Prefix != "" {
+			byPrefix[acceptance.Prefix] = acceptance
+		}
+	}
+
+	version := param.Registry_TermsOfServiceVersion.GetString()
+	statuses := make([]namespaceTermsStatus, 0, len(namespaces))
+	for _, ns := range namespaces {
+		status := namespaceTermsStatus{
+			NamespaceID: ns.ID,
+			Prefix:      ns.Prefix,
+			Registrant:  ns.AdminMetadata.UserID,
+		}
+		if status.Registrant == "" {
+			status.Registrant = identitySubject(ns.Identity)
+		}
+		acceptance := byPrefix[ns.Prefix]
+		if status.Registrant != "" {
+			acceptance = byUser[status.Registrant]
+		}
+		if acceptance != nil {
+			status.AcceptedVersion = acceptance.Version
+			status.AcceptedAt = &acceptance.AcceptedAt
+		}
+		status.Current = version == "" || status.AcceptedVersion == version
+		statuses = append(statuses, status)
+	}
+	ctx.JSON(http.StatusOK, statuses)
+}
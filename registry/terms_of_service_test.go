@@ -0,0 +1,150 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestTermsOfService(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Registry.TermsOfServiceVersion", "v1")
+	viper.Set("Registry.TermsOfServiceUrl", "https://example.com/terms")
+
+	setupMockRegistryDB(t)
+	defer teardownMockNamespaceDB(t)
+
+	router := gin.Default()
+	asUser := func(ctx *gin.Context) {
+		ctx.Set("User", ctx.GetHeader("X-Test-User"))
+	}
+	router.GET("/terms", asUser, getTermsOfService)
+	router.POST("/terms/accept", asUser, acceptTermsOfService)
+	router.GET("/terms/acceptances", asUser, listTermsAcceptances)
+	router.GET("/terms/status", asUser, listNamespaceTermsStatus)
+	router.POST("/namespaces", asUser, func(ctx *gin.Context) {
+		createUpdateNamespace(ctx, false)
+	})
+
+	doRequest := func(method, path, user string, body any) *httptest.ResponseRecorder {
+		var reqBody []byte
+		if body != nil {
+			var err error
+			reqBody, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, path, bytes.NewReader(reqBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	getTerms := func(user string) termsOfService {
+		w := doRequest("GET", "/terms", user, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		terms := termsOfService{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &terms))
+		return terms
+	}
+
+	t.Run("accept", func(t *testing.T) {
+		terms := getTerms("alice")
+		assert.Equal(t, "v1", terms.Version)
+		assert.Equal(t, "https://example.com/terms", terms.Url)
+		assert.False(t, terms.Accepted)
+
+		w := doRequest("POST", "/namespaces", "alice", Namespace{Prefix: "/alice"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		problem := common.Problem{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, common.ErrCodeTermsNotAccepted, problem.Code)
+		assert.Contains(t, problem.Detail, "https://example.com/terms")
+
+		w = doRequest("POST", "/terms/accept", "alice", acceptTermsReq{Version: "v0"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = doRequest("POST", "/terms/accept", "alice", acceptTermsReq{Version: "v1"})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		terms = getTerms("alice")
+		assert.True(t, terms.Accepted)
+		assert.NotNil(t, terms.AcceptedAt)
+
+		// Past the terms, the registration fails on its (missing) public key instead
+		w = doRequest("POST", "/namespaces", "alice", Namespace{Prefix: "/alice"})
+		assert.NotEqual(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("key-sign-registration", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", "/api/v1.0/registry", nil)
+		data := &registrationData{Prefix: "/bob", Identity: `{"sub": "bob"}`}
+		accepted, err := checkRegistrationTerms(ctx, data)
+		require.NoError(t, err)
+		assert.False(t, accepted)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		data.AcceptedTermsOfService = "v1"
+		accepted, err = checkRegistrationTerms(ctx, data)
+		require.NoError(t, err)
+		assert.True(t, accepted)
+
+		data = &registrationData{Prefix: "/anonymous", AcceptedTermsOfService: "v1"}
+		accepted, err = checkRegistrationTerms(ctx, data)
+		require.NoError(t, err)
+		assert.True(t, accepted)
+
+		acceptances, err := getTermsAcceptances("bob", "")
+		require.NoError(t, err)
+		require.Len(t, acceptances, 1)
+		assert.Equal(t, "/bob", acceptances[0].Prefix)
+	})
+
+	t.Run("version-change", func(t *testing.T) {
+		require.NoError(t, insertMockDBData([]Namespace{
+			mockNamespace("/alice", "", "", AdminMetadata{UserID: "alice"}),
+			mockNamespace("/bob", "", `{"sub": "bob"}`, AdminMetadata{}),
+			mockNamespace("/anonymous", "", "", AdminMetadata{}),
+			mockNamespace("/carol", "", "", AdminMetadata{UserID: "carol"}),
+		}))
+		viper.Set("Registry.TermsOfServiceVersion", "v2")
+		assert.False(t, getTerms("alice").Accepted)
+		w := doRequest("POST", "/terms/accept", "bob", acceptTermsReq{Version: "v2"})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		w = doRequest("GET", "/terms/status", "admin", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		statuses := []namespaceTermsStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+		byPrefix := map[string]namespaceTermsStatus{}
+		for _, status := range statuses {
+			byPrefix[status.Prefix] = status
+		}
+		require.Len(t, byPrefix, 4)
+		assert.Equal(t, "v1", byPrefix["/alice"].AcceptedVersion)
+		assert.False(t, byPrefix["/alice"].Current)
+		assert.Equal(t, "bob", byPrefix["/bob"].Registrant)
+		assert.True(t, byPrefix["/bob"].Current)
+		assert.Equal(t, "v1", byPrefix["/anonymous"].AcceptedVersion)
+		assert.Empty(t, byPrefix["/carol"].AcceptedVersion)
+		assert.False(t, byPrefix["/carol"].Current)
+
+		w = doRequest("GET", "/terms/acceptances?version=v1", "admin", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		acceptances := []TermsAcceptance{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &acceptances))
+		assert.Len(t, acceptances, 3)
+	})
+}
@@ -33,8 +33,10 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/director"
+	"github.com/pelicanplatform/pelican/events"
 	"github.com/pelicanplatform/pelican/metrics"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/server_utils"
@@ -43,13 +45,17 @@ import (
 )
 
 type directorResponse struct {
-	Error         string `json:"error"`
-	ApprovalError bool   `json:"approval_error"`
+	Error         string           `json:"error"`
+	Code          common.ErrorCode `json:"code"`
+	ApprovalError bool             `json:"approval_error"`
 }
 
 func LaunchPeriodicAdvertise(ctx context.Context, egrp *errgroup.Group, servers []server_utils.XRootDServer) error {
 	ticker := time.NewTicker(1 * time.Minute)
+	// A restart may have changed the advertisement, e.g. the server's health
+	restarts := events.Subscribe(events.XrootdRestartedType)
 	egrp.Go(func() error {
+		defer restarts.Close()
 		log.Debugf("About to advertise %d XRootD servers", len(servers))
 		err := Advertise(ctx, servers)
 		if err != nil {
@@ -61,6 +67,24 @@ func LaunchPeriodicAdvertise(ctx context.Context, egrp *errgroup.Group, servers
 
 		for {
 			select {
+			case <-server_utils.AdvertiseRequests():
+				log.Debugln("Advertising ahead of schedule as the advertisement changed")
+				err := Advertise(ctx, servers)
+				if err != nil {
+					log.Warningln("XRootD server advertise failed:", err)
+					metrics.SetComponentHealthStatus(metrics.OriginCache_Federation, metrics.StatusCritical, fmt.Sprintf("XRootD server advertise failed: %v", err))
+				} else {
+					metrics.SetComponentHealthStatus(metrics.OriginCache_Federation, metrics.StatusOK, "")
+				}
+			case <-restarts.Events():
+				log.Debugln("Advertising ahead of schedule as XRootD was restarted")
+				err := Advertise(ctx, servers)
+				if err != nil {
+					log.Warningln("XRootD server advertise failed:", err)
+					metrics.SetComponentHealthStatus(metrics.OriginCache_Federation, metrics.StatusCritical, fmt.Sprintf("XRootD server advertise failed: %v", err))
+				} else {
+					metrics.SetComponentHealthStatus(metrics.OriginCache_Federation, metrics.StatusOK, "")
+				}
 			case <-ticker.C:
 				err := Advertise(ctx, servers)
 				if err != nil {
@@ -103,10 +127,12 @@ func advertiseInternal(ctx context.Context, server server_utils.XRootDServer) er
 	if err != nil {
 		return err
 	}
-
-	body, err := json.Marshal(ad)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Failed to generate JSON description of %s", server.GetServerType()))
+	// A server failing its health checks still advertises, so the director stops
+	// sending it clients right away instead of when its last ad expires
+	if param.Server_AdvertiseHealthChecks.GetBool() {
+		if healthErr := checkAdvertiseHealth(ctx, server, ad.DataURL); healthErr != nil {
+			ad.Degraded = healthErr.Error()
+		}
 	}
 
 	directorUrlStr := param.Federation_DirectorUrl.GetString()
@@ -122,25 +148,44 @@ func advertiseInternal(ctx context.Context, server server_utils.XRootDServer) er
 
 	prefix := param.Origin_NamespacePrefix.GetString()
 
-	issuerUrl, err := director.GetNSIssuerURL(prefix)
+	tok, err := createAdvertiseToken(prefix)
 	if err != nil {
 		return err
 	}
 
-	advTokenCfg := utils.TokenConfig{
-		TokenProfile: utils.WLCG,
-		Version:      "1.0",
-		Lifetime:     time.Minute,
-		Issuer:       issuerUrl,
-		Audience:     []string{param.Federation_DirectorUrl.GetString()},
-		Subject:      "origin",
+	// The registry knows each of the origin's namespaces by its own key, so the director
+	// checks each namespace against a token signed with that namespace's key
+	signingPrefix := prefix
+	if server.GetServerType().IsEnabled(config.OriginType) {
+		ad.NamespaceTokens = map[string]string{}
+		nsPaths := append([]string{}, ad.PausedNamespaces...)
+		for _, nsAd := range ad.Namespaces {
+			nsPaths = append(nsPaths, nsAd.Path)
+		}
+		for _, nsPath := range nsPaths {
+			if ad.NamespaceTokens[nsPath], err = createAdvertiseToken(nsPath); err != nil {
+				return err
+			}
+		}
+		if len(nsPaths) > 0 {
+			signingPrefix = nsPaths[0]
+		}
 	}
-	advTokenCfg.AddScopes([]token_scopes.TokenScope{token_scopes.Pelican_Advertise})
 
-	// CreateToken also handles validation for us
-	tok, err := advTokenCfg.CreateToken()
+	body, err := json.Marshal(ad)
 	if err != nil {
-		return errors.Wrap(err, "failed to create director advertisement token")
+		return errors.Wrap(err, fmt.Sprintf("Failed to generate JSON description of %s", server.GetServerType()))
+	}
+
+	// Sign the advertisement so the director can tell it wasn't altered on the way,
+	// even behind proxies that don't terminate TLS at the director
+	signingKey, err := config.GetNamespaceIssuerPrivateJWK(signingPrefix)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the key to sign the advertisement with")
+	}
+	signature, err := director.SignAdvertisement(body, signingPrefix, signingKey)
+	if err != nil {
+		return err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", directorUrl.String(), bytes.NewBuffer(body))
@@ -150,6 +195,7 @@ func advertiseInternal(ctx context.Context, server server_utils.XRootDServer) er
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+tok)
+	req.Header.Set(director.AdvertisementSignatureHeader, signature)
 	userAgent := "pelican-" + strings.ToLower(server.GetServerType().String()) + "/" + config.PelicanVersion
 	req.Header.Set("User-Agent", userAgent)
 
@@ -170,11 +216,45 @@ func advertiseInternal(ctx context.Context, server server_utils.XRootDServer) er
 		if unmarshalErr := json.Unmarshal(body, &respErr); unmarshalErr != nil { // Error creating json
 			return errors.Wrapf(unmarshalErr, "Could not unmarshal the director's response, which responded %v from director registration: %v", resp.StatusCode, resp.Status)
 		}
-		if respErr.ApprovalError {
+		if respErr.ApprovalError || respErr.Code == common.ErrCodeNotApproved {
 			return fmt.Errorf("The namespace %q requires administrator approval. Please contact the administrators of %s for more information.", param.Origin_NamespacePrefix.GetString(), param.Federation_RegistryUrl.GetString())
 		}
 		return errors.Errorf("Error during director registration: %v\n", respErr.Error)
 	}
 
+	if ad.Degraded != "" {
+		return errors.Errorf("%s failed its health checks and was advertised as degraded: %s", server.GetServerType(), ad.Degraded)
+	}
 	return nil
 }
+
+// Create the token advertising the namespace at prefix to the director, issued by the
+// namespace's issuer at the registry and signed with the namespace's key
+func createAdvertiseToken(prefix string) (string, error) {
+	issuerUrl, err := director.GetNSIssuerURL(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	advTokenCfg := utils.TokenConfig{
+		TokenProfile: utils.WLCG,
+		Version:      "1.0",
+		Lifetime:     time.Minute,
+		Issuer:       issuerUrl,
+		Audience:     []string{param.Federation_DirectorUrl.GetString()},
+		Subject:      "origin",
+	}
+	advTokenCfg.AddScopes([]token_scopes.TokenScope{token_scopes.Pelican_Advertise})
+
+	// The registry knows the namespace by its own key, if it has one
+	key, err := config.GetNamespaceIssuerPrivateJWK(prefix)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load the namespace's issuer key")
+	}
+	// CreateTokenWithKey also handles validation for us
+	tok, err := advTokenCfg.CreateTokenWithKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create director advertisement token")
+	}
+	return tok, nil
+}
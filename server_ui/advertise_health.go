@@ -0,0 +1,137 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
+)
+
+// How long the XRootD server has to respond to the health check
+const xrootdHealthCheckTimeout = 5 * time.Second
+
+// Run the local health checks a server must pass before the director may send it
+// clients: XRootD responds at dataUrl, the server's storage is usable and its host
+// certificate is valid.  Returns the reasons the server is unhealthy, or nil.
+func checkAdvertiseHealth(ctx context.Context, server server_utils.XRootDServer, dataUrl string) error {
+	problems := []string{}
+	if err := checkXrootdResponding(ctx, dataUrl); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := checkStorageUsable(server); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := checkHostCertificate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Any HTTP response, even an error status, shows XRootD is up
+func checkXrootdResponding(ctx context.Context, dataUrl string) error {
+	ctx, cancel := context.WithTimeout(ctx, xrootdHealthCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dataUrl, nil)
+	if err != nil {
+		return errors.Wrap(err, "invalid XRootD URL")
+	}
+	client := http.Client{Transport: config.GetTransport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "XRootD isn't responding")
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Check the directory the server stores objects in exists and, if the server writes
+// to it, that a file can be created in it
+func checkStorageUsable(server server_utils.XRootDServer) error {
+	var dir string
+	needsWrite := false
+	switch server.GetServerType() {
+	case config.CacheType:
+		dir = param.Cache_DataLocation.GetString()
+		needsWrite = true
+	case config.OriginType:
+		if mode := param.Origin_Mode.GetString(); mode != "posix" && mode != "hsm" {
+			return nil
+		}
+		dir = param.Xrootd_Mount.GetString()
+		if volume := param.Origin_ExportVolume.GetString(); volume != "" {
+			dir = strings.SplitN(volume, ":", 2)[0]
+		}
+		needsWrite = param.Origin_EnableWrite.GetBool()
+	}
+	if dir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrap(err, "storage directory is unavailable")
+	}
+	if !info.IsDir() {
+		return errors.Errorf("storage directory %s isn't a directory", dir)
+	}
+	if !needsWrite {
+		return nil
+	}
+	probe, err := os.CreateTemp(dir, ".pelican-health-*")
+	if err != nil {
+		return errors.Wrap(err, "storage directory isn't writable")
+	}
+	probe.Close()
+	if err = os.Remove(probe.Name()); err != nil {
+		return errors.Wrapf(err, "failed to remove the health check file %s", filepath.Base(probe.Name()))
+	}
+	return nil
+}
+
+func checkHostCertificate() error {
+	certFile := param.Server_TLSCertificate.GetString()
+	if certFile == "" {
+		return nil
+	}
+	cert, err := config.LoadCertficate(certFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the host certificate")
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return errors.Errorf("the host certificate isn't valid until %s", cert.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return errors.Errorf("the host certificate expired at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
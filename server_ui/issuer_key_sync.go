@@ -0,0 +1,140 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/events"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/registry"
+)
+
+// Get the public keys the registry should hold for a namespace signed with the issuer key,
+// and the key to sign the update with. While the previous issuer key is still published,
+// the registry holds it next to the current key, and the update is signed with it as
+// the registry is sure to hold it; afterwards, it only holds the current key.
+func issuerKeysForRegistry() (keySet jwk.Set, signingKey jwk.Key, err error) {
+	key, err := config.GetIssuerPrivateJWK()
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []jwk.Key{key}
+	signingKey = key
+	if previous, _, ok := config.GetPreviousIssuerPrivateJWK(); ok {
+		keys = append(keys, previous)
+		signingKey = previous
+	}
+
+	keySet = jwk.NewSet()
+	for _, key := range keys {
+		pubKey, err := jwk.PublicKeyOf(key)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get the public key of the issuer key")
+		}
+		if err = keySet.AddKey(pubKey); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to add the issuer key to the JWKS")
+		}
+	}
+	return keySet, signingKey, nil
+}
+
+// Replace the registry's public keys for the namespace with the issuer keys it should hold
+func syncIssuerKeys(prefix string, registryUrl string) error {
+	keySet, signingKey, err := issuerKeysForRegistry()
+	if err != nil {
+		return err
+	}
+	endpoint, err := url.JoinPath(registryUrl, "updateNamespaceKey")
+	if err != nil {
+		return errors.Wrap(err, "failed to construct the key update endpoint URL")
+	}
+	if err = registry.NamespaceUpdateKey(endpoint, prefix, signingKey, keySet); err != nil {
+		return errors.Wrapf(err, "failed to update the keys of namespace %s in the registry", prefix)
+	}
+	log.Infof("Updated the registry's keys for namespace %s to the %d current issuer keys", prefix, keySet.Len())
+	return nil
+}
+
+// Keep the registry's public keys for the namespace in step with the rotation of the
+// issuer key: after each rotation, the registry gets the new key next to the previous
+// one, and once the overlap is over, the previous key is dropped.  A namespace with its
+// own key in Origin.Exports isn't rotated, so it's left alone.
+func launchIssuerKeySync(ctx context.Context, egrp *errgroup.Group, prefix string, registryUrl string) {
+	if param.Server_IssuerKeyRotationInterval.GetDuration() <= 0 {
+		return
+	}
+	if hasKey, err := config.HasNamespaceIssuerKey(prefix); err != nil {
+		log.Errorf("Failed to check whether namespace %s has its own issuer key; its keys won't be updated in the registry on rotation: %v", prefix, err)
+		return
+	} else if hasKey {
+		return
+	}
+	retryInterval := param.Server_RegistrationRetryInterval.GetDuration()
+	if retryInterval <= 0 {
+		retryInterval = 10 * time.Second
+	}
+
+	rotations := events.Subscribe(events.IssuerKeyRotatedType)
+	egrp.Go(func() error {
+		defer rotations.Close()
+		// A previous key on startup may not have made it to the registry, or may be due for removal
+		_, _, pending := config.GetPreviousIssuerPrivateJWK()
+		for {
+			if pending {
+				if err := syncIssuerKeys(prefix, registryUrl); err != nil {
+					log.Errorf("%v; retrying in %s", err, retryInterval)
+				} else {
+					pending = false
+				}
+			}
+
+			var timer *time.Timer
+			var timerC <-chan time.Time
+			if pending {
+				timer = time.NewTimer(retryInterval)
+				timerC = timer.C
+			} else if _, expires, ok := config.GetPreviousIssuerPrivateJWK(); ok {
+				timer = time.NewTimer(time.Until(expires))
+				timerC = timer.C
+			}
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return nil
+			case <-rotations.Events():
+			case <-timerC:
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			pending = true
+		}
+	})
+}
@@ -231,7 +231,7 @@ func registerNamespacePrep() (key jwk.Key, prefix string, registrationEndpointUR
 		err = errors.Wrap(err, "Failed to construct registration endpoint URL: %v")
 		return
 	}
-	key, err = config.GetIssuerPrivateJWK()
+	key, err = config.GetNamespaceIssuerPrivateJWK(prefix)
 	if err != nil {
 		err = errors.Wrap(err, "failed to load the origin's JWK")
 		return
@@ -247,19 +247,50 @@ func registerNamespacePrep() (key jwk.Key, prefix string, registrationEndpointUR
 		err = errors.Wrap(err, "Failed to determine whether namespace is already registered")
 		return
 	}
+	current, err := newRegistrationRecord(key, prefix, registrationEndpointURL)
+	if err != nil {
+		return
+	}
+	var previous *registrationRecord
+	if record, ok := getRegistrationRecord(prefix); ok {
+		previous = &record
+		reportURLChanges(record, current)
+	}
 	switch keyStatus {
 	case keyMatch:
 		isRegistered = true
+		if saveErr := saveRegistrationRecord(current); saveErr != nil {
+			log.Warningln("Failed to save the namespace's registration record:", saveErr)
+		}
 		return
 	case keyMismatch:
-		err = errors.Errorf("Namespace %v already registered under a different key", prefix)
+		err = keyMismatchError(current, previous)
 		return
 	case noKeyPresent:
-		log.Infof("Namespace %v not registered; new registration will proceed\n", prefix)
+		if previous != nil {
+			log.Warningf("Namespace %v was registered at %s, but the registry no longer has it; registering it again", prefix, previous.VerifiedAt.Format(time.RFC3339))
+		} else {
+			log.Infof("Namespace %v not registered; new registration will proceed\n", prefix)
+		}
 	}
 	return
 }
 
+// Register the namespace and record its registration
+func registerAndRecord(key jwk.Key, prefix string, registrationEndpointURL string) error {
+	if err := registerNamespaceImpl(key, prefix, registrationEndpointURL); err != nil {
+		return err
+	}
+	record, err := newRegistrationRecord(key, prefix, registrationEndpointURL)
+	if err == nil {
+		err = saveRegistrationRecord(record)
+	}
+	if err != nil {
+		log.Warningln("Failed to save the namespace's registration record:", err)
+	}
+	return nil
+}
+
 func registerNamespaceImpl(key jwk.Key, prefix string, registrationEndpointURL string) error {
 	if err := registry.NamespaceRegister(key, registrationEndpointURL, "", prefix); err != nil {
 		return errors.Wrapf(err, "Failed to register prefix %s", prefix)
@@ -267,40 +298,53 @@ func registerNamespaceImpl(key jwk.Key, prefix string, registrationEndpointURL s
 	return nil
 }
 
+// Register the server's namespace with the registry, retrying a failed registration in
+// the background with exponential backoff; see Server.RegistrationRetryInterval.  The
+// state of the registration is served at /api/v1.0/registration.
 func RegisterNamespaceWithRetry(ctx context.Context, egrp *errgroup.Group) error {
 	metrics.SetComponentHealthStatus(metrics.OriginCache_Federation, metrics.StatusCritical, "Origin not registered with federation")
-	retryInterval := param.Server_RegistrationRetryInterval.GetDuration()
-	if retryInterval == 0 {
-		log.Warning("Server.RegistrationRetryInterval is 0. Fall back to 10s")
-		retryInterval = 10 * time.Second
-	}
 
 	key, prefix, url, isRegistered, err := registerNamespacePrep()
+	registration.start(prefix, url)
 	if err != nil {
+		registration.failed(err)
 		return err
 	}
+	// Keep checking that the registry holds the namespace's key for as long as the server runs
+	launchRegistrationMonitor(ctx, egrp, prefix, url)
+	// Give the registry the new issuer key whenever it's rotated
+	launchIssuerKeySync(ctx, egrp, prefix, url)
 	if isRegistered {
 		log.Debugf("Origin already has prefix %v registered\n", prefix)
+		registration.verified()
 		return nil
 	}
 
-	if err = registerNamespaceImpl(key, prefix, url); err == nil {
+	err = registerAndRecord(key, prefix, url)
+	delay := registration.attempted(err)
+	if err == nil {
 		return nil
 	}
-	log.Errorf("Failed to register with namespace service: %v; will automatically retry in 10 seconds\n", err)
+	log.Errorf("Failed to register with namespace service: %v; will automatically retry in %s", err, delay)
 
 	egrp.Go(func() error {
-		ticker := time.NewTicker(retryInterval)
 		for {
+			timer := time.NewTimer(delay)
 			select {
-			case <-ticker.C:
-				if err := registerNamespaceImpl(key, prefix, url); err == nil {
-					return nil
-				}
-				log.Errorf("Failed to register with namespace service: %v; will automatically retry in 10 seconds\n", err)
+			case <-timer.C:
 			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			}
+			// The registration monitor may have registered the namespace meanwhile
+			if registration.getStatus().State == RegistrationComplete {
+				return nil
+			}
+			err := registerAndRecord(key, prefix, url)
+			if delay = registration.attempted(err); err == nil {
 				return nil
 			}
+			log.Errorf("Failed to register with namespace service: %v; will automatically retry in %s", err, delay)
 		}
 	})
 	return nil
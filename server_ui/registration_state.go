@@ -0,0 +1,245 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// What the server last confirmed the registry holds for its namespace, and the URLs
+// it served the namespace at, so it can tell what changed when the registry's key
+// stops matching its own
+type registrationRecord struct {
+	Prefix        string `json:"prefix"`
+	RegistryUrl   string `json:"registryUrl"`
+	KeyID         string `json:"keyId"`
+	KeyThumbprint string `json:"keyThumbprint"` // The base64url SHA-256 JWK thumbprint of the public key
+	ExternalUrl   string `json:"externalUrl"`
+	IssuerUrl     string `json:"issuerUrl"`
+	// When the registry was last seen holding the key
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+var registrationRecordsMutex sync.Mutex
+
+// The JWK thumbprint identifying the public key of key
+func keyThumbprint(key jwk.Key) (string, error) {
+	pubKey, err := key.PublicKey()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the public key")
+	}
+	thumbprint, err := pubKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to compute the key's thumbprint")
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// The registration of the namespace at prefix as the server currently sees it
+func newRegistrationRecord(key jwk.Key, prefix string, registryUrl string) (registrationRecord, error) {
+	thumbprint, err := keyThumbprint(key)
+	if err != nil {
+		return registrationRecord{}, err
+	}
+	return registrationRecord{
+		Prefix:        prefix,
+		RegistryUrl:   registryUrl,
+		KeyID:         key.KeyID(),
+		KeyThumbprint: thumbprint,
+		ExternalUrl:   param.Server_ExternalWebUrl.GetString(),
+		IssuerUrl:     param.Server_IssuerUrl.GetString(),
+	}, nil
+}
+
+func loadRegistrationRecords(filename string) (map[string]registrationRecord, error) {
+	records := map[string]registrationRecord{}
+	contents, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read the registration records")
+	}
+	if err = json.Unmarshal(contents, &records); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the registration records in %s", filename)
+	}
+	return records, nil
+}
+
+// The record saved for the namespace at prefix, if any
+func getRegistrationRecord(prefix string) (registrationRecord, bool) {
+	filename := param.Server_RegistrationStateFile.GetString()
+	if filename == "" {
+		return registrationRecord{}, false
+	}
+	registrationRecordsMutex.Lock()
+	defer registrationRecordsMutex.Unlock()
+	records, err := loadRegistrationRecords(filename)
+	if err != nil {
+		log.Warningln(err)
+		return registrationRecord{}, false
+	}
+	record, ok := records[prefix]
+	return record, ok
+}
+
+// Save the record, as verified now, to Server.RegistrationStateFile
+func saveRegistrationRecord(record registrationRecord) error {
+	filename := param.Server_RegistrationStateFile.GetString()
+	if filename == "" {
+		return nil
+	}
+	registrationRecordsMutex.Lock()
+	defer registrationRecordsMutex.Unlock()
+	records, err := loadRegistrationRecords(filename)
+	if err != nil {
+		log.Debugln("Overwriting the registration records:", err)
+		records = map[string]registrationRecord{}
+	}
+	record.VerifiedAt = time.Now()
+	records[record.Prefix] = record
+	contents, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the registration records")
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
+		return errors.Wrapf(err, "failed to create the directory for %s", filename)
+	}
+	tmpFile := filename + ".tmp"
+	if err = os.WriteFile(tmpFile, contents, 0640); err != nil {
+		return errors.Wrapf(err, "failed to write the registration records to %s", tmpFile)
+	}
+	return errors.Wrapf(os.Rename(tmpFile, filename), "failed to move the registration records into %s", filename)
+}
+
+// Log the URLs the server serves the namespace at that changed since the registration
+// was last verified.  The registry only holds the namespace's key, so the new URLs
+// reach the federation with the server's next advertisement to the director.
+func reportURLChanges(previous, current registrationRecord) {
+	if previous.ExternalUrl != "" && previous.ExternalUrl != current.ExternalUrl {
+		log.Warningf("The external URL of this server changed from %s to %s since namespace %s was last verified with the registry; "+
+			"the director will be told of the new URL when the server advertises", previous.ExternalUrl, current.ExternalUrl, current.Prefix)
+	}
+	if previous.IssuerUrl != "" && previous.IssuerUrl != current.IssuerUrl {
+		log.Warningf("The issuer URL of namespace %s changed from %s to %s since it was last verified with the registry; "+
+			"tokens issued for the old URL won't be accepted any longer", current.Prefix, previous.IssuerUrl, current.IssuerUrl)
+	}
+	if previous.RegistryUrl != "" && previous.RegistryUrl != current.RegistryUrl {
+		log.Warningf("The registry of namespace %s changed from %s to %s since it was last verified", current.Prefix, previous.RegistryUrl, current.RegistryUrl)
+	}
+}
+
+// The error for a namespace the registry holds under another key than the server's,
+// explaining what changed, if known, and how to fix it
+func keyMismatchError(current registrationRecord, previous *registrationRecord) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "Namespace %s is registered at %s under a different key than this server's issuer key (key ID %s); "+
+		"the federation would reject this server's advertisements and the tokens it issues.", current.Prefix, current.RegistryUrl, current.KeyID)
+	if previous != nil {
+		verified := previous.VerifiedAt.Format(time.RFC3339)
+		if previous.KeyThumbprint != current.KeyThumbprint {
+			fmt.Fprintf(&msg, " This server's issuer key changed since the registration was last verified at %s with key ID %s.", verified, previous.KeyID)
+		} else {
+			fmt.Fprintf(&msg, " This server's issuer key is the one the registry held at %s, so the namespace's key was changed in the registry since.", verified)
+		}
+	}
+	fmt.Fprintf(&msg, " To fix this, either restore the issuer key the namespace was registered with (IssuerKey, or the IssuerKey of the export in Origin.Exports),"+
+		" or ask an administrator of the registry to update the namespace's public key to this server's, or to delete the namespace so the server re-registers it on its next start.")
+	return errors.New(msg.String())
+}
+
+// Periodically check that the registry still holds the server's key for its namespace.
+// A namespace removed from the registry is registered again; a namespace the registry
+// holds under another key is reported with how to fix it.  The key is loaded on each
+// check, as the issuer key may have been rotated since.
+func launchRegistrationMonitor(ctx context.Context, egrp *errgroup.Group, prefix string, registryUrl string) {
+	interval := param.Server_RegistrationCheckInterval.GetDuration()
+	if interval <= 0 {
+		return
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				key, err := config.GetNamespaceIssuerPrivateJWK(prefix)
+				if err != nil {
+					log.Errorln("Failed to load the namespace's key to check its registration:", err)
+					continue
+				}
+				checkRegistration(key, prefix, registryUrl)
+			}
+		}
+	})
+}
+
+// Check the registration of the namespace once; see launchRegistrationMonitor
+func checkRegistration(key jwk.Key, prefix string, registryUrl string) {
+	current, err := newRegistrationRecord(key, prefix, registryUrl)
+	if err != nil {
+		log.Errorln("Failed to check the namespace's registration:", err)
+		return
+	}
+	status, err := keyIsRegistered(key, registryUrl, prefix)
+	if err != nil {
+		log.Warningf("Failed to check whether namespace %s is still registered: %v", prefix, err)
+		return
+	}
+	switch status {
+	case keyMatch:
+		registration.verified()
+		if err = saveRegistrationRecord(current); err != nil {
+			log.Warningln("Failed to save the namespace's registration record:", err)
+		}
+	case noKeyPresent:
+		log.Warningf("Namespace %s is no longer registered at %s; registering it again", prefix, registryUrl)
+		err = registerAndRecord(key, prefix, registryUrl)
+		registration.attempted(err)
+		if err != nil {
+			log.Errorf("Failed to register namespace %s again: %v", prefix, err)
+		}
+	case keyMismatch:
+		var previous *registrationRecord
+		if record, ok := getRegistrationRecord(prefix); ok {
+			previous = &record
+		}
+		err = keyMismatchError(current, previous)
+		registration.failed(err)
+		log.Errorln(err)
+	}
+}
@@ -0,0 +1,127 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNamespaceKey(t *testing.T) jwk.Key {
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(raw)
+	require.NoError(t, err)
+	require.NoError(t, jwk.AssignKeyID(key))
+	return key
+}
+
+func TestRegistrationRecords(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Server.RegistrationStateFile", filepath.Join(t.TempDir(), "registration.json"))
+	viper.Set("Server.ExternalWebUrl", "https://origin.example.org:8444")
+	viper.Set("Server.IssuerUrl", "https://origin.example.org:8443")
+	key := newTestNamespaceKey(t)
+
+	_, ok := getRegistrationRecord("/test")
+	assert.False(t, ok)
+
+	record, err := newRegistrationRecord(key, "/test", "https://registry.example.org/api/v1.0/registry")
+	require.NoError(t, err)
+	require.NoError(t, saveRegistrationRecord(record))
+	saved, ok := getRegistrationRecord("/test")
+	require.True(t, ok)
+	assert.Equal(t, key.KeyID(), saved.KeyID)
+	assert.Equal(t, "https://origin.example.org:8444", saved.ExternalUrl)
+	assert.WithinDuration(t, time.Now(), saved.VerifiedAt, time.Minute)
+
+	// The error for a key mismatch tells whose key changed
+	otherKey := newTestNamespaceKey(t)
+	current, err := newRegistrationRecord(otherKey, "/test", "https://registry.example.org/api/v1.0/registry")
+	require.NoError(t, err)
+	err = keyMismatchError(current, &saved)
+	assert.Contains(t, err.Error(), "issuer key changed since the registration was last verified")
+	assert.Contains(t, err.Error(), saved.KeyID)
+	err = keyMismatchError(record, &saved)
+	assert.Contains(t, err.Error(), "the namespace's key was changed in the registry")
+	err = keyMismatchError(current, nil)
+	assert.Contains(t, err.Error(), "To fix this")
+
+	// A URL change is reported
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.InfoLevel)
+	defer logrus.SetLevel(level)
+	viper.Set("Server.ExternalWebUrl", "https://new-origin.example.org:8444")
+	moved, err := newRegistrationRecord(key, "/test", "https://registry.example.org/api/v1.0/registry")
+	require.NoError(t, err)
+	reportURLChanges(saved, moved)
+	require.NotEmpty(t, hook.Entries)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "https://new-origin.example.org:8444")
+}
+
+func TestCheckRegistration(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	stateFile := filepath.Join(t.TempDir(), "registration.json")
+	viper.Set("Server.RegistrationStateFile", stateFile)
+	key := newTestNamespaceKey(t)
+
+	keyMatches := &atomic.Bool{}
+	keyMatches.Store(true)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1.0/registry/checkNamespaceExists", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(checkNamespaceExistsRes{PrefixExists: true, KeyMatch: keyMatches.Load()})
+	}))
+	defer svr.Close()
+	registryUrl := svr.URL + "/api/v1.0/registry"
+
+	// While the registry holds the key, the check is recorded
+	checkRegistration(key, "/test", registryUrl)
+	record, ok := getRegistrationRecord("/test")
+	require.True(t, ok)
+	assert.Equal(t, key.KeyID(), record.KeyID)
+
+	// Once the registry's key changes, the server says so loudly
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	keyMatches.Store(false)
+	checkRegistration(key, "/test", registryUrl)
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "the namespace's key was changed in the registry")
+}
@@ -0,0 +1,183 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/web_ui"
+)
+
+type (
+	RegistrationState string
+
+	// The status of the registration of the server's namespace with the registry
+	RegistrationStatus struct {
+		Prefix   string            `json:"prefix"`
+		Registry string            `json:"registry"`
+		State    RegistrationState `json:"state"`
+		// The failed attempts to register the namespace since it was last registered
+		FailedAttempts int        `json:"failedAttempts"`
+		LastError      string     `json:"lastError,omitempty"`
+		LastAttempt    *time.Time `json:"lastAttempt,omitempty"`
+		// When the failed registration is retried, if it is
+		NextAttempt *time.Time `json:"nextAttempt,omitempty"`
+		// When the registry was last seen holding the namespace with the server's key
+		VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+	}
+
+	// Tracks the registration of the server's namespace through its states
+	registrationTracker struct {
+		mutex  sync.Mutex
+		status RegistrationStatus
+	}
+)
+
+const (
+	RegistrationPending  RegistrationState = "pending"  // The namespace isn't registered yet, and no attempt failed
+	RegistrationFailed   RegistrationState = "failed"   // The last attempt failed, or the registry holds another key
+	RegistrationComplete RegistrationState = "complete" // The registry holds the namespace with the server's key
+)
+
+var registration = newRegistrationTracker()
+
+func newRegistrationTracker() *registrationTracker {
+	tracker := &registrationTracker{}
+	tracker.setState(RegistrationPending)
+	return tracker
+}
+
+// The delay before retrying a registration that failed the given number of times in a
+// row: Server.RegistrationRetryInterval, doubled for each further failure, up to
+// Server.RegistrationMaxRetryInterval
+func registrationRetryDelay(failures int) time.Duration {
+	delay := param.Server_RegistrationRetryInterval.GetDuration()
+	if delay <= 0 {
+		delay = 10 * time.Second
+	}
+	maxDelay := max(param.Server_RegistrationMaxRetryInterval.GetDuration(), delay)
+	for ; failures > 1 && delay < maxDelay; failures-- {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Set the state, with the mutex held
+func (tracker *registrationTracker) setState(state RegistrationState) {
+	tracker.status.State = state
+	for _, other := range []RegistrationState{RegistrationPending, RegistrationFailed, RegistrationComplete} {
+		value := 0.0
+		if other == state {
+			value = 1
+		}
+		metrics.PelicanRegistrationState.WithLabelValues(string(other)).Set(value)
+	}
+}
+
+// Start tracking the registration of the namespace at the registry
+func (tracker *registrationTracker) start(prefix string, registryUrl string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.status = RegistrationStatus{Prefix: prefix, Registry: registryUrl}
+	tracker.setState(RegistrationPending)
+}
+
+// Record that the registry holds the namespace with the server's key, with the mutex held
+func (tracker *registrationTracker) setVerified(now time.Time) {
+	tracker.status.VerifiedAt = &now
+	tracker.status.FailedAttempts = 0
+	tracker.status.LastError = ""
+	tracker.status.NextAttempt = nil
+	tracker.setState(RegistrationComplete)
+}
+
+// Record that the registry holds the namespace with the server's key
+func (tracker *registrationTracker) verified() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.setVerified(time.Now())
+}
+
+// Record an attempt to register the namespace.  A failed attempt is retried after
+// the returned delay, which grows with the failures in a row.
+func (tracker *registrationTracker) attempted(err error) time.Duration {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	metrics.PelicanRegistrationAttempts.WithLabelValues(result).Inc()
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	now := time.Now()
+	tracker.status.LastAttempt = &now
+	if err == nil {
+		tracker.setVerified(now)
+		return 0
+	}
+	tracker.status.FailedAttempts++
+	delay := registrationRetryDelay(tracker.status.FailedAttempts)
+	nextAttempt := now.Add(delay)
+	tracker.status.NextAttempt = &nextAttempt
+	tracker.status.LastError = err.Error()
+	tracker.setState(RegistrationFailed)
+	return delay
+}
+
+// Record that the registration failed for a reason retrying won't fix, such as the
+// registry holding the namespace under another key
+func (tracker *registrationTracker) failed(err error) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.status.LastError = err.Error()
+	tracker.status.NextAttempt = nil
+	tracker.setState(RegistrationFailed)
+}
+
+func (tracker *registrationTracker) getStatus() RegistrationStatus {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.status
+}
+
+// Get the status of the registration of the server's namespace with the registry
+func GetRegistrationStatus() RegistrationStatus {
+	return registration.getStatus()
+}
+
+func handleGetRegistrationStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, GetRegistrationStatus())
+}
+
+// Serve the status of the registration of the server's namespace
+func RegisterRegistrationStatusAPI(router *gin.Engine) {
+	web_ui.HandleAPI(&router.RouterGroup, http.MethodGet, "/api/v1.0/registration", web_ui.APIDoc{
+		Summary: "Return the status of the registration of the server's namespace with the registry",
+		Description: `The state is "pending" until the first attempt, "failed" after a failed attempt, which is retried ` +
+			`with exponential backoff, or when the registry holds another key, and "complete" once registered`,
+		Auth:     web_ui.APIAuthLogin,
+		Response: RegistrationStatus{},
+	}, web_ui.AuthHandler, handleGetRegistrationStatus)
+}
@@ -0,0 +1,125 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+func TestRegistrationRetryDelay(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Server.RegistrationRetryInterval", 10*time.Second)
+	viper.Set("Server.RegistrationMaxRetryInterval", time.Minute)
+	for failures, expected := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		4:  time.Minute,
+		50: time.Minute,
+	} {
+		assert.Equal(t, expected, registrationRetryDelay(failures), "after %d failures", failures)
+	}
+
+	// The maximum never shortens the first delay
+	viper.Set("Server.RegistrationMaxRetryInterval", time.Second)
+	assert.Equal(t, 10*time.Second, registrationRetryDelay(3))
+}
+
+func TestRegistrationTracker(t *testing.T) {
+	viper.Reset()
+	previous := registration
+	registration = newRegistrationTracker()
+	t.Cleanup(func() {
+		viper.Reset()
+		registration = previous
+	})
+	viper.Set("Server.RegistrationRetryInterval", 10*time.Second)
+	viper.Set("Server.RegistrationMaxRetryInterval", time.Minute)
+
+	router := gin.New()
+	router.GET("/api/v1.0/registration", handleGetRegistrationStatus)
+	getStatus := func() RegistrationStatus {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/api/v1.0/registration", nil)
+		require.NoError(t, err)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		status := RegistrationStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status
+	}
+	stateMetric := func(state RegistrationState) float64 {
+		return testutil.ToFloat64(metrics.PelicanRegistrationState.WithLabelValues(string(state)))
+	}
+
+	registration.start("/test", "https://registry.example.org/api/v1.0/registry")
+	status := getStatus()
+	assert.Equal(t, RegistrationPending, status.State)
+	assert.Equal(t, "/test", status.Prefix)
+	assert.Nil(t, status.LastAttempt)
+	assert.Equal(t, 1.0, stateMetric(RegistrationPending))
+
+	failures := testutil.ToFloat64(metrics.PelicanRegistrationAttempts.WithLabelValues("failure"))
+	assert.Equal(t, 10*time.Second, registration.attempted(errors.New("registry unreachable")))
+	assert.Equal(t, 20*time.Second, registration.attempted(errors.New("registry unreachable")))
+	status = getStatus()
+	assert.Equal(t, RegistrationFailed, status.State)
+	assert.Equal(t, 2, status.FailedAttempts)
+	assert.Equal(t, "registry unreachable", status.LastError)
+	require.NotNil(t, status.NextAttempt)
+	assert.WithinDuration(t, time.Now().Add(20*time.Second), *status.NextAttempt, 5*time.Second)
+	assert.Equal(t, failures+2, testutil.ToFloat64(metrics.PelicanRegistrationAttempts.WithLabelValues("failure")))
+	assert.Equal(t, 1.0, stateMetric(RegistrationFailed))
+	assert.Equal(t, 0.0, stateMetric(RegistrationPending))
+
+	assert.Zero(t, registration.attempted(nil))
+	status = getStatus()
+	assert.Equal(t, RegistrationComplete, status.State)
+	assert.Zero(t, status.FailedAttempts)
+	assert.Empty(t, status.LastError)
+	assert.Nil(t, status.NextAttempt)
+	assert.NotNil(t, status.VerifiedAt)
+	assert.Equal(t, 1.0, stateMetric(RegistrationComplete))
+
+	// A failure retrying won't fix isn't retried
+	registration.failed(errors.New("registered under a different key"))
+	status = getStatus()
+	assert.Equal(t, RegistrationFailed, status.State)
+	assert.Equal(t, "registered under a different key", status.LastError)
+	assert.Nil(t, status.NextAttempt)
+
+	// The backoff starts over after a success
+	registration.verified()
+	assert.Equal(t, 10*time.Second, registration.attempted(errors.New("registry unreachable")))
+}
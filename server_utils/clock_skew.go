@@ -0,0 +1,140 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// Measure how far the local clock is ahead of the clock of the Pelican server at
+// serviceUrl (negative if it's behind), using the Date header of the server's response.
+// The header has a resolution of a second, so smaller differences aren't measured.
+func MeasureClockSkew(ctx context.Context, serviceUrl string) (time.Duration, error) {
+	healthUrl, err := url.JoinPath(serviceUrl, "api", "v1.0", "health")
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid server URL %s", serviceUrl)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	httpClient := http.Client{Transport: config.GetTransport(), Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to query %s", healthUrl)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	// Compare against the middle of the request, when the server most likely answered
+	localTime := start.Add(time.Since(start) / 2)
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, errors.Errorf("the response from %s has no valid Date header", healthUrl)
+	}
+	return localTime.Sub(serverTime).Truncate(time.Second), nil
+}
+
+// Compare the local clock against the director's, reporting the result as the clock
+// component of the server's health.  Returns an error if the clocks differ by more
+// than Server.ClockSkewTolerance.
+func CheckClockSkew(ctx context.Context) error {
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	if directorUrl == "" {
+		return nil
+	}
+	skew, err := MeasureClockSkew(ctx, directorUrl)
+	if err != nil {
+		log.Warningln("Failed to compare the local clock against the director's:", err)
+		metrics.SetComponentHealthStatus(metrics.Server_Clock, metrics.StatusUnknown, "Failed to compare the local clock against the director's: "+err.Error())
+		return nil
+	}
+
+	tolerance := param.Server_ClockSkewTolerance.GetDuration()
+	absSkew := skew
+	if absSkew < 0 {
+		absSkew = -absSkew
+	}
+	if absSkew > tolerance {
+		direction := "ahead of"
+		if skew < 0 {
+			direction = "behind"
+		}
+		msg := fmt.Sprintf("The local clock is %s %s the director's, more than the tolerated %s (Server.ClockSkewTolerance);"+
+			" tokens will appear expired or not yet valid to other services. Synchronize the clock with NTP", absSkew, direction, tolerance)
+		metrics.SetComponentHealthStatus(metrics.Server_Clock, metrics.StatusWarning, msg)
+		return errors.New(msg)
+	}
+	log.Debugf("The local clock is within %s of the director's", tolerance)
+	metrics.SetComponentHealthStatus(metrics.Server_Clock, metrics.StatusOK, "")
+	return nil
+}
+
+// Check the local clock against the director's at startup and every
+// Server.ClockSkewCheckInterval afterward.  If Server.FailOnClockSkew is set, a clock
+// outside the tolerance at startup is an error.  The director is the reference for
+// the federation's time, so it doesn't check itself.
+func LaunchClockSkewCheck(ctx context.Context, egrp *errgroup.Group) error {
+	directorUrl := param.Federation_DirectorUrl.GetString()
+	if directorUrl == "" || strings.TrimSuffix(directorUrl, "/") == strings.TrimSuffix(param.Server_ExternalWebUrl.GetString(), "/") {
+		return nil
+	}
+	if err := CheckClockSkew(ctx); err != nil {
+		if param.Server_FailOnClockSkew.GetBool() {
+			return err
+		}
+		log.Warningln(err)
+	}
+
+	interval := param.Server_ClockSkewCheckInterval.GetDuration()
+	if interval <= 0 {
+		log.Warningln("Server.ClockSkewCheckInterval is not positive; falling back to 15m")
+		interval = 15 * time.Minute
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := CheckClockSkew(ctx); err != nil {
+					log.Warningln(err)
+				}
+			}
+		}
+	})
+	return nil
+}
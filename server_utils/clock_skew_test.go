@@ -0,0 +1,63 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckClockSkew(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	offset := time.Duration(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1.0/health", r.URL.Path)
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	viper.Set("Federation.DirectorUrl", server.URL)
+	viper.Set("Server.ClockSkewTolerance", "30s")
+
+	skew, err := MeasureClockSkew(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, skew.Abs(), time.Second)
+	assert.NoError(t, CheckClockSkew(context.Background()))
+
+	// The local clock is ahead of the director's
+	offset = -5 * time.Minute
+	skew, err = MeasureClockSkew(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.InDelta(t, 5*time.Minute, skew, float64(2*time.Second))
+	err = CheckClockSkew(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ahead of")
+
+	viper.Set("Server.ClockSkewTolerance", "10m")
+	assert.NoError(t, CheckClockSkew(context.Background()))
+}
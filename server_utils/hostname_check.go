@@ -0,0 +1,172 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+// A host clients reach the server at, and the parameter setting it
+type serverHost struct {
+	host  string
+	param string
+}
+
+// Get the hosts clients reach the server at: Server.Hostname and the hosts of the
+// URLs the server advertises to the director
+func getServerHosts() ([]serverHost, error) {
+	hosts := []serverHost{}
+	if hostname := param.Server_Hostname.GetString(); hostname != "" {
+		hosts = append(hosts, serverHost{hostname, "Server.Hostname"})
+	}
+	for _, urlParam := range []struct {
+		name  string
+		value string
+	}{
+		{"Server.ExternalWebUrl", param.Server_ExternalWebUrl.GetString()},
+		{"Origin.Url", param.Origin_Url.GetString()},
+	} {
+		if urlParam.value == "" {
+			continue
+		}
+		parsed, err := url.Parse(urlParam.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s %q", urlParam.name, urlParam.value)
+		}
+		if parsed.Hostname() == "" {
+			return nil, errors.Errorf("%s %q has no host", urlParam.name, urlParam.value)
+		}
+		hosts = append(hosts, serverHost{parsed.Hostname(), urlParam.name})
+	}
+	return hosts, nil
+}
+
+// Check that the hosts clients reach the server at agree with each other and are
+// covered by the server's TLS certificate.  Returns the hosts that are on another
+// host than Server.Hostname, which may be aliases, as warnings, and the hosts the
+// certificate doesn't cover, which clients would refuse to connect to, as the error.
+func checkHostnames() (warnings []string, err error) {
+	hosts, err := getServerHosts()
+	if err != nil {
+		return nil, err
+	}
+	hostname := param.Server_Hostname.GetString()
+	for _, host := range hosts {
+		if hostname != "" && !strings.EqualFold(host.host, hostname) {
+			warnings = append(warnings, fmt.Sprintf("%s is on the host %s, not on Server.Hostname (%s); make sure %s is an alias of this server",
+				host.param, host.host, hostname, host.host))
+		}
+	}
+
+	certFile := param.Server_TLSCertificate.GetString()
+	if certFile == "" {
+		return warnings, nil
+	}
+	cert, err := config.LoadCertficate(certFile)
+	if err != nil {
+		return warnings, errors.Wrapf(err, "failed to load the TLS certificate %s", certFile)
+	}
+	certNames := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		certNames = append(certNames, ip.String())
+	}
+	certNamesStr := "it has no subject alternative names"
+	if len(certNames) > 0 {
+		certNamesStr = "it covers " + strings.Join(certNames, ", ")
+	}
+
+	problems := []string{}
+	checked := map[string]bool{}
+	for _, host := range hosts {
+		if checked[strings.ToLower(host.host)] {
+			continue
+		}
+		checked[strings.ToLower(host.host)] = true
+		if err := cert.VerifyHostname(host.host); err != nil {
+			problems = append(problems, fmt.Sprintf("the TLS certificate %s doesn't cover %s, the host of %s (%s), so clients will refuse to connect;"+
+				" get a certificate whose subject alternative names include %s, or set %s to a host the certificate covers",
+				certFile, host.host, host.param, certNamesStr, host.host, host.param))
+		}
+	}
+	if len(problems) > 0 {
+		return warnings, errors.New(strings.Join(problems, "; "))
+	}
+	return warnings, nil
+}
+
+// Check that Server.Hostname, the URLs the server advertises and its TLS certificate
+// agree, reporting the result as the hostname component of the server's health.
+// Returns an error if the certificate doesn't cover one of the server's hosts.
+func CheckHostnames() error {
+	warnings, err := checkHostnames()
+	for _, warning := range warnings {
+		log.Warningln(warning)
+	}
+	if err != nil {
+		metrics.SetComponentHealthStatus(metrics.OriginCache_Hostname, metrics.StatusCritical, err.Error())
+		return err
+	}
+	if len(warnings) > 0 {
+		metrics.SetComponentHealthStatus(metrics.OriginCache_Hostname, metrics.StatusWarning, strings.Join(warnings, "; "))
+		return nil
+	}
+	log.Debugln("The server's hostname and advertised URLs are covered by its TLS certificate")
+	metrics.SetComponentHealthStatus(metrics.OriginCache_Hostname, metrics.StatusOK, "")
+	return nil
+}
+
+// Check the server's hostnames against its TLS certificate at startup and every
+// Server.HostnameCheckInterval afterward, so a renewed certificate missing one of
+// them is caught too
+func LaunchHostnameCheck(ctx context.Context, egrp *errgroup.Group) {
+	if err := CheckHostnames(); err != nil {
+		log.Errorln(err)
+	}
+
+	interval := param.Server_HostnameCheckInterval.GetDuration()
+	if interval <= 0 {
+		return
+	}
+	egrp.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := CheckHostnames(); err != nil {
+					log.Errorln(err)
+				}
+			}
+		}
+	})
+}
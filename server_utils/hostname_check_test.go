@@ -0,0 +1,131 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+// Write a self-signed certificate with the subject alternative names, configuring the
+// server to use it
+func writeHostnameTestCert(t *testing.T, dnsNames []string, ips []net.IP) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "origin.example.org"},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certFile := filepath.Join(t.TempDir(), "tls.crt")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	viper.Set("Server.TLSCertificate", certFile)
+}
+
+func TestCheckHostnames(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	healthStatus := func() string {
+		return metrics.GetHealthStatus().ComponentStatus[metrics.OriginCache_Hostname.String()].Status
+	}
+	viper.Set("Server.Hostname", "origin.example.org")
+	viper.Set("Server.ExternalWebUrl", "https://origin.example.org:8444")
+	viper.Set("Origin.Url", "https://origin.example.org:8443")
+
+	t.Run("consistent", func(t *testing.T) {
+		writeHostnameTestCert(t, []string{"origin.example.org"}, nil)
+		warnings, err := checkHostnames()
+		assert.NoError(t, err)
+		assert.Empty(t, warnings)
+		require.NoError(t, CheckHostnames())
+		assert.Equal(t, "ok", healthStatus())
+	})
+
+	t.Run("covered-alias", func(t *testing.T) {
+		writeHostnameTestCert(t, []string{"origin.example.org", "*.data.example.org"}, []net.IP{net.ParseIP("192.0.2.1")})
+		viper.Set("Origin.Url", "https://xfer.data.example.org:8443")
+		viper.Set("Server.ExternalWebUrl", "https://192.0.2.1:8444")
+		t.Cleanup(func() {
+			viper.Set("Origin.Url", "https://origin.example.org:8443")
+			viper.Set("Server.ExternalWebUrl", "https://origin.example.org:8444")
+		})
+		warnings, err := checkHostnames()
+		assert.NoError(t, err)
+		require.Len(t, warnings, 2)
+		assert.Contains(t, warnings[1], "Origin.Url is on the host xfer.data.example.org")
+		require.NoError(t, CheckHostnames())
+		assert.Equal(t, "warning", healthStatus())
+	})
+
+	t.Run("uncovered-url", func(t *testing.T) {
+		writeHostnameTestCert(t, []string{"origin.example.org"}, nil)
+		viper.Set("Origin.Url", "https://other.example.org:8443")
+		t.Cleanup(func() { viper.Set("Origin.Url", "https://origin.example.org:8443") })
+		err := CheckHostnames()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't cover other.example.org, the host of Origin.Url (it covers origin.example.org)")
+		assert.Equal(t, "critical", healthStatus())
+	})
+
+	t.Run("renamed-host", func(t *testing.T) {
+		// A certificate generated for the previous hostname covers none of the new ones,
+		// which are only reported once
+		writeHostnameTestCert(t, []string{"old.example.org"}, nil)
+		_, err := checkHostnames()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the host of Server.Hostname")
+		assert.NotContains(t, err.Error(), "Server.ExternalWebUrl")
+	})
+
+	t.Run("no-sans", func(t *testing.T) {
+		writeHostnameTestCert(t, nil, nil)
+		_, err := checkHostnames()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "it has no subject alternative names")
+	})
+
+	t.Run("invalid-url", func(t *testing.T) {
+		viper.Set("Server.ExternalWebUrl", "https://")
+		t.Cleanup(func() { viper.Set("Server.ExternalWebUrl", "https://origin.example.org:8444") })
+		_, err := checkHostnames()
+		assert.ErrorContains(t, err, "Server.ExternalWebUrl")
+	})
+}
@@ -0,0 +1,180 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// A module started by the launcher, such as the web engine or the director
+	Module string
+
+	// The readiness of a module, as reported by the health endpoint
+	ModuleReadiness struct {
+		Ready bool `json:"ready"`
+		// The modules this one depends on that aren't ready yet
+		WaitingOn []string `json:"waitingOn,omitempty"`
+		// When the module finished starting, as a unix timestamp
+		StartedAt int64 `json:"startedAt,omitempty"`
+	}
+
+	moduleState struct {
+		deps    []Module
+		started chan struct{}
+		startAt time.Time
+	}
+)
+
+const (
+	ModuleWebEngine Module = "web-engine"
+	ModuleRegistry  Module = "registry"
+	ModuleDirector  Module = "director"
+	ModuleOrigin    Module = "origin"
+)
+
+var (
+	modules      = map[Module]*moduleState{}
+	modulesMutex sync.RWMutex
+)
+
+func (module Module) String() string {
+	return string(module)
+}
+
+// Add a module to the readiness graph.  The modules it depends on must be registered
+// first, so the graph can't have cycles.  A module is ready once it's marked started
+// with MarkStarted and all of its dependencies are ready.
+func RegisterModule(module Module, deps ...Module) error {
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	if _, ok := modules[module]; ok {
+		return errors.Errorf("module %s is already registered", module)
+	}
+	for _, dep := range deps {
+		if _, ok := modules[dep]; !ok {
+			return errors.Errorf("module %s depends on module %s, which isn't registered", module, dep)
+		}
+	}
+	modules[module] = &moduleState{deps: deps, started: make(chan struct{})}
+	return nil
+}
+
+// Record that a module finished starting.  Modules that aren't registered are ignored.
+func MarkStarted(module Module) {
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	state, ok := modules[module]
+	if !ok {
+		return
+	}
+	select {
+	case <-state.started:
+	default:
+		state.startAt = time.Now()
+		close(state.started)
+		log.Debugf("Module %s has started", module)
+	}
+}
+
+// Whether a module and the modules it depends on are ready.  Modules that aren't
+// registered, e.g. because they run in another process, are considered ready.
+func IsReady(module Module) bool {
+	modulesMutex.RLock()
+	defer modulesMutex.RUnlock()
+	return len(waitingOn(module)) == 0
+}
+
+// The modules a module is waiting on, including itself if it hasn't started.
+// Must be called with modulesMutex held.
+func waitingOn(module Module) (waiting []Module) {
+	state, ok := modules[module]
+	if !ok {
+		return
+	}
+	for _, dep := range state.deps {
+		waiting = append(waiting, waitingOn(dep)...)
+	}
+	select {
+	case <-state.started:
+	default:
+		waiting = append(waiting, module)
+	}
+	return
+}
+
+// Block until a module and the modules it depends on are ready, or the context is cancelled
+func WaitReady(ctx context.Context, module Module) error {
+	modulesMutex.RLock()
+	state, ok := modules[module]
+	modulesMutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	for _, dep := range state.deps {
+		if err := WaitReady(ctx, dep); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-state.started:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "gave up waiting for module %s to start", module)
+	}
+}
+
+// Report the readiness of the registered modules
+func GetModuleReadiness() map[string]ModuleReadiness {
+	modulesMutex.RLock()
+	defer modulesMutex.RUnlock()
+	readiness := make(map[string]ModuleReadiness, len(modules))
+	for module, state := range modules {
+		status := ModuleReadiness{}
+		seen := map[string]bool{}
+		for _, waiting := range waitingOn(module) {
+			if waiting != module && !seen[waiting.String()] {
+				seen[waiting.String()] = true
+				status.WaitingOn = append(status.WaitingOn, waiting.String())
+			}
+		}
+		sort.Strings(status.WaitingOn)
+		select {
+		case <-state.started:
+			status.StartedAt = state.startAt.Unix()
+			status.Ready = len(status.WaitingOn) == 0
+		default:
+		}
+		readiness[module.String()] = status
+	}
+	return readiness
+}
+
+// Remove all the modules from the readiness graph
+func ResetModules() {
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	modules = map[Module]*moduleState{}
+}
@@ -0,0 +1,73 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package server_utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleReadiness(t *testing.T) {
+	ResetModules()
+	t.Cleanup(ResetModules)
+
+	require.NoError(t, RegisterModule(ModuleWebEngine))
+	require.NoError(t, RegisterModule(ModuleRegistry, ModuleWebEngine))
+	require.NoError(t, RegisterModule(ModuleDirector, ModuleWebEngine, ModuleRegistry))
+	assert.Error(t, RegisterModule(ModuleDirector), "modules can't be registered twice")
+	assert.Error(t, RegisterModule(ModuleOrigin, Module("cache")), "dependencies must be registered first")
+
+	// Modules of other processes don't hold anything up
+	assert.True(t, IsReady(ModuleOrigin))
+	assert.NoError(t, WaitReady(context.Background(), ModuleOrigin))
+
+	MarkStarted(ModuleRegistry)
+	MarkStarted(ModuleDirector)
+	assert.False(t, IsReady(ModuleDirector), "the director waits on the web engine")
+	readiness := GetModuleReadiness()
+	assert.Equal(t, []string{"web-engine"}, readiness["director"].WaitingOn)
+	assert.False(t, readiness["director"].Ready)
+	assert.NotZero(t, readiness["director"].StartedAt)
+	assert.False(t, readiness["web-engine"].Ready)
+	assert.Empty(t, readiness["web-engine"].WaitingOn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitReady(ctx, ModuleDirector), context.DeadlineExceeded)
+
+	done := make(chan error)
+	go func() { done <- WaitReady(context.Background(), ModuleDirector) }()
+	MarkStarted(ModuleWebEngine)
+	MarkStarted(ModuleWebEngine)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the director to be ready")
+	}
+	assert.True(t, IsReady(ModuleDirector))
+	for module, status := range GetModuleReadiness() {
+		assert.True(t, status.Ready, module)
+		assert.Empty(t, status.WaitingOn, module)
+	}
+}
@@ -19,6 +19,8 @@
 package server_utils
 
 import (
+	"sync"
+
 	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 )
@@ -33,13 +35,34 @@ type (
 
 	NamespaceHolder struct {
 		namespaceAds []common.NamespaceAdV2
+		mutex        sync.RWMutex
 	}
 )
 
+var advertiseRequests = make(chan struct{}, 1)
+
+// Ask the periodic advertisement loop to advertise right away, e.g. because
+// the server's advertisement changed. Requests made while one is pending are merged.
+func TriggerAdvertise() {
+	select {
+	case advertiseRequests <- struct{}{}:
+	default:
+	}
+}
+
+// The channel the periodic advertisement loop listens on for TriggerAdvertise requests
+func AdvertiseRequests() <-chan struct{} {
+	return advertiseRequests
+}
+
 func (ns *NamespaceHolder) SetNamespaceAds(ads []common.NamespaceAdV2) {
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
 	ns.namespaceAds = ads
 }
 
 func (ns *NamespaceHolder) GetNamespaceAds() []common.NamespaceAdV2 {
+	ns.mutex.RLock()
+	defer ns.mutex.RUnlock()
 	return ns.namespaceAds
 }
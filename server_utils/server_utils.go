@@ -22,6 +22,7 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"path"
 	"reflect"
 	"time"
 
@@ -89,6 +90,24 @@ func GetServerIssuerURL() (*url.URL, error) {
 	return issuerUrl, nil
 }
 
+// Get the URL of the token issuer of the namespace at prefix.  A namespace with its own
+// IssuerKey in Origin.Exports is its own issuer, below the server's issuer URL; any other
+// namespace is issued for by the server.
+func GetNamespaceIssuerURL(prefix string) (*url.URL, error) {
+	issuerUrl, err := GetServerIssuerURL()
+	if err != nil {
+		return nil, err
+	}
+	hasKey, err := config.HasNamespaceIssuerKey(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if !hasKey {
+		return issuerUrl, nil
+	}
+	return issuerUrl.JoinPath(".well-known", "namespaces", path.Clean(prefix)), nil
+}
+
 // Launch a maintenance goroutine.
 // The maintenance routine will watch the directory `dirPath`, invoking `maintenanceFunc` whenever
 // an event occurs in the directory.  Note the behavior of directory watching differs across platforms;
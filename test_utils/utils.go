@@ -26,7 +26,9 @@ import (
 	"testing"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -78,3 +80,12 @@ func GenerateJWK() (jwk.Key, jwk.Set, string, error) {
 
 	return jwkKey, jwks, string(jwksBytes), nil
 }
+
+// Parse the problem in the body of a server API's error response, failing the test
+// if the body isn't one
+func ParseProblem(t *testing.T, body []byte) common.Problem {
+	problem := common.Problem{}
+	require.NoError(t, json.Unmarshal(body, &problem), "The error response isn't JSON: %s", string(body))
+	require.NotEmpty(t, problem.Code, "The error response isn't a problem: %s", string(body))
+	return problem
+}
@@ -13,6 +13,8 @@ const (
 	Pelican_DirectorTestReport TokenScope = "pelican.director_test_report"
 	Pelican_DirectorServiceDiscovery TokenScope = "pelican.director_service_discovery"
 	Pelican_NamespaceDelete TokenScope = "pelican.namespace_delete"
+	Pelican_NamespaceKeyUpdate TokenScope = "pelican.namespace_key_update"
+	Pelican_AccessLogs TokenScope = "pelican.access_logs"
 	WebUi_Access TokenScope = "web_ui.access"
 	Monitoring_Scrape TokenScope = "monitoring.scrape"
 	Monitoring_Query TokenScope = "monitoring.query"
@@ -0,0 +1,61 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Mark the packets sent on conn with the DSCP value dscp, or clear the marking if
+// dscp is 0.  The DSCP is the upper 6 bits of the IPv4 TOS and IPv6 traffic class.
+func SetConnDSCP(conn net.Conn, dscp int) error {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.Errorf("cannot set the DSCP on a %T connection", conn)
+	}
+	tcpAddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return errors.Errorf("cannot set the DSCP on a connection from %s", conn.LocalAddr())
+	}
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if tcpAddr.IP.To4() != nil {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, dscp<<2)
+		} else {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, dscp<<2)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(sockErr, "failed to set the DSCP of the connection")
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getConnTOS(t *testing.T, conn net.Conn) int {
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	var tos int
+	var sockErr error
+	require.NoError(t, rawConn.Control(func(fd uintptr) {
+		tos, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+	}))
+	require.NoError(t, sockErr)
+	return tos
+}
+
+func TestSetConnDSCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, SetConnDSCP(conn, 34))
+	assert.Equal(t, 34<<2, getConnTOS(t, conn))
+
+	require.NoError(t, SetConnDSCP(conn, 0))
+	assert.Equal(t, 0, getConnTOS(t, conn))
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	assert.Error(t, SetConnDSCP(client, 34))
+}
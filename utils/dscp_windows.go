@@ -0,0 +1,33 @@
+//go:build windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Windows only lets its QoS policies mark packets, so setting the DSCP of a
+// connection is not supported
+func SetConnDSCP(conn net.Conn, dscp int) error {
+	return errors.New("setting the DSCP of a connection is not supported on Windows")
+}
@@ -0,0 +1,214 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// The cached public keys of a single JWKS URL
+	jwksCacheEntry struct {
+		mutex      sync.Mutex
+		keys       jwk.Set
+		fetched    time.Time     // When the keys were last retrieved
+		lastErr    error         // The error of the last failed retrieval, if any
+		backoff    time.Duration // The current delay between failed retrievals
+		retryAfter time.Time     // No retrieval is attempted before this time after a failure
+		refreshing bool          // Whether a background refresh is in flight
+	}
+
+	// A cache of the public keys published by token issuers, keyed by JWKS URL.
+	//
+	// Keys younger than Server.IssuerJwksRefreshInterval are returned as-is.  Older keys
+	// are still returned for up to Server.IssuerJwksMaxStaleness while they're refreshed
+	// in the background, so an issuer that's briefly unreachable doesn't fail token
+	// validation.  Failed retrievals are retried with exponential backoff.
+	JWKSCache struct {
+		mutex   sync.Mutex
+		entries map[string]*jwksCacheEntry
+	}
+)
+
+const (
+	jwksMinErrorBackoff = 5 * time.Second
+	jwksMaxErrorBackoff = 5 * time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+)
+
+// The cache shared by the token validation of every server in the process
+var issuerJWKS = NewJWKSCache()
+
+func NewJWKSCache() *JWKSCache {
+	return &JWKSCache{entries: make(map[string]*jwksCacheEntry)}
+}
+
+// Get the public keys published at jwksUrl from the process-wide issuer JWKS cache
+func GetIssuerJWKS(ctx context.Context, jwksUrl string) (jwk.Set, error) {
+	return issuerJWKS.Get(ctx, jwksUrl)
+}
+
+// Retrieve and parse the key set at jwksUrl
+func fetchJWKS(ctx context.Context, jwksUrl string) (jwk.Set, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: config.GetTransport()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s returned HTTP status %d", jwksUrl, resp.StatusCode)
+	}
+	keys, err := jwk.Parse(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the public keys at %s", jwksUrl)
+	}
+	if keys.Len() == 0 {
+		return nil, errors.Errorf("public key set at %s is empty", jwksUrl)
+	}
+	return keys, nil
+}
+
+func (c *JWKSCache) getEntry(jwksUrl string) *jwksCacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[jwksUrl]
+	if !ok {
+		entry = &jwksCacheEntry{}
+		c.entries[jwksUrl] = entry
+	}
+	return entry
+}
+
+// Record the result of a retrieval; the entry's mutex must be held
+func (entry *jwksCacheEntry) update(jwksUrl string, keys jwk.Set, err error) {
+	now := time.Now()
+	if err == nil {
+		entry.keys = keys
+		entry.fetched = now
+		entry.lastErr = nil
+		entry.backoff = 0
+		entry.retryAfter = time.Time{}
+		return
+	}
+
+	entry.lastErr = err
+	entry.backoff *= 2
+	if entry.backoff < jwksMinErrorBackoff {
+		entry.backoff = jwksMinErrorBackoff
+	} else if entry.backoff > jwksMaxErrorBackoff {
+		entry.backoff = jwksMaxErrorBackoff
+	}
+	entry.retryAfter = now.Add(entry.backoff)
+	if entry.keys != nil {
+		log.Warningf("Failed to refresh the public keys at %s; using the copy retrieved at %s until the next attempt in %s: %v",
+			jwksUrl, entry.fetched.Format(time.RFC3339), entry.backoff, err)
+	} else {
+		log.Warningf("Failed to retrieve the public keys at %s; retrying no sooner than %s: %v", jwksUrl, entry.backoff, err)
+	}
+}
+
+// Refresh the keys of an entry in the background
+func (c *JWKSCache) refresh(jwksUrl string, entry *jwksCacheEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	keys, err := fetchJWKS(ctx, jwksUrl)
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	entry.refreshing = false
+	entry.update(jwksUrl, keys, err)
+}
+
+// Get the public keys published at jwksUrl, retrieving them only if there's no
+// usable copy in the cache
+func (c *JWKSCache) Get(ctx context.Context, jwksUrl string) (jwk.Set, error) {
+	entry := c.getEntry(jwksUrl)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	now := time.Now()
+	refreshInterval := param.Server_IssuerJwksRefreshInterval.GetDuration()
+	maxStaleness := param.Server_IssuerJwksMaxStaleness.GetDuration()
+	if entry.keys != nil {
+		age := now.Sub(entry.fetched)
+		if age < refreshInterval {
+			return entry.keys, nil
+		}
+		if age < refreshInterval+maxStaleness {
+			if !entry.refreshing && !now.Before(entry.retryAfter) {
+				entry.refreshing = true
+				go c.refresh(jwksUrl, entry)
+			}
+			return entry.keys, nil
+		}
+	}
+
+	// No usable keys; retrieve them now unless a recent attempt failed
+	if now.Before(entry.retryAfter) {
+		return nil, errors.Wrapf(entry.lastErr, "public keys at %s are unavailable", jwksUrl)
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+	keys, err := fetchJWKS(fetchCtx, jwksUrl)
+	entry.update(jwksUrl, keys, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve the public keys at %s", jwksUrl)
+	}
+	return keys, nil
+}
+
+// Drop every cached key set
+func (c *JWKSCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]*jwksCacheEntry)
+}
+
+// Drop the cached key set of a JWKS URL, so the next lookup retrieves it anew
+func (c *JWKSCache) Invalidate(jwksUrl string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, jwksUrl)
+}
+
+// Drop the keys published at jwksUrl from the process-wide issuer JWKS cache, such as
+// after one of them was revoked
+func InvalidateIssuerJWKS(jwksUrl string) {
+	issuerJWKS.Invalidate(jwksUrl)
+}
@@ -0,0 +1,112 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSCache(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Server.IssuerJwksRefreshInterval", "100ms")
+	viper.Set("Server.IssuerJwksMaxStaleness", "1h")
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pubKey, err := jwk.FromRaw(&privKey.PublicKey)
+	require.NoError(t, err)
+	keySet := jwk.NewSet()
+	require.NoError(t, keySet.AddKey(pubKey))
+
+	var hits atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(keySet)
+	}))
+	t.Cleanup(server.Close)
+
+	cache := NewJWKSCache()
+	ctx := context.Background()
+
+	keys, err := cache.Get(ctx, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.Len())
+	_, err = cache.Get(ctx, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), hits.Load())
+
+	t.Run("stale-while-revalidate", func(t *testing.T) {
+		failing.Store(true)
+		time.Sleep(150 * time.Millisecond)
+
+		// The stale keys are served while the refresh fails in the background
+		keys, err := cache.Get(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, 1, keys.Len())
+		require.Eventually(t, func() bool { return hits.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+		// The failed refresh isn't retried until its backoff is over
+		_, err = cache.Get(ctx, server.URL)
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(2), hits.Load())
+	})
+
+	t.Run("too-stale", func(t *testing.T) {
+		viper.Set("Server.IssuerJwksMaxStaleness", "0s")
+		defer viper.Set("Server.IssuerJwksMaxStaleness", "1h")
+
+		// Still backing off from the failed refresh, so there are no usable keys
+		_, err := cache.Get(ctx, server.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), hits.Load())
+	})
+
+	t.Run("unavailable-issuer", func(t *testing.T) {
+		cache.Clear()
+		_, err := cache.Get(ctx, server.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(3), hits.Load())
+
+		failing.Store(false)
+		_, err = cache.Get(ctx, server.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(3), hits.Load())
+	})
+}
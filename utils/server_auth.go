@@ -69,8 +69,6 @@ const (
 )
 
 var (
-	federationJWK    *jwk.Cache
-	directorJWK      *jwk.Cache
 	directorMetadata *httprc.Cache
 	authChecker      AuthChecker
 )
@@ -122,16 +120,7 @@ func LoadDirectorPublicKey() (jwk.Key, error) {
 
 	jwksUri := metadata.JwksUri
 
-	directorJwkCtx := context.Background()
-	if directorJWK == nil {
-		client := &http.Client{Transport: config.GetTransport()}
-		directorJWK = jwk.NewCache(directorJwkCtx)
-		if err := directorJWK.Register(jwksUri, jwk.WithRefreshInterval(15*time.Minute), jwk.WithHTTPClient(client)); err != nil {
-			return nil, errors.Wrap(err, "Failed to register internal JWKS cache for director's public JWKS")
-		}
-	}
-
-	jwks, err := directorJWK.Get(directorJwkCtx, jwksUri)
+	jwks, err := GetIssuerJWKS(context.Background(), jwksUri)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get director's public JWKS")
 	}
@@ -146,7 +135,7 @@ func LoadDirectorPublicKey() (jwk.Key, error) {
 // Checks that the given token was signed by the federation jwk and also checks that the token has the expected scope
 func (a AuthCheckImpl) FederationCheck(c *gin.Context, strToken string, expectedScopes []string, allScopes bool) error {
 	fedURL := param.Federation_DiscoveryUrl.GetString()
-	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false))
+	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 
 	if err != nil {
 		return err
@@ -157,28 +146,19 @@ func (a AuthCheckImpl) FederationCheck(c *gin.Context, strToken string, expected
 	}
 
 	fedURIFile := param.Federation_JwkUrl.GetString()
-	ctx := context.Background()
-	if federationJWK == nil {
-		client := &http.Client{Transport: config.GetTransport()}
-		federationJWK = jwk.NewCache(ctx)
-		if err := federationJWK.Register(fedURIFile, jwk.WithRefreshInterval(15*time.Minute), jwk.WithHTTPClient(client)); err != nil {
-			return errors.Wrap(err, "Failed to register cache for federation's public JWKS")
-		}
-	}
-
-	jwks, err := federationJWK.Get(ctx, fedURIFile)
+	jwks, err := GetIssuerJWKS(context.Background(), fedURIFile)
 	if err != nil {
 		return errors.Wrap(err, "Failed to get federation's public JWKS")
 	}
 
-	parsed, err := jwt.Parse([]byte(strToken), jwt.WithKeySet(jwks))
+	parsed, err := jwt.Parse([]byte(strToken), jwt.WithKeySet(jwks), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 
 	if err != nil {
 		return errors.Wrap(err, "Failed to verify JWT by federation's key")
 	}
 
 	scopeValidator := token_scopes.CreateScopeValidator(expectedScopes, allScopes)
-	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator)); err != nil {
+	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration())); err != nil {
 		return errors.Wrap(err, "Failed to verify the scope of the token")
 	}
 
@@ -192,7 +172,7 @@ func (a AuthCheckImpl) FederationCheck(c *gin.Context, strToken string, expected
 // Note that this means the issuer jwk MUST be the one server created. It can't be provided by
 // the user if they want to use a different issuer than the server. This can be changed in the future.
 func (a AuthCheckImpl) IssuerCheck(c *gin.Context, strToken string, expectedScopes []string, allScopes bool) error {
-	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false))
+	token, err := jwt.Parse([]byte(strToken), jwt.WithVerify(false), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
 		return errors.Wrap(err, "Invalid JWT")
 	}
@@ -213,14 +193,14 @@ func (a AuthCheckImpl) IssuerCheck(c *gin.Context, strToken string, expectedScop
 		return errors.Wrap(err, "Failed to load issuer server's public key")
 	}
 
-	parsed, err := jwt.Parse([]byte(strToken), jwt.WithKeySet(jwks))
+	parsed, err := jwt.Parse([]byte(strToken), jwt.WithKeySet(jwks), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 
 	if err != nil {
 		return errors.Wrap(err, "Failed to verify JWT by issuer's key")
 	}
 
 	scopeValidator := token_scopes.CreateScopeValidator(expectedScopes, allScopes)
-	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator)); err != nil {
+	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration())); err != nil {
 		return errors.Wrap(err, "Failed to verify the scope of the token")
 	}
 
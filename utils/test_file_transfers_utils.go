@@ -54,13 +54,25 @@ type (
 const (
 	OriginSelfFileTest TestType = "self-test"
 	DirectorFileTest   TestType = "director-test"
+	CacheFileTest      TestType = "cache-test"
 )
 
 const (
 	selfTestBody     string = "This object was created by the Pelican self-test functionality"
 	directorTestBody string = "This object was created by the Pelican director-test functionality"
+	// The director serves the cache-test object itself, so the body is exported
+	CacheTestBody string = "This object was served by the Pelican director for the cache-test functionality"
 )
 
+// The object pulled through a cache by the cache test. The name is fixed so that
+// each test cycle doesn't leave a new object behind in the cache; the director
+// serves it with CacheTestBody and marks it as not to be stored.
+const CacheTestObjectPath = "/pelican/monitoring/" + string(CacheFileTest) + ".txt"
+
+// Older versions used a unique object under this prefix for each test cycle.
+// The director still serves them so those caches keep passing the test.
+const CacheTestObjectPrefix = "/pelican/monitoring/" + string(CacheFileTest) + "-"
+
 func (t TestType) String() string {
 	return string(t)
 }
@@ -95,6 +107,15 @@ func (t TestFileTransferImpl) generateFileTestScitoken() (string, error) {
 	return tok, nil
 }
 
+// The path to a test file of the `testType`. Uploaded test files are unique to each
+// test cycle, while the cache test always pulls the same object
+func testfilePath(testType TestType) string {
+	if testType == CacheFileTest {
+		return CacheTestObjectPath
+	}
+	return "/pelican/monitoring/" + testType.String() + "-" + time.Now().Format(time.RFC3339) + ".txt"
+}
+
 // Private function to upload a test file to the `baseUrl` of an exported xrootd file direcotry
 // the test file content is based on the `testType` attribute
 func (t TestFileTransferImpl) uploadTestfile(ctx context.Context, baseUrl string) (string, error) {
@@ -107,7 +128,7 @@ func (t TestFileTransferImpl) uploadTestfile(ctx context.Context, baseUrl string
 	if err != nil {
 		return "", errors.Wrap(err, "The baseUrl is not parseable as a URL")
 	}
-	uploadURL.Path = "/pelican/monitoring/" + t.testType.String() + "-" + time.Now().Format(time.RFC3339) + ".txt"
+	uploadURL.Path = testfilePath(t.testType)
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL.String(), bytes.NewBuffer([]byte(t.testBody)))
 	if err != nil {
@@ -144,6 +165,11 @@ func (t TestFileTransferImpl) downloadTestfile(ctx context.Context, downloadUrl
 		return errors.Wrap(err, "Failed to create GET request for test file transfer download")
 	}
 	req.Header.Set("Authorization", "Bearer "+tkn)
+	if t.testType == CacheFileTest {
+		// Ask the cache not to answer from a copy left by an earlier test cycle
+		req.Header.Set("Cache-Control", "no-cache")
+		req.Header.Set("Pragma", "no-cache")
+	}
 
 	client := http.Client{Transport: config.GetTransport()}
 
@@ -199,7 +225,8 @@ func (t TestFileTransferImpl) deleteTestfile(ctx context.Context, fileUrl string
 // Run a file transfer test suite with upload/download/delete a test file from
 // the server and a xrootd service. It expects `baseUrl` to be the url to the xrootd
 // endpoint, `issuerUrl` be the url to issue scitoken for file transfer, and the
-// test file content/name be based on `testType`
+// test file content/name be based on `testType`. For the CacheFileTest, `baseUrl` is
+// the url to the cache and the test only downloads a file served by the director
 //
 // Note that for this test to work, you need to have the `issuerUrl` registered in
 // your xrootd as a list of trusted token issuers and the issuer is expected to follow
@@ -214,6 +241,9 @@ func (t TestFileTransferImpl) RunTests(ctx context.Context, baseUrl, issuerUrl s
 		t.testBody = selfTestBody
 	} else if testType == DirectorFileTest {
 		t.testBody = directorTestBody
+	} else if testType == CacheFileTest {
+		t.testBody = CacheTestBody
+		return t.runCacheTest(ctx, baseUrl)
 	} else {
 		return false, errors.New("Unsupported testType: " + testType.String())
 	}
@@ -232,3 +262,20 @@ func (t TestFileTransferImpl) RunTests(ctx context.Context, baseUrl, issuerUrl s
 	}
 	return true, nil
 }
+
+// Run the download-only cache test. A cache can't accept uploads, so instead we pull
+// the CacheTestObjectPath object through the cache at `baseUrl`. On a cache miss,
+// the cache asks the director for the object and follows the director's redirect to fetch
+// it, so a successful download validates the full redirect+fetch path of the cache
+func (t TestFileTransferImpl) runCacheTest(ctx context.Context, baseUrl string) (bool, error) {
+	downloadUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return false, errors.Wrap(err, "The baseUrl is not parseable as a URL")
+	}
+	downloadUrl.Path = testfilePath(t.testType)
+
+	if err = t.downloadTestfile(ctx, downloadUrl.String()); err != nil {
+		return false, errors.Wrap(err, "Test file transfer failed during download")
+	}
+	return true, nil
+}
@@ -26,7 +26,6 @@ import (
 	"regexp"
 	"time"
 
-	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/pkg/errors"
@@ -178,9 +177,40 @@ func (tokenConfig *TokenConfig) CreateToken() (string, error) {
 		return "", errors.Wrap(err, "Invalid tokenConfig")
 	}
 
+	tok, err := tokenConfig.buildToken()
+	if err != nil {
+		return "", err
+	}
+
+	// Now that we have a token, it needs signing. Note that GetIssuerPrivateJWK
+	// will get the private key passed via the command line because that
+	// file path has already been bound to IssuerKey
+	key, err := config.GetIssuerPrivateJWK()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to load signing keys. Either generate one at the default "+
+			"location by serving an origin, or provide one via the --private-key flag")
+	}
+	return signToken(tok, key)
+}
+
+// CreateTokenWithKey is like CreateToken, but signs the token with key instead of the
+// server's issuer key, e.g. with the key of one of the origin's namespaces
+func (tokenConfig *TokenConfig) CreateTokenWithKey(key jwk.Key) (string, error) {
+	if ok, err := tokenConfig.Validate(); !ok || err != nil {
+		return "", errors.Wrap(err, "Invalid tokenConfig")
+	}
+	tok, err := tokenConfig.buildToken()
+	if err != nil {
+		return "", err
+	}
+	return signToken(tok, key)
+}
+
+// Build the unsigned token described by the TokenConfig
+func (tokenConfig *TokenConfig) buildToken() (jwt.Token, error) {
 	jti_bytes := make([]byte, 16)
 	if _, err := rand.Read(jti_bytes); err != nil {
-		return "", err
+		return nil, err
 	}
 	jti := base64.RawURLEncoding.EncodeToString(jti_bytes)
 
@@ -188,20 +218,20 @@ func (tokenConfig *TokenConfig) CreateToken() (string, error) {
 	if tokenConfig.Issuer != "" {
 		url, err := url.Parse(tokenConfig.Issuer)
 		if err != nil {
-			return "", errors.Wrap(err, "Failed to parse the configured IssuerUrl")
+			return nil, errors.Wrap(err, "Failed to parse the configured IssuerUrl")
 		}
 		issuerUrl = url.String()
 	} else {
 		issuerUrlStr := viper.GetString("IssuerUrl")
 		url, err := url.Parse(issuerUrlStr)
 		if err != nil {
-			return "", errors.Wrap(err, "Failed to parse the configured IssuerUrl")
+			return nil, errors.Wrap(err, "Failed to parse the configured IssuerUrl")
 		}
 		issuerUrl = url.String()
 	}
 
 	if issuerUrl == "" {
-		return "", errors.New("No issuer was found in the configuration file, and none was provided as a claim")
+		return nil, errors.New("No issuer was found in the configuration file, and none was provided as a claim")
 	}
 
 	now := time.Now()
@@ -232,25 +262,24 @@ func (tokenConfig *TokenConfig) CreateToken() (string, error) {
 
 	tok, err := builder.Build()
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to generate token")
+		return nil, errors.Wrap(err, "Failed to generate token")
 	}
+	return tok, nil
+}
 
-	// Now that we have a token, it needs signing. Note that GetIssuerPrivateJWK
-	// will get the private key passed via the command line because that
-	// file path has already been bound to IssuerKey
-	key, err := config.GetIssuerPrivateJWK()
+func signToken(tok jwt.Token, key jwk.Key) (string, error) {
+	// Get/assign the kid, needed for verification by the client
+	err := jwk.AssignKeyID(key)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to load signing keys. Either generate one at the default "+
-			"location by serving an origin, or provide one via the --private-key flag")
+		return "", errors.Wrap(err, "Failed to assign kid to the token")
 	}
 
-	// Get/assign the kid, needed for verification by the client
-	err = jwk.AssignKeyID(key)
+	alg, err := config.KeySignatureAlgorithm(key)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to assign kid to the token")
+		return "", errors.Wrap(err, "Failed to determine the algorithm to sign the token with")
 	}
 
-	signed, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, key))
+	signed, err := jwt.Sign(tok, jwt.WithKey(alg, key))
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to sign the deletion token")
 	}
@@ -0,0 +1,65 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Listen on the unix socket at socketPath.  Access to the socket is controlled by its
+// file permissions, given as an octal string such as "0660".  A socket left behind by
+// a previous run is replaced.
+func ListenUnixSocket(socketPath string, mode string) (net.Listener, error) {
+	if socketPath == "" {
+		return nil, errors.New("no path was given for the unix socket")
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return nil, errors.Errorf("invalid permissions %q for the unix socket %s; expected an octal mode such as 0660", mode, socketPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create the directory of the unix socket %s", socketPath)
+	}
+	if info, err := os.Lstat(socketPath); err == nil {
+		// Never remove something that isn't a socket, such as a mistyped path to a config file
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("%s exists and is not a unix socket", socketPath)
+		}
+		if err = os.Remove(socketPath); err != nil {
+			return nil, errors.Wrapf(err, "failed to remove the previous unix socket %s", socketPath)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", socketPath)
+	}
+	if err = os.Chmod(socketPath, os.FileMode(perm)); err != nil {
+		listener.Close()
+		return nil, errors.Wrapf(err, "failed to set the permissions of the unix socket %s", socketPath)
+	}
+	log.Debugf("Listening on the unix socket %s (mode %04o)", socketPath, perm)
+	return listener, nil
+}
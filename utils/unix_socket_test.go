@@ -0,0 +1,63 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package utils
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "sub", "pelican.sock")
+
+	_, err := ListenUnixSocket(socketPath, "0999")
+	assert.Error(t, err)
+	_, err = ListenUnixSocket(socketPath, "01777")
+	assert.Error(t, err)
+
+	ln, err := ListenUnixSocket(socketPath, "0660")
+	require.NoError(t, err)
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	conn.Close()
+
+	// Leave the socket behind as a crashed server would; the next listener replaces it
+	if unixLn, ok := ln.(*net.UnixListener); ok {
+		unixLn.SetUnlinkOnClose(false)
+	}
+	require.NoError(t, ln.Close())
+	ln, err = ListenUnixSocket(socketPath, "0600")
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+
+	// Other files are never replaced
+	filePath := filepath.Join(t.TempDir(), "pelican.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte("Debug: true"), 0644))
+	_, err = ListenUnixSocket(filePath, "0660")
+	assert.Error(t, err)
+	assert.FileExists(t, filePath)
+}
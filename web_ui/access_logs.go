@@ -0,0 +1,134 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/apiclient"
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/token_scopes"
+)
+
+// The number of accesses returned by default
+const defaultAccessLogLimit = 1000
+
+// Get the public keys the namespace prefix is registered with; a variable so
+// tests can avoid the registry
+var getNamespaceKeys = func(ctx context.Context, prefix string) (jwk.Set, error) {
+	client, err := apiclient.NewRegistryClient(param.Federation_RegistryUrl.GetString(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetNamespaceKeys(ctx, prefix)
+}
+
+// Check that the token is signed with a key the namespace is registered with and
+// grants the pelican.access_logs scope, proving the bearer owns the namespace
+func verifyNamespaceOwnerToken(ctx context.Context, token, namespace string) error {
+	keys, err := getNamespaceKeys(ctx, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the public keys of %s from the registry", namespace)
+	}
+	parsed, err := jwt.Parse([]byte(token), jwt.WithKeySet(keys), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
+	if err != nil {
+		return errors.Wrapf(err, "the token isn't signed with a key of %s", namespace)
+	}
+	scopeValidator := token_scopes.CreateScopeValidator([]string{token_scopes.Pelican_AccessLogs.String()}, true)
+	if err = jwt.Validate(parsed, jwt.WithValidator(scopeValidator), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration())); err != nil {
+		return errors.Wrap(err, "the token doesn't grant access to the access logs")
+	}
+	return nil
+}
+
+// Let the owners of the namespace in the "namespace" query parameter, with a
+// bearer token signed by the namespace's key, or the server's administrators through
+// the login cookie
+func accessLogAuthHandler(ctx *gin.Context) {
+	namespace := ctx.Query("namespace")
+	if namespace == "" || !strings.HasPrefix(namespace, "/") {
+		AbortWithProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidPrefix, "The 'namespace' query parameter must be an absolute path")
+		return
+	}
+	namespace = path.Clean(namespace)
+
+	if authHeader := ctx.GetHeader("Authorization"); authHeader != "" {
+		token, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok {
+			AbortWithProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "Authorization header is not Bearer type")
+			return
+		}
+		if err := verifyNamespaceOwnerToken(ctx.Request.Context(), token, namespace); err != nil {
+			log.Debugf("Refusing a request for the access logs of %s: %v", namespace, err)
+			AbortWithProblem(ctx, http.StatusForbidden, common.ErrCodeInvalidToken, "The token doesn't prove ownership of "+namespace+": "+err.Error())
+			return
+		}
+		ctx.Set("User", "owner of "+namespace)
+		ctx.Next()
+		return
+	}
+
+	user, err := GetUser(ctx)
+	if err != nil || user == "" {
+		AbortWithProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "Authentication required; log in or send a token signed by the namespace's key")
+		return
+	}
+	if isAdmin, msg := CheckAdmin(user); !isAdmin {
+		AbortWithProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, msg)
+		return
+	}
+	ctx.Set("User", user)
+	ctx.Next()
+}
+
+// GET /api/v1.0/access_logs
+//
+// List the recent accesses to the objects of a namespace
+func getAccessLogs(ctx *gin.Context) {
+	since := time.Time{}
+	if sinceStr := ctx.Query("since"); sinceStr != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, sinceStr); err != nil {
+			WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid time "+sinceStr+" in the 'since' query parameter; expected RFC 3339")
+			return
+		}
+	}
+	limit := defaultAccessLogLimit
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+			WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The 'limit' query parameter must be a positive integer")
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, metrics.GetAccessLog(path.Clean(ctx.Query("namespace")), since, limit))
+}
@@ -0,0 +1,111 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogAuthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newKey := func() jwk.Key {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(privKey)
+		require.NoError(t, err)
+		require.NoError(t, key.Set(jwk.KeyIDKey, "namespace-key"))
+		require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+		return key
+	}
+	signToken := func(key jwk.Key, scope string) string {
+		tok, err := jwt.NewBuilder().
+			Issuer("https://origin.example.com").
+			Expiration(time.Now().Add(time.Minute)).
+			Claim("scope", scope).
+			Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, key))
+		require.NoError(t, err)
+		return string(signed)
+	}
+
+	ownerKey := newKey()
+	publicKey, err := ownerKey.PublicKey()
+	require.NoError(t, err)
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(publicKey))
+
+	oldGetNamespaceKeys := getNamespaceKeys
+	t.Cleanup(func() { getNamespaceKeys = oldGetNamespaceKeys })
+	getNamespaceKeys = func(ctx context.Context, prefix string) (jwk.Set, error) {
+		if prefix != "/foo" {
+			return nil, errors.New("namespace not found")
+		}
+		return keys, nil
+	}
+
+	router := gin.New()
+	router.GET("/api/v1.0/access_logs", accessLogAuthHandler, func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.GetString("User"))
+	})
+
+	for _, tc := range []struct {
+		name      string
+		namespace string
+		token     string
+		status    int
+	}{
+		{"owner", "/foo", signToken(ownerKey, "pelican.access_logs"), http.StatusOK},
+		{"owner-uncleaned-namespace", "/foo/", signToken(ownerKey, "pelican.access_logs"), http.StatusOK},
+		{"missing-scope", "/foo", signToken(ownerKey, "storage.read:/"), http.StatusForbidden},
+		{"other-key", "/foo", signToken(newKey(), "pelican.access_logs"), http.StatusForbidden},
+		{"other-namespace", "/bar", signToken(ownerKey, "pelican.access_logs"), http.StatusForbidden},
+		{"no-namespace", "", signToken(ownerKey, "pelican.access_logs"), http.StatusBadRequest},
+		{"no-credentials", "/foo", "", http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1.0/access_logs?namespace="+tc.namespace, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.status == http.StatusOK {
+				assert.Equal(t, "owner of /foo", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/metrics"
+)
+
+// The number of days reported by default
+const defaultAccountingDays = 30
+
+// GET /api/v1.0/accounting
+//
+// Report the bytes read and written per token subject and day
+func getAccountingReport(ctx *gin.Context) {
+	filter := metrics.AccountingFilter{
+		From:    ctx.Query("from"),
+		To:      ctx.Query("to"),
+		Issuer:  ctx.Query("issuer"),
+		Subject: ctx.Query("subject"),
+	}
+	to := time.Now().UTC()
+	if filter.To != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, filter.To); err != nil {
+			WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid date %q in the 'to' query parameter; expected YYYY-MM-DD", filter.To))
+			return
+		}
+	}
+	filter.To = to.Format(time.DateOnly)
+	if filter.From == "" {
+		filter.From = to.AddDate(0, 0, -(defaultAccountingDays - 1)).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, filter.From); err != nil {
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprintf("Invalid date %q in the 'from' query parameter; expected YYYY-MM-DD", filter.From))
+		return
+	}
+	if filter.From > filter.To {
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "The 'from' date is after the 'to' date")
+		return
+	}
+	ctx.JSON(http.StatusOK, metrics.GetAccountingReport(filter))
+}
@@ -0,0 +1,507 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/metrics"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// A rule raising an alert for every series its PromQL expression returns,
+	// configured in Monitoring.Alerting.Rules
+	AlertRule struct {
+		Name     string        `mapstructure:"Name" json:"name"`
+		Expr     string        `mapstructure:"Expr" json:"expr"`
+		For      time.Duration `mapstructure:"For" json:"for"`
+		Severity string        `mapstructure:"Severity" json:"severity,omitempty"`
+		Summary  string        `mapstructure:"Summary" json:"summary,omitempty"`
+	}
+
+	AlertState string
+
+	// An alert a rule raised for one of the series its expression returned
+	Alert struct {
+		Rule     string            `json:"rule"`
+		Severity string            `json:"severity,omitempty"`
+		Summary  string            `json:"summary,omitempty"`
+		Labels   map[string]string `json:"labels"`
+		Value    float64           `json:"value"`
+		State    AlertState        `json:"state"`
+		ActiveAt time.Time         `json:"activeAt"` // When the expression first returned the series
+
+		firedAt      time.Time
+		resolvedAt   time.Time
+		lastNotified time.Time
+	}
+
+	// A series returned by the expression of an alerting rule
+	alertSample struct {
+		Labels map[string]string
+		Value  float64
+	}
+
+	// Evaluates the alerting rules over the embedded Prometheus and notifies of the
+	// alerts that fire, are still firing after the repeat interval, or resolve
+	alertEvaluator struct {
+		rules          []AlertRule
+		summaries      map[string]*template.Template
+		query          func(ctx context.Context, expr string) ([]alertSample, error)
+		notify         func(ctx context.Context, alerts []Alert)
+		repeatInterval time.Duration
+
+		mutex  sync.RWMutex
+		active map[string]*Alert // Keyed by the rule name and the series' labels
+	}
+
+	// The notification format of Alertmanager's webhook receiver
+	alertWebhookMessage struct {
+		Version           string              `json:"version"`
+		GroupKey          string              `json:"groupKey"`
+		Status            AlertState          `json:"status"`
+		Receiver          string              `json:"receiver"`
+		GroupLabels       map[string]string   `json:"groupLabels"`
+		CommonLabels      map[string]string   `json:"commonLabels"`
+		CommonAnnotations map[string]string   `json:"commonAnnotations"`
+		ExternalURL       string              `json:"externalURL"`
+		Alerts            []alertWebhookAlert `json:"alerts"`
+	}
+
+	alertWebhookAlert struct {
+		Status      AlertState        `json:"status"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		StartsAt    time.Time         `json:"startsAt"`
+		EndsAt      time.Time         `json:"endsAt"`
+	}
+)
+
+const (
+	AlertPending  AlertState = "pending"
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+var (
+	// The rules evaluated unless Monitoring.Alerting.DisableDefaultRules is set; a rule whose
+	// metrics the server doesn't have never fires
+	defaultAlertRules = []AlertRule{
+		{
+			Name:     "PelicanOriginDown",
+			Expr:     `up{job="origin_cache_servers", server_type="Origin"} == 0`,
+			For:      5 * time.Minute,
+			Severity: "critical",
+			Summary:  "The origin {{ .Labels.server_name }} is advertised to the director but can't be reached",
+		},
+		{
+			Name:     "PelicanCacheFull",
+			Expr:     `xrootd_storage_volume_bytes{ns="/cache", type="free"} / ignoring(type) xrootd_storage_volume_bytes{ns="/cache", type="total"} < 0.05`,
+			For:      15 * time.Minute,
+			Severity: "warning",
+			Summary:  "The cache{{ with .Labels.server_name }} {{ . }}{{ end }} has only {{ printf \"%.1f\" (mul .Value 100) }}% of its disk free",
+		},
+		{
+			Name:     "PelicanAdvertisementStale",
+			Expr:     `pelican_component_health_status{component="federation"} < 3`,
+			For:      10 * time.Minute,
+			Severity: "warning",
+			Summary:  "The server{{ with .Labels.server_name }} {{ . }}{{ end }} has failed to advertise to the director for 10 minutes",
+		},
+		{
+			Name:     "PelicanCertificateExpiring",
+			Expr:     `pelican_server_tls_certificate_expiry_timestamp_seconds - time() < 14 * 86400`,
+			Severity: "warning",
+			Summary:  "The TLS certificate of the server{{ with .Labels.server_name }} {{ . }}{{ end }} expires in {{ printf \"%.0f\" (div .Value 86400) }} days",
+		},
+	}
+
+	alertTemplateFuncs = template.FuncMap{
+		"mul": func(a, b float64) float64 { return a * b },
+		"div": func(a, b float64) float64 { return a / b },
+	}
+
+	activeAlertEvaluator      *alertEvaluator
+	activeAlertEvaluatorMutex sync.RWMutex
+)
+
+// Get the configured alerting rules and the default rules, unless disabled
+func getAlertRules() ([]AlertRule, error) {
+	rules := []AlertRule{}
+	if !param.Monitoring_Alerting_DisableDefaultRules.GetBool() {
+		rules = append(rules, defaultAlertRules...)
+	}
+	configured := []AlertRule{}
+	if err := param.Monitoring_Alerting_Rules.Unmarshal(&configured); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Monitoring.Alerting.Rules")
+	}
+	return append(rules, configured...), nil
+}
+
+func newAlertEvaluator(rules []AlertRule, query func(ctx context.Context, expr string) ([]alertSample, error),
+	notify func(ctx context.Context, alerts []Alert), repeatInterval time.Duration) (*alertEvaluator, error) {
+	evaluator := &alertEvaluator{
+		rules:          rules,
+		summaries:      make(map[string]*template.Template, len(rules)),
+		query:          query,
+		notify:         notify,
+		repeatInterval: repeatInterval,
+		active:         map[string]*Alert{},
+	}
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Expr == "" {
+			return nil, errors.New("every alerting rule must have a Name and an Expr")
+		}
+		if _, ok := evaluator.summaries[rule.Name]; ok {
+			return nil, errors.Errorf("there is more than one alerting rule named %s", rule.Name)
+		}
+		if rule.For < 0 {
+			return nil, errors.Errorf("the For of the alerting rule %s must not be negative", rule.Name)
+		}
+		summary, err := template.New(rule.Name).Funcs(alertTemplateFuncs).Option("missingkey=zero").Parse(rule.Summary)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid summary of the alerting rule %s", rule.Name)
+		}
+		evaluator.summaries[rule.Name] = summary
+	}
+	return evaluator, nil
+}
+
+// Identify the alert of a rule for a series by the rule's name and the series' labels
+func alertKey(ruleName string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := strings.Builder{}
+	key.WriteString(ruleName)
+	for _, name := range names {
+		fmt.Fprintf(&key, ",%s=%q", name, labels[name])
+	}
+	return key.String()
+}
+
+func (evaluator *alertEvaluator) renderSummary(alert *Alert) string {
+	buf := &bytes.Buffer{}
+	if err := evaluator.summaries[alert.Rule].Execute(buf, alert); err != nil {
+		log.Debugf("Failed to render the summary of the alert %s: %v", alert.Rule, err)
+		return evaluator.summaries[alert.Rule].Root.String()
+	}
+	return buf.String()
+}
+
+// Evaluate the rules at now and notify of the alerts that changed.  A rule that fails to
+// evaluate keeps its alerts as they were, so a broken query doesn't resolve them.
+func (evaluator *alertEvaluator) evaluate(ctx context.Context, now time.Time) {
+	toNotify := []Alert{}
+	evaluator.mutex.Lock()
+	for _, rule := range evaluator.rules {
+		samples, err := evaluator.query(ctx, rule.Expr)
+		if err != nil {
+			log.Warningf("Failed to evaluate the alerting rule %s: %v", rule.Name, err)
+			continue
+		}
+		returned := map[string]bool{}
+		for _, sample := range samples {
+			key := alertKey(rule.Name, sample.Labels)
+			returned[key] = true
+			alert, ok := evaluator.active[key]
+			if !ok {
+				alert = &Alert{Rule: rule.Name, Severity: rule.Severity, Labels: sample.Labels, State: AlertPending, ActiveAt: now}
+				evaluator.active[key] = alert
+			}
+			alert.Value = sample.Value
+			alert.Summary = evaluator.renderSummary(alert)
+			if alert.State == AlertPending && now.Sub(alert.ActiveAt) >= rule.For {
+				alert.State = AlertFiring
+				alert.firedAt = now
+				log.Warningf("Alert %s is firing: %s", rule.Name, alert.Summary)
+			}
+			if alert.State == AlertFiring && (alert.lastNotified.IsZero() ||
+				(evaluator.repeatInterval > 0 && now.Sub(alert.lastNotified) >= evaluator.repeatInterval)) {
+				alert.lastNotified = now
+				toNotify = append(toNotify, *alert)
+			}
+		}
+		for key, alert := range evaluator.active {
+			if alert.Rule != rule.Name || returned[key] {
+				continue
+			}
+			delete(evaluator.active, key)
+			if alert.State == AlertFiring {
+				alert.State = AlertResolved
+				alert.resolvedAt = now
+				log.Infof("Alert %s resolved: %s", rule.Name, alert.Summary)
+				toNotify = append(toNotify, *alert)
+			}
+		}
+	}
+	evaluator.mutex.Unlock()
+
+	if len(toNotify) > 0 {
+		evaluator.notify(ctx, toNotify)
+	}
+}
+
+// List the pending and firing alerts, ordered by rule and then by when they became active
+func (evaluator *alertEvaluator) listAlerts() []Alert {
+	evaluator.mutex.RLock()
+	defer evaluator.mutex.RUnlock()
+	alerts := make([]Alert, 0, len(evaluator.active))
+	for _, alert := range evaluator.active {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].ActiveAt.Before(alerts[j].ActiveAt)
+	})
+	return alerts
+}
+
+// Build the webhook notification of alerts of the same state
+func newAlertWebhookMessage(status AlertState, alerts []Alert) alertWebhookMessage {
+	externalUrl := param.Server_ExternalWebUrl.GetString()
+	msg := alertWebhookMessage{
+		Version:           "4",
+		GroupKey:          "pelican:" + externalUrl,
+		Status:            status,
+		Receiver:          "pelican",
+		GroupLabels:       map[string]string{},
+		CommonLabels:      map[string]string{},
+		CommonAnnotations: map[string]string{},
+		ExternalURL:       externalUrl,
+		Alerts:            make([]alertWebhookAlert, 0, len(alerts)),
+	}
+	for _, alert := range alerts {
+		labels := make(map[string]string, len(alert.Labels)+2)
+		for name, value := range alert.Labels {
+			labels[name] = value
+		}
+		labels["alertname"] = alert.Rule
+		if alert.Severity != "" {
+			labels["severity"] = alert.Severity
+		}
+		msg.Alerts = append(msg.Alerts, alertWebhookAlert{
+			Status:      alert.State,
+			Labels:      labels,
+			Annotations: map[string]string{"summary": alert.Summary},
+			StartsAt:    alert.firedAt,
+			EndsAt:      alert.resolvedAt,
+		})
+	}
+	return msg
+}
+
+// POST a notification of alerts to a webhook
+func sendAlertWebhook(ctx context.Context, webhookUrl string, msg alertWebhookMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := http.Client{Transport: config.GetTransport(), Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("the webhook responded with HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Format an e-mail notifying of alerts
+func formatAlertEmail(from string, to []string, alerts []Alert) []byte {
+	firing := 0
+	for _, alert := range alerts {
+		if alert.State == AlertFiring {
+			firing++
+		}
+	}
+	subject := fmt.Sprintf("[Pelican] %d alerts firing, %d resolved on %s", firing, len(alerts)-firing, param.Server_Hostname.GetString())
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n", from, strings.Join(to, ", "), subject)
+	for _, alert := range alerts {
+		fmt.Fprintf(buf, "[%s] %s", strings.ToUpper(string(alert.State)), alert.Rule)
+		if alert.Severity != "" {
+			fmt.Fprintf(buf, " (%s)", alert.Severity)
+		}
+		fmt.Fprintf(buf, "\r\n  %s\r\n  Active since %s\r\n\r\n", alert.Summary, alert.ActiveAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(buf, "Server: %s\r\n", param.Server_ExternalWebUrl.GetString())
+	return buf.Bytes()
+}
+
+// E-mail a notification of alerts through Monitoring.Alerting.Email.SmtpServer
+func sendAlertEmail(alerts []Alert) error {
+	server := param.Monitoring_Alerting_Email_SmtpServer.GetString()
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Monitoring.Alerting.Email.SmtpServer %s; expected host:port", server)
+	}
+	var auth smtp.Auth
+	if username := param.Monitoring_Alerting_Email_Username.GetString(); username != "" {
+		password, err := os.ReadFile(param.Monitoring_Alerting_Email_PasswordFile.GetString())
+		if err != nil {
+			return errors.Wrap(err, "failed to read Monitoring.Alerting.Email.PasswordFile")
+		}
+		auth = smtp.PlainAuth("", username, strings.TrimSpace(string(password)), host)
+	}
+	from := param.Monitoring_Alerting_Email_From.GetString()
+	to := param.Monitoring_Alerting_Email_To.GetStringSlice()
+	return smtp.SendMail(server, auth, from, to, formatAlertEmail(from, to, alerts))
+}
+
+// Get the function sending notifications of alerts to the configured webhooks and e-mail
+// addresses, or nil if none are configured
+func getAlertNotifier() (func(ctx context.Context, alerts []Alert), error) {
+	webhookUrls := param.Monitoring_Alerting_WebhookUrls.GetStringSlice()
+	emailTo := param.Monitoring_Alerting_Email_To.GetStringSlice()
+	if len(webhookUrls) == 0 && len(emailTo) == 0 {
+		return nil, nil
+	}
+	if len(emailTo) > 0 && (param.Monitoring_Alerting_Email_SmtpServer.GetString() == "" || param.Monitoring_Alerting_Email_From.GetString() == "") {
+		return nil, errors.New("Monitoring.Alerting.Email.To requires Monitoring.Alerting.Email.SmtpServer and Monitoring.Alerting.Email.From")
+	}
+
+	return func(ctx context.Context, alerts []Alert) {
+		for _, status := range []AlertState{AlertFiring, AlertResolved} {
+			batch := []Alert{}
+			for _, alert := range alerts {
+				if alert.State == status {
+					batch = append(batch, alert)
+				}
+			}
+			if len(batch) == 0 {
+				continue
+			}
+			msg := newAlertWebhookMessage(status, batch)
+			for _, webhookUrl := range webhookUrls {
+				if err := sendAlertWebhook(ctx, webhookUrl, msg); err != nil {
+					log.Errorf("Failed to send the alert notification to the webhook %s: %v", webhookUrl, err)
+				}
+			}
+		}
+		if len(emailTo) > 0 {
+			if err := sendAlertEmail(alerts); err != nil {
+				log.Errorln("Failed to e-mail the alert notification:", err)
+			}
+		}
+	}, nil
+}
+
+// Record when the server's TLS certificate expires, for the PelicanCertificateExpiring rule
+func recordCertificateExpiry() {
+	certFile := param.Server_TLSCertificate.GetString()
+	if certFile == "" {
+		return
+	}
+	if cert, err := config.LoadCertficate(certFile); err != nil {
+		log.Debugln("Failed to load the TLS certificate to record its expiry:", err)
+	} else {
+		metrics.PelicanTLSCertificateExpiry.Set(float64(cert.NotAfter.Unix()))
+	}
+}
+
+// Launch the evaluation of the alerting rules over the embedded Prometheus, if alert
+// notifications are sent anywhere
+func LaunchAlerting(ctx context.Context, egrp *errgroup.Group) error {
+	notify, err := getAlertNotifier()
+	if err != nil {
+		return err
+	} else if notify == nil {
+		return nil
+	}
+	rules, err := getAlertRules()
+	if err != nil {
+		return err
+	}
+	if err = validateAlertExprs(rules); err != nil {
+		return err
+	}
+	evaluator, err := newAlertEvaluator(rules, queryAlertSamples, notify, param.Monitoring_Alerting_RepeatInterval.GetDuration())
+	if err != nil {
+		return err
+	}
+	interval := param.Monitoring_Alerting_EvaluationInterval.GetDuration()
+	if interval <= 0 {
+		return errors.New("Monitoring.Alerting.EvaluationInterval must be positive")
+	}
+	activeAlertEvaluatorMutex.Lock()
+	activeAlertEvaluator = evaluator
+	activeAlertEvaluatorMutex.Unlock()
+
+	recordCertificateExpiry()
+	egrp.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				recordCertificateExpiry()
+				evaluator.evaluate(ctx, time.Now())
+			}
+		}
+	})
+	log.Infof("Evaluating %d alerting rules every %s", len(rules), interval)
+	return nil
+}
+
+// GET /api/v1.0/alerts
+//
+// List the pending and firing alerts of the server
+func listAlerts(ctx *gin.Context) {
+	activeAlertEvaluatorMutex.RLock()
+	evaluator := activeAlertEvaluator
+	activeAlertEvaluatorMutex.RUnlock()
+	if evaluator == nil {
+		ctx.JSON(http.StatusOK, []Alert{})
+		return
+	}
+	ctx.JSON(http.StatusOK, evaluator.listAlerts())
+}
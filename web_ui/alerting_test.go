@@ -0,0 +1,179 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAlertEvaluatorValidatesRules(t *testing.T) {
+	_, err := newAlertEvaluator(defaultAlertRules, nil, nil, 0)
+	assert.NoError(t, err)
+
+	_, err = newAlertEvaluator([]AlertRule{{Name: "NoExpr"}}, nil, nil, 0)
+	assert.Error(t, err)
+	_, err = newAlertEvaluator([]AlertRule{{Name: "Twice", Expr: "up"}, {Name: "Twice", Expr: "up"}}, nil, nil, 0)
+	assert.ErrorContains(t, err, "more than one alerting rule named Twice")
+	_, err = newAlertEvaluator([]AlertRule{{Name: "BadSummary", Expr: "up", Summary: "{{ .Labels"}}, nil, nil, 0)
+	assert.ErrorContains(t, err, "invalid summary")
+}
+
+func TestAlertEvaluator(t *testing.T) {
+	var samples []alertSample
+	var queryErr error
+	query := func(ctx context.Context, expr string) ([]alertSample, error) {
+		return samples, queryErr
+	}
+	notified := [][]Alert{}
+	notify := func(ctx context.Context, alerts []Alert) {
+		notified = append(notified, alerts)
+	}
+	rules := []AlertRule{{
+		Name:     "CacheFull",
+		Expr:     `xrootd_storage_volume_bytes < 0.05`,
+		For:      10 * time.Minute,
+		Severity: "warning",
+		Summary:  `Cache {{ .Labels.server_name }} is {{ printf "%.0f" (mul .Value 100) }}% free`,
+	}}
+	evaluator, err := newAlertEvaluator(rules, query, notify, time.Hour)
+	require.NoError(t, err)
+	ctx := context.Background()
+	start := time.Now()
+
+	samples = []alertSample{{Labels: map[string]string{"server_name": "cache-a"}, Value: 0.02}}
+	evaluator.evaluate(ctx, start)
+	alerts := evaluator.listAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, AlertPending, alerts[0].State)
+	assert.Equal(t, "Cache cache-a is 2% free", alerts[0].Summary)
+	assert.Empty(t, notified)
+
+	// The alert fires once it's been active for the rule's For
+	evaluator.evaluate(ctx, start.Add(10*time.Minute))
+	require.Len(t, notified, 1)
+	assert.Equal(t, AlertFiring, notified[0][0].State)
+
+	// A firing alert is only notified again after the repeat interval
+	evaluator.evaluate(ctx, start.Add(20*time.Minute))
+	assert.Len(t, notified, 1)
+	evaluator.evaluate(ctx, start.Add(71*time.Minute))
+	assert.Len(t, notified, 2)
+
+	// A rule that fails to evaluate keeps its alerts
+	queryErr = assert.AnError
+	evaluator.evaluate(ctx, start.Add(72*time.Minute))
+	assert.Len(t, evaluator.listAlerts(), 1)
+	assert.Len(t, notified, 2)
+
+	// The alert resolves once the expression no longer returns its series
+	queryErr = nil
+	samples = nil
+	evaluator.evaluate(ctx, start.Add(73*time.Minute))
+	assert.Empty(t, evaluator.listAlerts())
+	require.Len(t, notified, 3)
+	assert.Equal(t, AlertResolved, notified[2][0].State)
+
+	// A pending alert that goes away resolves silently
+	samples = []alertSample{{Labels: map[string]string{"server_name": "cache-b"}, Value: 0.01}}
+	evaluator.evaluate(ctx, start.Add(74*time.Minute))
+	samples = nil
+	evaluator.evaluate(ctx, start.Add(75*time.Minute))
+	assert.Empty(t, evaluator.listAlerts())
+	assert.Len(t, notified, 3)
+}
+
+func TestAlertWebhookNotification(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Server.ExternalWebUrl", "https://origin.example.com:8444")
+
+	received := make(chan alertWebhookMessage, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := alertWebhookMessage{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		received <- msg
+	}))
+	defer server.Close()
+
+	notify, err := getAlertNotifier()
+	require.NoError(t, err)
+	assert.Nil(t, notify, "nothing should be evaluated without a notification target")
+
+	viper.Set("Monitoring.Alerting.WebhookUrls", []string{server.URL})
+	notify, err = getAlertNotifier()
+	require.NoError(t, err)
+	require.NotNil(t, notify)
+
+	firedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	notify(context.Background(), []Alert{
+		{Rule: "PelicanOriginDown", Severity: "critical", Summary: "down", Labels: map[string]string{"server_name": "origin-a"}, State: AlertFiring, firedAt: firedAt},
+		{Rule: "PelicanCacheFull", Labels: map[string]string{}, State: AlertResolved, firedAt: firedAt, resolvedAt: time.Now()},
+	})
+
+	firing := <-received
+	assert.Equal(t, AlertFiring, firing.Status)
+	assert.Equal(t, "https://origin.example.com:8444", firing.ExternalURL)
+	require.Len(t, firing.Alerts, 1)
+	assert.Equal(t, map[string]string{"alertname": "PelicanOriginDown", "severity": "critical", "server_name": "origin-a"}, firing.Alerts[0].Labels)
+	assert.Equal(t, "down", firing.Alerts[0].Annotations["summary"])
+	assert.True(t, firedAt.Equal(firing.Alerts[0].StartsAt))
+
+	resolved := <-received
+	assert.Equal(t, AlertResolved, resolved.Status)
+	require.Len(t, resolved.Alerts, 1)
+	assert.False(t, resolved.Alerts[0].EndsAt.IsZero())
+
+	viper.Set("Monitoring.Alerting.Email.To", []string{"admin@example.com"})
+	_, err = getAlertNotifier()
+	assert.ErrorContains(t, err, "requires Monitoring.Alerting.Email.SmtpServer")
+}
+
+func TestFormatAlertEmail(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("Server.Hostname", "origin.example.com")
+
+	msg := string(formatAlertEmail("pelican@example.com", []string{"a@example.com", "b@example.com"}, []Alert{
+		{Rule: "PelicanOriginDown", Severity: "critical", Summary: "The origin is down", State: AlertFiring},
+		{Rule: "PelicanCacheFull", Summary: "The cache is full", State: AlertResolved},
+	}))
+	assert.True(t, strings.HasPrefix(msg, "From: pelican@example.com\r\nTo: a@example.com, b@example.com\r\n"))
+	assert.Contains(t, msg, "Subject: [Pelican] 1 alerts firing, 1 resolved on origin.example.com\r\n")
+	assert.Contains(t, msg, "[FIRING] PelicanOriginDown (critical)\r\n  The origin is down")
+	assert.Contains(t, msg, "[RESOLVED] PelicanCacheFull\r\n  The cache is full")
+}
+
+func TestDefaultAlertRuleSummaries(t *testing.T) {
+	evaluator, err := newAlertEvaluator(defaultAlertRules, nil, nil, 0)
+	require.NoError(t, err)
+	summary := evaluator.renderSummary(&Alert{Rule: "PelicanCertificateExpiring", Labels: map[string]string{}, Value: 3 * 86400})
+	assert.Equal(t, "The TLS certificate of the server expires in 3 days", summary)
+	summary = evaluator.renderSummary(&Alert{Rule: "PelicanCacheFull", Labels: map[string]string{"server_name": "cache-a"}, Value: 0.042})
+	assert.Equal(t, "The cache cache-a has only 4.2% of its disk free", summary)
+}
@@ -36,6 +36,7 @@ import (
 	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/token_scopes"
@@ -140,11 +141,11 @@ func GetUser(ctx *gin.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	parsed, err := jwt.Parse([]byte(token), jwt.WithKeySet(jwks))
+	parsed, err := jwt.Parse([]byte(token), jwt.WithKeySet(jwks), jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration()))
 	if err != nil {
 		return "", err
 	}
-	if err = jwt.Validate(parsed); err != nil {
+	if err = jwt.Validate(parsed, jwt.WithAcceptableSkew(param.Server_ClockSkewTolerance.GetDuration())); err != nil {
 		return "", err
 	}
 	return parsed.Subject(), nil
@@ -167,7 +168,7 @@ func setLoginCookie(ctx *gin.Context, user string) {
 	tok, err := loginCookieTokenCfg.CreateToken()
 	if err != nil {
 		log.Errorln("Failed to create login cookie token:", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create login cookies"})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Unable to create login cookies")
 		return
 	}
 
@@ -181,7 +182,7 @@ func AuthHandler(ctx *gin.Context) {
 	user, err := GetUser(ctx)
 	if err != nil || user == "" {
 		log.Errorln("Invalid user cookie or unable to parse user cookie:", err)
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required to perform this operation"})
+		AbortWithProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "Authentication required to perform this operation")
 	} else {
 		ctx.Set("User", user)
 		ctx.Next()
@@ -215,14 +216,14 @@ func AdminAuthHandler(ctx *gin.Context) {
 	user := ctx.GetString("User")
 	// This should be done by a regular auth handler from the upstream, but we check here just in case
 	if user == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Login required to view this page"})
+		WriteProblem(ctx, http.StatusUnauthorized, common.ErrCodeUnauthenticated, "Login required to view this page")
 	}
 	isAdmin, msg := CheckAdmin(user)
 	if isAdmin {
 		ctx.Next()
 		return
 	} else {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": msg})
+		WriteProblem(ctx, http.StatusForbidden, common.ErrCodeForbidden, msg)
 	}
 }
 
@@ -239,19 +240,19 @@ func loginHandler(ctx *gin.Context) {
 
 	login := Login{}
 	if ctx.ShouldBind(&login) != nil {
-		ctx.JSON(400, gin.H{"error": "Missing user/password in form data"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Missing user/password in form data")
 		return
 	}
 	if strings.TrimSpace(login.User) == "" {
-		ctx.JSON(400, gin.H{"error": "User is required"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "User is required")
 		return
 	}
 	if strings.TrimSpace(login.Password) == "" {
-		ctx.JSON(400, gin.H{"error": "Password is required"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Password is required")
 		return
 	}
 	if !db.Match(login.User, login.Password) {
-		ctx.JSON(401, gin.H{"error": "Password and user didn't match"})
+		WriteProblem(ctx, 401, common.ErrCodeUnauthenticated, "Password and user didn't match")
 		return
 	}
 
@@ -263,24 +264,24 @@ func loginHandler(ctx *gin.Context) {
 func initLoginHandler(ctx *gin.Context) {
 	db := authDB.Load()
 	if db != nil {
-		ctx.JSON(400, gin.H{"error": "Authentication is already initialized"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Authentication is already initialized")
 		return
 	}
 	curCode := currentCode.Load()
 	if curCode == nil {
-		ctx.JSON(400, gin.H{"error": "Code-based login is not available"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Code-based login is not available")
 		return
 	}
 	prevCode := previousCode.Load()
 
 	code := InitLogin{}
 	if ctx.ShouldBind(&code) != nil {
-		ctx.JSON(400, gin.H{"error": "Login code not provided"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Login code not provided")
 		return
 	}
 
 	if code.Code != *curCode && (prevCode == nil || code.Code != *prevCode) {
-		ctx.JSON(401, gin.H{"error": "Invalid login code"})
+		WriteProblem(ctx, 401, common.ErrCodeUnauthenticated, "Invalid login code")
 		return
 	}
 
@@ -291,7 +292,7 @@ func initLoginHandler(ctx *gin.Context) {
 func resetLoginHandler(ctx *gin.Context) {
 	passwordReset := PasswordReset{}
 	if ctx.ShouldBind(&passwordReset) != nil {
-		ctx.JSON(400, gin.H{"error": "Invalid password reset request"})
+		WriteProblem(ctx, 400, common.ErrCodeInvalidRequest, "Invalid password reset request")
 		return
 	}
 
@@ -299,7 +300,7 @@ func resetLoginHandler(ctx *gin.Context) {
 
 	if err := WritePasswordEntry(user, passwordReset.Password); err != nil {
 		log.Errorf("Password reset for user %s failed: %s", user, err)
-		ctx.JSON(500, gin.H{"error": "Failed to reset password"})
+		WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to reset password")
 	} else {
 		log.Infof("Password reset for user %s was successful", user)
 		ctx.JSON(200, gin.H{"msg": "Success"})
@@ -354,14 +355,33 @@ func configureAuthEndpoints(ctx context.Context, router *gin.Engine, egrp *errgr
 	}
 
 	group := router.Group("/api/v1.0/auth")
-	group.POST("/login", loginHandler)
-	group.POST("/logout", AuthHandler, logoutHandler)
-	group.POST("/initLogin", initLoginHandler)
-	group.POST("/resetLogin", AuthHandler, resetLoginHandler)
+	HandleAPI(group, http.MethodPost, "/login", APIDoc{
+		Summary:   "Log in to the web UI with a username and password",
+		Responses: map[int]string{http.StatusOK: "Logged in; the login cookie is set", http.StatusUnauthorized: "The password is wrong"},
+		Request:   Login{},
+	}, loginHandler)
+	HandleAPI(group, http.MethodPost, "/logout", APIDoc{
+		Summary: "Log out of the web UI",
+		Auth:    APIAuthLogin,
+	}, AuthHandler, logoutHandler)
+	HandleAPI(group, http.MethodPost, "/initLogin", APIDoc{
+		Summary: "Log in with the one-time activation code to initialize the web UI",
+		Request: InitLogin{},
+	}, initLoginHandler)
+	HandleAPI(group, http.MethodPost, "/resetLogin", APIDoc{
+		Summary: "Reset the password of the user",
+		Auth:    APIAuthLogin,
+		Request: PasswordReset{},
+	}, AuthHandler, resetLoginHandler)
 	// Pass csrfhanlder only to the whoami route to generate CSRF token
 	// while leaving other routes free of CSRF check (we might want to do it some time in the future)
-	group.GET("/whoami", csrfHandler, whoamiHandler)
-	group.GET("/loginInitialized", func(ctx *gin.Context) {
+	HandleAPI(group, http.MethodGet, "/whoami", APIDoc{
+		Summary:  "Return the authentication status of the caller",
+		Response: WhoAmIRes{},
+	}, csrfHandler, whoamiHandler)
+	HandleAPI(group, http.MethodGet, "/loginInitialized", APIDoc{
+		Summary: "Return whether the web UI has been initialized",
+	}, func(ctx *gin.Context) {
 		db := authDB.Load()
 		if db == nil {
 			ctx.JSON(200, gin.H{"initialized": false})
@@ -125,7 +125,7 @@ func TestCodeBasedLogin(t *testing.T) {
 
 		//Check the HTTP response code
 		assert.Equal(t, 401, recorder.Code)
-		assert.JSONEq(t, `{"error":"Invalid login code"}`, recorder.Body.String())
+		assert.Equal(t, "Invalid login code", test_utils.ParseProblem(t, recorder.Body.Bytes()).Detail)
 	})
 }
 
@@ -232,7 +232,7 @@ func TestPasswordResetAPI(t *testing.T) {
 		//Check ok http reponse
 		assert.Equal(t, 401, recorderReset.Code)
 		//Check that success message returned
-		assert.JSONEq(t, `{"error":"Authentication required to perform this operation"}`, recorderReset.Body.String())
+		assert.Equal(t, "Authentication required to perform this operation", test_utils.ParseProblem(t, recorderReset.Body.Bytes()).Detail)
 	})
 
 }
@@ -304,7 +304,7 @@ func TestPasswordBasedLoginAPI(t *testing.T) {
 		router.ServeHTTP(recorder, req)
 		//Check http reponse code 400
 		assert.Equal(t, 400, recorder.Code)
-		assert.JSONEq(t, `{"error":"Password is required"}`, recorder.Body.String())
+		assert.Equal(t, "Password is required", test_utils.ParseProblem(t, recorder.Body.Bytes()).Detail)
 	})
 
 	//Invoke with incorrect password should fail
@@ -319,7 +319,7 @@ func TestPasswordBasedLoginAPI(t *testing.T) {
 		router.ServeHTTP(recorder, req)
 		//Check http reponse code 401
 		assert.Equal(t, 401, recorder.Code)
-		assert.JSONEq(t, `{"error":"Password and user didn't match"}`, recorder.Body.String())
+		assert.Equal(t, "Password and user didn't match", test_utils.ParseProblem(t, recorder.Body.Bytes()).Detail)
 	})
 
 	//Invoke with incorrect user should fail
@@ -334,7 +334,7 @@ func TestPasswordBasedLoginAPI(t *testing.T) {
 		router.ServeHTTP(recorder, req)
 		//Check http reponse code 401
 		assert.Equal(t, 401, recorder.Code)
-		assert.JSONEq(t, `{"error":"Password and user didn't match"}`, recorder.Body.String())
+		assert.Equal(t, "Password and user didn't match", test_utils.ParseProblem(t, recorder.Body.Bytes()).Detail)
 	})
 
 	//Invoke with invalid user, should fail
@@ -349,7 +349,7 @@ func TestPasswordBasedLoginAPI(t *testing.T) {
 		router.ServeHTTP(recorder, req)
 		//Check http reponse code 400
 		assert.Equal(t, 400, recorder.Code)
-		assert.JSONEq(t, `{"error":"User is required"}`, recorder.Body.String())
+		assert.Equal(t, "User is required", test_utils.ParseProblem(t, recorder.Body.Bytes()).Detail)
 	})
 }
 
@@ -26,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/route"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/param"
 	"github.com/pelicanplatform/pelican/token_scopes"
 	"github.com/pelicanplatform/pelican/utils"
@@ -73,7 +74,7 @@ func promMetricAuthHandler(ctx *gin.Context) {
 
 		valid := utils.CheckAnyAuth(ctx, authOption)
 		if !valid {
-			ctx.AbortWithStatusJSON(403, gin.H{"error": "Authentication required to access this endpoint."})
+			AbortWithProblem(ctx, 403, common.ErrCodeForbidden, "Authentication required to access this endpoint.")
 		}
 		// Valid director/self request, pass to the next handler
 		ctx.Next()
@@ -96,7 +97,7 @@ func promQueryEngineAuthHandler(av1 *route.Router) gin.HandlerFunc {
 		if exists {
 			av1.ServeHTTP(c.Writer, c.Request)
 		} else {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Correct authorization required to access Prometheus query engine APIs"})
+			WriteProblem(c, http.StatusForbidden, common.ErrCodeForbidden, "Correct authorization required to access Prometheus query engine APIs")
 		}
 	}
 }
@@ -69,7 +69,7 @@ func setupPingEngine(t *testing.T, ctx context.Context, egrp *errgroup.Group) (c
 
 	doneChan := make(chan bool)
 	egrp.Go(func() error {
-		err = runEngineWithListener(ctx, ln, engine, egrp)
+		err = runEngineWithListeners(ctx, []net.Listener{ln}, engine, egrp)
 		require.NoError(t, err)
 		doneChan <- true
 		return err
@@ -113,9 +113,10 @@ func TestRunEngine(t *testing.T) {
 	defer cancel()
 
 	doneChan, cancel, _ := setupPingEngine(t, ctx, egrp)
+	defer cancel()
 
 	// Shutdown the engine
-	cancel()
+	require.NoError(t, config.Shutdown(ctx))
 	timeout := time.Tick(3 * time.Second)
 	select {
 	case ok := <-doneChan:
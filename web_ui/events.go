@@ -0,0 +1,77 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/events"
+)
+
+// How often a comment is sent on an idle event stream so proxies keep it open
+var eventKeepAliveInterval = 30 * time.Second
+
+// Stream the events published on the event bus of the server as server-sent events,
+// named by the event type, until the client disconnects
+func streamEvents(ctx *gin.Context) {
+	var types []events.Type
+	if typesStr := ctx.Query("types"); typesStr != "" {
+		for _, typeStr := range strings.Split(typesStr, ",") {
+			eventType := events.Type(strings.TrimSpace(typeStr))
+			if !slices.Contains(events.Types(), eventType) {
+				WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Unknown event type "+string(eventType))
+				return
+			}
+			types = append(types, eventType)
+		}
+	}
+
+	sub := events.Subscribe(types...)
+	defer sub.Close()
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case envelope, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(string(envelope.Type), envelope)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
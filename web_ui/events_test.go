@@ -0,0 +1,89 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/events"
+)
+
+func TestStreamEvents(t *testing.T) {
+	router := gin.New()
+	router.Use(problemMiddleware)
+	router.GET("/api/v1.0/events", streamEvents)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	t.Run("unknown-type", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1.0/events?types=health_changed,foo")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1.0/events?types=health_changed", nil)
+		require.NoError(t, err)
+		// The headers are sent once the handler subscribed to the events
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		events.Publish(events.XrootdRestarted{Daemons: []string{"xrootd"}})
+		events.Publish(events.HealthChanged{Component: "events-test", Status: "ok"})
+
+		scanner := bufio.NewScanner(resp.Body)
+		var eventName, data string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				eventName = name
+			} else if value, ok := strings.CutPrefix(line, "data:"); ok {
+				data = value
+				break
+			}
+		}
+		require.NoError(t, scanner.Err())
+		assert.Equal(t, "health_changed", eventName)
+
+		envelope := struct {
+			Type string               `json:"type"`
+			Data events.HealthChanged `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(data), &envelope))
+		assert.Equal(t, "health_changed", envelope.Type)
+		assert.Equal(t, events.HealthChanged{Component: "events-test", Status: "ok"}, envelope.Data)
+	})
+}
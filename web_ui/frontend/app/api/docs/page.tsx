@@ -1,16 +1,13 @@
 import React from "react";
-import 'server-only'
 
-import fs from "fs"
-import path from "path"
 import SwaggerUI from "./SwaggerUI";
 import "swagger-ui-react/swagger-ui.css"
 
-const pelicanSwaggerPath = "app/api/docs/pelican-swagger.yaml"
+// The server generates its OpenAPI document from the APIs it serves
+const pelicanOpenAPIUrl = "/api/openapi.json"
 
 function Page() {
-    const pelicanSwagger = fs.readFileSync(path.resolve(process.cwd(), pelicanSwaggerPath), "utf-8")
-    return <SwaggerUI spec={pelicanSwagger} />
+    return <SwaggerUI url={pelicanOpenAPIUrl} />
 }
 
 export default Page
@@ -22,6 +22,7 @@ import {Box, Button, Grid, Typography, Skeleton, Alert, Collapse} from "@mui/mat
 import React, {useEffect, useMemo, useState} from "react";
 
 import {PendingCard, Card, NamespaceCardSkeleton, CreateNamespaceCard} from "@/components/Namespace";
+import NamespaceSearch from "@/components/NamespaceSearch";
 import Link from "next/link";
 import {Namespace, Alert as AlertType} from "@/components/Main";
 import UnauthenticatedContent from "@/components/layout/UnauthenticatedContent";
@@ -97,6 +98,9 @@ export default function Home() {
                             <Alert severity={alert?.severity}>{alert?.message}</Alert>
                         </Box>
                     </Collapse>
+                    <Box mt={2}>
+                        <NamespaceSearch/>
+                    </Box>
                 </Grid>
                 <Grid item lg={6} xl={8}>
                 </Grid>
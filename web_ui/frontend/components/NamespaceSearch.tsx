@@ -0,0 +1,107 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+"use client"
+
+import {Autocomplete, Box, TextField, Typography} from "@mui/material";
+import React, {useEffect, useState} from "react";
+import {useRouter} from "next/navigation";
+
+import {Namespace} from "@/components/Main";
+
+interface NamespaceSearchResult {
+    namespaces: Namespace[];
+    total: number;
+    page: number;
+    page_size: number;
+}
+
+// Typeahead over the registered namespaces backed by /api/v1.0/registry/search
+const NamespaceSearch = () => {
+
+    const router = useRouter()
+    const [input, setInput] = useState<string>("")
+    const [options, setOptions] = useState<Namespace[]>([])
+    const [loading, setLoading] = useState<boolean>(false)
+
+    useEffect(() => {
+        if (input.trim() === "") {
+            setOptions([])
+            return
+        }
+
+        // Debounce requests while the user is still typing
+        const controller = new AbortController()
+        const timeout = setTimeout(async () => {
+            const url = new URL("/api/v1.0/registry/search", window.location.origin)
+            url.searchParams.set("q", input.trim())
+            url.searchParams.set("match", "substring")
+            url.searchParams.set("page_size", "10")
+
+            setLoading(true)
+            try {
+                const response = await fetch(url, {signal: controller.signal})
+                if (response.ok) {
+                    const result: NamespaceSearchResult = await response.json()
+                    setOptions(result.namespaces)
+                }
+            } catch (e) {
+                // Aborted by a newer search
+            } finally {
+                setLoading(false)
+            }
+        }, 250)
+
+        return () => {
+            clearTimeout(timeout)
+            controller.abort()
+        }
+    }, [input])
+
+    return (
+        <Autocomplete
+            size={"small"}
+            options={options}
+            loading={loading}
+            filterOptions={(x) => x}
+            getOptionLabel={(option) => option.prefix}
+            isOptionEqualToValue={(option, value) => option.id === value.id}
+            inputValue={input}
+            onInputChange={(_, value) => setInput(value)}
+            onChange={(_, value) => {
+                if (value) {
+                    router.push(`/registry/namespace/edit?id=${value.id}`)
+                }
+            }}
+            noOptionsText={input.trim() === "" ? "Type to search namespaces" : "No matching namespaces"}
+            renderOption={(props, option) => (
+                <Box component={"li"} {...props} key={option.id}>
+                    <Box>
+                        <Typography variant={"body2"}>{option.prefix}</Typography>
+                        <Typography variant={"caption"} color={"text.secondary"}>
+                            {option.admin_metadata.status}{option.admin_metadata.institution && ` · ${option.admin_metadata.institution}`}
+                        </Typography>
+                    </Box>
+                </Box>
+            )}
+            renderInput={(params) => <TextField {...params} label={"Search namespaces"}/>}
+        />
+    )
+}
+
+export default NamespaceSearch
@@ -34,6 +34,7 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	pelican_oauth2 "github.com/pelicanplatform/pelican/oauth2"
 	"github.com/pelicanplatform/pelican/param"
@@ -95,14 +96,14 @@ func generateCSRFCookie(ctx *gin.Context, nextUrl string) (string, error) {
 func handleOAuthLogin(ctx *gin.Context) {
 	req := oauthLoginRequest{}
 	if ctx.ShouldBindQuery(&req) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to bind next url"})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Failed to bind next url")
 	}
 
 	// CSRF token is required, embed next URL to the state
 	csrfState, err := generateCSRFCookie(ctx, req.NextUrl)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate CSRF token"})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Failed to generate CSRF token")
 		return
 	}
 
@@ -118,29 +119,29 @@ func handleOAuthCallback(ctx *gin.Context) {
 	c := context.Background()
 	csrfFromSession := session.Get("oauthstate")
 	if csrfFromSession == nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OAuth callback: CSRF token from cookie is missing"})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, "Invalid OAuth callback: CSRF token from cookie is missing")
 		return
 	}
 
 	req := oauthCallbackRequest{}
 	if ctx.ShouldBindQuery(&req) != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Invalid OAuth callback: fail to bind CSRF token from state query: ", ctx.Request.URL)})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprint("Invalid OAuth callback: fail to bind CSRF token from state query: ", ctx.Request.URL))
 		return
 	}
 
 	// Format of state: <[16]byte>:<nextURL>
 	parts := strings.SplitN(req.State, ":", 2)
 	if len(parts) != 2 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Invalid OAuth callback: fail to split state param: ", ctx.Request.URL)})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprint("Invalid OAuth callback: fail to split state param: ", ctx.Request.URL))
 		return
 	}
 	nextURL, err := url.QueryUnescape(parts[1])
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Invalid OAuth callback: fail to parse next_url: ", ctx.Request.URL)})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprint("Invalid OAuth callback: fail to parse next_url: ", ctx.Request.URL))
 	}
 
 	if parts[0] != csrfFromSession {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprint("Invalid OAuth callback: CSRF token doesn't match: ", ctx.Request.URL)})
+		WriteProblem(ctx, http.StatusBadRequest, common.ErrCodeInvalidRequest, fmt.Sprint("Invalid OAuth callback: CSRF token doesn't match: ", ctx.Request.URL))
 		return
 	}
 
@@ -149,7 +150,7 @@ func handleOAuthCallback(ctx *gin.Context) {
 	// for user access
 	token, err := ciLogonOAuthConfig.Load().Exchange(c, req.Code)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error in exchanging code for token: ", ctx.Request.URL)})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error in exchanging code for token: ", ctx.Request.URL))
 		return
 	}
 
@@ -161,25 +162,25 @@ func handleOAuthCallback(ctx *gin.Context) {
 	// Use access_token to get user info from CILogon
 	resp, err := client.PostForm(cilogonUserInfoUrl, data)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error requesting user info from CILogon: ", err)})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error requesting user info from CILogon: ", err))
 		return
 	}
 	body, _ := io.ReadAll(resp.Body)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error parsing user info from CILogon: ", err)})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error parsing user info from CILogon: ", err))
 		return
 	}
 
 	userInfo := cilogonUserInfo{}
 
 	if err := json.Unmarshal(body, &userInfo); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint("Error parsing user info from CILogon: ", err)})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, fmt.Sprint("Error parsing user info from CILogon: ", err))
 		return
 	}
 
 	userIdentifier := userInfo.Sub
 	if userIdentifier == "" {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error setting login cookie: can't find valid user id from CILogon"})
+		WriteProblem(ctx, http.StatusInternalServerError, common.ErrCodeInternal, "Error setting login cookie: can't find valid user id from CILogon")
 		return
 	}
 
@@ -242,8 +243,13 @@ func ConfigOAuthClientAPIs(engine *gin.Engine) error {
 
 	ciLogonGroup := engine.Group("/api/v1.0/auth/cilogon", sessionHandler)
 	{
-		ciLogonGroup.GET("/login", handleOAuthLogin)
-		ciLogonGroup.GET("/callback", handleOAuthCallback)
+		HandleAPI(ciLogonGroup, http.MethodGet, "/login", APIDoc{
+			Summary:   "Redirect the user to CILogon to log in to the web UI",
+			Responses: map[int]string{http.StatusTemporaryRedirect: "Redirect to the CILogon login page"},
+		}, handleOAuthLogin)
+		HandleAPI(ciLogonGroup, http.MethodGet, "/callback", APIDoc{
+			Summary: "The callback CILogon calls once the user is authenticated; logs the user in to the web UI",
+		}, handleOAuthCallback)
 	}
 	return nil
 }
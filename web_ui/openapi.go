@@ -0,0 +1,502 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+)
+
+type (
+	// How the callers of an API authenticate
+	APIAuth int
+
+	// The documentation of an API route, used to generate the server's OpenAPI document
+	APIDoc struct {
+		Summary     string
+		Description string
+		// The tag grouping the route in the document; by default, the first path
+		// segment after the API version (e.g. "director_ui")
+		Tag  string
+		Auth APIAuth
+		// The query parameters of the route and their descriptions
+		Query map[string]string
+		// The OpenAPI types of the query parameters that aren't strings, e.g. "integer"
+		QueryTypes map[string]string
+		// A value of the type of the JSON request body, e.g. Namespace{}; its schema is
+		// generated from the type
+		Request interface{}
+		// A value of the type of the JSON body of the successful response
+		Response interface{}
+		// The responses of the route, by HTTP status; 200 if empty.  Error responses
+		// are documented as problems (see common.Problem).
+		Responses map[int]string
+	}
+
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components OpenAPIComponents                       `json:"components"`
+	}
+
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	OpenAPIServer struct {
+		URL string `json:"url"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                     `json:"operationId"`
+		Summary     string                     `json:"summary,omitempty"`
+		Description string                     `json:"description,omitempty"`
+		Tags        []string                   `json:"tags,omitempty"`
+		Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]OpenAPIResponse `json:"responses"`
+		Security    []map[string][]string      `json:"security,omitempty"`
+	}
+
+	OpenAPIParameter struct {
+		Name        string            `json:"name"`
+		In          string            `json:"in"`
+		Description string            `json:"description,omitempty"`
+		Required    bool              `json:"required,omitempty"`
+		Schema      map[string]string `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema map[string]interface{} `json:"schema"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas         map[string]interface{}       `json:"schemas"`
+		SecuritySchemes map[string]map[string]string `json:"securitySchemes"`
+	}
+)
+
+const (
+	APIAuthNone   APIAuth = iota
+	APIAuthLogin          // The login cookie of the web UI
+	APIAuthAdmin          // The login cookie of a web UI administrator
+	APIAuthBearer         // A bearer token issued by a federation service
+)
+
+var (
+	apiDocs      = make(map[string]map[string]APIDoc)
+	apiDocsMutex sync.RWMutex
+
+	// The types of the schemas of the OpenAPI document, by name
+	schemaTypes      = make(map[string]reflect.Type)
+	schemaTypesMutex sync.Mutex
+)
+
+// Join a path relative to a router group the way gin does
+func joinRoutePath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	joined := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// Convert a gin route path to an OpenAPI path and the names of its path parameters
+func openAPIPath(routePath string) (string, []string) {
+	segments := strings.Split(routePath, "/")
+	params := []string{}
+	for idx, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[idx] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// Record the documentation of the API route for the server's OpenAPI document.  Routes
+// registered with HandleAPI are documented already.
+func DocumentAPI(method, routePath string, doc APIDoc) {
+	apiDocsMutex.Lock()
+	defer apiDocsMutex.Unlock()
+	if apiDocs[routePath] == nil {
+		apiDocs[routePath] = make(map[string]APIDoc)
+	}
+	apiDocs[routePath][method] = doc
+}
+
+// Register the handlers of an API route on the router group and document the route in
+// the server's OpenAPI document
+func HandleAPI(group *gin.RouterGroup, method, relativePath string, doc APIDoc, handlers ...gin.HandlerFunc) {
+	DocumentAPI(method, joinRoutePath(group.BasePath(), relativePath), doc)
+	group.Handle(method, relativePath, handlers...)
+}
+
+// Get the API routes of the engine, under /api/, that aren't documented in the
+// server's OpenAPI document, as "METHOD path"
+func UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	apiDocsMutex.RLock()
+	defer apiDocsMutex.RUnlock()
+	undocumented := []string{}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		if _, ok := apiDocs[route.Path][route.Method]; !ok {
+			undocumented = append(undocumented, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(undocumented)
+	return undocumented
+}
+
+func (doc APIDoc) tag(routePath string) string {
+	if doc.Tag != "" {
+		return doc.Tag
+	}
+	segments := strings.Split(strings.Trim(routePath, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+	}
+	if len(segments) > 1 && isVersionSegment(segments[0]) {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(segments[0], ".")
+}
+
+// Check if the path segment is an API version, e.g. "v1.0"
+func isVersionSegment(segment string) bool {
+	_, err := strconv.ParseFloat(strings.TrimPrefix(segment, "v"), 64)
+	return strings.HasPrefix(segment, "v") && err == nil
+}
+
+// Generate the operation ID of the route, e.g. "getDirectorUiServers" for GET
+// /api/v1.0/director_ui/servers.  API versions other than v1.0 are part of the ID.
+func operationID(method, routePath string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(routePath, "/") {
+		segment = strings.TrimLeft(segment, ":*.")
+		if segment == "api" || segment == "" || segment == "v1.0" {
+			continue
+		}
+		if isVersionSegment(segment) {
+			id.WriteString("V" + strings.TrimSuffix(segment[1:], ".0"))
+			continue
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return id.String()
+}
+
+// Get the OpenAPI type of the query parameter
+func (doc APIDoc) queryType(name string) string {
+	if queryType, ok := doc.QueryTypes[name]; ok {
+		return queryType
+	}
+	return "string"
+}
+
+func (doc APIDoc) operation(method, routePath string, schemas map[string]interface{}) *OpenAPIOperation {
+	_, pathParams := openAPIPath(routePath)
+	op := &OpenAPIOperation{
+		OperationID: operationID(method, routePath),
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Responses:   make(map[string]OpenAPIResponse),
+	}
+	if tag := doc.tag(routePath); tag != "" {
+		op.Tags = []string{tag}
+	}
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   map[string]string{"type": "string"},
+		})
+	}
+	queryNames := make([]string, 0, len(doc.Query))
+	for name := range doc.Query {
+		queryNames = append(queryNames, name)
+	}
+	sort.Strings(queryNames)
+	for _, name := range queryNames {
+		op.Parameters = append(op.Parameters, OpenAPIParameter{
+			Name:        name,
+			In:          "query",
+			Description: doc.Query[name],
+			Schema:      map[string]string{"type": doc.queryType(name)},
+		})
+	}
+	if doc.Request != nil {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				"application/json": {Schema: schemaOf(reflect.TypeOf(doc.Request), schemas)},
+			},
+		}
+	}
+
+	responses := doc.Responses
+	if len(responses) == 0 {
+		responses = map[int]string{http.StatusOK: "OK"}
+	}
+	// The response body is that of the first success status
+	successStatus := 0
+	for status := range responses {
+		if status < http.StatusBadRequest && (successStatus == 0 || status < successStatus) {
+			successStatus = status
+		}
+	}
+	addResponse := func(status int, description string) {
+		resp := OpenAPIResponse{Description: description}
+		if status >= http.StatusBadRequest {
+			resp.Content = map[string]OpenAPIMediaType{
+				"application/json": {Schema: map[string]interface{}{"$ref": "#/components/schemas/Problem"}},
+			}
+		} else if status == successStatus && doc.Response != nil {
+			resp.Content = map[string]OpenAPIMediaType{
+				"application/json": {Schema: schemaOf(reflect.TypeOf(doc.Response), schemas)},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+	for status, description := range responses {
+		addResponse(status, description)
+	}
+
+	switch doc.Auth {
+	case APIAuthLogin, APIAuthAdmin:
+		op.Security = []map[string][]string{{"login": {}}}
+		if _, ok := op.Responses["401"]; !ok {
+			addResponse(http.StatusUnauthorized, "The caller isn't logged in")
+		}
+		if doc.Auth == APIAuthAdmin {
+			if _, ok := op.Responses["403"]; !ok {
+				addResponse(http.StatusForbidden, "The caller isn't an administrator")
+			}
+		}
+	case APIAuthBearer:
+		op.Security = []map[string][]string{{"bearer": {}}}
+		if _, ok := op.Responses["403"]; !ok {
+			addResponse(http.StatusForbidden, "The token is missing or doesn't grant access")
+		}
+	}
+	return op
+}
+
+// Generate the OpenAPI document of the API routes documented by the server
+func GetOpenAPIDocument() OpenAPIDocument {
+	doc := OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       "Pelican Server APIs",
+			Description: "The APIs of the Pelican servers (director, registry, origin, cache) enabled in this process",
+			Version:     config.PelicanVersion,
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{
+			Schemas: map[string]interface{}{
+				"Problem": map[string]interface{}{
+					"type":        "object",
+					"description": "An RFC 7807 problem describing an error; code is stable across releases",
+					"required":    []string{"type", "title", "status", "code", "error"},
+					"properties": map[string]interface{}{
+						"type":     map[string]string{"type": "string"},
+						"title":    map[string]string{"type": "string"},
+						"status":   map[string]string{"type": "integer"},
+						"detail":   map[string]string{"type": "string"},
+						"instance": map[string]string{"type": "string"},
+						"code":     map[string]string{"type": "string"},
+						"error":    map[string]string{"type": "string"},
+					},
+				},
+			},
+			SecuritySchemes: map[string]map[string]string{
+				"login":  {"type": "apiKey", "in": "cookie", "name": "login"},
+				"bearer": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	if webUrl := param.Server_ExternalWebUrl.GetString(); webUrl != "" {
+		doc.Servers = []OpenAPIServer{{URL: webUrl}}
+	}
+
+	apiDocsMutex.RLock()
+	defer apiDocsMutex.RUnlock()
+	for routePath, methods := range apiDocs {
+		docPath, _ := openAPIPath(routePath)
+		if doc.Paths[docPath] == nil {
+			doc.Paths[docPath] = make(map[string]*OpenAPIOperation)
+		}
+		for method, routeDoc := range methods {
+			doc.Paths[docPath][strings.ToLower(method)] = routeDoc.operation(method, routePath, doc.Components.Schemas)
+		}
+	}
+	return doc
+}
+
+// Get the schema of values of the Go type as they're marshaled to JSON.  Named struct
+// types are added to schemas and referenced by their name.
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(url.URL{}):
+		return map[string]interface{}{"type": "string", "format": "uri"}
+	}
+	// Types marshaling themselves may be any JSON value
+	if t.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) {
+		return map[string]interface{}{}
+	}
+	if t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name := schemaName(t, schemas)
+		if _, ok := schemas[name]; !ok {
+			// Registered before the fields for the types that refer to themselves
+			schemas[name] = map[string]interface{}{"type": "object"}
+			schemas[name] = structSchema(t, schemas)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// Get the name of the schema of the named type: its name, or its package and name if
+// another type with the same name has the schema already
+func schemaName(t reflect.Type, schemas map[string]interface{}) string {
+	schemaTypesMutex.Lock()
+	defer schemaTypesMutex.Unlock()
+	name := t.Name()
+	if other, ok := schemaTypes[name]; ok && other != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	if _, ok := schemas[name]; !ok {
+		schemaTypes[name] = t
+	}
+	return name
+}
+
+// Get the schema of the fields of the struct type
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for idx := 0; idx < t.NumField(); idx++ {
+			field := t.Field(idx)
+			tag, ok := field.Tag.Lookup("json")
+			if !ok {
+				// Structs bound from either a form or JSON only carry the form tag
+				tag = field.Tag.Get("form")
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+				addFields(fieldType)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaOf(field.Type, schemas)
+		}
+	}
+	addFields(t)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// Serve the OpenAPI document of the server
+func serveOpenAPIDocument(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, GetOpenAPIDocument())
+}
@@ -0,0 +1,143 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testThingMeta struct {
+	Created time.Time `json:"created"`
+}
+
+type testThing struct {
+	testThingMeta
+	ID       int               `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Parent   *testThing        `json:"parent,omitempty"`
+	Owner    string            `form:"owner"`
+	internal bool
+	Ignored  string `json:"-"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schemas := map[string]interface{}{}
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/testThing"}},
+		schemaOf(reflect.TypeOf([]testThing{}), schemas))
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"created": map[string]interface{}{"type": "string", "format": "date-time"},
+			"id":      map[string]interface{}{"type": "integer"},
+			"name":    map[string]interface{}{"type": "string"},
+			"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"labels":  map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+			"parent":  map[string]interface{}{"$ref": "#/components/schemas/testThing"},
+			"owner":   map[string]interface{}{"type": "string"},
+		},
+	}, schemas["testThing"])
+}
+
+func TestOperationID(t *testing.T) {
+	assert.Equal(t, "getDirectorUiServers", operationID(http.MethodGet, "/api/v1.0/director_ui/servers"))
+	assert.Equal(t, "getDirectorListNamespaces", operationID(http.MethodGet, "/api/v1.0/director/listNamespaces"))
+	assert.Equal(t, "getV2DirectorListNamespaces", operationID(http.MethodGet, "/api/v2.0/director/listNamespaces"))
+	assert.Equal(t, "patchRegistryUiNamespacesIdApprove", operationID(http.MethodPatch, "/api/v1.0/registry_ui/namespaces/:id/approve"))
+	assert.Equal(t, "getWellKnownIssuerJwks", operationID(http.MethodGet, "/.well-known/issuer.jwks"))
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	group := engine.Group("/api/v1.0/test_ui")
+	HandleAPI(group, http.MethodGet, "/things/:id", APIDoc{
+		Summary:    "Get a thing",
+		Auth:       APIAuthLogin,
+		Query:      map[string]string{"verbose": "Include everything", "limit": "The most things to return"},
+		QueryTypes: map[string]string{"limit": "integer"},
+		Responses:  map[int]string{http.StatusOK: "The thing", http.StatusNotFound: "No such thing"},
+	}, func(ctx *gin.Context) {})
+	HandleAPI(group, http.MethodPost, "/things", APIDoc{
+		Request:   testThing{},
+		Response:  testThing{},
+		Responses: map[int]string{http.StatusCreated: "The new thing", http.StatusBadRequest: "Invalid thing"},
+	}, func(ctx *gin.Context) {})
+	HandleAPI(group, http.MethodDelete, "/things/*path", APIDoc{Auth: APIAuthAdmin}, func(ctx *gin.Context) {})
+	engine.GET("/api/v1.0/test_ui/undocumented", func(ctx *gin.Context) {})
+	engine.GET("/view/test", func(ctx *gin.Context) {})
+
+	assert.Equal(t, []string{"GET /api/v1.0/test_ui/undocumented"}, UndocumentedRoutes(engine.Routes()))
+
+	doc := GetOpenAPIDocument()
+	require.Contains(t, doc.Paths, "/api/v1.0/test_ui/things/{id}")
+	op := doc.Paths["/api/v1.0/test_ui/things/{id}"]["get"]
+	require.NotNil(t, op)
+	assert.Equal(t, "Get a thing", op.Summary)
+	assert.Equal(t, []string{"test_ui"}, op.Tags)
+	assert.Equal(t, []OpenAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: map[string]string{"type": "string"}},
+		{Name: "limit", In: "query", Description: "The most things to return", Schema: map[string]string{"type": "integer"}},
+		{Name: "verbose", In: "query", Description: "Include everything", Schema: map[string]string{"type": "string"}},
+	}, op.Parameters)
+	assert.Equal(t, []map[string][]string{{"login": {}}}, op.Security)
+	assert.Contains(t, op.Responses, "401")
+	assert.Equal(t, "#/components/schemas/Problem", op.Responses["404"].Content["application/json"].Schema["$ref"])
+	assert.Empty(t, op.Responses["200"].Content)
+
+	op = doc.Paths["/api/v1.0/test_ui/things"]["post"]
+	require.NotNil(t, op)
+	require.NotNil(t, op.RequestBody)
+	assert.Equal(t, "#/components/schemas/testThing", op.RequestBody.Content["application/json"].Schema["$ref"])
+	assert.Equal(t, "#/components/schemas/testThing", op.Responses["201"].Content["application/json"].Schema["$ref"])
+	assert.Contains(t, doc.Components.Schemas, "testThing")
+
+	op = doc.Paths["/api/v1.0/test_ui/things/{path}"]["delete"]
+	require.NotNil(t, op)
+	assert.Contains(t, op.Responses, "200")
+	assert.Contains(t, op.Responses, "403")
+
+	// The served document reads back as the generated one
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/openapi.json", APIDoc{Tag: "docs"}, serveOpenAPIDocument)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	served := OpenAPIDocument{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, "3.0.3", served.OpenAPI)
+	assert.Equal(t, GetOpenAPIDocument().Paths, served.Paths)
+}
+
+// Every API of the web engine common to all servers must be documented
+func TestCommonEndpointsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	require.NoError(t, configureCommonEndpoints(engine))
+	assert.Empty(t, UndocumentedRoutes(engine.Routes()))
+}
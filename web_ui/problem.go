@@ -0,0 +1,173 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+// Respond to the request with the problem for an error with the HTTP status and error
+// code.  The response is labeled application/problem+json for clients that accept it
+// and application/json otherwise.
+func WriteProblem(ctx *gin.Context, status int, code common.ErrorCode, detail string) {
+	problem := common.NewProblem(status, code, detail)
+	problem.Instance = ctx.Request.URL.Path
+	contentType := "application/json; charset=utf-8"
+	if strings.Contains(ctx.GetHeader("Accept"), common.ProblemContentType) {
+		contentType = common.ProblemContentType
+	}
+	ctx.Render(status, problemRender{problem: problem, contentType: contentType})
+}
+
+// Like WriteProblem, but also stops the handlers after the current one from running
+func AbortWithProblem(ctx *gin.Context, status int, code common.ErrorCode, detail string) {
+	ctx.Abort()
+	WriteProblem(ctx, status, code, detail)
+}
+
+type problemRender struct {
+	problem     common.Problem
+	contentType string
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	buf, err := json.Marshal(r.problem)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.contentType)
+}
+
+// Holds back the JSON body of error responses so problemMiddleware can rewrite it
+type problemWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *problemWriter) shouldBuffer() bool {
+	if w.buffered {
+		return true
+	}
+	if w.ResponseWriter.Written() || w.ResponseWriter.Status() < http.StatusBadRequest {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	w.buffered = mediaType == "application/json"
+	return w.buffered
+}
+
+func (w *problemWriter) Write(data []byte) (int, error) {
+	if w.shouldBuffer() {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *problemWriter) WriteString(data string) (int, error) {
+	if w.shouldBuffer() {
+		return w.body.WriteString(data)
+	}
+	return w.ResponseWriter.WriteString(data)
+}
+
+func (w *problemWriter) Written() bool {
+	return w.buffered || w.ResponseWriter.Written()
+}
+
+// Convert an error response body of the form {"error": "..."} to an RFC 7807 problem.
+// The other members of the body are kept as extension members, and "error" is kept
+// so clients written against the older responses keep working.  Returns nil if the
+// body isn't such an error response.
+func toProblem(status int, instance string, body []byte) map[string]interface{} {
+	members := map[string]interface{}{}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil
+	}
+	detail, ok := members["error"].(string)
+	if !ok {
+		return nil
+	}
+	if _, ok := members["type"]; ok {
+		// Already a problem
+		return nil
+	}
+	code := common.ErrorCode("")
+	if codeStr, ok := members["code"].(string); ok {
+		code = common.ErrorCode(codeStr)
+	}
+	problem := common.NewProblem(status, code, detail)
+	members["type"] = problem.Type
+	members["title"] = problem.Title
+	members["status"] = problem.Status
+	members["detail"] = problem.Detail
+	members["code"] = problem.Code
+	members["instance"] = instance
+	return members
+}
+
+// Rewrite the remaining {"error": "..."} responses, such as those of handlers that
+// add extension members, to the standard error format of common.Problem.  Handlers
+// should respond with WriteProblem; this only keeps the older responses compatible.
+func problemMiddleware(ctx *gin.Context) {
+	writer := &problemWriter{ResponseWriter: ctx.Writer}
+	ctx.Writer = writer
+	defer func() {
+		ctx.Writer = writer.ResponseWriter
+	}()
+
+	ctx.Next()
+
+	if !writer.buffered {
+		return
+	}
+	body := writer.body.Bytes()
+	if problem := toProblem(writer.Status(), ctx.Request.URL.Path, body); problem != nil {
+		if problemBody, err := json.Marshal(problem); err == nil {
+			body = problemBody
+			contentType := "application/json; charset=utf-8"
+			if strings.Contains(ctx.GetHeader("Accept"), common.ProblemContentType) {
+				contentType = common.ProblemContentType
+			}
+			writer.Header().Set("Content-Type", contentType)
+		} else {
+			log.Debugln("Failed to marshal the error response of", ctx.Request.URL.Path, "as a problem:", err)
+		}
+	}
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := writer.ResponseWriter.Write(body); err != nil {
+		log.Debugln("Failed to write the error response of", ctx.Request.URL.Path, ":", err)
+	}
+}
@@ -0,0 +1,124 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/common"
+)
+
+func TestProblemMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(problemMiddleware)
+	engine.GET("/missing", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "no such object"})
+	})
+	engine.GET("/unapproved", func(ctx *gin.Context) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"approval_error": true, "code": common.ErrCodeNotApproved, "error": "not approved"})
+	})
+	engine.GET("/exists", func(ctx *gin.Context) {
+		WriteProblem(ctx, http.StatusConflict, common.ErrCodeNamespaceExists, "the prefix is already registered")
+	})
+	engine.GET("/ok", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"error": "not really"})
+	})
+	engine.GET("/text", func(ctx *gin.Context) {
+		ctx.String(http.StatusBadRequest, "bad request")
+	})
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("error-becomes-problem", func(t *testing.T) {
+		w := get("/missing", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+		problem, ok := common.ParseProblem(w.Code, w.Body.Bytes())
+		require.True(t, ok)
+		assert.Equal(t, common.NewProblem(http.StatusNotFound, common.ErrCodeNotFound, "no such object"), common.Problem{
+			Type:   problem.Type,
+			Title:  problem.Title,
+			Status: problem.Status,
+			Detail: problem.Detail,
+			Code:   problem.Code,
+			Error:  problem.Error,
+		})
+		assert.Equal(t, "/missing", problem.Instance)
+	})
+
+	t.Run("problem-content-type", func(t *testing.T) {
+		w := get("/missing", "application/problem+json, application/json")
+		assert.Equal(t, common.ProblemContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("keeps-code-and-extensions", func(t *testing.T) {
+		w := get("/unapproved", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		members := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+		assert.Equal(t, string(common.ErrCodeNotApproved), members["code"])
+		assert.Equal(t, common.ErrCodeNotApproved.TypeURI(), members["type"])
+		assert.Equal(t, true, members["approval_error"])
+		assert.Equal(t, "not approved", members["error"])
+	})
+
+	t.Run("handler-problem", func(t *testing.T) {
+		w := get("/exists", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		problem, ok := common.ParseProblem(w.Code, w.Body.Bytes())
+		require.True(t, ok)
+		assert.Equal(t, common.ErrCodeNamespaceExists, problem.Code)
+		assert.Equal(t, common.ErrCodeNamespaceExists.TypeURI(), problem.Type)
+		assert.Equal(t, "the prefix is already registered", problem.Detail)
+		assert.Equal(t, "the prefix is already registered", problem.Error)
+		assert.Equal(t, "/exists", problem.Instance)
+
+		w = get("/exists", common.ProblemContentType)
+		assert.Equal(t, common.ProblemContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("other-responses-untouched", func(t *testing.T) {
+		w := get("/ok", "")
+		assert.JSONEq(t, `{"error": "not really"}`, w.Body.String())
+
+		w = get("/text", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "bad request", w.Body.String())
+	})
+}
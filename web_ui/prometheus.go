@@ -57,6 +57,7 @@ import (
 	"github.com/prometheus/prometheus/model/metadata"
 	"github.com/prometheus/prometheus/model/relabel"
 	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 
@@ -76,6 +77,11 @@ var (
 
 	globalConfig    config.Config
 	globalConfigMtx sync.RWMutex
+
+	// The embedded Prometheus' query engine and storage, set once it's configured
+	embeddedQueryEngine    *promql.Engine
+	embeddedQueryable      storage.Queryable
+	embeddedQueryEngineMtx sync.RWMutex
 )
 
 func init() {
@@ -143,6 +149,51 @@ func runtimeInfo() (api_v1.RuntimeInfo, error) {
 	return api_v1.RuntimeInfo{}, nil
 }
 
+// Evaluate an instant PromQL query at the current time against the server's embedded
+// Prometheus, e.g., so the director can use the metrics it scrapes from origins and caches
+func QueryEmbeddedPrometheus(ctx context.Context, query string) (promql.Vector, error) {
+	embeddedQueryEngineMtx.RLock()
+	engine, queryable := embeddedQueryEngine, embeddedQueryable
+	embeddedQueryEngineMtx.RUnlock()
+	if engine == nil {
+		return nil, errors.New("The embedded Prometheus server is not running")
+	}
+
+	q, err := engine.NewInstantQuery(ctx, queryable, nil, query, time.Now())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid PromQL query %q", query)
+	}
+	defer q.Close()
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return nil, errors.Wrapf(res.Err, "Failed to evaluate PromQL query %q", query)
+	}
+	return res.Vector()
+}
+
+// Evaluate the expression of an alerting rule against the embedded Prometheus
+func queryAlertSamples(ctx context.Context, expr string) ([]alertSample, error) {
+	vector, err := QueryEmbeddedPrometheus(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]alertSample, 0, len(vector))
+	for _, sample := range vector {
+		samples = append(samples, alertSample{Labels: sample.Metric.Map(), Value: sample.F})
+	}
+	return samples, nil
+}
+
+// Check the syntax of the expressions of the alerting rules
+func validateAlertExprs(rules []AlertRule) error {
+	for _, rule := range rules {
+		if _, err := parser.ParseExpr(rule.Expr); err != nil {
+			return errors.Wrapf(err, "invalid expression of the alerting rule %s", rule.Name)
+		}
+	}
+	return nil
+}
+
 // Configure director's Prometheus scraper to use HTTP service discovery for origins/caches
 func configDirectorPromScraper(ctx context.Context) (*config.ScrapeConfig, error) {
 	directorBaseUrl, err := url.Parse(param.Server_ExternalWebUrl.GetString())
@@ -477,6 +528,10 @@ func ConfigureEmbeddedPrometheus(ctx context.Context, engine *gin.Engine) error
 		queryEngine = promql.NewEngine(opts)
 
 	}
+	embeddedQueryEngineMtx.Lock()
+	embeddedQueryEngine = queryEngine
+	embeddedQueryable = fanoutStorage
+	embeddedQueryEngineMtx.Unlock()
 	scraper.Set(scrapeManager)
 
 	TSDBDir := localStoragePath
@@ -554,10 +609,16 @@ func ConfigureEmbeddedPrometheus(ctx context.Context, engine *gin.Engine) error
 
 	// TODO: Add authorization to director's PromQL endpoint once there's a
 	// way that user can be authenticated or we have a web UI for director
+	promDoc := APIDoc{
+		Summary:     "Query the metrics of the server with the Prometheus HTTP API",
+		Description: "See https://prometheus.io/docs/prometheus/latest/querying/api/ for the API under this path",
+	}
 	if !isDirector {
-		engine.GET("/api/v1.0/prometheus/*any", promQueryEngineAuthHandler(av1))
+		promDoc.Auth = APIAuthBearer
+		promDoc.Description += ".  Callers authenticate with the login cookie or a bearer token with the monitoring.query scope."
+		HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/prometheus/*any", promDoc, promQueryEngineAuthHandler(av1))
 	} else {
-		engine.GET("/api/v1.0/prometheus/*any", func(ctx *gin.Context) {
+		HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/prometheus/*any", promDoc, func(ctx *gin.Context) {
 			av1.ServeHTTP(ctx.Writer, ctx.Request)
 		})
 	}
@@ -0,0 +1,76 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The header carrying the ID of a request, which a proxy in front of the server may
+// have already assigned
+const RequestIDHeader = "X-Request-Id"
+
+// The key of the request ID in the gin context
+const requestIDKey = "RequestID"
+
+// The longest incoming request ID that's reused rather than replaced
+const maxRequestIDLength = 128
+
+// Only reuse incoming request IDs that can't break up or forge log lines
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' || c == '"' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Assign each request an ID, reusing the one in the X-Request-Id header if present, and
+// echo it in the response so a client's report can be matched to the server's logs
+func requestIDMiddleware(ctx *gin.Context) {
+	id := ctx.GetHeader(RequestIDHeader)
+	if !isValidRequestID(id) {
+		id = newRequestID()
+	}
+	if id != "" {
+		ctx.Set(requestIDKey, id)
+		ctx.Header(RequestIDHeader, id)
+	}
+	ctx.Next()
+}
+
+// Get the ID assigned to the request, or an empty string if there's none
+func GetRequestID(ctx *gin.Context) string {
+	return ctx.GetString(requestIDKey)
+}
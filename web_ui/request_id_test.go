@@ -0,0 +1,70 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package web_ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(requestIDMiddleware)
+	engine.GET("/id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, GetRequestID(ctx))
+	})
+
+	get := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/id", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		first := get("")
+		second := get("")
+		assert.Len(t, first.Body.String(), 32)
+		assert.Equal(t, first.Body.String(), first.Header().Get(RequestIDHeader))
+		assert.NotEqual(t, first.Body.String(), second.Body.String())
+	})
+
+	t.Run("reused", func(t *testing.T) {
+		resp := get("proxy-1234")
+		assert.Equal(t, "proxy-1234", resp.Body.String())
+		assert.Equal(t, "proxy-1234", resp.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		for _, id := range []string{"a b", "id\" level=fatal", strings.Repeat("x", maxRequestIDLength+1)} {
+			resp := get(id)
+			assert.NotEqual(t, id, resp.Body.String())
+			assert.Len(t, resp.Body.String(), 32)
+		}
+	})
+}
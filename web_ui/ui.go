@@ -35,8 +35,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/pelicanplatform/pelican/common"
 	"github.com/pelicanplatform/pelican/config"
 	"github.com/pelicanplatform/pelican/server_utils"
+	"github.com/pelicanplatform/pelican/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pelicanplatform/pelican/metrics"
@@ -58,12 +60,12 @@ var (
 func getConfigValues(ctx *gin.Context) {
 	user := ctx.GetString("User")
 	if user == "" {
-		ctx.JSON(401, gin.H{"error": "Authentication required to visit this API"})
+		WriteProblem(ctx, 401, common.ErrCodeUnauthenticated, "Authentication required to visit this API")
 		return
 	}
 	rawConfig, err := param.UnmarshalConfig()
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Failed to get the unmarshaled rawConfig"})
+		WriteProblem(ctx, 500, common.ErrCodeInternal, "Failed to get the unmarshaled rawConfig")
 		return
 	}
 	configWithType := param.ConvertToConfigWithType(rawConfig)
@@ -74,13 +76,57 @@ func getConfigValues(ctx *gin.Context) {
 func getEnabledServers(ctx *gin.Context) {
 	enabledServers := config.GetEnabledServerString(true)
 	if len(enabledServers) == 0 {
-		ctx.JSON(500, gin.H{"error": "No enabled servers found"})
+		WriteProblem(ctx, 500, common.ErrCodeInternal, "No enabled servers found")
 		return
 	}
 
 	ctx.JSON(200, gin.H{"servers": enabledServers})
 }
 
+// Report the readiness of the modules the process runs, with a 503 status until
+// they're all ready, for use as the readiness probe of the server
+func getModuleReadiness(ctx *gin.Context) {
+	readiness := server_utils.GetModuleReadiness()
+	for _, module := range readiness {
+		if !module.Ready {
+			ctx.JSON(http.StatusServiceUnavailable, readiness)
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, readiness)
+}
+
+// Get the feature flags of the enabled server modules, so that other services
+// can tell what this server supports without relying on its version alone
+func getFeatureFlags() map[string]bool {
+	features := map[string]bool{
+		"webUI": param.Server_EnableUI.GetBool(),
+	}
+	if config.IsServerEnabled(config.OriginType) {
+		features["originWrites"] = param.Origin_EnableWrite.GetBool()
+		features["originPublicReads"] = param.Origin_EnablePublicReads.GetBool()
+		features["originFallbackRead"] = param.Origin_EnableFallbackRead.GetBool()
+	}
+	if config.IsServerEnabled(config.DirectorType) {
+		features["directorTopology"] = true
+	}
+	if config.IsServerEnabled(config.RegistryType) {
+		features["registryNamespaceFilters"] = true
+		features["registryOriginApproval"] = param.Registry_RequireOriginApproval.GetBool()
+		features["registryCacheApproval"] = param.Registry_RequireCacheApproval.GetBool()
+	}
+	return features
+}
+
+func getVersionInfo(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, common.VersionInfo{
+		Version:          config.PelicanVersion,
+		Modules:          config.GetEnabledServerString(true),
+		AdSchemaVersions: common.AdSchemaVersions,
+		Features:         getFeatureFlags(),
+	})
+}
+
 func configureWebResource(engine *gin.Engine) error {
 	engine.GET("/view/*requestPath", func(ctx *gin.Context) {
 		requestPath := ctx.Param("requestPath")
@@ -176,7 +222,11 @@ func configureWebResource(engine *gin.Engine) error {
 		}
 	})
 
-	engine.GET("/api/v1.0/docs", func(ctx *gin.Context) {
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/docs", APIDoc{
+		Summary:   "Show the OpenAPI document of the server in Swagger UI",
+		Tag:       "docs",
+		Responses: map[int]string{http.StatusOK: "The HTML page of Swagger UI"},
+	}, func(ctx *gin.Context) {
 
 		filePath := "frontend/out/api/docs/index.html"
 		file, _ := webAssets.ReadFile(filePath)
@@ -192,12 +242,37 @@ func configureWebResource(engine *gin.Engine) error {
 
 // Configure common endpoint available to all server web UI which are located at /api/v1.0/*
 func configureCommonEndpoints(engine *gin.Engine) error {
-	engine.GET("/api/v1.0/config", AuthHandler, getConfigValues)
-	engine.GET("/api/v1.0/servers", getEnabledServers)
+	router := &engine.RouterGroup
+	HandleAPI(router, http.MethodGet, "/api/v1.0/config", APIDoc{
+		Summary: "Return the configuration values of the server and their type",
+		Auth:    APIAuthLogin,
+	}, AuthHandler, getConfigValues)
+	HandleAPI(router, http.MethodGet, "/api/v1.0/servers", APIDoc{
+		Summary: "Return the servers enabled in the process, in lower case and sorted alphabetically",
+	}, getEnabledServers)
+	HandleAPI(router, http.MethodGet, "/api/v1.0/version", APIDoc{
+		Summary:  "Return the version, enabled servers, advertisement schema versions and features of the server",
+		Response: common.VersionInfo{},
+	}, getVersionInfo)
 	// Health check endpoint for web engine
-	engine.GET("/api/v1.0/health", func(ctx *gin.Context) {
-		ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Web Engine Running. Time: %s", time.Now().String())})
+	HandleAPI(router, http.MethodGet, "/api/v1.0/health", APIDoc{
+		Summary:     "Health check endpoint for the server web engine",
+		Description: "Also reports the readiness of the modules the process runs",
+	}, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("Web Engine Running. Time: %s", time.Now().String()),
+			"modules": server_utils.GetModuleReadiness(),
+		})
 	})
+	HandleAPI(router, http.MethodGet, "/api/v1.0/health/ready", APIDoc{
+		Summary:   "Readiness check endpoint reporting whether all the modules the process runs are ready",
+		Response:  map[string]server_utils.ModuleReadiness{},
+		Responses: map[int]string{http.StatusOK: "All the modules are ready", http.StatusServiceUnavailable: "Some modules are still starting"},
+	}, getModuleReadiness)
+	HandleAPI(router, http.MethodGet, "/api/openapi.json", APIDoc{
+		Summary: "Return the OpenAPI 3 document of the APIs of the server",
+		Tag:     "docs",
+	}, serveOpenAPIDocument)
 	return nil
 }
 
@@ -209,10 +284,58 @@ func configureMetrics(ctx context.Context, engine *gin.Engine) error {
 	prometheusMonitor := ginprometheus.NewPrometheus("gin")
 	prometheusMonitor.Use(engine)
 
-	engine.GET("/api/v1.0/metrics/health", AuthHandler, func(ctx *gin.Context) {
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/metrics/health", APIDoc{
+		Summary:  "Return the health status of the server components",
+		Auth:     APIAuthLogin,
+		Response: metrics.HealthStatus{},
+	}, AuthHandler, func(ctx *gin.Context) {
 		healthStatus := metrics.GetHealthStatus()
 		ctx.JSON(http.StatusOK, healthStatus)
 	})
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/alerts", APIDoc{
+		Summary:  "List the pending and firing alerts of the rules in Monitoring.Alerting.Rules and the default rules",
+		Auth:     APIAuthLogin,
+		Response: []Alert{},
+	}, AuthHandler, listAlerts)
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/events", APIDoc{
+		Summary: "Stream the events of the server, such as health changes and XRootD restarts, as server-sent events",
+		Description: "Each event is named by its type and carries a JSON object with the type, the time " +
+			"and the data of the event.  The stream is kept open until the client disconnects.",
+		Auth: APIAuthAdmin,
+		Query: map[string]string{
+			"types": "A comma-separated list of the event types to stream, among ad_received, namespace_approved, " +
+				"xrootd_restarted, health_changed and issuer_key_rotated; all types by default",
+		},
+		Responses: map[int]string{http.StatusOK: "OK", http.StatusBadRequest: "An event type is unknown"},
+	}, AuthHandler, AdminAuthHandler, streamEvents)
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/accounting", APIDoc{
+		Summary:     "Report the bytes read and written per token subject and day",
+		Description: "Requires Monitoring.EnableAccounting; by default, the last 30 days are reported",
+		Auth:        APIAuthAdmin,
+		Query: map[string]string{
+			"from":    "The first day of the report, as YYYY-MM-DD",
+			"to":      "The last day of the report, as YYYY-MM-DD; defaults to today (UTC)",
+			"issuer":  "Only report the subjects of this token issuer",
+			"subject": "Only report this token subject",
+		},
+		Response:  metrics.AccountingReport{},
+		Responses: map[int]string{http.StatusOK: "OK", http.StatusBadRequest: "A date is malformed"},
+	}, AuthHandler, AdminAuthHandler, getAccountingReport)
+	HandleAPI(&engine.RouterGroup, http.MethodGet, "/api/v1.0/access_logs", APIDoc{
+		Summary: "List the recent accesses to the objects of a namespace",
+		Description: "Requires Monitoring.EnableAccessLogs.  The owner of the namespace may authenticate with a bearer token " +
+			"signed by the key the namespace is registered with and granting the pelican.access_logs scope; " +
+			"the server's administrators may use their login cookie.",
+		Auth: APIAuthBearer,
+		Query: map[string]string{
+			"namespace": "The namespace whose accesses are listed (required)",
+			"since":     "Only list accesses after this time, in RFC 3339 format",
+			"limit":     "The maximum number of accesses to list, newest first; 1000 by default",
+		},
+		QueryTypes: map[string]string{"limit": "integer"},
+		Response:   []metrics.AccessLogEntry{},
+		Responses:  map[int]string{http.StatusOK: "OK", http.StatusBadRequest: "A query parameter is malformed", http.StatusUnauthorized: "No token or login cookie was sent"},
+	}, accessLogAuthHandler, getAccessLogs)
 	return nil
 }
 
@@ -321,6 +444,7 @@ func GetEngine() (*gin.Engine, error) {
 	engine := gin.New()
 	engine.Use(gin.Recovery())
 	webLogger := log.WithFields(log.Fields{"daemon": "gin"})
+	engine.Use(requestIDMiddleware)
 	engine.Use(func(ctx *gin.Context) {
 		startTime := time.Now()
 
@@ -328,12 +452,14 @@ func GetEngine() (*gin.Engine, error) {
 
 		latency := time.Since(startTime)
 		webLogger.WithFields(log.Fields{"method": ctx.Request.Method,
-			"status":   ctx.Writer.Status(),
-			"time":     latency.String(),
-			"client":   ctx.RemoteIP(),
-			"resource": ctx.Request.URL.Path},
+			"status":     ctx.Writer.Status(),
+			"time":       latency.String(),
+			"client":     ctx.RemoteIP(),
+			"resource":   ctx.Request.URL.Path,
+			"request_id": GetRequestID(ctx)},
 		).Info("Served Request")
 	})
+	engine.Use(problemMiddleware)
 	engine.HandleMethodNotAllowed = true
 	return engine, nil
 }
@@ -343,28 +469,76 @@ func GetEngine() (*gin.Engine, error) {
 // Will use a background golang routine to periodically reload the certificate
 // utilized by the UI.
 func RunEngine(ctx context.Context, engine *gin.Engine, egrp *errgroup.Group) error {
-	addr := fmt.Sprintf("%v:%v", param.Server_WebHost.GetString(), param.Server_WebPort.GetInt())
-
-	ln, err := net.Listen("tcp", addr)
+	addrs, err := config.GetListenAddresses(param.Server_WebPort.GetInt())
 	if err != nil {
 		return err
 	}
 
-	defer ln.Close()
+	lns := make([]net.Listener, 0, len(addrs))
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen on %s", addr)
+		}
+		lns = append(lns, ln)
+	}
 
-	return runEngineWithListener(ctx, ln, engine, egrp)
+	if socketPath := param.Server_UnixSocket.GetString(); socketPath != "" {
+		unixLn, err := utils.ListenUnixSocket(socketPath, param.Server_UnixSocketMode.GetString())
+		if err != nil {
+			return errors.Wrap(err, "failed to listen on Server.UnixSocket")
+		}
+		runEngineWithUnixSocket(unixLn, engine, egrp)
+	}
+
+	return runEngineWithListeners(ctx, lns, engine, egrp)
+}
+
+type connContextKey struct{}
+
+// Keep the connection in the context of its requests, for GetConn
+func saveConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+// Get the connection the request came in on, or nil if the request wasn't
+// served by the web engine
+func GetConn(ctx *gin.Context) net.Conn {
+	conn, _ := ctx.Request.Context().Value(connContextKey{}).(net.Conn)
+	return conn
+}
+
+// Serve the engine over plain HTTP on a unix socket until the server shuts down.  The
+// socket's permissions control who can connect, so TLS adds nothing here.
+func runEngineWithUnixSocket(ln net.Listener, engine *gin.Engine, egrp *errgroup.Group) {
+	server := &http.Server{
+		Handler:     engine.Handler(),
+		ConnContext: saveConn,
+	}
+	log.Infoln("Starting web engine on unix socket", ln.Addr().String())
+
+	config.RegisterShutdownHook("web engine on the unix socket", config.ShutdownOrderWebEngine, 10*time.Second, server.Shutdown)
+	egrp.Go(func() error {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorln("Failure when serving the web engine on the unix socket:", err)
+			return err
+		}
+		return nil
+	})
 }
 
-// Run the engine with a given listener.
+// Run the engine with the given listeners, one per address in Server.ListenAddresses.
 // This was split out from RunEngine to allow unit tests to provide a Unix domain socket'
 // as a listener.
-func runEngineWithListener(ctx context.Context, ln net.Listener, engine *gin.Engine, egrp *errgroup.Group) error {
+func runEngineWithListeners(ctx context.Context, lns []net.Listener, engine *gin.Engine, egrp *errgroup.Group) error {
 	certFile := param.Server_TLSCertificate.GetString()
 	keyFile := param.Server_TLSKey.GetString()
 
-	port := param.Server_WebPort.GetInt()
-	addr := fmt.Sprintf("%v:%v", param.Server_WebHost.GetString(), port)
-
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
 		panic(err)
@@ -395,29 +569,34 @@ func runEngineWithListener(ctx context.Context, ln net.Listener, engine *gin.Eng
 		return certPtr.Load(), nil
 	}
 
-	config := &tls.Config{
+	tlsConfig := &tls.Config{
 		GetCertificate: getCert,
 	}
 	server := &http.Server{
-		Addr:      addr,
-		Handler:   engine.Handler(),
-		TLSConfig: config,
+		Handler:     engine.Handler(),
+		TLSConfig:   tlsConfig,
+		ConnContext: saveConn,
 	}
-	log.Debugln("Starting web engine at address", addr)
-
-	// Once the context has been canceled, shutdown the HTTPS server.  Give it
-	// 10 seconds to shutdown existing requests.
-	egrp.Go(func() error {
-		<-ctx.Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		err = server.Shutdown(ctx)
-		if err != nil {
-			log.Errorln("Failed to shutdown server:", err)
-		}
-		return err
-	})
 
+	// Once the server shuts down, shutdown the HTTPS server.  Give it 10 seconds
+	// to shutdown existing requests.
+	config.RegisterShutdownHook("web engine", config.ShutdownOrderWebEngine, 10*time.Second, server.Shutdown)
+
+	// Serve all but the last listener in the background; the server's shutdown
+	// closes all of them
+	for _, ln := range lns[:len(lns)-1] {
+		ln := ln
+		log.Debugln("Starting web engine at address", ln.Addr().String())
+		egrp.Go(func() error {
+			if err := server.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Errorf("Failure when serving the web engine at %s: %v", ln.Addr().String(), err)
+				return err
+			}
+			return nil
+		})
+	}
+	ln := lns[len(lns)-1]
+	log.Debugln("Starting web engine at address", ln.Addr().String())
 	if err := server.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -0,0 +1,145 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package xrootd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The largest number of changed lines of an artifact logged at the debug level
+const maxLoggedDiffLines = 50
+
+var (
+	// The hash of the contents last written to each generated artifact, by path
+	artifactHashes      = map[string][sha256.Size]byte{}
+	artifactHashesMutex = sync.Mutex{}
+)
+
+// Forget the artifacts written so far, so the next write of each happens unconditionally
+func resetArtifactHashes() {
+	artifactHashesMutex.Lock()
+	defer artifactHashesMutex.Unlock()
+	artifactHashes = map[string][sha256.Size]byte{}
+}
+
+// Get the lines only in before and the lines only in after, in the order they appear
+func diffLines(before, after []byte) (removed, added []string) {
+	count := func(contents []byte) map[string]int {
+		counts := map[string]int{}
+		for _, line := range strings.Split(string(contents), "\n") {
+			counts[line]++
+		}
+		return counts
+	}
+	beforeCounts, afterCounts := count(before), count(after)
+	for _, line := range strings.Split(string(before), "\n") {
+		if afterCounts[line] > 0 {
+			afterCounts[line]--
+		} else {
+			removed = append(removed, line)
+		}
+	}
+	for _, line := range strings.Split(string(after), "\n") {
+		if beforeCounts[line] > 0 {
+			beforeCounts[line]--
+		} else {
+			added = append(added, line)
+		}
+	}
+	return
+}
+
+// Write a generated artifact XRootD reads, such as its configuration file, authfile, or
+// scitokens.cfg, unless it already holds exactly these contents from an earlier write.
+// The file is written to a temporary file and renamed into place, so XRootD never reads
+// a partial file.  Returns whether the file changed; when a regeneration changes it, the
+// lines that changed are logged.
+func writeGeneratedArtifact(name, path string, contents []byte, gid int) (bool, error) {
+	hash := sha256.Sum256(contents)
+
+	artifactHashesMutex.Lock()
+	defer artifactHashesMutex.Unlock()
+	lastHash, written := artifactHashes[path]
+	var before []byte
+	if written {
+		// The file may have been changed or removed behind our back
+		if onDisk, err := os.ReadFile(path); err == nil {
+			if sha256.Sum256(onDisk) == lastHash && lastHash == hash {
+				log.Debugf("The generated %s at %s is unchanged; not rewriting it", name, path)
+				return false, nil
+			}
+			before = onDisk
+		}
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to create the temporary %s file %s", name, tmpPath)
+	}
+	defer file.Close()
+	if err = os.Chown(tmpPath, -1, gid); err != nil {
+		return false, errors.Wrapf(err, "unable to change ownership of the generated %s %v to desired daemon gid %v", name, tmpPath, gid)
+	}
+	if _, err = file.Write(contents); err != nil {
+		return false, errors.Wrapf(err, "failed to write the generated %s to %s", name, tmpPath)
+	}
+	if err = file.Close(); err != nil {
+		return false, errors.Wrapf(err, "failed to write the generated %s to %s", name, tmpPath)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return false, errors.Wrapf(err, "failed to move the generated %s into place at %s", name, path)
+	}
+	artifactHashes[path] = hash
+
+	if written && before != nil && !bytes.Equal(before, contents) {
+		removed, added := diffLines(before, contents)
+		log.WithFields(log.Fields{
+			"artifact":      name,
+			"path":          path,
+			"lines_added":   len(added),
+			"lines_removed": len(removed),
+		}).Info("The generated XRootD artifact changed")
+		if log.IsLevelEnabled(log.DebugLevel) {
+			for idx, line := range removed {
+				if idx >= maxLoggedDiffLines {
+					log.Debugf("... %d more removed lines of %s", len(removed)-idx, name)
+					break
+				}
+				log.Debugf("%s: - %s", name, line)
+			}
+			for idx, line := range added {
+				if idx >= maxLoggedDiffLines {
+					log.Debugf("... %d more added lines of %s", len(added)-idx, name)
+					break
+				}
+				log.Debugf("%s: + %s", name, line)
+			}
+		}
+	}
+	return true, nil
+}
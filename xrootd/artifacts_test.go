@@ -0,0 +1,86 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package xrootd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	removed, added := diffLines([]byte("a\nb\nc\nb"), []byte("a\nc\nd\nb"))
+	assert.Equal(t, []string{"b"}, removed)
+	assert.Equal(t, []string{"d"}, added)
+
+	removed, added = diffLines([]byte("same"), []byte("same"))
+	assert.Empty(t, removed)
+	assert.Empty(t, added)
+}
+
+func TestWriteGeneratedArtifact(t *testing.T) {
+	resetArtifactHashes()
+	t.Cleanup(resetArtifactHashes)
+	path := filepath.Join(t.TempDir(), "xrootd.cfg")
+	gid := os.Getgid()
+
+	changed, err := writeGeneratedArtifact("xrootd.cfg", path, []byte("all.sitename test\n"), gid)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "all.sitename test\n", string(contents))
+
+	// Regenerating the same contents doesn't touch the file
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, past, past))
+	changed, err = writeGeneratedArtifact("xrootd.cfg", path, []byte("all.sitename test\n"), gid)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	// New contents are written
+	changed, err = writeGeneratedArtifact("xrootd.cfg", path, []byte("all.sitename other\n"), gid)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	contents, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "all.sitename other\n", string(contents))
+
+	// A file changed or removed behind our back is restored
+	require.NoError(t, os.WriteFile(path, []byte("edited\n"), 0640))
+	changed, err = writeGeneratedArtifact("xrootd.cfg", path, []byte("all.sitename other\n"), gid)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.NoError(t, os.Remove(path))
+	changed, err = writeGeneratedArtifact("xrootd.cfg", path, []byte("all.sitename other\n"), gid)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	contents, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "all.sitename other\n", string(contents))
+}
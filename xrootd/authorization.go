@@ -172,34 +172,20 @@ func writeScitokensConfiguration(modules config.ServerType, cfg *ScitokensCfg) e
 		return err
 	}
 
-	xrootdRun := param.Xrootd_RunLocation.GetString()
-	configPath := filepath.Join(xrootdRun, "scitokens-generated.cfg.tmp")
-	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to create a temporary scitokens file %s", configPath)
-	}
-	defer file.Close()
-	if err = os.Chown(configPath, -1, gid); err != nil {
-		return errors.Wrapf(err, "Unable to change ownership of generated scitokens"+
-			" configuration file %v to desired daemon gid %v", configPath, gid)
-	}
-
-	err = templ.Execute(file, cfg)
-	if err != nil {
+	output := new(bytes.Buffer)
+	if err = templ.Execute(output, cfg); err != nil {
 		return errors.Wrapf(err, "Unable to create scitokens.cfg template")
 	}
 
-	// Note that we write to the file then rename it into place.  This is because the
-	// xrootd daemon will periodically reload the scitokens.cfg and, in some cases,
-	// we may want to update it without restarting the server.
+	// The xrootd daemon periodically reloads the scitokens.cfg, so an update takes effect
+	// without restarting the server; an unchanged configuration isn't rewritten at all
+	xrootdRun := param.Xrootd_RunLocation.GetString()
 	finalConfigPath := filepath.Join(xrootdRun, "scitokens-origin-generated.cfg")
 	if modules.IsEnabled(config.CacheType) {
 		finalConfigPath = filepath.Join(xrootdRun, "scitokens-cache-generated.cfg")
 	}
-	if err = os.Rename(configPath, finalConfigPath); err != nil {
-		return errors.Wrapf(err, "Failed to rename scitokens.cfg to final location")
-	}
-	return nil
+	_, err = writeGeneratedArtifact("scitokens.cfg", finalConfigPath, output.Bytes(), gid)
+	return err
 }
 
 // Retrieves authorization auth files for OSDF caches and origins
@@ -255,6 +241,12 @@ func getOSDFAuthFiles(server server_utils.XRootDServer) ([]byte, error) {
 
 // Parse the input xrootd authfile, add any default configurations, and then save it
 // into the xrootd runtime directory
+// Whether the origin's export should be publicly readable.  A paused export
+// isn't, so the origin refuses anonymous reads of it.
+func originPublicReadsEnabled() bool {
+	return param.Origin_EnablePublicReads.GetBool() && !origin_ui.IsExportPaused(param.Origin_NamespacePrefix.GetString())
+}
+
 func EmitAuthfile(server server_utils.XRootDServer) error {
 	authfile := param.Xrootd_Authfile.GetString()
 	log.Debugln("Location of input authfile:", authfile)
@@ -289,7 +281,7 @@ func EmitAuthfile(server server_utils.XRootDServer) error {
 			if server.GetServerType().IsEnabled(config.OriginType) {
 				outStr := "u * /.well-known lr "
 				// Set up public reads if the origin is configured for it
-				if param.Origin_EnablePublicReads.GetBool() {
+				if originPublicReadsEnabled() {
 					outStr += param.Origin_NamespacePrefix.GetString() + " lr "
 				}
 				output.Write([]byte(outStr + strings.Join(words[2:], " ") + "\n"))
@@ -305,7 +297,7 @@ func EmitAuthfile(server server_utils.XRootDServer) error {
 	// If Origin and no authfile already exists, add the ./well-known to the authfile
 	if !foundPublicLine && server.GetServerType().IsEnabled(config.OriginType) {
 		outStr := "u * /.well-known lr"
-		if param.Origin_EnablePublicReads.GetBool() {
+		if originPublicReadsEnabled() {
 			outStr += " " + param.Origin_NamespacePrefix.GetString() + " lr"
 		}
 		outStr += "\n"
@@ -335,25 +327,15 @@ func EmitAuthfile(server server_utils.XRootDServer) error {
 		return err
 	}
 
+	// XRootD periodically reloads the authfile, so an update takes effect without
+	// restarting the server; an unchanged authfile isn't rewritten at all
 	xrootdRun := param.Xrootd_RunLocation.GetString()
 	finalAuthPath := filepath.Join(xrootdRun, "authfile-origin-generated")
 	if server.GetServerType().IsEnabled(config.CacheType) {
 		finalAuthPath = filepath.Join(xrootdRun, "authfile-cache-generated")
 	}
-	file, err := os.OpenFile(finalAuthPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to create a generated authfile %s", finalAuthPath)
-	}
-	defer file.Close()
-	if err = os.Chown(finalAuthPath, -1, gid); err != nil {
-		return errors.Wrapf(err, "Unable to change ownership of generated auth"+
-			"file %v to desired daemon gid %v", finalAuthPath, gid)
-	}
-	if _, err := output.WriteTo(file); err != nil {
-		return errors.Wrapf(err, "Failed to write to generated authfile %v", finalAuthPath)
-	}
-
-	return nil
+	_, err = writeGeneratedArtifact("authfile", finalAuthPath, output.Bytes(), gid)
+	return err
 }
 
 // Given a filename, load and parse the file into a ScitokensCfg object
@@ -438,6 +420,23 @@ func GenerateMonitoringIssuer() (issuer Issuer, err error) {
 	return
 }
 
+// A namespace with its own key in Origin.Exports is its own issuer, so tokens for it are
+// only accepted from that issuer
+func GenerateNamespaceIssuer(prefix string) (issuer Issuer, err error) {
+	issuerUrl, err := server_utils.GetNamespaceIssuerURL(prefix)
+	if err != nil {
+		return
+	}
+	issuer.Name = "Origin namespace " + prefix
+	issuer.Issuer = issuerUrl.String()
+	issuer.BasePaths = []string{prefix}
+	issuer.RestrictedPaths = param.Origin_ScitokensRestrictedPaths.GetStringSlice()
+	issuer.MapSubject = param.Origin_ScitokensMapSubject.GetBool()
+	issuer.DefaultUser = param.Origin_ScitokensDefaultUser.GetString()
+	issuer.UsernameClaim = param.Origin_ScitokensUsernameClaim.GetString()
+	return
+}
+
 func GenerateOriginIssuer(exportedPaths []string) (issuer Issuer, err error) {
 	// TODO: Return to this and figure out how to get a proper unmarshal to work
 	if len(exportedPaths) == 0 {
@@ -527,7 +526,25 @@ func WriteOriginScitokensConfig(exportedPaths []string) error {
 			cfg.Global.Audience = append(cfg.Global.Audience, issuer.Issuer)
 		}
 	}
-	if issuer, err := GenerateOriginIssuer(exportedPaths); err == nil && len(issuer.Name) > 0 {
+	// Namespaces with their own keys are left to their own issuers
+	originPaths := []string{}
+	for _, exportedPath := range exportedPaths {
+		hasKey, err := config.HasNamespaceIssuerKey(exportedPath)
+		if err != nil {
+			return err
+		}
+		if !hasKey {
+			originPaths = append(originPaths, exportedPath)
+			continue
+		}
+		issuer, err := GenerateNamespaceIssuer(exportedPath)
+		if err != nil {
+			return err
+		}
+		cfg.IssuerMap[issuer.Issuer] = issuer
+		cfg.Global.Audience = append(cfg.Global.Audience, issuer.Issuer)
+	}
+	if issuer, err := GenerateOriginIssuer(originPaths); err == nil && len(issuer.Name) > 0 {
 		if val, ok := cfg.IssuerMap[issuer.Issuer]; ok {
 			val.BasePaths = append(val.BasePaths, issuer.BasePaths...)
 			cfg.IssuerMap[issuer.Issuer] = val
@@ -554,9 +571,17 @@ func WriteCacheScitokensConfig(nsAds []common.NamespaceAdV2) error {
 	if err != nil {
 		return err
 	}
+	validateIssuers := param.Cache_EnableIssuerValidation.GetBool()
 	for _, ad := range nsAds {
 		if !ad.PublicRead {
 			for _, ti := range ad.Issuer {
+				// Leaving an issuer out of the configuration causes the cache to deny its tokens
+				if validateIssuers {
+					if err := validateIssuer(ti.IssuerUrl.String()); err != nil {
+						log.Warningf("Not accepting tokens from issuer %s for namespace %s: %v", ti.IssuerUrl.String(), ad.Path, err)
+						continue
+					}
+				}
 				if val, ok := cfg.IssuerMap[ti.IssuerUrl.String()]; ok {
 					val.BasePaths = append(val.BasePaths, ti.BasePaths...)
 					cfg.IssuerMap[ti.IssuerUrl.String()] = val
@@ -567,6 +592,15 @@ func WriteCacheScitokensConfig(nsAds []common.NamespaceAdV2) error {
 			}
 		}
 	}
+	// The director's cache test pulls a monitoring object through the cache with a director-issued token
+	if issuer, err := GenerateDirectorMonitoringIssuer(); err == nil && len(issuer.Name) > 0 {
+		if val, ok := cfg.IssuerMap[issuer.Issuer]; ok {
+			val.BasePaths = append(val.BasePaths, issuer.BasePaths...)
+			cfg.IssuerMap[issuer.Issuer] = val
+		} else {
+			cfg.IssuerMap[issuer.Issuer] = issuer
+		}
+	}
 
 	return writeScitokensConfiguration(config.CacheType, &cfg)
 }
@@ -643,5 +677,46 @@ func EmitIssuerMetadata(exportPath string) error {
 		return errors.Wrap(err, "Failed to write OpenID configuration file")
 	}
 
+	return emitNamespaceIssuerMetadata(wellKnownPath, gid)
+}
+
+// Write the metadata of the issuers of the namespaces with their own keys, each below
+// the origin's own metadata in wellKnownPath, where XRootD serves it
+func emitNamespaceIssuerMetadata(wellKnownPath string, gid int) error {
+	prefixes, err := config.GetNamespacesWithIssuerKeys()
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		issuerUrl, err := server_utils.GetNamespaceIssuerURL(prefix)
+		if err != nil {
+			return err
+		}
+		keys, err := config.GetNamespaceIssuerPublicJWKS(prefix)
+		if err != nil {
+			return err
+		}
+		nsWellKnownPath := filepath.Join(wellKnownPath, "namespaces", filepath.FromSlash(prefix), ".well-known")
+		if err = config.MkdirAll(nsWellKnownPath, 0755, -1, gid); err != nil {
+			return err
+		}
+		buf, err := json.MarshalIndent(keys, "", " ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to marshal public keys")
+		}
+		if err = os.WriteFile(filepath.Join(nsWellKnownPath, "issuer.jwks"), buf, 0644); err != nil {
+			return errors.Wrapf(err, "Failed to write the public key set of namespace %s", prefix)
+		}
+		buf, err = json.MarshalIndent(openIdConfig{
+			Issuer:  issuerUrl.String(),
+			JWKSURI: issuerUrl.JoinPath(".well-known", "issuer.jwks").String(),
+		}, "", " ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to marshal OpenID configuration file contents")
+		}
+		if err = os.WriteFile(filepath.Join(nsWellKnownPath, "openid-configuration"), buf, 0644); err != nil {
+			return errors.Wrapf(err, "Failed to write the OpenID configuration of namespace %s", prefix)
+		}
+	}
 	return nil
 }
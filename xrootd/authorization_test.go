@@ -308,6 +308,35 @@ func TestEmitAuthfile(t *testing.T) {
 	}
 }
 
+func TestEmitAuthfilePausedExport(t *testing.T) {
+	dirName := t.TempDir()
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		require.NoError(t, origin_ui.LoadPausedExports())
+	})
+	viper.Set("Xrootd.Authfile", filepath.Join(dirName, "authfile"))
+	viper.Set("Xrootd.RunLocation", dirName)
+	viper.Set("Origin.NamespacePrefix", "/test")
+	viper.Set("Origin.EnablePublicReads", true)
+	viper.Set("Origin.PausedExportsFile", filepath.Join(dirName, "paused-exports.yaml"))
+	require.NoError(t, os.WriteFile(filepath.Join(dirName, "authfile"), []byte(""), fs.FileMode(0600)))
+	server := &origin_ui.OriginServer{}
+
+	emit := func() string {
+		require.NoError(t, EmitAuthfile(server))
+		contents, err := os.ReadFile(filepath.Join(dirName, "authfile-origin-generated"))
+		require.NoError(t, err)
+		return string(contents)
+	}
+	assert.Equal(t, "u * /.well-known lr /test lr\n", emit())
+
+	// A paused export is no longer publicly readable
+	require.NoError(t, os.WriteFile(filepath.Join(dirName, "paused-exports.yaml"), []byte("- /test\n"), fs.FileMode(0644)))
+	require.NoError(t, origin_ui.LoadPausedExports())
+	assert.Equal(t, "u * /.well-known lr\n", emit())
+}
+
 func TestEmitCfg(t *testing.T) {
 	dirname := t.TempDir()
 	viper.Reset()
@@ -0,0 +1,151 @@
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+//
+// This file validates the token issuers advertised by protected namespaces
+// before the cache trusts them in its scitokens.cfg.  Issuers whose metadata
+// can't be retrieved are left out of the configuration, meaning the cache
+// denies any client token they issued.
+//
+
+package xrootd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/utils"
+)
+
+type issuerValidation struct {
+	err error // nil if the issuer's metadata and public keys were retrieved successfully
+}
+
+var (
+	// The result of the last validation of each issuer.  Good issuers are cached for
+	// Cache.IssuerMetadataRefreshInterval and bad ones for Cache.IssuerNegativeCacheTTL
+	issuerValidations = ttlcache.New[string, issuerValidation](
+		ttlcache.WithDisableTouchOnHit[string, issuerValidation](),
+	)
+	issuerValidationsMutex = sync.RWMutex{}
+
+	// Concurrent validations of the same issuer share a single round of requests
+	issuerValidationGroup = singleflight.Group{}
+)
+
+// Fetch the body of a URL, failing on any non-200 response
+func fetchIssuerDocument(ctx context.Context, client *http.Client, docUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s returned HTTP status %d", docUrl, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// Retrieve the OpenID configuration and public keys of the issuer, checking
+// that they are well-formed enough for the cache to verify tokens against
+func checkIssuerMetadata(issuerUrl string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := &http.Client{Transport: config.GetTransport()}
+
+	wellKnownUrl := strings.TrimSuffix(issuerUrl, "/") + "/.well-known/openid-configuration"
+	body, err := fetchIssuerDocument(ctx, client, wellKnownUrl)
+	if err != nil {
+		return errors.Wrap(err, "failed to retrieve the issuer metadata")
+	}
+	metadata := openIdConfig{}
+	if err = json.Unmarshal(body, &metadata); err != nil {
+		return errors.Wrapf(err, "failed to parse the issuer metadata at %s", wellKnownUrl)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuerUrl, "/") {
+		return errors.Errorf("issuer metadata at %s is for a different issuer (%s)", wellKnownUrl, metadata.Issuer)
+	}
+	if metadata.JWKSURI == "" {
+		return errors.Errorf("issuer metadata at %s does not include a jwks_uri", wellKnownUrl)
+	}
+
+	if _, err = utils.GetIssuerJWKS(ctx, metadata.JWKSURI); err != nil {
+		return errors.Wrap(err, "failed to retrieve the issuer public keys")
+	}
+	return nil
+}
+
+// Check whether the cache should accept tokens from the issuer, using the cached
+// result if the issuer was checked recently
+func validateIssuer(issuerUrl string) error {
+	if result, ok := getIssuerValidation(issuerUrl); ok {
+		return result.err
+	}
+
+	// The metadata is fetched without holding the lock so a slow issuer doesn't
+	// hold up the validation of the others
+	result, _, _ := issuerValidationGroup.Do(issuerUrl, func() (interface{}, error) {
+		if result, ok := getIssuerValidation(issuerUrl); ok {
+			return result, nil
+		}
+		result := issuerValidation{err: checkIssuerMetadata(issuerUrl)}
+		ttl := param.Cache_IssuerMetadataRefreshInterval.GetDuration()
+		if result.err != nil {
+			ttl = param.Cache_IssuerNegativeCacheTTL.GetDuration()
+			log.Warningf("Token issuer %s failed validation and will not be trusted for the next %s: %v",
+				issuerUrl, ttl, result.err)
+		} else {
+			log.Debugln("Successfully validated the metadata of token issuer", issuerUrl)
+		}
+		issuerValidationsMutex.Lock()
+		defer issuerValidationsMutex.Unlock()
+		issuerValidations.Set(issuerUrl, result, ttl)
+		return result, nil
+	})
+	return result.(issuerValidation).err
+}
+
+// Look up the unexpired result of the last validation of the issuer
+func getIssuerValidation(issuerUrl string) (issuerValidation, bool) {
+	issuerValidationsMutex.RLock()
+	defer issuerValidationsMutex.RUnlock()
+	if item := issuerValidations.Get(issuerUrl); item != nil && !item.IsExpired() {
+		return item.Value(), true
+	}
+	return issuerValidation{}, false
+}
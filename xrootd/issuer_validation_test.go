@@ -0,0 +1,319 @@
+//go:build !windows
+
+/***************************************************************
+ *
+ * Copyright (C) 2024, Pelican Project, Morgridge Institute for Research
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you
+ * may not use this file except in compliance with the License.  You may
+ * obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ ***************************************************************/
+
+package xrootd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pelicanplatform/pelican/cache_ui"
+	"github.com/pelicanplatform/pelican/common"
+	"github.com/pelicanplatform/pelican/config"
+	"github.com/pelicanplatform/pelican/daemon"
+	"github.com/pelicanplatform/pelican/param"
+	"github.com/pelicanplatform/pelican/server_utils"
+	"github.com/pelicanplatform/pelican/test_utils"
+)
+
+// Start an issuer serving its OpenID configuration and public keys.  If `claimedIssuer`
+// is non-empty, the metadata claims to belong to that issuer instead.
+func newTestIssuer(t *testing.T, claimedIssuer string) *httptest.Server {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pubKey, err := jwk.FromRaw(&privKey.PublicKey)
+	require.NoError(t, err)
+	keySet := jwk.NewSet()
+	require.NoError(t, keySet.AddKey(pubKey))
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := claimedIssuer
+		if issuer == "" {
+			issuer = server.URL
+		}
+		_ = json.NewEncoder(w).Encode(openIdConfig{Issuer: issuer, JWKSURI: server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(keySet)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCacheIssuerValidation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerValidations.DeleteAll()
+	})
+	issuerValidations.DeleteAll()
+
+	dirname := t.TempDir()
+	viper.Set("Xrootd.RunLocation", dirname)
+	viper.Set("Xrootd.ScitokensConfig", filepath.Join(dirname, "scitokens.cfg"))
+	viper.Set("Cache.EnableIssuerValidation", true)
+	viper.Set("Cache.IssuerMetadataRefreshInterval", "1h")
+	viper.Set("Cache.IssuerNegativeCacheTTL", "1h")
+
+	goodIssuer := newTestIssuer(t, "")
+	impostorIssuer := newTestIssuer(t, "https://someone-else.example.com")
+	var missingHits atomic.Int32
+	missingIssuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		missingHits.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(missingIssuer.Close)
+
+	protectedAd := func(path string, issuer string) common.NamespaceAdV2 {
+		issuerUrl, err := url.Parse(issuer)
+		require.NoError(t, err)
+		return common.NamespaceAdV2{
+			Path:   path,
+			Caps:   common.Capabilities{Read: true},
+			Issuer: []common.TokenIssuer{{IssuerUrl: *issuerUrl, BasePaths: []string{path}}},
+		}
+	}
+	nsAds := []common.NamespaceAdV2{
+		protectedAd("/good", goodIssuer.URL),
+		protectedAd("/impostor", impostorIssuer.URL),
+		protectedAd("/missing", missingIssuer.URL),
+	}
+
+	writeAndLoad := func() ScitokensCfg {
+		require.NoError(t, WriteCacheScitokensConfig(nsAds))
+		cfg, err := LoadScitokensConfig(filepath.Join(param.Xrootd_RunLocation.GetString(), "scitokens-cache-generated.cfg"))
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("deny-bad-issuers", func(t *testing.T) {
+		cfg := writeAndLoad()
+		require.Len(t, cfg.IssuerMap, 1)
+		assert.Equal(t, []string{"/good"}, cfg.IssuerMap[goodIssuer.URL].BasePaths)
+		assert.Equal(t, []string{goodIssuer.URL}, cfg.Global.Audience)
+		assert.Equal(t, int32(1), missingHits.Load())
+	})
+
+	t.Run("negative-cache", func(t *testing.T) {
+		cfg := writeAndLoad()
+		assert.Len(t, cfg.IssuerMap, 1)
+		// The failed issuer isn't queried again until its negative cache entry expires
+		assert.Equal(t, int32(1), missingHits.Load())
+
+		issuerValidations.Delete(missingIssuer.URL)
+		writeAndLoad()
+		assert.Equal(t, int32(2), missingHits.Load())
+	})
+
+	t.Run("validation-disabled", func(t *testing.T) {
+		viper.Set("Cache.EnableIssuerValidation", false)
+		defer viper.Set("Cache.EnableIssuerValidation", true)
+		cfg := writeAndLoad()
+		assert.Len(t, cfg.IssuerMap, 3)
+	})
+}
+
+func TestConcurrentIssuerValidation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerValidations.DeleteAll()
+	})
+	issuerValidations.DeleteAll()
+	viper.Set("Cache.IssuerMetadataRefreshInterval", "1h")
+	viper.Set("Cache.IssuerNegativeCacheTTL", "1h")
+
+	var slowHits atomic.Int32
+	release := make(chan struct{})
+	slowIssuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowHits.Add(1)
+		<-release
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(slowIssuer.Close)
+	goodIssuer := newTestIssuer(t, "")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Error(t, validateIssuer(slowIssuer.URL))
+		}()
+	}
+
+	// Other issuers can be validated while the slow issuer's metadata is being fetched
+	require.Eventually(t, func() bool { return slowHits.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	done := make(chan error)
+	go func() { done <- validateIssuer(goodIssuer.URL) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Validation of one issuer was blocked by another")
+	}
+
+	close(release)
+	wg.Wait()
+	// All the concurrent validations of the slow issuer shared one fetch
+	assert.Equal(t, int32(1), slowHits.Load())
+}
+
+// Run a cache whose only protected namespace trusts an issuer that fails validation,
+// and check that XRootD actually refuses a token from that issuer
+func TestCacheRefusesInvalidIssuerToken(t *testing.T) {
+	ctx, cancel, egrp := test_utils.TestContext(context.Background(), t)
+	defer func() { require.NoError(t, egrp.Wait()) }()
+	defer cancel()
+
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		issuerValidations.DeleteAll()
+	})
+	issuerValidations.DeleteAll()
+
+	// Create our own temp directory (for some reason t.TempDir() does not play well with xrootd)
+	tmpPath, err := os.MkdirTemp("", "XRootD-Test_Cache*")
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(tmpPath, 0755))
+	t.Cleanup(func() {
+		os.RemoveAll(tmpPath)
+	})
+
+	viper.Set("ConfigDir", tmpPath)
+	viper.Set("Xrootd.RunLocation", filepath.Join(tmpPath, "xrootd"))
+	viper.Set("Cache.DataLocation", filepath.Join(tmpPath, "xcache"))
+	viper.Set("Cache.EnableIssuerValidation", true)
+	viper.Set("Cache.IssuerNegativeCacheTTL", "1h")
+	viper.Set("Federation.DirectorUrl", "https://localhost:1")
+	viper.Set("TLSSkipVerify", true)
+	viper.Set("Logging.Level", "Debug")
+	config.InitConfig()
+	require.NoError(t, config.InitServer(ctx, config.CacheType))
+	require.NoError(t, config.GeneratePrivateKey(param.Server_TLSKey.GetString(), elliptic.P256()))
+	require.NoError(t, config.GenerateCert())
+
+	// The issuer serves real keys that XRootD could verify the token with, but its
+	// metadata claims to be for another issuer, so it fails the cache's validation
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signingKey, err := jwk.FromRaw(privKey)
+	require.NoError(t, err)
+	require.NoError(t, signingKey.Set(jwk.KeyIDKey, "impostor"))
+	require.NoError(t, signingKey.Set(jwk.AlgorithmKey, jwa.ES256))
+	pubKey, err := signingKey.PublicKey()
+	require.NoError(t, err)
+	keySet := jwk.NewSet()
+	require.NoError(t, keySet.AddKey(pubKey))
+
+	var issuerUrl string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(openIdConfig{Issuer: "https://someone-else.example.com", JWKSURI: issuerUrl + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(keySet)
+	})
+	// Serve the issuer with the cache's own certificate so XRootD would trust it
+	tlsCert, err := tls.LoadX509KeyPair(param.Server_TLSCertificate.GetString(), param.Server_TLSKey.GetString())
+	require.NoError(t, err)
+	issuer := httptest.NewUnstartedServer(mux)
+	issuer.Listener.Close()
+	issuer.Listener, err = net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	issuer.TLS = &tls.Config{Certificates: []tls.Certificate{tlsCert}}
+	issuer.StartTLS()
+	t.Cleanup(issuer.Close)
+	issuerUrl = "https://" + param.Server_Hostname.GetString() + ":" + strconv.Itoa(issuer.Listener.Addr().(*net.TCPAddr).Port)
+
+	parsedIssuer, err := url.Parse(issuerUrl)
+	require.NoError(t, err)
+	cacheServer := &cache_ui.CacheServer{}
+	cacheServer.SetNamespaceAds([]common.NamespaceAdV2{{
+		Path:   "/protected",
+		Caps:   common.Capabilities{Read: true},
+		Issuer: []common.TokenIssuer{{IssuerUrl: *parsedIssuer, BasePaths: []string{"/protected"}}},
+	}})
+
+	require.NoError(t, CheckXrootdEnv(cacheServer))
+	cfg, err := LoadScitokensConfig(filepath.Join(param.Xrootd_RunLocation.GetString(), "scitokens-cache-generated.cfg"))
+	require.NoError(t, err)
+	assert.NotContains(t, cfg.IssuerMap, issuerUrl)
+
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	defer shutdownCancel()
+	configPath, err := ConfigXrootd(shutdownCtx, false)
+	require.NoError(t, err)
+	launchers, err := ConfigureLaunchers(false, configPath, false, true)
+	require.NoError(t, err)
+	require.NoError(t, daemon.LaunchDaemons(shutdownCtx, launchers, egrp))
+
+	cacheUrl := "https://" + param.Server_Hostname.GetString() + ":" + strconv.Itoa(param.Xrootd_Port.GetInt())
+	// In this case a 403 means its running
+	require.NoError(t, server_utils.WaitUntilWorking(ctx, "GET", cacheUrl, "xrootd", 403))
+
+	tok, err := jwt.NewBuilder().
+		Issuer(issuerUrl).
+		Audience([]string{issuerUrl}).
+		Subject("user").
+		Claim("scope", "storage.read:/").
+		Claim("wlcg.ver", "1.0").
+		IssuedAt(time.Now()).
+		NotBefore(time.Now()).
+		Expiration(time.Now().Add(time.Minute)).
+		Build()
+	require.NoError(t, err)
+	signed, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, signingKey))
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cacheUrl+"/protected/hello_world.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+string(signed))
+	resp, err := (&http.Client{Transport: config.GetTransport()}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -83,6 +83,7 @@ type (
 		EnableMacaroons  bool
 		EnableVoms       bool
 		EnableDirListing bool
+		EnableWrite      bool
 		SelfTest         bool
 		NamespacePrefix  string
 		Mode             string
@@ -92,6 +93,8 @@ type (
 		S3ServiceUrl     string
 		S3AccessKeyfile  string
 		S3SecretKeyfile  string
+		HsmStageCommand  string
+		UploadEventsPipe string
 	}
 
 	CacheConfig struct {
@@ -100,6 +103,10 @@ type (
 		ExportLocation string
 		DataLocation   string
 		PSSOrigin      string
+		BlockSize      string
+		PrefetchBlocks int
+		RamSize        string
+		MaxRequestSize string
 	}
 
 	XrootdOptions struct {
@@ -152,7 +159,8 @@ type (
 
 func CheckOriginXrootdEnv(exportPath string, server server_utils.XRootDServer, uid int, gid int, groupname string) (string, error) {
 	originMode := param.Origin_Mode.GetString()
-	if originMode == "posix" {
+	// An HSM origin exports the disk cache of the tape system like a POSIX directory
+	if originMode == "posix" || originMode == "hsm" {
 		// If we use "volume mount" style options, configure the export directories.
 		volumeMount := param.Origin_ExportVolume.GetString()
 		if volumeMount != "" {
@@ -576,39 +584,62 @@ func LaunchXrootdMaintenance(ctx context.Context, server server_utils.XRootDServ
 }
 
 func ConfigXrootd(ctx context.Context, origin bool) (string, error) {
+	runtimeCAs := getRuntimeCABundlePath()
+	caCount, err := utils.LaunchPeriodicWriteCABundle(ctx, runtimeCAs, 2*time.Minute)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to setup the runtime CA bundle")
+	}
+	log.Debugf("A total of %d CA certificates were written", caCount)
+
+	configPath, _, err := writeXrootdConfig(origin, caCount > 0)
+	return configPath, err
+}
+
+// Regenerate the XRootD configuration file from the current configuration, after
+// ConfigXrootd created it.  XRootD only reads the file at startup, so the daemons
+// must be restarted to apply the changes -- but only if the file changed, which is
+// the returned bool.
+func RegenerateXrootdConfig(origin bool) (string, bool, error) {
+	info, err := os.Stat(getRuntimeCABundlePath())
+	return writeXrootdConfig(origin, err == nil && info.Size() > 0)
+}
+
+// The CA bundle kept up to date for XRootD while the server runs
+func getRuntimeCABundlePath() string {
+	return filepath.Join(param.Xrootd_RunLocation.GetString(), "ca-bundle.crt")
+}
 
+// Write the XRootD configuration file from the current configuration; useRuntimeCAs
+// is whether XRootD should trust the runtime CA bundle.  Returns the path of the file
+// and whether its contents changed.
+func writeXrootdConfig(origin bool, useRuntimeCAs bool) (string, bool, error) {
 	gid, err := config.GetDaemonGID()
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	var xrdConfig XrootdConfig
 	xrdConfig.Xrootd.LocalMonitoringPort = -1
 	if err := viper.Unmarshal(&xrdConfig); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	// Map out xrootd logs
 	mapXrootdLogLevels(&xrdConfig)
 
-	runtimeCAs := filepath.Join(param.Xrootd_RunLocation.GetString(), "ca-bundle.crt")
-	caCount, err := utils.LaunchPeriodicWriteCABundle(ctx, runtimeCAs, 2*time.Minute)
-	if err != nil {
-		return "", errors.Wrap(err, "Failed to setup the runtime CA bundle")
-	}
-	log.Debugf("A total of %d CA certificates were written", caCount)
-	if caCount > 0 {
-		xrdConfig.Server.TLSCACertificateFile = runtimeCAs
+	if useRuntimeCAs {
+		xrdConfig.Server.TLSCACertificateFile = getRuntimeCABundlePath()
 	}
 
 	if origin {
+		xrdConfig.Origin.UploadEventsPipe = origin_ui.GetUploadEventsPipe()
 		if xrdConfig.Origin.Multiuser {
 			ok, err := config.HasMultiuserCaps()
 			if err != nil {
-				return "", errors.Wrap(err, "Failed to determine if the origin can run in multiuser mode")
+				return "", false, errors.Wrap(err, "Failed to determine if the origin can run in multiuser mode")
 			}
 			if !ok {
-				return "", errors.New("Origin.Multiuser is set to `true` but the command was run without sufficient privilege; was it launched as root?")
+				return "", false, errors.New("Origin.Multiuser is set to `true` but the command was run without sufficient privilege; was it launched as root?")
 			}
 		}
 	} else if xrdConfig.Cache.PSSOrigin != "" {
@@ -616,7 +647,7 @@ func ConfigXrootd(ctx context.Context, origin bool) (string, error) {
 		// XRootD crashes.
 		urlParsed, err := url.Parse(xrdConfig.Cache.PSSOrigin)
 		if err != nil {
-			return "", errors.Errorf("Director URL (%s) does not parse as a URL", xrdConfig.Cache.PSSOrigin)
+			return "", false, errors.Errorf("Director URL (%s) does not parse as a URL", xrdConfig.Cache.PSSOrigin)
 		}
 		if !strings.Contains(urlParsed.Host, ":") {
 			switch urlParsed.Scheme {
@@ -642,34 +673,21 @@ func ConfigXrootd(ctx context.Context, origin bool) (string, error) {
 
 	templ := template.Must(template.New("xrootd.cfg").Parse(xrootdCfg))
 
-	xrootdRun := param.Xrootd_RunLocation.GetString()
-	configPath := filepath.Join(xrootdRun, "xrootd.cfg")
-	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
-	if err != nil {
-		return "", err
-	}
-	if err = os.Chown(configPath, -1, gid); err != nil {
-		return "", errors.Wrapf(err, "Unable to change ownership of configuration file %v"+
-			" to desired daemon gid %v", configPath, gid)
-	}
-
-	defer file.Close()
-
-	err = templ.Execute(file, xrdConfig)
-	if err != nil {
-		return "", err
+	buffer := new(bytes.Buffer)
+	if err = templ.Execute(buffer, xrdConfig); err != nil {
+		return "", false, err
 	}
-
 	if log.IsLevelEnabled(log.DebugLevel) {
-		buffer := new(bytes.Buffer)
-		err = templ.Execute(buffer, xrdConfig)
-		if err != nil {
-			return "", err
-		}
 		log.Debugln("XRootD configuration file contents:\n", buffer.String())
 	}
 
-	return configPath, nil
+	xrootdRun := param.Xrootd_RunLocation.GetString()
+	configPath := filepath.Join(xrootdRun, "xrootd.cfg")
+	changed, err := writeGeneratedArtifact("xrootd.cfg", configPath, buffer.Bytes(), gid)
+	if err != nil {
+		return "", false, err
+	}
+	return configPath, changed, nil
 }
 
 // Set up xrootd monitoring